@@ -1,19 +1,67 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"connectrpc.com/connect"
 	"github.com/forge/api/gen/forge/v1/forgev1connect"
+	"github.com/forge/api/internal/alerting"
+	"github.com/forge/api/internal/apps"
+	"github.com/forge/api/internal/audit"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/authguard"
 	"github.com/forge/api/internal/cache"
+	"github.com/forge/api/internal/certs"
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/credentials"
 	"github.com/forge/api/internal/db"
+	"github.com/forge/api/internal/deprecation"
+	"github.com/forge/api/internal/deps"
+	"github.com/forge/api/internal/discovery"
+	"github.com/forge/api/internal/errtrack"
+	"github.com/forge/api/internal/events"
+	"github.com/forge/api/internal/files"
+	"github.com/forge/api/internal/flags"
 	"github.com/forge/api/internal/handlers"
+	"github.com/forge/api/internal/history"
+	"github.com/forge/api/internal/hooks"
+	"github.com/forge/api/internal/idempotency"
+	"github.com/forge/api/internal/jobs"
 	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/logmetrics"
+	"github.com/forge/api/internal/logpipelines"
+	"github.com/forge/api/internal/logsampling"
 	"github.com/forge/api/internal/logsources"
+	"github.com/forge/api/internal/messaging"
 	"github.com/forge/api/internal/middleware"
+	"github.com/forge/api/internal/monitors"
+	"github.com/forge/api/internal/mqtt"
+	"github.com/forge/api/internal/notify"
 	"github.com/forge/api/internal/observe"
+	"github.com/forge/api/internal/operations"
+	"github.com/forge/api/internal/ratelimit"
+	"github.com/forge/api/internal/resources"
+	"github.com/forge/api/internal/routelogs"
+	"github.com/forge/api/internal/routeprobes"
 	"github.com/forge/api/internal/routes"
+	"github.com/forge/api/internal/secrets"
+	"github.com/forge/api/internal/server"
+	"github.com/forge/api/internal/services"
+	"github.com/forge/api/internal/setup"
+	"github.com/forge/api/internal/snapshots"
+	"github.com/forge/api/internal/storage"
+	"github.com/forge/api/internal/system"
+	"github.com/forge/api/internal/tracing"
+	"github.com/forge/api/internal/version"
+	"github.com/forge/api/internal/webhooks"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"golang.org/x/net/http2"
@@ -25,117 +73,997 @@ var startTime = time.Now()
 func main() {
 	log := logger.Get()
 
-	// Configuration from environment
-	port := getEnv("PORT", "8080")
+	// Configuration from forge.yaml and the environment
+	cfg, err := config.FromEnv()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Configuration invalid")
+	}
+	if cfg.Path != "" {
+		log.Info().Str("path", cfg.Path).Msg("Configuration file loaded")
+	}
+	port := strconv.Itoa(cfg.Server.Port)
+	build := version.Get()
+	log.Info().Str("version", build.Version).Str("commit", build.Commit).Str("build_date", build.BuildDate).Msg("Forge API build")
+
+	// OpenTelemetry self-instrumentation (spans exported to Tempo)
+	if _, err := tracing.Init(context.Background(), cfg.Observe, "forge-api", version.Version); err != nil {
+		log.Warn().Err(err).Msg("Tracing init failed")
+	}
+
+	// Dependency state registry (drives degraded behaviour in handlers)
+	depsRegistry := deps.NewRegistry()
+	depsRegistry.SetHint("mysql", "Enable the 'db' profile in COMPOSE_PROFILES and check MYSQL_* settings")
+	depsRegistry.SetHint("redis", "Enable the 'cache' profile in COMPOSE_PROFILES and check REDIS_* settings")
+	depsRegistry.SetHint("storage", "Enable the 'storage' profile in COMPOSE_PROFILES and check STORAGE_* settings")
+	depsRegistry.SetHint("messaging", "Enable the 'messaging' profile in COMPOSE_PROFILES and check MESSAGING_* settings")
+	depsRegistry.SetHint("mqtt", "Enable the 'mqtt' profile in COMPOSE_PROFILES or point MQTT_URL at your broker, and check MQTT_* settings")
+
+	// Initialize clients; supervisors keep reconnecting in the background,
+	// so MySQL and Redis may come up after the API or go away and return
+	mysqlClient, err := db.NewMySQLClient(cfg.MySQL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("MySQL client init failed")
+	}
+	mysqlSupervisor := depsRegistry.Supervise("mysql", mysqlClient.Ping, func(reason string) {
+		depsRegistry.MarkUnavailable("mysql", reason, "db query", "db execute", "web UI login", "metric snapshots", "error tracking", "usage history")
+	})
+	go mysqlSupervisor.Run(context.Background())
+
+	redisClient := cache.NewRedisClient(cfg.Redis)
+	redisSupervisor := depsRegistry.Supervise("redis", redisClient.Ping, func(reason string) {
+		depsRegistry.MarkDegraded("redis", reason, "memory", "cache persistence", "cache shared between API instances")
+	})
+	go redisSupervisor.Run(context.Background())
+
+	// Object storage is not waited for; nothing else needs it to start
+	storageClient, err := storage.NewClient(cfg.Storage)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Storage client init failed")
+	}
+	storageSupervisor := depsRegistry.Supervise("storage", storageClient.Ping, func(reason string) {
+		depsRegistry.MarkUnavailable("storage", reason, "object storage", "storage credentials")
+	})
+	storageSupervisor.OnConnect(func() {
+		if err := storageClient.EnsureBucket(context.Background()); err != nil {
+			log.Warn().Err(err).Str("bucket", storageClient.DefaultBucket()).Msg("Failed to create the default storage bucket")
+		}
+	})
+	go storageSupervisor.Run(context.Background())
+
+	// The message bus is optional too; its supervisor keeps the connection up
+	messagingClient, err := messaging.NewClient(cfg.Messaging)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Messaging client init failed")
+	}
+	messagingSupervisor := depsRegistry.Supervise("messaging", messagingClient.Ping, func(reason string) {
+		depsRegistry.MarkUnavailable("messaging", reason, "publish", "subscribe")
+	})
+	go messagingSupervisor.Run(context.Background())
+
+	// So is the MQTT broker; bridges subscribe whenever it is reached
+	mqttClient, err := mqtt.NewClient(cfg.MQTT)
+	if err != nil {
+		log.Fatal().Err(err).Msg("MQTT client init failed")
+	}
+	mqttSupervisor := depsRegistry.Supervise("mqtt", mqttClient.Ping, func(reason string) {
+		depsRegistry.MarkUnavailable("mqtt", reason, "mqtt publish", "mqtt bridges")
+	})
+	go mqttSupervisor.Run(context.Background())
 
-	// Initialize clients
-	mysqlClient, err := db.NewMySQLClient()
+	// Give both a moment so features that need them start in order;
+	// those that need MySQL start once it is reached otherwise
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), cfg.Server.DependencyWait)
+	if !mysqlSupervisor.Wait(waitCtx) {
+		log.Warn().Msg("MySQL not available yet, dependent features start once it is")
+	}
+	if !redisSupervisor.Wait(waitCtx) {
+		log.Warn().Msg("Redis not available yet, cache falls back to memory until it is")
+	}
+	cancelWait()
+
+	lokiClient := observe.NewLokiClient(cfg.Observe.LokiURL)
+	lokiClient.Start()
+	tempoClient := observe.NewTempoClient(cfg.Observe.TempoURL, cfg.Observe.TempoQueryURL)
+
+	// Parsing/labelling pipelines for entries pushed through Forge
+	logPipelines, err := logpipelines.NewManager(cfg.Paths.LogPipelines)
+	if err != nil {
+		log.Warn().Err(err).Msg("Log pipelines init failed")
+	} else {
+		lokiClient.AddProcessor(logPipelines)
+	}
+
+	// Counters derived from pushed log lines (after pipelines have run)
+	metricsRegistry := observe.NewMetricsRegistry(prometheus.DefaultRegisterer)
+	logMetrics, err := logmetrics.NewManager(cfg.Paths.LogMetrics, metricsRegistry)
 	if err != nil {
-		log.Warn().Err(err).Msg("MySQL not available")
+		log.Warn().Err(err).Msg("Log metrics init failed")
+	} else {
+		lokiClient.AddProcessor(logMetrics)
 	}
 
-	redisClient, err := cache.NewRedisClient()
+	// Level filtering and sampling, last so log metrics still count every entry
+	logSampling, err := logsampling.NewManager(cfg.Paths.LogSampling)
 	if err != nil {
-		log.Warn().Err(err).Msg("Redis not available")
+		log.Warn().Err(err).Msg("Log sampling init failed")
+	} else {
+		lokiClient.AddProcessor(logSampling)
 	}
 
-	lokiClient := observe.NewLokiClient()
+	// Optional syslog ingestion (UDP+TCP), forwarded to Loki
+	if addr := cfg.Server.SyslogAddr; addr != "" {
+		if err := observe.NewSyslogListener(addr, lokiClient).Start(); err != nil {
+			log.Warn().Err(err).Msg("Syslog listener failed to start")
+		} else {
+			version.Enable("syslog")
+		}
+	}
+	promClient := observe.NewPrometheusClient(cfg.Observe.PrometheusURL)
 
 	// Initialize routes manager
-	routesConfigPath := getEnv("ROUTES_CONFIG", "/app/data/routes/routes.yaml")
-	nginxDynamicConf := getEnv("NGINX_DYNAMIC_CONF", "/app/data/routes/routes.conf")
-	routesManager, err := routes.NewManager(routesConfigPath, nginxDynamicConf)
+	// Proxy backend: nginx config files (default) or Caddy's admin API
+	var proxyBackend routes.Backend
+	var nginxBackend *routes.Nginx
+	version.Enable("proxy_" + cfg.Features.ProxyBackend)
+	switch cfg.Features.ProxyBackend {
+	case "caddy":
+		proxyBackend = routes.NewCaddy(routes.NewCaddyConfig(cfg.Proxy))
+	default:
+		nginxBackend = routes.NewNginx(cfg.Paths.NginxConf)
+		if reloader, err := routes.NewReloader(cfg.Proxy); err != nil {
+			log.Warn().Err(err).Msg("Invalid nginx reload method, using the Docker API")
+		} else {
+			nginxBackend.SetReloader(reloader)
+		}
+		proxyBackend = nginxBackend
+	}
+	routesManager, err := routes.NewManager(cfg.Paths.Routes, proxyBackend)
 	if err != nil {
 		log.Warn().Err(err).Msg("Routes manager init failed")
 	}
 
+	// Internal events (route changes, container health, alerts, backups),
+	// delivered to webhook subscriptions
+	eventBus := events.NewBus()
+	if routesManager != nil {
+		routesManager.OnRouteChange(func(c routes.RouteChange) { eventBus.Publish(events.RouteChanged, c) })
+	}
+
+	// Secrets store (referenced from routes as ${secret.name})
+	secretsStore := secrets.NewStore(cfg.Paths.Secrets)
+	if routesManager != nil {
+		routesManager.RegisterVariables("secret", secretsStore.Get)
+	}
+
+	// TLS certificates (ACME) for routes with a domain; Caddy obtains its own
+	certsManager, err := certs.NewManager(certs.NewConfig(cfg.Certs))
+	if err != nil {
+		log.Warn().Err(err).Msg("Certificates manager init failed")
+	}
+	if certsManager != nil && routesManager != nil && nginxBackend != nil {
+		nginxBackend.SetCertificates(certsManager.Paths)
+		routesManager.OnChange(func() { certsManager.Ensure(routesManager.Domains()) })
+		certsManager.OnIssued(func() {
+			if err := routesManager.Regenerate(); err != nil {
+				log.Warn().Err(err).Msg("Failed to apply certificates to nginx")
+			}
+		})
+		certsManager.Ensure(routesManager.Domains())
+	}
+
+	// First-boot setup; its external host applies unless one is configured
+	setupManager, err := setup.NewManager(cfg.Paths.Setup)
+	if err != nil {
+		log.Warn().Err(err).Msg("Setup manager init failed")
+	}
+	// externalHost is the host SDK clients outside Docker reach the stack
+	// on; empty until configured or chosen during setup
+	externalHost := func() string {
+		if cfg.Server.ExternalHost != "" {
+			return cfg.Server.ExternalHost
+		}
+		if setupManager != nil {
+			return setupManager.State().ExternalHost
+		}
+		return ""
+	}
+
 	// Create handlers
-	forgeHandler := handlers.NewForgeHandler(startTime, mysqlClient, redisClient)
-	dbHandler := handlers.NewDatabaseHandler(mysqlClient)
-	cacheHandler := handlers.NewCacheHandler(redisClient)
-	observeHandler := handlers.NewObserveHandler(lokiClient)
+	forgeHandler := handlers.NewForgeHandler(startTime, mysqlClient, redisClient, depsRegistry, cfg.Health)
+	// Connection info hands out short-lived accounts scoped per requester,
+	// never the root MySQL or Redis credentials
+	mysqlBroker := credentials.NewMySQLBroker(mysqlClient.DB(), cfg.MySQL.CredentialsDatabase, cfg.MySQL.Database)
+	mysqlSupervisor.OnConnect(func() { go mysqlBroker.Run(context.Background()) })
+	redisBroker := credentials.NewRedisBroker(redisClient)
+	redisSupervisor.OnConnect(func() { go redisBroker.Run(context.Background()) })
+	dbHandler := handlers.NewDatabaseHandler(mysqlClient, mysqlBroker, depsRegistry, cfg.MySQL.Port, externalHost)
+	cacheHandler := handlers.NewCacheHandler(redisClient, redisBroker, depsRegistry, cfg.Redis.Port, externalHost)
+	storageHandler := handlers.NewStorageHandler(storageClient, credentials.NewStorageBroker(storageClient), depsRegistry)
+	messagingHandler := handlers.NewMessagingHandler(messagingClient, depsRegistry)
+	observeHandler := handlers.NewObserveHandler(lokiClient, tempoClient, metricsRegistry)
+
+	// Deprecated endpoints (headers + usage tracking)
+	deprecations := deprecation.NewRegistry()
+	deprecations.Register(deprecation.Endpoint{
+		Path:        "/forge.v1.ForgeService/Info",
+		Since:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Replacement: "/api/v1/health",
+		Message:     "Use /api/v1/health for service status, /api/v1/version for the build and /api/v1/system for details",
+	})
+
+	// API keys; enforced once a key is issued or FORGE_ADMIN_KEY is set.
+	// A keys file that cannot be read must not leave the API open.
+	authStore, err := auth.NewStore(cfg.Paths.AuthKeys, cfg.Auth.AdminKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Auth keys init failed")
+	}
+	// OIDC identity provider; JWTs are accepted alongside API keys
+	if oidcConfig, err := auth.NewOIDCConfig(cfg.Auth.OIDC); err != nil {
+		log.Fatal().Err(err).Msg("OIDC config invalid")
+	} else if oidcConfig != nil {
+		authStore.SetJWT(auth.NewJWTVerifier(*oidcConfig))
+		version.Enable("oidc")
+		log.Info().Str("issuer", oidcConfig.Issuer).Str("role_claim", oidcConfig.RoleClaim).Msg("OIDC authentication enabled")
+	}
+	// Verified TLS client certificates identify machine clients (mTLS)
+	if certConfig, err := auth.NewCertConfig(cfg.TLS); err != nil {
+		log.Fatal().Err(err).Msg("Client certificate config invalid")
+	} else if certConfig != nil {
+		authStore.SetCerts(certConfig)
+		version.Enable("client_certs")
+		log.Info().Str("ca", cfg.TLS.ClientCAFile).Str("default_role", certConfig.DefaultRole).Msg("Client certificate authentication enabled")
+	}
+	interceptors := []connect.Interceptor{auth.NewInterceptor(authStore)}
+
+	// Rate limits per client and endpoint class, counted in memory or Redis.
+	// Like lockouts and idempotency keys below, they use Redis whenever it
+	// is up and memory while it is down.
+	limiter, err := ratelimit.FromConfig(cfg.RateLimits, redisClient, redisSupervisor.Up)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Rate limit config invalid")
+	}
+	if limiter != nil {
+		interceptors = append(interceptors, ratelimit.NewInterceptor(limiter))
+		client, global := limiter.Limits()
+		version.Enable("rate_limits")
+		log.Info().Str("per_client", client.String()).Str("global", global.String()).Msg("Rate limiting enabled")
+	}
+	// Lockouts after repeated authentication failures, counted in Redis
+	// when available so replicas share them
+	authGuard := authguard.FromConfig(cfg.Auth.Guard, redisClient, redisSupervisor.Up)
+	if authGuard != nil {
+		version.Enable("auth_guard")
+		guardCfg := authGuard.Config()
+		log.Info().Int("threshold", guardCfg.Threshold).Dur("window", guardCfg.Window).Msg("Authentication failure lockouts enabled")
+	}
+	connectOpts := connect.WithHandlerOptions(
+		connect.WithInterceptors(interceptors...),
+		middleware.ConnectRecover(),
+		// Bounds decompressed messages; Limits bounds them as sent
+		connect.WithReadMaxBytes(middleware.MaxIngestBodySize),
+	)
 
 	// Create mux
 	mux := http.NewServeMux()
 
+	authHandler := handlers.NewAuthHandler(authStore)
+	mux.HandleFunc("/api/v1/auth/keys", authHandler.HandleKeys)
+	mux.HandleFunc("/api/v1/auth/keys/", authHandler.HandleKeys)
+	mux.HandleFunc("/api/v1/auth/whoami", authHandler.WhoAmI)
+
+	// Web UI logins: users with bcrypt passwords and session cookies in MySQL
+	// The bootstrap admin is checked before serving; creating it waits for
+	// MySQL, where a failure is logged rather than taking the API down
+	adminUser, adminPassword := cfg.Auth.AdminUser, cfg.Auth.AdminPassword
+	if adminPassword != "" {
+		if err := auth.ValidateBootstrap(adminUser, adminPassword); err != nil {
+			log.Fatal().Err(err).Msg("Bootstrap admin user invalid")
+		}
+	}
+	mysqlSupervisor.OnConnect(func() {
+		sessionStore, err := auth.NewSessionStore(mysqlClient.DB(), cfg.MySQL.Database, cfg.Auth.SessionTTL)
+		if err != nil {
+			log.Warn().Err(err).Msg("Session store init failed, web UI login disabled")
+		} else {
+			if adminPassword != "" {
+				if created, err := sessionStore.Bootstrap(context.Background(), adminUser, adminPassword); err != nil {
+					log.Error().Err(err).Msg("Bootstrap admin user not created")
+				} else if created {
+					log.Info().Str("username", adminUser).Msg("Created bootstrap admin user")
+				}
+			}
+			authStore.SetSessions(sessionStore)
+			version.Enable("web_login")
+			go sessionStore.Run(context.Background())
+			sessionsHandler := handlers.NewSessionsHandler(sessionStore, authGuard, cfg.Auth.SessionCookieSecure)
+			mux.HandleFunc(auth.LoginPath, sessionsHandler.Login)
+			mux.HandleFunc("/api/v1/auth/logout", sessionsHandler.Logout)
+			mux.HandleFunc("/api/v1/auth/session", sessionsHandler.Session)
+			mux.HandleFunc("/api/v1/auth/users", sessionsHandler.HandleUsers)
+			mux.HandleFunc("/api/v1/auth/users/", sessionsHandler.HandleUsers)
+		}
+	})
+
+	// Labeled resources from all subsystems, searchable at /api/v1/resources
+	resourceIndex := resources.NewIndex()
+
+	// Long-running background operations, polled at /api/v1/operations/{id}
+	operationsManager := operations.NewManager()
+	operationsHandler := handlers.NewOperationsHandler(operationsManager)
+	mux.HandleFunc("/api/v1/operations", operationsHandler.HandleOperations)
+	mux.HandleFunc("/api/v1/operations/", operationsHandler.HandleOperations)
+
 	// Register Connect services
-	mux.Handle(forgev1connect.NewForgeServiceHandler(forgeHandler))
-	mux.Handle(forgev1connect.NewDatabaseServiceHandler(dbHandler))
-	mux.Handle(forgev1connect.NewCacheServiceHandler(cacheHandler))
-	mux.Handle(forgev1connect.NewObserveServiceHandler(observeHandler))
+	mux.Handle(forgev1connect.NewForgeServiceHandler(forgeHandler, connectOpts))
+	mux.Handle(forgev1connect.NewDatabaseServiceHandler(dbHandler, connectOpts))
+	mux.Handle(forgev1connect.NewCacheServiceHandler(cacheHandler, connectOpts))
+	mux.Handle(forgev1connect.NewObserveServiceHandler(observeHandler, connectOpts))
+	mux.Handle(forgev1connect.NewStorageServiceHandler(storageHandler, connectOpts))
+	mux.Handle(forgev1connect.NewMessagingServiceHandler(messagingHandler, connectOpts))
 
 	// Prometheus metrics endpoint
-	mux.Handle("/metrics", promhttp.Handler())
+	// OpenMetrics format is needed to expose trace_id exemplars
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})))
 
 	// REST endpoints
 	mux.HandleFunc("/api/v1/health", handlers.HealthREST(forgeHandler))
+	mux.HandleFunc("/api/v1/version", handlers.VersionREST(startTime))
+
+	// Runtime diagnostics (pprof, expvar, profile dumps) for admins
+	if cfg.Features.DebugEndpoints {
+		mux.Handle("/debug/", handlers.NewDebugHandler(cfg.Paths.DebugDumps))
+		version.Enable("debug_endpoints")
+		log.Info().Str("dumps", cfg.Paths.DebugDumps).Msg("Debug endpoints enabled at /debug/")
+	}
 	mux.HandleFunc("/api/v1/db/query", handlers.QueryREST(dbHandler))
 	mux.HandleFunc("/api/v1/db/execute", handlers.ExecuteREST(dbHandler))
 	mux.HandleFunc("/api/v1/db/info", handlers.DBInfoREST(dbHandler))
+	mux.HandleFunc("/api/v1/db/hints", handlers.VizHintsREST())
 	mux.HandleFunc("/api/v1/cache/", handlers.CacheREST(cacheHandler))
 	mux.HandleFunc("/api/v1/cache/info", handlers.CacheInfoREST(cacheHandler))
+	mux.HandleFunc("/api/v1/storage/", storageHandler.HandleStorage)
+	mux.HandleFunc("/api/v1/storage/info", handlers.StorageInfoREST(storageHandler))
+	mux.HandleFunc("/api/v1/messaging/", messagingHandler.HandleMessaging)
+
+	// Files on the local volume, for setups without object storage
+	filesStore, err := files.NewStore(cfg.Paths.Files, cfg.Files.MaxSize(), cfg.Files.Quota())
+	if err != nil {
+		log.Warn().Err(err).Msg("Files init failed")
+	} else {
+		filesHandler := handlers.NewFilesHandler(filesStore)
+		mux.HandleFunc("/api/v1/files", filesHandler.HandleFiles)
+		mux.HandleFunc("/api/v1/files/", filesHandler.HandleFiles)
+	}
+
 	mux.HandleFunc("/api/v1/logs", handlers.LogsREST(observeHandler))
+	mux.HandleFunc("/api/v1/logs/query", handlers.LogsQueryREST(observeHandler))
+	mux.HandleFunc("/api/v1/logs/tail", handlers.LogsTailREST(observeHandler))
+	mux.HandleFunc("/api/v1/logs/ingest/fluent", handlers.LogsIngestFluentREST(observeHandler))
+	mux.HandleFunc("/api/v1/profiles/ingest", handlers.ProfilesIngestREST(observe.NewPyroscopeClient(cfg.Observe.PyroscopeURL)))
 	mux.HandleFunc("/api/v1/metrics", handlers.MetricsREST(observeHandler))
+	mux.HandleFunc("/api/v1/metrics/timing", handlers.TimingREST(observeHandler))
+	promQLHandler := handlers.NewPromQLHandler(promClient)
+	mux.HandleFunc("/api/v1/metrics/query", promQLHandler.Query)
+	mux.HandleFunc("/api/v1/metrics/query_range", promQLHandler.QueryRange)
 	mux.HandleFunc("/api/v1/traces", handlers.TracesREST(observeHandler))
+	mux.HandleFunc("/api/v1/traces/", handlers.TraceLookupREST(observeHandler))
+	grafanaHandler := handlers.NewGrafanaHandler(observe.NewGrafanaClient(cfg.Observe.Grafana))
+	mux.HandleFunc("/api/v1/grafana/datasources", grafanaHandler.HandleDatasources)
+	mux.HandleFunc("/api/v1/grafana/datasources/", grafanaHandler.HandleDatasources)
 
 	// Routes management (dynamic nginx routes)
 	if routesManager != nil {
-		routesHandler := handlers.NewRoutesHandler(routesManager)
+		routesHandler := handlers.NewRoutesHandler(routesManager, operationsManager)
 		mux.HandleFunc("/api/v1/routes", routesHandler.HandleRoutes)
 		mux.HandleFunc("/api/v1/routes/", routesHandler.HandleRoutes)
 	}
 
+	// Routes for containers labelled forge.route.path (Traefik-style discovery)
+	if routesManager != nil && cfg.Features.DockerDiscovery {
+		version.Enable("docker_discovery")
+		go discovery.New(routesManager, system.NewDockerClient(cfg.Docker), cfg.Features.DockerDiscoveryInterval).Run(context.Background())
+	}
+
+	// Service registry (apps register with TTL heartbeats; routes and
+	// scrape targets for the services that ask for them)
+	servicesRegistry, err := services.NewRegistry(cfg.Paths.Services, cfg.Paths.ServiceTargets, routesManager)
+	if err != nil {
+		log.Warn().Err(err).Msg("Service registry init failed")
+	} else {
+		go servicesRegistry.Run(context.Background())
+		forgeHandler.SetRegisteredServices(servicesRegistry.List)
+		servicesHandler := handlers.NewServicesHandler(servicesRegistry)
+		mux.HandleFunc("/api/v1/services", servicesHandler.HandleServices)
+		mux.HandleFunc("/api/v1/services/", servicesHandler.HandleServices)
+	}
+
+	// Certificates management and ACME http-01 challenges (proxied by nginx)
+	if certsManager != nil {
+		go certsManager.Run(context.Background())
+		certsHandler := handlers.NewCertsHandler(certsManager)
+		mux.HandleFunc("/api/v1/certs", certsHandler.HandleCerts)
+		mux.HandleFunc("/api/v1/certs/", certsHandler.HandleCerts)
+		mux.HandleFunc("/.well-known/acme-challenge/", certsManager.ServeChallenge)
+	}
+
 	// Log sources management (dynamic Promtail config)
-	logSourcesConfigPath := getEnv("PROMTAIL_SOURCES_CONFIG", "/app/data/promtail/logsources.yaml")
-	promtailDynamicConf := getEnv("PROMTAIL_DYNAMIC_CONF", "/app/data/promtail/promtail-dynamic.yml")
-	logSourcesManager, err := logsources.NewManager(logSourcesConfigPath, promtailDynamicConf)
+	logSourcesManager, err := logsources.NewManager(cfg.Paths.PromtailSources, cfg.Paths.PromtailConf)
 	if err != nil {
 		log.Warn().Err(err).Msg("Log sources manager init failed")
 	}
 	if logSourcesManager != nil {
-		logSourcesHandler := handlers.NewLogSourcesHandler(logSourcesManager)
+		logSourcesHandler := handlers.NewLogSourcesHandler(logSourcesManager, cfg.Observe.PromtailURL)
 		mux.HandleFunc("/api/v1/logs/sources", logSourcesHandler.HandleLogSources)
 		mux.HandleFunc("/api/v1/logs/sources/", logSourcesHandler.HandleLogSources)
+
+		// Ship per-route nginx access logs into Loki with a route label
+		if routesManager != nil && nginxBackend != nil {
+			routelogs.New(routesManager, logSourcesManager, cfg.Paths.PromtailRouteLogs).Sync()
+		}
+	}
+	if logPipelines != nil {
+		logPipelinesHandler := handlers.NewLogPipelinesHandler(logPipelines)
+		mux.HandleFunc("/api/v1/logs/pipelines", logPipelinesHandler.HandlePipelines)
+		mux.HandleFunc("/api/v1/logs/pipelines/", logPipelinesHandler.HandlePipelines)
+	}
+	if logSampling != nil {
+		logSamplingHandler := handlers.NewLogSamplingHandler(logSampling)
+		mux.HandleFunc("/api/v1/logs/sampling", logSamplingHandler.HandleSampling)
+		mux.HandleFunc("/api/v1/logs/sampling/", logSamplingHandler.HandleSampling)
+	}
+	if logMetrics != nil {
+		logMetricsHandler := handlers.NewLogMetricsHandler(logMetrics)
+		mux.HandleFunc("/api/v1/observe/log-metrics", logMetricsHandler.HandleLogMetrics)
+		mux.HandleFunc("/api/v1/observe/log-metrics/", logMetricsHandler.HandleLogMetrics)
+	}
+
+	// Long-term metric snapshots (PromQL -> MySQL)
+	mysqlSupervisor.OnConnect(func() {
+		snapshotManager, err := snapshots.NewManager(
+			cfg.Paths.Snapshots,
+			mysqlClient.DB(),
+			cfg.MySQL.Database,
+			promClient,
+		)
+		if err != nil {
+			log.Warn().Err(err).Msg("Metric snapshots init failed")
+		} else {
+			go snapshotManager.Run(context.Background())
+			resourceIndex.Register("snapshot", func() []resources.Resource {
+				var list []resources.Resource
+				for _, s := range snapshotManager.List() {
+					list = append(list, resources.Resource{Kind: "snapshot", Name: s.Name, Labels: s.Labels})
+				}
+				return list
+			})
+			snapshotsHandler := handlers.NewSnapshotsHandler(snapshotManager)
+			mux.HandleFunc("/api/v1/metrics/snapshots", snapshotsHandler.HandleSnapshots)
+			mux.HandleFunc("/api/v1/metrics/snapshots/", snapshotsHandler.HandleSnapshots)
+		}
+	})
+
+	// Error tracking (exception events grouped by fingerprint -> MySQL)
+	mysqlSupervisor.OnConnect(func() {
+		errorStore, err := errtrack.NewStore(mysqlClient.DB(), cfg.MySQL.Database)
+		if err != nil {
+			log.Warn().Err(err).Msg("Error tracking init failed")
+		} else {
+			go errorStore.Run(context.Background())
+			errorsHandler := handlers.NewErrorsHandler(errorStore)
+			mux.Handle(forgev1connect.NewErrorsServiceHandler(errorsHandler, connectOpts))
+			mux.HandleFunc("/api/v1/errors", errorsHandler.HandleErrors)
+			mux.HandleFunc("/api/v1/errors/", errorsHandler.HandleErrors)
+		}
+	})
+
+	// Feature flags for hosted apps (MySQL, flag set cached in Redis)
+	mysqlSupervisor.OnConnect(func() {
+		flagStore, err := flags.NewStore(mysqlClient.DB(), cfg.MySQL.Database, redisClient, redisSupervisor.Up)
+		if err != nil {
+			log.Warn().Err(err).Msg("Feature flags init failed")
+		} else {
+			redisSupervisor.OnConnect(func() { go flagStore.Run(context.Background()) })
+			flagsHandler := handlers.NewFlagsHandler(flagStore)
+			mux.Handle(forgev1connect.NewFlagsServiceHandler(flagsHandler, connectOpts))
+			mux.HandleFunc("/api/v1/flags", flagsHandler.HandleFlags)
+			mux.HandleFunc("/api/v1/flags/", flagsHandler.HandleFlags)
+		}
+	})
+
+	// Alerting (Alertmanager receivers, alerts, silences)
+	alertmanagerClient := alerting.NewClient(cfg.Observe.AlertmanagerURL)
+	alertmanagerClient.OnAlert(func(a alerting.PostableAlert, resolved bool) {
+		if resolved {
+			eventBus.Publish(events.AlertResolved, a)
+		} else {
+			eventBus.Publish(events.AlertFired, a)
+		}
+	})
+	if authGuard != nil {
+		authGuard.SetNotifier(alertmanagerClient)
+	}
+	alertingManager, err := alerting.NewManager(
+		cfg.Paths.Alerting,
+		cfg.Paths.AlertmanagerConf,
+		cfg.SMTP,
+		alertmanagerClient,
+	)
+	if err != nil {
+		log.Warn().Err(err).Msg("Alerting manager init failed")
+	}
+	logRules, err := alerting.NewRuleManager(
+		cfg.Paths.AlertingLogRules,
+		lokiClient,
+		alertmanagerClient,
+	)
+	if err != nil {
+		log.Warn().Err(err).Msg("Log rules init failed")
+	}
+	if alertingManager != nil && logRules != nil {
+		go logRules.Run(context.Background())
+		resourceIndex.Register("logrule", func() []resources.Resource {
+			var list []resources.Resource
+			for _, r := range logRules.List() {
+				list = append(list, resources.Resource{Kind: "logrule", Name: r.Name, Labels: r.Labels})
+			}
+			return list
+		})
+		alertsHandler := handlers.NewAlertsHandler(alertingManager, alertmanagerClient, logRules)
+		mux.HandleFunc("/api/v1/alerts", alertsHandler.HandleAlerts)
+		mux.HandleFunc("/api/v1/alerts/", alertsHandler.HandleAlerts)
+	}
+
+	// Uptime monitors (HTTP/TCP/ping checks, alerts via Alertmanager)
+	monitorsManager, err := monitors.NewManager(cfg.Paths.Monitors, alertmanagerClient)
+	if err != nil {
+		log.Warn().Err(err).Msg("Monitors init failed")
+	}
+	var routeProber *routeprobes.Prober
+	if monitorsManager != nil {
+		// Health probes for routes that ask for one, checked through nginx
+		if routesManager != nil {
+			routeProber = routeprobes.New(routesManager, monitorsManager, cfg.Proxy.ProbeBaseURL)
+			routeProber.Sync()
+		}
+		go monitorsManager.Run(context.Background())
+		resourceIndex.Register("monitor", func() []resources.Resource {
+			var list []resources.Resource
+			for _, m := range monitorsManager.List() {
+				list = append(list, resources.Resource{Kind: "monitor", Name: m.Name, Labels: m.Labels})
+			}
+			return list
+		})
+		monitorsHandler := handlers.NewMonitorsHandler(monitorsManager)
+		mux.HandleFunc("/api/v1/monitors", monitorsHandler.HandleMonitors)
+		mux.HandleFunc("/api/v1/monitors/", monitorsHandler.HandleMonitors)
+	}
+
+	// Scheduled jobs (HTTP calls, SQL, cache ops, restarts, backups on cron)
+	jobActions := jobs.NewActions(mysqlClient.DB(), redisClient, redisSupervisor.Up, system.NewDockerClient(cfg.Docker), cfg.Paths.Backups)
+	jobsManager, err := jobs.NewManager(cfg.Paths.Jobs, jobActions)
+	if err != nil {
+		log.Warn().Err(err).Msg("Jobs init failed")
+	} else {
+		jobsManager.OnRun(func(job jobs.Job, run jobs.Run) {
+			if job.Action.Type == jobs.ActionBackup {
+				eventBus.Publish(events.BackupFinished, map[string]any{"job": job.Name, "database": job.Action.Database, "run": run})
+			}
+			if run.Status == jobs.StatusFailed {
+				eventBus.Publish(events.JobFailed, map[string]any{"job": job.Name, "action": job.Action.Type, "run": run})
+			}
+		})
+		go jobsManager.Run(context.Background())
+		resourceIndex.Register("job", func() []resources.Resource {
+			var list []resources.Resource
+			for _, j := range jobsManager.List() {
+				list = append(list, resources.Resource{Kind: "job", Name: j.Name, Labels: j.Labels})
+			}
+			return list
+		})
+		jobsHandler := handlers.NewJobsHandler(jobsManager)
+		mux.HandleFunc("/api/v1/jobs", jobsHandler.HandleJobs)
+		mux.HandleFunc("/api/v1/jobs/", jobsHandler.HandleJobs)
+	}
+
+	// Outbound webhooks (signed event deliveries with retries)
+	webhooksManager, err := webhooks.NewManager(cfg.Paths.Webhooks)
+	if err != nil {
+		log.Warn().Err(err).Msg("Webhooks init failed")
+	} else {
+		eventBus.OnEvent(webhooksManager.Dispatch)
+		go webhooksManager.Run(context.Background())
+		resourceIndex.Register("webhook", func() []resources.Resource {
+			var list []resources.Resource
+			for _, wh := range webhooksManager.List() {
+				list = append(list, resources.Resource{Kind: "webhook", Name: wh.Name, Labels: wh.Labels})
+			}
+			return list
+		})
+		webhooksHandler := handlers.NewWebhooksHandler(webhooksManager)
+		mux.HandleFunc("/api/v1/webhooks", webhooksHandler.HandleWebhooks)
+		mux.HandleFunc("/api/v1/webhooks/", webhooksHandler.HandleWebhooks)
+	}
+
+	// Notification channels (Slack, Discord, Telegram, Gotify, ntfy,
+	// webhooks) that apps send to by name and bus events are routed to
+	notifyManager, err := notify.NewManager(cfg.Paths.Notify)
+	if err != nil {
+		log.Warn().Err(err).Msg("Notifications init failed")
+	} else {
+		eventBus.OnEvent(notifyManager.Dispatch)
+		go notifyManager.Run(context.Background())
+		resourceIndex.Register("channel", func() []resources.Resource {
+			var list []resources.Resource
+			for _, ch := range notifyManager.List() {
+				list = append(list, resources.Resource{Kind: "channel", Name: ch.Name, Labels: ch.Labels})
+			}
+			return list
+		})
+		notifyHandler := handlers.NewNotifyHandler(notifyManager)
+		mux.HandleFunc("/api/v1/notify", notifyHandler.HandleNotify)
+		mux.HandleFunc("/api/v1/notify/", notifyHandler.HandleNotify)
+	}
+
+	// Inbound hooks (signed third-party webhooks fanned out to queues,
+	// internal services, logs and metrics)
+	hookTargets := hooks.NewTargets(redisClient, redisSupervisor.Up, lokiClient, metricsRegistry)
+	hooksManager, err := hooks.NewManager(cfg.Paths.Hooks, hookTargets)
+	if err != nil {
+		log.Warn().Err(err).Msg("Hooks init failed")
+	} else {
+		resourceIndex.Register("hook", func() []resources.Resource {
+			var list []resources.Resource
+			for _, hk := range hooksManager.List() {
+				list = append(list, resources.Resource{Kind: "hook", Name: hk.Name, Labels: hk.Labels})
+			}
+			return list
+		})
+		hooksHandler := handlers.NewHooksHandler(hooksManager)
+		mux.HandleFunc("/api/v1/hooks", hooksHandler.HandleHooks)
+		mux.HandleFunc("/api/v1/hooks/", hooksHandler.HandleHooks)
+		mux.HandleFunc(auth.HooksPrefix, hooksHandler.Receive)
+	}
+
+	// MQTT bridges (broker topics forwarded to logs, metrics and webhooks)
+	mqttTargets := mqtt.NewTargets(lokiClient, metricsRegistry, eventBus)
+	mqttManager, err := mqtt.NewManager(cfg.Paths.MQTT, mqttClient, mqttTargets)
+	if err != nil {
+		log.Warn().Err(err).Msg("MQTT bridges init failed")
+	} else {
+		go mqttManager.Run(context.Background())
+		resourceIndex.Register("bridge", func() []resources.Resource {
+			var list []resources.Resource
+			for _, b := range mqttManager.List() {
+				list = append(list, resources.Resource{Kind: "bridge", Name: b.Name, Labels: b.Labels})
+			}
+			return list
+		})
+		mqttHandler := handlers.NewMQTTHandler(mqttClient, mqttManager, depsRegistry)
+		mux.HandleFunc("/api/v1/mqtt/", mqttHandler.HandleMQTT)
+	}
+
+	// First-boot setup
+	if setupManager != nil {
+		if setupManager.Required() {
+			log.Info().Msg("First-boot setup required: POST /api/v1/setup")
+		}
+		authStore.SetSetup(setupManager)
+		setupHandler := handlers.NewSetupHandler(setupManager, cfg.Observe.Grafana)
+		mux.HandleFunc("/api/v1/setup", setupHandler.HandleSetup)
+	}
+	if !authStore.Enforced() {
+		log.Warn().Msg("API is unauthenticated until setup completes or a key is issued at /api/v1/auth/keys")
+	}
+
+	// Clock skew diagnostics against downstream services
+	clockChecker := system.NewClockChecker()
+	clockChecker.AddSource("mysql", 0, mysqlClient.ServerTime)
+	clockChecker.AddSource("loki", time.Second, system.HTTPDateSource(cfg.Observe.LokiURL+"/ready"))
+	clockChecker.AddSource("tempo", time.Second, system.HTTPDateSource(cfg.Observe.TempoQueryURL+"/ready"))
+
+	// Docker Engine API (or Podman's) used for container stats and events
+	if endpoint, err := system.NewDockerEndpoint(cfg.Docker); err != nil {
+		log.Warn().Err(err).Msg("Invalid Docker endpoint, container features unavailable")
+	} else {
+		log.Info().Str("endpoint", endpoint.String()).Msg("Docker endpoint")
 	}
 
 	// System information (container stats, resources)
-	systemHandler := handlers.NewSystemHandler()
+	systemHandler := handlers.NewSystemHandler(system.NewDockerClient(cfg.Docker), clockChecker)
+	if routeProber != nil {
+		systemHandler.SetRouteAvailability(routeProber.Availability)
+	}
+
+	// Other containers to report next to the stack, e.g. the user's own apps
+	containerFilter, err := system.ParseContainerFilter(cfg.System.Containers, cfg.System.ContainerLabels)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid container selection, reporting forge containers only")
+	} else {
+		systemHandler.SetContainerFilter(containerFilter)
+	}
+
+	// Host CPU, memory, load, disks and network, read from the host's /proc
+	hostCollector := system.NewHostCollector(cfg.System.HostProc, cfg.System.HostRoot)
+	go hostCollector.Run(context.Background(), 15*time.Second)
+	systemHandler.SetHostStats(hostCollector.Latest)
+
+	// NVIDIA GPUs, from a DCGM exporter or nvidia-smi when either is present
+	if gpuCollector := system.GPUCollectorFromConfig(cfg.System); gpuCollector != nil {
+		go gpuCollector.Run(context.Background(), 15*time.Second)
+		systemHandler.SetGPUStats(gpuCollector.Latest)
+	}
+
+	// Usage history (host and container samples -> MySQL) and trends
+	var usageHistory atomic.Pointer[history.Store]
+	systemHandler.SetUsageTrends(func() []system.UsageTrend {
+		if historyStore := usageHistory.Load(); historyStore != nil {
+			return historyStore.Latest()
+		}
+		return nil
+	})
+	mysqlSupervisor.OnConnect(func() {
+		historyStore, err := history.NewStore(mysqlClient.DB(), cfg.MySQL.Database, system.NewDockerClient(cfg.Docker), containerFilter, hostCollector.Latest)
+		if err != nil {
+			log.Warn().Err(err).Msg("Usage history init failed")
+		} else {
+			go historyStore.Run(context.Background())
+			usageHistory.Store(historyStore)
+			historyHandler := handlers.NewHistoryHandler(historyStore)
+			mux.HandleFunc("/api/v1/system/history", historyHandler.HandleHistory)
+			mux.HandleFunc("/api/v1/system/history/", historyHandler.HandleHistory)
+		}
+	})
 	mux.HandleFunc("/api/v1/system", systemHandler.GetSystemInfo)
+	mux.HandleFunc("/api/v1/system/stream", systemHandler.StreamSystemInfo)
+	mux.HandleFunc("/api/v1/system/disk", systemHandler.HandleDisk)
+	mux.HandleFunc("/api/v1/system/disk/", systemHandler.HandleDisk)
+	mux.HandleFunc("/api/v1/system/prune", systemHandler.Prune)
+	systemHandler.SetOperations(operationsManager)
+	mux.HandleFunc("/api/v1/system/upgrade", systemHandler.Upgrade)
+
+	// Container start/stop/die/oom events, kept for the API and sent to Loki
+	// as {job="docker_events"} for alerting
+	eventWatcher := system.NewEventWatcher(system.NewDockerClient(cfg.Docker))
+	eventWatcher.OnEvent(func(e system.ContainerEvent) {
+		labels := map[string]string{
+			"job":       "docker_events",
+			"container": e.Container,
+			"event":     e.Action,
+		}
+		if e.Service != "" {
+			labels["service"] = e.Service
+		}
+		fields := map[string]any{"image": e.Image}
+		if e.ExitCode != nil {
+			fields["exit_code"] = *e.ExitCode
+		}
+		lokiClient.PushEntry(context.Background(), observe.Entry{
+			Level:     e.Level(),
+			Message:   e.Message(),
+			Labels:    labels,
+			Fields:    fields,
+			Timestamp: e.Time,
+		})
+	})
+	eventWatcher.OnEvent(func(e system.ContainerEvent) {
+		switch {
+		case e.Action == "unhealthy":
+			eventBus.Publish(events.ContainerUnhealthy, e)
+		case e.Action == "die" && e.ExitCode != nil && *e.ExitCode != 0:
+			eventBus.Publish(events.ContainerDied, e)
+		}
+	})
+	go eventWatcher.Run(context.Background())
+	systemHandler.SetEvents(eventWatcher)
+	mux.HandleFunc("/api/v1/system/events", systemHandler.GetEvents)
+
+	// Restarts exited or unhealthy containers per the stored policy
+	watchdog, err := system.NewWatchdog(system.NewDockerClient(cfg.Docker), eventWatcher, containerFilter, cfg.Paths.Watchdog)
+	if err != nil {
+		log.Warn().Err(err).Msg("Watchdog init failed")
+	} else {
+		watchdog.OnIncident(func(i system.Incident) { eventBus.Publish(events.WatchdogIncident, i) })
+		go watchdog.Run(context.Background())
+		systemHandler.SetWatchdog(watchdog)
+	}
+	mux.HandleFunc("/api/v1/system/watchdog", systemHandler.HandleWatchdog)
+
+	// Container memory and CPU limits, reapplied when containers start
+	limits, err := system.NewLimitManager(system.NewDockerClient(cfg.Docker), eventWatcher, containerFilter, cfg.Paths.Limits)
+	if err != nil {
+		log.Warn().Err(err).Msg("Resource limits init failed")
+	} else {
+		go limits.Run(context.Background())
+		systemHandler.SetLimits(limits)
+	}
+	mux.HandleFunc("/api/v1/system/limits", systemHandler.HandleLimits)
+	mux.HandleFunc("/api/v1/system/limits/", systemHandler.HandleLimits)
+
+	// Docker images (list, pull, remove, registry update check)
+	imagesHandler := handlers.NewImagesHandler(system.NewDockerClient(cfg.Docker))
+	mux.HandleFunc("/api/v1/images", imagesHandler.HandleImages)
+	mux.HandleFunc("/api/v1/images/", imagesHandler.HandleImages)
+
+	// App containers deployed from images or catalog templates, with
+	// optional route and log source
+	appsManager := apps.NewManager(system.NewDockerClient(cfg.Docker), routesManager, logSourcesManager, cfg.Apps.Network, cfg.Apps.VolumeRoot)
+	appTemplates := apps.NewCatalog(cfg.Paths.AppsTemplates)
+	appsHandler := handlers.NewAppsHandler(appsManager, appTemplates, operationsManager)
+	mux.HandleFunc("/api/v1/apps", appsHandler.HandleApps)
+	mux.HandleFunc("/api/v1/apps/", appsHandler.HandleApps)
+	mux.HandleFunc("/api/v1/system/clock", systemHandler.GetClock)
+
+	// Cross-resource label search
+	if routesManager != nil {
+		resourceIndex.Register("route", func() []resources.Resource {
+			var list []resources.Resource
+			for _, r := range routesManager.List() {
+				list = append(list, resources.Resource{Kind: "route", Name: r.Name, Labels: r.Labels})
+			}
+			return list
+		})
+	}
+	if logSourcesManager != nil {
+		resourceIndex.Register("logsource", func() []resources.Resource {
+			var list []resources.Resource
+			for _, s := range logSourcesManager.List() {
+				list = append(list, resources.Resource{Kind: "logsource", Name: s.Name, Labels: s.Labels})
+			}
+			return list
+		})
+	}
+	resourceIndex.Register("secret", func() []resources.Resource {
+		names, _ := secretsStore.Names()
+		labels := secretsStore.Labels()
+		list := make([]resources.Resource, 0, len(names))
+		for _, name := range names {
+			list = append(list, resources.Resource{Kind: "secret", Name: name, Labels: labels[name]})
+		}
+		return list
+	})
+	mux.HandleFunc("/api/v1/resources", handlers.ResourcesREST(resourceIndex))
+
+	// Deprecation report
+	mux.HandleFunc("/api/v1/deprecations", handlers.DeprecationsREST(deprecations))
 
 	// Swagger docs
 	mux.HandleFunc("/docs", handlers.SwaggerUI)
 	mux.HandleFunc("/docs/", handlers.SwaggerUI)
 	mux.HandleFunc("/openapi.json", handlers.OpenAPISpec)
 
-	// Apply metrics middleware
-	metricsHandler := middleware.Metrics(mux)
+	// Replays of POSTs retried with an Idempotency-Key, kept in Redis when
+	// available
+	idempotencyStore := idempotency.FromConfig(cfg.Idempotency, redisClient, redisSupervisor.Up)
+	if idempotencyStore != nil {
+		version.Enable("idempotency")
+		log.Info().Dur("ttl", idempotencyStore.TTL()).Msg("Idempotency keys enabled")
+	}
+	// Proxies whose X-Forwarded-For hops are believed when telling clients
+	// apart for rate limits, lockouts and idempotency scopes
+	proxies, err := middleware.TrustedProxies(cfg.Auth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Trusted proxies config invalid")
+	}
+
+	// Administrative endpoints only from allowed networks, whatever the key
+	allowlist, err := middleware.NewIPAllowlist(cfg.Auth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Admin allowlist config invalid")
+	} else if allowlist != nil {
+		log.Info().Str("allowed", allowlist.String()).Msg("Administrative endpoints restricted by client address")
+	} else {
+		log.Warn().Msg("ADMIN_ALLOWED_CIDRS=off: administrative endpoints are reachable from any address")
+	}
+
+	// Audit log of mutating requests
+	auditLog, err := audit.NewLog(cfg.Paths.AuditLog, 0)
+	if err != nil {
+		log.Warn().Err(err).Msg("Audit log init failed, writes are not audited")
+		auditLog = nil
+	} else {
+		mux.HandleFunc("/api/v1/audit", handlers.NewAuditHandler(auditLog).HandleAudit)
+		version.Enable("audit_log")
+	}
 
-	// CORS middleware
-	corsHandler := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: true,
-	}).Handler(metricsHandler)
+	// apiStack wraps the API for a listener: authentication, and rate limits
+	// once the caller is known (not on admin listeners), limited to what the
+	// listener serves
+	apiStack := func(serves string) http.Handler {
+		var apiHandler http.Handler = middleware.Deprecation(deprecations, mux)
+		if idempotencyStore != nil {
+			apiHandler = middleware.Idempotency(idempotencyStore, apiHandler)
+		}
+		if limiter != nil && serves != config.ServeAdmin {
+			apiHandler = middleware.RateLimit(limiter, apiHandler)
+		}
+		apiHandler = middleware.Auth(authStore, apiHandler)
+		apiHandler = middleware.ClientAddr(proxies, apiHandler)
+		if authGuard != nil {
+			apiHandler = middleware.AuthGuard(authGuard, proxies, apiHandler)
+		}
+		if allowlist != nil {
+			apiHandler = middleware.AdminAllowlist(allowlist, apiHandler)
+		}
+		apiHandler = middleware.ListenerScope(serves, apiHandler)
+		if auditLog != nil {
+			apiHandler = middleware.Audit(auditLog, apiHandler)
+		}
 
-	// HTTP/2 support for Connect
-	handler := h2c.NewHandler(corsHandler, &http2.Server{})
+		// Apply metrics middleware; panics are recovered inside it so they
+		// are logged with the request ID and counted as 500s. Body size
+		// limits and write deadlines apply to every route, Connect included.
+		metricsHandler := middleware.Tracing(middleware.Correlation(middleware.Metrics(middleware.Recover(middleware.Limits(apiHandler)))))
+
+		// Response compression by route prefix, and security headers
+		metricsHandler = middleware.SecurityHeaders(cfg.Server.HSTSMaxAge, middleware.Compress(cfg.Server.CompressPrefixes, metricsHandler))
+
+		// CORS middleware
+		return cors.New(cors.Options{
+			AllowedOrigins:   []string{"*"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"*"},
+			ExposedHeaders:   []string{middleware.RequestIDHeader, "traceparent"},
+			AllowCredentials: true,
+		}).Handler(metricsHandler)
+	}
+	corsHandler := apiStack(cfg.Server.PortServes)
+
+	// Plaintext listener with HTTP/2 (h2c) for Connect, used on the
+	// internal network
+	plainHandler := corsHandler
+
+	// HTTPS listener, serving HTTP/2 through ALPN
+	var servers []*http.Server
+	tlsConfig := server.NewTLSConfig(cfg.TLS, externalHost())
+	if tlsConfig != nil {
+		version.Enable("tls")
+		tlsPort := strconv.Itoa(cfg.Server.TLSPort)
+		httpsServer := server.New(":"+tlsPort, corsHandler)
+		if httpsServer.TLSConfig, err = tlsConfig.Load(); err != nil {
+			log.Fatal().Err(err).Msg("TLS certificate unavailable")
+		}
+		servers = append(servers, httpsServer)
+		if cfg.Server.TLSRedirect {
+			plainHandler = server.Redirect(strconv.Itoa(cfg.Server.RedirectPort()), plainHandler)
+		}
+		log.Info().
+			Str("port", tlsPort).
+			Str("cert", tlsConfig.CertFile).
+			Bool("self_signed", tlsConfig.SelfSigned).
+			Bool("client_certs_required", tlsConfig.RequireClientCert).
+			Str("rest", "https://localhost:"+tlsPort+"/api/v1/").
+			Msg("Forge API serving HTTPS")
+	}
+	servers = append(servers, server.New(":"+port, h2c.NewHandler(plainHandler, &http2.Server{})))
+
+	// Further listeners, e.g. an admin port or a Unix socket for nginx
+	server.SocketMode, _ = cfg.Server.SocketMode()
+	for _, l := range cfg.Server.Listeners {
+		servers = append(servers, server.New(l.Addr, h2c.NewHandler(apiStack(l.Serves), &http2.Server{})))
+		log.Info().Str("addr", l.Addr).Str("serves", l.Serves).Msg("Forge API listening")
+	}
 
 	log.Info().
 		Str("port", port).
+		Str("serves", cfg.Server.PortServes).
 		Str("rest", "http://localhost:"+port+"/api/v1/").
 		Str("metrics", "http://localhost:"+port+"/metrics").
 		Str("docs", "http://localhost:"+port+"/docs").
 		Msg("Forge API starting")
 
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
+	// Serve until SIGINT/SIGTERM, then let in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Run(ctx, servers...); err != nil {
 		log.Fatal().Err(err).Msg("Server failed to start")
 	}
-}
 
-func getEnv(key, fallback string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+	// Push the logs still queued, including those of the last requests
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFlush()
+	if err := lokiClient.Close(flushCtx); err != nil {
+		log.Warn().Err(err).Msg("Loki flush did not finish; queued logs were dropped")
 	}
-	return fallback
+	log.Info().Msg("Forge API stopped")
 }
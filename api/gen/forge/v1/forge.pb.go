@@ -64,16 +64,22 @@ type ExecuteResponse struct {
 
 // GetInfoRequest is the request for GetInfo RPC
 type GetInfoRequest struct {
-	Type string `json:"type"`
+	Type       string `json:"type"`
+	Database   string `json:"database"`
+	Access     string `json:"access"`
+	TtlSeconds int64  `json:"ttl_seconds"`
 }
 
 // GetInfoResponse is the response for GetInfo RPC
 type GetInfoResponse struct {
-	Host     string `json:"host"`
-	Port     int32  `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	Url      string `json:"url"`
+	Host      string `json:"host"`
+	Port      int32  `json:"port"`
+	User      string `json:"user"`
+	Password  string `json:"password"`
+	Url       string `json:"url"`
+	Database  string `json:"database"`
+	Access    string `json:"access"`
+	ExpiresAt int64  `json:"expires_at"`
 }
 
 // GetRequest is the request for cache Get RPC
@@ -114,15 +120,22 @@ type DeleteResponse struct {
 
 // CacheInfoRequest is the request for cache GetInfo RPC
 type CacheInfoRequest struct {
-	Type string `json:"type"`
+	Type       string `json:"type"`
+	KeyPrefix  string `json:"key_prefix"`
+	Access     string `json:"access"`
+	TtlSeconds int64  `json:"ttl_seconds"`
 }
 
 // CacheInfoResponse is the response for cache GetInfo RPC
 type CacheInfoResponse struct {
-	Host     string `json:"host"`
-	Port     int32  `json:"port"`
-	Password string `json:"password"`
-	Url      string `json:"url"`
+	Host      string `json:"host"`
+	Port      int32  `json:"port"`
+	User      string `json:"user"`
+	Password  string `json:"password"`
+	Url       string `json:"url"`
+	KeyPrefix string `json:"key_prefix"`
+	Access    string `json:"access"`
+	ExpiresAt int64  `json:"expires_at"`
 }
 
 // LogRequest is the request for Log RPC
@@ -131,6 +144,8 @@ type LogRequest struct {
 	Level       string            `json:"level"`
 	Labels      map[string]string `json:"labels"`
 	TimestampMs int64             `json:"timestamp_ms"`
+	Fields      map[string]any    `json:"fields,omitempty"`
+	Metadata    []string          `json:"metadata,omitempty"`
 }
 
 // LogResponse is the response for Log RPC
@@ -142,8 +157,9 @@ type LogResponse struct {
 type MetricRequest struct {
 	Name   string            `json:"name"`
 	Value  float64           `json:"value"`
-	Type   string            `json:"type"`
-	Labels map[string]string `json:"labels"`
+	Type    string            `json:"type"`
+	Labels  map[string]string `json:"labels"`
+	Buckets []float64         `json:"buckets,omitempty"`
 }
 
 // MetricResponse is the response for Metric RPC
@@ -151,6 +167,14 @@ type MetricResponse struct {
 	Ok bool `json:"ok"`
 }
 
+// TimingRequest is the request for Timing RPC
+type TimingRequest struct {
+	Name       string            `json:"name"`
+	DurationMs float64           `json:"duration_ms"`
+	Labels     map[string]string `json:"labels"`
+	Buckets    []float64         `json:"buckets,omitempty"`
+}
+
 // TraceRequest is the request for Trace RPC
 type TraceRequest struct {
 	Name         string            `json:"name"`
@@ -169,3 +193,250 @@ type TraceResponse struct {
 	SpanId  string `json:"span_id"`
 }
 
+
+// LogQueryRequest is the request for log Query RPC
+type LogQueryRequest struct {
+	Query     string `json:"query"`
+	StartMs   int64  `json:"start_ms"`
+	EndMs     int64  `json:"end_ms"`
+	Limit     int32  `json:"limit"`
+	Direction string `json:"direction"`
+	Instant   bool   `json:"instant"`
+}
+
+// LogEntry is a single log line returned by a query
+type LogEntry struct {
+	TimestampMs int64  `json:"timestamp_ms"`
+	Line        string `json:"line"`
+}
+
+// LogStream is a set of log entries sharing the same labels
+type LogStream struct {
+	Labels  map[string]string `json:"labels"`
+	Entries []*LogEntry       `json:"entries"`
+}
+
+// LogQueryResponse is the response for log Query RPC
+type LogQueryResponse struct {
+	Streams    []*LogStream `json:"streams"`
+	EntryCount int32        `json:"entry_count"`
+}
+
+// ErrorEvent is the request for errors Capture RPC
+type ErrorEvent struct {
+	Message     string            `json:"message"`
+	Type        string            `json:"type"`
+	Stacktrace  string            `json:"stacktrace"`
+	Release     string            `json:"release"`
+	Environment string            `json:"environment"`
+	Service     string            `json:"service"`
+	Level       string            `json:"level"`
+	User        map[string]string `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	TimestampMs int64             `json:"timestamp_ms"`
+}
+
+// CaptureErrorResponse is the response for errors Capture RPC
+type CaptureErrorResponse struct {
+	Ok          bool   `json:"ok"`
+	Fingerprint string `json:"fingerprint"`
+	NewGroup    bool   `json:"new_group"`
+}
+
+// EvaluateFlagsRequest is the request for flags Evaluate RPC
+type EvaluateFlagsRequest struct {
+	Key        string            `json:"key"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Flags      []string          `json:"flags,omitempty"`
+}
+
+// FlagEvaluation is the outcome of evaluating a flag
+type FlagEvaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant,omitempty"`
+	Reason  string `json:"reason"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// EvaluateFlagsResponse is the response for flags Evaluate RPC
+type EvaluateFlagsResponse struct {
+	Flags map[string]*FlagEvaluation `json:"flags"`
+}
+
+// BucketInfo describes a storage bucket
+type BucketInfo struct {
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// ListBucketsRequest is the request for storage ListBuckets RPC
+type ListBucketsRequest struct{}
+
+// ListBucketsResponse is the response for storage ListBuckets RPC
+type ListBucketsResponse struct {
+	Buckets []*BucketInfo `json:"buckets"`
+}
+
+// CreateBucketRequest is the request for storage CreateBucket RPC
+type CreateBucketRequest struct {
+	Bucket string `json:"bucket"`
+}
+
+// CreateBucketResponse is the response for storage CreateBucket RPC
+type CreateBucketResponse struct {
+	Ok bool `json:"ok"`
+}
+
+// DeleteBucketRequest is the request for storage DeleteBucket RPC
+type DeleteBucketRequest struct {
+	Bucket string `json:"bucket"`
+}
+
+// DeleteBucketResponse is the response for storage DeleteBucket RPC
+type DeleteBucketResponse struct {
+	Ok bool `json:"ok"`
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	Etag         string `json:"etag"`
+	ContentType  string `json:"content_type,omitempty"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// PutObjectRequest is the request for storage PutObject RPC
+type PutObjectRequest struct {
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	Data        []byte `json:"data"`
+	ContentType string `json:"content_type"`
+}
+
+// PutObjectResponse is the response for storage PutObject RPC
+type PutObjectResponse struct {
+	Bucket string      `json:"bucket"`
+	Object *ObjectInfo `json:"object"`
+}
+
+// GetObjectRequest is the request for storage GetObject RPC
+type GetObjectRequest struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// GetObjectResponse is the response for storage GetObject RPC
+type GetObjectResponse struct {
+	Object *ObjectInfo `json:"object"`
+	Data   []byte      `json:"data"`
+}
+
+// ListObjectsRequest is the request for storage ListObjects RPC
+type ListObjectsRequest struct {
+	Bucket            string `json:"bucket"`
+	Prefix            string `json:"prefix"`
+	Delimiter         string `json:"delimiter"`
+	MaxKeys           int32  `json:"max_keys"`
+	ContinuationToken string `json:"continuation_token"`
+}
+
+// ListObjectsResponse is the response for storage ListObjects RPC
+type ListObjectsResponse struct {
+	Objects               []*ObjectInfo `json:"objects"`
+	Prefixes              []string      `json:"prefixes"`
+	Truncated             bool          `json:"truncated"`
+	NextContinuationToken string        `json:"next_continuation_token"`
+}
+
+// DeleteObjectRequest is the request for storage DeleteObject RPC
+type DeleteObjectRequest struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// DeleteObjectResponse is the response for storage DeleteObject RPC
+type DeleteObjectResponse struct {
+	Ok bool `json:"ok"`
+}
+
+// PresignURLRequest is the request for storage PresignURL RPC
+type PresignURLRequest struct {
+	Bucket         string `json:"bucket"`
+	Key            string `json:"key"`
+	Method         string `json:"method"`
+	ExpiresSeconds int64  `json:"expires_seconds"`
+}
+
+// PresignURLResponse is the response for storage PresignURL RPC
+type PresignURLResponse struct {
+	Url       string `json:"url"`
+	Method    string `json:"method"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// StorageInfoRequest is the request for storage GetInfo RPC
+type StorageInfoRequest struct {
+	Type       string `json:"type"`
+	Bucket     string `json:"bucket"`
+	Prefix     string `json:"prefix"`
+	Access     string `json:"access"`
+	TtlSeconds int64  `json:"ttl_seconds"`
+}
+
+// StorageInfoResponse is the response for storage GetInfo RPC
+type StorageInfoResponse struct {
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	AccessKeyId     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix"`
+	PathStyle       bool   `json:"path_style"`
+	Access          string `json:"access"`
+	ExpiresAt       int64  `json:"expires_at"`
+}
+
+// PublishRequest is the request for messaging Publish RPC
+type PublishRequest struct {
+	Subject string `json:"subject"`
+	Data    []byte `json:"data"`
+	ReplyTo string `json:"reply_to"`
+}
+
+// PublishResponse is the response for messaging Publish RPC
+type PublishResponse struct {
+	Ok bool `json:"ok"`
+}
+
+// SubscribeRequest is the request for messaging Subscribe RPC
+type SubscribeRequest struct {
+	Subject string `json:"subject"`
+	Queue   string `json:"queue"`
+}
+
+// MessagingMessage is a message streamed by messaging Subscribe RPC
+type MessagingMessage struct {
+	Subject    string `json:"subject"`
+	Data       []byte `json:"data"`
+	ReplyTo    string `json:"reply_to,omitempty"`
+	ReceivedAt int64  `json:"received_at"`
+}
+
+// MessagingInfoRequest is the request for messaging GetInfo RPC
+type MessagingInfoRequest struct {
+	Access string `json:"access"`
+}
+
+// MessagingInfoResponse is the response for messaging GetInfo RPC
+type MessagingInfoResponse struct {
+	Url           string `json:"url"`
+	User          string `json:"user"`
+	Password      string `json:"password"`
+	Access        string `json:"access"`
+	MaxPayload    int64  `json:"max_payload"`
+	ServerVersion string `json:"server_version"`
+}
@@ -35,6 +35,38 @@ type ObserveServiceHandler interface {
 	Log(context.Context, *connect.Request[forgev1.LogRequest]) (*connect.Response[forgev1.LogResponse], error)
 	Metric(context.Context, *connect.Request[forgev1.MetricRequest]) (*connect.Response[forgev1.MetricResponse], error)
 	Trace(context.Context, *connect.Request[forgev1.TraceRequest]) (*connect.Response[forgev1.TraceResponse], error)
+	Query(context.Context, *connect.Request[forgev1.LogQueryRequest]) (*connect.Response[forgev1.LogQueryResponse], error)
+	Timing(context.Context, *connect.Request[forgev1.TimingRequest]) (*connect.Response[forgev1.MetricResponse], error)
+}
+
+// ErrorsServiceHandler is the interface for ErrorsService
+type ErrorsServiceHandler interface {
+	Capture(context.Context, *connect.Request[forgev1.ErrorEvent]) (*connect.Response[forgev1.CaptureErrorResponse], error)
+}
+
+// FlagsServiceHandler is the interface for FlagsService
+type FlagsServiceHandler interface {
+	Evaluate(context.Context, *connect.Request[forgev1.EvaluateFlagsRequest]) (*connect.Response[forgev1.EvaluateFlagsResponse], error)
+}
+
+// StorageServiceHandler is the interface for StorageService
+type StorageServiceHandler interface {
+	ListBuckets(context.Context, *connect.Request[forgev1.ListBucketsRequest]) (*connect.Response[forgev1.ListBucketsResponse], error)
+	CreateBucket(context.Context, *connect.Request[forgev1.CreateBucketRequest]) (*connect.Response[forgev1.CreateBucketResponse], error)
+	DeleteBucket(context.Context, *connect.Request[forgev1.DeleteBucketRequest]) (*connect.Response[forgev1.DeleteBucketResponse], error)
+	PutObject(context.Context, *connect.Request[forgev1.PutObjectRequest]) (*connect.Response[forgev1.PutObjectResponse], error)
+	GetObject(context.Context, *connect.Request[forgev1.GetObjectRequest]) (*connect.Response[forgev1.GetObjectResponse], error)
+	ListObjects(context.Context, *connect.Request[forgev1.ListObjectsRequest]) (*connect.Response[forgev1.ListObjectsResponse], error)
+	DeleteObject(context.Context, *connect.Request[forgev1.DeleteObjectRequest]) (*connect.Response[forgev1.DeleteObjectResponse], error)
+	PresignURL(context.Context, *connect.Request[forgev1.PresignURLRequest]) (*connect.Response[forgev1.PresignURLResponse], error)
+	GetInfo(context.Context, *connect.Request[forgev1.StorageInfoRequest]) (*connect.Response[forgev1.StorageInfoResponse], error)
+}
+
+// MessagingServiceHandler is the interface for MessagingService
+type MessagingServiceHandler interface {
+	Publish(context.Context, *connect.Request[forgev1.PublishRequest]) (*connect.Response[forgev1.PublishResponse], error)
+	Subscribe(context.Context, *connect.Request[forgev1.SubscribeRequest], *connect.ServerStream[forgev1.MessagingMessage]) error
+	GetInfo(context.Context, *connect.Request[forgev1.MessagingInfoRequest]) (*connect.Response[forgev1.MessagingInfoResponse], error)
 }
 
 // NewForgeServiceHandler creates HTTP handlers for ForgeService
@@ -125,7 +157,118 @@ func NewObserveServiceHandler(svc ObserveServiceHandler, opts ...connect.Handler
 		svc.Trace,
 		opts...,
 	))
+	mux.Handle("/forge.v1.ObserveService/Query", connect.NewUnaryHandler(
+		"/forge.v1.ObserveService/Query",
+		svc.Query,
+		opts...,
+	))
+	mux.Handle("/forge.v1.ObserveService/Timing", connect.NewUnaryHandler(
+		"/forge.v1.ObserveService/Timing",
+		svc.Timing,
+		opts...,
+	))
 	
 	return "/forge.v1.ObserveService/", mux
 }
 
+// NewErrorsServiceHandler creates HTTP handlers for ErrorsService
+func NewErrorsServiceHandler(svc ErrorsServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	mux := http.NewServeMux()
+	
+	mux.Handle("/forge.v1.ErrorsService/Capture", connect.NewUnaryHandler(
+		"/forge.v1.ErrorsService/Capture",
+		svc.Capture,
+		opts...,
+	))
+	
+	return "/forge.v1.ErrorsService/", mux
+}
+
+// NewFlagsServiceHandler creates HTTP handlers for FlagsService
+func NewFlagsServiceHandler(svc FlagsServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	mux := http.NewServeMux()
+	
+	mux.Handle("/forge.v1.FlagsService/Evaluate", connect.NewUnaryHandler(
+		"/forge.v1.FlagsService/Evaluate",
+		svc.Evaluate,
+		opts...,
+	))
+	
+	return "/forge.v1.FlagsService/", mux
+}
+
+// NewStorageServiceHandler creates HTTP handlers for StorageService
+func NewStorageServiceHandler(svc StorageServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	mux := http.NewServeMux()
+	
+	mux.Handle("/forge.v1.StorageService/ListBuckets", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/ListBuckets",
+		svc.ListBuckets,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/CreateBucket", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/CreateBucket",
+		svc.CreateBucket,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/DeleteBucket", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/DeleteBucket",
+		svc.DeleteBucket,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/PutObject", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/PutObject",
+		svc.PutObject,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/GetObject", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/GetObject",
+		svc.GetObject,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/ListObjects", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/ListObjects",
+		svc.ListObjects,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/DeleteObject", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/DeleteObject",
+		svc.DeleteObject,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/PresignURL", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/PresignURL",
+		svc.PresignURL,
+		opts...,
+	))
+	mux.Handle("/forge.v1.StorageService/GetInfo", connect.NewUnaryHandler(
+		"/forge.v1.StorageService/GetInfo",
+		svc.GetInfo,
+		opts...,
+	))
+	
+	return "/forge.v1.StorageService/", mux
+}
+
+// NewMessagingServiceHandler creates HTTP handlers for MessagingService
+func NewMessagingServiceHandler(svc MessagingServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	mux := http.NewServeMux()
+	
+	mux.Handle("/forge.v1.MessagingService/Publish", connect.NewUnaryHandler(
+		"/forge.v1.MessagingService/Publish",
+		svc.Publish,
+		opts...,
+	))
+	mux.Handle("/forge.v1.MessagingService/Subscribe", connect.NewServerStreamHandler(
+		"/forge.v1.MessagingService/Subscribe",
+		svc.Subscribe,
+		opts...,
+	))
+	mux.Handle("/forge.v1.MessagingService/GetInfo", connect.NewUnaryHandler(
+		"/forge.v1.MessagingService/GetInfo",
+		svc.GetInfo,
+		opts...,
+	))
+	
+	return "/forge.v1.MessagingService/", mux
+}
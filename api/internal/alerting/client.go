@@ -0,0 +1,254 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultResolveTimeout is how long Alertmanager keeps an alert posted
+// without EndsAt firing
+const defaultResolveTimeout = 5 * time.Minute
+
+// Client talks to the Alertmanager v2 API
+type Client struct {
+	url    string
+	client *http.Client
+
+	mu       sync.Mutex
+	active   map[string]time.Time // alerts posted through the client, by labels, until they end
+	handlers []func(PostableAlert, bool)
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		active: make(map[string]time.Time),
+	}
+}
+
+// Alert is a firing or pending alert as reported by Alertmanager
+type Alert struct {
+	Fingerprint  string            `json:"fingerprint"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Status       struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+	Receivers []struct {
+		Name string `json:"name"`
+	} `json:"receivers"`
+}
+
+// Matcher is a silence label matcher
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+// Silence suppresses notifications for matching alerts
+type Silence struct {
+	ID        string    `json:"id,omitempty"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	Status    *struct {
+		State string `json:"state"`
+	} `json:"status,omitempty"`
+}
+
+// Alerts returns current alerts; filter is an optional list of matchers like `severity="critical"`
+func (c *Client) Alerts(ctx context.Context, filter []string, includeSilenced bool) ([]Alert, error) {
+	params := url.Values{}
+	for _, f := range filter {
+		params.Add("filter", f)
+	}
+	params.Set("silenced", fmt.Sprint(includeSilenced))
+	params.Set("inhibited", fmt.Sprint(includeSilenced))
+
+	var alerts []Alert
+	if err := c.do(ctx, "GET", "/api/v2/alerts?"+params.Encode(), nil, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// PostableAlert is an alert sent to Alertmanager. An alert whose EndsAt
+// is in the past is resolved.
+type PostableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Message describes the alert: its name and severity, then its summary
+// or description
+func (a PostableAlert) Message() string {
+	msg := a.Labels["alertname"]
+	if severity := a.Labels["severity"]; severity != "" {
+		msg += " (" + severity + ")"
+	}
+	text := a.Annotations["summary"]
+	if text == "" {
+		text = a.Annotations["description"]
+	}
+	if text != "" {
+		msg += ": " + text
+	}
+	return msg
+}
+
+// PostAlerts sends (or refreshes) alerts
+func (c *Client) PostAlerts(ctx context.Context, alerts []PostableAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	if err := c.do(ctx, "POST", "/api/v2/alerts", alerts, nil); err != nil {
+		return err
+	}
+	c.track(alerts)
+	return nil
+}
+
+// OnAlert calls fn when an alert posted through the client starts firing
+// (resolved false) or resolves (resolved true). Refreshes of a firing
+// alert are not reported. It must be called before alerts are posted.
+func (c *Client) OnAlert(fn func(alert PostableAlert, resolved bool)) {
+	c.handlers = append(c.handlers, fn)
+}
+
+// track follows which posted alerts are firing and reports transitions
+func (c *Client) track(alerts []PostableAlert) {
+	if len(c.handlers) == 0 {
+		return
+	}
+	now := time.Now()
+	type transition struct {
+		alert    PostableAlert
+		resolved bool
+	}
+	var changes []transition
+
+	c.mu.Lock()
+	for key, ends := range c.active {
+		if now.After(ends) {
+			delete(c.active, key)
+		}
+	}
+	for _, a := range alerts {
+		key := labelsKey(a.Labels)
+		_, firing := c.active[key]
+		if !a.EndsAt.IsZero() && !a.EndsAt.After(now) {
+			if firing {
+				delete(c.active, key)
+				changes = append(changes, transition{a, true})
+			}
+			continue
+		}
+		ends := a.EndsAt
+		if ends.IsZero() {
+			ends = now.Add(defaultResolveTimeout)
+		}
+		c.active[key] = ends
+		if !firing {
+			changes = append(changes, transition{a, false})
+		}
+	}
+	c.mu.Unlock()
+
+	for _, t := range changes {
+		for _, fn := range c.handlers {
+			fn(t.alert, t.resolved)
+		}
+	}
+}
+
+// Silences returns all silences
+func (c *Client) Silences(ctx context.Context) ([]Silence, error) {
+	var silences []Silence
+	if err := c.do(ctx, "GET", "/api/v2/silences", nil, &silences); err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+// CreateSilence creates a silence and returns its ID
+func (c *Client) CreateSilence(ctx context.Context, s Silence) (string, error) {
+	if len(s.Matchers) == 0 {
+		return "", fmt.Errorf("at least one matcher is required")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return "", fmt.Errorf("endsAt must be after startsAt")
+	}
+
+	var resp struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := c.do(ctx, "POST", "/api/v2/silences", s, &resp); err != nil {
+		return "", err
+	}
+	return resp.SilenceID, nil
+}
+
+// DeleteSilence expires a silence
+func (c *Client) DeleteSilence(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v2/silence/"+url.PathEscape(id), nil, nil)
+}
+
+// Reload tells Alertmanager to re-read its config file
+func (c *Client) Reload(ctx context.Context) error {
+	return c.do(ctx, "POST", "/-/reload", nil, nil)
+}
+
+// do performs a JSON request against Alertmanager
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("alertmanager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("alertmanager %s %s failed: %d %s", method, path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,465 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/observe"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MinRuleInterval is the shortest allowed evaluation interval
+	MinRuleInterval = 15 * time.Second
+	// DefaultRuleInterval is used when a rule does not set one
+	DefaultRuleInterval = time.Minute
+
+	ruleSchedulerTick = 5 * time.Second
+	// alertSourceLabel marks alerts raised by Forge log rules
+	alertSourceLabel = "forge_log_rule"
+)
+
+// Rule states
+const (
+	RuleInactive = "inactive"
+	RulePending  = "pending"
+	RuleFiring   = "firing"
+)
+
+// LogRule fires when a LogQL metric expression crosses a threshold
+type LogRule struct {
+	Name      string  `json:"name" yaml:"name"`
+	Expr      string  `json:"expr" yaml:"expr"` // e.g. sum(rate({app="shop"} |= "error" [5m]))
+	Op        string  `json:"op" yaml:"op"`     // >, >=, <, <=, ==, != (default >)
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	For       string  `json:"for,omitempty" yaml:"for,omitempty"`           // how long the condition must hold
+	Interval  string  `json:"interval,omitempty" yaml:"interval,omitempty"` // evaluation interval, default 1m
+	Severity  string  `json:"severity,omitempty" yaml:"severity,omitempty"` // default "warning"
+
+	Summary     string            `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"` // added to fired alerts
+}
+
+// RuleStatus is a rule with its current evaluation state
+type RuleStatus struct {
+	LogRule
+	State          string    `json:"state"`
+	ActiveSeries   int       `json:"active_series"`
+	LastEvaluation time.Time `json:"last_evaluation,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// seriesState tracks one result series of a rule
+type seriesState struct {
+	labels      map[string]string
+	value       float64
+	activeSince time.Time
+	firing      bool
+	alertLabels map[string]string // labels of the alert sent to Alertmanager
+}
+
+type ruleState struct {
+	lastEval time.Time
+	lastErr  string
+	series   map[string]*seriesState
+}
+
+type logRulesFile struct {
+	Rules []LogRule `yaml:"rules"`
+}
+
+// RuleManager stores log rules and evaluates them against Loki on a
+// schedule, sending firing and resolved alerts to Alertmanager
+type RuleManager struct {
+	mu         sync.RWMutex
+	rules      map[string]LogRule
+	state      map[string]*ruleState
+	configPath string
+	loki       *observe.LokiClient
+	client     *Client
+}
+
+// NewRuleManager loads log rules from configPath
+func NewRuleManager(configPath string, loki *observe.LokiClient, client *Client) (*RuleManager, error) {
+	m := &RuleManager{
+		rules:      make(map[string]LogRule),
+		state:      make(map[string]*ruleState),
+		configPath: configPath,
+		loki:       loki,
+		client:     client,
+	}
+
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all rules with their state, sorted by name
+func (m *RuleManager) List() []RuleStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]RuleStatus, 0, len(m.rules))
+	for name := range m.rules {
+		list = append(list, m.status(name))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a rule with its state
+func (m *RuleManager) Get(name string) (RuleStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.rules[name]; !ok {
+		return RuleStatus{}, false
+	}
+	return m.status(name), true
+}
+
+// status builds a RuleStatus; m.mu must be held
+func (m *RuleManager) status(name string) RuleStatus {
+	st := RuleStatus{LogRule: m.rules[name], State: RuleInactive}
+	if rs, ok := m.state[name]; ok {
+		st.LastEvaluation = rs.lastEval
+		st.LastError = rs.lastErr
+		st.ActiveSeries = len(rs.series)
+		for _, s := range rs.series {
+			if s.firing {
+				st.State = RuleFiring
+				break
+			}
+			st.State = RulePending
+		}
+	}
+	return st
+}
+
+// Add creates or replaces a rule. Replacing a rule resets its state.
+func (m *RuleManager) Add(r LogRule) error {
+	if err := validateRule(&r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.rules[r.Name] = r
+	old := m.state[r.Name]
+	delete(m.state, r.Name)
+	m.mu.Unlock()
+
+	m.resolve(old)
+	return m.save()
+}
+
+// Remove deletes a rule and resolves its firing alerts
+func (m *RuleManager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.rules[name]; !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("log rule not found: %s", name)
+	}
+	delete(m.rules, name)
+	old := m.state[name]
+	delete(m.state, name)
+	m.mu.Unlock()
+
+	m.resolve(old)
+	return m.save()
+}
+
+// Run evaluates due rules until ctx is cancelled
+func (m *RuleManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(ruleSchedulerTick)
+	defer ticker.Stop()
+
+	for {
+		m.runDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue evaluates every rule whose interval has elapsed
+func (m *RuleManager) runDue(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	var due []LogRule
+	for name, r := range m.rules {
+		rs, ok := m.state[name]
+		if !ok {
+			rs = &ruleState{series: make(map[string]*seriesState)}
+			m.state[name] = rs
+		}
+		interval, _ := parseRuleDuration(r.Interval, DefaultRuleInterval)
+		if now.Sub(rs.lastEval) >= interval {
+			rs.lastEval = now
+			due = append(due, r)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, r := range due {
+		m.evaluate(ctx, r, now)
+	}
+}
+
+// evaluate runs one rule and notifies Alertmanager of firing/resolved series
+func (m *RuleManager) evaluate(ctx context.Context, r LogRule, now time.Time) {
+	result, err := m.loki.QueryMetric(ctx, r.Expr, now)
+
+	m.mu.Lock()
+	rs, ok := m.state[r.Name]
+	if !ok {
+		// Rule was removed or replaced during evaluation
+		m.mu.Unlock()
+		return
+	}
+	if err != nil {
+		rs.lastErr = err.Error()
+		m.mu.Unlock()
+		logger.Error("Log rule evaluation failed: "+r.Name, err)
+		return
+	}
+	rs.lastErr = ""
+
+	interval, _ := parseRuleDuration(r.Interval, DefaultRuleInterval)
+	holdFor, _ := parseRuleDuration(r.For, 0)
+
+	var alerts []PostableAlert
+	seen := make(map[string]bool)
+	for _, series := range result {
+		if len(series.Samples) == 0 {
+			continue
+		}
+		value := series.Samples[0].Value
+		if !compare(value, r.Op, r.Threshold) {
+			continue
+		}
+
+		key := labelsKey(series.Labels)
+		seen[key] = true
+		s, ok := rs.series[key]
+		if !ok {
+			s = &seriesState{labels: series.Labels, activeSince: now}
+			rs.series[key] = s
+		}
+		s.value = value
+		if now.Sub(s.activeSince) >= holdFor {
+			s.firing = true
+			// Alertmanager resolves the alert itself if we stop refreshing it
+			alerts = append(alerts, m.alertFor(r, s, now.Add(3*interval)))
+		}
+	}
+
+	for key, s := range rs.series {
+		if seen[key] {
+			continue
+		}
+		if s.firing {
+			alerts = append(alerts, m.alertFor(r, s, now))
+		}
+		delete(rs.series, key)
+	}
+	m.mu.Unlock()
+
+	if err := m.client.PostAlerts(ctx, alerts); err != nil {
+		logger.Error("Failed to send log rule alerts: "+r.Name, err)
+	}
+}
+
+// resolve ends all firing alerts of a removed or replaced rule
+func (m *RuleManager) resolve(rs *ruleState) {
+	if rs == nil {
+		return
+	}
+
+	now := time.Now()
+	var alerts []PostableAlert
+	for _, s := range rs.series {
+		if s.firing {
+			alerts = append(alerts, PostableAlert{Labels: s.alertLabels, StartsAt: s.activeSince, EndsAt: now})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.client.PostAlerts(ctx, alerts); err != nil {
+		logger.Error("Failed to resolve log rule alerts", err)
+	}
+}
+
+// alertFor builds the Alertmanager alert for a series; m.mu must be held
+func (m *RuleManager) alertFor(r LogRule, s *seriesState, endsAt time.Time) PostableAlert {
+	if s.alertLabels == nil {
+		labels := make(map[string]string, len(s.labels)+len(r.Labels)+3)
+		for k, v := range s.labels {
+			labels[k] = v
+		}
+		for k, v := range r.Labels {
+			labels[k] = v
+		}
+		labels["alertname"] = r.Name
+		labels["severity"] = r.Severity
+		labels[alertSourceLabel] = "true"
+		s.alertLabels = labels
+	}
+
+	annotations := map[string]string{
+		"value": fmt.Sprintf("%g", s.value),
+		"expr":  r.Expr,
+	}
+	if r.Summary != "" {
+		annotations["summary"] = r.Summary
+	}
+	if r.Description != "" {
+		annotations["description"] = r.Description
+	}
+
+	return PostableAlert{
+		Labels:      s.alertLabels,
+		Annotations: annotations,
+		StartsAt:    s.activeSince,
+		EndsAt:      endsAt,
+	}
+}
+
+var ruleOps = map[string]bool{">": true, ">=": true, "<": true, "<=": true, "==": true, "!=": true}
+
+// compare applies a rule operator
+func compare(value float64, op string, threshold float64) bool {
+	if math.IsNaN(value) {
+		return false
+	}
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// labelsKey returns a canonical string for a label set
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	key := ""
+	for _, k := range keys {
+		key += k + "=" + labels[k] + "\xff"
+	}
+	return key
+}
+
+// validateRule checks a rule and fills in defaults
+func validateRule(r *LogRule) error {
+	if !receiverNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("name must be 1-64 characters of letters, digits, '_' or '-'")
+	}
+	if r.Expr == "" {
+		return fmt.Errorf("expr is required")
+	}
+	if r.Op == "" {
+		r.Op = ">"
+	}
+	if !ruleOps[r.Op] {
+		return fmt.Errorf("invalid op: %q (must be >, >=, <, <=, == or !=)", r.Op)
+	}
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) {
+		return fmt.Errorf("threshold must be finite")
+	}
+	if _, err := parseRuleDuration(r.For, 0); err != nil {
+		return fmt.Errorf("invalid for: %w", err)
+	}
+	interval, err := parseRuleDuration(r.Interval, DefaultRuleInterval)
+	if err != nil {
+		return fmt.Errorf("invalid interval: %w", err)
+	}
+	if interval < MinRuleInterval {
+		return fmt.Errorf("interval must be at least %s", MinRuleInterval)
+	}
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	return resources.ValidateLabels(r.Labels)
+}
+
+// parseRuleDuration parses an optional duration
+func parseRuleDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+// load reads rules from the config file
+func (m *RuleManager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f logRulesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range f.Rules {
+		m.rules[r.Name] = r
+	}
+	return nil
+}
+
+// save writes rules to the config file
+func (m *RuleManager) save() error {
+	m.mu.RLock()
+	f := logRulesFile{Rules: make([]LogRule, 0, len(m.rules))}
+	for _, r := range m.rules {
+		f.Rules = append(f.Rules, r)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Rules, func(i, j int) bool { return f.Rules[i].Name < f.Rules[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
@@ -0,0 +1,332 @@
+// Package alerting manages Alertmanager notification receivers, firing
+// alerts and silences
+//
+// Receivers are stored in receivers.yaml and rendered into the
+// Alertmanager config file, after which Alertmanager is told to reload.
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/forge/api/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Receiver types
+const (
+	TypeSlack   = "slack"
+	TypeDiscord = "discord"
+	TypeEmail   = "email"
+	TypeWebhook = "webhook"
+)
+
+// ErrReloadFailed means the config was saved but Alertmanager did not reload it
+var ErrReloadFailed = errors.New("alertmanager reload failed")
+
+var receiverNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Receiver is a notification channel for alerts
+type Receiver struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+
+	// URL is the Slack/Discord/generic webhook URL
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// Channel is the Slack channel (optional, defaults to the webhook's)
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	// To is the email recipient
+	To string `json:"to,omitempty" yaml:"to,omitempty"`
+
+	// Matchers limit which alerts reach this receiver, e.g. ["severity=\"critical\""]
+	Matchers []string `json:"matchers,omitempty" yaml:"matchers,omitempty"`
+	// SendResolved also notifies when alerts resolve
+	SendResolved bool `json:"send_resolved" yaml:"send_resolved"`
+}
+
+type receiversFile struct {
+	Receivers []Receiver `yaml:"receivers"`
+}
+
+// Manager stores receivers and keeps the Alertmanager config in sync
+type Manager struct {
+	mu         sync.RWMutex
+	receivers  map[string]Receiver
+	configPath string // receivers.yaml
+	amConfPath string // generated alertmanager.yml
+	smtp       config.SMTP
+	client     *Client
+}
+
+// NewManager creates a receivers manager
+func NewManager(configPath, amConfPath string, smtp config.SMTP, client *Client) (*Manager, error) {
+	m := &Manager{
+		receivers:  make(map[string]Receiver),
+		configPath: configPath,
+		amConfPath: amConfPath,
+		smtp:       smtp,
+		client:     client,
+	}
+
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// Make sure Alertmanager has a valid config even before any receiver exists
+	if _, err := os.Stat(amConfPath); os.IsNotExist(err) {
+		if err := m.writeAlertmanagerConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// List returns all receivers sorted by name
+func (m *Manager) List() []Receiver {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Receiver, 0, len(m.receivers))
+	for _, r := range m.receivers {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a receiver by name
+func (m *Manager) Get(name string) (Receiver, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.receivers[name]
+	return r, ok
+}
+
+// Add creates or updates a receiver and reloads Alertmanager
+func (m *Manager) Add(ctx context.Context, r Receiver) error {
+	if err := m.validate(r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.receivers[r.Name] = r
+	m.mu.Unlock()
+
+	return m.apply(ctx)
+}
+
+// Remove deletes a receiver and reloads Alertmanager
+func (m *Manager) Remove(ctx context.Context, name string) error {
+	m.mu.Lock()
+	if _, ok := m.receivers[name]; !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("receiver not found: %s", name)
+	}
+	delete(m.receivers, name)
+	m.mu.Unlock()
+
+	return m.apply(ctx)
+}
+
+// validate checks a receiver's type-specific fields
+func (m *Manager) validate(r Receiver) error {
+	if !receiverNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("name must be 1-64 characters of letters, digits, '_' or '-'")
+	}
+	switch r.Type {
+	case TypeSlack, TypeDiscord, TypeWebhook:
+		u, err := url.Parse(r.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s receiver requires a valid http(s) url", r.Type)
+		}
+	case TypeEmail:
+		if _, err := mail.ParseAddress(r.To); err != nil {
+			return fmt.Errorf("email receiver requires a valid 'to' address")
+		}
+		if m.smtp.Smarthost == "" || m.smtp.From == "" {
+			return fmt.Errorf("email receivers require SMTP_SMARTHOST and SMTP_FROM to be set")
+		}
+	default:
+		return fmt.Errorf("invalid receiver type: %q (must be slack, discord, email or webhook)", r.Type)
+	}
+	return nil
+}
+
+// apply persists receivers, regenerates the Alertmanager config and reloads it
+func (m *Manager) apply(ctx context.Context) error {
+	if err := m.save(); err != nil {
+		return err
+	}
+	if err := m.writeAlertmanagerConfig(); err != nil {
+		return err
+	}
+	if err := m.client.Reload(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrReloadFailed, err)
+	}
+	return nil
+}
+
+// Alertmanager config structure (subset rendered by Forge)
+type amConfig struct {
+	Global    map[string]any `yaml:"global,omitempty"`
+	Route     amRoute        `yaml:"route"`
+	Receivers []amReceiver   `yaml:"receivers"`
+}
+
+type amRoute struct {
+	Receiver       string    `yaml:"receiver"`
+	GroupBy        []string  `yaml:"group_by,omitempty"`
+	GroupWait      string    `yaml:"group_wait,omitempty"`
+	GroupInterval  string    `yaml:"group_interval,omitempty"`
+	RepeatInterval string    `yaml:"repeat_interval,omitempty"`
+	Matchers       []string  `yaml:"matchers,omitempty"`
+	Continue       bool      `yaml:"continue,omitempty"`
+	Routes         []amRoute `yaml:"routes,omitempty"`
+}
+
+type amReceiver struct {
+	Name           string           `yaml:"name"`
+	SlackConfigs   []map[string]any `yaml:"slack_configs,omitempty"`
+	DiscordConfigs []map[string]any `yaml:"discord_configs,omitempty"`
+	EmailConfigs   []map[string]any `yaml:"email_configs,omitempty"`
+	WebhookConfigs []map[string]any `yaml:"webhook_configs,omitempty"`
+}
+
+// blackholeReceiver catches alerts no configured receiver wants
+const blackholeReceiver = "forge-null"
+
+// generateAlertmanagerConfig renders receivers into Alertmanager YAML
+func (m *Manager) generateAlertmanagerConfig() ([]byte, error) {
+	receivers := m.List()
+
+	cfg := amConfig{
+		Route: amRoute{
+			Receiver:       blackholeReceiver,
+			GroupBy:        []string{"alertname", "service"},
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "4h",
+		},
+		Receivers: []amReceiver{{Name: blackholeReceiver}},
+	}
+
+	if m.smtp.Smarthost != "" {
+		cfg.Global = map[string]any{
+			"smtp_smarthost": m.smtp.Smarthost,
+			"smtp_from":      m.smtp.From,
+		}
+		if m.smtp.Username != "" {
+			cfg.Global["smtp_auth_username"] = m.smtp.Username
+			cfg.Global["smtp_auth_password"] = m.smtp.Password
+		}
+	}
+
+	for _, r := range receivers {
+		rcv := amReceiver{Name: r.Name}
+		switch r.Type {
+		case TypeSlack:
+			c := map[string]any{"api_url": r.URL, "send_resolved": r.SendResolved}
+			if r.Channel != "" {
+				c["channel"] = r.Channel
+			}
+			rcv.SlackConfigs = []map[string]any{c}
+		case TypeDiscord:
+			rcv.DiscordConfigs = []map[string]any{{"webhook_url": r.URL, "send_resolved": r.SendResolved}}
+		case TypeEmail:
+			rcv.EmailConfigs = []map[string]any{{"to": r.To, "send_resolved": r.SendResolved}}
+		case TypeWebhook:
+			rcv.WebhookConfigs = []map[string]any{{"url": r.URL, "send_resolved": r.SendResolved}}
+		}
+		cfg.Receivers = append(cfg.Receivers, rcv)
+
+		// Every receiver sees every matching alert
+		cfg.Route.Routes = append(cfg.Route.Routes, amRoute{
+			Receiver: r.Name,
+			Matchers: r.Matchers,
+			Continue: true,
+		})
+	}
+
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte("# Alertmanager config - auto-generated by Forge API\n# Do not edit manually\n\n")
+	return append(header, data...), nil
+}
+
+// writeAlertmanagerConfig writes the generated config via a temp file + rename
+func (m *Manager) writeAlertmanagerConfig() error {
+	data, err := m.generateAlertmanagerConfig()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(m.amConfPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".alertmanager-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Contains webhook URLs and SMTP credentials
+	if err := tmp.Chmod(0640); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.amConfPath)
+}
+
+// load reads receivers from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f receiversFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range f.Receivers {
+		m.receivers[r.Name] = r
+	}
+	return nil
+}
+
+// save writes receivers to the config file
+func (m *Manager) save() error {
+	f := receiversFile{Receivers: m.List()}
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0600)
+}
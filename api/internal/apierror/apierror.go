@@ -0,0 +1,113 @@
+// Package apierror writes the API's JSON error envelope
+//
+//	{"error": {"code": "internal", "message": "...", "details": {...}}}
+//
+// Codes are Connect code names, so REST and Connect clients see the same
+// vocabulary.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+)
+
+// Body is the content of an error envelope
+type Body struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Envelope is the JSON document of an error response
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+// Write sends an error envelope with status
+func Write(w http.ResponseWriter, status int, code connect.Code, message string, details map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Envelope{Error: Body{Code: code.String(), Message: message, Details: details}})
+}
+
+// Error sends message as an error envelope with status, the counterpart of
+// http.Error; the code is derived from the status
+func Error(w http.ResponseWriter, message string, status int) {
+	Write(w, status, CodeFromStatus(status), message, nil)
+}
+
+// FromConnect sends the envelope for an error returned by a Connect handler
+// method, with the HTTP status its code maps to
+func FromConnect(w http.ResponseWriter, err error, details map[string]any) {
+	code := connect.CodeOf(err)
+	message := err.Error()
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		message = connectErr.Message()
+	}
+	Write(w, StatusFromCode(code), code, message, details)
+}
+
+// StatusFromCode maps Connect codes to HTTP statuses as the Connect
+// protocol does
+func StatusFromCode(code connect.Code) int {
+	switch code {
+	case connect.CodeCanceled:
+		return 499
+	case connect.CodeInvalidArgument, connect.CodeFailedPrecondition, connect.CodeOutOfRange:
+		return http.StatusBadRequest
+	case connect.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case connect.CodePermissionDenied:
+		return http.StatusForbidden
+	case connect.CodeNotFound:
+		return http.StatusNotFound
+	case connect.CodeAlreadyExists, connect.CodeAborted:
+		return http.StatusConflict
+	case connect.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case connect.CodeUnimplemented:
+		return http.StatusNotImplemented
+	case connect.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case connect.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeFromStatus maps HTTP statuses to the closest Connect code
+func CodeFromStatus(status int) connect.Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return connect.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return connect.CodeUnauthenticated
+	case http.StatusForbidden:
+		return connect.CodePermissionDenied
+	case http.StatusNotFound:
+		return connect.CodeNotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return connect.CodeUnimplemented
+	case http.StatusConflict:
+		return connect.CodeAlreadyExists
+	case http.StatusPreconditionFailed:
+		return connect.CodeFailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusInsufficientStorage:
+		return connect.CodeResourceExhausted
+	case 499:
+		return connect.CodeCanceled
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return connect.CodeUnavailable
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return connect.CodeDeadlineExceeded
+	}
+	if status >= 500 {
+		return connect.CodeInternal
+	}
+	return connect.CodeUnknown
+}
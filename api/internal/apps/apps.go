@@ -0,0 +1,387 @@
+// Package apps deploys user containers on the forge host from an image,
+// optionally with a route and a log source, as a minimal PaaS. Docker is
+// the source of truth: apps are the containers carrying the app label.
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/logsources"
+	"github.com/forge/api/internal/routes"
+	"github.com/forge/api/internal/system"
+)
+
+const (
+	// Label marks app containers; its value is the app name
+	Label = "forge.app"
+	// ownerLabel marks routes and log sources created for apps
+	ownerLabel   = "forge_app"
+	sourcePrefix = "app-"
+	// DefaultNetwork is the network apps join so routes can reach them
+	DefaultNetwork = "forge-net"
+)
+
+// Restart policies
+const (
+	RestartNo            = "no"
+	RestartAlways        = "always"
+	RestartUnlessStopped = "unless-stopped" // default
+	RestartOnFailure     = "on-failure"
+)
+
+var (
+	// ErrNotFound is returned for unknown apps
+	ErrNotFound = errors.New("app not found")
+	// ErrExists is returned when the app name or its route name is taken
+	ErrExists = errors.New("app already exists")
+	// ErrInvalid wraps validation errors
+	ErrInvalid = errors.New("invalid app")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+// volumeNamePattern matches Docker named volumes
+var volumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// App is an app to deploy
+type App struct {
+	Name    string               `json:"name" yaml:"name"`
+	Image   string               `json:"image" yaml:"image"`
+	Command []string             `json:"command,omitempty" yaml:"command,omitempty"`
+	Env     map[string]string    `json:"env,omitempty" yaml:"env,omitempty"`
+	Ports   []system.PortBinding `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Volumes []Volume             `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Restart string               `json:"restart,omitempty" yaml:"restart,omitempty"`
+
+	// Route proxies a path (or host) to the app through the forge proxy
+	Route *Route `json:"route,omitempty" yaml:"route,omitempty"`
+	// Logs ships the app's container logs to Loki as {app="<name>"}
+	Logs bool `json:"logs,omitempty" yaml:"logs,omitempty"`
+}
+
+// Volume mounts a named volume, or a host path under the manager's volume
+// root, into the app
+type Volume struct {
+	Source   string `json:"source" yaml:"source"`
+	Target   string `json:"target" yaml:"target"`
+	ReadOnly bool   `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+// Route is the route registered for an app. It is named after the app.
+type Route struct {
+	Path        string `json:"path" yaml:"path"`
+	Port        int    `json:"port,omitempty" yaml:"port,omitempty"` // container port, default the first published one or 80
+	Host        string `json:"host,omitempty" yaml:"host,omitempty"`
+	Domain      string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	StripPrefix bool   `json:"strip_prefix,omitempty" yaml:"strip_prefix,omitempty"`
+	WebSocket   bool   `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+}
+
+// Status is a deployed app
+type Status struct {
+	Name      string `json:"name"`
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	State     string `json:"state"`
+	Status    string `json:"status"`
+	Route     string `json:"route,omitempty"`      // route name
+	LogSource string `json:"log_source,omitempty"` // log source name
+}
+
+// Manager deploys and removes apps. Routes and log sources are optional.
+type Manager struct {
+	docker  *system.DockerClient
+	routes  *routes.Manager
+	sources *logsources.Manager
+	network string
+	// volumeRoot is the host directory apps may bind paths from; "" allows
+	// named volumes only
+	volumeRoot string
+}
+
+// NewManager creates a manager whose apps join network and may bind host
+// paths under volumeRoot; rm and lm may be nil when routes or log sources
+// are disabled
+func NewManager(docker *system.DockerClient, rm *routes.Manager, lm *logsources.Manager, network, volumeRoot string) *Manager {
+	if network == "" {
+		network = DefaultNetwork
+	}
+	if volumeRoot != "" {
+		volumeRoot = path.Clean(volumeRoot)
+	}
+	return &Manager{docker: docker, routes: rm, sources: lm, network: network, volumeRoot: volumeRoot}
+}
+
+// Validate checks an app and fills in defaults, before anything is pulled
+// or created
+func (m *Manager) Validate(ctx context.Context, app *App) error {
+	if !namePattern.MatchString(app.Name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits, '_', '.' or '-'", ErrInvalid)
+	}
+	if strings.HasPrefix(app.Name, "forge-") {
+		return fmt.Errorf("%w: the forge- prefix is reserved for the stack", ErrInvalid)
+	}
+	if app.Name == "templates" {
+		return fmt.Errorf("%w: templates is a reserved name", ErrInvalid)
+	}
+	if app.Image == "" {
+		return fmt.Errorf("%w: image is required", ErrInvalid)
+	}
+	for k := range app.Env {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("%w: invalid env name %q", ErrInvalid, k)
+		}
+	}
+	for i, p := range app.Ports {
+		if p.Container < 1 || p.Container > 65535 || p.Host < 0 || p.Host > 65535 {
+			return fmt.Errorf("%w: ports[%d]: ports must be 1-65535", ErrInvalid, i)
+		}
+		if p.Protocol != "" && p.Protocol != "tcp" && p.Protocol != "udp" {
+			return fmt.Errorf("%w: ports[%d]: protocol must be tcp or udp", ErrInvalid, i)
+		}
+	}
+	for i, v := range app.Volumes {
+		source, err := m.volumeSource(v.Source)
+		if err != nil {
+			return fmt.Errorf("%w: volumes[%d]: %v", ErrInvalid, i, err)
+		}
+		app.Volumes[i].Source = source
+		if !path.IsAbs(v.Target) || strings.Contains(v.Target, ":") {
+			return fmt.Errorf("%w: volumes[%d]: target must be an absolute path", ErrInvalid, i)
+		}
+	}
+	switch app.Restart {
+	case "":
+		app.Restart = RestartUnlessStopped
+	case RestartNo, RestartAlways, RestartUnlessStopped, RestartOnFailure:
+	default:
+		return fmt.Errorf("%w: restart must be %s, %s, %s or %s", ErrInvalid, RestartNo, RestartAlways, RestartUnlessStopped, RestartOnFailure)
+	}
+
+	if app.Route != nil {
+		if m.routes == nil {
+			return fmt.Errorf("%w: routes are not enabled", ErrInvalid)
+		}
+		if app.Route.Port == 0 {
+			app.Route.Port = 80
+			if len(app.Ports) > 0 {
+				app.Route.Port = app.Ports[0].Container
+			}
+		}
+		if p := m.routes.Preview(m.routeFor(*app)); !p.Valid {
+			return fmt.Errorf("%w: route: %s", ErrInvalid, p.Error)
+		}
+		if _, ok := m.routes.Get(app.Name); ok {
+			return fmt.Errorf("%w: route %s already exists", ErrExists, app.Name)
+		}
+	}
+	if app.Logs {
+		if m.sources == nil {
+			return fmt.Errorf("%w: log sources are not enabled", ErrInvalid)
+		}
+		if _, ok := m.sources.Get(sourcePrefix + app.Name); ok {
+			return fmt.Errorf("%w: log source %s already exists", ErrExists, sourcePrefix+app.Name)
+		}
+	}
+
+	existing, err := m.docker.ContainersByLabel(ctx, Label+"="+app.Name)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%w: %s", ErrExists, app.Name)
+	}
+	return nil
+}
+
+// volumeSource checks a volume source: a named volume, or a host path under
+// the volume root. Any other host path (/, the Docker socket) would hand
+// the host to the app.
+func (m *Manager) volumeSource(source string) (string, error) {
+	if volumeNamePattern.MatchString(source) {
+		return source, nil
+	}
+	if !path.IsAbs(source) || strings.Contains(source, ":") {
+		return "", errors.New("source must be a volume name or an absolute path")
+	}
+	if m.volumeRoot == "" {
+		return "", errors.New("host paths are disabled; use a named volume or set APPS_VOLUME_ROOT")
+	}
+	source = path.Clean(source)
+	if source != m.volumeRoot && !strings.HasPrefix(source, strings.TrimSuffix(m.volumeRoot, "/")+"/") {
+		return "", fmt.Errorf("host paths must be under %s", m.volumeRoot)
+	}
+	return source, nil
+}
+
+// Deploy pulls the image if needed, creates and starts the container and
+// registers its route and log source. app must have passed Validate. On
+// failure everything created is removed again.
+func (m *Manager) Deploy(ctx context.Context, app App, logf func(format string, args ...any)) (Status, error) {
+	logf("pulling %s", app.Image)
+	if err := m.docker.EnsureImage(ctx, app.Image); err != nil {
+		return Status{}, err
+	}
+	network, err := m.docker.ComposeNetwork(ctx, m.network)
+	if err != nil {
+		return Status{}, fmt.Errorf("find network %s: %w", m.network, err)
+	}
+
+	binds := make([]string, 0, len(app.Volumes))
+	for _, v := range app.Volumes {
+		bind := v.Source + ":" + v.Target
+		if v.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+	logf("starting container %s", app.Name)
+	if _, err := m.docker.CreateContainer(ctx, app.Name, system.ContainerSpec{
+		Image:   app.Image,
+		Cmd:     app.Command,
+		Env:     app.Env,
+		Labels:  map[string]string{Label: app.Name},
+		Ports:   app.Ports,
+		Binds:   binds,
+		Restart: app.Restart,
+		Network: network,
+	}); err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Name: app.Name, Container: app.Name, Image: app.Image, State: "running"}
+	undo := func() {
+		if err := m.Remove(context.WithoutCancel(ctx), app.Name); err != nil {
+			logger.Error("Failed to clean up app "+app.Name, err)
+		}
+	}
+	if app.Route != nil {
+		logf("adding route %s", app.Name)
+		if err := m.routes.Add(m.routeFor(app)); err != nil {
+			undo()
+			return Status{}, fmt.Errorf("add route: %w", err)
+		}
+		status.Route = app.Name
+	}
+	if app.Logs {
+		logf("adding log source %s", sourcePrefix+app.Name)
+		source := logsources.LogSource{
+			Name:            sourcePrefix + app.Name,
+			Type:            logsources.TypeDocker,
+			ContainerLabels: map[string]string{Label: app.Name},
+			Labels:          map[string]string{ownerLabel: app.Name, "app": app.Name},
+		}
+		if err := m.sources.Add(source); err != nil {
+			undo()
+			return Status{}, fmt.Errorf("add log source: %w", err)
+		}
+		if err := m.sources.ReloadPromtail(); err != nil {
+			logf("Promtail reload failed: %v", err)
+		}
+		status.LogSource = source.Name
+	}
+	return status, nil
+}
+
+// List returns the deployed apps
+func (m *Manager) List(ctx context.Context) ([]Status, error) {
+	containers, err := m.docker.ContainersByLabel(ctx, Label)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(containers))
+	for _, ct := range containers {
+		list = append(list, m.status(ct))
+	}
+	return list, nil
+}
+
+// Get returns a deployed app
+func (m *Manager) Get(ctx context.Context, name string) (Status, error) {
+	containers, err := m.docker.ContainersByLabel(ctx, Label+"="+name)
+	if err != nil {
+		return Status{}, err
+	}
+	if len(containers) == 0 {
+		return Status{}, ErrNotFound
+	}
+	return m.status(containers[0]), nil
+}
+
+// status describes an app container with its route and log source
+func (m *Manager) status(ct system.ContainerSummary) Status {
+	name := ct.Labels[Label]
+	s := Status{Name: name, Container: ct.Name, Image: ct.Image, State: ct.State, Status: ct.Status}
+	if m.routes != nil {
+		if r, ok := m.routes.Get(name); ok && r.Labels[ownerLabel] == name {
+			s.Route = r.Name
+		}
+	}
+	if m.sources != nil {
+		if src, ok := m.sources.Get(sourcePrefix + name); ok && src.Labels[ownerLabel] == name {
+			s.LogSource = src.Name
+		}
+	}
+	return s
+}
+
+// Remove stops and removes an app with its route and log source
+func (m *Manager) Remove(ctx context.Context, name string) error {
+	containers, err := m.docker.ContainersByLabel(ctx, Label+"="+name)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	found := len(containers) > 0
+	for _, ct := range containers {
+		if err := m.docker.RemoveContainer(ctx, ct.ID); err != nil && !errors.Is(err, system.ErrContainerNotFound) {
+			errs = append(errs, fmt.Errorf("remove container: %w", err))
+		}
+	}
+	if m.routes != nil {
+		if r, ok := m.routes.Get(name); ok && r.Labels[ownerLabel] == name {
+			found = true
+			if err := m.routes.Remove(name); err != nil {
+				errs = append(errs, fmt.Errorf("remove route: %w", err))
+			}
+		}
+	}
+	if m.sources != nil {
+		if src, ok := m.sources.Get(sourcePrefix + name); ok && src.Labels[ownerLabel] == name {
+			found = true
+			if err := m.sources.Delete(src.Name); err != nil {
+				errs = append(errs, fmt.Errorf("remove log source: %w", err))
+			} else if err := m.sources.ReloadPromtail(); err != nil {
+				errs = append(errs, fmt.Errorf("reload Promtail: %w", err))
+			}
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return errors.Join(errs...)
+}
+
+// routeFor builds the route of an app
+func (m *Manager) routeFor(app App) routes.Route {
+	r := routes.Route{
+		Name:        app.Name,
+		Path:        app.Route.Path,
+		Target:      fmt.Sprintf("http://%s:%d", app.Name, app.Route.Port),
+		Host:        app.Route.Host,
+		Domain:      app.Route.Domain,
+		StripPrefix: app.Route.StripPrefix,
+		Labels:      map[string]string{ownerLabel: app.Name},
+	}
+	if app.Route.WebSocket {
+		r.WebSocket = &routes.WebSocket{}
+	}
+	return r
+}
@@ -0,0 +1,156 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrTemplateNotFound is returned for unknown templates
+var ErrTemplateNotFound = errors.New("template not found")
+
+// namePlaceholder in env values and volume sources is replaced by the app
+// name, so two apps from one template get their own volumes
+const namePlaceholder = "{name}"
+
+// Template is a catalog entry: an app with defaults, deployable in one call
+type Template struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Website     string `json:"website,omitempty" yaml:"website,omitempty"`
+	App         App    `json:"app" yaml:"app"`
+}
+
+// TemplateOverrides customise a template at deploy time
+type TemplateOverrides struct {
+	Name  string            `json:"name,omitempty"`  // app name, default the template name
+	Image string            `json:"image,omitempty"` // e.g. to pin another tag
+	Env   map[string]string `json:"env,omitempty"`   // merged over the template env
+	Route *Route            `json:"route,omitempty"` // non-empty fields replace the template's
+	Logs  *bool             `json:"logs,omitempty"`
+}
+
+// Catalog reads templates from a directory of YAML files, one template
+// per file. Files are read on each call, so added templates show up
+// without a restart.
+type Catalog struct {
+	dir string
+}
+
+// NewCatalog creates a catalog of the templates in dir
+func NewCatalog(dir string) *Catalog {
+	return &Catalog{dir: dir}
+}
+
+// List returns the templates sorted by name. Files that fail to parse are
+// skipped and reported in the error.
+func (c *Catalog) List() ([]Template, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Template, 0, len(files))
+	var errs []error
+	for _, file := range files {
+		t, err := readTemplate(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, errors.Join(errs...)
+}
+
+// Get returns a template by name
+func (c *Catalog) Get(name string) (Template, error) {
+	if !namePattern.MatchString(name) {
+		return Template{}, ErrTemplateNotFound
+	}
+	t, err := readTemplate(filepath.Join(c.dir, name+".yaml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Template{}, ErrTemplateNotFound
+	}
+	return t, err
+}
+
+// readTemplate parses a template file; the template is named after the file
+func readTemplate(file string) (Template, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Template{}, err
+	}
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return Template{}, fmt.Errorf("template %s: %w", filepath.Base(file), err)
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(file), ".yaml")
+	if t.App.Image == "" {
+		return Template{}, fmt.Errorf("template %s: app.image is required", t.Name)
+	}
+	return t, nil
+}
+
+// Instantiate returns the app a template deploys with overrides applied
+// and placeholders filled in
+func (t Template) Instantiate(o TemplateOverrides) (App, error) {
+	app := t.App
+	app.Name = t.Name
+	if o.Name != "" {
+		app.Name = o.Name
+	}
+	if o.Image != "" {
+		app.Image = o.Image
+	}
+	if o.Logs != nil {
+		app.Logs = *o.Logs
+	}
+
+	env := make(map[string]string, len(app.Env)+len(o.Env))
+	for k, v := range app.Env {
+		env[k] = strings.ReplaceAll(v, namePlaceholder, app.Name)
+	}
+	for k, v := range o.Env {
+		env[k] = v
+	}
+	app.Env = env
+
+	app.Volumes = make([]Volume, len(t.App.Volumes))
+	for i, v := range t.App.Volumes {
+		v.Source = strings.ReplaceAll(v.Source, namePlaceholder, app.Name)
+		app.Volumes[i] = v
+	}
+	app.Ports = append(app.Ports[:0:0], t.App.Ports...)
+
+	if t.App.Route != nil {
+		route := *t.App.Route
+		if o.Route != nil {
+			if o.Route.Path != "" {
+				route.Path = o.Route.Path
+			}
+			if o.Route.Host != "" {
+				route.Host = o.Route.Host
+			}
+			if o.Route.Domain != "" {
+				route.Domain = o.Route.Domain
+			}
+		}
+		route.Path = strings.ReplaceAll(route.Path, namePlaceholder, app.Name)
+		// A root path would take over the main server, so those apps need
+		// a host of their own
+		if route.Path == "/" && route.Host == "" && route.Domain == "" {
+			return App{}, fmt.Errorf("%w: template %s serves from / and needs route.host or route.domain", ErrInvalid, t.Name)
+		}
+		app.Route = &route
+	} else if o.Route != nil {
+		route := *o.Route
+		app.Route = &route
+	}
+	return app, nil
+}
@@ -0,0 +1,298 @@
+// Package audit records mutating API requests in an append-only log
+//
+// Every write (REST requests other than GET/HEAD/OPTIONS and Connect RPCs
+// that change state) is recorded with who made it, when, what it targeted
+// with a redacted summary of its payload, and the result. Entries are JSON
+// lines appended to a file that is never rewritten; when it grows past the
+// size limit it is rotated once, and the log is queried across both files.
+// Telemetry ingestion (logs, metrics, traces, error events) is not audited.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the log size at which it is rotated
+	DefaultMaxSize = 100 << 20
+	// DefaultLimit and MaxLimit bound the entries a query returns
+	DefaultLimit = 100
+	MaxLimit     = 1000
+	// MaxSummaryBody is the largest request body that is summarized
+	MaxSummaryBody = 64 << 10
+	// maxSummaryString truncates long string fields in summaries
+	maxSummaryString = 200
+	// maxSummaryFields bounds the fields of a summary
+	maxSummaryFields = 32
+)
+
+// Entry is one audited request
+type Entry struct {
+	Time       time.Time      `json:"time"`
+	Actor      string         `json:"actor"`                 // key or user name; anonymous before auth is enforced
+	KeyID      string         `json:"key_id,omitempty"`      // API key ID, "setup", "bootstrap" or "oidc"
+	Subject    string         `json:"subject,omitempty"`     // sub claim of OIDC tokens
+	Role       string         `json:"role,omitempty"`        // role of the caller
+	RemoteAddr string         `json:"remote_addr,omitempty"` // caller IP
+	Method     string         `json:"method"`
+	Path       string         `json:"path"`
+	Procedure  string         `json:"procedure,omitempty"` // Connect RPC, when the request is one
+	Payload    map[string]any `json:"payload,omitempty"`   // redacted summary of the request body
+	Status     int            `json:"status"`
+	DurationMs int64          `json:"duration_ms"`
+	RequestID  string         `json:"request_id,omitempty"`
+}
+
+// Query filters entries; zero values match all
+type Query struct {
+	From   time.Time
+	To     time.Time
+	Actor  string // exact actor or key ID
+	Path   string // path prefix
+	Failed bool   // only entries with a status of 400 or more
+	Limit  int
+}
+
+// Log is an append-only audit log file
+type Log struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewLog opens (or creates) the audit log at path
+func NewLog(path string, maxSize int64) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	l := &Log{path: path, maxSize: maxSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Record appends an entry
+func (l *Log) Record(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size+int64(len(line)) > l.maxSize && l.size > 0 {
+		if err := l.rotateLocked(); err != nil {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotateLocked moves the log to <path>.1, replacing an older rotation; the
+// caller holds l.mu
+func (l *Log) rotateLocked() error {
+	l.file.Close()
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Query returns matching entries, newest first
+func (l *Log) Query(q Query) ([]Entry, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	for _, path := range []string{l.path + ".1", l.path} {
+		if err := scan(path, func(e Entry) {
+			if q.matches(e) {
+				entries = append(entries, e)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	if len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+func (q Query) matches(e Entry) bool {
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && e.Time.After(q.To) {
+		return false
+	}
+	if q.Actor != "" && e.Actor != q.Actor && e.KeyID != q.Actor {
+		return false
+	}
+	if q.Path != "" && !strings.HasPrefix(e.Path, q.Path) {
+		return false
+	}
+	return !q.Failed || e.Status >= 400
+}
+
+// scan calls fn for each entry of the file at path; a missing file has none
+func scan(path string, fn func(Entry)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for s.Scan() {
+		var e Entry
+		if json.Unmarshal(s.Bytes(), &e) == nil {
+			fn(e)
+		}
+	}
+	return s.Err()
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// sensitiveField matches payload fields whose values are never recorded
+var sensitiveField = regexp.MustCompile(`(?i)pass|secret|token|credential|private|api_?key|value|webhook|cookie`)
+
+// Summarize returns a redacted summary of a JSON request body: top-level
+// scalars (long strings truncated), sizes of nested objects and lists, and
+// "[redacted]" for fields that may hold secrets or user data. Bodies that
+// are not JSON objects are summarized by their size.
+func Summarize(body []byte, contentType string) map[string]any {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) > MaxSummaryBody || (contentType != "" && !strings.Contains(contentType, "json")) {
+		return map[string]any{"_bytes": len(body)}
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return map[string]any{"_bytes": len(body)}
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary := make(map[string]any, len(fields))
+	for i, name := range names {
+		if i == maxSummaryFields {
+			summary["_truncated"] = len(names) - maxSummaryFields
+			break
+		}
+		if sensitiveField.MatchString(name) {
+			summary[name] = "[redacted]"
+			continue
+		}
+		switch v := fields[name].(type) {
+		case string:
+			if len(v) > maxSummaryString {
+				v = v[:maxSummaryString] + "…"
+			}
+			summary[name] = v
+		case map[string]any:
+			summary[name] = fmt.Sprintf("{%d fields}", len(v))
+		case []any:
+			summary[name] = fmt.Sprintf("[%d items]", len(v))
+		default:
+			summary[name] = v
+		}
+	}
+	return summary
+}
+
+// unauditedPaths are writes that are not recorded: telemetry ingestion,
+// which is too frequent to audit and does not change forge's state, and
+// read-only RPCs and queries sent as POST
+var unauditedPaths = map[string]bool{
+	"/api/v1/logs":                    true,
+	"/api/v1/logs/ingest/fluent":      true,
+	"/api/v1/metrics":                 true,
+	"/api/v1/metrics/timing":          true,
+	"/api/v1/traces":                  true,
+	"/forge.v1.ObserveService/Log":    true,
+	"/forge.v1.ObserveService/Metric": true,
+	"/forge.v1.ObserveService/Trace":  true,
+	"/forge.v1.ObserveService/Timing": true,
+	"/forge.v1.ErrorsService/Capture": true,
+
+	"/api/v1/metrics/query":             true,
+	"/api/v1/metrics/query_range":       true,
+	"/forge.v1.ForgeService/Health":     true,
+	"/forge.v1.ForgeService/Info":       true,
+	"/forge.v1.CacheService/Get":        true,
+	"/forge.v1.CacheService/GetInfo":    true,
+	"/forge.v1.ObserveService/Query":    true,
+	"/forge.v1.DatabaseService/GetInfo": true,
+}
+
+// Audited reports whether a request is a write that must be recorded
+func Audited(method, path string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	if unauditedPaths[path] {
+		return false
+	}
+	// Error events are captured with POST /api/v1/errors; changes to
+	// tracked errors use PATCH and DELETE
+	return !(method == "POST" && path == "/api/v1/errors")
+}
@@ -0,0 +1,220 @@
+// Package auth authenticates API callers with API keys or, when an
+// identity provider is configured, OIDC JWTs
+//
+// Keys are random tokens shown once when issued; only their SHA-256 hash
+// is stored, in a YAML file. Each key has a role:
+//
+//   - read: GET requests and read-only RPCs
+//   - write: every request except key management
+//   - admin: everything, including issuing and revoking keys and reading
+//     the audit log
+//
+// The admin key created by first-boot setup is accepted as an admin key.
+// Authentication is enforced once a key exists, setup has completed or a
+// bootstrap admin key is configured (FORGE_ADMIN_KEY); until then the API
+// stays open, so setup can run and the first key can be issued. Callers send the key as
+// "Authorization: Bearer <key>".
+//
+// With OIDC_ISSUER set, bearer tokens that are JWTs are validated against
+// the provider's signing keys instead, and a claim (groups by default) is
+// mapped to a role, so teams can sign in with their SSO. Configuring a
+// provider enforces authentication.
+//
+// With MySQL available, web UI users sign in at /api/v1/auth/login with a
+// username and password and get an HttpOnly session cookie. Requests
+// authenticated by the cookie that change state must echo the session's
+// CSRF token in the X-CSRF-Token header.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Roles, from least to most privileged
+const (
+	RoleRead  = "read"
+	RoleWrite = "write"
+	RoleAdmin = "admin"
+)
+
+var roleRank = map[string]int{RoleRead: 1, RoleWrite: 2, RoleAdmin: 3}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// Allows reports whether role has at least the privileges of required
+func Allows(role, required string) bool {
+	return roleRank[role] >= roleRank[required]
+}
+
+var (
+	// ErrUnauthenticated is returned for missing, unknown or expired keys and tokens
+	ErrUnauthenticated = errors.New("missing or invalid API key or token")
+	// ErrForbidden is returned when the caller's role is not sufficient
+	ErrForbidden = errors.New("role does not allow this request")
+)
+
+// Identity is an authenticated caller
+type Identity struct {
+	KeyID   string `json:"key_id"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+	Method  string `json:"method"`            // how the caller authenticated: api_key, oidc, mtls or session
+	Subject string `json:"subject,omitempty"` // sub claim of OIDC tokens, certificate subject or username
+	// RateLimits are the key's own requests per minute by endpoint class
+	RateLimits map[string]int `json:"rate_limits,omitempty"`
+}
+
+type identityKey struct{}
+
+// WithIdentity returns ctx carrying the caller's identity
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	if slot, ok := ctx.Value(identitySlotKey{}).(*identitySlot); ok {
+		slot.mu.Lock()
+		slot.id = id
+		slot.mu.Unlock()
+	}
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// Track returns ctx with a slot that WithIdentity fills, and a function
+// reading it. Outer middleware such as the audit log uses it to learn who
+// made a request authenticated further in (e.g. by the Connect interceptor).
+func Track(ctx context.Context) (context.Context, func() *Identity) {
+	slot := new(identitySlot)
+	return context.WithValue(ctx, identitySlotKey{}, slot), func() *Identity {
+		slot.mu.Lock()
+		defer slot.mu.Unlock()
+		return slot.id
+	}
+}
+
+type identitySlotKey struct{}
+
+type identitySlot struct {
+	mu sync.Mutex
+	id *Identity
+}
+
+// FromContext returns the caller's identity, or nil when the request was
+// not authenticated (authentication is not enforced)
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// publicPaths are served without a key: health checks, Prometheus scrapes
+// and the API docs
+var publicPaths = map[string]bool{
+	"/api/v1/health":                true,
+	"/forge.v1.ForgeService/Health": true,
+	"/metrics":                      true,
+	"/docs":                         true,
+	"/openapi.json":                 true,
+}
+
+// Public reports whether path is served without authentication
+func Public(path string) bool {
+	return publicPaths[path] || strings.HasPrefix(path, "/docs/")
+}
+
+// LoginPath takes a username and password, so it is served without a key;
+// unlike public paths it is still rate limited
+const LoginPath = "/api/v1/auth/login"
+
+// HooksPrefix is where inbound webhooks are received. Their senders sign
+// requests instead of presenting a key, so hooks are served without one;
+// like the login they are still rate limited.
+const HooksPrefix = "/hooks/"
+
+// AdminPaths need the admin role: key and user management, the audit log,
+// runtime diagnostics, scheduled jobs (which run SQL and HTTP calls),
+// webhooks (which hold signing secrets and receive events of the stack),
+// inbound hooks (which hold secrets and reach internal services),
+// notification channels, alert receivers and Grafana datasources (which
+// hold tokens and credentials), MQTT bridges (which write logs and
+// metrics), apps and images (which run containers on the host), stack
+// upgrades (which recreate containers), cleanups (which can delete
+// volumes), resource limits (which apply to every container) and the
+// container watchdog (which restarts containers); sending to a channel or
+// publishing to MQTT needs only the write role
+var AdminPaths = []string{
+	"/api/v1/auth/keys",
+	"/api/v1/auth/users",
+	"/api/v1/audit",
+	"/debug",
+	"/api/v1/jobs",
+	"/api/v1/webhooks",
+	"/api/v1/hooks",
+	"/api/v1/notify/channels",
+	"/api/v1/mqtt/bridges",
+	"/api/v1/apps",
+	"/api/v1/images",
+	"/api/v1/system/upgrade",
+	"/api/v1/system/prune",
+	"/api/v1/system/limits",
+	"/api/v1/system/watchdog",
+	"/api/v1/alerts/receivers",
+	"/api/v1/grafana/datasources",
+	"/api/v1/system/disk/prune",
+}
+
+// RequiredRole returns the role a REST request needs
+func RequiredRole(r *http.Request) string {
+	for _, p := range AdminPaths {
+		if r.URL.Path == p || strings.HasPrefix(r.URL.Path, p+"/") {
+			return RoleAdmin
+		}
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return RoleRead
+	}
+	return RoleWrite
+}
+
+// readProcedures are the RPCs the read role may call. Database queries are
+// excluded as they run with root credentials; GetInfo issues read-only
+// accounts to the read role, PresignURL only download URLs, and messaging
+// GetInfo a subscribe-only account.
+var readProcedures = map[string]bool{
+	"/forge.v1.ForgeService/Health":     true,
+	"/forge.v1.ForgeService/Info":       true,
+	"/forge.v1.CacheService/Get":        true,
+	"/forge.v1.CacheService/GetInfo":    true,
+	"/forge.v1.DatabaseService/GetInfo": true,
+	"/forge.v1.ObserveService/Query":    true,
+	"/forge.v1.FlagsService/Evaluate":   true,
+
+	"/forge.v1.StorageService/ListBuckets": true,
+	"/forge.v1.StorageService/GetObject":   true,
+	"/forge.v1.StorageService/ListObjects": true,
+	"/forge.v1.StorageService/PresignURL":  true,
+	"/forge.v1.StorageService/GetInfo":     true,
+
+	"/forge.v1.MessagingService/Subscribe": true,
+	"/forge.v1.MessagingService/GetInfo":   true,
+}
+
+// RequiredProcedureRole returns the role an RPC needs
+func RequiredProcedureRole(procedure string) string {
+	if readProcedures[procedure] {
+		return RoleRead
+	}
+	return RoleWrite
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"connectrpc.com/connect"
+)
+
+// Interceptor authenticates Connect RPCs with the store's keys
+type Interceptor struct {
+	store *Store
+}
+
+// NewInterceptor creates an interceptor checking keys against store
+func NewInterceptor(store *Store) *Interceptor {
+	return &Interceptor{store: store}
+}
+
+// WrapUnary checks the key of unary RPCs
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, err := i.authorize(ctx, req.Spec().Procedure, req.Header().Get("Authorization"))
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient leaves outgoing streams alone
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler checks the key of streaming RPCs
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authorize(ctx, conn.Spec().Procedure, conn.RequestHeader().Get("Authorization"))
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// authorize returns ctx with the caller's identity, or a Connect error. RPCs
+// without a token use the client certificate or session identity that
+// middleware.Auth put in ctx.
+func (i *Interceptor) authorize(ctx context.Context, procedure, header string) (context.Context, error) {
+	token := BearerToken(header)
+	var id *Identity
+	if token == "" {
+		id = PeerIdentity(ctx)
+	}
+	if id == nil {
+		if token == "" && (Public(procedure) || !i.store.Enforced()) {
+			return ctx, nil
+		}
+		var err error
+		id, err = i.store.Authenticate(ctx, token)
+		if errors.Is(err, ErrForbidden) {
+			return ctx, connect.NewError(connect.CodePermissionDenied, err)
+		}
+		if err != nil {
+			return ctx, connect.NewError(connect.CodeUnauthenticated, err)
+		}
+	}
+	if !Allows(id.Role, RequiredProcedureRole(procedure)) {
+		return ctx, connect.NewError(connect.CodePermissionDenied, ErrForbidden)
+	}
+	return WithIdentity(ctx, id), nil
+}
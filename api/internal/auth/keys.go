@@ -0,0 +1,358 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/setup"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// keyPrefix marks forge API keys, as for the setup admin key, so leaked
+	// ones are easy to spot
+	keyPrefix = "forge_"
+	// keyBytes is the entropy of a key
+	keyBytes = 32
+	// displayPrefixLen is how much of a key is kept to recognise it
+	displayPrefixLen = len(keyPrefix) + 8
+	// lastUsedResolution limits how often last_used_at changes are saved
+	lastUsedResolution = time.Hour
+)
+
+var (
+	// ErrKeyNotFound is returned for unknown key IDs
+	ErrKeyNotFound = errors.New("API key not found")
+	// ErrInvalidKey is returned for key requests that cannot be issued
+	ErrInvalidKey = errors.New("invalid API key request")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_. -]{1,64}$`)
+)
+
+// Key is an issued API key. The key itself is never stored.
+type Key struct {
+	ID         string     `json:"id" yaml:"id"`
+	Name       string     `json:"name" yaml:"name"`
+	Role       string     `json:"role" yaml:"role"`
+	Prefix     string     `json:"prefix" yaml:"prefix"` // start of the key, to recognise it
+	Hash       string     `json:"-" yaml:"hash"`        // SHA-256 of the key, hex
+	CreatedAt  time.Time  `json:"created_at" yaml:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" yaml:"last_used_at,omitempty"`
+	// RateLimits override the per-client rate limits, in requests per
+	// minute by endpoint class (0 is unlimited)
+	RateLimits map[string]int `json:"rate_limits,omitempty" yaml:"rate_limits,omitempty"`
+}
+
+// KeyRequest describes a key to issue
+type KeyRequest struct {
+	Name      string `json:"name"`
+	Role      string `json:"role"`                 // read, write or admin
+	ExpiresIn string `json:"expires_in,omitempty"` // Go duration; never expires when empty
+	// RateLimits override the per-client rate limits by endpoint class
+	RateLimits map[string]int `json:"rate_limits,omitempty"`
+}
+
+type keysFile struct {
+	Keys []Key `yaml:"keys"`
+}
+
+// Store holds issued keys in a YAML file and authenticates requests
+type Store struct {
+	path      string
+	bootstrap string // SHA-256 of FORGE_ADMIN_KEY, hex
+	setup     *setup.Manager
+	jwt       *JWTVerifier
+	certs     *CertConfig
+	sessions  *SessionStore
+
+	mu     sync.RWMutex
+	keys   map[string]*Key // by ID
+	byHash map[string]*Key
+}
+
+// NewStore loads the keys in path. bootstrapKey, when set, is an admin
+// key that always works and is never stored.
+func NewStore(path, bootstrapKey string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		keys:   make(map[string]*Key),
+		byHash: make(map[string]*Key),
+	}
+	if bootstrapKey != "" {
+		s.bootstrap = hashKey(bootstrapKey)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var f keysFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("auth keys: %w", err)
+		}
+		for i := range f.Keys {
+			k := f.Keys[i]
+			s.keys[k.ID] = &k
+			s.byHash[k.Hash] = &k
+		}
+	}
+	return s, nil
+}
+
+// SetSetup accepts the admin key created by first-boot setup, and enforces
+// authentication once setup has completed
+func (s *Store) SetSetup(m *setup.Manager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setup = m
+}
+
+// SetJWT accepts JWTs validated by v, and enforces authentication
+func (s *Store) SetJWT(v *JWTVerifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwt = v
+}
+
+// SetCerts accepts verified TLS client certificates as identities
+func (s *Store) SetCerts(c *CertConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs = c
+}
+
+// SetSessions accepts web UI session cookies, and enforces authentication
+// once a user exists
+func (s *Store) SetSessions(sessions *SessionStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = sessions
+}
+
+// Sessions returns the session store; nil when sessions are not enabled
+func (s *Store) Sessions() *SessionStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sessions
+}
+
+// SessionIdentity returns the identity of the request's session cookie;
+// nil without one or when sessions are not enabled
+func (s *Store) SessionIdentity(r *http.Request) (*Identity, error) {
+	sessions := s.Sessions()
+	if sessions == nil {
+		return nil, nil
+	}
+	c, err := r.Cookie(SessionCookie)
+	if err != nil || c.Value == "" {
+		return nil, nil
+	}
+	return sessions.Identity(r, c.Value)
+}
+
+// CertIdentity returns the identity of the connection's verified client
+// certificate; nil without one or when certificates are not accepted
+func (s *Store) CertIdentity(state *tls.ConnectionState) (*Identity, error) {
+	s.mu.RLock()
+	certs := s.certs
+	s.mu.RUnlock()
+	if certs == nil {
+		return nil, nil
+	}
+	return certs.Identity(state)
+}
+
+// Enforced reports whether requests must authenticate: once a key exists,
+// setup has created the admin key, a web UI user exists, or a bootstrap
+// key, identity provider or client CA is configured
+func (s *Store) Enforced() bool {
+	if s.bootstrap != "" {
+		return true
+	}
+	s.mu.RLock()
+	m, jwt, certs, sessions := s.setup, s.jwt, s.certs, s.sessions
+	s.mu.RUnlock()
+	if jwt != nil || certs != nil {
+		return true
+	}
+	if sessions != nil && sessions.HasUsers() {
+		return true
+	}
+	if m != nil && !m.Required() {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys) > 0
+}
+
+// Authenticate returns the identity of a key or, with an identity provider
+// configured, of a JWT
+func (s *Store) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+	s.mu.RLock()
+	jwt := s.jwt
+	s.mu.RUnlock()
+	if jwt != nil && LooksLikeJWT(token) {
+		return jwt.Verify(ctx, token)
+	}
+
+	hash := hashKey(token)
+	if s.bootstrap != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.bootstrap)) == 1 {
+		return &Identity{KeyID: "bootstrap", Name: "FORGE_ADMIN_KEY", Role: RoleAdmin, Method: "api_key"}, nil
+	}
+
+	now := time.Now().UTC()
+	s.mu.RLock()
+	m := s.setup
+	k, ok := s.byHash[hash]
+	var id *Identity
+	stale := false
+	if ok && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt)) {
+		id = &Identity{KeyID: k.ID, Name: k.Name, Role: k.Role, Method: "api_key", RateLimits: k.RateLimits}
+		stale = k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= lastUsedResolution
+	}
+	s.mu.RUnlock()
+	if id == nil && m != nil && m.VerifyAdminKey(token) {
+		name := "setup"
+		if admin := m.State().Admin; admin != nil {
+			name = admin.Username
+		}
+		return &Identity{KeyID: "setup", Name: name, Role: RoleAdmin, Method: "api_key"}, nil
+	}
+	if id == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	if stale {
+		s.mu.Lock()
+		if k, ok := s.keys[id.KeyID]; ok {
+			k.LastUsedAt = &now
+			s.saveLocked()
+		}
+		s.mu.Unlock()
+	}
+	return id, nil
+}
+
+// List returns the issued keys sorted by creation time
+func (s *Store) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		list = append(list, *k)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Issue creates a key and returns it with the plaintext token, which is
+// not kept
+func (s *Store) Issue(req KeyRequest) (Key, string, error) {
+	if !namePattern.MatchString(req.Name) {
+		return Key{}, "", fmt.Errorf("%w: name must be 1-64 letters, digits, spaces or _.-", ErrInvalidKey)
+	}
+	if !ValidRole(req.Role) {
+		return Key{}, "", fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidKey, RoleRead, RoleWrite, RoleAdmin)
+	}
+
+	for class, n := range req.RateLimits {
+		if n < 0 {
+			return Key{}, "", fmt.Errorf("%w: rate limit of %s must not be negative", ErrInvalidKey, class)
+		}
+	}
+
+	now := time.Now().UTC()
+	k := Key{Name: req.Name, Role: req.Role, CreatedAt: now, RateLimits: req.RateLimits}
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			return Key{}, "", fmt.Errorf("%w: expires_in must be a positive duration such as 720h", ErrInvalidKey)
+		}
+		expires := now.Add(d)
+		k.ExpiresAt = &expires
+	}
+
+	secret := make([]byte, keyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, "", err
+	}
+	token := keyPrefix + hex.EncodeToString(secret)
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, "", err
+	}
+	k.ID = hex.EncodeToString(id)
+	k.Prefix = token[:displayPrefixLen]
+	k.Hash = hashKey(token)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[k.ID] = &k
+	s.byHash[k.Hash] = &k
+	if err := s.saveLocked(); err != nil {
+		delete(s.keys, k.ID)
+		delete(s.byHash, k.Hash)
+		return Key{}, "", fmt.Errorf("save keys: %w", err)
+	}
+	return k, token, nil
+}
+
+// Revoke deletes a key
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	delete(s.keys, id)
+	delete(s.byHash, k.Hash)
+	if err := s.saveLocked(); err != nil {
+		s.keys[id] = k
+		s.byHash[k.Hash] = k
+		return fmt.Errorf("save keys: %w", err)
+	}
+	return nil
+}
+
+// saveLocked writes the keys file, readable by its owner only; the caller
+// holds s.mu
+func (s *Store) saveLocked() error {
+	f := keysFile{Keys: make([]Key, 0, len(s.keys))}
+	for _, k := range s.keys {
+		f.Keys = append(f.Keys, *k)
+	}
+	sort.Slice(f.Keys, func(i, j int) bool { return f.Keys[i].CreatedAt.Before(f.Keys[j].CreatedAt) })
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func hashKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/forge/api/internal/config"
+)
+
+// ErrCertNoRole is returned for verified client certificates that map to
+// no role
+var ErrCertNoRole = fmt.Errorf("%w: client certificate maps to no forge role", ErrForbidden)
+
+// CertConfig maps verified TLS client certificates to identities
+type CertConfig struct {
+	// RoleMap maps certificate names (common name, DNS or URI SAN) and
+	// organizational units to forge roles
+	RoleMap map[string]string
+	// DefaultRole is given to certificates nothing maps; such clients are
+	// refused when empty
+	DefaultRole string
+}
+
+// NewCertConfig returns the client certificate settings of cfg; nil when
+// no client CA is set
+func NewCertConfig(cfg config.TLS) (*CertConfig, error) {
+	if cfg.ClientCAFile == "" {
+		return nil, nil
+	}
+	c := &CertConfig{DefaultRole: cfg.ClientRole}
+	if c.DefaultRole != "" && !ValidRole(c.DefaultRole) {
+		return nil, fmt.Errorf("tls.client_role: unknown role %q", c.DefaultRole)
+	}
+	roleMap, err := checkRoleMap("tls.client_role_map", cfg.ClientRoleMap)
+	if err != nil {
+		return nil, err
+	}
+	c.RoleMap = roleMap
+	return c, nil
+}
+
+// Identity returns the identity of the client certificate verified during
+// the handshake; nil when the connection has none. Certificates that map
+// to no role return ErrCertNoRole.
+func (c *CertConfig) Identity(state *tls.ConnectionState) (*Identity, error) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	cert := state.VerifiedChains[0][0]
+	name := certName(cert)
+
+	// The highest role any of the names maps to
+	role := c.RoleMap[name]
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if r, ok := c.RoleMap[ou]; ok && (role == "" || Allows(r, role)) {
+			role = r
+		}
+	}
+	if role == "" {
+		role = c.DefaultRole
+	}
+	if role == "" {
+		return nil, ErrCertNoRole
+	}
+	return &Identity{
+		KeyID:   "mtls",
+		Name:    name,
+		Role:    role,
+		Method:  "mtls",
+		Subject: cert.Subject.String(),
+	}, nil
+}
+
+// certName is the name a client certificate identifies: its common name,
+// else its first DNS or URI (e.g. SPIFFE ID) subject alternative name
+func certName(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return cert.SerialNumber.String()
+}
+
+type peerIdentityKey struct{}
+
+// WithPeerIdentity returns ctx carrying the identity of the connection's
+// client certificate or the request's session cookie, for auth.Interceptor
+// to use when an RPC has no token
+func WithPeerIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, peerIdentityKey{}, id)
+}
+
+// PeerIdentity returns the client certificate or session identity in ctx,
+// or nil
+func PeerIdentity(ctx context.Context) *Identity {
+	id, _ := ctx.Value(peerIdentityKey{}).(*Identity)
+	return id
+}
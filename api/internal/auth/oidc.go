@@ -0,0 +1,451 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // hashes for RS256/ES256/PS256
+	_ "crypto/sha512" // hashes for RS384/RS512/ES384/ES512
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+const (
+	// jwksRefresh is how long fetched signing keys are used
+	jwksRefresh = time.Hour
+	// jwksMinRefresh limits refetches for tokens signed with an unknown key
+	jwksMinRefresh = time.Minute
+	// clockLeeway tolerates clock differences with the identity provider
+	clockLeeway = time.Minute
+	// defaultRoleClaim is the claim mapped to roles when none is configured
+	defaultRoleClaim = "groups"
+)
+
+// ErrNoRole is returned for valid tokens whose claims map to no role
+var ErrNoRole = fmt.Errorf("%w: token claims map to no forge role", ErrForbidden)
+
+// OIDCConfig configures JWT validation against an identity provider
+type OIDCConfig struct {
+	Issuer   string // iss claim, and where the JWKS is discovered
+	Audience string // required aud value, usually the client ID
+	JWKSURL  string // signing keys; discovered from the issuer when empty
+	// RoleClaim is the claim holding groups or roles, dotted for nested
+	// claims (e.g. realm_access.roles)
+	RoleClaim string
+	// RoleMap maps claim values to forge roles. Only mapped values give a
+	// role, so a group the IdP happens to call "admin" gives none.
+	RoleMap map[string]string
+	// DefaultRole is given to valid tokens no claim value maps; such tokens
+	// are refused when empty
+	DefaultRole string
+}
+
+// NewOIDCConfig returns the OIDC settings of cfg; nil when no issuer is
+// set. An audience is required with it, as without an audience check tokens
+// the issuer made for any other client would be accepted.
+func NewOIDCConfig(cfg config.OIDC) (*OIDCConfig, error) {
+	if cfg.Issuer == "" {
+		return nil, nil
+	}
+	if cfg.Audience == "" {
+		return nil, errors.New("auth.oidc.audience is required with auth.oidc.issuer")
+	}
+	c := &OIDCConfig{
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+		JWKSURL:     cfg.JWKSURL,
+		RoleClaim:   cfg.RoleClaim,
+		DefaultRole: cfg.DefaultRole,
+	}
+	if c.RoleClaim == "" {
+		c.RoleClaim = defaultRoleClaim
+	}
+	if c.DefaultRole != "" && !ValidRole(c.DefaultRole) {
+		return nil, fmt.Errorf("auth.oidc.default_role: unknown role %q", c.DefaultRole)
+	}
+	roleMap, err := checkRoleMap("auth.oidc.role_map", cfg.RoleMap)
+	if err != nil {
+		return nil, err
+	}
+	c.RoleMap = roleMap
+	return c, nil
+}
+
+// checkRoleMap checks that a value=role map names only roles
+func checkRoleMap(name string, m map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(m))
+	for value, role := range m {
+		if !ValidRole(role) {
+			return nil, fmt.Errorf("%s: %q is not value=read|write|admin", name, value+"="+role)
+		}
+		out[value] = role
+	}
+	return out, nil
+}
+
+// JWTVerifier validates JWTs issued by an OIDC identity provider
+type JWTVerifier struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	jwksURL   string
+	keys      map[string]crypto.PublicKey // by kid
+	fetchedAt time.Time
+}
+
+// NewJWTVerifier creates a verifier; signing keys are fetched on first use
+func NewJWTVerifier(cfg OIDCConfig) *JWTVerifier {
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = defaultRoleClaim
+	}
+	return &JWTVerifier{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		jwksURL: cfg.JWKSURL,
+	}
+}
+
+// LooksLikeJWT reports whether token has the three parts of a JWS
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the token's signature, issuer, audience and lifetime, and
+// maps its claims to a role
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrUnauthenticated
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed token header", ErrUnauthenticated)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token signature", ErrUnauthenticated)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrUnauthenticated)
+	}
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	role := v.role(claims)
+	if role == "" {
+		return nil, ErrNoRole
+	}
+	sub, _ := claims["sub"].(string)
+	return &Identity{
+		KeyID:   "oidc",
+		Name:    displayName(claims),
+		Role:    role,
+		Method:  "oidc",
+		Subject: sub,
+	}, nil
+}
+
+// checkClaims validates iss, aud, exp and nbf
+func (v *JWTVerifier) checkClaims(claims map[string]any, now time.Time) error {
+	if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if v.cfg.Audience == "" || !contains(stringValues(claims["aud"]), v.cfg.Audience) {
+		return errors.New("token is not for this audience")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockLeeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	return nil
+}
+
+// role returns the highest role the role claim maps to
+func (v *JWTVerifier) role(claims map[string]any) string {
+	var value any = claims
+	for _, name := range strings.Split(v.cfg.RoleClaim, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			value = nil
+			break
+		}
+		value = m[name]
+	}
+
+	best := ""
+	for _, s := range stringValues(value) {
+		if role := v.cfg.RoleMap[s]; role != "" && roleRank[role] > roleRank[best] {
+			best = role
+		}
+	}
+	if best == "" {
+		return v.cfg.DefaultRole
+	}
+	return best
+}
+
+// displayName picks a readable name for the caller
+func displayName(claims map[string]any) string {
+	for _, c := range []string{"preferred_username", "email", "name", "sub"} {
+		if s, ok := claims[c].(string); ok && s != "" {
+			return s
+		}
+	}
+	return "oidc"
+}
+
+// key returns the signing key kid, fetching the JWKS when it is stale or
+// does not have kid
+func (v *JWTVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.lookup(kid)
+	age := time.Since(v.fetchedAt)
+	if ok && age < jwksRefresh {
+		return key, nil
+	}
+	if ok || age >= jwksMinRefresh {
+		if err := v.fetchLocked(ctx); err != nil {
+			if ok {
+				return key, nil // keep using known keys while the provider is unreachable
+			}
+			return nil, fmt.Errorf("OIDC signing keys: %w", err)
+		}
+		key, ok = v.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrUnauthenticated, kid)
+	}
+	return key, nil
+}
+
+// lookup finds kid in the cached keys; a token without kid matches the
+// only key of a single-key JWKS
+func (v *JWTVerifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			return k, true
+		}
+	}
+	k, ok := v.keys[kid]
+	return k, ok
+}
+
+// fetchLocked downloads the JWKS, discovering its URL from the issuer
+// first if needed; the caller holds v.mu
+func (v *JWTVerifier) fetchLocked(ctx context.Context) error {
+	v.fetchedAt = time.Now()
+	if v.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return err
+		}
+		if discovery.JWKSURI == "" {
+			return errors.New("issuer discovery has no jwks_uri")
+		}
+		v.jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURL, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no usable signing keys")
+	}
+	v.keys = keys
+	return nil
+}
+
+func (v *JWTVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jwk is an RSA or EC public key of a JWKS
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifySignature checks a JWS signature. Only asymmetric algorithms are
+// accepted, so a token cannot be signed with a public key as HMAC secret.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch alg[0] {
+	case 'R', 'P':
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("signing key is not an RSA key")
+		}
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(pub, hash, digest, sig, nil)
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+	default:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("signing key is not an EC key")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+}
+
+func decodeSegment(seg string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// stringValues returns a claim that is a string or a list of strings
+func stringValues(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+func TestJWTVerifierRole(t *testing.T) {
+	roleMap := map[string]string{"forge-admins": RoleAdmin, "developers": RoleWrite, "staff": RoleRead}
+
+	tests := []struct {
+		name   string
+		cfg    OIDCConfig
+		claims map[string]any
+		want   string
+	}{
+		{
+			name:   "mapped group",
+			cfg:    OIDCConfig{RoleMap: roleMap},
+			claims: map[string]any{"groups": []any{"developers"}},
+			want:   RoleWrite,
+		},
+		{
+			name:   "highest mapped role wins",
+			cfg:    OIDCConfig{RoleMap: roleMap},
+			claims: map[string]any{"groups": []any{"staff", "forge-admins", "developers"}},
+			want:   RoleAdmin,
+		},
+		{
+			name:   "single string claim",
+			cfg:    OIDCConfig{RoleMap: roleMap},
+			claims: map[string]any{"groups": "staff"},
+			want:   RoleRead,
+		},
+		{
+			name:   "unmapped group named admin gives no role",
+			cfg:    OIDCConfig{RoleMap: roleMap},
+			claims: map[string]any{"groups": []any{"admin"}},
+			want:   "",
+		},
+		{
+			name:   "unmapped group named admin gets the default role",
+			cfg:    OIDCConfig{RoleMap: roleMap, DefaultRole: RoleRead},
+			claims: map[string]any{"groups": []any{"admin", "write"}},
+			want:   RoleRead,
+		},
+		{
+			name:   "no map gives only the default role",
+			cfg:    OIDCConfig{DefaultRole: RoleRead},
+			claims: map[string]any{"groups": []any{"admin"}},
+			want:   RoleRead,
+		},
+		{
+			name:   "nested claim",
+			cfg:    OIDCConfig{RoleClaim: "realm_access.roles", RoleMap: roleMap},
+			claims: map[string]any{"realm_access": map[string]any{"roles": []any{"forge-admins"}}},
+			want:   RoleAdmin,
+		},
+		{
+			name:   "missing nested claim",
+			cfg:    OIDCConfig{RoleClaim: "realm_access.roles", RoleMap: roleMap},
+			claims: map[string]any{"realm_access": "forge-admins"},
+			want:   "",
+		},
+		{
+			name:   "non-string values ignored",
+			cfg:    OIDCConfig{RoleMap: roleMap},
+			claims: map[string]any{"groups": []any{1.0, true, "developers"}},
+			want:   RoleWrite,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewJWTVerifier(tt.cfg)
+			if got := v.role(tt.claims); got != tt.want {
+				t.Errorf("role() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierCheckClaims(t *testing.T) {
+	const issuer = "https://id.example.com"
+	now := time.Unix(1700000000, 0)
+	exp := float64(now.Add(time.Hour).Unix())
+
+	tests := []struct {
+		name     string
+		audience string
+		claims   map[string]any
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": "forge", "exp": exp},
+		},
+		{
+			name:     "audience in list",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": []any{"other", "forge"}, "exp": exp},
+		},
+		{
+			name:     "other audience",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": "other", "exp": exp},
+			wantErr:  true,
+		},
+		{
+			name:     "no audience configured",
+			audience: "",
+			claims:   map[string]any{"iss": issuer, "aud": "other", "exp": exp},
+			wantErr:  true,
+		},
+		{
+			name:     "other issuer",
+			audience: "forge",
+			claims:   map[string]any{"iss": "https://evil.example.com", "aud": "forge", "exp": exp},
+			wantErr:  true,
+		},
+		{
+			name:     "no expiry",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": "forge"},
+			wantErr:  true,
+		},
+		{
+			name:     "expired beyond leeway",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": "forge", "exp": float64(now.Add(-2 * clockLeeway).Unix())},
+			wantErr:  true,
+		},
+		{
+			name:     "expired within leeway",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": "forge", "exp": float64(now.Add(-clockLeeway / 2).Unix())},
+		},
+		{
+			name:     "not valid yet",
+			audience: "forge",
+			claims:   map[string]any{"iss": issuer, "aud": "forge", "exp": exp, "nbf": float64(now.Add(2 * clockLeeway).Unix())},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewJWTVerifier(OIDCConfig{Issuer: issuer, Audience: tt.audience})
+			err := v.checkClaims(tt.claims, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkClaims() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewOIDCConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.OIDC
+		wantNil  bool
+		wantErr  bool
+		wantRole map[string]string
+	}{
+		{
+			name:    "disabled",
+			wantNil: true,
+		},
+		{
+			name:    "audience required",
+			cfg:     config.OIDC{Issuer: "https://id.example.com"},
+			wantErr: true,
+		},
+		{
+			name: "role map",
+			cfg: config.OIDC{
+				Issuer:   "https://id.example.com",
+				Audience: "forge",
+				RoleMap:  map[string]string{"forge-admins": "admin", "developers": "write"},
+			},
+			wantRole: map[string]string{"forge-admins": RoleAdmin, "developers": RoleWrite},
+		},
+		{
+			name: "invalid role in map",
+			cfg: config.OIDC{
+				Issuer:   "https://id.example.com",
+				Audience: "forge",
+				RoleMap:  map[string]string{"forge-admins": "owner"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid default role",
+			cfg: config.OIDC{
+				Issuer:      "https://id.example.com",
+				Audience:    "forge",
+				DefaultRole: "owner",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewOIDCConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOIDCConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (cfg == nil) != tt.wantNil {
+				t.Fatalf("NewOIDCConfig() = %+v, wantNil %v", cfg, tt.wantNil)
+			}
+			if cfg == nil {
+				return
+			}
+			if cfg.RoleClaim != defaultRoleClaim {
+				t.Errorf("RoleClaim = %q, want %q", cfg.RoleClaim, defaultRoleClaim)
+			}
+			if len(cfg.RoleMap) != len(tt.wantRole) {
+				t.Fatalf("RoleMap = %v, want %v", cfg.RoleMap, tt.wantRole)
+			}
+			for k, want := range tt.wantRole {
+				if cfg.RoleMap[k] != want {
+					t.Errorf("RoleMap[%q] = %q, want %q", k, cfg.RoleMap[k], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// SessionCookie holds the session token; HttpOnly, so scripts cannot
+	// read it
+	SessionCookie = "forge_session"
+	// CSRFCookie holds the session's CSRF token for the web UI to echo in
+	// CSRFHeader
+	CSRFCookie = "forge_csrf"
+	// CSRFHeader must carry the CSRF token on requests that change state
+	CSRFHeader = "X-CSRF-Token"
+	// DefaultSessionTTL is how long a login lasts
+	DefaultSessionTTL = 12 * time.Hour
+
+	sessionPrefix      = "fs_"
+	sessionBytes       = 32
+	bcryptCost         = 12
+	minPasswordLength  = 12
+	maxPasswordLength  = 72 // bcrypt ignores longer input
+	sessionPruneWindow = 10 * time.Minute
+)
+
+var (
+	// ErrUserNotFound is returned for unknown usernames
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidUser is returned for user requests that cannot be applied
+	ErrInvalidUser = errors.New("invalid user request")
+	// ErrInvalidLogin is returned for wrong usernames or passwords
+	ErrInvalidLogin = errors.New("invalid username or password")
+	// ErrCSRF is returned for session requests without a matching CSRF token
+	ErrCSRF = fmt.Errorf("%w: missing or invalid %s header", ErrForbidden, CSRFHeader)
+
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.@-]{1,64}$`)
+)
+
+// User is a web UI account. The password is only stored as a bcrypt hash.
+type User struct {
+	Username    string     `json:"username"`
+	Role        string     `json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// UserRequest creates or updates a user; empty fields are left unchanged
+// on update
+type UserRequest struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"` // read, write or admin
+}
+
+// Session is a login. The token is only known when the session is created.
+type Session struct {
+	Token     string    `json:"-"`
+	CSRFToken string    `json:"csrf_token"`
+	User      User      `json:"user"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore keeps users and their sessions in MySQL. Only SHA-256
+// hashes of session tokens are stored.
+type SessionStore struct {
+	db       *sql.DB
+	users    string
+	sessions string
+	ttl      time.Duration
+	// dummyHash is compared against for unknown users, so logins take as
+	// long whether or not the user exists
+	dummyHash []byte
+	hasUsers  atomic.Bool
+}
+
+// NewSessionStore prepares the user and session tables in database.
+// Sessions last ttl (DefaultSessionTTL when zero).
+func NewSessionStore(db *sql.DB, database string, ttl time.Duration) (*SessionStore, error) {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	dummy, err := bcrypt.GenerateFromPassword([]byte("forge-dummy-password"), bcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	s := &SessionStore{
+		db:        db,
+		users:     fmt.Sprintf("`%s`.`users`", database),
+		sessions:  fmt.Sprintf("`%s`.`sessions`", database),
+		ttl:       ttl,
+		dummyHash: dummy,
+	}
+	ctx := context.Background()
+	if err := s.migrate(ctx, database); err != nil {
+		return nil, fmt.Errorf("failed to create session tables: %w", err)
+	}
+	if err := s.countUsers(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate creates the database and tables if missing
+func (s *SessionStore) migrate(ctx context.Context, database string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database)); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.users+` (
+		username VARCHAR(64) PRIMARY KEY,
+		password_hash VARCHAR(60) NOT NULL,
+		role VARCHAR(16) NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL,
+		last_login_at DATETIME(3) NULL
+	)`); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.sessions+` (
+		token_hash CHAR(64) PRIMARY KEY,
+		username VARCHAR(64) NOT NULL,
+		csrf_token VARCHAR(64) NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		expires_at DATETIME(3) NOT NULL,
+		INDEX idx_username (username),
+		INDEX idx_expires_at (expires_at)
+	)`)
+	return err
+}
+
+func (s *SessionStore) countUsers(ctx context.Context) error {
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+s.users).Scan(&n); err != nil {
+		return err
+	}
+	s.hasUsers.Store(n > 0)
+	return nil
+}
+
+// HasUsers reports whether any user exists; Store.Enforced uses it
+func (s *SessionStore) HasUsers() bool {
+	return s.hasUsers.Load()
+}
+
+// ValidateBootstrap checks the bootstrap admin's username and password,
+// so a bad FORGE_ADMIN_PASSWORD fails at startup rather than once MySQL
+// connects
+func ValidateBootstrap(username, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: username must be 1-64 letters, digits or _.@-", ErrInvalidUser)
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: password must be %d-%d bytes", ErrInvalidUser, minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// Bootstrap creates an admin user when there are no users yet, so the
+// first login needs no API key. It reports whether the user was created.
+func (s *SessionStore) Bootstrap(ctx context.Context, username, password string) (bool, error) {
+	if s.HasUsers() {
+		return false, nil
+	}
+	if _, err := s.CreateUser(ctx, UserRequest{Username: username, Password: password, Role: RoleAdmin}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Users lists users by name
+func (s *SessionStore) Users(ctx context.Context) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT username, role, created_at, updated_at, last_login_at FROM "+s.users+" ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// CreateUser adds a user with a password and role
+func (s *SessionStore) CreateUser(ctx context.Context, req UserRequest) (User, error) {
+	if !usernamePattern.MatchString(req.Username) {
+		return User{}, fmt.Errorf("%w: username must be 1-64 letters, digits or _.@-", ErrInvalidUser)
+	}
+	if !ValidRole(req.Role) {
+		return User{}, fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidUser, RoleRead, RoleWrite, RoleAdmin)
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return User{}, err
+	}
+
+	now := time.Now().UTC()
+	res, err := s.db.ExecContext(ctx, "INSERT IGNORE INTO "+s.users+
+		" (username, password_hash, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		req.Username, hash, req.Role, now, now)
+	if err != nil {
+		return User{}, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return User{}, fmt.Errorf("%w: user %s already exists", ErrInvalidUser, req.Username)
+	}
+	s.hasUsers.Store(true)
+	return User{Username: req.Username, Role: req.Role, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+// UpdateUser changes a user's password and/or role. The user's sessions
+// end, so a new password or lower role applies at once.
+func (s *SessionStore) UpdateUser(ctx context.Context, username string, req UserRequest) (User, error) {
+	if req.Password == "" && req.Role == "" {
+		return User{}, fmt.Errorf("%w: set password or role", ErrInvalidUser)
+	}
+	if req.Role != "" && !ValidRole(req.Role) {
+		return User{}, fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidUser, RoleRead, RoleWrite, RoleAdmin)
+	}
+	var hash string
+	if req.Password != "" {
+		var err error
+		if hash, err = hashPassword(req.Password); err != nil {
+			return User{}, err
+		}
+	}
+
+	res, err := s.db.ExecContext(ctx, "UPDATE "+s.users+
+		" SET password_hash = IF(? = '', password_hash, ?), role = IF(? = '', role, ?), updated_at = ? WHERE username = ?",
+		hash, hash, req.Role, req.Role, time.Now().UTC(), username)
+	if err != nil {
+		return User{}, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return User{}, ErrUserNotFound
+	}
+	if err := s.endSessions(ctx, username); err != nil {
+		return User{}, err
+	}
+	return s.user(ctx, username)
+}
+
+// DeleteUser removes a user and ends their sessions
+func (s *SessionStore) DeleteUser(ctx context.Context, username string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM "+s.users+" WHERE username = ?", username)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	if err := s.endSessions(ctx, username); err != nil {
+		return err
+	}
+	return s.countUsers(ctx)
+}
+
+func (s *SessionStore) user(ctx context.Context, username string) (User, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT username, role, created_at, updated_at, last_login_at FROM "+s.users+" WHERE username = ?", username)
+	u, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	var lastLogin sql.NullTime
+	if err := row.Scan(&u.Username, &u.Role, &u.CreatedAt, &u.UpdatedAt, &lastLogin); err != nil {
+		return User{}, err
+	}
+	if lastLogin.Valid {
+		u.LastLoginAt = &lastLogin.Time
+	}
+	return u, nil
+}
+
+// Login checks a username and password and starts a session
+func (s *SessionStore) Login(ctx context.Context, username, password string) (*Session, error) {
+	var u User
+	var hash string
+	var lastLogin sql.NullTime
+	err := s.db.QueryRowContext(ctx, "SELECT username, password_hash, role, created_at, updated_at, last_login_at FROM "+s.users+" WHERE username = ?", username).
+		Scan(&u.Username, &hash, &u.Role, &u.CreatedAt, &u.UpdatedAt, &lastLogin)
+	if errors.Is(err, sql.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, ErrInvalidLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return nil, ErrInvalidLogin
+	}
+
+	token, err := randomToken(sessionPrefix)
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomToken("")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.ttl)
+	if _, err := s.db.ExecContext(ctx, "INSERT INTO "+s.sessions+
+		" (token_hash, username, csrf_token, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		hashKey(token), u.Username, csrf, now, expiresAt); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, "UPDATE "+s.users+" SET last_login_at = ? WHERE username = ?", now, u.Username); err != nil {
+		return nil, err
+	}
+	u.LastLoginAt = &now
+	return &Session{Token: token, CSRFToken: csrf, User: u, ExpiresAt: expiresAt}, nil
+}
+
+// Session returns the unexpired session of a token
+func (s *SessionStore) Session(ctx context.Context, token string) (*Session, error) {
+	var sess Session
+	var lastLogin sql.NullTime
+	err := s.db.QueryRowContext(ctx, `SELECT u.username, u.role, u.created_at, u.updated_at, u.last_login_at, s.csrf_token, s.expires_at
+		FROM `+s.sessions+` s JOIN `+s.users+` u ON u.username = s.username
+		WHERE s.token_hash = ? AND s.expires_at > ?`, hashKey(token), time.Now().UTC()).
+		Scan(&sess.User.Username, &sess.User.Role, &sess.User.CreatedAt, &sess.User.UpdatedAt, &lastLogin, &sess.CSRFToken, &sess.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastLogin.Valid {
+		sess.User.LastLoginAt = &lastLogin.Time
+	}
+	return &sess, nil
+}
+
+// Logout ends the session of a token
+func (s *SessionStore) Logout(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM "+s.sessions+" WHERE token_hash = ?", hashKey(token))
+	return err
+}
+
+func (s *SessionStore) endSessions(ctx context.Context, username string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM "+s.sessions+" WHERE username = ?", username)
+	return err
+}
+
+// Identity returns the identity of a session token. Requests that change
+// state must carry the session's CSRF token in the X-CSRF-Token header;
+// the session cookie alone is sent by the browser on any request.
+func (s *SessionStore) Identity(r *http.Request, token string) (*Identity, error) {
+	sess, err := s.Session(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		header := r.Header.Get(CSRFHeader)
+		if header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(sess.CSRFToken)) != 1 {
+			return nil, ErrCSRF
+		}
+	}
+	return &Identity{
+		KeyID:   "session",
+		Name:    sess.User.Username,
+		Role:    sess.User.Role,
+		Method:  "session",
+		Subject: sess.User.Username,
+	}, nil
+}
+
+// Run deletes expired sessions until ctx is done
+func (s *SessionStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(sessionPruneWindow)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM "+s.sessions+" WHERE expires_at <= ?", time.Now().UTC()); err != nil && ctx.Err() == nil {
+			logger.Error("Failed to prune expired sessions", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", fmt.Errorf("%w: password must be %d-%d bytes", ErrInvalidUser, minPasswordLength, maxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, sessionBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
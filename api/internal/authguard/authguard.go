@@ -0,0 +1,299 @@
+// Package authguard slows down credential guessing against the API
+//
+// Failed authentications are counted per client: the source IP, the API
+// key or token presented, and the username of web UI logins. A client with
+// Threshold failures within Window is locked out, for Lockout the first
+// time and twice as long on each further lockout within a day, up to
+// MaxLockout. Locked clients are refused before their credentials are
+// checked. Counters live in Redis when available, so replicas share them,
+// and in memory otherwise.
+//
+// Lockouts and bursts of failures across all clients (SpikeThreshold per
+// Window) raise Alertmanager alerts that resolve on their own.
+package authguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/alerting"
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+const (
+	// strikeMemory is how long past lockouts count towards the next one
+	strikeMemory = 24 * time.Hour
+	// alertTimeout bounds alert delivery, which runs in the background
+	alertTimeout = 10 * time.Second
+	// alertSourceLabel marks alerts raised by the guard
+	alertSourceLabel = "forge_auth_guard"
+	keyPrefix        = "forge:authguard:"
+)
+
+// Client kinds
+const (
+	KindIP   = "ip"
+	KindKey  = "key"
+	KindUser = "user"
+)
+
+// Config sets the failure thresholds and lockout lengths
+type Config struct {
+	Threshold  int           // failures within Window that lock a client out
+	Window     time.Duration // how long failures are counted
+	Lockout    time.Duration // length of the first lockout
+	MaxLockout time.Duration // longest lockout
+	// SpikeThreshold is the number of failures across all clients within
+	// Window that raises an alert; 0 disables the alert
+	SpikeThreshold int
+}
+
+// Backend stores counters and lockouts; *cache.RedisClient implements it
+type Backend interface {
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+// Notifier delivers alerts; *alerting.Client implements it
+type Notifier interface {
+	PostAlerts(ctx context.Context, alerts []alerting.PostableAlert) error
+}
+
+// Client is a kind and ID that failures are counted for
+type Client struct {
+	Kind string
+	ID   string
+}
+
+func (c Client) String() string {
+	return c.Kind + ":" + c.ID
+}
+
+// Guard tracks failures and lockouts
+type Guard struct {
+	cfg Config
+	// shared, when set, is used while sharedUp reports it reachable;
+	// otherwise counters are kept in local
+	shared   Backend
+	sharedUp func() bool
+	local    Backend
+
+	mu       sync.RWMutex
+	notifier Notifier
+}
+
+// New creates a guard counting in shared while sharedUp reports it
+// reachable and in memory otherwise
+func New(cfg Config, shared Backend, sharedUp func() bool) *Guard {
+	return &Guard{cfg: cfg, shared: shared, sharedUp: sharedUp, local: newMemoryBackend()}
+}
+
+// backend returns where counters are kept right now, so Redis is used
+// once it comes up even if it was down at startup
+func (g *Guard) backend() Backend {
+	if g.shared != nil && g.sharedUp() {
+		return g.shared
+	}
+	return g.local
+}
+
+// FromConfig creates a guard from cfg; nil when lockouts are disabled.
+// shared, when set, holds the counters while sharedUp reports it
+// reachable.
+func FromConfig(cfg config.Guard, shared Backend, sharedUp func() bool) *Guard {
+	if cfg.Threshold == 0 {
+		return nil
+	}
+	c := Config{
+		Threshold:      cfg.Threshold,
+		Window:         cfg.Window,
+		Lockout:        cfg.Lockout,
+		MaxLockout:     max(cfg.MaxLockout, cfg.Lockout),
+		SpikeThreshold: cfg.Spike,
+	}
+	return New(c, shared, sharedUp)
+}
+
+// Config returns the guard's settings
+func (g *Guard) Config() Config {
+	return g.cfg
+}
+
+// SetNotifier sends lockout and spike alerts through n
+func (g *Guard) SetNotifier(n Notifier) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.notifier = n
+}
+
+// KeyClient identifies a presented API key or token without keeping it
+func KeyClient(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Locked reports whether any of the clients is locked out, and until when.
+// A backend that fails lets the request through rather than failing the
+// API with Redis.
+func (g *Guard) Locked(ctx context.Context, clients ...Client) (bool, time.Time) {
+	var until time.Time
+	for _, c := range clients {
+		v, ok, err := g.backend().Get(ctx, keyPrefix+"lock:"+c.String())
+		if err != nil {
+			lg := logger.Get()
+			lg.Warn().Err(err).Msg("Auth guard counters unavailable")
+			return false, time.Time{}
+		}
+		if !ok {
+			continue
+		}
+		unix, _ := strconv.ParseInt(v, 10, 64)
+		if t := time.Unix(unix, 0); t.After(until) {
+			until = t
+		}
+	}
+	if until.After(time.Now()) {
+		metrics.AuthLockedTotal.Inc()
+		return true, until
+	}
+	return false, time.Time{}
+}
+
+// Fail records a failed authentication of kind (key or login) by clients,
+// locking out those over the threshold
+func (g *Guard) Fail(ctx context.Context, kind string, clients ...Client) {
+	metrics.AuthFailuresTotal.WithLabelValues(kind).Inc()
+	if g.cfg.SpikeThreshold > 0 {
+		// Bucketed by window so the count starts over, like the rate limiter
+		bucket := time.Now().Truncate(g.cfg.Window).Unix()
+		n, err := g.backend().Incr(ctx, fmt.Sprintf("%sfailures:all:%d", keyPrefix, bucket), g.cfg.Window)
+		if err == nil && n == int64(g.cfg.SpikeThreshold) {
+			g.alert(spikeAlert(g.cfg, n))
+		}
+	}
+
+	g.Track(ctx, clients...)
+}
+
+// Track counts a failure against clients without recording another
+// attempt, for clients Fail does not know, e.g. the username of a failed
+// login
+func (g *Guard) Track(ctx context.Context, clients ...Client) {
+	for _, c := range clients {
+		if c.ID == "" {
+			continue
+		}
+		failures := keyPrefix + "failures:" + c.String()
+		n, err := g.backend().Incr(ctx, failures, g.cfg.Window)
+		if err != nil {
+			lg := logger.Get()
+			lg.Warn().Err(err).Msg("Auth guard counters unavailable")
+			return
+		}
+		if n < int64(g.cfg.Threshold) {
+			continue
+		}
+		g.backend().Delete(ctx, failures)
+		g.lock(ctx, c, n)
+	}
+}
+
+// Succeed forgets the failures of clients, e.g. a username after a
+// successful login
+func (g *Guard) Succeed(ctx context.Context, clients ...Client) {
+	for _, c := range clients {
+		g.backend().Delete(ctx, keyPrefix+"failures:"+c.String())
+	}
+}
+
+// lock locks a client out, for twice as long as its previous lockout
+func (g *Guard) lock(ctx context.Context, c Client, failures int64) {
+	strikes, err := g.backend().Incr(ctx, keyPrefix+"strikes:"+c.String(), strikeMemory)
+	if err != nil {
+		strikes = 1
+	}
+	lockout := g.cfg.Lockout
+	for i := int64(1); i < strikes && lockout < g.cfg.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > g.cfg.MaxLockout {
+		lockout = g.cfg.MaxLockout
+	}
+	until := time.Now().Add(lockout).Truncate(time.Second).Add(time.Second)
+	if err := g.backend().Set(ctx, keyPrefix+"lock:"+c.String(), strconv.FormatInt(until.Unix(), 10), time.Until(until)); err != nil {
+		lg := logger.Get()
+		lg.Warn().Err(err).Msg("Auth guard counters unavailable")
+		return
+	}
+
+	metrics.AuthLockoutsTotal.WithLabelValues(c.Kind).Inc()
+	lg := logger.Get()
+	lg.Warn().
+		Str("client", c.String()).
+		Int64("failures", failures).
+		Int64("strike", strikes).
+		Time("until", until).
+		Msg("Locked out client after repeated authentication failures")
+	g.alert(lockoutAlert(c, failures, strikes, until))
+}
+
+// alert delivers an alert in the background, so requests do not wait on
+// Alertmanager
+func (g *Guard) alert(a alerting.PostableAlert) {
+	g.mu.RLock()
+	n := g.notifier
+	g.mu.RUnlock()
+	if n == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+		defer cancel()
+		if err := n.PostAlerts(ctx, []alerting.PostableAlert{a}); err != nil {
+			logger.Error("Failed to send auth guard alert", err)
+		}
+	}()
+}
+
+func lockoutAlert(c Client, failures, strikes int64, until time.Time) alerting.PostableAlert {
+	return alerting.PostableAlert{
+		Labels: map[string]string{
+			"alertname":      "ForgeAuthLockout",
+			"severity":       "warning",
+			"client_kind":    c.Kind,
+			"client":         c.ID,
+			alertSourceLabel: "true",
+		},
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%s %s locked out after %d failed authentication attempts", c.Kind, c.ID, failures),
+			"description": fmt.Sprintf("Lockout %d within a day, until %s", strikes, until.UTC().Format(time.RFC3339)),
+		},
+		StartsAt: time.Now(),
+		EndsAt:   until,
+	}
+}
+
+func spikeAlert(cfg Config, failures int64) alerting.PostableAlert {
+	return alerting.PostableAlert{
+		Labels: map[string]string{
+			"alertname":      "ForgeAuthFailureSpike",
+			"severity":       "critical",
+			alertSourceLabel: "true",
+		},
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%d failed authentication attempts within %s", failures, cfg.Window),
+			"description": "Failures across all clients; a distributed guessing attack may be under way",
+		},
+		StartsAt: time.Now(),
+		EndsAt:   time.Now().Add(cfg.Window),
+	}
+}
@@ -0,0 +1,85 @@
+package authguard
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// gcInterval is how often expired entries are dropped
+const gcInterval = time.Minute
+
+// memoryBackend keeps counters in this process, for single-replica setups
+// without Redis
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	lastGC  time.Time
+}
+
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{entries: make(map[string]*entry)}
+}
+
+// live returns the unexpired entry of key; b.mu must be held
+func (b *memoryBackend) live(key string, now time.Time) (*entry, bool) {
+	if now.Sub(b.lastGC) > gcInterval {
+		for k, e := range b.entries {
+			if !now.Before(e.expires) {
+				delete(b.entries, k)
+			}
+		}
+		b.lastGC = now
+	}
+	e, ok := b.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil, false
+	}
+	return e, true
+}
+
+func (b *memoryBackend) Incr(_ context.Context, key string, window time.Duration) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	e, ok := b.live(key, now)
+	if !ok {
+		e = &entry{value: "0", expires: now.Add(window)}
+		b.entries[key] = e
+	}
+	n, _ := strconv.ParseInt(e.value, 10, 64)
+	n++
+	e.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (b *memoryBackend) Get(_ context.Context, key string) (string, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.live(key, time.Now())
+	if !ok {
+		return "", false, nil
+	}
+	return e.value, true, nil
+}
+
+func (b *memoryBackend) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[key] = &entry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (b *memoryBackend) Delete(_ context.Context, key string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.entries[key]
+	delete(b.entries, key)
+	return ok, nil
+}
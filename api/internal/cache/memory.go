@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Store is the key/value interface shared by Redis and the in-memory fallback
+type Store interface {
+	Ping(ctx context.Context) error
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+// DefaultMemoryMaxKeys bounds the in-memory fallback so it cannot exhaust the API's memory
+const DefaultMemoryMaxKeys = 10000
+
+// ErrMemoryFull is returned when the in-memory fallback has no room for new keys
+var ErrMemoryFull = errors.New("in-memory cache is full")
+
+type memoryEntry struct {
+	value   string
+	expires time.Time // zero means no expiry
+}
+
+// MemoryStore is a process-local cache used when Redis is unavailable.
+// Data is lost on restart and not shared between API instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	maxKeys int
+}
+
+// NewMemoryStore creates an in-memory store holding at most maxKeys keys
+func NewMemoryStore(maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		maxKeys: maxKeys,
+	}
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return "", false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entries[key]; !exists && len(m.entries) >= m.maxKeys {
+		m.evictExpired()
+		if len(m.entries) >= m.maxKeys {
+			return ErrMemoryFull
+		}
+	}
+
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	m.entries[key] = e
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.entries[key]
+	delete(m.entries, key)
+	return ok, nil
+}
+
+// evictExpired removes expired entries; caller must hold the lock
+func (m *MemoryStore) evictExpired() {
+	now := time.Now()
+	for k, e := range m.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package cache
 
 import (
 	"context"
-	"os"
 	"time"
 
+	"github.com/forge/api/internal/config"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -12,52 +12,17 @@ type RedisClient struct {
 	client *redis.Client
 }
 
-func NewRedisClient() (*RedisClient, error) {
-	host := os.Getenv("REDIS_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	port := os.Getenv("REDIS_PORT")
-	if port == "" {
-		port = "6379"
-	}
-	password := os.Getenv("REDIS_PASSWORD")
-
+// NewRedisClient creates a client for the server in cfg. It does not
+// connect; go-redis dials on first use and after failures, and a
+// deps.Supervisor tracks whether Redis is reachable.
+func NewRedisClient(cfg config.Redis) *RedisClient {
 	client := redis.NewClient(&redis.Options{
-		Addr:     host + ":" + port,
-		Password: password,
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
 		DB:       0,
 	})
-
-	// Retry connection with backoff (Redis might still be starting)
-	maxRetries := 10
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		err := client.Ping(ctx).Err()
-		cancel()
-		if err == nil {
-			return &RedisClient{client: client}, nil
-		} else {
-			lastErr = err
-		}
-
-		// Wait before retrying (1s, 2s, 3s, ... up to 5s), but not after the last attempt
-		if i < maxRetries-1 {
-			waitTime := time.Duration(min(i+1, 5)) * time.Second
-			time.Sleep(waitTime)
-		}
-	}
-
-	client.Close() // Clean up the client since we failed to connect
-	return nil, lastErr
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	client.AddHook(tracingHook{})
+	return &RedisClient{client: client}
 }
 
 func (c *RedisClient) Ping(ctx context.Context) error {
@@ -79,6 +44,11 @@ func (c *RedisClient) Set(ctx context.Context, key, value string, ttl time.Durat
 	return c.client.Set(ctx, key, value, ttl).Err()
 }
 
+// SetNX sets key only if it does not exist, and reports whether it did
+func (c *RedisClient) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, ttl).Result()
+}
+
 func (c *RedisClient) Delete(ctx context.Context, key string) (bool, error) {
 	result, err := c.client.Del(ctx, key).Result()
 	if err != nil {
@@ -87,6 +57,86 @@ func (c *RedisClient) Delete(ctx context.Context, key string) (bool, error) {
 	return result > 0, nil
 }
 
+// Incr increments a counter that expires window after its first increment,
+// for fixed-window rate limits
+func (c *RedisClient) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := c.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// RPush appends value to the list at key, trimming it to its newest maxLen
+// items, and returns the length of the list
+func (c *RedisClient) RPush(ctx context.Context, key, value string, maxLen int64) (int64, error) {
+	pipe := c.client.TxPipeline()
+	push := pipe.RPush(ctx, key, value)
+	pipe.LTrim(ctx, key, -maxLen, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return min(push.Val(), maxLen), nil
+}
+
+// ACLSetUser creates or updates an ACL user with rules
+func (c *RedisClient) ACLSetUser(ctx context.Context, user string, rules ...string) error {
+	args := []any{"ACL", "SETUSER", user}
+	for _, r := range rules {
+		args = append(args, r)
+	}
+	return c.client.Do(ctx, args...).Err()
+}
+
+// ACLUsers lists the ACL user names
+func (c *RedisClient) ACLUsers(ctx context.Context) ([]string, error) {
+	return c.client.Do(ctx, "ACL", "USERS").StringSlice()
+}
+
+// ACLDelUser deletes an ACL user and disconnects its clients
+func (c *RedisClient) ACLDelUser(ctx context.Context, user string) error {
+	if err := c.client.Do(ctx, "ACL", "DELUSER", user).Err(); err != nil {
+		return err
+	}
+	return c.client.ClientKillByFilter(ctx, "USER", user).Err()
+}
+
+// Publish sends message to the subscribers of channel
+func (c *RedisClient) Publish(ctx context.Context, channel, message string) error {
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe returns the messages published to channel until ctx is done.
+// go-redis resubscribes after connection failures; messages published
+// meanwhile are lost.
+func (c *RedisClient) Subscribe(ctx context.Context, channel string) <-chan string {
+	pubsub := c.client.Subscribe(ctx, channel)
+	out := make(chan string, 64)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
 func (c *RedisClient) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"net"
+
+	"github.com/forge/api/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook creates a client span for every Redis command. Arguments
+// (keys and values) are not recorded.
+type tracingHook struct{}
+
+func (tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := tracing.StartKind(ctx, "redis dial", trace.SpanKindClient, semconv.DBSystemRedis)
+		conn, err := next(ctx, network, addr)
+		tracing.End(span, err)
+		return conn, err
+	}
+}
+
+func (tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := tracing.StartKind(ctx, "redis "+cmd.Name(), trace.SpanKindClient,
+			semconv.DBSystemRedis,
+			semconv.DBOperationName(cmd.Name()),
+		)
+		err := next(ctx, cmd)
+		if err == redis.Nil {
+			// A cache miss is not an error
+			tracing.End(span, nil)
+		} else {
+			tracing.End(span, err)
+		}
+		return err
+	}
+}
+
+func (tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := tracing.StartKind(ctx, "redis pipeline", trace.SpanKindClient,
+			semconv.DBSystemRedis,
+			semconv.DBOperationName("pipeline"),
+		)
+		err := next(ctx, cmds)
+		tracing.End(span, err)
+		return err
+	}
+}
@@ -0,0 +1,169 @@
+package certs
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// obtainTimeout bounds one ACME order, including DNS propagation
+const obtainTimeout = 10 * time.Minute
+
+// obtain runs an ACME order for domain and stores the certificate
+func (m *Manager) obtain(ctx context.Context, domain, challenge string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, obtainTimeout)
+	defer cancel()
+
+	client, err := m.client(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("create order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := m.authorize(ctx, client, authzURL, challenge); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if _, err := client.WaitOrder(ctx, order.URI); err != nil {
+		return time.Time{}, fmt.Errorf("wait for order: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return time.Time{}, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{domain}}, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("finalize order: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return m.writeCertificate(domain, chain, keyDER)
+}
+
+// authorize completes one authorization with the requested challenge type
+func (m *Manager) authorize(ctx context.Context, client *acme.Client, authzURL, challengeType string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("get authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == challengeType {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("CA offered no %s challenge for %s", challengeType, authz.Identifier.Value)
+	}
+
+	switch challengeType {
+	case ChallengeHTTP:
+		keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return err
+		}
+		m.tokens.Store(chal.Token, keyAuth)
+		defer m.tokens.Delete(chal.Token)
+	case ChallengeDNS:
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return err
+		}
+		fqdn := "_acme-challenge." + strings.TrimPrefix(authz.Identifier.Value, "*.")
+		if err := m.cfg.DNS.Present(ctx, fqdn, value); err != nil {
+			return fmt.Errorf("create TXT record: %w", err)
+		}
+		defer m.cfg.DNS.CleanUp(context.Background(), fqdn, value)
+		waitForTXT(ctx, fqdn, value)
+	}
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accept %s challenge: %w", challengeType, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("%s challenge failed: %w", challengeType, err)
+	}
+	return nil
+}
+
+// client returns an ACME client with a registered account, creating the
+// account key on first use
+func (m *Manager) client(ctx context.Context) (*acme.Client, error) {
+	key, err := m.accountKey()
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{Key: key, DirectoryURL: m.cfg.DirectoryURL, UserAgent: "forge"}
+
+	acct := &acme.Account{}
+	if m.cfg.Email != "" {
+		acct.Contact = []string{"mailto:" + m.cfg.Email}
+	}
+	if _, err := client.Register(ctx, acct, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("register ACME account: %w", err)
+	}
+	return client, nil
+}
+
+// accountKey loads or creates the ACME account key
+func (m *Manager) accountKey() (crypto.Signer, error) {
+	path := filepath.Join(m.cfg.Dir, "account.key")
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("invalid account key: %s", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(m.cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
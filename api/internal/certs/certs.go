@@ -0,0 +1,457 @@
+// Package certs obtains and renews TLS certificates from an ACME CA such as
+// Let's Encrypt and stores them where nginx can load them
+package certs
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// Challenge types
+const (
+	ChallengeHTTP = "http-01" // served by the API through nginx on port 80
+	ChallengeDNS  = "dns-01"  // TXT record created through the DNS provider
+)
+
+// Certificate states
+const (
+	StatusPending = "pending" // not issued yet
+	StatusValid   = "valid"
+	StatusFailed  = "failed" // last attempt failed and no usable certificate exists
+)
+
+const (
+	// LetsEncryptURL is the default ACME directory
+	LetsEncryptURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+	renewBefore   = 30 * 24 * time.Hour
+	checkInterval = time.Hour
+	retryAfter    = time.Hour // wait between failed attempts for a domain
+)
+
+// ErrNotFound is returned for domains without a managed certificate
+var ErrNotFound = errors.New("certificate not found")
+
+var domainPattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,62}$`)
+
+// Certificate is a managed certificate for one domain
+type Certificate struct {
+	Domain      string    `json:"domain" yaml:"domain"`
+	Challenge   string    `json:"challenge" yaml:"challenge"`
+	Status      string    `json:"status" yaml:"-"`
+	NotAfter    time.Time `json:"not_after,omitempty" yaml:"not_after,omitempty"`
+	LastAttempt time.Time `json:"last_attempt,omitempty" yaml:"last_attempt,omitempty"`
+	LastError   string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+}
+
+// Config configures the ACME account and certificate storage
+type Config struct {
+	Dir          string // certificates and account key, shared with nginx
+	NginxDir     string // Dir as mounted in the nginx container
+	Email        string
+	DirectoryURL string
+	DNS          DNSProvider // nil disables dns-01
+}
+
+// NewConfig returns the ACME settings of c, with dns-01 through
+// Cloudflare when it has a token
+func NewConfig(c config.Certs) Config {
+	cfg := Config{
+		Dir:          c.Dir,
+		NginxDir:     c.NginxDir,
+		Email:        c.ACMEEmail,
+		DirectoryURL: c.ACMEDirectoryURL,
+	}
+	if cfg.DirectoryURL == "" {
+		cfg.DirectoryURL = LetsEncryptURL
+	}
+	if c.CloudflareToken != "" {
+		cfg.DNS = NewCloudflare(c.CloudflareToken)
+	}
+	return cfg
+}
+
+type certsFile struct {
+	Certificates []Certificate `yaml:"certificates"`
+}
+
+// Manager tracks requested certificates and keeps them issued
+type Manager struct {
+	mu       sync.RWMutex
+	certs    map[string]*Certificate
+	cfg      Config
+	tokens   sync.Map // http-01 token -> key authorization
+	trigger  chan struct{}
+	onIssued []func()
+
+	obtainMu sync.Mutex // one ACME order at a time
+}
+
+// NewManager loads managed certificates from cfg.Dir
+func NewManager(cfg Config) (*Manager, error) {
+	m := &Manager{
+		certs:   make(map[string]*Certificate),
+		cfg:     cfg,
+		trigger: make(chan struct{}, 1),
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ValidDomain reports whether domain is a lowercase DNS name, optionally a
+// wildcard (*.example.com)
+func ValidDomain(domain string) bool {
+	return len(domain) <= 253 && domainPattern.MatchString(domain)
+}
+
+// OnIssued registers fn to be called after a certificate is issued or
+// removed. It must be called before Run.
+func (m *Manager) OnIssued(fn func()) {
+	m.onIssued = append(m.onIssued, fn)
+}
+
+// List returns all managed certificates sorted by domain
+func (m *Manager) List() []Certificate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Certificate, 0, len(m.certs))
+	for _, c := range m.certs {
+		list = append(list, m.withStatus(*c))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Domain < list[j].Domain })
+	return list
+}
+
+// Get returns a managed certificate
+func (m *Manager) Get(domain string) (Certificate, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.certs[domain]
+	if !ok {
+		return Certificate{}, false
+	}
+	return m.withStatus(*c), true
+}
+
+// Request adds a domain to manage. The certificate is obtained in the
+// background; an existing domain keeps its certificate and switches challenge.
+func (m *Manager) Request(domain, challenge string) (Certificate, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if challenge == "" {
+		challenge = ChallengeHTTP
+	}
+	if !ValidDomain(domain) {
+		return Certificate{}, fmt.Errorf("invalid domain: %q", domain)
+	}
+	switch challenge {
+	case ChallengeHTTP:
+		if strings.HasPrefix(domain, "*.") {
+			return Certificate{}, fmt.Errorf("wildcard certificates require the %s challenge", ChallengeDNS)
+		}
+	case ChallengeDNS:
+		if m.cfg.DNS == nil {
+			return Certificate{}, fmt.Errorf("%s requires a DNS provider (set CLOUDFLARE_API_TOKEN)", ChallengeDNS)
+		}
+	default:
+		return Certificate{}, fmt.Errorf("invalid challenge %q: must be %s or %s", challenge, ChallengeHTTP, ChallengeDNS)
+	}
+
+	m.mu.Lock()
+	c, ok := m.certs[domain]
+	if !ok {
+		c = &Certificate{Domain: domain}
+		m.certs[domain] = c
+	}
+	if c.Challenge != challenge {
+		c.Challenge = challenge
+		c.LastAttempt = time.Time{} // retry now with the new challenge
+	}
+	saved := m.withStatus(*c)
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return Certificate{}, err
+	}
+	m.wake()
+	return saved, nil
+}
+
+// Ensure requests http-01 certificates for domains not managed yet
+func (m *Manager) Ensure(domains []string) {
+	for _, d := range domains {
+		if _, ok := m.Get(d); ok || m.Lookup(d) {
+			continue
+		}
+		if _, err := m.Request(d, ChallengeHTTP); err != nil {
+			logger.Error("Failed to request certificate for "+d, err)
+		}
+	}
+}
+
+// Remove stops managing a domain and deletes its certificate files
+func (m *Manager) Remove(domain string) error {
+	m.mu.Lock()
+	if _, ok := m.certs[domain]; !ok {
+		m.mu.Unlock()
+		return ErrNotFound
+	}
+	delete(m.certs, domain)
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return err
+	}
+	metrics.CertificateExpiry.DeleteLabelValues(domain)
+
+	// Drop the nginx server blocks before the files they load
+	m.notify()
+	return os.RemoveAll(filepath.Join(m.cfg.Dir, dirName(domain)))
+}
+
+// Renew obtains a new certificate for a managed domain now
+func (m *Manager) Renew(ctx context.Context, domain string) error {
+	c, ok := m.Get(domain)
+	if !ok {
+		return ErrNotFound
+	}
+	return m.issue(ctx, c)
+}
+
+// Lookup reports whether a usable certificate covers domain, directly or
+// through a wildcard
+func (m *Manager) Lookup(domain string) bool {
+	_, _, ok := m.Paths(domain)
+	return ok
+}
+
+// Paths returns the nginx paths of the certificate and key covering domain
+func (m *Manager) Paths(domain string) (cert, key string, ok bool) {
+	candidates := []string{domain}
+	if i := strings.Index(domain, "."); i > 0 && !strings.HasPrefix(domain, "*.") {
+		candidates = append(candidates, "*"+domain[i:])
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, d := range candidates {
+		c, managed := m.certs[d]
+		if !managed || c.NotAfter.IsZero() || time.Now().After(c.NotAfter) {
+			continue
+		}
+		dir := filepath.Join(m.cfg.NginxDir, dirName(d))
+		return filepath.Join(dir, "fullchain.pem"), filepath.Join(dir, "privkey.pem"), true
+	}
+	return "", "", false
+}
+
+// Run obtains pending certificates and renews those close to expiry until
+// ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	for _, c := range m.List() {
+		if !c.NotAfter.IsZero() {
+			metrics.CertificateExpiry.WithLabelValues(c.Domain).Set(float64(c.NotAfter.Unix()))
+		}
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		m.runDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.trigger:
+		}
+	}
+}
+
+// runDue issues every certificate that is missing or due for renewal
+func (m *Manager) runDue(ctx context.Context, now time.Time) {
+	for _, c := range m.List() {
+		due := c.NotAfter.IsZero() || now.After(c.NotAfter.Add(-renewBefore))
+		if !due || now.Sub(c.LastAttempt) < retryAfter {
+			continue
+		}
+		if err := m.issue(ctx, c); err != nil {
+			logger.Error("Failed to obtain certificate for "+c.Domain, err)
+		}
+	}
+}
+
+// issue obtains a certificate and records the outcome
+func (m *Manager) issue(ctx context.Context, c Certificate) error {
+	m.obtainMu.Lock()
+	defer m.obtainMu.Unlock()
+
+	notAfter, err := m.obtain(ctx, c.Domain, c.Challenge)
+
+	m.mu.Lock()
+	if cur, ok := m.certs[c.Domain]; ok {
+		cur.LastAttempt = time.Now().UTC()
+		cur.LastError = ""
+		if err != nil {
+			cur.LastError = err.Error()
+		} else {
+			cur.NotAfter = notAfter.UTC()
+		}
+	}
+	m.mu.Unlock()
+
+	if saveErr := m.save(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		return err
+	}
+
+	metrics.CertificateExpiry.WithLabelValues(c.Domain).Set(float64(notAfter.Unix()))
+	logger.Info("Certificate issued for " + c.Domain)
+	m.notify()
+	return nil
+}
+
+// ServeChallenge answers http-01 challenges at /.well-known/acme-challenge/{token}
+func (m *Manager) ServeChallenge(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+	keyAuth, ok := m.tokens.Load(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth.(string)))
+}
+
+func (m *Manager) withStatus(c Certificate) Certificate {
+	switch {
+	case !c.NotAfter.IsZero() && time.Now().Before(c.NotAfter):
+		c.Status = StatusValid
+	case c.LastError != "":
+		c.Status = StatusFailed
+	default:
+		c.Status = StatusPending
+	}
+	return c
+}
+
+// wake makes Run check for due certificates now
+func (m *Manager) wake() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) notify() {
+	for _, fn := range m.onIssued {
+		fn()
+	}
+}
+
+// dirName is the directory holding the files of domain
+func dirName(domain string) string {
+	return strings.Replace(domain, "*", "_wildcard", 1)
+}
+
+// writeCertificate stores the chain and key of domain and returns the
+// expiry of the leaf certificate
+func (m *Manager) writeCertificate(domain string, chain [][]byte, keyDER []byte) (time.Time, error) {
+	if len(chain) == 0 {
+		return time.Time{}, fmt.Errorf("CA returned an empty certificate chain")
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	dir := filepath.Join(m.cfg.Dir, dirName(domain))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return time.Time{}, err
+	}
+	// Key first: nginx must never see a new certificate with the old key
+	if err := writeFileAtomic(filepath.Join(dir, "privkey.pem"), keyPEM, 0600); err != nil {
+		return time.Time{}, err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "fullchain.pem"), certPEM, 0644); err != nil {
+		return time.Time{}, err
+	}
+	return leaf.NotAfter, nil
+}
+
+// writeFileAtomic replaces path with data via a rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load reads managed certificates from certs.yaml
+func (m *Manager) load() error {
+	data, err := os.ReadFile(filepath.Join(m.cfg.Dir, "certs.yaml"))
+	if err != nil {
+		return err
+	}
+
+	var f certsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range f.Certificates {
+		c := c
+		m.certs[c.Domain] = &c
+	}
+	return nil
+}
+
+// save writes managed certificates to certs.yaml
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := certsFile{Certificates: make([]Certificate, 0, len(m.certs))}
+	for _, c := range m.certs {
+		f.Certificates = append(f.Certificates, *c)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Certificates, func(i, j int) bool { return f.Certificates[i].Domain < f.Certificates[j].Domain })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.cfg.Dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.cfg.Dir, "certs.yaml"), data, 0644)
+}
@@ -0,0 +1,166 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cloudflareAPI     = "https://api.cloudflare.com/client/v4"
+	propagationWait   = 2 * time.Minute
+	propagationPoll   = 5 * time.Second
+	challengeTXTTTL   = 120
+	cloudflareTimeout = 30 * time.Second
+)
+
+// DNSProvider creates and removes the TXT records of dns-01 challenges
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// Cloudflare is a DNSProvider using an API token with Zone.DNS edit permission
+type Cloudflare struct {
+	token   string
+	client  *http.Client
+	mu      sync.Mutex
+	records map[string]cloudflareRecord // fqdn + value -> created record
+}
+
+type cloudflareRecord struct {
+	zoneID string
+	id     string
+}
+
+// NewCloudflare creates a Cloudflare DNS provider
+func NewCloudflare(token string) *Cloudflare {
+	return &Cloudflare{
+		token:   token,
+		client:  &http.Client{Timeout: cloudflareTimeout},
+		records: make(map[string]cloudflareRecord),
+	}
+}
+
+// Present creates the TXT record fqdn=value
+func (c *Cloudflare) Present(ctx context.Context, fqdn, value string) error {
+	zoneID, err := c.zoneID(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]any{"type": "TXT", "name": fqdn, "content": value, "ttl": challengeTXTTTL}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, "POST", "/zones/"+zoneID+"/dns_records", body, &created); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.records[fqdn+" "+value] = cloudflareRecord{zoneID: zoneID, id: created.ID}
+	c.mu.Unlock()
+	return nil
+}
+
+// CleanUp deletes a TXT record created by Present
+func (c *Cloudflare) CleanUp(ctx context.Context, fqdn, value string) error {
+	c.mu.Lock()
+	rec, ok := c.records[fqdn+" "+value]
+	delete(c.records, fqdn+" "+value)
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return c.do(ctx, "DELETE", "/zones/"+rec.zoneID+"/dns_records/"+rec.id, nil, nil)
+}
+
+// zoneID finds the zone of fqdn by trying each parent domain
+func (c *Cloudflare) zoneID(ctx context.Context, fqdn string) (string, error) {
+	labels := strings.Split(fqdn, ".")
+	for i := 1; i < len(labels)-1; i++ {
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		name := strings.Join(labels[i:], ".")
+		if err := c.do(ctx, "GET", "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("no Cloudflare zone found for %s", fqdn)
+}
+
+// do calls the Cloudflare API and decodes the result field into out
+func (c *Cloudflare) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool                       `json:"success"`
+		Errors  []struct{ Message string } `json:"errors"`
+		Result  json.RawMessage            `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("cloudflare: status %d: %w", resp.StatusCode, err)
+	}
+	if !envelope.Success {
+		msgs := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("cloudflare: status %d: %s", resp.StatusCode, strings.Join(msgs, "; "))
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Result, out)
+	}
+	return nil
+}
+
+// waitForTXT polls DNS until fqdn serves value or the propagation wait
+// elapses; the CA makes the final check either way
+func waitForTXT(ctx context.Context, fqdn, value string) {
+	deadline := time.Now().Add(propagationWait)
+	for time.Now().Before(deadline) {
+		records, _ := net.DefaultResolver.LookupTXT(ctx, fqdn)
+		for _, r := range records {
+			if r == value {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(propagationPoll):
+		}
+	}
+}
@@ -0,0 +1,1227 @@
+// Package config loads the API's settings from one file with an
+// environment overlay
+//
+// Settings start from built-in defaults, are replaced by forge.yaml when
+// present (FORGE_CONFIG, default /app/data/config/forge.yaml) and finally
+// by environment variables, so existing .env files and compose settings
+// keep working and win over the file. The result is validated once at
+// startup and handed to constructors, instead of each package reading the
+// environment on its own.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is where the config file is looked for when FORGE_CONFIG is
+// unset; it is optional there
+const DefaultPath = "/app/data/config/forge.yaml"
+
+// Config is the API's configuration
+type Config struct {
+	Server      Server      `yaml:"server"`
+	MySQL       MySQL       `yaml:"mysql"`
+	Redis       Redis       `yaml:"redis"`
+	Storage     Storage     `yaml:"storage"`
+	Messaging   Messaging   `yaml:"messaging"`
+	MQTT        MQTT        `yaml:"mqtt"`
+	Files       Files       `yaml:"files"`
+	Paths       Paths       `yaml:"paths"`
+	Features    Features    `yaml:"features"`
+	Health      Health      `yaml:"health"`
+	TLS         TLS         `yaml:"tls"`
+	Auth        Auth        `yaml:"auth"`
+	RateLimits  RateLimits  `yaml:"rate_limits"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Observe     Observe     `yaml:"observe"`
+	SMTP        SMTP        `yaml:"smtp"`
+	Certs       Certs       `yaml:"certs"`
+	Proxy       Proxy       `yaml:"proxy"`
+	Docker      Docker      `yaml:"docker"`
+	System      System      `yaml:"system"`
+	Apps        Apps        `yaml:"apps"`
+
+	// Path is the file the config was read from; empty without one
+	Path string `yaml:"-"`
+}
+
+// Server configures the API's listeners and HTTP behaviour
+type Server struct {
+	Port    int `yaml:"port"`     // PORT
+	TLSPort int `yaml:"tls_port"` // TLS_PORT
+	// TLSRedirect sends plain HTTP requests to HTTPS (TLS_REDIRECT)
+	TLSRedirect bool `yaml:"tls_redirect"`
+	// TLSRedirectPort is the HTTPS port clients see, e.g. a published port
+	// (TLS_REDIRECT_PORT, default TLSPort)
+	TLSRedirectPort int `yaml:"tls_redirect_port"`
+	// HSTSMaxAge is the Strict-Transport-Security max-age in seconds; 0
+	// disables the header (HSTS_MAX_AGE)
+	HSTSMaxAge int `yaml:"hsts_max_age"`
+	// CompressPrefixes are the route prefixes whose responses are
+	// compressed; empty disables compression (COMPRESS_PREFIXES, "off")
+	CompressPrefixes []string `yaml:"compress_prefixes"`
+	// SyslogAddr enables the syslog listener, e.g. ":1514" (SYSLOG_ADDR)
+	SyslogAddr string `yaml:"syslog_addr"`
+	// DependencyWait is how long startup waits for MySQL and Redis; later
+	// connections are picked up in the background (DEPENDENCY_WAIT)
+	DependencyWait time.Duration `yaml:"dependency_wait"`
+	// PortServes is what the Port and TLSPort listeners serve: all, public
+	// or admin (PORT_SERVES)
+	PortServes string `yaml:"port_serves"`
+	// Listeners are further plaintext listeners, e.g. an admin port bound to
+	// a private address or a Unix socket for a proxy on the same host
+	// (LISTENERS, serves=addr pairs)
+	Listeners []Listener `yaml:"listeners"`
+	// UnixSocketMode is the octal file mode of Unix socket listeners
+	// (UNIX_SOCKET_MODE)
+	UnixSocketMode string `yaml:"unix_socket_mode"`
+	// ExternalHost is the host SDK clients outside Docker reach the stack
+	// at, given in connection info and self-signed certificates; the one
+	// entered during setup is used when empty (EXTERNAL_HOST)
+	ExternalHost string `yaml:"external_host"`
+}
+
+// What a listener serves
+const (
+	// ServeAll serves every endpoint
+	ServeAll = "all"
+	// ServePublic serves everything but the administrative endpoints
+	ServePublic = "public"
+	// ServeAdmin serves the administrative endpoints, health checks,
+	// metrics and the docs, without rate limits
+	ServeAdmin = "admin"
+)
+
+// UnixPrefix marks listener addresses that are Unix socket paths
+const UnixPrefix = "unix:"
+
+// Listener is a plaintext listener: Addr is host:port, or unix: and the
+// path of a socket, e.g. "unix:/run/forge/api.sock"
+type Listener struct {
+	Serves string `yaml:"serves"`
+	Addr   string `yaml:"addr"`
+}
+
+// MySQL configures the MySQL connection (MYSQL_*)
+type MySQL struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// Database holds Forge's own tables (FORGE_DATABASE)
+	Database string `yaml:"database"`
+	// CredentialsDatabase is the database issued accounts are scoped to
+	// (CREDENTIALS_DATABASE)
+	CredentialsDatabase string `yaml:"credentials_database"`
+}
+
+// DSN returns the go-sql-driver DSN for the server, without a database
+func (m MySQL) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/", m.User, m.Password, m.Host, m.Port)
+}
+
+// Redis configures the Redis connection (REDIS_*)
+type Redis struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Password string `yaml:"password"`
+}
+
+// Addr returns host:port
+func (r Redis) Addr() string {
+	return r.Host + ":" + strconv.Itoa(r.Port)
+}
+
+// Storage configures the S3-compatible object store, the bundled MinIO or
+// any S3 endpoint (STORAGE_*)
+type Storage struct {
+	// Endpoint is the URL the API reaches the store at (STORAGE_ENDPOINT)
+	Endpoint string `yaml:"endpoint"`
+	// PublicURL is the URL clients reach the store at, used in presigned
+	// URLs and connection info (STORAGE_PUBLIC_URL, default Endpoint)
+	PublicURL string `yaml:"public_url"`
+	Region    string `yaml:"region"`     // STORAGE_REGION
+	AccessKey string `yaml:"access_key"` // STORAGE_ACCESS_KEY
+	SecretKey string `yaml:"secret_key"` // STORAGE_SECRET_KEY
+	// Bucket is used when a request names none and is created once the
+	// store is reached (STORAGE_BUCKET)
+	Bucket string `yaml:"bucket"`
+	// PathStyle addresses buckets as endpoint/bucket rather than
+	// bucket.endpoint, as MinIO expects (STORAGE_PATH_STYLE)
+	PathStyle bool `yaml:"path_style"`
+	// RoleARN is the role assumed for issued credentials; AWS needs one,
+	// MinIO ignores it (STORAGE_ROLE_ARN)
+	RoleARN string `yaml:"role_arn"`
+}
+
+// ClientURL returns the URL clients reach the store at
+func (s Storage) ClientURL() string {
+	if s.PublicURL != "" {
+		return s.PublicURL
+	}
+	return s.Endpoint
+}
+
+// Messaging configures the NATS server behind the MessagingService, the
+// bundled forge-nats or any other (MESSAGING_*)
+type Messaging struct {
+	// URL is the nats:// or tls:// URL the API reaches the server at
+	// (MESSAGING_URL)
+	URL string `yaml:"url"`
+	// PublicURL is the URL clients reach the server at, returned by
+	// GetInfo (MESSAGING_PUBLIC_URL, default URL)
+	PublicURL string `yaml:"public_url"`
+	// User and Password are the API's own account (MESSAGING_USER,
+	// MESSAGING_PASSWORD)
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// ClientUser and ClientPassword are handed to callers with the write
+	// role, which may publish and subscribe (MESSAGING_CLIENT_USER,
+	// MESSAGING_CLIENT_PASSWORD)
+	ClientUser     string `yaml:"client_user"`
+	ClientPassword string `yaml:"client_password"`
+	// ReadUser and ReadPassword are handed to callers with the read role,
+	// which may only subscribe (MESSAGING_READ_USER, MESSAGING_READ_PASSWORD)
+	ReadUser     string `yaml:"read_user"`
+	ReadPassword string `yaml:"read_password"`
+}
+
+// ClientURL returns the URL clients reach the server at
+func (m Messaging) ClientURL() string {
+	if m.PublicURL != "" {
+		return m.PublicURL
+	}
+	return m.URL
+}
+
+// MQTT configures the broker MQTT bridges subscribe to: the bundled
+// forge-mosquitto or a home-automation broker (MQTT_*)
+type MQTT struct {
+	// URL is the mqtt:// or mqtts:// URL of the broker (MQTT_URL)
+	URL string `yaml:"url"`
+	// User and Password log in to the broker (MQTT_USER, MQTT_PASSWORD)
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// ClientID identifies the API to the broker; only one client per ID
+	// may be connected (MQTT_CLIENT_ID)
+	ClientID string `yaml:"client_id"`
+}
+
+// Files configures the files API, which keeps uploads in Paths.Files on a
+// local volume (FILES_*)
+type Files struct {
+	// MaxSizeMB bounds one file (FILES_MAX_SIZE_MB)
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// QuotaMB bounds all files together; 0 is unlimited (FILES_QUOTA_MB)
+	QuotaMB int `yaml:"quota_mb"`
+}
+
+// MaxSize returns the largest file size in bytes
+func (f Files) MaxSize() int64 {
+	return int64(f.MaxSizeMB) << 20
+}
+
+// Quota returns the quota in bytes, 0 for none
+func (f Files) Quota() int64 {
+	return int64(f.QuotaMB) << 20
+}
+
+// Paths are the files and directories the API keeps its state in
+type Paths struct {
+	Routes            string `yaml:"routes"`              // ROUTES_CONFIG
+	NginxConf         string `yaml:"nginx_conf"`          // NGINX_DYNAMIC_CONF
+	Secrets           string `yaml:"secrets"`             // SECRETS_DIR
+	AuthKeys          string `yaml:"auth_keys"`           // AUTH_KEYS_FILE
+	LogPipelines      string `yaml:"log_pipelines"`       // LOG_PIPELINES_CONFIG
+	LogMetrics        string `yaml:"log_metrics"`         // LOG_METRICS_CONFIG
+	LogSampling       string `yaml:"log_sampling"`        // LOG_SAMPLING_CONFIG
+	PromtailSources   string `yaml:"promtail_sources"`    // PROMTAIL_SOURCES_CONFIG
+	PromtailConf      string `yaml:"promtail_conf"`       // PROMTAIL_DYNAMIC_CONF
+	PromtailRouteLogs string `yaml:"promtail_route_logs"` // PROMTAIL_ROUTE_LOG_DIR
+	Snapshots         string `yaml:"snapshots"`           // SNAPSHOTS_CONFIG
+	Alerting          string `yaml:"alerting"`            // ALERTING_CONFIG
+	AlertmanagerConf  string `yaml:"alertmanager_conf"`   // ALERTMANAGER_CONF
+	AlertingLogRules  string `yaml:"alerting_log_rules"`  // ALERTING_LOG_RULES
+	Monitors          string `yaml:"monitors"`            // MONITORS_CONFIG
+	Setup             string `yaml:"setup"`               // SETUP_CONFIG
+	Watchdog          string `yaml:"watchdog"`            // WATCHDOG_CONFIG
+	Limits            string `yaml:"limits"`              // LIMITS_CONFIG
+	AppsTemplates     string `yaml:"apps_templates"`      // APPS_TEMPLATES_DIR
+	AuditLog          string `yaml:"audit_log"`           // AUDIT_LOG
+	DebugDumps        string `yaml:"debug_dumps"`         // DEBUG_DUMP_DIR
+	Jobs              string `yaml:"jobs"`                // JOBS_CONFIG
+	Backups           string `yaml:"backups"`             // BACKUP_DIR
+	Webhooks          string `yaml:"webhooks"`            // WEBHOOKS_CONFIG
+	Hooks             string `yaml:"hooks"`               // HOOKS_CONFIG
+	Notify            string `yaml:"notify"`              // NOTIFY_CONFIG
+	MQTT              string `yaml:"mqtt"`                // MQTT_CONFIG
+	Services          string `yaml:"services"`            // SERVICES_CONFIG
+	ServiceTargets    string `yaml:"service_targets"`     // SERVICE_TARGETS_FILE
+	Files             string `yaml:"files"`               // FILES_DIR
+}
+
+// Features toggles optional behaviour
+type Features struct {
+	// ProxyBackend serves routes: "nginx" or "caddy" (PROXY_BACKEND)
+	ProxyBackend string `yaml:"proxy_backend"`
+	// DockerDiscovery adds routes for labelled containers (DOCKER_DISCOVERY)
+	DockerDiscovery bool `yaml:"docker_discovery"`
+	// DockerDiscoveryInterval is how often containers are listed
+	// (DOCKER_DISCOVERY_INTERVAL)
+	DockerDiscoveryInterval time.Duration `yaml:"docker_discovery_interval"`
+	// DebugEndpoints serves pprof, expvar and profile dumps under /debug to
+	// admins (DEBUG_ENDPOINTS)
+	DebugEndpoints bool `yaml:"debug_endpoints"`
+}
+
+// Health configures the checks behind GET /api/v1/health
+type Health struct {
+	// Targets are the HTTP services checked next to MySQL and Redis
+	// (HEALTH_TARGETS as name=url pairs, "off" for none)
+	Targets []HealthTarget `yaml:"targets"`
+	// Timeout is the deadline shared by all checks (HEALTH_TIMEOUT)
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long results are reused; 0 checks on every request
+	// (HEALTH_CACHE_TTL)
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// HealthTarget is an HTTP service that is healthy when url answers 2xx
+type HealthTarget struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// TLS configures the HTTPS listener and client certificates (TLS_*)
+type TLS struct {
+	CertFile string `yaml:"cert_file"` // TLS_CERT_FILE
+	KeyFile  string `yaml:"key_file"`  // TLS_KEY_FILE
+	// SelfSigned generates a certificate in Dir when no files are given
+	// (TLS_SELF_SIGNED, TLS_DIR)
+	SelfSigned bool   `yaml:"self_signed"`
+	Dir        string `yaml:"dir"`
+	// SelfSignedHosts are named in the generated certificate next to
+	// localhost, the hostname and Server.ExternalHost
+	// (TLS_SELF_SIGNED_HOSTS)
+	SelfSignedHosts []string `yaml:"self_signed_hosts"`
+	// ClientCAFile enables client certificates verified against these CAs
+	// (TLS_CLIENT_CA_FILE)
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is optional or require (TLS_CLIENT_AUTH)
+	ClientAuth string `yaml:"client_auth"`
+	// ClientRole is given to certificates ClientRoleMap does not cover;
+	// empty refuses them (TLS_CLIENT_ROLE)
+	ClientRole string `yaml:"client_role"`
+	// ClientRoleMap maps certificate names and organizational units to
+	// roles (TLS_CLIENT_ROLE_MAP, name=role pairs)
+	ClientRoleMap map[string]string `yaml:"client_role_map"`
+}
+
+// Auth configures how callers authenticate and where administrative
+// endpoints may be reached from
+type Auth struct {
+	// AdminKey is an admin API key accepted next to the keys file
+	// (FORGE_ADMIN_KEY)
+	AdminKey string `yaml:"admin_key"`
+	// AdminUser and AdminPassword create the first web UI user while there
+	// is none (FORGE_ADMIN_USER, FORGE_ADMIN_PASSWORD)
+	AdminUser     string `yaml:"admin_user"`
+	AdminPassword string `yaml:"admin_password"`
+	// SessionTTL is how long web UI logins last (SESSION_TTL)
+	SessionTTL time.Duration `yaml:"session_ttl"`
+	// SessionCookieSecure marks session cookies Secure even over plain
+	// HTTP, e.g. behind a TLS-terminating proxy (SESSION_COOKIE_SECURE)
+	SessionCookieSecure bool `yaml:"session_cookie_secure"`
+	// AllowedCIDRs may reach administrative endpoints; empty allows
+	// loopback and private networks, "off" anyone (ADMIN_ALLOWED_CIDRS)
+	AllowedCIDRs string `yaml:"allowed_cidrs"`
+	// TrustedProxies may report the client in X-Forwarded-For; empty
+	// trusts private networks, "none" no proxy (ADMIN_TRUSTED_PROXIES)
+	TrustedProxies string `yaml:"trusted_proxies"`
+
+	OIDC  OIDC  `yaml:"oidc"`
+	Guard Guard `yaml:"guard"`
+}
+
+// OIDC configures JWTs from an identity provider (OIDC_*)
+type OIDC struct {
+	// Issuer enables OIDC; tokens must carry it as iss and the signing
+	// keys are discovered from it (OIDC_ISSUER)
+	Issuer string `yaml:"issuer"`
+	// Audience is required with Issuer, usually the client ID
+	// (OIDC_AUDIENCE)
+	Audience string `yaml:"audience"`
+	JWKSURL  string `yaml:"jwks_url"` // OIDC_JWKS_URL
+	// RoleClaim holds groups or roles, dotted for nested claims
+	// (OIDC_ROLE_CLAIM, default groups)
+	RoleClaim string `yaml:"role_claim"`
+	// RoleMap maps claim values to roles (OIDC_ROLE_MAP, value=role pairs)
+	RoleMap map[string]string `yaml:"role_map"`
+	// DefaultRole is given to tokens RoleMap does not cover; empty refuses
+	// them (OIDC_DEFAULT_ROLE)
+	DefaultRole string `yaml:"default_role"`
+}
+
+// Guard configures lockouts after repeated authentication failures
+// (AUTH_GUARD_*)
+type Guard struct {
+	// Threshold failures within Window lock a client out; 0 disables
+	// lockouts (AUTH_GUARD_THRESHOLD, "off")
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"` // AUTH_GUARD_WINDOW
+	// Lockout is the first lockout, doubling up to MaxLockout
+	// (AUTH_GUARD_LOCKOUT, AUTH_GUARD_MAX_LOCKOUT)
+	Lockout    time.Duration `yaml:"lockout"`
+	MaxLockout time.Duration `yaml:"max_lockout"`
+	// Spike failures across all clients within Window raise an alert; 0
+	// disables the alert (AUTH_GUARD_SPIKE)
+	Spike int `yaml:"spike"`
+}
+
+// RateLimits configures request rate limits (RATE_LIMIT*)
+type RateLimits struct {
+	// Client are the requests per minute per client by endpoint class,
+	// e.g. "read=600,write=120"; empty uses the defaults and "off"
+	// disables rate limiting (RATE_LIMITS)
+	Client string `yaml:"client"`
+	// Global are limits across all clients together (RATE_LIMITS_GLOBAL)
+	Global string `yaml:"global"`
+	// Backend counts in memory or redis (RATE_LIMIT_BACKEND)
+	Backend string `yaml:"backend"`
+}
+
+// Idempotency configures Idempotency-Key replays
+type Idempotency struct {
+	// TTL is how long responses are replayed; 0 disables idempotency keys
+	// (IDEMPOTENCY_TTL, "off")
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Observe locates the observability stack
+type Observe struct {
+	LokiURL       string `yaml:"loki_url"`        // LOKI_URL
+	PrometheusURL string `yaml:"prometheus_url"`  // PROMETHEUS_URL
+	TempoURL      string `yaml:"tempo_url"`       // TEMPO_URL, OTLP/HTTP
+	TempoQueryURL string `yaml:"tempo_query_url"` // TEMPO_QUERY_URL
+	PyroscopeURL  string `yaml:"pyroscope_url"`   // PYROSCOPE_URL
+	PromtailURL   string `yaml:"promtail_url"`    // PROMTAIL_URL
+	// AlertmanagerURL receives alerts and receiver changes
+	// (ALERTMANAGER_URL)
+	AlertmanagerURL string `yaml:"alertmanager_url"`
+	// Tracing sends the API's own spans to TempoURL (TRACING_ENABLED)
+	Tracing bool    `yaml:"tracing"`
+	Grafana Grafana `yaml:"grafana"`
+}
+
+// Grafana locates Grafana and its admin account (GRAFANA_*)
+type Grafana struct {
+	URL      string `yaml:"url"`      // GRAFANA_URL
+	User     string `yaml:"user"`     // GRAFANA_ADMIN_USER
+	Password string `yaml:"password"` // GRAFANA_ADMIN_PASSWORD
+}
+
+// SMTP is the mail server email alert receivers send through (SMTP_*)
+type SMTP struct {
+	Smarthost string `yaml:"smarthost"` // SMTP_SMARTHOST, host:port
+	From      string `yaml:"from"`      // SMTP_FROM
+	Username  string `yaml:"username"`  // SMTP_USERNAME
+	Password  string `yaml:"password"`  // SMTP_PASSWORD
+}
+
+// Certs configures ACME certificates for host routes
+type Certs struct {
+	// Dir keeps certificates and the account key, shared with nginx
+	// (CERTS_DIR)
+	Dir string `yaml:"dir"`
+	// NginxDir is Dir as mounted in the nginx container (CERTS_NGINX_DIR)
+	NginxDir string `yaml:"nginx_dir"`
+	// ACMEEmail is the ACME account contact (ACME_EMAIL)
+	ACMEEmail string `yaml:"acme_email"`
+	// ACMEDirectoryURL is the ACME server, e.g. Let's Encrypt staging
+	// (ACME_DIRECTORY_URL)
+	ACMEDirectoryURL string `yaml:"acme_directory_url"`
+	// CloudflareToken enables dns-01 challenges (CLOUDFLARE_API_TOKEN)
+	CloudflareToken string `yaml:"cloudflare_token"`
+}
+
+// Proxy configures the reverse proxy serving routes
+type Proxy struct {
+	// NginxReload is how nginx reloads: docker, exec or an http(s) URL
+	// (NGINX_RELOAD)
+	NginxReload    string `yaml:"nginx_reload"`
+	NginxContainer string `yaml:"nginx_container"` // NGINX_CONTAINER
+	// CaddyAdminURL is Caddy's admin API (CADDY_ADMIN_URL)
+	CaddyAdminURL string `yaml:"caddy_admin_url"`
+	// CaddyServer and CaddyTLSServer receive path and host routes
+	// (CADDY_SERVER, CADDY_TLS_SERVER)
+	CaddyServer    string `yaml:"caddy_server"`
+	CaddyTLSServer string `yaml:"caddy_tls_server"`
+	// ProbeBaseURL is where route probes reach the proxy
+	// (ROUTE_PROBE_BASE_URL)
+	ProbeBaseURL string `yaml:"probe_base_url"`
+}
+
+// Docker locates the Docker Engine API, or Podman's, the way the docker
+// CLI does
+type Docker struct {
+	// Host is unix:///path or tcp://host:port; empty uses the first
+	// existing Docker or Podman socket (DOCKER_HOST)
+	Host string `yaml:"host"`
+	// CertPath holds ca.pem, cert.pem and key.pem for TLS
+	// (DOCKER_CERT_PATH)
+	CertPath string `yaml:"cert_path"`
+	// TLSVerify verifies the daemon's certificate; any value of
+	// DOCKER_TLS_VERIFY sets it
+	TLSVerify bool `yaml:"tls_verify"`
+	// RuntimeDir is searched for rootless sockets (XDG_RUNTIME_DIR)
+	RuntimeDir string `yaml:"runtime_dir"`
+}
+
+// System configures host and container reporting
+type System struct {
+	// HostProc and HostRoot are the host's /proc and / as mounted in the
+	// container (HOST_PROC, HOST_ROOT)
+	HostProc string `yaml:"host_proc"`
+	HostRoot string `yaml:"host_root"`
+	// Containers and ContainerLabels select containers reported next to
+	// the stack (SYSTEM_CONTAINERS, SYSTEM_CONTAINER_LABELS)
+	Containers      string `yaml:"containers"`
+	ContainerLabels string `yaml:"container_labels"`
+	// GPUNvidiaSMI and GPUDCGMURL read NVIDIA GPUs; nvidia-smi is looked
+	// for in PATH when unset (GPU_NVIDIA_SMI, GPU_DCGM_URL)
+	GPUNvidiaSMI string `yaml:"gpu_nvidia_smi"`
+	GPUDCGMURL   string `yaml:"gpu_dcgm_url"`
+}
+
+// Apps configures deployed apps (APPS_*)
+type Apps struct {
+	// Network is the Docker network apps join (APPS_NETWORK)
+	Network string `yaml:"network"`
+	// VolumeRoot is the host directory apps may bind paths from; empty
+	// allows named volumes only (APPS_VOLUME_ROOT)
+	VolumeRoot string `yaml:"volume_root"`
+}
+
+// Default returns the built-in defaults
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:             8080,
+			TLSPort:          8443,
+			HSTSMaxAge:       31536000,
+			CompressPrefixes: []string{"/api/v1/", "/openapi.json"},
+			DependencyWait:   30 * time.Second,
+			PortServes:       ServeAll,
+			Listeners:        []Listener{},
+			UnixSocketMode:   "0660",
+		},
+		MySQL: MySQL{
+			Host:                "localhost",
+			Port:                3306,
+			User:                "root",
+			Password:            "forgeroot",
+			Database:            "forge",
+			CredentialsDatabase: "app",
+		},
+		Redis: Redis{
+			Host: "localhost",
+			Port: 6379,
+		},
+		Storage: Storage{
+			Endpoint:  "http://localhost:9000",
+			Region:    "us-east-1",
+			AccessKey: "forge",
+			SecretKey: "forgeminio",
+			Bucket:    "forge",
+			PathStyle: true,
+		},
+		Messaging: Messaging{
+			URL:            "nats://localhost:4222",
+			User:           "forge",
+			Password:       "forgenats",
+			ClientUser:     "forge_app",
+			ClientPassword: "forgenatsapp",
+			ReadUser:       "forge_read",
+			ReadPassword:   "forgenatsread",
+		},
+		MQTT: MQTT{
+			URL:      "mqtt://localhost:1883",
+			User:     "forge",
+			Password: "forgemqtt",
+			ClientID: "forge",
+		},
+		Files: Files{
+			MaxSizeMB: 100,
+			QuotaMB:   10240,
+		},
+		Paths: Paths{
+			Routes:            "/app/data/routes/routes.yaml",
+			NginxConf:         "/app/data/routes/routes.conf",
+			Secrets:           "/app/data/secrets",
+			AuthKeys:          "/app/data/auth/keys.yaml",
+			LogPipelines:      "/app/data/pipelines/pipelines.yaml",
+			LogMetrics:        "/app/data/pipelines/log-metrics.yaml",
+			LogSampling:       "/app/data/pipelines/sampling.yaml",
+			PromtailSources:   "/app/data/promtail/logsources.yaml",
+			PromtailConf:      "/app/data/promtail/promtail-dynamic.yml",
+			PromtailRouteLogs: "/var/log/forge-routes",
+			Snapshots:         "/app/data/snapshots/snapshots.yaml",
+			Alerting:          "/app/data/alertmanager/receivers.yaml",
+			AlertmanagerConf:  "/app/data/alertmanager/alertmanager.yml",
+			AlertingLogRules:  "/app/data/alertmanager/log-rules.yaml",
+			Monitors:          "/app/data/monitors/monitors.yaml",
+			Setup:             "/app/data/setup/setup.yaml",
+			Watchdog:          "/app/data/watchdog/watchdog.yaml",
+			Limits:            "/app/data/limits/limits.yaml",
+			AppsTemplates:     "/app/data/apps/templates",
+			AuditLog:          "/app/data/audit/audit.jsonl",
+			DebugDumps:        "/app/data/debug",
+			Jobs:              "/app/data/jobs/jobs.yaml",
+			Backups:           "/app/data/backups",
+			Webhooks:          "/app/data/webhooks/webhooks.yaml",
+			Hooks:             "/app/data/hooks/hooks.yaml",
+			Notify:            "/app/data/notify/channels.yaml",
+			MQTT:              "/app/data/mqtt/bridges.yaml",
+			Services:          "/app/data/services/services.yaml",
+			ServiceTargets:    "/app/data/prometheus/services.json",
+			Files:             "/app/data/files",
+		},
+		Features: Features{
+			ProxyBackend:            "nginx",
+			DockerDiscovery:         true,
+			DockerDiscoveryInterval: 10 * time.Second,
+		},
+		Health: Health{
+			Targets: []HealthTarget{
+				{Name: "grafana", URL: "http://grafana:3000/api/health"},
+				{Name: "prometheus", URL: "http://prometheus:9090/-/ready"},
+				{Name: "loki", URL: "http://loki:3100/ready"},
+				{Name: "tempo", URL: "http://tempo:3200/ready"},
+				{Name: "nginx", URL: "http://nginx:80/"},
+			},
+			Timeout:  2 * time.Second,
+			CacheTTL: 5 * time.Second,
+		},
+		TLS: TLS{
+			Dir:             "/app/data/tls",
+			SelfSignedHosts: []string{},
+			ClientAuth:      "optional",
+			ClientRole:      "write",
+		},
+		Auth: Auth{
+			AdminUser:  "admin",
+			SessionTTL: 12 * time.Hour,
+			OIDC:       OIDC{RoleClaim: "groups"},
+			Guard: Guard{
+				Threshold:  10,
+				Window:     15 * time.Minute,
+				Lockout:    time.Minute,
+				MaxLockout: 24 * time.Hour,
+				Spike:      200,
+			},
+		},
+		RateLimits: RateLimits{
+			Backend: "memory",
+		},
+		Idempotency: Idempotency{
+			TTL: 24 * time.Hour,
+		},
+		Observe: Observe{
+			LokiURL:         "http://localhost:3100",
+			PrometheusURL:   "http://localhost:9090",
+			TempoURL:        "http://localhost:4318",
+			TempoQueryURL:   "http://localhost:3200",
+			PyroscopeURL:    "http://localhost:4040",
+			PromtailURL:     "http://promtail:9080",
+			AlertmanagerURL: "http://localhost:9093",
+			Tracing:         true,
+			Grafana: Grafana{
+				URL:      "http://grafana:3000",
+				User:     "admin",
+				Password: "admin",
+			},
+		},
+		Certs: Certs{
+			Dir:              "/app/data/certs",
+			NginxDir:         "/etc/nginx/certs",
+			ACMEDirectoryURL: "https://acme-v02.api.letsencrypt.org/directory",
+		},
+		Proxy: Proxy{
+			NginxReload:    "docker",
+			NginxContainer: "forge-nginx",
+			CaddyAdminURL:  "http://caddy:2019",
+			CaddyServer:    "forge",
+			CaddyTLSServer: "forge_tls",
+			ProbeBaseURL:   "http://nginx",
+		},
+		System: System{
+			HostProc:   "/proc",
+			Containers: "forge",
+		},
+		Apps: Apps{
+			Network: "forge-net",
+		},
+	}
+}
+
+// FromEnv loads the file named by FORGE_CONFIG, or DefaultPath when it
+// exists, then overlays the environment and validates the result
+func FromEnv() (*Config, error) {
+	path := os.Getenv("FORGE_CONFIG")
+	if path == "" {
+		if _, err := os.Stat(DefaultPath); err != nil {
+			return load("", os.LookupEnv)
+		}
+		path = DefaultPath
+	}
+	return load(path, os.LookupEnv)
+}
+
+// Load reads the config file at path, overlays the environment and
+// validates the result
+func Load(path string) (*Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookupenv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+		cfg.Path = path
+	}
+	for _, v := range cfg.envVars() {
+		if s, ok := lookupenv(v.name); ok && (s != "" || keepEmpty[v.name]) {
+			if err := v.set(s); err != nil {
+				return nil, fmt.Errorf("config: %s: %w", v.name, err)
+			}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envVar binds an environment variable to a setting
+type envVar struct {
+	name string
+	set  func(string) error
+}
+
+// keepEmpty are the variables applied even when set but empty, as for
+// them empty means something other than the default
+var keepEmpty = map[string]bool{"TLS_CLIENT_ROLE": true}
+
+func (c *Config) envVars() []envVar {
+	return []envVar{
+		{"PORT", intVar(&c.Server.Port)},
+		{"TLS_PORT", intVar(&c.Server.TLSPort)},
+		{"TLS_REDIRECT", boolVar(&c.Server.TLSRedirect)},
+		{"TLS_REDIRECT_PORT", intVar(&c.Server.TLSRedirectPort)},
+		{"HSTS_MAX_AGE", intVar(&c.Server.HSTSMaxAge)},
+		{"COMPRESS_PREFIXES", listVar(&c.Server.CompressPrefixes)},
+		{"SYSLOG_ADDR", stringVar(&c.Server.SyslogAddr)},
+		{"DEPENDENCY_WAIT", durationVar(&c.Server.DependencyWait)},
+		{"PORT_SERVES", stringVar(&c.Server.PortServes)},
+		{"LISTENERS", listenersVar(&c.Server.Listeners)},
+		{"UNIX_SOCKET_MODE", stringVar(&c.Server.UnixSocketMode)},
+
+		{"MYSQL_HOST", stringVar(&c.MySQL.Host)},
+		{"MYSQL_PORT", intVar(&c.MySQL.Port)},
+		{"MYSQL_USER", stringVar(&c.MySQL.User)},
+		{"MYSQL_PASSWORD", stringVar(&c.MySQL.Password)},
+		{"FORGE_DATABASE", stringVar(&c.MySQL.Database)},
+		{"CREDENTIALS_DATABASE", stringVar(&c.MySQL.CredentialsDatabase)},
+
+		{"REDIS_HOST", stringVar(&c.Redis.Host)},
+		{"REDIS_PORT", intVar(&c.Redis.Port)},
+		{"REDIS_PASSWORD", stringVar(&c.Redis.Password)},
+
+		{"STORAGE_ENDPOINT", stringVar(&c.Storage.Endpoint)},
+		{"STORAGE_PUBLIC_URL", stringVar(&c.Storage.PublicURL)},
+		{"STORAGE_REGION", stringVar(&c.Storage.Region)},
+		{"STORAGE_ACCESS_KEY", stringVar(&c.Storage.AccessKey)},
+		{"STORAGE_SECRET_KEY", stringVar(&c.Storage.SecretKey)},
+		{"STORAGE_BUCKET", stringVar(&c.Storage.Bucket)},
+		{"STORAGE_PATH_STYLE", boolVar(&c.Storage.PathStyle)},
+		{"STORAGE_ROLE_ARN", stringVar(&c.Storage.RoleARN)},
+
+		{"MESSAGING_URL", stringVar(&c.Messaging.URL)},
+		{"MESSAGING_PUBLIC_URL", stringVar(&c.Messaging.PublicURL)},
+		{"MESSAGING_USER", stringVar(&c.Messaging.User)},
+		{"MESSAGING_PASSWORD", stringVar(&c.Messaging.Password)},
+		{"MESSAGING_CLIENT_USER", stringVar(&c.Messaging.ClientUser)},
+		{"MESSAGING_CLIENT_PASSWORD", stringVar(&c.Messaging.ClientPassword)},
+		{"MESSAGING_READ_USER", stringVar(&c.Messaging.ReadUser)},
+		{"MESSAGING_READ_PASSWORD", stringVar(&c.Messaging.ReadPassword)},
+
+		{"MQTT_URL", stringVar(&c.MQTT.URL)},
+		{"MQTT_USER", stringVar(&c.MQTT.User)},
+		{"MQTT_PASSWORD", stringVar(&c.MQTT.Password)},
+		{"MQTT_CLIENT_ID", stringVar(&c.MQTT.ClientID)},
+
+		{"FILES_MAX_SIZE_MB", intVar(&c.Files.MaxSizeMB)},
+		{"FILES_QUOTA_MB", intVar(&c.Files.QuotaMB)},
+
+		{"ROUTES_CONFIG", stringVar(&c.Paths.Routes)},
+		{"NGINX_DYNAMIC_CONF", stringVar(&c.Paths.NginxConf)},
+		{"SECRETS_DIR", stringVar(&c.Paths.Secrets)},
+		{"AUTH_KEYS_FILE", stringVar(&c.Paths.AuthKeys)},
+		{"LOG_PIPELINES_CONFIG", stringVar(&c.Paths.LogPipelines)},
+		{"LOG_METRICS_CONFIG", stringVar(&c.Paths.LogMetrics)},
+		{"LOG_SAMPLING_CONFIG", stringVar(&c.Paths.LogSampling)},
+		{"PROMTAIL_SOURCES_CONFIG", stringVar(&c.Paths.PromtailSources)},
+		{"PROMTAIL_DYNAMIC_CONF", stringVar(&c.Paths.PromtailConf)},
+		{"PROMTAIL_ROUTE_LOG_DIR", stringVar(&c.Paths.PromtailRouteLogs)},
+		{"SNAPSHOTS_CONFIG", stringVar(&c.Paths.Snapshots)},
+		{"ALERTING_CONFIG", stringVar(&c.Paths.Alerting)},
+		{"ALERTMANAGER_CONF", stringVar(&c.Paths.AlertmanagerConf)},
+		{"ALERTING_LOG_RULES", stringVar(&c.Paths.AlertingLogRules)},
+		{"MONITORS_CONFIG", stringVar(&c.Paths.Monitors)},
+		{"SETUP_CONFIG", stringVar(&c.Paths.Setup)},
+		{"WATCHDOG_CONFIG", stringVar(&c.Paths.Watchdog)},
+		{"LIMITS_CONFIG", stringVar(&c.Paths.Limits)},
+		{"APPS_TEMPLATES_DIR", stringVar(&c.Paths.AppsTemplates)},
+		{"AUDIT_LOG", stringVar(&c.Paths.AuditLog)},
+		{"DEBUG_DUMP_DIR", stringVar(&c.Paths.DebugDumps)},
+		{"JOBS_CONFIG", stringVar(&c.Paths.Jobs)},
+		{"BACKUP_DIR", stringVar(&c.Paths.Backups)},
+		{"WEBHOOKS_CONFIG", stringVar(&c.Paths.Webhooks)},
+		{"HOOKS_CONFIG", stringVar(&c.Paths.Hooks)},
+		{"NOTIFY_CONFIG", stringVar(&c.Paths.Notify)},
+		{"MQTT_CONFIG", stringVar(&c.Paths.MQTT)},
+		{"SERVICES_CONFIG", stringVar(&c.Paths.Services)},
+		{"SERVICE_TARGETS_FILE", stringVar(&c.Paths.ServiceTargets)},
+		{"FILES_DIR", stringVar(&c.Paths.Files)},
+
+		{"PROXY_BACKEND", stringVar(&c.Features.ProxyBackend)},
+		{"DOCKER_DISCOVERY", boolVar(&c.Features.DockerDiscovery)},
+		{"DOCKER_DISCOVERY_INTERVAL", durationVar(&c.Features.DockerDiscoveryInterval)},
+		{"DEBUG_ENDPOINTS", boolVar(&c.Features.DebugEndpoints)},
+
+		{"HEALTH_TARGETS", healthTargetsVar(&c.Health.Targets)},
+		{"HEALTH_TIMEOUT", durationVar(&c.Health.Timeout)},
+		{"HEALTH_CACHE_TTL", durationVar(&c.Health.CacheTTL)},
+
+		{"EXTERNAL_HOST", stringVar(&c.Server.ExternalHost)},
+
+		{"TLS_CERT_FILE", stringVar(&c.TLS.CertFile)},
+		{"TLS_KEY_FILE", stringVar(&c.TLS.KeyFile)},
+		{"TLS_SELF_SIGNED", boolVar(&c.TLS.SelfSigned)},
+		{"TLS_DIR", stringVar(&c.TLS.Dir)},
+		{"TLS_SELF_SIGNED_HOSTS", listVar(&c.TLS.SelfSignedHosts)},
+		{"TLS_CLIENT_CA_FILE", stringVar(&c.TLS.ClientCAFile)},
+		{"TLS_CLIENT_AUTH", stringVar(&c.TLS.ClientAuth)},
+		{"TLS_CLIENT_ROLE", stringVar(&c.TLS.ClientRole)},
+		{"TLS_CLIENT_ROLE_MAP", mapVar(&c.TLS.ClientRoleMap)},
+
+		{"FORGE_ADMIN_KEY", stringVar(&c.Auth.AdminKey)},
+		{"FORGE_ADMIN_USER", stringVar(&c.Auth.AdminUser)},
+		{"FORGE_ADMIN_PASSWORD", stringVar(&c.Auth.AdminPassword)},
+		{"SESSION_TTL", durationVar(&c.Auth.SessionTTL)},
+		{"SESSION_COOKIE_SECURE", boolVar(&c.Auth.SessionCookieSecure)},
+		{"ADMIN_ALLOWED_CIDRS", stringVar(&c.Auth.AllowedCIDRs)},
+		{"ADMIN_TRUSTED_PROXIES", stringVar(&c.Auth.TrustedProxies)},
+		{"OIDC_ISSUER", stringVar(&c.Auth.OIDC.Issuer)},
+		{"OIDC_AUDIENCE", stringVar(&c.Auth.OIDC.Audience)},
+		{"OIDC_JWKS_URL", stringVar(&c.Auth.OIDC.JWKSURL)},
+		{"OIDC_ROLE_CLAIM", stringVar(&c.Auth.OIDC.RoleClaim)},
+		{"OIDC_ROLE_MAP", mapVar(&c.Auth.OIDC.RoleMap)},
+		{"OIDC_DEFAULT_ROLE", stringVar(&c.Auth.OIDC.DefaultRole)},
+		{"AUTH_GUARD_THRESHOLD", offIntVar(&c.Auth.Guard.Threshold)},
+		{"AUTH_GUARD_WINDOW", durationVar(&c.Auth.Guard.Window)},
+		{"AUTH_GUARD_LOCKOUT", durationVar(&c.Auth.Guard.Lockout)},
+		{"AUTH_GUARD_MAX_LOCKOUT", durationVar(&c.Auth.Guard.MaxLockout)},
+		{"AUTH_GUARD_SPIKE", intVar(&c.Auth.Guard.Spike)},
+
+		{"RATE_LIMITS", stringVar(&c.RateLimits.Client)},
+		{"RATE_LIMITS_GLOBAL", stringVar(&c.RateLimits.Global)},
+		{"RATE_LIMIT_BACKEND", stringVar(&c.RateLimits.Backend)},
+		{"IDEMPOTENCY_TTL", offDurationVar(&c.Idempotency.TTL)},
+
+		{"LOKI_URL", stringVar(&c.Observe.LokiURL)},
+		{"PROMETHEUS_URL", stringVar(&c.Observe.PrometheusURL)},
+		{"TEMPO_URL", stringVar(&c.Observe.TempoURL)},
+		{"TEMPO_QUERY_URL", stringVar(&c.Observe.TempoQueryURL)},
+		{"PYROSCOPE_URL", stringVar(&c.Observe.PyroscopeURL)},
+		{"PROMTAIL_URL", stringVar(&c.Observe.PromtailURL)},
+		{"ALERTMANAGER_URL", stringVar(&c.Observe.AlertmanagerURL)},
+		{"TRACING_ENABLED", boolVar(&c.Observe.Tracing)},
+		{"GRAFANA_URL", stringVar(&c.Observe.Grafana.URL)},
+		{"GRAFANA_ADMIN_USER", stringVar(&c.Observe.Grafana.User)},
+		{"GRAFANA_ADMIN_PASSWORD", stringVar(&c.Observe.Grafana.Password)},
+
+		{"SMTP_SMARTHOST", stringVar(&c.SMTP.Smarthost)},
+		{"SMTP_FROM", stringVar(&c.SMTP.From)},
+		{"SMTP_USERNAME", stringVar(&c.SMTP.Username)},
+		{"SMTP_PASSWORD", stringVar(&c.SMTP.Password)},
+
+		{"CERTS_DIR", stringVar(&c.Certs.Dir)},
+		{"CERTS_NGINX_DIR", stringVar(&c.Certs.NginxDir)},
+		{"ACME_EMAIL", stringVar(&c.Certs.ACMEEmail)},
+		{"ACME_DIRECTORY_URL", stringVar(&c.Certs.ACMEDirectoryURL)},
+		{"CLOUDFLARE_API_TOKEN", stringVar(&c.Certs.CloudflareToken)},
+
+		{"NGINX_RELOAD", stringVar(&c.Proxy.NginxReload)},
+		{"NGINX_CONTAINER", stringVar(&c.Proxy.NginxContainer)},
+		{"CADDY_ADMIN_URL", stringVar(&c.Proxy.CaddyAdminURL)},
+		{"CADDY_SERVER", stringVar(&c.Proxy.CaddyServer)},
+		{"CADDY_TLS_SERVER", stringVar(&c.Proxy.CaddyTLSServer)},
+		{"ROUTE_PROBE_BASE_URL", stringVar(&c.Proxy.ProbeBaseURL)},
+
+		{"DOCKER_HOST", stringVar(&c.Docker.Host)},
+		{"DOCKER_CERT_PATH", stringVar(&c.Docker.CertPath)},
+		{"DOCKER_TLS_VERIFY", setVar(&c.Docker.TLSVerify)},
+		{"XDG_RUNTIME_DIR", stringVar(&c.Docker.RuntimeDir)},
+
+		{"HOST_PROC", stringVar(&c.System.HostProc)},
+		{"HOST_ROOT", stringVar(&c.System.HostRoot)},
+		{"SYSTEM_CONTAINERS", stringVar(&c.System.Containers)},
+		{"SYSTEM_CONTAINER_LABELS", stringVar(&c.System.ContainerLabels)},
+		{"GPU_NVIDIA_SMI", stringVar(&c.System.GPUNvidiaSMI)},
+		{"GPU_DCGM_URL", stringVar(&c.System.GPUDCGMURL)},
+
+		{"APPS_NETWORK", stringVar(&c.Apps.Network)},
+		{"APPS_VOLUME_ROOT", stringVar(&c.Apps.VolumeRoot)},
+	}
+}
+
+func stringVar(p *string) func(string) error {
+	return func(s string) error {
+		*p = s
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", s)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", s)
+		}
+		*p = b
+		return nil
+	}
+}
+
+// offIntVar sets a number; "off" sets 0
+func offIntVar(p *int) func(string) error {
+	return func(s string) error {
+		if s == "off" {
+			*p = 0
+			return nil
+		}
+		return intVar(p)(s)
+	}
+}
+
+// setVar sets true for any value, as the docker CLI reads
+// DOCKER_TLS_VERIFY
+func setVar(p *bool) func(string) error {
+	return func(s string) error {
+		*p = true
+		return nil
+	}
+}
+
+// offDurationVar sets a duration; "off" sets 0
+func offDurationVar(p *time.Duration) func(string) error {
+	return func(s string) error {
+		if s == "off" {
+			*p = 0
+			return nil
+		}
+		return durationVar(p)(s)
+	}
+}
+
+func durationVar(p *time.Duration) func(string) error {
+	return func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("want a duration such as 10s, got %q", s)
+		}
+		*p = d
+		return nil
+	}
+}
+
+// listVar sets a comma-separated list; "off" sets an empty one
+func listVar(p *[]string) func(string) error {
+	return func(s string) error {
+		*p = []string{}
+		if s == "off" {
+			return nil
+		}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+		return nil
+	}
+}
+
+// healthTargetsVar sets targets from name=url pairs; "off" sets none
+func healthTargetsVar(p *[]HealthTarget) func(string) error {
+	return func(s string) error {
+		*p = []HealthTarget{}
+		if s == "off" {
+			return nil
+		}
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			name, u, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("want name=url pairs, got %q", pair)
+			}
+			*p = append(*p, HealthTarget{Name: strings.TrimSpace(name), URL: strings.TrimSpace(u)})
+		}
+		return nil
+	}
+}
+
+// mapVar sets a map from key=value pairs
+func mapVar(p *map[string]string) func(string) error {
+	return func(s string) error {
+		*p = make(map[string]string)
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("want key=value pairs, got %q", pair)
+			}
+			(*p)[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		return nil
+	}
+}
+
+// listenersVar sets listeners from serves=addr pairs; "off" sets none
+func listenersVar(p *[]Listener) func(string) error {
+	return func(s string) error {
+		*p = []Listener{}
+		if s == "off" {
+			return nil
+		}
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			serves, addr, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("want serves=addr pairs, got %q", pair)
+			}
+			*p = append(*p, Listener{Serves: strings.TrimSpace(serves), Addr: strings.TrimSpace(addr)})
+		}
+		return nil
+	}
+}
+
+// Validate checks the configuration, reporting every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	port := func(name string, p int) {
+		check(p > 0 && p < 65536, "%s: want a port between 1 and 65535, got %d", name, p)
+	}
+
+	port("server.port", c.Server.Port)
+	port("server.tls_port", c.Server.TLSPort)
+	if c.Server.TLSRedirectPort != 0 {
+		port("server.tls_redirect_port", c.Server.TLSRedirectPort)
+	}
+	check(c.Server.HSTSMaxAge >= 0, "server.hsts_max_age: want a number of seconds, got %d", c.Server.HSTSMaxAge)
+	check(c.Server.DependencyWait >= 0, "server.dependency_wait must not be negative")
+	for _, p := range c.Server.CompressPrefixes {
+		check(strings.HasPrefix(p, "/"), "server.compress_prefixes: %q must start with /", p)
+	}
+	check(validServes(c.Server.PortServes), "server.port_serves: want all, public or admin, got %q", c.Server.PortServes)
+	for _, l := range c.Server.Listeners {
+		check(validServes(l.Serves), "server.listeners: %s: want all, public or admin, got %q", l.Addr, l.Serves)
+		if path, ok := strings.CutPrefix(l.Addr, UnixPrefix); ok {
+			check(filepath.IsAbs(path), "server.listeners: %s: want an absolute socket path", l.Addr)
+			continue
+		}
+		_, p, err := net.SplitHostPort(l.Addr)
+		n, _ := strconv.Atoi(p)
+		check(err == nil && n > 0 && n < 65536, "server.listeners: want host:port or unix:/path, got %q", l.Addr)
+	}
+	if _, err := c.Server.SocketMode(); err != nil {
+		errs = append(errs, err)
+	}
+
+	check(c.MySQL.Host != "", "mysql.host is required")
+	port("mysql.port", c.MySQL.Port)
+	check(c.MySQL.User != "", "mysql.user is required")
+	check(c.MySQL.Database != "", "mysql.database is required")
+	check(c.MySQL.CredentialsDatabase != "", "mysql.credentials_database is required")
+
+	check(c.Redis.Host != "", "redis.host is required")
+	port("redis.port", c.Redis.Port)
+
+	for _, u := range []struct{ name, value string }{
+		{"storage.endpoint", c.Storage.Endpoint}, {"storage.public_url", c.Storage.ClientURL()},
+	} {
+		parsed, err := url.Parse(u.value)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" && strings.Trim(parsed.Path, "/") == "",
+			"%s: want an http(s) URL without a path, got %q", u.name, u.value)
+	}
+	check(c.Storage.Region != "", "storage.region is required")
+	check(c.Storage.AccessKey != "" && c.Storage.SecretKey != "", "storage.access_key and storage.secret_key are required")
+	check(c.Storage.Bucket != "", "storage.bucket is required")
+
+	for _, u := range []struct{ name, value string }{
+		{"messaging.url", c.Messaging.URL}, {"messaging.public_url", c.Messaging.ClientURL()},
+	} {
+		parsed, err := url.Parse(u.value)
+		check(err == nil && (parsed.Scheme == "nats" || parsed.Scheme == "tls") && parsed.Host != "" && strings.Trim(parsed.Path, "/") == "",
+			"%s: want a nats:// or tls:// URL without a path, got %q", u.name, u.value)
+	}
+
+	parsed, err := url.Parse(c.MQTT.URL)
+	check(err == nil && (parsed.Scheme == "mqtt" || parsed.Scheme == "mqtts") && parsed.Host != "" && strings.Trim(parsed.Path, "/") == "",
+		"mqtt.url: want an mqtt:// or mqtts:// URL without a path, got %q", c.MQTT.URL)
+	check(c.MQTT.ClientID != "" && len(c.MQTT.ClientID) <= 64, "mqtt.client_id: want 1-64 characters, got %q", c.MQTT.ClientID)
+
+	check(c.Files.MaxSizeMB > 0, "files.max_size_mb must be positive")
+	check(c.Files.QuotaMB >= 0, "files.quota_mb must not be negative")
+
+	for _, p := range []struct{ name, value string }{
+		{"routes", c.Paths.Routes}, {"nginx_conf", c.Paths.NginxConf}, {"secrets", c.Paths.Secrets},
+		{"auth_keys", c.Paths.AuthKeys}, {"log_pipelines", c.Paths.LogPipelines},
+		{"log_metrics", c.Paths.LogMetrics}, {"log_sampling", c.Paths.LogSampling},
+		{"promtail_sources", c.Paths.PromtailSources}, {"promtail_conf", c.Paths.PromtailConf},
+		{"promtail_route_logs", c.Paths.PromtailRouteLogs}, {"snapshots", c.Paths.Snapshots},
+		{"alerting", c.Paths.Alerting}, {"alertmanager_conf", c.Paths.AlertmanagerConf},
+		{"alerting_log_rules", c.Paths.AlertingLogRules}, {"monitors", c.Paths.Monitors},
+		{"setup", c.Paths.Setup}, {"watchdog", c.Paths.Watchdog}, {"limits", c.Paths.Limits},
+		{"apps_templates", c.Paths.AppsTemplates}, {"audit_log", c.Paths.AuditLog},
+		{"debug_dumps", c.Paths.DebugDumps}, {"jobs", c.Paths.Jobs}, {"backups", c.Paths.Backups},
+		{"webhooks", c.Paths.Webhooks}, {"hooks", c.Paths.Hooks}, {"notify", c.Paths.Notify},
+		{"mqtt", c.Paths.MQTT}, {"services", c.Paths.Services},
+		{"service_targets", c.Paths.ServiceTargets}, {"files", c.Paths.Files},
+	} {
+		check(p.value != "", "paths.%s is required", p.name)
+	}
+
+	switch c.Features.ProxyBackend {
+	case "nginx", "caddy":
+	default:
+		errs = append(errs, fmt.Errorf("features.proxy_backend: want nginx or caddy, got %q", c.Features.ProxyBackend))
+	}
+	check(c.Features.DockerDiscoveryInterval > 0, "features.docker_discovery_interval must be positive")
+
+	names := map[string]bool{"api": true, "mysql": true, "redis": true}
+	for _, t := range c.Health.Targets {
+		check(t.Name != "" && !names[t.Name], "health.targets: name %q is empty or taken", t.Name)
+		names[t.Name] = true
+		u, err := url.Parse(t.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"health.targets: %s: want an http(s) URL, got %q", t.Name, t.URL)
+	}
+	check(c.Health.Timeout > 0, "health.timeout must be positive")
+	check(c.Health.CacheTTL >= 0, "health.cache_ttl must not be negative")
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls.cert_file and tls.key_file must be set together")
+	check(!c.TLS.SelfSigned || c.TLS.Dir != "", "tls.dir is required with tls.self_signed")
+	check(c.TLS.ClientCAFile == "" || c.TLS.CertFile != "" || c.TLS.SelfSigned,
+		"tls.client_ca_file needs tls.cert_file and tls.key_file, or tls.self_signed")
+	check(c.TLS.ClientAuth == "optional" || c.TLS.ClientAuth == "require", "tls.client_auth: want optional or require, got %q", c.TLS.ClientAuth)
+
+	check(c.Auth.AdminUser != "", "auth.admin_user is required")
+	check(c.Auth.SessionTTL > 0, "auth.session_ttl must be positive")
+	check(c.Auth.OIDC.Issuer == "" || c.Auth.OIDC.Audience != "", "auth.oidc.audience is required with auth.oidc.issuer")
+	check(c.Auth.Guard.Threshold >= 0, "auth.guard.threshold must not be negative")
+	check(c.Auth.Guard.Spike >= 0, "auth.guard.spike must not be negative")
+	check(c.Auth.Guard.Window > 0 && c.Auth.Guard.Lockout > 0 && c.Auth.Guard.MaxLockout > 0,
+		"auth.guard.window, lockout and max_lockout must be positive")
+
+	check(c.RateLimits.Backend == "memory" || c.RateLimits.Backend == "redis",
+		"rate_limits.backend: want memory or redis, got %q", c.RateLimits.Backend)
+	check(c.Idempotency.TTL >= 0, "idempotency.ttl must not be negative")
+
+	for _, u := range []struct{ name, value string }{
+		{"observe.loki_url", c.Observe.LokiURL}, {"observe.prometheus_url", c.Observe.PrometheusURL},
+		{"observe.tempo_url", c.Observe.TempoURL}, {"observe.tempo_query_url", c.Observe.TempoQueryURL},
+		{"observe.pyroscope_url", c.Observe.PyroscopeURL}, {"observe.promtail_url", c.Observe.PromtailURL},
+		{"observe.alertmanager_url", c.Observe.AlertmanagerURL}, {"observe.grafana.url", c.Observe.Grafana.URL},
+		{"certs.acme_directory_url", c.Certs.ACMEDirectoryURL}, {"proxy.caddy_admin_url", c.Proxy.CaddyAdminURL},
+		{"proxy.probe_base_url", c.Proxy.ProbeBaseURL},
+	} {
+		parsed, err := url.Parse(u.value)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "",
+			"%s: want an http(s) URL, got %q", u.name, u.value)
+	}
+
+	check(c.Certs.Dir != "" && c.Certs.NginxDir != "", "certs.dir and certs.nginx_dir are required")
+	check(c.Proxy.NginxContainer != "", "proxy.nginx_container is required")
+	check(c.Proxy.CaddyServer != "" && c.Proxy.CaddyTLSServer != "", "proxy.caddy_server and proxy.caddy_tls_server are required")
+	check(c.System.HostProc != "", "system.host_proc is required")
+	check(c.Apps.Network != "", "apps.network is required")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config invalid: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func validServes(s string) bool {
+	return s == ServeAll || s == ServePublic || s == ServeAdmin
+}
+
+// SocketMode returns the file mode of Unix socket listeners
+func (s Server) SocketMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s.UnixSocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("server.unix_socket_mode: want an octal mode such as 0660, got %q", s.UnixSocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// RedirectPort returns the HTTPS port plain HTTP requests are sent to
+func (s Server) RedirectPort() int {
+	if s.TLSRedirectPort != 0 {
+		return s.TLSRedirectPort
+	}
+	return s.TLSPort
+}
@@ -0,0 +1,131 @@
+// Package credentials issues short-lived, scoped MySQL and Redis accounts
+// and object storage keys to API callers, so connection details never
+// carry the root secrets
+//
+// Every MySQL and Redis credential is a real server account named
+// forge_<expiry>_<random>. The expiry in the name lets the sweeper drop
+// expired accounts (and kill their connections) without any state of its
+// own, so credentials survive API restarts and are still cleaned up.
+// Storage keys come from the store's STS API and expire on their own.
+package credentials
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/forge/api/internal/auth"
+)
+
+// Access levels of a credential
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+const (
+	// DefaultTTL is how long a credential is valid unless asked otherwise
+	DefaultTTL = time.Hour
+	// MinTTL and MaxTTL bound requested lifetimes
+	MinTTL = time.Minute
+	MaxTTL = 24 * time.Hour
+
+	// sweepInterval is how often expired accounts are dropped
+	sweepInterval = time.Minute
+)
+
+var (
+	// ErrInvalidRequest is returned for bad scopes, access levels or TTLs
+	ErrInvalidRequest = errors.New("invalid credential request")
+
+	// userPattern matches issued account names and captures the expiry
+	userPattern = regexp.MustCompile(`^forge_([0-9]{10})_[0-9a-f]{12}$`)
+)
+
+// Request describes the credential a caller wants
+type Request struct {
+	// Requester identifies the caller (API key ID or token subject)
+	Requester string
+	// Access is AccessRead or AccessWrite; empty picks the most the
+	// caller's role allows
+	Access string
+	// Scope is the database (MySQL), key prefix (Redis) or bucket/prefix
+	// (storage); empty uses the broker's default
+	Scope string
+	// TTL is the lifetime; zero means DefaultTTL
+	TTL time.Duration
+}
+
+// Credential is an issued account
+type Credential struct {
+	User     string
+	Password string
+	// Token is the session token of storage keys
+	Token     string
+	Access    string
+	Scope     string
+	ExpiresAt time.Time
+}
+
+// ResolveAccess returns the access level for a caller with role: the
+// requested one if the role allows it, otherwise auth.ErrForbidden
+func ResolveAccess(role, requested string) (string, error) {
+	switch requested {
+	case "":
+		if auth.Allows(role, auth.RoleWrite) {
+			return AccessWrite, nil
+		}
+		return AccessRead, nil
+	case AccessRead:
+		return AccessRead, nil
+	case AccessWrite:
+		if !auth.Allows(role, auth.RoleWrite) {
+			return "", fmt.Errorf("%w: write credentials need the write role", auth.ErrForbidden)
+		}
+		return AccessWrite, nil
+	}
+	return "", fmt.Errorf("%w: access must be %s or %s", ErrInvalidRequest, AccessRead, AccessWrite)
+}
+
+// ttl validates a requested lifetime
+func ttl(d time.Duration) (time.Duration, error) {
+	if d == 0 {
+		return DefaultTTL, nil
+	}
+	if d < MinTTL || d > MaxTTL {
+		return 0, fmt.Errorf("%w: ttl must be between %s and %s", ErrInvalidRequest, MinTTL, MaxTTL)
+	}
+	return d, nil
+}
+
+// newAccount generates an account name carrying its expiry, and a password
+func newAccount(expiresAt time.Time) (user, password string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	user = fmt.Sprintf("forge_%d_%s", expiresAt.Unix(), hex.EncodeToString(id))
+	return user, base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// expiry returns when an issued account expires; false for other accounts
+func expiry(user string) (time.Time, bool) {
+	m := userPattern.FindStringSubmatch(user)
+	if m == nil {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
@@ -0,0 +1,197 @@
+package credentials
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+)
+
+// maxUserConnections caps concurrent connections per issued MySQL account
+const maxUserConnections = 20
+
+// Privileges granted on the scoped database; neither level includes GRANT
+// OPTION or any server-wide privilege
+var mysqlPrivileges = map[string]string{
+	AccessRead:  "SELECT, SHOW VIEW",
+	AccessWrite: "SELECT, INSERT, UPDATE, DELETE, CREATE, ALTER, DROP, INDEX, REFERENCES, CREATE TEMPORARY TABLES, LOCK TABLES, CREATE VIEW, SHOW VIEW, EXECUTE, TRIGGER",
+}
+
+var (
+	databasePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+	// systemDatabases can never be granted
+	systemDatabases = map[string]bool{
+		"mysql":              true,
+		"sys":                true,
+		"information_schema": true,
+		"performance_schema": true,
+	}
+)
+
+// MySQLBroker issues MySQL accounts scoped to one database
+type MySQLBroker struct {
+	db              *sql.DB
+	defaultDatabase string
+	reserved        map[string]bool
+}
+
+// NewMySQLBroker creates a broker that issues accounts through db (which
+// must be able to create users and grant privileges). Credentials without a
+// database are scoped to defaultDatabase; reserved databases, such as
+// Forge's own, are refused like the system ones.
+func NewMySQLBroker(db *sql.DB, defaultDatabase string, reserved ...string) *MySQLBroker {
+	b := &MySQLBroker{db: db, defaultDatabase: defaultDatabase, reserved: map[string]bool{}}
+	for _, name := range reserved {
+		b.reserved[strings.ToLower(name)] = true
+	}
+	return b
+}
+
+// Issue creates an account for req, scoped to req.Scope or the default
+// database
+func (b *MySQLBroker) Issue(ctx context.Context, req Request) (*Credential, error) {
+	database := req.Scope
+	if database == "" {
+		database = b.defaultDatabase
+	}
+	if !databasePattern.MatchString(database) {
+		return nil, fmt.Errorf("%w: database must be 1-64 letters, digits or underscores", ErrInvalidRequest)
+	}
+	if lower := strings.ToLower(database); systemDatabases[lower] || b.reserved[lower] {
+		return nil, fmt.Errorf("%w: database %s is reserved", ErrInvalidRequest, database)
+	}
+	privileges, ok := mysqlPrivileges[req.Access]
+	if !ok {
+		return nil, fmt.Errorf("%w: access must be %s or %s", ErrInvalidRequest, AccessRead, AccessWrite)
+	}
+	lifetime, err := ttl(req.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(lifetime).Truncate(time.Second)
+	user, password, err := newAccount(expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	attribute, _ := json.Marshal(map[string]any{
+		"requester":  sanitize(req.Requester),
+		"access":     req.Access,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+
+	// Account names and passwords cannot be placeholders; both are
+	// generated from safe alphabets and the attribute is sanitized JSON
+	account := "'" + user + "'@'%'"
+	create := fmt.Sprintf("CREATE USER %s IDENTIFIED BY '%s' WITH MAX_USER_CONNECTIONS %d ATTRIBUTE '%s'",
+		account, password, maxUserConnections, attribute)
+	if _, err := b.db.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("create mysql user: %w", err)
+	}
+	if _, err := b.db.ExecContext(ctx, fmt.Sprintf("GRANT %s ON `%s`.* TO %s", privileges, database, account)); err != nil {
+		b.drop(context.Background(), user)
+		return nil, fmt.Errorf("grant mysql privileges: %w", err)
+	}
+
+	return &Credential{
+		User:      user,
+		Password:  password,
+		Access:    req.Access,
+		Scope:     database,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Run drops expired accounts every minute until ctx is done
+func (b *MySQLBroker) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.sweep(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("Failed to drop expired MySQL credentials", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *MySQLBroker) sweep(ctx context.Context) error {
+	rows, err := b.db.QueryContext(ctx, "SELECT User FROM mysql.user WHERE User LIKE 'forge\\_%' AND Host = '%'")
+	if err != nil {
+		return err
+	}
+	var expired []string
+	for rows.Next() {
+		var user string
+		if err := rows.Scan(&user); err != nil {
+			rows.Close()
+			return err
+		}
+		if at, ok := expiry(user); ok && time.Now().After(at) {
+			expired = append(expired, user)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, user := range expired {
+		if err := b.drop(ctx, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drop removes an issued account and closes its open connections, which
+// would otherwise outlive the account
+func (b *MySQLBroker) drop(ctx context.Context, user string) error {
+	if !userPattern.MatchString(user) {
+		return fmt.Errorf("not an issued account: %s", user)
+	}
+	if _, err := b.db.ExecContext(ctx, "DROP USER IF EXISTS '"+user+"'@'%'"); err != nil {
+		return fmt.Errorf("drop mysql user %s: %w", user, err)
+	}
+
+	rows, err := b.db.QueryContext(ctx, "SELECT ID FROM information_schema.PROCESSLIST WHERE USER = ?", user)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	for _, id := range ids {
+		b.db.ExecContext(ctx, fmt.Sprintf("KILL CONNECTION %d", id)) // may already be gone
+	}
+	return nil
+}
+
+// sanitize keeps requester identifiers safe to embed in SQL literals
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("_-.@:|", r):
+			return r
+		}
+		return -1
+	}, s)
+}
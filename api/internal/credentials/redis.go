@@ -0,0 +1,96 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/cache"
+	"github.com/forge/api/internal/logger"
+)
+
+// Command categories per access level. Connection commands (AUTH, PING,
+// HELLO) are allowed; admin and dangerous ones (FLUSHALL, KEYS, CONFIG,
+// DEBUG, ...) never are.
+var redisRules = map[string][]string{
+	AccessRead:  {"-@all", "+@read", "+@connection", "-@admin", "-@dangerous"},
+	AccessWrite: {"-@all", "+@read", "+@write", "+@connection", "-@admin", "-@dangerous"},
+}
+
+// RedisBroker issues Redis ACL users limited to a key prefix
+type RedisBroker struct {
+	client *cache.RedisClient
+}
+
+// NewRedisBroker creates a broker that manages ACL users through client
+func NewRedisBroker(client *cache.RedisClient) *RedisBroker {
+	return &RedisBroker{client: client}
+}
+
+// Issue creates an ACL user for req, limited to keys starting with
+// req.Scope (all keys when empty)
+func (b *RedisBroker) Issue(ctx context.Context, req Request) (*Credential, error) {
+	if strings.ContainsAny(req.Scope, "*?[]\\ \t\r\n") || len(req.Scope) > 128 {
+		return nil, fmt.Errorf("%w: key prefix may not contain glob characters or whitespace", ErrInvalidRequest)
+	}
+	commands, ok := redisRules[req.Access]
+	if !ok {
+		return nil, fmt.Errorf("%w: access must be %s or %s", ErrInvalidRequest, AccessRead, AccessWrite)
+	}
+	lifetime, err := ttl(req.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(lifetime).Truncate(time.Second)
+	user, password, err := newAccount(expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	rules := append([]string{"reset", "on", ">" + password, "~" + req.Scope + "*"}, commands...)
+	if err := b.client.ACLSetUser(ctx, user, rules...); err != nil {
+		return nil, fmt.Errorf("create redis user: %w", err)
+	}
+
+	return &Credential{
+		User:      user,
+		Password:  password,
+		Access:    req.Access,
+		Scope:     req.Scope,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Run deletes expired ACL users every minute until ctx is done
+func (b *RedisBroker) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.sweep(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("Failed to delete expired Redis credentials", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *RedisBroker) sweep(ctx context.Context) error {
+	users, err := b.client.ACLUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if at, ok := expiry(user); ok && time.Now().After(at) {
+			if err := b.client.ACLDelUser(ctx, user); err != nil {
+				return fmt.Errorf("delete redis user %s: %w", user, err)
+			}
+		}
+	}
+	return nil
+}
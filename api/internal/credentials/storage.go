@@ -0,0 +1,115 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/storage"
+)
+
+// Object actions per access level. Listing is allowed under the prefix
+// only; bucket administration never is.
+var storageActions = map[string][]string{
+	AccessRead:  {"s3:GetObject"},
+	AccessWrite: {"s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"},
+}
+
+// StorageBroker issues temporary object storage keys limited to a bucket
+// and key prefix
+type StorageBroker struct {
+	client *storage.Client
+}
+
+// NewStorageBroker creates a broker that requests keys through client
+func NewStorageBroker(client *storage.Client) *StorageBroker {
+	return &StorageBroker{client: client}
+}
+
+// Issue requests keys for req from the store's STS API. req.Scope is a
+// bucket, optionally followed by /prefix; empty means the whole default
+// bucket. Keys last at least storage.MinSessionDuration.
+func (b *StorageBroker) Issue(ctx context.Context, req Request) (*Credential, error) {
+	bucket, prefix, _ := strings.Cut(req.Scope, "/")
+	bucket = b.client.Bucket(bucket)
+	if !storage.ValidBucket(bucket) {
+		return nil, fmt.Errorf("%w: bucket names are 3-63 lowercase letters, digits, '.' or '-'", ErrInvalidRequest)
+	}
+	if strings.ContainsAny(prefix, "*?$") || len(prefix) > 512 {
+		return nil, fmt.Errorf("%w: prefix may not contain '*', '?' or '$'", ErrInvalidRequest)
+	}
+	actions, ok := storageActions[req.Access]
+	if !ok {
+		return nil, fmt.Errorf("%w: access must be %s or %s", ErrInvalidRequest, AccessRead, AccessWrite)
+	}
+	lifetime, err := ttl(req.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if lifetime < storage.MinSessionDuration || lifetime > storage.MaxSessionDuration {
+		return nil, fmt.Errorf("%w: storage credentials last between %s and %s", ErrInvalidRequest, storage.MinSessionDuration, storage.MaxSessionDuration)
+	}
+
+	policy, err := storagePolicy(bucket, prefix, actions)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := b.client.AssumeRole(ctx, policy, lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("issue storage keys: %w", err)
+	}
+
+	expiresAt := keys.Expires
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(lifetime)
+	}
+	return &Credential{
+		User:      keys.AccessKey,
+		Password:  keys.SecretKey,
+		Token:     keys.SessionToken,
+		Access:    req.Access,
+		Scope:     bucket + "/" + prefix,
+		ExpiresAt: expiresAt.Truncate(time.Second),
+	}, nil
+}
+
+// storagePolicy builds the session policy that limits keys to objects
+// under prefix in bucket
+func storagePolicy(bucket, prefix string, actions []string) (string, error) {
+	type statement struct {
+		Effect    string         `json:"Effect"`
+		Action    []string       `json:"Action"`
+		Resource  []string       `json:"Resource"`
+		Condition map[string]any `json:"Condition,omitempty"`
+	}
+	policy := struct {
+		Version   string      `json:"Version"`
+		Statement []statement `json:"Statement"`
+	}{
+		Version: "2012-10-17",
+		Statement: []statement{
+			{
+				Effect:   "Allow",
+				Action:   actions,
+				Resource: []string{"arn:aws:s3:::" + bucket + "/" + prefix + "*"},
+			},
+			{
+				Effect:   "Allow",
+				Action:   []string{"s3:ListBucket"},
+				Resource: []string{"arn:aws:s3:::" + bucket},
+				Condition: map[string]any{
+					"StringLike": map[string]string{"s3:prefix": prefix + "*"},
+				},
+			},
+			{
+				Effect:   "Allow",
+				Action:   []string{"s3:GetBucketLocation"},
+				Resource: []string{"arn:aws:s3:::" + bucket},
+			},
+		},
+	}
+	data, err := json.Marshal(policy)
+	return string(data), err
+}
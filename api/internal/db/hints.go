@@ -0,0 +1,184 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column types inferred from query results
+const (
+	TypeInteger  = "integer"
+	TypeFloat    = "float"
+	TypeBoolean  = "boolean"
+	TypeDatetime = "datetime"
+	TypeDate     = "date"
+	TypeString   = "string"
+	TypeNull     = "null" // column had no non-empty values
+)
+
+// Visualization kinds suggested for query results
+const (
+	VizTimeSeries = "timeseries"
+	VizBar        = "bar"
+	VizStat       = "stat"
+	VizTable      = "table"
+)
+
+// maxBarCategories is the most rows a bar chart is suggested for
+const maxBarCategories = 50
+
+// datetimeLayouts are the formats MySQL and common clients produce
+var datetimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// ColumnHint describes the inferred type of one column
+type ColumnHint struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Numeric  bool   `json:"numeric"`
+	Temporal bool   `json:"temporal"`
+	Nulls    int    `json:"nulls"`
+	Distinct int    `json:"distinct"`
+}
+
+// Visualization is a suggested chart for the results
+type Visualization struct {
+	Kind   string   `json:"kind"`
+	X      string   `json:"x,omitempty"`
+	Y      []string `json:"y,omitempty"`
+	Reason string   `json:"reason"`
+}
+
+// VisualizationHints is the result of analysing query results
+type VisualizationHints struct {
+	Columns     []ColumnHint    `json:"columns"`
+	Suggestions []Visualization `json:"suggestions"` // best first; always ends with "table"
+}
+
+// InferHints infers column types and suggests visualizations for rows as
+// returned by Query (all values stringified, NULL as "")
+func InferHints(columns []string, rows []map[string]string) VisualizationHints {
+	hints := VisualizationHints{Columns: make([]ColumnHint, 0, len(columns))}
+
+	for _, col := range columns {
+		hints.Columns = append(hints.Columns, inferColumn(col, rows))
+	}
+
+	var temporal, numeric, categorical []string
+	for _, c := range hints.Columns {
+		switch {
+		case c.Temporal:
+			temporal = append(temporal, c.Name)
+		case c.Numeric:
+			numeric = append(numeric, c.Name)
+		case c.Type == TypeString || c.Type == TypeBoolean:
+			categorical = append(categorical, c.Name)
+		}
+	}
+
+	if len(temporal) > 0 && len(numeric) > 0 && len(rows) > 1 {
+		hints.Suggestions = append(hints.Suggestions, Visualization{
+			Kind:   VizTimeSeries,
+			X:      temporal[0],
+			Y:      numeric,
+			Reason: "time column with numeric values",
+		})
+	}
+	if len(rows) == 1 && len(numeric) == 1 && len(columns) == 1 {
+		hints.Suggestions = append(hints.Suggestions, Visualization{
+			Kind:   VizStat,
+			Y:      numeric,
+			Reason: "single numeric value",
+		})
+	}
+	if len(categorical) > 0 && len(numeric) > 0 && len(rows) > 0 && len(rows) <= maxBarCategories {
+		hints.Suggestions = append(hints.Suggestions, Visualization{
+			Kind:   VizBar,
+			X:      categorical[0],
+			Y:      numeric,
+			Reason: "categorical column with numeric values",
+		})
+	}
+	hints.Suggestions = append(hints.Suggestions, Visualization{
+		Kind:   VizTable,
+		Reason: "always applicable",
+	})
+
+	return hints
+}
+
+// inferColumn picks the narrowest type that fits every non-empty value
+func inferColumn(name string, rows []map[string]string) ColumnHint {
+	hint := ColumnHint{Name: name}
+	distinct := make(map[string]struct{})
+
+	isInt, isFloat, isBool, isDatetime, isDate := true, true, true, true, true
+	seen := 0
+	for _, row := range rows {
+		v := strings.TrimSpace(row[name])
+		if v == "" {
+			hint.Nulls++
+			continue
+		}
+		seen++
+		distinct[v] = struct{}{}
+
+		if isInt {
+			if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+				isInt = false
+			}
+		}
+		if isFloat {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				isFloat = false
+			}
+		}
+		if isBool {
+			isBool = v == "true" || v == "false"
+		}
+		if isDate {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				isDate = false
+			}
+		}
+		if isDatetime {
+			isDatetime = parsesAsDatetime(v)
+		}
+	}
+	hint.Distinct = len(distinct)
+
+	switch {
+	case seen == 0:
+		hint.Type = TypeNull
+	case isBool:
+		hint.Type = TypeBoolean
+	case isInt:
+		hint.Type = TypeInteger
+	case isFloat:
+		hint.Type = TypeFloat
+	case isDate:
+		hint.Type = TypeDate
+	case isDatetime:
+		hint.Type = TypeDatetime
+	default:
+		hint.Type = TypeString
+	}
+	hint.Numeric = hint.Type == TypeInteger || hint.Type == TypeFloat
+	hint.Temporal = hint.Type == TypeDate || hint.Type == TypeDatetime
+
+	return hint
+}
+
+func parsesAsDatetime(v string) bool {
+	for _, layout := range datetimeLayouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -4,71 +4,55 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/tracing"
 	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MySQLClient struct {
 	db *sql.DB
 }
 
-func NewMySQLClient() (*MySQLClient, error) {
-	host := os.Getenv("MYSQL_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	port := os.Getenv("MYSQL_PORT")
-	if port == "" {
-		port = "3306"
-	}
-	user := os.Getenv("MYSQL_USER")
-	if user == "" {
-		user = "root"
-	}
-	password := os.Getenv("MYSQL_PASSWORD")
-	if password == "" {
-		password = "forgeroot"
-	}
-	
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/", user, password, host, port)
-	
-	db, err := sql.Open("mysql", dsn)
+// NewMySQLClient creates a client for the server in cfg. It does not
+// connect; connections are made on first use and re-established after
+// failures, and a deps.Supervisor tracks whether MySQL is reachable.
+func NewMySQLClient(cfg config.MySQL) (*MySQLClient, error) {
+	db, err := sql.Open("mysql", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
-	
-	// Retry connection with backoff (MySQL might still be starting)
-	maxRetries := 10
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		if err := db.Ping(); err == nil {
-			return &MySQLClient{db: db}, nil
-		} else {
-			lastErr = err
-	}
-	
-		// Wait before retrying (1s, 2s, 3s, ... up to 5s)
-		waitTime := time.Duration(min(i+1, 5)) * time.Second
-		time.Sleep(waitTime)
-	}
-	
-	return nil, fmt.Errorf("failed to connect after %d retries: %w", maxRetries, lastErr)
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	return &MySQLClient{db: db}, nil
 }
 
 func (c *MySQLClient) Ping(ctx context.Context) error {
 	return c.db.PingContext(ctx)
 }
 
+// ServerTime returns the MySQL server's current time (microsecond precision)
+func (c *MySQLClient) ServerTime(ctx context.Context) (time.Time, error) {
+	var micros int64
+	err := c.db.QueryRowContext(ctx, "SELECT CAST(UNIX_TIMESTAMP(NOW(6)) * 1000000 AS SIGNED)").Scan(&micros)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(micros), nil
+}
+
 func (c *MySQLClient) Query(ctx context.Context, query string, database string) ([]map[string]string, []string, error) {
+	ctx, span := tracing.StartKind(ctx, "mysql query", trace.SpanKindClient, spanAttributes(query, database)...)
+	rows, columns, err := c.query(ctx, query, database)
+	span.SetAttributes(attribute.Int("db.rows_returned", len(rows)))
+	tracing.End(span, err)
+	return rows, columns, err
+}
+
+func (c *MySQLClient) query(ctx context.Context, query string, database string) ([]map[string]string, []string, error) {
 	db := c.db
 	
 	// If database specified, use it
@@ -124,6 +108,14 @@ func (c *MySQLClient) Query(ctx context.Context, query string, database string)
 }
 
 func (c *MySQLClient) Execute(ctx context.Context, query string, database string) (int64, int64, error) {
+	ctx, span := tracing.StartKind(ctx, "mysql execute", trace.SpanKindClient, spanAttributes(query, database)...)
+	affected, lastID, err := c.execute(ctx, query, database)
+	span.SetAttributes(attribute.Int64("db.rows_affected", affected))
+	tracing.End(span, err)
+	return affected, lastID, err
+}
+
+func (c *MySQLClient) execute(ctx context.Context, query string, database string) (int64, int64, error) {
 	db := c.db
 	
 	if database != "" {
@@ -144,6 +136,25 @@ func (c *MySQLClient) Execute(ctx context.Context, query string, database string
 	return affected, lastID, nil
 }
 
+// DB returns the underlying connection pool for packages that manage their own tables
+// spanAttributes describes a statement without recording its (possibly
+// sensitive) text: only the operation keyword is kept
+func spanAttributes(query, database string) []attribute.KeyValue {
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query)+" ", " ", 2)[0])
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMySQL,
+		semconv.DBOperationName(operation),
+	}
+	if database != "" {
+		attrs = append(attrs, semconv.DBNamespace(database))
+	}
+	return attrs
+}
+
+func (c *MySQLClient) DB() *sql.DB {
+	return c.db
+}
+
 func (c *MySQLClient) Close() error {
 	return c.db.Close()
 }
@@ -0,0 +1,109 @@
+// Package deprecation tracks deprecated endpoints and who still calls them
+//
+// Endpoints are marked deprecated in code with Register. The middleware
+// adds Deprecation/Sunset/Link headers (RFC 9745, RFC 8594) to responses
+// and counts usage, which is reported at /api/v1/deprecations.
+package deprecation
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Endpoint describes a deprecated endpoint or RPC
+type Endpoint struct {
+	Path        string    `json:"path"`                  // exact request path, e.g. "/forge.v1.ForgeService/Info"
+	Since       time.Time `json:"since"`                 // when it was deprecated
+	Sunset      time.Time `json:"sunset,omitempty"`      // when it will be removed (optional)
+	Replacement string    `json:"replacement,omitempty"` // successor endpoint (optional)
+	Message     string    `json:"message,omitempty"`
+}
+
+// Usage is the per-endpoint usage report
+type Usage struct {
+	Endpoint
+	Calls         int64            `json:"calls"`
+	LastCalledAt  time.Time        `json:"last_called_at,omitempty"`
+	PastSunset    bool             `json:"past_sunset"`
+	CallsByClient map[string]int64 `json:"calls_by_client,omitempty"`
+}
+
+// maxClientsPerEndpoint bounds the per-client breakdown to avoid unbounded growth
+const maxClientsPerEndpoint = 100
+
+// Registry holds deprecated endpoints and their usage
+type Registry struct {
+	mu        sync.RWMutex
+	endpoints map[string]*Usage
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{endpoints: make(map[string]*Usage)}
+}
+
+// Register marks an endpoint as deprecated
+func (r *Registry) Register(e Endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoints[e.Path] = &Usage{
+		Endpoint:      e,
+		CallsByClient: make(map[string]int64),
+	}
+}
+
+// Lookup returns the deprecation for path, if any
+func (r *Registry) Lookup(path string) (Endpoint, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.endpoints[path]
+	if !ok {
+		return Endpoint{}, false
+	}
+	return u.Endpoint, true
+}
+
+// Record counts a call to a deprecated endpoint from client
+func (r *Registry) Record(path, client string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.endpoints[path]
+	if !ok {
+		return
+	}
+	u.Calls++
+	u.LastCalledAt = time.Now().UTC()
+
+	if client == "" {
+		client = "unknown"
+	}
+	if _, seen := u.CallsByClient[client]; seen || len(u.CallsByClient) < maxClientsPerEndpoint {
+		u.CallsByClient[client]++
+	} else {
+		u.CallsByClient["other"]++
+	}
+}
+
+// Report returns usage for all deprecated endpoints, sorted by path
+func (r *Registry) Report() []Usage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	report := make([]Usage, 0, len(r.endpoints))
+	for _, u := range r.endpoints {
+		entry := *u
+		entry.PastSunset = !u.Sunset.IsZero() && now.After(u.Sunset)
+		entry.CallsByClient = make(map[string]int64, len(u.CallsByClient))
+		for k, v := range u.CallsByClient {
+			entry.CallsByClient[k] = v
+		}
+		report = append(report, entry)
+	}
+
+	sort.Slice(report, func(i, j int) bool { return report[i].Path < report[j].Path })
+	return report
+}
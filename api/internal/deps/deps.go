@@ -0,0 +1,136 @@
+// Package deps tracks the availability of Forge's downstream dependencies
+//
+// Handlers consult the registry instead of checking for nil clients, so a
+// missing dependency produces a consistent, explained degradation: a
+// fallback mode where one exists, or an Unavailable error carrying the
+// reason and a hint about which capabilities are affected.
+package deps
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Modes a dependency can be in
+const (
+	ModeNormal      = "normal"
+	ModeDegraded    = "degraded"    // unavailable, but a fallback is serving requests
+	ModeUnavailable = "unavailable" // unavailable and the dependent features are off
+)
+
+// State describes one dependency
+type State struct {
+	Name      string    `json:"name"`
+	Mode      string    `json:"mode"`
+	Reason    string    `json:"reason,omitempty"`
+	Fallback  string    `json:"fallback,omitempty"`
+	Affected  []string  `json:"affected,omitempty"` // capabilities lost or reduced
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Available reports whether the real dependency is serving requests
+func (s State) Available() bool {
+	return s.Mode == ModeNormal
+}
+
+// UnavailableError explains why a request could not be served
+type UnavailableError struct {
+	Dependency string   `json:"dependency"`
+	Reason     string   `json:"reason"`
+	Affected   []string `json:"affected,omitempty"`
+	Hint       string   `json:"hint,omitempty"`
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%s unavailable", e.Dependency)
+	}
+	return fmt.Sprintf("%s unavailable: %s", e.Dependency, e.Reason)
+}
+
+// Registry holds the state of all dependencies
+type Registry struct {
+	mu     sync.RWMutex
+	states map[string]State
+	hints  map[string]string
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		states: make(map[string]State),
+		hints:  make(map[string]string),
+	}
+}
+
+// SetHint sets the remediation hint returned when name is unavailable
+func (r *Registry) SetHint(name, hint string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hints[name] = hint
+}
+
+// MarkAvailable records that name is working normally
+func (r *Registry) MarkAvailable(name string) {
+	r.set(State{Name: name, Mode: ModeNormal})
+}
+
+// MarkDegraded records that name is down but fallback is serving requests
+func (r *Registry) MarkDegraded(name, reason, fallback string, affected ...string) {
+	r.set(State{Name: name, Mode: ModeDegraded, Reason: reason, Fallback: fallback, Affected: affected})
+}
+
+// MarkUnavailable records that name is down with no fallback
+func (r *Registry) MarkUnavailable(name, reason string, affected ...string) {
+	r.set(State{Name: name, Mode: ModeUnavailable, Reason: reason, Affected: affected})
+}
+
+func (r *Registry) set(s State) {
+	s.UpdatedAt = time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[s.Name] = s
+}
+
+// Get returns the state of name. Unknown dependencies are reported as unavailable.
+func (r *Registry) Get(name string) State {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.states[name]
+	if !ok {
+		return State{Name: name, Mode: ModeUnavailable, Reason: "not configured"}
+	}
+	return s
+}
+
+// All returns every dependency state, sorted by name
+func (r *Registry) All() []State {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make([]State, 0, len(r.states))
+	for _, s := range r.states {
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Unavailable builds the error handlers return when name cannot serve a request
+func (r *Registry) Unavailable(name string) *UnavailableError {
+	s := r.Get(name)
+
+	r.mu.RLock()
+	hint := r.hints[name]
+	r.mu.RUnlock()
+
+	return &UnavailableError{
+		Dependency: name,
+		Reason:     s.Reason,
+		Affected:   s.Affected,
+		Hint:       hint,
+	}
+}
@@ -0,0 +1,156 @@
+package deps
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+const (
+	// superviseInterval is how often a connected dependency is pinged
+	superviseInterval = 10 * time.Second
+	// maxBackoff bounds the wait between attempts while a dependency is down
+	maxBackoff  = 30 * time.Second
+	pingTimeout = 5 * time.Second
+)
+
+// Supervisor keeps a dependency's state current: it pings the dependency
+// while it is up and keeps reconnecting with backoff (1s doubling up to
+// 30s) while it is down, so a dependency that was not up at boot, or went
+// away, is picked up again without a restart. Transitions update the
+// registry and forge_service_up.
+type Supervisor struct {
+	registry *Registry
+	name     string
+	ping     func(context.Context) error
+	down     func(reason string)
+
+	mu        sync.Mutex
+	up        bool
+	checked   bool // pinged at least once
+	connected bool // reached at least once
+	ready     chan struct{}
+	onConnect []func()
+}
+
+// Supervise creates a supervisor for name. ping checks the connection;
+// down records the state while it fails (MarkUnavailable or MarkDegraded
+// with the affected capabilities). Start it with Run.
+func (r *Registry) Supervise(name string, ping func(context.Context) error, down func(reason string)) *Supervisor {
+	return &Supervisor{
+		registry: r,
+		name:     name,
+		ping:     ping,
+		down:     down,
+		ready:    make(chan struct{}),
+	}
+}
+
+// Up reports whether the last ping succeeded
+func (s *Supervisor) Up() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.up
+}
+
+// Wait blocks until the dependency is first reached or ctx is done, and
+// reports whether it was reached
+func (s *Supervisor) Wait(ctx context.Context) bool {
+	select {
+	case <-s.ready:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// OnConnect runs fn once the dependency is first reached: right away when
+// it already was, otherwise from Run. Features that need the dependency
+// to start (e.g. to create their tables) use it to come up late rather
+// than stay off until a restart.
+func (s *Supervisor) OnConnect(fn func()) {
+	s.mu.Lock()
+	if !s.connected {
+		s.onConnect = append(s.onConnect, fn)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	fn()
+}
+
+// Run pings the dependency until ctx is done
+func (s *Supervisor) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := s.ping(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := superviseInterval
+		if err != nil {
+			s.markDown(err)
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+		} else {
+			s.markUp()
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (s *Supervisor) markUp() {
+	s.mu.Lock()
+	if s.up {
+		s.mu.Unlock()
+		return
+	}
+	s.up = true
+	s.checked = true
+	first := !s.connected
+	s.connected = true
+	hooks := s.onConnect
+	s.onConnect = nil
+	s.mu.Unlock()
+
+	s.registry.MarkAvailable(s.name)
+	metrics.ServiceUp.WithLabelValues(s.name).Set(1)
+	log := logger.Get()
+	if !first {
+		metrics.ServiceReconnectsTotal.WithLabelValues(s.name).Inc()
+		log.Info().Str("service", s.name).Msg("Reconnected")
+		return
+	}
+	log.Info().Str("service", s.name).Msg("Connected")
+	for _, fn := range hooks {
+		fn()
+	}
+	close(s.ready)
+}
+
+func (s *Supervisor) markDown(err error) {
+	s.mu.Lock()
+	changed := s.up || !s.checked
+	s.up = false
+	s.checked = true
+	s.mu.Unlock()
+
+	s.down(err.Error())
+	metrics.ServiceUp.WithLabelValues(s.name).Set(0)
+	if changed {
+		log := logger.Get()
+		log.Warn().Err(err).Str("service", s.name).Msg("Unavailable, reconnecting in the background")
+	}
+}
@@ -0,0 +1,155 @@
+// Package discovery creates routes for Docker containers that ask for one
+// through labels, the way Traefik does, and removes them when the
+// container stops
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/routes"
+	"github.com/forge/api/internal/system"
+)
+
+// Container labels
+const (
+	LabelPath        = "forge.route.path" // required, e.g. /myapp/
+	LabelPort        = "forge.route.port" // container port, default 80
+	LabelName        = "forge.route.name" // route name, default the container name
+	LabelStripPrefix = "forge.route.strip_prefix"
+	LabelHost        = "forge.route.host"
+	LabelDomain      = "forge.route.domain"
+	LabelProtocol    = "forge.route.protocol"
+	LabelWebSocket   = "forge.route.websocket" // true to allow WebSocket upgrades
+)
+
+// ownerLabel marks discovered routes; its value is the container name
+const ownerLabel = "forge_container"
+
+// DefaultInterval is how often Docker is polled for labelled containers
+const DefaultInterval = 10 * time.Second
+
+// Watcher syncs routes from Docker container labels
+type Watcher struct {
+	routes   *routes.Manager
+	docker   *system.DockerClient
+	interval time.Duration
+	lastErr  string
+}
+
+// New creates a watcher polling docker every interval
+func New(rm *routes.Manager, docker *system.DockerClient, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{routes: rm, docker: docker, interval: interval}
+}
+
+// Run syncs routes until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.report(w.Sync(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync creates, updates or removes discovered routes to match the running
+// containers. Containers whose route name is taken by a manual route are
+// skipped.
+func (w *Watcher) Sync(ctx context.Context) error {
+	containers, err := w.docker.ContainersWithLabel(ctx, LabelPath)
+	if err != nil {
+		return fmt.Errorf("list containers: %w", err)
+	}
+
+	var desired []routes.Route
+	var errs []error
+	for _, c := range containers {
+		r, err := routeFor(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", c.Name, err))
+			continue
+		}
+		if existing, ok := w.routes.Get(r.Name); ok && existing.Labels[ownerLabel] == "" {
+			errs = append(errs, fmt.Errorf("container %s: route %s already exists", c.Name, r.Name))
+			continue
+		}
+		desired = append(desired, r)
+	}
+
+	if err := w.routes.Sync(ownerLabel, desired); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// routeFor builds the route requested by a container's labels
+func routeFor(c system.LabeledContainer) (routes.Route, error) {
+	port := c.Labels[LabelPort]
+	if port == "" {
+		port = "80"
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return routes.Route{}, fmt.Errorf("invalid %s: %q", LabelPort, port)
+	}
+
+	name := c.Labels[LabelName]
+	if name == "" {
+		name = c.Name
+	}
+
+	r := routes.Route{
+		Name:     name,
+		Path:     c.Labels[LabelPath],
+		Target:   "http://" + c.Name + ":" + port,
+		Host:     c.Labels[LabelHost],
+		Domain:   c.Labels[LabelDomain],
+		Protocol: c.Labels[LabelProtocol],
+		Labels:   map[string]string{ownerLabel: c.Name},
+	}
+	if v := c.Labels[LabelStripPrefix]; v != "" {
+		strip, err := strconv.ParseBool(v)
+		if err != nil {
+			return routes.Route{}, fmt.Errorf("invalid %s: %q", LabelStripPrefix, v)
+		}
+		r.StripPrefix = strip
+	}
+	if v := c.Labels[LabelWebSocket]; v != "" {
+		ws, err := strconv.ParseBool(v)
+		if err != nil {
+			return routes.Route{}, fmt.Errorf("invalid %s: %q", LabelWebSocket, v)
+		}
+		if ws {
+			r.WebSocket = &routes.WebSocket{}
+		}
+	}
+	return r, nil
+}
+
+// report logs sync errors once until they change
+func (w *Watcher) report(err error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if msg == w.lastErr {
+		return
+	}
+	w.lastErr = msg
+	if err != nil {
+		logger.Error("Docker route discovery failed", err)
+	} else {
+		logger.Info("Docker route discovery in sync")
+	}
+}
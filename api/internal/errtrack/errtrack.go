@@ -0,0 +1,434 @@
+// Package errtrack stores application exception events in MySQL, grouped
+// by fingerprint with counts and first/last seen times
+package errtrack
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+const (
+	// DefaultRetention is how long individual events are kept; groups are kept
+	// until deleted
+	DefaultRetention = 30 * 24 * time.Hour
+
+	pruneInterval      = time.Hour
+	maxMessageLength   = 8 << 10
+	maxStacktraceBytes = 64 << 10
+	maxContextEntries  = 50
+	fingerprintFrames  = 10
+	defaultListLimit   = 50
+	maxListLimit       = 500
+)
+
+// Group statuses
+const (
+	StatusUnresolved = "unresolved"
+	StatusResolved   = "resolved"
+	StatusIgnored    = "ignored"
+)
+
+var (
+	// ErrInvalidEvent is returned for events that cannot be stored
+	ErrInvalidEvent = errors.New("invalid error event")
+	// ErrGroupNotFound is returned for unknown fingerprints
+	ErrGroupNotFound = errors.New("error group not found")
+
+	levels             = map[string]bool{"fatal": true, "error": true, "warning": true}
+	fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	// volatile parts of a message or frame that should not split groups
+	volatilePattern = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+)
+
+// Event is a captured exception
+type Event struct {
+	Message     string            `json:"message"`
+	Type        string            `json:"type,omitempty"`
+	Stacktrace  string            `json:"stacktrace,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Service     string            `json:"service,omitempty"`
+	Level       string            `json:"level"`
+	User        map[string]string `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Fingerprint []string          `json:"-"` // custom grouping key
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// Group aggregates events with the same fingerprint
+type Group struct {
+	Fingerprint string    `json:"fingerprint"`
+	Service     string    `json:"service,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	Message     string    `json:"message"`
+	Level       string    `json:"level"`
+	Status      string    `json:"status"`
+	Count       int64     `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastRelease string    `json:"last_release,omitempty"`
+}
+
+// ListOptions filters groups
+type ListOptions struct {
+	Service string
+	Status  string
+	Limit   int
+}
+
+// Store persists events and groups
+type Store struct {
+	db        *sql.DB
+	groups    string
+	events    string
+	retention time.Duration
+}
+
+// NewStore prepares the error tables in database
+func NewStore(db *sql.DB, database string) (*Store, error) {
+	s := &Store{
+		db:        db,
+		groups:    fmt.Sprintf("`%s`.`error_groups`", database),
+		events:    fmt.Sprintf("`%s`.`error_events`", database),
+		retention: DefaultRetention,
+	}
+	if err := s.migrate(context.Background(), database); err != nil {
+		return nil, fmt.Errorf("failed to create error tables: %w", err)
+	}
+	return s, nil
+}
+
+// migrate creates the database and tables if missing
+func (s *Store) migrate(ctx context.Context, database string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database)); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.groups+` (
+		fingerprint CHAR(40) PRIMARY KEY,
+		service VARCHAR(128) NOT NULL,
+		type VARCHAR(255) NOT NULL,
+		message TEXT NOT NULL,
+		level VARCHAR(16) NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		count BIGINT NOT NULL,
+		first_seen DATETIME(3) NOT NULL,
+		last_seen DATETIME(3) NOT NULL,
+		last_release VARCHAR(128) NOT NULL,
+		INDEX idx_last_seen (last_seen),
+		INDEX idx_service (service, last_seen)
+	)`); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.events+` (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		fingerprint CHAR(40) NOT NULL,
+		ts DATETIME(3) NOT NULL,
+		message TEXT NOT NULL,
+		stacktrace MEDIUMTEXT NOT NULL,
+		release_name VARCHAR(128) NOT NULL,
+		environment VARCHAR(64) NOT NULL,
+		user_context JSON NOT NULL,
+		tags JSON NOT NULL,
+		INDEX idx_fingerprint_ts (fingerprint, ts),
+		INDEX idx_ts (ts)
+	)`)
+	return err
+}
+
+// Capture stores an event and updates its group. A resolved group that
+// receives a new event is reopened.
+func (s *Store) Capture(ctx context.Context, e Event) (fingerprint string, newGroup bool, err error) {
+	if err := normalize(&e); err != nil {
+		return "", false, err
+	}
+	fingerprint = Fingerprint(e)
+
+	user, _ := json.Marshal(nonNil(e.User))
+	tags, _ := json.Marshal(nonNil(e.Tags))
+	ts := e.Timestamp.UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO `+s.groups+`
+		(fingerprint, service, type, message, level, status, count, first_seen, last_seen, last_release)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			count = count + 1,
+			first_seen = LEAST(first_seen, VALUES(first_seen)),
+			last_seen = GREATEST(last_seen, VALUES(last_seen)),
+			last_release = IF(VALUES(last_release) = '', last_release, VALUES(last_release)),
+			status = IF(status = ?, ?, status)`,
+		fingerprint, e.Service, e.Type, e.Message, e.Level, StatusUnresolved, ts, ts, e.Release,
+		StatusResolved, StatusUnresolved)
+	if err != nil {
+		return "", false, err
+	}
+	// MySQL reports 1 affected row for an insert and 2 for an update
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		newGroup = true
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+s.events+`
+		(fingerprint, ts, message, stacktrace, release_name, environment, user_context, tags)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		fingerprint, ts, e.Message, e.Stacktrace, e.Release, e.Environment, string(user), string(tags)); err != nil {
+		return "", false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+
+	metrics.ErrorEventsTotal.WithLabelValues(e.Level).Inc()
+	return fingerprint, newGroup, nil
+}
+
+// List returns groups ordered by last seen, newest first
+func (s *Store) List(ctx context.Context, opts ListOptions) ([]Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM ` + s.groups
+	var where []string
+	var args []any
+	if opts.Service != "" {
+		where = append(where, "service = ?")
+		args = append(args, opts.Service)
+	}
+	if opts.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, opts.Status)
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY last_seen DESC LIMIT ?"
+	args = append(args, clampLimit(opts.Limit))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// Get returns a group with its most recent events
+func (s *Store) Get(ctx context.Context, fingerprint string, limit int) (Group, []Event, error) {
+	if !fingerprintPattern.MatchString(fingerprint) {
+		return Group{}, nil, ErrGroupNotFound
+	}
+
+	g, err := scanGroup(s.db.QueryRowContext(ctx, `SELECT `+groupColumns+` FROM `+s.groups+` WHERE fingerprint = ?`, fingerprint))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Group{}, nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return Group{}, nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT CAST(UNIX_TIMESTAMP(ts) * 1000 AS SIGNED), message, stacktrace, release_name, environment, user_context, tags
+		FROM `+s.events+` WHERE fingerprint = ? ORDER BY ts DESC LIMIT ?`, fingerprint, clampLimit(limit))
+	if err != nil {
+		return Group{}, nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		e := Event{Service: g.Service, Type: g.Type, Level: g.Level}
+		var tsMs int64
+		var user, tags []byte
+		if err := rows.Scan(&tsMs, &e.Message, &e.Stacktrace, &e.Release, &e.Environment, &user, &tags); err != nil {
+			return Group{}, nil, err
+		}
+		e.Timestamp = time.UnixMilli(tsMs).UTC()
+		json.Unmarshal(user, &e.User)
+		json.Unmarshal(tags, &e.Tags)
+		events = append(events, e)
+	}
+	return g, events, rows.Err()
+}
+
+// groupColumns selects a Group in the order scanGroup expects
+const groupColumns = `fingerprint, service, type, message, level, status, count,
+	CAST(UNIX_TIMESTAMP(first_seen) * 1000 AS SIGNED), CAST(UNIX_TIMESTAMP(last_seen) * 1000 AS SIGNED), last_release`
+
+func scanGroup(row interface{ Scan(...any) error }) (Group, error) {
+	var g Group
+	var firstMs, lastMs int64
+	if err := row.Scan(&g.Fingerprint, &g.Service, &g.Type, &g.Message, &g.Level, &g.Status,
+		&g.Count, &firstMs, &lastMs, &g.LastRelease); err != nil {
+		return Group{}, err
+	}
+	g.FirstSeen = time.UnixMilli(firstMs).UTC()
+	g.LastSeen = time.UnixMilli(lastMs).UTC()
+	return g, nil
+}
+
+// SetStatus marks a group unresolved, resolved or ignored
+func (s *Store) SetStatus(ctx context.Context, fingerprint, status string) error {
+	switch status {
+	case StatusUnresolved, StatusResolved, StatusIgnored:
+	default:
+		return fmt.Errorf("%w: status must be unresolved, resolved or ignored", ErrInvalidEvent)
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE "+s.groups+" SET status = ? WHERE fingerprint = ?", status, fingerprint)
+	if err != nil {
+		return err
+	}
+	return s.found(ctx, res, fingerprint)
+}
+
+// Delete removes a group and its events
+func (s *Store) Delete(ctx context.Context, fingerprint string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM "+s.events+" WHERE fingerprint = ?", fingerprint); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, "DELETE FROM "+s.groups+" WHERE fingerprint = ?", fingerprint)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+// found distinguishes a no-op update from a missing group
+func (s *Store) found(ctx context.Context, res sql.Result, fingerprint string) error {
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM "+s.groups+" WHERE fingerprint = ?", fingerprint).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrGroupNotFound
+	}
+	return err
+}
+
+// Run prunes events older than the retention until ctx is cancelled
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-s.retention).UTC()
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM "+s.events+" WHERE ts < ?", cutoff); err != nil && ctx.Err() == nil {
+			logger.Error("Failed to prune error events", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Fingerprint returns the grouping key of an event: the custom fingerprint
+// if set, otherwise the service, type and top stack frames (or the message
+// when there is no stack trace) with numbers stripped
+func Fingerprint(e Event) string {
+	parts := []string{e.Service}
+	switch {
+	case len(e.Fingerprint) > 0:
+		parts = append(parts, e.Fingerprint...)
+	case e.Stacktrace != "":
+		parts = append(parts, e.Type)
+		parts = append(parts, topFrames(e.Stacktrace)...)
+	default:
+		parts = append(parts, e.Type, volatilePattern.ReplaceAllString(e.Message, "N"))
+	}
+
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// topFrames returns the innermost frames of a stack trace, normalised.
+// Tracebacks list the innermost frame last, so the tail is used.
+func topFrames(stacktrace string) []string {
+	var frames []string
+	for _, line := range strings.Split(stacktrace, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		frames = append(frames, volatilePattern.ReplaceAllString(line, "N"))
+	}
+	if len(frames) > fingerprintFrames {
+		frames = frames[len(frames)-fingerprintFrames:]
+	}
+	return frames
+}
+
+// normalize validates an event and fills in defaults
+func normalize(e *Event) error {
+	e.Message = strings.TrimSpace(e.Message)
+	if e.Message == "" && e.Type == "" {
+		return fmt.Errorf("%w: message or type is required", ErrInvalidEvent)
+	}
+	if e.Message == "" {
+		e.Message = e.Type
+	}
+	if e.Level == "" {
+		e.Level = "error"
+	}
+	if !levels[e.Level] {
+		return fmt.Errorf("%w: level must be fatal, error or warning", ErrInvalidEvent)
+	}
+	if len(e.Service) > 128 || len(e.Type) > 255 || len(e.Release) > 128 || len(e.Environment) > 64 {
+		return fmt.Errorf("%w: service, type, release or environment too long", ErrInvalidEvent)
+	}
+	if len(e.User) > maxContextEntries || len(e.Tags) > maxContextEntries {
+		return fmt.Errorf("%w: too many user or tag entries (max %d)", ErrInvalidEvent, maxContextEntries)
+	}
+	if len(e.Message) > maxMessageLength {
+		e.Message = e.Message[:maxMessageLength]
+	}
+	if len(e.Stacktrace) > maxStacktraceBytes {
+		e.Stacktrace = e.Stacktrace[len(e.Stacktrace)-maxStacktraceBytes:]
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	return nil
+}
+
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
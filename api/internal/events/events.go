@@ -0,0 +1,94 @@
+// Package events is the internal event bus: subsystems publish what
+// happened (a route changed, a container turned unhealthy, an alert fired,
+// a backup finished) and consumers such as outbound webhooks receive it
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	RouteChanged       = "route.changed"       // a route was created, updated or deleted
+	ContainerUnhealthy = "container.unhealthy" // a container's healthcheck started failing
+	ContainerDied      = "container.died"      // a container exited non-zero, OOM kills included
+	AlertFired         = "alert.fired"         // an alert raised by Forge started firing
+	AlertResolved      = "alert.resolved"      // an alert raised by Forge resolved
+	BackupFinished     = "backup.finished"     // a backup job run ended, successfully or not
+	JobFailed          = "job.failed"          // a scheduled job run failed
+	WatchdogIncident   = "watchdog.incident"   // the watchdog restarted a container, failed to, or gave up
+	MQTTMessage        = "mqtt.message"        // an MQTT bridge with a webhook target received a message
+	WebhookTest        = "webhook.test"        // sent on demand to test a webhook
+)
+
+// Types lists the event types published on the bus
+var Types = []string{RouteChanged, ContainerUnhealthy, ContainerDied, AlertFired, AlertResolved, BackupFinished, JobFailed, WatchdogIncident, MQTTMessage, WebhookTest}
+
+// Event is something that happened in the stack
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// New creates an event of type typ happening now
+func New(typ string, data any) Event {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return Event{ID: "evt_" + hex.EncodeToString(id), Type: typ, Time: time.Now().UTC(), Data: data}
+}
+
+// Matches reports whether typ matches pattern: an event type, a family
+// such as "container.*", or "*" for all
+func Matches(pattern, typ string) bool {
+	if pattern == "*" || pattern == typ {
+		return true
+	}
+	family, ok := strings.CutSuffix(pattern, ".*")
+	return ok && strings.HasPrefix(typ, family+".")
+}
+
+// ValidPattern reports whether pattern matches at least one event type
+func ValidPattern(pattern string) bool {
+	for _, t := range Types {
+		if Matches(pattern, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus hands published events to its handlers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+}
+
+// NewBus creates an event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// OnEvent calls fn for every event published. fn runs on the publisher's
+// goroutine and must not block.
+func (b *Bus) OnEvent(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, fn)
+}
+
+// Publish sends an event of type typ with data to the handlers
+func (b *Bus) Publish(typ string, data any) {
+	e := New(typ, data)
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
@@ -0,0 +1,409 @@
+// Package files stores uploaded files on a local volume
+//
+// Each file is kept under the store's directory at its own path, e.g.
+// reports/2024/q1.pdf, with a JSON sidecar under .meta holding its size,
+// SHA-256 checksum and content type. Uploads are written to .tmp first and
+// renamed into place, so readers never see a partial file. Every file is
+// kept under a size limit and all of them together under a quota.
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metaDir = ".meta"
+	tmpDir  = ".tmp"
+
+	// MaxPathLength bounds file paths in bytes
+	MaxPathLength = 1024
+	// sniffLength is how much of a file content type detection looks at
+	sniffLength = 512
+)
+
+var (
+	// ErrInvalidPath is returned for paths that are empty, too long or
+	// could escape the store
+	ErrInvalidPath = errors.New("invalid file path")
+	// ErrNotFound is returned for files that do not exist
+	ErrNotFound = errors.New("file not found")
+	// ErrTooLarge is returned for files over the size limit
+	ErrTooLarge = errors.New("file too large")
+	// ErrQuota is returned when a file would take the store over its quota
+	ErrQuota = errors.New("storage quota exceeded")
+	// ErrChecksum is returned when an upload does not match the checksum
+	// the client declared
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrConflict is returned for paths that are used as both a file and a
+	// directory, such as a/b when a is a file
+	ErrConflict = errors.New("file path conflict")
+)
+
+// File describes a stored file
+type File struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	ContentType string    `json:"content_type"`
+	Modified    time.Time `json:"modified"`
+}
+
+// Usage is how much of the store is in use; Quota 0 means unlimited
+type Usage struct {
+	Files   int   `json:"files"`
+	Used    int64 `json:"used_bytes"`
+	Quota   int64 `json:"quota_bytes"`
+	MaxSize int64 `json:"max_file_bytes"`
+}
+
+// Store keeps files in a directory
+type Store struct {
+	dir     string
+	maxSize int64
+	quota   int64
+
+	// mu guards used and count, and serialises replacing files so the
+	// quota holds
+	mu    sync.Mutex
+	used  int64
+	count int
+}
+
+// NewStore creates a store in dir holding files of up to maxSize bytes and
+// quota bytes in total (0 for no quota). Leftover partial uploads are
+// removed and the usage of existing files is counted.
+func NewStore(dir string, maxSize, quota int64) (*Store, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("files: max size must be positive")
+	}
+	s := &Store{dir: dir, maxSize: maxSize, quota: quota}
+	if err := os.RemoveAll(filepath.Join(dir, tmpDir)); err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	for _, d := range []string{dir, filepath.Join(dir, metaDir), filepath.Join(dir, tmpDir)} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, fmt.Errorf("files: %w", err)
+		}
+	}
+
+	files, err := s.List("")
+	if err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	for _, f := range files {
+		s.used += f.Size
+	}
+	s.count = len(files)
+	return s, nil
+}
+
+// Usage returns how much of the store is in use
+func (s *Store) Usage() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Usage{Files: s.count, Used: s.used, Quota: s.quota, MaxSize: s.maxSize}
+}
+
+// Put stores the contents of r at name, replacing any file there. size is
+// the length the client declared, or -1; contentType is the declared type,
+// detected from the name and contents when empty or generic; checksum is
+// the hex SHA-256 the contents must match, or empty. It reports whether
+// the file is new.
+func (s *Store) Put(name string, r io.Reader, size int64, contentType, checksum string) (*File, bool, error) {
+	if err := checkPath(name); err != nil {
+		return nil, false, err
+	}
+	if size > s.maxSize {
+		return nil, false, fmt.Errorf("%w: %d bytes is over the limit of %d", ErrTooLarge, size, s.maxSize)
+	}
+	// Refuse uploads that cannot fit before reading them
+	if size > 0 && s.quota > 0 {
+		old, _ := s.Stat(name)
+		if err := s.checkQuota(old, size); err != nil {
+			return nil, false, err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, tmpDir), "upload-*")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	head := &prefixWriter{limit: sniffLength}
+	n, err := io.Copy(io.MultiWriter(tmp, hash, head), io.LimitReader(r, s.maxSize+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if n > s.maxSize {
+		return nil, false, fmt.Errorf("%w: over the limit of %d bytes", ErrTooLarge, s.maxSize)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && !strings.EqualFold(checksum, sum) {
+		return nil, false, fmt.Errorf("%w: contents hash to %s", ErrChecksum, sum)
+	}
+
+	f := &File{
+		Path:        name,
+		Size:        n,
+		SHA256:      sum,
+		ContentType: detectType(name, contentType, head.buf),
+		Modified:    time.Now().UTC().Truncate(time.Second),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.Stat(name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+	if err := s.checkQuota(old, n); err != nil {
+		return nil, false, err
+	}
+	if err := s.checkConflict(name); err != nil {
+		return nil, false, err
+	}
+
+	data := s.dataPath(name)
+	if err := os.MkdirAll(filepath.Dir(data), 0755); err != nil {
+		return nil, false, err
+	}
+	if err := os.Rename(tmp.Name(), data); err != nil {
+		return nil, false, err
+	}
+	if err := s.writeMeta(f); err != nil {
+		return nil, false, err
+	}
+
+	if old != nil {
+		s.used -= old.Size
+	} else {
+		s.count++
+	}
+	s.used += n
+	return f, old == nil, nil
+}
+
+// checkQuota reports whether replacing old with size bytes fits the quota
+func (s *Store) checkQuota(old *File, size int64) error {
+	if s.quota <= 0 {
+		return nil
+	}
+	used := s.used + size
+	if old != nil {
+		used -= old.Size
+	}
+	if used > s.quota {
+		return fmt.Errorf("%w: %d of %d bytes in use", ErrQuota, s.used, s.quota)
+	}
+	return nil
+}
+
+// checkConflict reports whether name is a directory of other files or
+// below an existing file
+func (s *Store) checkConflict(name string) error {
+	if info, err := os.Stat(s.dataPath(name)); err == nil && info.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", ErrConflict, name)
+	}
+	for i := range name {
+		if name[i] != '/' {
+			continue
+		}
+		if _, err := s.Stat(name[:i]); err == nil {
+			return fmt.Errorf("%w: %s is a file", ErrConflict, name[:i])
+		}
+	}
+	return nil
+}
+
+// Stat returns the description of the file at name
+func (s *Store) Stat(name string) (*File, error) {
+	if err := checkPath(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.metaPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("file %s: %w", name, err)
+	}
+	return &f, nil
+}
+
+// Open returns the description and contents of the file at name; the
+// caller closes the contents
+func (s *Store) Open(name string) (*File, *os.File, error) {
+	f, err := s.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.Open(s.dataPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, data, nil
+}
+
+// Delete removes the file at name, and directories it leaves empty
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.Stat(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.dataPath(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.metaPath(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	s.used -= f.Size
+	s.count--
+
+	s.removeEmptyDirs(filepath.Dir(s.dataPath(name)), s.dir)
+	s.removeEmptyDirs(filepath.Dir(s.metaPath(name)), filepath.Join(s.dir, metaDir))
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents up to root while they are
+// empty
+func (s *Store) removeEmptyDirs(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// List returns the files whose paths start with prefix, sorted by path
+func (s *Store) List(prefix string) ([]File, error) {
+	root := filepath.Join(s.dir, metaDir)
+	files := []File{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".json") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, strings.TrimSuffix(p, ".json"))
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		f, err := s.Stat(name)
+		if err != nil {
+			return err
+		}
+		files = append(files, *f)
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (s *Store) dataPath(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s *Store) metaPath(name string) string {
+	return filepath.Join(s.dir, metaDir, filepath.FromSlash(name)+".json")
+}
+
+func (s *Store) writeMeta(f *File) error {
+	p := s.metaPath(f.Path)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// checkPath rejects paths that could escape the store or reach its own
+// directories: every segment must be non-empty and not start with a dot
+func checkPath(name string) error {
+	if name == "" || len(name) > MaxPathLength {
+		return fmt.Errorf("%w: paths are 1-%d bytes", ErrInvalidPath, MaxPathLength)
+	}
+	if strings.ContainsAny(name, "\\\x00") {
+		return fmt.Errorf("%w: %q may not contain '\\' or NUL", ErrInvalidPath, name)
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if seg == "" || strings.HasPrefix(seg, ".") {
+			return fmt.Errorf("%w: %q has an empty segment or one starting with '.'", ErrInvalidPath, name)
+		}
+	}
+	return nil
+}
+
+// detectType returns declared unless it is empty or generic, then the type
+// registered for the name's extension, then the type sniffed from head
+func detectType(name, declared string, head []byte) string {
+	if declared != "" && declared != "application/octet-stream" {
+		if _, _, err := mime.ParseMediaType(declared); err == nil {
+			return declared
+		}
+	}
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(head)
+}
+
+// prefixWriter keeps the first limit bytes written to it
+type prefixWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if n := w.limit - len(w.buf); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,340 @@
+// Package flags stores feature flags for hosted apps and evaluates them
+//
+// A flag is one of three types:
+//
+//   - boolean: on or off for everyone the rules do not target
+//   - percentage: on for a stable share of evaluation keys (e.g. user IDs)
+//   - variant: serves one of several named variants, split by weight
+//
+// Rules target attributes of the evaluation context (e.g. country=DE, or
+// the key itself) and are checked in order; the first match decides what
+// is served. A disabled flag serves false, or its off variant.
+// Percentage and variant splits hash the flag and evaluation keys, so a
+// key keeps its bucket across evaluations and replicas.
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Flag types
+const (
+	TypeBoolean    = "boolean"
+	TypePercentage = "percentage"
+	TypeVariant    = "variant"
+)
+
+// Rule operators
+const (
+	OpIn       = "in"
+	OpNotIn    = "not_in"
+	OpPrefix   = "prefix"
+	OpSuffix   = "suffix"
+	OpContains = "contains"
+)
+
+// Evaluation reasons
+const (
+	ReasonDisabled   = "disabled"    // the flag is off
+	ReasonRule       = "rule"        // a targeting rule matched
+	ReasonRollout    = "rollout"     // the key's bucket decided
+	ReasonDefault    = "default"     // an enabled boolean flag no rule matched
+	ReasonMissingKey = "missing_key" // a split flag evaluated without a key
+	ReasonNotFound   = "not_found"   // no such flag
+)
+
+const (
+	maxRules    = 50
+	maxVariants = 20
+	maxValues   = 500
+	// buckets is the resolution of percentage splits: 0.01%
+	buckets = 10000
+)
+
+var (
+	// ErrInvalidFlag is returned for flags that cannot be stored
+	ErrInvalidFlag = errors.New("invalid flag")
+	// ErrFlagNotFound is returned for unknown flag keys
+	ErrFlagNotFound = errors.New("flag not found")
+	// ErrFlagExists is returned when creating a flag whose key is taken
+	ErrFlagExists = errors.New("flag already exists")
+
+	keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,127}$`)
+	// reservedKeys collide with the REST paths under /api/v1/flags
+	reservedKeys = map[string]bool{"evaluate": true}
+	operators    = map[string]bool{OpIn: true, OpNotIn: true, OpPrefix: true, OpSuffix: true, OpContains: true}
+)
+
+// Flag is a feature flag definition
+type Flag struct {
+	Key         string    `json:"key"`
+	Description string    `json:"description,omitempty"`
+	Type        string    `json:"type"` // boolean, percentage or variant
+	Enabled     bool      `json:"enabled"`
+	Percentage  float64   `json:"percentage,omitempty"`  // percentage flags: share of keys served true, 0-100
+	Variants    []Variant `json:"variants,omitempty"`    // variant flags
+	OffVariant  string    `json:"off_variant,omitempty"` // variant flags: served while disabled, the first variant by default
+	Rules       []Rule    `json:"rules,omitempty"`
+	Version     int64     `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Variant is a named value of a variant flag, served to a share of keys
+// proportional to its weight
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Rule serves Serve when the Attribute of the evaluation context matches
+// Values under Operator. Serve is "true" or "false" for boolean and
+// percentage flags, and a variant name for variant flags.
+type Rule struct {
+	Attribute string   `json:"attribute"` // context attribute, or "key" for the evaluation key
+	Operator  string   `json:"operator"`  // in, not_in, prefix, suffix or contains
+	Values    []string `json:"values"`
+	Serve     string   `json:"serve"`
+}
+
+// Context is what a flag is evaluated for
+type Context struct {
+	Key        string            // user or entity the flag is evaluated for
+	Attributes map[string]string // targeting attributes, e.g. country or plan
+}
+
+// Result is the outcome of evaluating a flag. Enabled is the value of
+// boolean and percentage flags; variant flags serve Variant, and Enabled
+// reports whether the flag is on.
+type Result struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant,omitempty"`
+	Reason  string `json:"reason"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// Validate checks a flag definition and fills in defaults
+func (f *Flag) Validate() error {
+	if !keyPattern.MatchString(f.Key) || reservedKeys[f.Key] {
+		return fmt.Errorf("%w: key must be 1-128 lowercase letters, digits, '.', '_' or '-', and not %q", ErrInvalidFlag, "evaluate")
+	}
+	if len(f.Description) > 1024 {
+		return fmt.Errorf("%w: description too long (max 1024)", ErrInvalidFlag)
+	}
+	if f.Type == "" {
+		f.Type = TypeBoolean
+	}
+
+	switch f.Type {
+	case TypeBoolean:
+		if f.Percentage != 0 || len(f.Variants) > 0 || f.OffVariant != "" {
+			return fmt.Errorf("%w: boolean flags take no percentage or variants", ErrInvalidFlag)
+		}
+	case TypePercentage:
+		if f.Percentage < 0 || f.Percentage > 100 {
+			return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidFlag)
+		}
+		if len(f.Variants) > 0 || f.OffVariant != "" {
+			return fmt.Errorf("%w: percentage flags take no variants", ErrInvalidFlag)
+		}
+	case TypeVariant:
+		if err := f.validateVariants(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: type must be boolean, percentage or variant", ErrInvalidFlag)
+	}
+
+	if len(f.Rules) > maxRules {
+		return fmt.Errorf("%w: too many rules (max %d)", ErrInvalidFlag, maxRules)
+	}
+	for i, r := range f.Rules {
+		if err := f.validateRule(r); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", ErrInvalidFlag, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (f *Flag) validateVariants() error {
+	if f.Percentage != 0 {
+		return fmt.Errorf("%w: variant flags take no percentage", ErrInvalidFlag)
+	}
+	if len(f.Variants) == 0 || len(f.Variants) > maxVariants {
+		return fmt.Errorf("%w: variant flags need 1-%d variants", ErrInvalidFlag, maxVariants)
+	}
+	total := 0
+	seen := map[string]bool{}
+	for _, v := range f.Variants {
+		if !keyPattern.MatchString(v.Name) {
+			return fmt.Errorf("%w: variant name %q must be lowercase letters, digits, '.', '_' or '-'", ErrInvalidFlag, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: duplicate variant %q", ErrInvalidFlag, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 || v.Weight > buckets {
+			return fmt.Errorf("%w: variant %q: weight must be between 0 and %d", ErrInvalidFlag, v.Name, buckets)
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: at least one variant needs a positive weight", ErrInvalidFlag)
+	}
+	if f.OffVariant == "" {
+		f.OffVariant = f.Variants[0].Name
+	}
+	if !seen[f.OffVariant] {
+		return fmt.Errorf("%w: off_variant %q is not a variant", ErrInvalidFlag, f.OffVariant)
+	}
+	return nil
+}
+
+func (f *Flag) validateRule(r Rule) error {
+	if r.Attribute == "" {
+		return errors.New("attribute is required")
+	}
+	if !operators[r.Operator] {
+		return errors.New("operator must be in, not_in, prefix, suffix or contains")
+	}
+	if len(r.Values) == 0 || len(r.Values) > maxValues {
+		return fmt.Errorf("1-%d values are required", maxValues)
+	}
+	if f.Type == TypeVariant {
+		if !f.hasVariant(r.Serve) {
+			return fmt.Errorf("serve %q is not a variant", r.Serve)
+		}
+		return nil
+	}
+	if r.Serve != "true" && r.Serve != "false" {
+		return errors.New(`serve must be "true" or "false"`)
+	}
+	return nil
+}
+
+func (f *Flag) hasVariant(name string) bool {
+	for _, v := range f.Variants {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns what the flag serves for ctx
+func (f *Flag) Evaluate(ctx Context) Result {
+	res := Result{Key: f.Key, Version: f.Version}
+	if !f.Enabled {
+		res.Reason = ReasonDisabled
+		res.Variant = f.OffVariant
+		return res
+	}
+
+	res.Enabled = true
+	for _, r := range f.Rules {
+		if !r.matches(ctx) {
+			continue
+		}
+		res.Reason = ReasonRule
+		if f.Type == TypeVariant {
+			res.Variant = r.Serve
+		} else {
+			res.Enabled = r.Serve == "true"
+		}
+		return res
+	}
+
+	switch f.Type {
+	case TypeBoolean:
+		res.Reason = ReasonDefault
+	case TypePercentage:
+		if ctx.Key == "" {
+			res.Enabled = false
+			res.Reason = ReasonMissingKey
+			return res
+		}
+		res.Reason = ReasonRollout
+		res.Enabled = float64(bucket(f.Key, ctx.Key)) < f.Percentage*buckets/100
+	case TypeVariant:
+		if ctx.Key == "" {
+			res.Variant = f.OffVariant
+			res.Reason = ReasonMissingKey
+			return res
+		}
+		res.Reason = ReasonRollout
+		res.Variant = f.pickVariant(bucket(f.Key, ctx.Key))
+	}
+	return res
+}
+
+// pickVariant maps a bucket onto the variants, each taking a share of the
+// buckets proportional to its weight
+func (f *Flag) pickVariant(b int) string {
+	total := 0
+	for _, v := range f.Variants {
+		total += v.Weight
+	}
+	point := b * total / buckets
+	for _, v := range f.Variants {
+		if point < v.Weight {
+			return v.Name
+		}
+		point -= v.Weight
+	}
+	return f.Variants[len(f.Variants)-1].Name
+}
+
+// bucket places key in one of the buckets of flag, stably
+func bucket(flag, key string) int {
+	sum := sha256.Sum256([]byte(flag + "\x00" + key))
+	return int(binary.BigEndian.Uint64(sum[:8]) % buckets)
+}
+
+func (r Rule) matches(ctx Context) bool {
+	value, ok := ctx.Attributes[r.Attribute]
+	if r.Attribute == "key" {
+		value, ok = ctx.Key, ctx.Key != ""
+	}
+	if r.Operator == OpNotIn {
+		return !ok || !contains(r.Values, value)
+	}
+	if !ok {
+		return false
+	}
+	for _, v := range r.Values {
+		switch r.Operator {
+		case OpIn:
+			if value == v {
+				return true
+			}
+		case OpPrefix:
+			if strings.HasPrefix(value, v) {
+				return true
+			}
+		case OpSuffix:
+			if strings.HasSuffix(value, v) {
+				return true
+			}
+		case OpContains:
+			if strings.Contains(value, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,361 @@
+package flags
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+const (
+	// cacheKey holds the flag set in the shared cache
+	cacheKey = "forge:flags:all"
+	// cacheTTL bounds how long a replica can serve a flag set that another
+	// replica's write raced with
+	cacheTTL = 30 * time.Second
+	// changesChannel carries changes between replicas
+	changesChannel = "forge:flags:changes"
+)
+
+// Change actions
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// Change tells subscribers that a flag was created, updated or deleted
+type Change struct {
+	Key     string `json:"key"`
+	Action  string `json:"action"`
+	Version int64  `json:"version"`
+}
+
+// Cache shares the flag set and changes between API replicas;
+// *cache.RedisClient implements it
+type Cache interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) (bool, error)
+	Publish(ctx context.Context, channel, message string) error
+	Subscribe(ctx context.Context, channel string) <-chan string
+}
+
+// Store persists flags in MySQL, caches the flag set in the shared cache
+// while it is up, and fans changes out to subscribers. Slow subscribers
+// miss changes rather than block the others.
+type Store struct {
+	db       *sql.DB
+	table    string
+	cache    Cache
+	cacheUp  func() bool
+	instance string // tells this replica's changes apart on the channel
+
+	mu   sync.Mutex
+	subs map[chan Change]struct{}
+}
+
+// NewStore prepares the flags table in database. cache, when set, is used
+// while cacheUp reports it reachable.
+func NewStore(db *sql.DB, database string, cache Cache, cacheUp func() bool) (*Store, error) {
+	id := make([]byte, 8)
+	rand.Read(id)
+	s := &Store{
+		db:       db,
+		table:    fmt.Sprintf("`%s`.`feature_flags`", database),
+		cache:    cache,
+		cacheUp:  cacheUp,
+		instance: hex.EncodeToString(id),
+		subs:     make(map[chan Change]struct{}),
+	}
+	if err := s.migrate(context.Background(), database); err != nil {
+		return nil, fmt.Errorf("failed to create flags table: %w", err)
+	}
+	return s, nil
+}
+
+// migrate creates the database and table if missing
+func (s *Store) migrate(ctx context.Context, database string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database)); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.table+` (
+		flag_key VARCHAR(128) PRIMARY KEY,
+		definition JSON NOT NULL,
+		version BIGINT NOT NULL,
+		updated_at DATETIME(3) NOT NULL
+	)`)
+	return err
+}
+
+// shared reports whether the shared cache may be used
+func (s *Store) shared() bool {
+	return s.cache != nil && (s.cacheUp == nil || s.cacheUp())
+}
+
+// List returns all flags ordered by key
+func (s *Store) List(ctx context.Context) ([]Flag, error) {
+	if s.shared() {
+		if v, ok, err := s.cache.Get(ctx, cacheKey); err == nil && ok {
+			var list []Flag
+			if json.Unmarshal([]byte(v), &list) == nil {
+				return list, nil
+			}
+		}
+	}
+
+	list, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.shared() {
+		if data, err := json.Marshal(list); err == nil {
+			s.cache.Set(ctx, cacheKey, string(data), cacheTTL)
+		}
+	}
+	return list, nil
+}
+
+// Get returns a flag
+func (s *Store) Get(ctx context.Context, key string) (Flag, error) {
+	list, err := s.List(ctx)
+	if err != nil {
+		return Flag{}, err
+	}
+	for _, f := range list {
+		if f.Key == key {
+			return f, nil
+		}
+	}
+	return Flag{}, ErrFlagNotFound
+}
+
+// load reads the flags from MySQL
+func (s *Store) load(ctx context.Context) ([]Flag, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT flag_key, definition, version, CAST(UNIX_TIMESTAMP(updated_at) * 1000 AS SIGNED)
+		FROM `+s.table+` ORDER BY flag_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Flag{}
+	for rows.Next() {
+		var f Flag
+		var key string
+		var definition []byte
+		var version, updatedMs int64
+		if err := rows.Scan(&key, &definition, &version, &updatedMs); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(definition, &f); err != nil {
+			return nil, fmt.Errorf("flag %s: %w", key, err)
+		}
+		f.Key, f.Version, f.UpdatedAt = key, version, time.UnixMilli(updatedMs).UTC()
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// Create stores a new flag
+func (s *Store) Create(ctx context.Context, f Flag) (Flag, error) {
+	if err := f.Validate(); err != nil {
+		return Flag{}, err
+	}
+	f.Version = 1
+	f.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	definition, err := json.Marshal(f)
+	if err != nil {
+		return Flag{}, err
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT IGNORE INTO `+s.table+` (flag_key, definition, version, updated_at) VALUES (?, ?, ?, ?)`,
+		f.Key, string(definition), f.Version, f.UpdatedAt)
+	if err != nil {
+		return Flag{}, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return Flag{}, ErrFlagExists
+	}
+
+	s.changed(ctx, Change{Key: f.Key, Action: ActionCreated, Version: f.Version})
+	return f, nil
+}
+
+// Update replaces the definition of a flag
+func (s *Store) Update(ctx context.Context, key string, f Flag) (Flag, error) {
+	f.Key = key
+	if err := f.Validate(); err != nil {
+		return Flag{}, err
+	}
+	return s.modify(ctx, key, func(cur *Flag) {
+		*cur = f
+	})
+}
+
+// SetEnabled turns a flag on or off
+func (s *Store) SetEnabled(ctx context.Context, key string, enabled bool) (Flag, error) {
+	return s.modify(ctx, key, func(cur *Flag) {
+		cur.Enabled = enabled
+	})
+}
+
+// modify applies fn to a flag under a row lock and bumps its version
+func (s *Store) modify(ctx context.Context, key string, fn func(*Flag)) (Flag, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Flag{}, err
+	}
+	defer tx.Rollback()
+
+	var definition []byte
+	var version int64
+	err = tx.QueryRowContext(ctx, `SELECT definition, version FROM `+s.table+` WHERE flag_key = ? FOR UPDATE`, key).Scan(&definition, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Flag{}, ErrFlagNotFound
+	}
+	if err != nil {
+		return Flag{}, err
+	}
+	var f Flag
+	if err := json.Unmarshal(definition, &f); err != nil {
+		return Flag{}, fmt.Errorf("flag %s: %w", key, err)
+	}
+
+	fn(&f)
+	f.Key = key
+	f.Version = version + 1
+	f.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	if definition, err = json.Marshal(f); err != nil {
+		return Flag{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE `+s.table+` SET definition = ?, version = ?, updated_at = ? WHERE flag_key = ?`,
+		string(definition), f.Version, f.UpdatedAt, key); err != nil {
+		return Flag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Flag{}, err
+	}
+
+	s.changed(ctx, Change{Key: key, Action: ActionUpdated, Version: f.Version})
+	return f, nil
+}
+
+// Delete removes a flag
+func (s *Store) Delete(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE flag_key = ?", key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrFlagNotFound
+	}
+	s.changed(ctx, Change{Key: key, Action: ActionDeleted})
+	return nil
+}
+
+// Evaluate evaluates the flags with keys, or all flags when keys is empty.
+// Unknown keys are reported with reason not_found.
+func (s *Store) Evaluate(ctx context.Context, ectx Context, keys []string) (map[string]Result, error) {
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]Result)
+	if len(keys) == 0 {
+		for i := range list {
+			results[list[i].Key] = list[i].Evaluate(ectx)
+		}
+	} else {
+		byKey := make(map[string]*Flag, len(list))
+		for i := range list {
+			byKey[list[i].Key] = &list[i]
+		}
+		for _, k := range keys {
+			if f, ok := byKey[k]; ok {
+				results[k] = f.Evaluate(ectx)
+			} else {
+				results[k] = Result{Key: k, Reason: ReasonNotFound}
+			}
+		}
+	}
+
+	for _, r := range results {
+		metrics.FlagEvaluationsTotal.WithLabelValues(r.Reason).Inc()
+	}
+	return results, nil
+}
+
+// Subscribe returns a channel of changes, including those made through
+// other replicas while the shared cache is up. Call unsubscribe when done.
+func (s *Store) Subscribe() (changes <-chan Change, unsubscribe func()) {
+	ch := make(chan Change, 64)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, ch)
+	}
+}
+
+// notify sends c to every subscriber that has room for it
+func (s *Store) notify(c Change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+}
+
+// message is a change on the shared channel
+type message struct {
+	Instance string `json:"instance"`
+	Change
+}
+
+// changed drops the cached flag set and announces c here and, through the
+// shared channel, to the other replicas
+func (s *Store) changed(ctx context.Context, c Change) {
+	if !s.shared() {
+		s.notify(c)
+		return
+	}
+	// Drop the cached set first, so subscribers re-evaluating see the change
+	if _, err := s.cache.Delete(ctx, cacheKey); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to drop cached flags: %v", err))
+	}
+	s.notify(c)
+	data, _ := json.Marshal(message{Instance: s.instance, Change: c})
+	if err := s.cache.Publish(ctx, changesChannel, string(data)); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to publish flag change: %v", err))
+	}
+}
+
+// Run relays changes made through other replicas to subscribers until ctx
+// is cancelled. Start it once the shared cache is reachable.
+func (s *Store) Run(ctx context.Context) {
+	if s.cache == nil {
+		return
+	}
+	for data := range s.cache.Subscribe(ctx, changesChannel) {
+		var m message
+		if err := json.Unmarshal([]byte(data), &m); err != nil || m.Instance == s.instance {
+			continue
+		}
+		s.notify(m.Change)
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/alerting"
+	"github.com/forge/api/internal/apierror"
+)
+
+// AlertsHandler handles alerts, notification receivers, silences and log rules
+type AlertsHandler struct {
+	manager *alerting.Manager
+	client  *alerting.Client
+	rules   *alerting.RuleManager
+}
+
+// NewAlertsHandler creates a new alerts handler
+func NewAlertsHandler(manager *alerting.Manager, client *alerting.Client, rules *alerting.RuleManager) *AlertsHandler {
+	return &AlertsHandler{manager: manager, client: client, rules: rules}
+}
+
+// HandleAlerts handles /api/v1/alerts requests
+func (h *AlertsHandler) HandleAlerts(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/alerts")
+	path = strings.Trim(path, "/")
+
+	switch {
+	case path == "":
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.listAlerts(w, r)
+	case path == "receivers" || strings.HasPrefix(path, "receivers/"):
+		h.handleReceivers(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "receivers"), "/"))
+	case path == "silences" || strings.HasPrefix(path, "silences/"):
+		h.handleSilences(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "silences"), "/"))
+	case path == "log-rules" || strings.HasPrefix(path, "log-rules/"):
+		h.handleLogRules(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "log-rules"), "/"))
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// listAlerts returns currently firing alerts; ?filter=label="value" may be repeated
+func (h *AlertsHandler) listAlerts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	alerts, err := h.client.Alerts(r.Context(), q["filter"], q.Get("silenced") == "true")
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"items": alerts,
+		"count": len(alerts),
+	})
+}
+
+func (h *AlertsHandler) handleReceivers(w http.ResponseWriter, r *http.Request, name string) {
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addReceiver(w, r)
+	case name != "" && r.Method == "GET":
+		rcv, ok := h.manager.Get(name)
+		if !ok {
+			apierror.Error(w, "Receiver not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rcv)
+	case name != "" && r.Method == "DELETE":
+		if _, ok := h.manager.Get(name); !ok {
+			apierror.Error(w, "Receiver not found", http.StatusNotFound)
+			return
+		}
+		if err := h.manager.Remove(r.Context(), name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addReceiver creates or updates a notification receiver
+func (h *AlertsHandler) addReceiver(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var rcv alerting.Receiver
+	if err := json.NewDecoder(r.Body).Decode(&rcv); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(r.Context(), rcv); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, alerting.ErrReloadFailed) {
+			status = http.StatusBadGateway
+		}
+		apierror.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "receiver": rcv})
+}
+
+func (h *AlertsHandler) handleSilences(w http.ResponseWriter, r *http.Request, id string) {
+	switch {
+	case id == "" && r.Method == "GET":
+		silences, err := h.client.Silences(r.Context())
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": silences,
+			"count": len(silences),
+		})
+	case id == "" && r.Method == "POST":
+		h.createSilence(w, r)
+	case id != "" && r.Method == "DELETE":
+		if err := h.client.DeleteSilence(r.Context(), id); err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": id})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// silenceRequest is the body for creating a silence; duration is used when endsAt is unset
+type silenceRequest struct {
+	Matchers  []alerting.Matcher `json:"matchers"`
+	StartsAt  time.Time          `json:"startsAt"`
+	EndsAt    time.Time          `json:"endsAt"`
+	Duration  string             `json:"duration"`
+	CreatedBy string             `json:"createdBy"`
+	Comment   string             `json:"comment"`
+}
+
+// createSilence creates a silence in Alertmanager
+func (h *AlertsHandler) createSilence(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req silenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.StartsAt.IsZero() {
+		req.StartsAt = time.Now()
+	}
+	if req.EndsAt.IsZero() {
+		d := time.Hour
+		if req.Duration != "" {
+			parsed, err := time.ParseDuration(req.Duration)
+			if err != nil || parsed <= 0 {
+				apierror.Error(w, "Invalid duration", http.StatusBadRequest)
+				return
+			}
+			d = parsed
+		}
+		req.EndsAt = req.StartsAt.Add(d)
+	}
+	if req.CreatedBy == "" {
+		req.CreatedBy = "forge"
+	}
+	if len(req.Matchers) == 0 {
+		apierror.Error(w, "At least one matcher is required", http.StatusBadRequest)
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		apierror.Error(w, "endsAt must be after startsAt", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.client.CreateSilence(r.Context(), alerting.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: req.CreatedBy,
+		Comment:   req.Comment,
+	})
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "id": id, "endsAt": req.EndsAt})
+}
+
+func (h *AlertsHandler) handleLogRules(w http.ResponseWriter, r *http.Request, name string) {
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.rules.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addLogRule(w, r)
+	case name != "" && r.Method == "GET":
+		rule, ok := h.rules.Get(name)
+		if !ok {
+			apierror.Error(w, "Log rule not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rule)
+	case name != "" && r.Method == "DELETE":
+		if err := h.rules.Remove(name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addLogRule creates or replaces a log-based alert rule
+func (h *AlertsHandler) addLogRule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var rule alerting.LogRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.rules.Add(rule); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, _ := h.rules.Get(rule.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "rule": saved.LogRule})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/apps"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/operations"
+)
+
+// AppsHandler deploys and removes app containers
+type AppsHandler struct {
+	manager    *apps.Manager
+	catalog    *apps.Catalog
+	operations *operations.Manager
+}
+
+// NewAppsHandler creates a new apps handler
+func NewAppsHandler(manager *apps.Manager, catalog *apps.Catalog, ops *operations.Manager) *AppsHandler {
+	return &AppsHandler{manager: manager, catalog: catalog, operations: ops}
+}
+
+// HandleApps handles /api/v1/apps requests
+func (h *AppsHandler) HandleApps(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/apps"), "/")
+	if name == "templates" || strings.HasPrefix(name, "templates/") {
+		h.handleTemplates(w, r, strings.TrimPrefix(strings.TrimPrefix(name, "templates"), "/"))
+		return
+	}
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list, err := h.manager.List(r.Context())
+		if err != nil {
+			apierror.Error(w, "Failed to list apps: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"apps": list, "count": len(list)})
+	case name == "" && r.Method == "POST":
+		h.deployApp(w, r)
+	case name != "" && r.Method == "GET":
+		app, err := h.manager.Get(r.Context(), name)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(app)
+	case name != "" && r.Method == "DELETE":
+		if err := h.manager.Remove(r.Context(), name); err != nil {
+			writeAppError(w, err)
+			return
+		}
+		logger.Info("Removed app " + name)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deployApp deploys an app as a background operation, since pulling the
+// image can take minutes
+func (h *AppsHandler) deployApp(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	var app apps.App
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.startDeploy(w, r, app)
+}
+
+// handleTemplates handles /api/v1/apps/templates: listing the catalog,
+// showing a template and deploying one
+func (h *AppsHandler) handleTemplates(w http.ResponseWriter, r *http.Request, path string) {
+	name, action, _ := strings.Cut(path, "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list, err := h.catalog.List()
+		if err != nil {
+			// Broken files are skipped; the rest of the catalog is still served
+			logger.Error("Failed to read app templates", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"templates": list, "count": len(list)})
+	case name != "" && action == "" && r.Method == "GET":
+		t, err := h.catalog.Get(name)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	case name != "" && action == "deploy" && r.Method == "POST":
+		t, err := h.catalog.Get(name)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		// The body is optional; without it the template defaults are used
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+		var overrides apps.TemplateOverrides
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+				apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		app, err := t.Instantiate(overrides)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		h.startDeploy(w, r, app)
+	case name != "" && (action == "" || action == "deploy"):
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// startDeploy validates an app and deploys it as a background operation
+func (h *AppsHandler) startDeploy(w http.ResponseWriter, r *http.Request, app apps.App) {
+	if err := h.manager.Validate(r.Context(), &app); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	op := h.operations.Start("apps.deploy", app.Name, func(ctx context.Context, op *operations.Operation) error {
+		status, err := h.manager.Deploy(ctx, app, op.Logf)
+		if err != nil {
+			return err
+		}
+		op.SetResult(status)
+		logger.Info("Deployed app " + app.Name)
+		return nil
+	})
+
+	writeOperationAccepted(w, op)
+}
+
+// writeAppError maps apps errors to HTTP statuses
+func writeAppError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, apps.ErrInvalid):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, apps.ErrNotFound), errors.Is(err, apps.ErrTemplateNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, apps.ErrExists):
+		apierror.Error(w, err.Error(), http.StatusConflict)
+	default:
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
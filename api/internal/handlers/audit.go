@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/audit"
+)
+
+// AuditHandler serves the audit log of mutating requests
+type AuditHandler struct {
+	log *audit.Log
+}
+
+// NewAuditHandler creates a new audit log handler
+func NewAuditHandler(log *audit.Log) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// HandleAudit handles GET /api/v1/audit; from/to are ms since epoch
+// (default last 7 days), actor a key or user name or key ID, path a path
+// prefix, failed=true only refused or failed requests, limit at most 1000
+func (h *AuditHandler) HandleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := audit.Query{
+		To:     time.Now(),
+		Actor:  q.Get("actor"),
+		Path:   q.Get("path"),
+		Failed: q.Get("failed") == "true",
+	}
+	query.From = query.To.Add(-7 * 24 * time.Hour)
+	if ms, err := strconv.ParseInt(q.Get("from"), 10, 64); err == nil {
+		query.From = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(q.Get("to"), 10, 64); err == nil {
+		query.To = time.UnixMilli(ms)
+	}
+	if !query.From.Before(query.To) {
+		apierror.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			apierror.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = n
+	}
+
+	entries, err := h.log.Query(query)
+	if err != nil {
+		apierror.Error(w, "Failed to read audit log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"from":    query.From.UnixMilli(),
+		"to":      query.To.UnixMilli(),
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+// AuthHandler handles API key issuance and revocation
+type AuthHandler struct {
+	store *auth.Store
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(store *auth.Store) *AuthHandler {
+	return &AuthHandler{store: store}
+}
+
+// HandleKeys handles /api/v1/auth/keys requests
+func (h *AuthHandler) HandleKeys(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/auth/keys")
+	id = strings.Trim(id, "/")
+
+	switch {
+	case id == "" && r.Method == "GET":
+		list := h.store.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys":     list,
+			"count":    len(list),
+			"enforced": h.store.Enforced(),
+		})
+	case id == "" && r.Method == "POST":
+		h.issue(w, r)
+	case id != "" && r.Method == "DELETE":
+		if err := h.store.Revoke(id); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, auth.ErrKeyNotFound) {
+				status = http.StatusNotFound
+			}
+			apierror.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "revoked": id})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// issue creates a key; the plaintext key is only in this response
+func (h *AuthHandler) issue(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req auth.KeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for class := range req.RateLimits {
+		if !ratelimit.ValidClass(class) {
+			apierror.Error(w, "Unknown rate limit class "+class+", want "+strings.Join(ratelimit.Classes, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+
+	key, token, err := h.store.Issue(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrInvalidKey) {
+			status = http.StatusBadRequest
+		}
+		apierror.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"ok":      true,
+		"key":     key,
+		"api_key": token,
+		"message": "Store this key now, it is not shown again",
+	})
+}
+
+// WhoAmI handles GET /api/v1/auth/whoami
+func (h *AuthHandler) WhoAmI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"authenticated": auth.FromContext(r.Context()) != nil,
+		"identity":      auth.FromContext(r.Context()),
+		"enforced":      h.store.Enforced(),
+	})
+}
@@ -3,23 +3,62 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"connectrpc.com/connect"
 	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
 	"github.com/forge/api/internal/cache"
+	"github.com/forge/api/internal/credentials"
+	"github.com/forge/api/internal/deps"
 )
 
 type CacheHandler struct {
-	redisClient *cache.RedisClient
+	redis  *cache.RedisClient
+	memory *cache.MemoryStore // fallback while Redis is down
+	broker *credentials.RedisBroker
+	deps   *deps.Registry
+	port   int
+	host   func() string // external host for SDK clients
 }
 
-func NewCacheHandler(redis *cache.RedisClient) *CacheHandler {
+// NewCacheHandler serves from Redis, or from an in-memory fallback while
+// registry reports Redis down. Fallback responses carry the
+// X-Forge-Degraded header. GetInfo issues ACL users through broker and
+// reports port on the host externalHost returns, or on localhost when it
+// is empty.
+func NewCacheHandler(redis *cache.RedisClient, broker *credentials.RedisBroker, registry *deps.Registry, port int, externalHost func() string) *CacheHandler {
 	return &CacheHandler{
-		redisClient: redis,
+		redis:  redis,
+		memory: cache.NewMemoryStore(cache.DefaultMemoryMaxKeys),
+		broker: broker,
+		deps:   registry,
+		port:   port,
+		host:   externalHost,
+	}
+}
+
+// available reports whether Redis is currently reachable
+func (h *CacheHandler) available() bool {
+	return h.redis != nil && h.deps.Get("redis").Available()
+}
+
+// store returns Redis, or the in-memory fallback while it is down
+func (h *CacheHandler) store() cache.Store {
+	if h.available() {
+		return h.redis
+	}
+	return h.memory
+}
+
+// markDegraded flags responses served by the in-memory fallback
+func (h *CacheHandler) markDegraded(header http.Header) {
+	if !h.available() {
+		header.Set(degradedHeader, "redis; fallback=memory")
 	}
 }
 
@@ -27,80 +66,90 @@ func (h *CacheHandler) Get(
 	ctx context.Context,
 	req *connect.Request[forgev1.GetRequest],
 ) (*connect.Response[forgev1.GetResponse], error) {
-	if h.redisClient == nil {
-		return nil, connect.NewError(connect.CodeUnavailable, nil)
-	}
-	
-	value, found, err := h.redisClient.Get(ctx, req.Msg.Key)
+	value, found, err := h.store().Get(ctx, req.Msg.Key)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 	
-	return connect.NewResponse(&forgev1.GetResponse{
+	resp := connect.NewResponse(&forgev1.GetResponse{
 		Value: value,
 		Found: found,
-	}), nil
+	})
+	h.markDegraded(resp.Header())
+	return resp, nil
 }
 
 func (h *CacheHandler) Set(
 	ctx context.Context,
 	req *connect.Request[forgev1.SetRequest],
 ) (*connect.Response[forgev1.SetResponse], error) {
-	if h.redisClient == nil {
-		return nil, connect.NewError(connect.CodeUnavailable, nil)
-	}
-	
 	ttl := time.Duration(req.Msg.TtlSeconds) * time.Second
-	err := h.redisClient.Set(ctx, req.Msg.Key, req.Msg.Value, ttl)
+	err := h.store().Set(ctx, req.Msg.Key, req.Msg.Value, ttl)
+	if errors.Is(err, cache.ErrMemoryFull) {
+		return nil, connect.NewError(connect.CodeResourceExhausted, err)
+	}
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 	
-	return connect.NewResponse(&forgev1.SetResponse{
+	resp := connect.NewResponse(&forgev1.SetResponse{
 		Ok: true,
-	}), nil
+	})
+	h.markDegraded(resp.Header())
+	return resp, nil
 }
 
 func (h *CacheHandler) Delete(
 	ctx context.Context,
 	req *connect.Request[forgev1.DeleteRequest],
 ) (*connect.Response[forgev1.DeleteResponse], error) {
-	if h.redisClient == nil {
-		return nil, connect.NewError(connect.CodeUnavailable, nil)
-	}
-	
-	deleted, err := h.redisClient.Delete(ctx, req.Msg.Key)
+	deleted, err := h.store().Delete(ctx, req.Msg.Key)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
 	
-	return connect.NewResponse(&forgev1.DeleteResponse{
+	resp := connect.NewResponse(&forgev1.DeleteResponse{
 		Deleted: deleted,
-	}), nil
+	})
+	h.markDegraded(resp.Header())
+	return resp, nil
 }
 
+// GetInfo issues the caller a short-lived Redis ACL user limited to a key
+// prefix, with read or write commands according to their role
 func (h *CacheHandler) GetInfo(
 	ctx context.Context,
 	req *connect.Request[forgev1.CacheInfoRequest],
 ) (*connect.Response[forgev1.CacheInfoResponse], error) {
-	host := os.Getenv("REDIS_HOST")
-	if host == "" {
-		host = "localhost"
+	if h.broker == nil || !h.available() {
+		return nil, connect.NewError(connect.CodeUnavailable, errors.New("redis is unavailable, no credentials can be issued"))
 	}
-	port := os.Getenv("REDIS_PORT")
-	if port == "" {
-		port = "6379"
+	
+	credReq, err := credentialRequest(ctx, req.Msg.Access, req.Msg.KeyPrefix, req.Msg.TtlSeconds)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := h.broker.Issue(ctx, credReq)
+	if err != nil {
+		return nil, credentialError(err)
 	}
-	externalHost := os.Getenv("EXTERNAL_HOST")
+	logCredential(ctx, "redis", credReq, cred)
+	
+	port := strconv.Itoa(h.port)
+	externalHost := h.host()
 	if externalHost == "" {
 		externalHost = "localhost"
 	}
 	
 	return connect.NewResponse(&forgev1.CacheInfoResponse{
-		Host:     externalHost,
-		Port:     6379,
-		Password: "",
-		Url:      "redis://" + externalHost + ":" + port,
+		Host:      externalHost,
+		Port:      int32(h.port),
+		User:      cred.User,
+		Password:  cred.Password,
+		Url:       "redis://" + cred.User + ":" + cred.Password + "@" + externalHost + ":" + port,
+		KeyPrefix: cred.Scope,
+		Access:    cred.Access,
+		ExpiresAt: cred.ExpiresAt.Unix(),
 	}), nil
 }
 
@@ -115,12 +164,13 @@ func CacheREST(h *CacheHandler) http.HandlerFunc {
 		
 		key := path
 		ctx := r.Context()
+		h.markDegraded(w.Header())
 		
 		switch r.Method {
 		case "GET":
 			resp, err := h.Get(ctx, connect.NewRequest(&forgev1.GetRequest{Key: key}))
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeRPCError(w, err)
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
@@ -132,7 +182,7 @@ func CacheREST(h *CacheHandler) http.HandlerFunc {
 				TTL   int64  `json:"ttl"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				apierror.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
 			resp, err := h.Set(ctx, connect.NewRequest(&forgev1.SetRequest{
@@ -141,7 +191,7 @@ func CacheREST(h *CacheHandler) http.HandlerFunc {
 				TtlSeconds: body.TTL,
 			}))
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeRPCError(w, err)
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
@@ -150,27 +200,42 @@ func CacheREST(h *CacheHandler) http.HandlerFunc {
 		case "DELETE":
 			resp, err := h.Delete(ctx, connect.NewRequest(&forgev1.DeleteRequest{Key: key}))
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeRPCError(w, err)
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(resp.Msg)
 			
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
 func CacheInfoREST(h *CacheHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		resp, err := h.GetInfo(r.Context(), connect.NewRequest(&forgev1.CacheInfoRequest{}))
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		
+		access, ttl, err := credentialQuery(r)
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		resp, err := h.GetInfo(r.Context(), connect.NewRequest(&forgev1.CacheInfoRequest{
+			KeyPrefix:  r.URL.Query().Get("prefix"),
+			Access:     access,
+			TtlSeconds: ttl,
+		}))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeRPCError(w, err)
 			return
 		}
 		
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
 		json.NewEncoder(w).Encode(resp.Msg)
 	}
 }
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/certs"
+)
+
+// CertsHandler manages TLS certificates
+type CertsHandler struct {
+	manager *certs.Manager
+}
+
+// NewCertsHandler creates a new certificates handler
+func NewCertsHandler(manager *certs.Manager) *CertsHandler {
+	return &CertsHandler{manager: manager}
+}
+
+// HandleCerts handles /api/v1/certs requests
+func (h *CertsHandler) HandleCerts(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/certs"), "/")
+	domain, action, _ := strings.Cut(path, "/")
+
+	switch {
+	case domain == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case domain == "" && r.Method == "POST":
+		h.requestCert(w, r)
+	case action == "renew" && r.Method == "POST":
+		if err := h.manager.Renew(r.Context(), domain); err != nil {
+			status := http.StatusBadGateway
+			if errors.Is(err, certs.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			apierror.Error(w, err.Error(), status)
+			return
+		}
+		c, _ := h.manager.Get(domain)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c)
+	case action == "" && r.Method == "GET":
+		c, ok := h.manager.Get(domain)
+		if !ok {
+			apierror.Error(w, "Certificate not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c)
+	case action == "" && r.Method == "DELETE":
+		if err := h.manager.Remove(domain); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, certs.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			apierror.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": domain})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// requestCert starts managing a domain; the certificate is obtained in the background
+func (h *CertsHandler) requestCert(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Domain    string `json:"domain"`
+		Challenge string `json:"challenge"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.manager.Request(body.Domain, body.Challenge)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "certificate": c})
+}
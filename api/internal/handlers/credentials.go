@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/credentials"
+	"github.com/forge/api/internal/logger"
+)
+
+// credentialRequest builds a broker request for the caller. Brokered
+// credentials need an authenticated caller even while API keys are not
+// enforced, and are never more than the caller's role allows.
+func credentialRequest(ctx context.Context, access, scope string, ttlSeconds int64) (credentials.Request, error) {
+	id := auth.FromContext(ctx)
+	if id == nil {
+		return credentials.Request{}, connect.NewError(connect.CodeUnauthenticated, auth.ErrUnauthenticated)
+	}
+	access, err := credentials.ResolveAccess(id.Role, access)
+	if err != nil {
+		return credentials.Request{}, credentialError(err)
+	}
+
+	requester := id.KeyID
+	if id.Subject != "" {
+		requester = id.Method + ":" + id.Subject
+	}
+	return credentials.Request{
+		Requester: requester,
+		Access:    access,
+		Scope:     scope,
+		TTL:       time.Duration(ttlSeconds) * time.Second,
+	}, nil
+}
+
+// credentialError maps broker errors to Connect codes
+func credentialError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		return connect.NewError(connect.CodePermissionDenied, err)
+	case errors.Is(err, credentials.ErrInvalidRequest):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	return connect.NewError(connect.CodeInternal, err)
+}
+
+// logCredential records who was issued which account
+func logCredential(ctx context.Context, kind string, req credentials.Request, cred *credentials.Credential) {
+	log := logger.FromContext(ctx)
+	log.Info().
+		Str("kind", kind).
+		Str("requester", req.Requester).
+		Str("user", cred.User).
+		Str("access", cred.Access).
+		Str("scope", cred.Scope).
+		Time("expires_at", cred.ExpiresAt).
+		Msg("Issued credential")
+}
+
+// credentialQuery reads the access and ttl query parameters of the REST
+// info endpoints
+func credentialQuery(r *http.Request) (access string, ttlSeconds int64, err error) {
+	q := r.URL.Query()
+	if v := q.Get("ttl"); v != "" {
+		if ttlSeconds, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return "", 0, connect.NewError(connect.CodeInvalidArgument, errors.New("ttl must be a number of seconds"))
+		}
+	}
+	return q.Get("access"), ttlSeconds, nil
+}
@@ -4,29 +4,49 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"os"
+	"strconv"
 
 	"connectrpc.com/connect"
 	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/credentials"
 	"github.com/forge/api/internal/db"
+	"github.com/forge/api/internal/deps"
 )
 
 type DatabaseHandler struct {
 	mysqlClient *db.MySQLClient
+	broker      *credentials.MySQLBroker
+	deps        *deps.Registry
+	port        int
+	host        func() string // external host for SDK clients
 }
 
-func NewDatabaseHandler(mysql *db.MySQLClient) *DatabaseHandler {
+// NewDatabaseHandler creates a database handler; GetInfo issues accounts
+// through broker. Requests fail with Unavailable while registry reports
+// MySQL down, and succeed again once it reconnects. GetInfo reports port
+// on the host externalHost returns, or on localhost when it is empty.
+func NewDatabaseHandler(mysql *db.MySQLClient, broker *credentials.MySQLBroker, registry *deps.Registry, port int, externalHost func() string) *DatabaseHandler {
 	return &DatabaseHandler{
 		mysqlClient: mysql,
+		broker:      broker,
+		deps:        registry,
+		port:        port,
+		host:        externalHost,
 	}
 }
 
+// available reports whether MySQL is currently reachable
+func (h *DatabaseHandler) available() bool {
+	return h.mysqlClient != nil && h.deps.Get("mysql").Available()
+}
+
 func (h *DatabaseHandler) Query(
 	ctx context.Context,
 	req *connect.Request[forgev1.QueryRequest],
 ) (*connect.Response[forgev1.QueryResponse], error) {
-	if h.mysqlClient == nil {
-		return nil, connect.NewError(connect.CodeUnavailable, nil)
+	if !h.available() {
+		return nil, connect.NewError(connect.CodeUnavailable, h.deps.Unavailable("mysql"))
 	}
 	
 	rows, columns, err := h.mysqlClient.Query(ctx, req.Msg.Sql, req.Msg.Database)
@@ -50,8 +70,8 @@ func (h *DatabaseHandler) Execute(
 	ctx context.Context,
 	req *connect.Request[forgev1.ExecuteRequest],
 ) (*connect.Response[forgev1.ExecuteResponse], error) {
-	if h.mysqlClient == nil {
-		return nil, connect.NewError(connect.CodeUnavailable, nil)
+	if !h.available() {
+		return nil, connect.NewError(connect.CodeUnavailable, h.deps.Unavailable("mysql"))
 	}
 	
 	affected, lastID, err := h.mysqlClient.Execute(ctx, req.Msg.Sql, req.Msg.Database)
@@ -65,50 +85,63 @@ func (h *DatabaseHandler) Execute(
 	}), nil
 }
 
+// GetInfo issues the caller a short-lived MySQL account scoped to one
+// database, with read or write privileges according to their role
 func (h *DatabaseHandler) GetInfo(
 	ctx context.Context,
 	req *connect.Request[forgev1.GetInfoRequest],
 ) (*connect.Response[forgev1.GetInfoResponse], error) {
-	port := getEnvOrDefault("MYSQL_PORT", "3306")
-	user := getEnvOrDefault("MYSQL_USER", "root")
-	password := getEnvOrDefault("MYSQL_PASSWORD", "forgeroot")
+	if h.broker == nil || !h.available() {
+		return nil, connect.NewError(connect.CodeUnavailable, h.deps.Unavailable("mysql"))
+	}
+	
+	credReq, err := credentialRequest(ctx, req.Msg.Access, req.Msg.Database, req.Msg.TtlSeconds)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := h.broker.Issue(ctx, credReq)
+	if err != nil {
+		return nil, credentialError(err)
+	}
+	logCredential(ctx, "mysql", credReq, cred)
+	
+	port := strconv.Itoa(h.port)
 	
 	// External host (for SDK clients outside Docker)
-	host := getEnvOrDefault("EXTERNAL_HOST", "localhost")
+	host := h.host()
+	if host == "" {
+		host = "localhost"
+	}
 	
 	return connect.NewResponse(&forgev1.GetInfoResponse{
-		Host:     host,
-		Port:     3306,
-		User:     user,
-		Password: password,
-		Url:      "mysql+pymysql://" + user + ":" + password + "@" + host + ":" + port,
+		Host:      host,
+		Port:      int32(h.port),
+		User:      cred.User,
+		Password:  cred.Password,
+		Url:       "mysql+pymysql://" + cred.User + ":" + cred.Password + "@" + host + ":" + port + "/" + cred.Scope,
+		Database:  cred.Scope,
+		Access:    cred.Access,
+		ExpiresAt: cred.ExpiresAt.Unix(),
 	}), nil
 }
 
-func getEnvOrDefault(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return def
-}
-
 // REST handlers
 func QueryREST(h *DatabaseHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		
 		var req forgev1.QueryRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		
 		resp, err := h.Query(r.Context(), connect.NewRequest(&req))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeRPCError(w, err)
 			return
 		}
 		
@@ -119,13 +152,28 @@ func QueryREST(h *DatabaseHandler) http.HandlerFunc {
 
 func DBInfoREST(h *DatabaseHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		resp, err := h.GetInfo(r.Context(), connect.NewRequest(&forgev1.GetInfoRequest{}))
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		
+		access, ttl, err := credentialQuery(r)
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		resp, err := h.GetInfo(r.Context(), connect.NewRequest(&forgev1.GetInfoRequest{
+			Database:   r.URL.Query().Get("database"),
+			Access:     access,
+			TtlSeconds: ttl,
+		}))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeRPCError(w, err)
 			return
 		}
 		
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
 		json.NewEncoder(w).Encode(resp.Msg)
 	}
 }
@@ -133,19 +181,19 @@ func DBInfoREST(h *DatabaseHandler) http.HandlerFunc {
 func ExecuteREST(h *DatabaseHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		
 		var req forgev1.ExecuteRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		
 		resp, err := h.Execute(r.Context(), connect.NewRequest(&req))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeRPCError(w, err)
 			return
 		}
 		
@@ -154,3 +202,32 @@ func ExecuteREST(h *DatabaseHandler) http.HandlerFunc {
 	}
 }
 
+
+// VizHintsREST infers column types and suggests visualizations for query
+// results. The body is a QueryResponse ({"columns": [...], "rows": [...]}).
+func VizHintsREST() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+		var results forgev1.QueryResponse
+		if err := json.NewDecoder(r.Body).Decode(&results); err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows := make([]map[string]string, 0, len(results.Rows))
+		for _, row := range results.Rows {
+			if row != nil {
+				rows = append(rows, row.Values)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(db.InferHints(results.Columns, rows))
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/logger"
+)
+
+// DebugHandler serves runtime diagnostics under /debug: pprof profiles,
+// expvar variables and dumps of the goroutine and heap profiles to disk.
+// It only answers admins, so it stays closed while authentication is not
+// enforced.
+type DebugHandler struct {
+	dumpDir string
+	mux     *http.ServeMux
+}
+
+// NewDebugHandler creates a debug handler writing dumps to dumpDir
+func NewDebugHandler(dumpDir string) *DebugHandler {
+	h := &DebugHandler{dumpDir: dumpDir, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	h.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	h.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	h.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	h.mux.Handle("/debug/vars", expvar.Handler())
+	h.mux.HandleFunc("/debug/dump", h.dump)
+	return h
+}
+
+// ServeHTTP handles /debug/ requests
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if id := auth.FromContext(r.Context()); id == nil || !auth.Allows(id.Role, auth.RoleAdmin) {
+		apierror.Error(w, "debug endpoints need an admin key, and authentication to be enforced", http.StatusForbidden)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// dump handles POST /debug/dump: writes the goroutine and heap profiles
+// to the dump directory and returns their paths. gc=true collects garbage
+// first, so the heap profile reflects live memory only.
+func (h *DebugHandler) dump(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := os.MkdirAll(h.dumpDir, 0o700); err != nil {
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("gc") == "true" {
+		runtime.GC()
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	files := map[string]string{}
+	for _, name := range []string{"goroutine", "heap"} {
+		path := filepath.Join(h.dumpDir, fmt.Sprintf("%s-%s.pb.gz", name, stamp))
+		if err := writeProfile(name, path); err != nil {
+			apierror.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		files[name] = path
+	}
+
+	log := logger.FromContext(r.Context())
+	log.Info().Str("goroutine", files["goroutine"]).Str("heap", files["heap"]).Msg("Runtime profiles dumped")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"ok":         true,
+		"files":      files,
+		"goroutines": runtime.NumGoroutine(),
+	})
+}
+
+func writeProfile(name, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := rpprof.Lookup(name).WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
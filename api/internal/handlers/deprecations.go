@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/deprecation"
+)
+
+// DeprecationsREST reports deprecated endpoints and their usage
+func DeprecationsREST(registry *deprecation.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := registry.Report()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"deprecations": report,
+			"count":        len(report),
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/deps"
+)
+
+// degradedHeader lists dependencies being served by a fallback
+const degradedHeader = "X-Forge-Degraded"
+
+// writeRPCError writes an error returned by a Connect handler method as
+// the JSON error envelope. Dependency outages carry the reason and which
+// capabilities are affected in its details.
+func writeRPCError(w http.ResponseWriter, err error) {
+	var unavailable *deps.UnavailableError
+	if errors.As(err, &unavailable) {
+		apierror.Write(w, http.StatusServiceUnavailable, connect.CodeUnavailable, unavailable.Error(), map[string]any{
+			"dependency": unavailable.Dependency,
+			"reason":     unavailable.Reason,
+			"affected":   unavailable.Affected,
+			"hint":       unavailable.Hint,
+		})
+		return
+	}
+
+	apierror.FromConnect(w, err, nil)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/errtrack"
+)
+
+// ErrorsHandler captures exception events and serves error groups
+type ErrorsHandler struct {
+	store *errtrack.Store
+}
+
+// NewErrorsHandler creates a new error tracking handler
+func NewErrorsHandler(store *errtrack.Store) *ErrorsHandler {
+	return &ErrorsHandler{store: store}
+}
+
+// Capture stores an exception event
+func (h *ErrorsHandler) Capture(
+	ctx context.Context,
+	req *connect.Request[forgev1.ErrorEvent],
+) (*connect.Response[forgev1.CaptureErrorResponse], error) {
+	event := errtrack.Event{
+		Message:     req.Msg.Message,
+		Type:        req.Msg.Type,
+		Stacktrace:  req.Msg.Stacktrace,
+		Release:     req.Msg.Release,
+		Environment: req.Msg.Environment,
+		Service:     req.Msg.Service,
+		Level:       req.Msg.Level,
+		User:        req.Msg.User,
+		Tags:        req.Msg.Tags,
+		Fingerprint: req.Msg.Fingerprint,
+	}
+	if req.Msg.TimestampMs > 0 {
+		event.Timestamp = time.UnixMilli(req.Msg.TimestampMs)
+	}
+
+	fingerprint, newGroup, err := h.store.Capture(ctx, event)
+	if err != nil {
+		if errors.Is(err, errtrack.ErrInvalidEvent) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(&forgev1.CaptureErrorResponse{
+		Ok:          true,
+		Fingerprint: fingerprint,
+		NewGroup:    newGroup,
+	}), nil
+}
+
+// HandleErrors handles /api/v1/errors requests
+func (h *ErrorsHandler) HandleErrors(w http.ResponseWriter, r *http.Request) {
+	fingerprint := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/errors"), "/")
+
+	switch {
+	case fingerprint == "" && r.Method == "GET":
+		h.listGroups(w, r)
+	case fingerprint == "" && r.Method == "POST":
+		h.capture(w, r)
+	case fingerprint != "" && r.Method == "GET":
+		h.getGroup(w, r, fingerprint)
+	case fingerprint != "" && r.Method == "PATCH":
+		h.updateGroup(w, r, fingerprint)
+	case fingerprint != "" && r.Method == "DELETE":
+		if err := h.store.Delete(r.Context(), fingerprint); err != nil {
+			writeErrtrackError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": fingerprint})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// capture is the REST form of the Capture RPC
+func (h *ErrorsHandler) capture(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req forgev1.ErrorEvent
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.Capture(r.Context(), connect.NewRequest(&req))
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp.Msg)
+}
+
+// listGroups returns error groups, most recently seen first
+func (h *ErrorsHandler) listGroups(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	groups, err := h.store.List(r.Context(), errtrack.ListOptions{
+		Service: q.Get("service"),
+		Status:  q.Get("status"),
+		Limit:   limit,
+	})
+	if err != nil {
+		writeErrtrackError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"items": groups,
+		"count": len(groups),
+	})
+}
+
+// getGroup returns a group with its most recent events
+func (h *ErrorsHandler) getGroup(w http.ResponseWriter, r *http.Request, fingerprint string) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	group, events, err := h.store.Get(r.Context(), fingerprint, limit)
+	if err != nil {
+		writeErrtrackError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"group":  group,
+		"events": events,
+	})
+}
+
+// updateGroup changes the status of a group
+func (h *ErrorsHandler) updateGroup(w http.ResponseWriter, r *http.Request, fingerprint string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.SetStatus(r.Context(), fingerprint, body.Status); err != nil {
+		writeErrtrackError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "fingerprint": fingerprint, "status": body.Status})
+}
+
+// writeErrtrackError maps store errors to HTTP statuses
+func writeErrtrackError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errtrack.ErrGroupNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errtrack.ErrInvalidEvent):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
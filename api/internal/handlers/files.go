@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/files"
+)
+
+// checksumHeader carries the hex SHA-256 of a file: uploads are refused
+// when they do not match it, downloads report it
+const checksumHeader = "X-Checksum-Sha256"
+
+// FilesHandler serves files kept on the local volume
+type FilesHandler struct {
+	store *files.Store
+}
+
+// NewFilesHandler creates a new files handler
+func NewFilesHandler(store *files.Store) *FilesHandler {
+	return &FilesHandler{store: store}
+}
+
+// HandleFiles handles /api/v1/files requests. Files are uploaded and
+// downloaded as their raw bytes; downloads honour Range and conditional
+// requests.
+func (h *FilesHandler) HandleFiles(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/files"), "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list, err := h.store.List(r.URL.Query().Get("prefix"))
+		if err != nil {
+			writeFilesError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+			"usage": h.store.Usage(),
+		})
+	case name == "":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case r.Method == "GET" || r.Method == "HEAD":
+		h.download(w, r, name)
+	case r.Method == "PUT":
+		f, created, err := h.store.Put(name, r.Body, r.ContentLength, r.Header.Get("Content-Type"), r.Header.Get(checksumHeader))
+		if err != nil {
+			writeFilesError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(f)
+	case r.Method == "DELETE":
+		if err := h.store.Delete(name); err != nil {
+			writeFilesError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// download serves a file; http.ServeContent answers Range, If-Range and
+// If-None-Match against the checksum ETag
+func (h *FilesHandler) download(w http.ResponseWriter, r *http.Request, name string) {
+	f, data, err := h.store.Open(name)
+	if err != nil {
+		writeFilesError(w, err)
+		return
+	}
+	defer data.Close()
+
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("ETag", `"`+f.SHA256+`"`)
+	w.Header().Set(checksumHeader, f.SHA256)
+	http.ServeContent(w, r, f.Path, f.Modified, data)
+}
+
+func writeFilesError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, files.ErrInvalidPath), errors.Is(err, files.ErrChecksum):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, files.ErrNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, files.ErrConflict):
+		apierror.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, files.ErrTooLarge):
+		apierror.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, files.ErrQuota):
+		apierror.Error(w, err.Error(), http.StatusInsufficientStorage)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
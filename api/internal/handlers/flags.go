@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/flags"
+)
+
+// FlagsHandler manages feature flags and evaluates them for apps
+type FlagsHandler struct {
+	store *flags.Store
+}
+
+// NewFlagsHandler creates a new feature flags handler
+func NewFlagsHandler(store *flags.Store) *FlagsHandler {
+	return &FlagsHandler{store: store}
+}
+
+// Evaluate evaluates flags for a key and its attributes
+func (h *FlagsHandler) Evaluate(
+	ctx context.Context,
+	req *connect.Request[forgev1.EvaluateFlagsRequest],
+) (*connect.Response[forgev1.EvaluateFlagsResponse], error) {
+	results, err := h.store.Evaluate(ctx, flags.Context{Key: req.Msg.Key, Attributes: req.Msg.Attributes}, req.Msg.Flags)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	resp := &forgev1.EvaluateFlagsResponse{Flags: make(map[string]*forgev1.FlagEvaluation, len(results))}
+	for key, r := range results {
+		resp.Flags[key] = &forgev1.FlagEvaluation{
+			Key:     r.Key,
+			Enabled: r.Enabled,
+			Variant: r.Variant,
+			Reason:  r.Reason,
+			Version: r.Version,
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// HandleFlags handles /api/v1/flags requests
+func (h *FlagsHandler) HandleFlags(w http.ResponseWriter, r *http.Request) {
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/flags"), "/")
+
+	switch {
+	case key == "evaluate" && r.Method == "GET":
+		h.evaluate(w, r)
+	case key == "" && r.Method == "GET":
+		list, err := h.store.List(r.Context())
+		if err != nil {
+			writeFlagsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"items": list, "count": len(list)})
+	case key == "" && r.Method == "POST":
+		h.createFlag(w, r)
+	case key != "" && r.Method == "GET":
+		f, err := h.store.Get(r.Context(), key)
+		if err != nil {
+			writeFlagsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(f)
+	case key != "" && r.Method == "PUT":
+		h.updateFlag(w, r, key)
+	case key != "" && r.Method == "PATCH":
+		h.toggleFlag(w, r, key)
+	case key != "" && r.Method == "DELETE":
+		if err := h.store.Delete(r.Context(), key); err != nil {
+			writeFlagsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": key})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createFlag stores a new flag
+func (h *FlagsHandler) createFlag(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var f flags.Flag
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.store.Create(r.Context(), f)
+	if err != nil {
+		writeFlagsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// updateFlag replaces the definition of a flag
+func (h *FlagsHandler) updateFlag(w http.ResponseWriter, r *http.Request, key string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var f flags.Flag
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.Key != "" && f.Key != key {
+		apierror.Error(w, "Flag key in the body does not match the path", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.store.Update(r.Context(), key, f)
+	if err != nil {
+		writeFlagsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// toggleFlag turns a flag on or off
+func (h *FlagsHandler) toggleFlag(w http.ResponseWriter, r *http.Request, key string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Enabled == nil {
+		apierror.Error(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.store.SetEnabled(r.Context(), key, *body.Enabled)
+	if err != nil {
+		writeFlagsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// evaluate handles GET /api/v1/flags/evaluate: ?key= is the user or entity,
+// ?flags= a comma-separated list of flags (all when omitted), and every
+// other parameter a targeting attribute. Clients accepting
+// text/event-stream get a "snapshot" event with the results, then a
+// "change" event with the new result of each flag changed afterwards.
+func (h *FlagsHandler) evaluate(w http.ResponseWriter, r *http.Request) {
+	ectx, keys := evaluationRequest(r.URL.Query())
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		results, err := h.store.Evaluate(r.Context(), ectx, keys)
+		if err != nil {
+			writeFlagsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"flags": results})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before evaluating so no change falls between them
+	changes, unsubscribe := h.store.Subscribe()
+	defer unsubscribe()
+	results, err := h.store.Evaluate(r.Context(), ectx, keys)
+	if err != nil {
+		writeFlagsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	writeSSE(w, "snapshot", map[string]any{"flags": results})
+	flusher.Flush()
+
+	watched := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		watched[k] = true
+	}
+	ticker := time.NewTicker(tailKeepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		case c := <-changes:
+			if len(keys) > 0 && !watched[c.Key] {
+				continue
+			}
+			results, err := h.store.Evaluate(r.Context(), ectx, []string{c.Key})
+			if err != nil {
+				continue
+			}
+			writeSSE(w, "change", map[string]any{"action": c.Action, "flag": results[c.Key]})
+			flusher.Flush()
+		}
+	}
+}
+
+// evaluationRequest reads the evaluation context and flag keys of a query
+func evaluationRequest(q url.Values) (flags.Context, []string) {
+	ectx := flags.Context{Key: q.Get("key"), Attributes: map[string]string{}}
+	var keys []string
+	for _, k := range strings.Split(q.Get("flags"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	for name, values := range q {
+		if name != "key" && name != "flags" && len(values) > 0 {
+			ectx.Attributes[name] = values[0]
+		}
+	}
+	return ectx, keys
+}
+
+// writeFlagsError maps store errors to HTTP statuses
+func writeFlagsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, flags.ErrFlagNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, flags.ErrFlagExists):
+		apierror.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, flags.ErrInvalidFlag):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -4,41 +4,84 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
 	"github.com/forge/api/internal/cache"
+	"github.com/forge/api/internal/config"
 	"github.com/forge/api/internal/db"
+	"github.com/forge/api/internal/deps"
+	"github.com/forge/api/internal/services"
+	"github.com/forge/api/internal/version"
 )
 
 type ForgeHandler struct {
 	startTime   time.Time
 	mysqlClient *db.MySQLClient
 	redisClient *cache.RedisClient
+	deps        *deps.Registry
+	health      config.Health
+	registered  func() []services.Service
+
+	// Last health check results, reused for health.CacheTTL
+	healthMu      sync.Mutex
+	healthResults map[string]*ServiceHealth
+	healthChecked time.Time
 }
 
-func NewForgeHandler(startTime time.Time, mysql *db.MySQLClient, redis *cache.RedisClient) *ForgeHandler {
+// NewForgeHandler creates the handler for service status. GET
+// /api/v1/health checks MySQL, Redis and health.Targets.
+func NewForgeHandler(startTime time.Time, mysql *db.MySQLClient, redis *cache.RedisClient, registry *deps.Registry, health config.Health) *ForgeHandler {
 	return &ForgeHandler{
 		startTime:   startTime,
 		mysqlClient: mysql,
 		redisClient: redis,
+		deps:        registry,
+		health:      health,
 	}
 }
 
+// SetRegisteredServices adds the services apps registered, from fn, to
+// the health report
+func (h *ForgeHandler) SetRegisteredServices(fn func() []services.Service) {
+	h.registered = fn
+}
+
 // ServiceHealth represents the health of a single service
 type ServiceHealth struct {
-	Status  string `json:"status"` // "healthy", "unhealthy", "unknown"
-	Message string `json:"message,omitempty"`
+	Status   string `json:"status"` // "healthy", "unhealthy", "degraded", "unknown"
+	Message  string `json:"message,omitempty"`
+	Fallback string `json:"fallback,omitempty"`
 }
 
 // HealthResponse represents the full health check response
 type HealthCheckResponse struct {
 	OK       bool                      `json:"ok"`
+	Partial  bool                      `json:"partial"` // some services are degraded or down, but the API is serving
 	Uptime   string                    `json:"uptime"`
 	Services map[string]*ServiceHealth `json:"services"`
+	// Registered are the services in the service registry, by name
+	Registered map[string]*ServiceHealth `json:"registered,omitempty"`
+	Degraded   []deps.State              `json:"degraded,omitempty"`
+}
+
+// notConfiguredHealth reports a dependency that was not connected at startup
+func (h *ForgeHandler) notConfiguredHealth(name string) *ServiceHealth {
+	state := h.deps.Get(name)
+	health := &ServiceHealth{Status: "unhealthy", Message: state.Reason, Fallback: state.Fallback}
+	if state.Mode == deps.ModeDegraded {
+		health.Status = "degraded"
+	}
+	if health.Message == "" {
+		health.Message = "not configured"
+	}
+	return health
 }
 
 func (h *ForgeHandler) Health(
@@ -56,15 +99,18 @@ func (h *ForgeHandler) Info(
 ) (*connect.Response[forgev1.InfoResponse], error) {
 	// Deprecated - keeping for proto compatibility
 	return connect.NewResponse(&forgev1.InfoResponse{
-		Version: "0.1.0",
+		Version: version.Version,
 		Uptime:  time.Since(h.startTime).String(),
 	}), nil
 }
 
-// checkServiceHealth checks health of external services via HTTP
-func checkHTTPHealth(url string, timeout time.Duration) *ServiceHealth {
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(url)
+// checkHTTPHealth checks health of external services via HTTP
+func checkHTTPHealth(ctx context.Context, url string) *ServiceHealth {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &ServiceHealth{Status: "unhealthy", Message: err.Error()}
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return &ServiceHealth{Status: "unhealthy", Message: err.Error()}
 	}
@@ -76,84 +122,126 @@ func checkHTTPHealth(url string, timeout time.Duration) *ServiceHealth {
 	return &ServiceHealth{Status: "unhealthy", Message: resp.Status}
 }
 
-// HealthREST returns detailed health of all services
-func HealthREST(h *ForgeHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		timeout := 2 * time.Second
+// checkPing reports the health of a client's Ping, or the registry state
+// of a dependency without a client
+func (h *ForgeHandler) checkPing(ctx context.Context, name string, ping func(context.Context) error) *ServiceHealth {
+	if ping == nil {
+		return h.notConfiguredHealth(name)
+	}
+	if err := ping(ctx); err != nil {
+		return &ServiceHealth{Status: "unhealthy", Message: err.Error()}
+	}
+	return &ServiceHealth{Status: "healthy"}
+}
 
-		services := make(map[string]*ServiceHealth)
-		allHealthy := true
+// checkServices runs every health check concurrently under one deadline,
+// so a slow service costs at most health.Timeout. Results are reused for
+// health.CacheTTL; concurrent requests wait for the checks in progress.
+func (h *ForgeHandler) checkServices() map[string]*ServiceHealth {
+	h.healthMu.Lock()
+	defer h.healthMu.Unlock()
+	if h.healthResults != nil && time.Since(h.healthChecked) < h.health.CacheTTL {
+		return h.healthResults
+	}
 
-		// API (self)
-		services["api"] = &ServiceHealth{Status: "healthy"}
+	// Not the request's context: results are shared between requests
+	ctx, cancel := context.WithTimeout(context.Background(), h.health.Timeout)
+	defer cancel()
 
-		// MySQL
-		if h.mysqlClient != nil {
-			if err := h.mysqlClient.Ping(ctx); err == nil {
-				services["mysql"] = &ServiceHealth{Status: "healthy"}
-			} else {
-				services["mysql"] = &ServiceHealth{Status: "unhealthy", Message: err.Error()}
-				allHealthy = false
-			}
-		} else {
-			services["mysql"] = &ServiceHealth{Status: "unhealthy", Message: "not configured"}
-			allHealthy = false
-		}
+	checks := map[string]func() *ServiceHealth{
+		"api": func() *ServiceHealth { return &ServiceHealth{Status: "healthy"} },
+	}
+	var mysqlPing, redisPing func(context.Context) error
+	if h.mysqlClient != nil {
+		mysqlPing = h.mysqlClient.Ping
+	}
+	if h.redisClient != nil {
+		redisPing = h.redisClient.Ping
+	}
+	checks["mysql"] = func() *ServiceHealth { return h.checkPing(ctx, "mysql", mysqlPing) }
+	checks["redis"] = func() *ServiceHealth { return h.checkPing(ctx, "redis", redisPing) }
+	for _, t := range h.health.Targets {
+		url := t.URL
+		checks[t.Name] = func() *ServiceHealth { return checkHTTPHealth(ctx, url) }
+	}
 
-		// Redis
-		if h.redisClient != nil {
-			if err := h.redisClient.Ping(ctx); err == nil {
-				services["redis"] = &ServiceHealth{Status: "healthy"}
-			} else {
-				services["redis"] = &ServiceHealth{Status: "unhealthy", Message: err.Error()}
-				allHealthy = false
-			}
-		} else {
-			services["redis"] = &ServiceHealth{Status: "unhealthy", Message: "not configured"}
-			allHealthy = false
-		}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]*ServiceHealth, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func() *ServiceHealth) {
+			defer wg.Done()
+			result := check()
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
 
-		// Grafana
-		services["grafana"] = checkHTTPHealth("http://grafana:3000/api/health", timeout)
-		if services["grafana"].Status != "healthy" {
-			allHealthy = false
-		}
+	h.healthResults = results
+	h.healthChecked = time.Now()
+	return results
+}
 
-		// Prometheus
-		services["prometheus"] = checkHTTPHealth("http://prometheus:9090/-/ready", timeout)
-		if services["prometheus"].Status != "healthy" {
-			allHealthy = false
+// registeredHealth reports the registered services: healthy when every
+// instance passes, degraded when some do and unhealthy when none does
+func (h *ForgeHandler) registeredHealth() map[string]*ServiceHealth {
+	if h.registered == nil {
+		return nil
+	}
+	results := make(map[string]*ServiceHealth)
+	for _, svc := range h.registered() {
+		health := &ServiceHealth{Status: "healthy"}
+		switch svc.Status {
+		case services.StatusWarning:
+			health.Status = "degraded"
+		case services.StatusCritical:
+			health.Status = "unhealthy"
 		}
-
-		// Loki
-		services["loki"] = checkHTTPHealth("http://loki:3100/ready", timeout)
-		if services["loki"].Status != "healthy" {
-			allHealthy = false
+		if health.Status != "healthy" {
+			health.Message = fmt.Sprintf("%d of %d instances passing", svc.Passing, len(svc.Instances))
 		}
+		results[svc.Name] = health
+	}
+	return results
+}
 
-		// Tempo
-		services["tempo"] = checkHTTPHealth("http://tempo:3200/ready", timeout)
-		if services["tempo"].Status != "healthy" {
-			allHealthy = false
+// HealthREST returns detailed health of all services
+func HealthREST(h *ForgeHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checked := h.checkServices()
+		registered := h.registeredHealth()
+		allHealthy := true
+		for _, s := range checked {
+			if s.Status != "healthy" {
+				allHealthy = false
+			}
 		}
-
-		// Nginx (check via localhost since it's the entry point)
-		services["nginx"] = checkHTTPHealth("http://nginx:80/", timeout)
-		if services["nginx"].Status != "healthy" {
-			allHealthy = false
+		for _, s := range registered {
+			if s.Status != "healthy" {
+				allHealthy = false
+			}
 		}
 
 		response := HealthCheckResponse{
-			OK:       allHealthy,
-			Uptime:   time.Since(h.startTime).Round(time.Second).String(),
-			Services: services,
+			OK:         allHealthy,
+			Partial:    !allHealthy,
+			Uptime:     time.Since(h.startTime).Round(time.Second).String(),
+			Services:   checked,
+			Registered: registered,
+		}
+		for _, state := range h.deps.All() {
+			if !state.Available() {
+				response.Degraded = append(response.Degraded, state)
+			}
 		}
 
 		var buf bytes.Buffer
 		if err := json.NewEncoder(&buf).Encode(response); err != nil {
 			log.Printf("failed to encode health response: %v", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apierror.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/observe"
+)
+
+// GrafanaHandler manages Grafana datasources
+type GrafanaHandler struct {
+	client *observe.GrafanaClient
+}
+
+// NewGrafanaHandler creates a new Grafana handler
+func NewGrafanaHandler(client *observe.GrafanaClient) *GrafanaHandler {
+	return &GrafanaHandler{client: client}
+}
+
+// HandleDatasources handles /api/v1/grafana/datasources requests
+func (h *GrafanaHandler) HandleDatasources(w http.ResponseWriter, r *http.Request) {
+	uid := strings.TrimPrefix(r.URL.Path, "/api/v1/grafana/datasources")
+	uid = strings.Trim(uid, "/")
+
+	switch {
+	case uid == "" && r.Method == "GET":
+		list, err := h.client.ListDatasources(r.Context())
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case uid == "" && r.Method == "POST":
+		h.saveDatasource(w, r, "")
+	case uid != "" && r.Method == "GET":
+		ds, err := h.client.GetDatasource(r.Context(), uid)
+		if err != nil {
+			writeGrafanaError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ds)
+	case uid != "" && r.Method == "PUT":
+		h.saveDatasource(w, r, uid)
+	case uid != "" && r.Method == "DELETE":
+		if err := h.client.DeleteDatasource(r.Context(), uid); err != nil {
+			writeGrafanaError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": uid})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// datasourceRequest is a datasource plus a convenience password field
+// that is sent to Grafana as secureJsonData.password
+type datasourceRequest struct {
+	observe.Datasource
+	Password string `json:"password,omitempty"`
+}
+
+// saveDatasource creates (uid == "") or updates a datasource
+func (h *GrafanaHandler) saveDatasource(w http.ResponseWriter, r *http.Request, uid string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req datasourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ds := req.Datasource
+	if req.Password != "" {
+		if ds.SecureJSONData == nil {
+			ds.SecureJSONData = make(map[string]string)
+		}
+		ds.SecureJSONData["password"] = req.Password
+	}
+	if uid != "" {
+		ds.UID = uid
+	}
+	if err := ds.Validate(); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	status := http.StatusOK
+	if uid == "" {
+		err = h.client.CreateDatasource(r.Context(), ds)
+		status = http.StatusCreated
+	} else {
+		err = h.client.UpdateDatasource(r.Context(), ds)
+	}
+	if err != nil {
+		writeGrafanaError(w, err)
+		return
+	}
+
+	// Never echo credentials back
+	ds.SecureJSONData = nil
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "datasource": ds})
+}
+
+func writeGrafanaError(w http.ResponseWriter, err error) {
+	if errors.Is(err, observe.ErrDatasourceNotFound) {
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	apierror.Error(w, err.Error(), http.StatusBadGateway)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/history"
+)
+
+// HistoryHandler serves stored host and container usage
+type HistoryHandler struct {
+	store *history.Store
+}
+
+// NewHistoryHandler creates a new usage history handler
+func NewHistoryHandler(store *history.Store) *HistoryHandler {
+	return &HistoryHandler{store: store}
+}
+
+// HandleHistory handles /api/v1/system/history and
+// /api/v1/system/history/trends requests
+func (h *HistoryHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/system/history"), "/") {
+	case "":
+		h.getSeries(w, r)
+	case "trends":
+		h.getTrends(w, r)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// getSeries returns usage per step; from/to are ms since epoch (default
+// last 24 hours), step a duration, kind host or container, name a
+// container name
+func (h *HistoryHandler) getSeries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := history.Query{To: time.Now(), Kind: q.Get("kind"), Name: q.Get("name")}
+	query.From = query.To.Add(-24 * time.Hour)
+	if ms, err := strconv.ParseInt(q.Get("from"), 10, 64); err == nil {
+		query.From = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(q.Get("to"), 10, 64); err == nil {
+		query.To = time.UnixMilli(ms)
+	}
+	if !query.From.Before(query.To) {
+		apierror.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	if step := q.Get("step"); step != "" {
+		d, err := time.ParseDuration(step)
+		if err != nil || d <= 0 {
+			apierror.Error(w, "Invalid step", http.StatusBadRequest)
+			return
+		}
+		query.Step = d
+	}
+	switch query.Kind {
+	case "", history.KindHost, history.KindContainer:
+	default:
+		apierror.Error(w, "kind must be host or container", http.StatusBadRequest)
+		return
+	}
+
+	series, err := h.store.Series(r.Context(), query)
+	if err != nil {
+		apierror.Error(w, "Failed to read usage history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"from":   query.From.UnixMilli(),
+		"to":     query.To.UnixMilli(),
+		"series": series,
+	})
+}
+
+// getTrends compares usage on the first and last day of ?days= (default 7)
+func (h *HistoryHandler) getTrends(w http.ResponseWriter, r *http.Request) {
+	days := history.DefaultTrendDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 || n > history.MaxTrendDays {
+			apierror.Error(w, "days must be between 2 and 90", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	trends, err := h.store.Trends(r.Context(), days, time.Now())
+	if err != nil {
+		apierror.Error(w, "Failed to compute usage trends: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"days": days, "trends": trends, "count": len(trends)})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/hooks"
+)
+
+// maxHookBodySize bounds requests to inbound hooks; senders such as GitHub
+// post payloads of several megabytes
+const maxHookBodySize = 5 << 20
+
+// HooksHandler receives inbound webhooks and manages the hooks
+type HooksHandler struct {
+	manager *hooks.Manager
+}
+
+// NewHooksHandler creates a new hooks handler
+func NewHooksHandler(manager *hooks.Manager) *HooksHandler {
+	return &HooksHandler{manager: manager}
+}
+
+// Receive handles requests to /hooks/{name}. Senders are answered 200 once
+// every matching target succeeded and 502 when one failed, so they retry.
+func (h *HooksHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hooks/"), "/")
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHookBodySize)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rc, err := h.manager.Receive(r.Context(), name, r.Header, body)
+	switch {
+	case errors.Is(err, hooks.ErrHookNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, hooks.ErrUnverified):
+		apierror.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, hooks.ErrTargetsFailed):
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "id": rc.ID, "status": rc.Status})
+}
+
+// HandleHooks handles /api/v1/hooks requests
+func (h *HooksHandler) HandleHooks(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/hooks"), "/")
+	parts := strings.Split(path, "/")
+	name := parts[0]
+
+	switch {
+	case path == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case path == "" && r.Method == "POST":
+		h.saveHook(w, r)
+	case len(parts) == 1 && r.Method == "GET":
+		st, err := h.manager.Get(name)
+		if err != nil {
+			writeHooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case len(parts) == 1 && r.Method == "PATCH":
+		h.toggleHook(w, r, name)
+	case len(parts) == 1 && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			writeHooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	case len(parts) == 2 && parts[1] == "receipts" && r.Method == "GET":
+		list, err := h.manager.Receipts(name)
+		if err != nil {
+			writeHooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case len(parts) > 1 && r.Method != "GET":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case len(parts) > 1:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveHook creates or replaces a hook. A secret generated for an HMAC hook
+// is returned in the response, and only then.
+func (h *HooksHandler) saveHook(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The secret is not part of the JSON form of a hook, so it is read
+	// separately
+	var hook hooks.Hook
+	var secret struct {
+		Verify struct {
+			Secret string `json:"secret"`
+		} `json:"verify"`
+	}
+	if err := json.Unmarshal(data, &hook); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.Unmarshal(data, &secret)
+	hook.Verify.Secret = secret.Verify.Secret
+
+	generated, created, err := h.manager.Save(hook)
+	if err != nil {
+		writeHooksError(w, err)
+		return
+	}
+
+	saved, _ := h.manager.Get(hook.Name)
+	resp := map[string]any{"ok": true, "hook": saved, "url": "/hooks/" + hook.Name}
+	if generated != "" {
+		resp["secret"] = generated
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// toggleHook disables or enables a hook
+func (h *HooksHandler) toggleHook(w http.ResponseWriter, r *http.Request, name string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Disabled == nil {
+		apierror.Error(w, "disabled is required", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.manager.SetDisabled(name, *body.Disabled)
+	if err != nil {
+		writeHooksError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// writeHooksError maps manager errors to HTTP statuses
+func writeHooksError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, hooks.ErrHookNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, hooks.ErrInvalidHook):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
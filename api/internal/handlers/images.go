@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/system"
+)
+
+// ImagesHandler manages Docker images
+type ImagesHandler struct {
+	docker *system.DockerClient
+}
+
+// NewImagesHandler creates a new images handler
+func NewImagesHandler(docker *system.DockerClient) *ImagesHandler {
+	return &ImagesHandler{docker: docker}
+}
+
+// HandleImages handles /api/v1/images requests
+func (h *ImagesHandler) HandleImages(w http.ResponseWriter, r *http.Request) {
+	// Image references contain slashes, so the rest of the path is the name
+	ref := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/images"), "/")
+
+	switch {
+	case ref == "" && r.Method == "GET":
+		images, err := h.docker.ListImages(r.Context())
+		if err != nil {
+			apierror.Error(w, "Failed to list images: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"images": images, "count": len(images)})
+	case ref == "updates" && r.Method == "GET":
+		updates, err := h.docker.CheckUpdates(r.Context())
+		if err != nil {
+			apierror.Error(w, "Failed to check updates: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		available := 0
+		for _, u := range updates {
+			if u.UpdateAvailable {
+				available++
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"containers": updates, "updates_available": available})
+	case ref == "pull" && r.Method == "POST":
+		h.pullImage(w, r)
+	case ref != "" && r.Method == "DELETE":
+		force := r.URL.Query().Get("force") == "true"
+		if err := h.docker.RemoveImage(r.Context(), ref, force); err != nil {
+			if errors.Is(err, system.ErrImageNotFound) {
+				apierror.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to remove image: "+err.Error(), http.StatusConflict)
+			return
+		}
+		logger.Info("Removed image " + ref)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": ref})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// pullImage pulls an image from its registry
+func (h *ImagesHandler) pullImage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	var req struct {
+		Image string `json:"image"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Image == "" {
+		apierror.Error(w, "image is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.docker.PullImage(r.Context(), req.Image); err != nil {
+		apierror.Error(w, "Failed to pull image: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	logger.Info("Pulled image " + req.Image)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "image": req.Image})
+}
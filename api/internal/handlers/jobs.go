@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/jobs"
+)
+
+// JobsHandler manages scheduled jobs
+type JobsHandler struct {
+	manager *jobs.Manager
+}
+
+// NewJobsHandler creates a new jobs handler
+func NewJobsHandler(manager *jobs.Manager) *JobsHandler {
+	return &JobsHandler{manager: manager}
+}
+
+// HandleJobs handles /api/v1/jobs requests
+func (h *JobsHandler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/jobs"), "/")
+	name, sub, _ := strings.Cut(path, "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addJob(w, r)
+	case name != "" && sub == "" && r.Method == "GET":
+		st, err := h.manager.Get(name)
+		if err != nil {
+			writeJobsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case name != "" && sub == "" && r.Method == "PATCH":
+		h.pauseJob(w, r, name)
+	case name != "" && sub == "" && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			writeJobsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	case sub == "run" && r.Method == "POST":
+		run, err := h.manager.Trigger(name)
+		if err != nil {
+			writeJobsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(run)
+	case sub == "runs" && r.Method == "GET":
+		runs, err := h.manager.Runs(name)
+		if err != nil {
+			writeJobsError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": runs,
+			"count": len(runs),
+		})
+	case sub != "" && sub != "run" && sub != "runs":
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addJob creates or replaces a job
+func (h *JobsHandler) addJob(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var job jobs.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(job); err != nil {
+		writeJobsError(w, err)
+		return
+	}
+
+	saved, _ := h.manager.Get(job.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "job": saved})
+}
+
+// pauseJob pauses or resumes the schedule of a job
+func (h *JobsHandler) pauseJob(w http.ResponseWriter, r *http.Request, name string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Paused *bool `json:"paused"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Paused == nil {
+		apierror.Error(w, "paused is required", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.manager.SetPaused(name, *body.Paused)
+	if err != nil {
+		writeJobsError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// writeJobsError maps manager errors to HTTP statuses
+func writeJobsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, jobs.ErrInvalidJob):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
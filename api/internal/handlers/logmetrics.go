@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/logmetrics"
+)
+
+// LogMetricsHandler manages log-derived metrics
+type LogMetricsHandler struct {
+	manager *logmetrics.Manager
+}
+
+// NewLogMetricsHandler creates a new log metrics handler
+func NewLogMetricsHandler(manager *logmetrics.Manager) *LogMetricsHandler {
+	return &LogMetricsHandler{manager: manager}
+}
+
+// HandleLogMetrics handles /api/v1/observe/log-metrics requests
+func (h *LogMetricsHandler) HandleLogMetrics(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/observe/log-metrics"), "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addLogMetric(w, r)
+	case name != "" && r.Method == "GET":
+		lm, ok := h.manager.Get(name)
+		if !ok {
+			apierror.Error(w, "Log metric not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lm)
+	case name != "" && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addLogMetric creates or replaces a log metric
+func (h *LogMetricsHandler) addLogMetric(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var lm logmetrics.LogMetric
+	if err := json.NewDecoder(r.Body).Decode(&lm); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(lm); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, _ := h.manager.Get(lm.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "metric": saved.LogMetric})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/logpipelines"
+)
+
+// LogPipelinesHandler manages log processing pipelines
+type LogPipelinesHandler struct {
+	manager *logpipelines.Manager
+}
+
+// NewLogPipelinesHandler creates a new log pipelines handler
+func NewLogPipelinesHandler(manager *logpipelines.Manager) *LogPipelinesHandler {
+	return &LogPipelinesHandler{manager: manager}
+}
+
+// HandlePipelines handles /api/v1/logs/pipelines requests
+func (h *LogPipelinesHandler) HandlePipelines(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/logs/pipelines"), "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addPipeline(w, r)
+	case name != "" && r.Method == "GET":
+		p, ok := h.manager.Get(name)
+		if !ok {
+			apierror.Error(w, "Pipeline not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	case name != "" && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addPipeline creates or replaces a pipeline
+func (h *LogPipelinesHandler) addPipeline(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var p logpipelines.Pipeline
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(p); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, _ := h.manager.Get(p.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "pipeline": saved})
+}
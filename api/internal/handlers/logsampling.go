@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/logsampling"
+)
+
+// LogSamplingHandler manages log ingestion sampling rules
+type LogSamplingHandler struct {
+	manager *logsampling.Manager
+}
+
+// NewLogSamplingHandler creates a new log sampling handler
+func NewLogSamplingHandler(manager *logsampling.Manager) *LogSamplingHandler {
+	return &LogSamplingHandler{manager: manager}
+}
+
+// HandleSampling handles /api/v1/logs/sampling requests
+func (h *LogSamplingHandler) HandleSampling(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/logs/sampling"), "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addRule(w, r)
+	case name != "" && r.Method == "GET":
+		p, ok := h.manager.Get(name)
+		if !ok {
+			apierror.Error(w, "Sampling rule not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	case name != "" && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addRule creates or replaces a sampling rule
+func (h *LogSamplingHandler) addRule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var rule logsampling.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(rule); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, _ := h.manager.Get(rule.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "rule": saved})
+}
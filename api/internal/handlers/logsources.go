@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/forge/api/internal/apierror"
 	"github.com/forge/api/internal/logsources"
 )
 
@@ -14,12 +15,13 @@ const maxRequestBodySize = 1 << 20
 
 // LogSourcesHandler handles log source management requests
 type LogSourcesHandler struct {
-	manager *logsources.Manager
+	manager     *logsources.Manager
+	promtailURL string
 }
 
 // NewLogSourcesHandler creates a new log sources handler
-func NewLogSourcesHandler(manager *logsources.Manager) *LogSourcesHandler {
-	return &LogSourcesHandler{manager: manager}
+func NewLogSourcesHandler(manager *logsources.Manager, promtailURL string) *LogSourcesHandler {
+	return &LogSourcesHandler{manager: manager, promtailURL: promtailURL}
 }
 
 // HandleLogSources handles /api/v1/logs/sources requests
@@ -32,6 +34,8 @@ func (h *LogSourcesHandler) HandleLogSources(w http.ResponseWriter, r *http.Requ
 	case "GET":
 		if path == "" || path == "reload" {
 			h.listSources(w, r)
+		} else if name, ok := strings.CutSuffix(path, "/status"); ok {
+			h.sourceStatus(w, r, name)
 		} else {
 			h.getSource(w, r, path)
 		}
@@ -45,10 +49,10 @@ func (h *LogSourcesHandler) HandleLogSources(w http.ResponseWriter, r *http.Requ
 		if path != "" {
 			h.deleteSource(w, r, path)
 		} else {
-			http.Error(w, "Source name required", http.StatusBadRequest)
+			apierror.Error(w, "Source name required", http.StatusBadRequest)
 		}
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
@@ -67,7 +71,7 @@ func (h *LogSourcesHandler) listSources(w http.ResponseWriter, _ *http.Request)
 func (h *LogSourcesHandler) getSource(w http.ResponseWriter, _ *http.Request, name string) {
 	source, found := h.manager.Get(name)
 	if !found {
-		http.Error(w, "Source not found", http.StatusNotFound)
+		apierror.Error(w, "Source not found", http.StatusNotFound)
 		return
 	}
 
@@ -75,6 +79,28 @@ func (h *LogSourcesHandler) getSource(w http.ResponseWriter, _ *http.Request, na
 	json.NewEncoder(w).Encode(source)
 }
 
+// sourceStatus reports what Promtail is tailing for a source
+func (h *LogSourcesHandler) sourceStatus(w http.ResponseWriter, r *http.Request, name string) {
+	source, found := h.manager.Get(name)
+	if !found {
+		apierror.Error(w, "Source not found", http.StatusNotFound)
+		return
+	}
+	if source.Type != "" && source.Type != logsources.TypeFile {
+		apierror.Error(w, "Status is only reported for file sources", http.StatusBadRequest)
+		return
+	}
+
+	status, err := logsources.PromtailStatus(r.Context(), h.promtailURL, *source)
+	if err != nil {
+		apierror.Error(w, "Failed to get status: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
 // addSource adds a new log source
 func (h *LogSourcesHandler) addSource(w http.ResponseWriter, r *http.Request) {
 	// Limit request body size to prevent oversized payloads
@@ -84,26 +110,21 @@ func (h *LogSourcesHandler) addSource(w http.ResponseWriter, r *http.Request) {
 	if err := json.NewDecoder(r.Body).Decode(&source); err != nil {
 		var maxBytesErr *http.MaxBytesError
 		if errors.As(err, &maxBytesErr) {
-			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			apierror.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
 			return
 		}
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Validate
-	if source.Name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
-		return
-	}
-	if source.Path == "" {
-		http.Error(w, "path is required", http.StatusBadRequest)
+	if err := source.Validate(); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Add source
 	if err := h.manager.Add(source); err != nil {
-		http.Error(w, "Failed to add source: "+err.Error(), http.StatusInternalServerError)
+		apierror.Error(w, "Failed to add source: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -132,9 +153,9 @@ func (h *LogSourcesHandler) addSource(w http.ResponseWriter, r *http.Request) {
 func (h *LogSourcesHandler) deleteSource(w http.ResponseWriter, _ *http.Request, name string) {
 	if err := h.manager.Delete(name); err != nil {
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			apierror.Error(w, err.Error(), http.StatusNotFound)
 		} else {
-			http.Error(w, "Failed to delete source: "+err.Error(), http.StatusInternalServerError)
+			apierror.Error(w, "Failed to delete source: "+err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -153,7 +174,7 @@ func (h *LogSourcesHandler) deleteSource(w http.ResponseWriter, _ *http.Request,
 // reloadPromtail forces a Promtail config reload
 func (h *LogSourcesHandler) reloadPromtail(w http.ResponseWriter, _ *http.Request) {
 	if err := h.manager.ReloadPromtail(); err != nil {
-		http.Error(w, "Reload failed: "+err.Error(), http.StatusInternalServerError)
+		apierror.Error(w, "Reload failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/credentials"
+	"github.com/forge/api/internal/deps"
+	"github.com/forge/api/internal/messaging"
+)
+
+// subscribeKeepalive is how often an SSE comment is sent on idle
+// subscriptions
+const subscribeKeepalive = 15 * time.Second
+
+// MessagingHandler serves the message bus
+type MessagingHandler struct {
+	client *messaging.Client
+	deps   *deps.Registry
+}
+
+// NewMessagingHandler creates a messaging handler. Requests fail with
+// Unavailable while registry reports the bus down.
+func NewMessagingHandler(client *messaging.Client, registry *deps.Registry) *MessagingHandler {
+	return &MessagingHandler{client: client, deps: registry}
+}
+
+// available reports whether the bus is currently reachable
+func (h *MessagingHandler) available() bool {
+	return h.client != nil && h.deps.Get("messaging").Available()
+}
+
+func (h *MessagingHandler) unavailable() error {
+	return connect.NewError(connect.CodeUnavailable, h.deps.Unavailable("messaging"))
+}
+
+func (h *MessagingHandler) Publish(
+	ctx context.Context,
+	req *connect.Request[forgev1.PublishRequest],
+) (*connect.Response[forgev1.PublishResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	if err := h.client.Publish(ctx, req.Msg.Subject, req.Msg.ReplyTo, req.Msg.Data); err != nil {
+		return nil, messagingError(err)
+	}
+	return connect.NewResponse(&forgev1.PublishResponse{Ok: true}), nil
+}
+
+// Subscribe streams messages on a subject until the caller goes away or
+// the connection to the bus is lost
+func (h *MessagingHandler) Subscribe(
+	ctx context.Context,
+	req *connect.Request[forgev1.SubscribeRequest],
+	stream *connect.ServerStream[forgev1.MessagingMessage],
+) error {
+	if !h.available() {
+		return h.unavailable()
+	}
+
+	sub, err := h.client.Subscribe(req.Msg.Subject, req.Msg.Queue)
+	if err != nil {
+		return messagingError(err)
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-sub.Messages():
+			if !ok {
+				return messagingError(sub.Err())
+			}
+			if err := stream.Send(messagingMessage(m)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// GetInfo returns the bus's address and the account for the caller's
+// role: publish and subscribe with the write role, subscribe only with
+// the read role
+func (h *MessagingHandler) GetInfo(
+	ctx context.Context,
+	req *connect.Request[forgev1.MessagingInfoRequest],
+) (*connect.Response[forgev1.MessagingInfoResponse], error) {
+	if h.client == nil {
+		return nil, h.unavailable()
+	}
+
+	credReq, err := credentialRequest(ctx, req.Msg.Access, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	user, password := h.client.Account(credReq.Access == credentials.AccessWrite)
+	return connect.NewResponse(&forgev1.MessagingInfoResponse{
+		Url:           h.client.PublicURL(),
+		User:          user,
+		Password:      password,
+		Access:        credReq.Access,
+		MaxPayload:    h.client.MaxPayload(),
+		ServerVersion: h.client.ServerVersion(),
+	}), nil
+}
+
+func messagingMessage(m messaging.Msg) *forgev1.MessagingMessage {
+	return &forgev1.MessagingMessage{
+		Subject:    m.Subject,
+		Data:       m.Data,
+		ReplyTo:    m.Reply,
+		ReceivedAt: m.Received.UnixMilli(),
+	}
+}
+
+// messagingError maps bus errors to Connect codes
+func messagingError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, messaging.ErrInvalidSubject):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, messaging.ErrTooLarge), errors.Is(err, messaging.ErrTooManySubscriptions):
+		return connect.NewError(connect.CodeResourceExhausted, err)
+	case errors.Is(err, messaging.ErrNotConnected), errors.Is(err, messaging.ErrConnectionLost):
+		return connect.NewError(connect.CodeUnavailable, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(connect.CodeDeadlineExceeded, err)
+	}
+	return connect.NewError(connect.CodeInternal, err)
+}
+
+// HandleMessaging handles /api/v1/messaging requests: messages are
+// published as the raw request body and received as Server-Sent Events
+func (h *MessagingHandler) HandleMessaging(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/messaging"), "/")
+	op, subject, _ := strings.Cut(path, "/")
+
+	switch {
+	case path == "info" && r.Method == "GET":
+		access, _, err := credentialQuery(r)
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		resp, err := h.GetInfo(r.Context(), connect.NewRequest(&forgev1.MessagingInfoRequest{Access: access}))
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(resp.Msg)
+	case op == "publish" && subject != "" && r.Method == "POST":
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			apierror.Error(w, "Failed to read body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err = h.Publish(r.Context(), connect.NewRequest(&forgev1.PublishRequest{
+			Subject: subject,
+			Data:    data,
+			ReplyTo: r.URL.Query().Get("reply_to"),
+		}))
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "subject": subject})
+	case op == "subscribe" && subject != "" && r.Method == "GET":
+		h.subscribeSSE(w, r, subject, r.URL.Query().Get("queue"))
+	case path == "info" || ((op == "publish" || op == "subscribe") && subject != ""):
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// subscribeSSE streams messages on a subject as Server-Sent Events. Each
+// message is sent as a "message" event; an "error" event ends the stream
+// when the connection to the bus is lost.
+func (h *MessagingHandler) subscribeSSE(w http.ResponseWriter, r *http.Request, subject, queue string) {
+	if !h.available() {
+		writeRPCError(w, h.unavailable())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, err := h.client.Subscribe(subject, queue)
+	if err != nil {
+		writeRPCError(w, messagingError(err))
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(subscribeKeepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		case m, ok := <-sub.Messages():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					writeSSE(w, "error", map[string]string{"error": err.Error()})
+					flusher.Flush()
+				}
+				return
+			}
+			writeSSE(w, "message", messagingMessage(m))
+			flusher.Flush()
+		}
+	}
+}
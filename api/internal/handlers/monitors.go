@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/monitors"
+)
+
+// MonitorsHandler manages uptime monitors
+type MonitorsHandler struct {
+	manager *monitors.Manager
+}
+
+// NewMonitorsHandler creates a new monitors handler
+func NewMonitorsHandler(manager *monitors.Manager) *MonitorsHandler {
+	return &MonitorsHandler{manager: manager}
+}
+
+// HandleMonitors handles /api/v1/monitors requests
+func (h *MonitorsHandler) HandleMonitors(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/monitors"), "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addMonitor(w, r)
+	case name != "" && r.Method == "GET":
+		st, ok := h.manager.Get(name)
+		if !ok {
+			apierror.Error(w, "Monitor not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case name != "" && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addMonitor creates or replaces a monitor
+func (h *MonitorsHandler) addMonitor(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var mon monitors.Monitor
+	if err := json.NewDecoder(r.Body).Decode(&mon); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(mon); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, _ := h.manager.Get(mon.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "monitor": saved.Monitor})
+}
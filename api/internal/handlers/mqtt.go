@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/deps"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/mqtt"
+)
+
+// mqttPublishTimeout bounds waiting for the broker to take a QoS 1 message
+const mqttPublishTimeout = 10 * time.Second
+
+// MQTTHandler publishes to the MQTT broker and manages bridges
+type MQTTHandler struct {
+	client  *mqtt.Client
+	manager *mqtt.Manager
+	deps    *deps.Registry
+}
+
+// NewMQTTHandler creates an MQTT handler. Publishing fails with 503 while
+// registry reports the broker down.
+func NewMQTTHandler(client *mqtt.Client, manager *mqtt.Manager, registry *deps.Registry) *MQTTHandler {
+	return &MQTTHandler{client: client, manager: manager, deps: registry}
+}
+
+// HandleMQTT handles /api/v1/mqtt requests: GET /api/v1/mqtt/status shows
+// the connection, POST /api/v1/mqtt/publish/{topic} publishes the raw
+// request body and /api/v1/mqtt/bridges manages bridges
+func (h *MQTTHandler) HandleMQTT(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/mqtt"), "/")
+	op, rest, _ := strings.Cut(path, "/")
+
+	switch {
+	case path == "status" && r.Method == "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.client.Info())
+	case op == "publish" && rest != "" && r.Method == "POST":
+		h.publish(w, r, rest)
+	case path == "status" || (op == "publish" && rest != ""):
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case op == "bridges":
+		var parts []string
+		if rest != "" {
+			parts = strings.Split(rest, "/")
+		}
+		h.handleBridges(w, r, parts)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// publish sends the request body to a topic, which may hold slashes
+func (h *MQTTHandler) publish(w http.ResponseWriter, r *http.Request, topic string) {
+	if !h.deps.Get("mqtt").Available() {
+		writeRPCError(w, h.deps.Unavailable("mqtt"))
+		return
+	}
+
+	q := r.URL.Query()
+	var qos int
+	if s := q.Get("qos"); s != "" {
+		var err error
+		if qos, err = strconv.Atoi(s); err != nil || qos < 0 || qos > 1 {
+			apierror.Error(w, mqtt.ErrInvalidQoS.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	retain := q.Get("retain") == "true"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		apierror.Error(w, "Failed to read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), mqttPublishTimeout)
+	defer cancel()
+	if err := h.client.Publish(ctx, topic, payload, byte(qos), retain); err != nil {
+		metrics.MQTTPublishedTotal.WithLabelValues("failed").Inc()
+		writeMQTTError(w, err)
+		return
+	}
+	metrics.MQTTPublishedTotal.WithLabelValues("published").Inc()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "topic": topic, "qos": qos, "retain": retain})
+}
+
+// handleBridges handles /api/v1/mqtt/bridges requests
+func (h *MQTTHandler) handleBridges(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case len(parts) == 0 && r.Method == "POST":
+		h.saveBridge(w, r)
+	case len(parts) == 0:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case len(parts) == 1 && r.Method == "GET":
+		st, err := h.manager.Get(parts[0])
+		if err != nil {
+			writeMQTTError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case len(parts) == 1 && r.Method == "PATCH":
+		h.toggleBridge(w, r, parts[0])
+	case len(parts) == 1 && r.Method == "DELETE":
+		if err := h.manager.Remove(parts[0]); err != nil {
+			writeMQTTError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": parts[0]})
+	case len(parts) == 1:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// saveBridge creates or replaces a bridge
+func (h *MQTTHandler) saveBridge(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var bridge mqtt.Bridge
+	if err := json.NewDecoder(r.Body).Decode(&bridge); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.manager.Save(bridge)
+	if err != nil {
+		writeMQTTError(w, err)
+		return
+	}
+
+	saved, _ := h.manager.Get(bridge.Name)
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "bridge": saved})
+}
+
+// toggleBridge disables or enables a bridge
+func (h *MQTTHandler) toggleBridge(w http.ResponseWriter, r *http.Request, name string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Disabled == nil {
+		apierror.Error(w, "disabled is required", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.manager.SetDisabled(name, *body.Disabled)
+	if err != nil {
+		writeMQTTError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// writeMQTTError maps client and manager errors to HTTP statuses
+func writeMQTTError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, mqtt.ErrBridgeNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, mqtt.ErrInvalidBridge), errors.Is(err, mqtt.ErrInvalidTopic), errors.Is(err, mqtt.ErrInvalidQoS):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, mqtt.ErrTooLarge):
+		apierror.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, mqtt.ErrNotConnected), errors.Is(err, mqtt.ErrConnectionLost):
+		apierror.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, context.DeadlineExceeded):
+		apierror.Error(w, "Timed out waiting for the MQTT broker", http.StatusGatewayTimeout)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/notify"
+)
+
+// NotifyHandler sends notifications and manages notification channels
+type NotifyHandler struct {
+	manager *notify.Manager
+}
+
+// NewNotifyHandler creates a new notify handler
+func NewNotifyHandler(manager *notify.Manager) *NotifyHandler {
+	return &NotifyHandler{manager: manager}
+}
+
+// HandleNotify handles /api/v1/notify requests: POST /api/v1/notify sends
+// a message to a channel, /api/v1/notify/channels manages channels
+func (h *NotifyHandler) HandleNotify(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/notify"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "" && r.Method == "POST":
+		h.send(w, r)
+	case path == "":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case parts[0] == "channels":
+		h.handleChannels(w, r, parts[1:])
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleChannels handles /api/v1/notify/channels requests
+func (h *NotifyHandler) handleChannels(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case len(parts) == 0 && r.Method == "POST":
+		h.saveChannel(w, r)
+	case len(parts) == 0:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case len(parts) == 1 && r.Method == "GET":
+		st, err := h.manager.Get(parts[0])
+		if err != nil {
+			writeNotifyError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case len(parts) == 1 && r.Method == "PATCH":
+		h.toggleChannel(w, r, parts[0])
+	case len(parts) == 1 && r.Method == "DELETE":
+		if err := h.manager.Remove(parts[0]); err != nil {
+			writeNotifyError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": parts[0]})
+	case len(parts) == 2 && parts[1] == "test" && r.Method == "POST":
+		if err := h.manager.Test(r.Context(), parts[0]); err != nil {
+			writeNotifyError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "channel": parts[0]})
+	case len(parts) == 2 && r.Method != "POST":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case len(parts) > 1:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// send sends a message to a channel and waits for its service to accept it
+func (h *NotifyHandler) send(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		notify.Message
+		Channel string `json:"channel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Channel == "" {
+		apierror.Error(w, "channel is required", http.StatusBadRequest)
+		return
+	}
+
+	// Events are set by Forge for the messages it routes from the bus
+	body.Message.Event = ""
+	if err := h.manager.Send(r.Context(), body.Channel, body.Message); err != nil {
+		writeNotifyError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "channel": body.Channel})
+}
+
+// saveChannel creates or replaces a channel
+func (h *NotifyHandler) saveChannel(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		notify.Channel
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ch := body.Channel
+	ch.Token = body.Token
+
+	created, err := h.manager.Save(ch)
+	if err != nil {
+		writeNotifyError(w, err)
+		return
+	}
+
+	saved, _ := h.manager.Get(ch.Name)
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "channel": saved})
+}
+
+// toggleChannel disables or enables a channel
+func (h *NotifyHandler) toggleChannel(w http.ResponseWriter, r *http.Request, name string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Disabled == nil {
+		apierror.Error(w, "disabled is required", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.manager.SetDisabled(name, *body.Disabled)
+	if err != nil {
+		writeNotifyError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// writeNotifyError maps manager errors to HTTP statuses
+func writeNotifyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, notify.ErrChannelNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, notify.ErrInvalidChannel), errors.Is(err, notify.ErrInvalidMessage):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, notify.ErrChannelDisabled):
+		apierror.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, notify.ErrSendFailed):
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,22 +1,37 @@
 package handlers
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"connectrpc.com/connect"
 	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/metrics"
 	"github.com/forge/api/internal/observe"
 )
 
+var errQueryRequired = errors.New("query is required")
+
 type ObserveHandler struct {
-	lokiClient *observe.LokiClient
+	lokiClient      *observe.LokiClient
+	tempoClient     *observe.TempoClient
+	metricsRegistry *observe.MetricsRegistry
 }
 
-func NewObserveHandler(loki *observe.LokiClient) *ObserveHandler {
+func NewObserveHandler(loki *observe.LokiClient, tempo *observe.TempoClient, registry *observe.MetricsRegistry) *ObserveHandler {
 	return &ObserveHandler{
-		lokiClient: loki,
+		lokiClient:      loki,
+		tempoClient:     tempo,
+		metricsRegistry: registry,
 	}
 }
 
@@ -24,7 +39,24 @@ func (h *ObserveHandler) Log(
 	ctx context.Context,
 	req *connect.Request[forgev1.LogRequest],
 ) (*connect.Response[forgev1.LogResponse], error) {
-	err := h.lokiClient.Push(ctx, req.Msg.Level, req.Msg.Message, req.Msg.Labels)
+	entry := observe.Entry{
+		Level:    req.Msg.Level,
+		Message:  req.Msg.Message,
+		Labels:   req.Msg.Labels,
+		Fields:   req.Msg.Fields,
+		Metadata: req.Msg.Metadata,
+	}
+	if req.Msg.TimestampMs > 0 {
+		entry.Timestamp = time.UnixMilli(req.Msg.TimestampMs)
+	}
+
+	err := h.lokiClient.PushEntry(ctx, entry)
+	if errors.Is(err, observe.ErrLogQueueFull) {
+		return nil, connect.NewError(connect.CodeResourceExhausted, err)
+	}
+	if errors.Is(err, observe.ErrInvalidLogEntry) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, err)
 	}
@@ -38,8 +70,30 @@ func (h *ObserveHandler) Metric(
 	ctx context.Context,
 	req *connect.Request[forgev1.MetricRequest],
 ) (*connect.Response[forgev1.MetricResponse], error) {
-	// For now, metrics are collected via Prometheus scraping
-	// This endpoint can be used for push-based metrics in the future
+	err := h.metricsRegistry.PushWithBuckets(req.Msg.Name, req.Msg.Type, req.Msg.Value, req.Msg.Labels, req.Msg.Buckets)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connect.NewResponse(&forgev1.MetricResponse{
+		Ok: true,
+	}), nil
+}
+
+// Timing records a duration, converted to seconds, into a histogram
+func (h *ObserveHandler) Timing(
+	ctx context.Context,
+	req *connect.Request[forgev1.TimingRequest],
+) (*connect.Response[forgev1.MetricResponse], error) {
+	if req.Msg.DurationMs < 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("duration_ms cannot be negative"))
+	}
+
+	err := h.metricsRegistry.Observe(req.Msg.Name, req.Msg.DurationMs/1000, req.Msg.Labels, req.Msg.Buckets)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	return connect.NewResponse(&forgev1.MetricResponse{
 		Ok: true,
 	}), nil
@@ -49,31 +103,94 @@ func (h *ObserveHandler) Trace(
 	ctx context.Context,
 	req *connect.Request[forgev1.TraceRequest],
 ) (*connect.Response[forgev1.TraceResponse], error) {
-	// TODO: Implement trace pushing to Tempo
+	span := observe.Span{
+		Name:         req.Msg.Name,
+		TraceID:      req.Msg.TraceId,
+		SpanID:       req.Msg.SpanId,
+		ParentSpanID: req.Msg.ParentSpanId,
+		Duration:     time.Duration(req.Msg.DurationMs) * time.Millisecond,
+		Attributes:   req.Msg.Attributes,
+	}
+	if req.Msg.StartTimeMs > 0 {
+		span.Start = time.UnixMilli(req.Msg.StartTimeMs)
+	}
+
+	span, err := h.tempoClient.Push(ctx, span)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnavailable, err)
+	}
+
 	return connect.NewResponse(&forgev1.TraceResponse{
 		Ok:      true,
-		TraceId: req.Msg.TraceId,
-		SpanId:  req.Msg.SpanId,
+		TraceId: span.TraceID,
+		SpanId:  span.SpanID,
 	}), nil
 }
 
+func (h *ObserveHandler) Query(
+	ctx context.Context,
+	req *connect.Request[forgev1.LogQueryRequest],
+) (*connect.Response[forgev1.LogQueryResponse], error) {
+	if req.Msg.Query == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errQueryRequired)
+	}
+
+	opts := observe.QueryOptions{
+		Query:     req.Msg.Query,
+		Limit:     int(req.Msg.Limit),
+		Direction: req.Msg.Direction,
+		Instant:   req.Msg.Instant,
+	}
+	if req.Msg.StartMs > 0 {
+		opts.Start = time.UnixMilli(req.Msg.StartMs)
+	}
+	if req.Msg.EndMs > 0 {
+		opts.End = time.UnixMilli(req.Msg.EndMs)
+	}
+
+	streams, err := h.lokiClient.Query(ctx, opts)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnavailable, err)
+	}
+
+	resp := &forgev1.LogQueryResponse{
+		Streams: make([]*forgev1.LogStream, 0, len(streams)),
+	}
+	for _, s := range streams {
+		stream := &forgev1.LogStream{
+			Labels:  s.Labels,
+			Entries: make([]*forgev1.LogEntry, 0, len(s.Entries)),
+		}
+		for _, e := range s.Entries {
+			stream.Entries = append(stream.Entries, &forgev1.LogEntry{
+				TimestampMs: e.Timestamp.UnixMilli(),
+				Line:        e.Line,
+			})
+		}
+		resp.EntryCount += int32(len(stream.Entries))
+		resp.Streams = append(resp.Streams, stream)
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
 // REST handlers
 func LogsREST(h *ObserveHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		
 		var req forgev1.LogRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		
 		resp, err := h.Log(r.Context(), connect.NewRequest(&req))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeRPCError(w, err)
 			return
 		}
 		
@@ -85,19 +202,23 @@ func LogsREST(h *ObserveHandler) http.HandlerFunc {
 func MetricsREST(h *ObserveHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		
 		var req forgev1.MetricRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		
 		resp, err := h.Metric(r.Context(), connect.NewRequest(&req))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			if connect.CodeOf(err) == connect.CodeInvalidArgument {
+				status = http.StatusBadRequest
+			}
+			apierror.Error(w, err.Error(), status)
 			return
 		}
 		
@@ -106,22 +227,47 @@ func MetricsREST(h *ObserveHandler) http.HandlerFunc {
 	}
 }
 
+// TimingREST records a duration into a histogram
+func TimingREST(h *ObserveHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req forgev1.TimingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.Timing(r.Context(), connect.NewRequest(&req))
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp.Msg)
+	}
+}
+
 func TracesREST(h *ObserveHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		
 		var req forgev1.TraceRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		
 		resp, err := h.Trace(r.Context(), connect.NewRequest(&req))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeRPCError(w, err)
 			return
 		}
 		
@@ -129,4 +275,224 @@ func TracesREST(h *ObserveHandler) http.HandlerFunc {
 		json.NewEncoder(w).Encode(resp.Msg)
 	}
 }
+// maxIngestBodySize bounds batched log ingestion payloads (8MB, after decompression)
+const maxIngestBodySize = 8 << 20
 
+// LogsIngestFluentREST accepts records from the Fluent Bit/Fluentd HTTP
+// output (json or json_lines, optionally gzip) and relays them to Loki. The
+// tag is taken from the X-Fluent-Tag header, the tag query parameter, or a
+// "tag" key in each record.
+func LogsIngestFluentREST(h *ObserveHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.Contains(r.Header.Get("Content-Type"), "msgpack") {
+			apierror.Error(w, "msgpack is not supported, use format json or json_lines", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		tag := r.Header.Get("X-Fluent-Tag")
+		if tag == "" {
+			tag = r.URL.Query().Get("tag")
+		}
+		if tag != "" && !observe.ValidFluentTag(tag) {
+			apierror.Error(w, "invalid tag", http.StatusBadRequest)
+			return
+		}
+
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				apierror.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+			body = gz
+		}
+		data, err := io.ReadAll(io.LimitReader(body, maxIngestBodySize+1))
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxIngestBodySize {
+			apierror.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		records, err := observe.ParseFluentRecords(data)
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		accepted, rejected, dropped := 0, 0, 0
+		for _, rec := range records {
+			entry, err := observe.FluentEntry(tag, rec)
+			if err == nil {
+				err = h.lokiClient.PushEntry(r.Context(), entry)
+			}
+			switch {
+			case err == nil:
+				accepted++
+				metrics.FluentRecordsTotal.WithLabelValues("forwarded").Inc()
+			case errors.Is(err, observe.ErrLogQueueFull):
+				dropped++
+				metrics.FluentRecordsTotal.WithLabelValues("dropped").Inc()
+			default:
+				rejected++
+				metrics.FluentRecordsTotal.WithLabelValues("invalid").Inc()
+			}
+		}
+
+		// Let the shipper retry the whole chunk when nothing could be queued
+		if dropped > 0 && accepted == 0 {
+			apierror.Error(w, observe.ErrLogQueueFull.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":       true,
+			"accepted": accepted,
+			"rejected": rejected,
+			"dropped":  dropped,
+		})
+	}
+}
+
+// LogsQueryREST runs a LogQL query. Accepts GET with query parameters
+// (query, start, end, limit, direction, instant) or POST with a JSON body.
+func LogsQueryREST(h *ObserveHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req forgev1.LogQueryRequest
+
+		switch r.Method {
+		case "GET":
+			q := r.URL.Query()
+			req.Query = q.Get("query")
+			req.Direction = q.Get("direction")
+			req.Instant = q.Get("instant") == "true"
+			req.StartMs, _ = strconv.ParseInt(q.Get("start"), 10, 64)
+			req.EndMs, _ = strconv.ParseInt(q.Get("end"), 10, 64)
+			if limit, err := strconv.Atoi(q.Get("limit")); err == nil {
+				req.Limit = int32(limit)
+			}
+		case "POST":
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apierror.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if req.Query == "" {
+			apierror.Error(w, "query is required", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.Query(r.Context(), connect.NewRequest(&req))
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp.Msg)
+	}
+}
+
+// tailKeepalive is how often an SSE comment is sent on idle log tails
+const tailKeepalive = 15 * time.Second
+
+// LogsTailREST streams new log entries matching a LogQL selector as
+// Server-Sent Events. Each entry is sent as a "log" event.
+func LogsTailREST(h *ObserveHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query().Get("query")
+		if query == "" {
+			apierror.Error(w, "query is required", http.StatusBadRequest)
+			return
+		}
+		var start time.Time
+		if ms, err := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64); err == nil && ms > 0 {
+			start = time.UnixMilli(ms)
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			apierror.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		batches := make(chan []observe.QueryStream)
+		tailErr := make(chan error, 1)
+		go func() {
+			tailErr <- h.lokiClient.Tail(ctx, query, start, func(streams []observe.QueryStream) error {
+				select {
+				case batches <- streams:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(tailKeepalive)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-tailErr:
+				if err != nil && ctx.Err() == nil {
+					writeSSE(w, "error", map[string]string{"error": err.Error()})
+					flusher.Flush()
+				}
+				return
+			case <-ticker.C:
+				w.Write([]byte(": keepalive\n\n"))
+				flusher.Flush()
+			case streams := <-batches:
+				for _, s := range streams {
+					for _, e := range s.Entries {
+						writeSSE(w, "log", forgev1.LogStream{
+							Labels:  s.Labels,
+							Entries: []*forgev1.LogEntry{{TimestampMs: e.Timestamp.UnixMilli(), Line: e.Line}},
+						})
+					}
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writeSSE writes a single Server-Sent Event with a JSON payload
+func writeSSE(w http.ResponseWriter, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/operations"
+)
+
+// OperationsHandler exposes background operations
+type OperationsHandler struct {
+	manager *operations.Manager
+}
+
+// NewOperationsHandler creates a new operations handler
+func NewOperationsHandler(manager *operations.Manager) *OperationsHandler {
+	return &OperationsHandler{manager: manager}
+}
+
+// HandleOperations handles /api/v1/operations requests
+func (h *OperationsHandler) HandleOperations(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/operations")
+	path = strings.Trim(path, "/")
+
+	if path == "" {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ops := h.manager.List(r.URL.Query().Get("kind"), r.URL.Query().Get("state"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"operations": ops,
+			"count":      len(ops),
+		})
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+	switch {
+	case action == "" && r.Method == "GET":
+		op, ok := h.manager.Get(id)
+		if !ok {
+			apierror.Error(w, "Operation not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(op.Snapshot(true))
+	case (action == "cancel" && r.Method == "POST") || (action == "" && r.Method == "DELETE"):
+		if err := h.manager.Cancel(id); err != nil {
+			status := http.StatusConflict
+			if strings.Contains(err.Error(), "not found") {
+				status = http.StatusNotFound
+			}
+			apierror.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "id": id, "message": "Cancellation requested"})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeOperationAccepted responds 202 with the operation and where to poll it
+func writeOperationAccepted(w http.ResponseWriter, op *operations.Operation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/operations/"+op.ID())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(op.Snapshot(false))
+}
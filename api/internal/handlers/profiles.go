@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/observe"
+)
+
+// ProfilesIngestREST relays profile uploads to Pyroscope. It speaks the
+// Pyroscope /ingest API, so profiler agents can use
+// http://<forge>/api/v1/profiles as their server address.
+func ProfilesIngestREST(pyroscope *observe.PyroscopeClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		if !observe.ValidProfileName(query.Get("name")) {
+			metrics.ProfilesTotal.WithLabelValues("invalid").Inc()
+			apierror.Error(w, "name is required, e.g. myapp.cpu{env=prod}", http.StatusBadRequest)
+			return
+		}
+
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxIngestBodySize+1))
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxIngestBodySize {
+			metrics.ProfilesTotal.WithLabelValues("invalid").Inc()
+			apierror.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err := pyroscope.Ingest(r.Context(), query, r.Header.Get("Content-Type"), data); err != nil {
+			if errors.Is(err, observe.ErrPyroscopeUnavailable) {
+				metrics.ProfilesTotal.WithLabelValues("failed").Inc()
+				apierror.Error(w, err.Error()+" (enable the 'profiling' profile in COMPOSE_PROFILES)", http.StatusServiceUnavailable)
+				return
+			}
+			metrics.ProfilesTotal.WithLabelValues("invalid").Inc()
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		metrics.ProfilesTotal.WithLabelValues("forwarded").Inc()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	}
+}
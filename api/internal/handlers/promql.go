@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/observe"
+)
+
+// MetricSample is a simplified [timestamp, value] point
+type MetricSample struct {
+	TimestampMs int64    `json:"timestamp_ms"`
+	Value       *float64 `json:"value"` // null for NaN/Inf, which JSON cannot encode
+}
+
+// MetricSeries is a simplified Prometheus series
+type MetricSeries struct {
+	Labels  map[string]string `json:"labels"`
+	Samples []MetricSample    `json:"samples"`
+}
+
+// PromQLHandler proxies PromQL queries to Prometheus
+type PromQLHandler struct {
+	client *observe.PrometheusClient
+}
+
+// NewPromQLHandler creates a new PromQL proxy handler
+func NewPromQLHandler(client *observe.PrometheusClient) *PromQLHandler {
+	return &PromQLHandler{client: client}
+}
+
+// Query handles /api/v1/metrics/query (instant query).
+// Parameters: query, time (ms since epoch, default now).
+func (h *PromQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := q.Get("query")
+	if query == "" {
+		apierror.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+	var at time.Time
+	if ms, err := strconv.ParseInt(q.Get("time"), 10, 64); err == nil {
+		at = time.UnixMilli(ms)
+	}
+
+	series, err := h.client.Query(r.Context(), query, at)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	writeMetricSeries(w, series)
+}
+
+// QueryRange handles /api/v1/metrics/query_range.
+// Parameters: query, start/end (ms since epoch, default last hour), step (duration, default 60s).
+func (h *PromQLHandler) QueryRange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := q.Get("query")
+	if query == "" {
+		apierror.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	end := time.Now()
+	if ms, err := strconv.ParseInt(q.Get("end"), 10, 64); err == nil {
+		end = time.UnixMilli(ms)
+	}
+	start := end.Add(-time.Hour)
+	if ms, err := strconv.ParseInt(q.Get("start"), 10, 64); err == nil {
+		start = time.UnixMilli(ms)
+	}
+	step := time.Minute
+	if s := q.Get("step"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			apierror.Error(w, "invalid step: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+
+	series, err := h.client.QueryRange(r.Context(), query, start, end, step)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	writeMetricSeries(w, series)
+}
+
+// writeMetricSeries encodes series in the simplified response format
+func writeMetricSeries(w http.ResponseWriter, series []observe.Series) {
+	result := make([]MetricSeries, 0, len(series))
+	for _, s := range series {
+		ms := MetricSeries{Labels: s.Labels, Samples: make([]MetricSample, 0, len(s.Samples))}
+		for _, sample := range s.Samples {
+			point := MetricSample{TimestampMs: sample.Timestamp.UnixMilli()}
+			if !math.IsNaN(sample.Value) && !math.IsInf(sample.Value, 0) {
+				v := sample.Value
+				point.Value = &v
+			}
+			ms.Samples = append(ms.Samples, point)
+		}
+		result = append(result, ms)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"series": result,
+		"count":  len(result),
+	})
+}
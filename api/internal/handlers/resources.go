@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/resources"
+)
+
+// ResourcesREST searches labeled resources across subsystems.
+// Query parameters: label (repeatable selector, e.g. label=team=payments)
+// and kind (repeatable or comma-separated, e.g. kind=route,logsource).
+func ResourcesREST(index *resources.Index) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+
+		var selector resources.Selector
+		for _, expr := range q["label"] {
+			sel, err := resources.ParseSelector(expr)
+			if err != nil {
+				apierror.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			selector = append(selector, sel...)
+		}
+
+		var kinds []string
+		for _, k := range q["kind"] {
+			for _, kind := range strings.Split(k, ",") {
+				if kind = strings.TrimSpace(kind); kind != "" {
+					kinds = append(kinds, kind)
+				}
+			}
+		}
+
+		result := index.Search(kinds, selector)
+		if result == nil {
+			result = []resources.Resource{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"resources": result,
+			"count":     len(result),
+			"kinds":     index.Kinds(),
+		})
+	}
+}
@@ -1,27 +1,53 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/operations"
 	"github.com/forge/api/internal/routes"
+	"gopkg.in/yaml.v3"
 )
 
 // RoutesHandler handles route management API
 type RoutesHandler struct {
-	manager *routes.Manager
+	manager    *routes.Manager
+	operations *operations.Manager
 }
 
 // NewRoutesHandler creates a new routes handler
-func NewRoutesHandler(manager *routes.Manager) *RoutesHandler {
-	return &RoutesHandler{manager: manager}
+func NewRoutesHandler(manager *routes.Manager, ops *operations.Manager) *RoutesHandler {
+	return &RoutesHandler{manager: manager, operations: ops}
+}
+
+// checkSecretReferences refuses routes referencing ${secret.*} to callers
+// below admin: the proxy sends resolved values upstream, so whoever can
+// point such a route at their own server can read the secret
+func checkSecretReferences(r *http.Request, rs ...routes.Route) error {
+	id := auth.FromContext(r.Context())
+	if id == nil || auth.Allows(id.Role, auth.RoleAdmin) {
+		return nil
+	}
+	for _, route := range rs {
+		if routes.References(route, routes.SecretNamespace) {
+			return fmt.Errorf("%w: route %s references ${%s.*}, which needs the admin role", auth.ErrForbidden, route.Name, routes.SecretNamespace)
+		}
+	}
+	return nil
 }
 
 // ListRoutes returns all dynamic routes
 func (h *RoutesHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -37,18 +63,22 @@ func (h *RoutesHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
 // AddRoute creates or updates a route
 func (h *RoutesHandler) AddRoute(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var route routes.Route
 	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkSecretReferences(r, route); err != nil {
+		apierror.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
 	if err := h.manager.Add(route); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -64,7 +94,7 @@ func (h *RoutesHandler) AddRoute(w http.ResponseWriter, r *http.Request) {
 // DeleteRoute removes a route
 func (h *RoutesHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "DELETE" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -73,12 +103,12 @@ func (h *RoutesHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 	name := strings.TrimSuffix(path, "/")
 
 	if name == "" {
-		http.Error(w, "Route name is required", http.StatusBadRequest)
+		apierror.Error(w, "Route name is required", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.manager.Remove(name); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apierror.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
@@ -89,15 +119,215 @@ func (h *RoutesHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PreviewRoute validates a proposed route and returns the nginx config it
+// would generate, without saving it or reloading nginx
+func (h *RoutesHandler) PreviewRoute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var route routes.Route
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.manager.Preview(route))
+}
+
+// BulkApply adds or updates many routes as a background operation
+func (h *RoutesHandler) BulkApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Routes []routes.Route `json:"routes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body.Routes) == 0 {
+		apierror.Error(w, "routes is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkSecretReferences(r, body.Routes...); err != nil {
+		apierror.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	op := h.operations.Start("routes.bulk_apply", fmt.Sprintf("%d routes", len(body.Routes)), func(ctx context.Context, op *operations.Operation) error {
+		op.Logf("validating %d routes", len(body.Routes))
+		err := h.manager.AddAll(body.Routes, func(done, total int) {
+			// Validation is most of the work; the final 10% is save + reload
+			op.SetProgress(float64(done) / float64(total) * 90)
+		})
+		if err != nil {
+			return err
+		}
+		op.Logf("applied %d routes and reloaded nginx", len(body.Routes))
+		return nil
+	})
+
+	writeOperationAccepted(w, op)
+}
+
+// ExportRoutes returns all routes as a routes file, YAML by default or JSON
+// with ?format=json
+func (h *RoutesHandler) ExportRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := h.manager.Export()
+
+	switch r.URL.Query().Get("format") {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="routes.json"`)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(cfg)
+	case "", "yaml":
+		data, err := yaml.Marshal(&cfg)
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("Content-Disposition", `attachment; filename="routes.yaml"`)
+		w.Write(data)
+	default:
+		apierror.Error(w, "format must be yaml or json", http.StatusBadRequest)
+	}
+}
+
+// ImportRoutes applies a routes file (YAML, or JSON with a JSON
+// Content-Type) in one step. ?mode=replace removes routes not in the file;
+// the default mode=merge keeps them. Nothing is applied if any route is invalid.
+func (h *RoutesHandler) ImportRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "replace" {
+		apierror.Error(w, "mode must be merge or replace", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		apierror.Error(w, "Failed to read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Unknown fields are rejected so a typo never silently drops a setting
+	var cfg routes.RoutesConfig
+	if strings.Contains(r.Header.Get("Content-Type"), "json") {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(body))
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	}
+	if err != nil {
+		apierror.Error(w, "Invalid routes file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(cfg.Routes) == 0 {
+		apierror.Error(w, "routes is required", http.StatusBadRequest)
+		return
+	}
+	if err := checkSecretReferences(r, cfg.Routes...); err != nil {
+		apierror.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if mode == "replace" {
+		err = h.manager.ReplaceAll(cfg.Routes)
+	} else {
+		err = h.manager.AddAll(cfg.Routes, nil)
+	}
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":       true,
+		"mode":     mode,
+		"imported": len(cfg.Routes),
+		"message":  "Routes imported and nginx reloaded",
+	})
+}
+
+// HandleCanary shifts, promotes or removes the canary of a route:
+// PUT {weight} sets the canary share, POST .../promote makes the canary the
+// only target and DELETE sends all traffic back to the primary target
+func (h *RoutesHandler) HandleCanary(w http.ResponseWriter, r *http.Request, name, action string) {
+	var route routes.Route
+	var err error
+
+	switch {
+	case action == "" && r.Method == "PUT":
+		var body struct {
+			Weight *int `json:"weight"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body.Weight == nil {
+			apierror.Error(w, "weight is required", http.StatusBadRequest)
+			return
+		}
+		route, err = h.manager.SetCanaryWeight(name, *body.Weight)
+	case action == "" && r.Method == "DELETE":
+		route, err = h.manager.RemoveCanary(name)
+	case action == "promote" && r.Method == "POST":
+		route, err = h.manager.PromoteCanary(name)
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, routes.ErrRouteNotFound) {
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":    true,
+		"route": route,
+	})
+}
+
 // ReloadNginx forces nginx reload
 func (h *RoutesHandler) ReloadNginx(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	if err := h.manager.Reload(); err != nil {
-		http.Error(w, "Reload failed: "+err.Error(), http.StatusInternalServerError)
+		apierror.Error(w, "Reload failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -121,13 +351,34 @@ func (h *RoutesHandler) HandleRoutes(w http.ResponseWriter, r *http.Request) {
 		case "POST":
 			h.AddRoute(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 
 	case path == "/reload":
 		// /api/v1/routes/reload
 		h.ReloadNginx(w, r)
 
+	case path == "/preview":
+		// /api/v1/routes/preview
+		h.PreviewRoute(w, r)
+
+	case path == "/export":
+		// /api/v1/routes/export
+		h.ExportRoutes(w, r)
+
+	case path == "/import":
+		// /api/v1/routes/import
+		h.ImportRoutes(w, r)
+
+	case path == "/bulk":
+		// /api/v1/routes/bulk
+		h.BulkApply(w, r)
+
+	case strings.Contains(strings.Trim(path, "/"), "/canary"):
+		// /api/v1/routes/{name}/canary[/promote]
+		name, rest, _ := strings.Cut(strings.Trim(path, "/"), "/canary")
+		h.HandleCanary(w, r, name, strings.Trim(rest, "/"))
+
 	default:
 		// /api/v1/routes/{name}
 		switch r.Method {
@@ -139,13 +390,13 @@ func (h *RoutesHandler) HandleRoutes(w http.ResponseWriter, r *http.Request) {
 			name = strings.TrimSuffix(name, "/")
 			route, ok := h.manager.Get(name)
 			if !ok {
-				http.Error(w, "Route not found", http.StatusNotFound)
+				apierror.Error(w, "Route not found", http.StatusNotFound)
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(route)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/services"
+)
+
+// ServicesHandler handles the service registry
+type ServicesHandler struct {
+	registry *services.Registry
+}
+
+// NewServicesHandler creates a service registry handler
+func NewServicesHandler(registry *services.Registry) *ServicesHandler {
+	return &ServicesHandler{registry: registry}
+}
+
+// HandleServices handles /api/v1/services requests: apps POST to register,
+// POST /api/v1/services/{name}/{id}/heartbeat to stay registered and
+// DELETE /api/v1/services/{name}/{id} to leave
+func (h *ServicesHandler) HandleServices(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/services"), "/")
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	switch {
+	case len(parts) == 0 && r.Method == "GET":
+		list := h.registry.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case len(parts) == 0 && r.Method == "POST":
+		h.register(w, r)
+	case len(parts) == 1 && r.Method == "GET":
+		h.lookup(w, r, parts[0])
+	case len(parts) == 2 && r.Method == "GET":
+		inst, err := h.registry.Instance(parts[0], parts[1])
+		if err != nil {
+			writeServicesError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(inst)
+	case len(parts) == 2 && r.Method == "DELETE":
+		if err := h.registry.Deregister(parts[0], parts[1]); err != nil {
+			writeServicesError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": parts[1]})
+	case len(parts) == 3 && parts[2] == "heartbeat" && r.Method == "POST":
+		inst, err := h.registry.Heartbeat(parts[0], parts[1])
+		if err != nil {
+			writeServicesError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "expires": inst.Expires})
+	case len(parts) <= 2, len(parts) == 3 && parts[2] == "heartbeat":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// register adds or replaces an instance
+func (h *ServicesHandler) register(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var reg services.Registration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inst, created, err := h.registry.Register(reg)
+	if err != nil {
+		writeServicesError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "instance": inst})
+}
+
+// lookup returns a service; ?passing=true keeps only passing instances
+func (h *ServicesHandler) lookup(w http.ResponseWriter, r *http.Request, name string) {
+	svc, err := h.registry.Get(name)
+	if err != nil {
+		writeServicesError(w, err)
+		return
+	}
+	if r.URL.Query().Get("passing") == "true" {
+		passing := make([]services.Instance, 0, svc.Passing)
+		for _, inst := range svc.Instances {
+			if inst.Status == services.StatusPassing {
+				passing = append(passing, inst)
+			}
+		}
+		svc.Instances = passing
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(svc)
+}
+
+// writeServicesError maps registry errors to HTTP statuses
+func writeServicesError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalid):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrRouteConflict):
+		apierror.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrTooMany):
+		apierror.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/authguard"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+// SessionsHandler handles web UI logins and user management
+type SessionsHandler struct {
+	sessions *auth.SessionStore
+	guard    *authguard.Guard // nil when the auth guard is off
+	// secure marks cookies Secure even on plain HTTP requests, for
+	// deployments behind a TLS proxy that does not set X-Forwarded-Proto
+	secure bool
+}
+
+// NewSessionsHandler creates a new sessions handler. guard, when set,
+// locks out usernames after repeated failed logins.
+func NewSessionsHandler(sessions *auth.SessionStore, guard *authguard.Guard, secureCookies bool) *SessionsHandler {
+	return &SessionsHandler{sessions: sessions, guard: guard, secure: secureCookies}
+}
+
+// Login handles POST /api/v1/auth/login
+func (h *SessionsHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// HTML forms cannot send JSON, so other sites cannot sign a browser
+	// in to an account of theirs
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		apierror.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Source IPs are counted by middleware.AuthGuard, usernames here
+	user := authguard.Client{Kind: authguard.KindUser, ID: req.Username}
+	if h.guard != nil {
+		if locked, until := h.guard.Locked(r.Context(), user); locked {
+			retry := time.Until(until)
+			w.Header().Set("Retry-After", ratelimit.RetryAfter(retry))
+			apierror.Error(w, "too many failed logins for this user, try again later", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	sess, err := h.sessions.Login(r.Context(), req.Username, req.Password)
+	if errors.Is(err, auth.ErrInvalidLogin) {
+		if h.guard != nil {
+			h.guard.Track(r.Context(), user)
+		}
+		apierror.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.guard != nil {
+		h.guard.Succeed(r.Context(), user)
+	}
+	// Attribute the login in the audit log
+	auth.WithIdentity(r.Context(), &auth.Identity{
+		KeyID:   "session",
+		Name:    sess.User.Username,
+		Role:    sess.User.Role,
+		Method:  "session",
+		Subject: sess.User.Username,
+	})
+
+	h.setCookie(w, r, auth.SessionCookie, sess.Token, sess.ExpiresAt, true)
+	h.setCookie(w, r, auth.CSRFCookie, sess.CSRFToken, sess.ExpiresAt, false)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{
+		"ok":         true,
+		"user":       sess.User,
+		"csrf_token": sess.CSRFToken,
+		"expires_at": sess.ExpiresAt,
+	})
+}
+
+// Logout handles POST /api/v1/auth/logout
+func (h *SessionsHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if c, err := r.Cookie(auth.SessionCookie); err == nil && c.Value != "" {
+		if err := h.sessions.Logout(r.Context(), c.Value); err != nil {
+			apierror.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.setCookie(w, r, auth.SessionCookie, "", time.Time{}, true)
+	h.setCookie(w, r, auth.CSRFCookie, "", time.Time{}, false)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true})
+}
+
+// Session handles GET /api/v1/auth/session, returning the signed-in user
+// and CSRF token so the web UI can resume after a reload
+func (h *SessionsHandler) Session(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := r.Cookie(auth.SessionCookie)
+	if err != nil || c.Value == "" {
+		apierror.Error(w, "Not signed in", http.StatusUnauthorized)
+		return
+	}
+	sess, err := h.sessions.Session(r.Context(), c.Value)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		apierror.Error(w, "session expired or signed out", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(sess)
+}
+
+// HandleUsers handles /api/v1/auth/users requests
+func (h *SessionsHandler) HandleUsers(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/auth/users")
+	name = strings.Trim(name, "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		users, err := h.sessions.Users(r.Context())
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"users": users, "count": len(users)})
+	case name == "" && r.Method == "POST":
+		var req auth.UserRequest
+		if !decodeUserRequest(w, r, &req) {
+			return
+		}
+		user, err := h.sessions.CreateUser(r.Context(), req)
+		if err != nil {
+			userError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "user": user})
+	case name != "" && r.Method == "PUT":
+		var req auth.UserRequest
+		if !decodeUserRequest(w, r, &req) {
+			return
+		}
+		user, err := h.sessions.UpdateUser(r.Context(), name, req)
+		if err != nil {
+			userError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "user": user})
+	case name != "" && r.Method == "DELETE":
+		if err := h.sessions.DeleteUser(r.Context(), name); err != nil {
+			userError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func decodeUserRequest(w http.ResponseWriter, r *http.Request, req *auth.UserRequest) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func userError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, auth.ErrInvalidUser):
+		status = http.StatusBadRequest
+	}
+	apierror.Error(w, err.Error(), status)
+}
+
+// setCookie sets a session cookie, or deletes it when expires is zero.
+// Cookies are SameSite=Strict, so browsers never send them on requests
+// started by other sites, and Secure whenever the request came over HTTPS.
+func (h *SessionsHandler) setCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time, httpOnly bool) {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: httpOnly,
+		Secure:   h.secure || r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"),
+		SameSite: http.SameSiteStrictMode,
+	}
+	if expires.IsZero() {
+		c.MaxAge = -1
+	} else {
+		c.Expires = expires
+		c.MaxAge = int(time.Until(expires).Seconds())
+	}
+	http.SetCookie(w, c)
+}
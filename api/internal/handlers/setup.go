@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/setup"
+)
+
+// SetupHandler handles the first-boot setup flow
+type SetupHandler struct {
+	manager *setup.Manager
+	grafana config.Grafana
+}
+
+// NewSetupHandler creates a new setup handler; grafana is where dashboards
+// are seeded
+func NewSetupHandler(manager *setup.Manager, grafana config.Grafana) *SetupHandler {
+	return &SetupHandler{manager: manager, grafana: grafana}
+}
+
+// HandleSetup handles /api/v1/setup requests
+func (h *SetupHandler) HandleSetup(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		h.getStatus(w, r)
+	case "POST":
+		h.complete(w, r)
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getStatus reports whether setup is required and the current choices
+func (h *SetupHandler) getStatus(w http.ResponseWriter, _ *http.Request) {
+	state := h.manager.State()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"required":             !state.Completed,
+		"state":                state,
+		"available_subsystems": setup.Subsystems,
+	})
+}
+
+// complete runs the one-time setup and returns the admin API key
+func (h *SetupHandler) complete(w http.ResponseWriter, r *http.Request) {
+	if !h.manager.Required() {
+		apierror.Error(w, setup.ErrAlreadyCompleted.Error(), http.StatusConflict)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req setup.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.manager.Complete(req)
+	if err != nil {
+		if errors.Is(err, setup.ErrAlreadyCompleted) {
+			apierror.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]any{
+		"ok":      true,
+		"api_key": key,
+		"message": "Setup complete. Store the API key now, it will not be shown again.",
+		"state":   h.manager.State(),
+	}
+
+	if req.SeedDashboards {
+		if err := setup.SeedDashboards(r.Context(), h.grafana); err != nil {
+			response["warning"] = "Dashboard seeding failed: " + err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
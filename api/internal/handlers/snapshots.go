@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/snapshots"
+)
+
+// SnapshotsHandler handles metric snapshot definitions and retrieval
+type SnapshotsHandler struct {
+	manager *snapshots.Manager
+}
+
+// NewSnapshotsHandler creates a new snapshots handler
+func NewSnapshotsHandler(manager *snapshots.Manager) *SnapshotsHandler {
+	return &SnapshotsHandler{manager: manager}
+}
+
+// HandleSnapshots handles /api/v1/metrics/snapshots requests
+func (h *SnapshotsHandler) HandleSnapshots(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/metrics/snapshots")
+	name = strings.Trim(name, "/")
+
+	switch {
+	case name == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"series": list,
+			"count":  len(list),
+		})
+	case name == "" && r.Method == "POST":
+		h.addSeries(w, r)
+	case name != "" && r.Method == "GET":
+		h.getPoints(w, r, name)
+	case name != "" && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addSeries creates or updates a snapshot definition
+func (h *SnapshotsHandler) addSeries(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var s snapshots.Series
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.manager.Add(s); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "series": s})
+}
+
+// getPoints returns stored points; from/to are ms since epoch (default last 30 days)
+func (h *SnapshotsHandler) getPoints(w http.ResponseWriter, r *http.Request, name string) {
+	to := time.Now()
+	from := to.Add(-30 * 24 * time.Hour)
+	if ms, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64); err == nil {
+		from = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64); err == nil {
+		to = time.UnixMilli(ms)
+	}
+
+	series, err := h.manager.Points(r.Context(), name, from, to)
+	if err != nil {
+		apierror.Error(w, "Failed to read snapshots: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"name":   name,
+		"from":   from.UnixMilli(),
+		"to":     to.UnixMilli(),
+		"series": series,
+	})
+}
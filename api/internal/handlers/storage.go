@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	forgev1 "github.com/forge/api/gen/forge/v1"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/credentials"
+	"github.com/forge/api/internal/deps"
+	"github.com/forge/api/internal/storage"
+)
+
+// maxRPCObjectSize bounds objects read through GetObject, which holds them
+// in memory; larger ones are downloaded from a presigned URL
+const maxRPCObjectSize = 8 << 20
+
+// StorageHandler serves object storage on MinIO or any S3 endpoint
+type StorageHandler struct {
+	client *storage.Client
+	broker *credentials.StorageBroker
+	deps   *deps.Registry
+}
+
+// NewStorageHandler creates a storage handler; GetInfo issues keys through
+// broker. Requests fail with Unavailable while registry reports the store
+// down.
+func NewStorageHandler(client *storage.Client, broker *credentials.StorageBroker, registry *deps.Registry) *StorageHandler {
+	return &StorageHandler{
+		client: client,
+		broker: broker,
+		deps:   registry,
+	}
+}
+
+// available reports whether the store is currently reachable
+func (h *StorageHandler) available() bool {
+	return h.client != nil && h.deps.Get("storage").Available()
+}
+
+func (h *StorageHandler) unavailable() error {
+	return connect.NewError(connect.CodeUnavailable, h.deps.Unavailable("storage"))
+}
+
+func (h *StorageHandler) ListBuckets(
+	ctx context.Context,
+	req *connect.Request[forgev1.ListBucketsRequest],
+) (*connect.Response[forgev1.ListBucketsResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	buckets, err := h.client.ListBuckets(ctx)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	resp := &forgev1.ListBucketsResponse{Buckets: make([]*forgev1.BucketInfo, len(buckets))}
+	for i, b := range buckets {
+		resp.Buckets[i] = &forgev1.BucketInfo{Name: b.Name, CreatedAt: b.Created.Unix()}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (h *StorageHandler) CreateBucket(
+	ctx context.Context,
+	req *connect.Request[forgev1.CreateBucketRequest],
+) (*connect.Response[forgev1.CreateBucketResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	if err := h.client.CreateBucket(ctx, req.Msg.Bucket); err != nil {
+		return nil, storageError(err)
+	}
+	return connect.NewResponse(&forgev1.CreateBucketResponse{Ok: true}), nil
+}
+
+func (h *StorageHandler) DeleteBucket(
+	ctx context.Context,
+	req *connect.Request[forgev1.DeleteBucketRequest],
+) (*connect.Response[forgev1.DeleteBucketResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	if err := h.client.DeleteBucket(ctx, req.Msg.Bucket); err != nil {
+		return nil, storageError(err)
+	}
+	return connect.NewResponse(&forgev1.DeleteBucketResponse{Ok: true}), nil
+}
+
+func (h *StorageHandler) PutObject(
+	ctx context.Context,
+	req *connect.Request[forgev1.PutObjectRequest],
+) (*connect.Response[forgev1.PutObjectResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	bucket := h.client.Bucket(req.Msg.Bucket)
+	data := req.Msg.Data
+	obj, err := h.client.PutObject(ctx, bucket, req.Msg.Key, bytes.NewReader(data), int64(len(data)), req.Msg.ContentType)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	return connect.NewResponse(&forgev1.PutObjectResponse{
+		Bucket: bucket,
+		Object: objectInfo(obj),
+	}), nil
+}
+
+// GetObject returns an object of up to 8MB; larger ones are downloaded
+// from a presigned URL
+func (h *StorageHandler) GetObject(
+	ctx context.Context,
+	req *connect.Request[forgev1.GetObjectRequest],
+) (*connect.Response[forgev1.GetObjectResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	obj, body, err := h.client.GetObject(ctx, h.client.Bucket(req.Msg.Bucket), req.Msg.Key)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	defer body.Close()
+
+	tooLarge := connect.NewError(connect.CodeFailedPrecondition,
+		fmt.Errorf("object is larger than %d bytes, download it from a presigned URL", maxRPCObjectSize))
+	if obj.Size > maxRPCObjectSize {
+		return nil, tooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxRPCObjectSize+1))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	if len(data) > maxRPCObjectSize {
+		return nil, tooLarge
+	}
+	return connect.NewResponse(&forgev1.GetObjectResponse{
+		Object: objectInfo(obj),
+		Data:   data,
+	}), nil
+}
+
+func (h *StorageHandler) ListObjects(
+	ctx context.Context,
+	req *connect.Request[forgev1.ListObjectsRequest],
+) (*connect.Response[forgev1.ListObjectsResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	listing, err := h.client.ListObjects(ctx, h.client.Bucket(req.Msg.Bucket), storage.ListOptions{
+		Prefix:            req.Msg.Prefix,
+		Delimiter:         req.Msg.Delimiter,
+		MaxKeys:           int(req.Msg.MaxKeys),
+		ContinuationToken: req.Msg.ContinuationToken,
+	})
+	if err != nil {
+		return nil, storageError(err)
+	}
+	resp := &forgev1.ListObjectsResponse{
+		Objects:               make([]*forgev1.ObjectInfo, len(listing.Objects)),
+		Prefixes:              listing.Prefixes,
+		Truncated:             listing.Truncated,
+		NextContinuationToken: listing.NextContinuationToken,
+	}
+	for i, obj := range listing.Objects {
+		resp.Objects[i] = objectInfo(obj)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (h *StorageHandler) DeleteObject(
+	ctx context.Context,
+	req *connect.Request[forgev1.DeleteObjectRequest],
+) (*connect.Response[forgev1.DeleteObjectResponse], error) {
+	if !h.available() {
+		return nil, h.unavailable()
+	}
+
+	if err := h.client.DeleteObject(ctx, h.client.Bucket(req.Msg.Bucket), req.Msg.Key); err != nil {
+		return nil, storageError(err)
+	}
+	return connect.NewResponse(&forgev1.DeleteObjectResponse{Ok: true}), nil
+}
+
+// PresignURL signs a URL on the store's public address for one object.
+// Download URLs need the read role, upload URLs the write role.
+func (h *StorageHandler) PresignURL(
+	ctx context.Context,
+	req *connect.Request[forgev1.PresignURLRequest],
+) (*connect.Response[forgev1.PresignURLResponse], error) {
+	if h.client == nil {
+		return nil, h.unavailable()
+	}
+
+	method := strings.ToUpper(req.Msg.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if id := auth.FromContext(ctx); method == "PUT" && id != nil && !auth.Allows(id.Role, auth.RoleWrite) {
+		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("%w: upload URLs need the write role", auth.ErrForbidden))
+	}
+
+	expires := time.Duration(req.Msg.ExpiresSeconds) * time.Second
+	url, expiresAt, err := h.client.Presign(method, h.client.Bucket(req.Msg.Bucket), req.Msg.Key, expires)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	return connect.NewResponse(&forgev1.PresignURLResponse{
+		Url:       url,
+		Method:    method,
+		ExpiresAt: expiresAt.Unix(),
+	}), nil
+}
+
+// GetInfo issues the caller temporary keys limited to a bucket and key
+// prefix, with read or write access according to their role
+func (h *StorageHandler) GetInfo(
+	ctx context.Context,
+	req *connect.Request[forgev1.StorageInfoRequest],
+) (*connect.Response[forgev1.StorageInfoResponse], error) {
+	if h.broker == nil || !h.available() {
+		return nil, h.unavailable()
+	}
+
+	scope := h.client.Bucket(req.Msg.Bucket) + "/" + req.Msg.Prefix
+	credReq, err := credentialRequest(ctx, req.Msg.Access, scope, req.Msg.TtlSeconds)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := h.broker.Issue(ctx, credReq)
+	if err != nil {
+		return nil, credentialError(err)
+	}
+	logCredential(ctx, "storage", credReq, cred)
+
+	bucket, prefix, _ := strings.Cut(cred.Scope, "/")
+	return connect.NewResponse(&forgev1.StorageInfoResponse{
+		Endpoint:        h.client.PublicURL(),
+		Region:          h.client.Region(),
+		AccessKeyId:     cred.User,
+		SecretAccessKey: cred.Password,
+		SessionToken:    cred.Token,
+		Bucket:          bucket,
+		Prefix:          prefix,
+		PathStyle:       h.client.PathStyle(),
+		Access:          cred.Access,
+		ExpiresAt:       cred.ExpiresAt.Unix(),
+	}), nil
+}
+
+func objectInfo(obj storage.Object) *forgev1.ObjectInfo {
+	info := &forgev1.ObjectInfo{
+		Key:         obj.Key,
+		Size:        obj.Size,
+		Etag:        obj.ETag,
+		ContentType: obj.ContentType,
+	}
+	if !obj.LastModified.IsZero() {
+		info.LastModified = obj.LastModified.Unix()
+	}
+	return info
+}
+
+// storageError maps store errors to Connect codes
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrInvalidRequest):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.Is(err, storage.ErrNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, storage.ErrConflict):
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	case errors.Is(err, storage.ErrDenied):
+		return connect.NewError(connect.CodePermissionDenied, err)
+	}
+	return connect.NewError(connect.CodeInternal, err)
+}
+
+// REST handlers
+func StorageInfoREST(h *StorageHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		access, ttl, err := credentialQuery(r)
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+		q := r.URL.Query()
+		resp, err := h.GetInfo(r.Context(), connect.NewRequest(&forgev1.StorageInfoRequest{
+			Bucket:     q.Get("bucket"),
+			Prefix:     q.Get("prefix"),
+			Access:     access,
+			TtlSeconds: ttl,
+		}))
+		if err != nil {
+			writeRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(resp.Msg)
+	}
+}
+
+// HandleStorage handles /api/v1/storage requests: buckets, the objects in
+// them and presigned URLs. Objects are streamed as their raw bytes.
+func (h *StorageHandler) HandleStorage(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/storage"), "/")
+	parts := strings.SplitN(path, "/", 4)
+	ctx := r.Context()
+
+	var msg any
+	var err error
+	status := http.StatusOK
+	switch {
+	case path == "presign" && r.Method == "GET":
+		q := r.URL.Query()
+		var expires int64
+		if v := q.Get("expires"); v != "" {
+			if expires, err = strconv.ParseInt(v, 10, 64); err != nil {
+				apierror.Error(w, "expires must be a number of seconds", http.StatusBadRequest)
+				return
+			}
+		}
+		var resp *connect.Response[forgev1.PresignURLResponse]
+		resp, err = h.PresignURL(ctx, connect.NewRequest(&forgev1.PresignURLRequest{
+			Bucket:         q.Get("bucket"),
+			Key:            q.Get("key"),
+			Method:         q.Get("method"),
+			ExpiresSeconds: expires,
+		}))
+		if err == nil {
+			msg = resp.Msg
+		}
+	case path == "buckets" && r.Method == "GET":
+		var resp *connect.Response[forgev1.ListBucketsResponse]
+		if resp, err = h.ListBuckets(ctx, connect.NewRequest(&forgev1.ListBucketsRequest{})); err == nil {
+			msg = resp.Msg
+		}
+	case path == "buckets" && r.Method == "POST":
+		var body forgev1.CreateBucketRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err = h.CreateBucket(ctx, connect.NewRequest(&body)); err == nil {
+			msg = map[string]any{"ok": true, "created": body.Bucket}
+			status = http.StatusCreated
+		}
+	case len(parts) == 2 && parts[0] == "buckets" && r.Method == "DELETE":
+		if _, err = h.DeleteBucket(ctx, connect.NewRequest(&forgev1.DeleteBucketRequest{Bucket: parts[1]})); err == nil {
+			msg = map[string]any{"ok": true, "deleted": parts[1]}
+		}
+	case len(parts) == 3 && parts[0] == "buckets" && parts[2] == "objects" && r.Method == "GET":
+		q := r.URL.Query()
+		maxKeys, _ := strconv.Atoi(q.Get("max_keys"))
+		var resp *connect.Response[forgev1.ListObjectsResponse]
+		resp, err = h.ListObjects(ctx, connect.NewRequest(&forgev1.ListObjectsRequest{
+			Bucket:            parts[1],
+			Prefix:            q.Get("prefix"),
+			Delimiter:         q.Get("delimiter"),
+			MaxKeys:           int32(maxKeys),
+			ContinuationToken: q.Get("continuation_token"),
+		}))
+		if err == nil {
+			msg = resp.Msg
+		}
+	case len(parts) == 4 && parts[0] == "buckets" && parts[2] == "objects":
+		h.handleObject(w, r, parts[1], parts[3])
+		return
+	case parts[0] == "presign" || parts[0] == "buckets":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		writeRPCError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
+
+// handleObject streams an object to or from the store
+func (h *StorageHandler) handleObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if r.Method != "GET" && r.Method != "PUT" && r.Method != "DELETE" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.available() {
+		writeRPCError(w, h.unavailable())
+		return
+	}
+	ctx := r.Context()
+
+	switch r.Method {
+	case "GET":
+		obj, body, err := h.client.GetObject(ctx, bucket, key)
+		if err != nil {
+			writeRPCError(w, storageError(err))
+			return
+		}
+		defer body.Close()
+		if obj.ContentType != "" {
+			w.Header().Set("Content-Type", obj.ContentType)
+		}
+		if obj.Size >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+		}
+		if obj.ETag != "" {
+			w.Header().Set("ETag", `"`+obj.ETag+`"`)
+		}
+		if !obj.LastModified.IsZero() {
+			w.Header().Set("Last-Modified", obj.LastModified.UTC().Format(http.TimeFormat))
+		}
+		io.Copy(w, body)
+
+	case "PUT":
+		// The store needs the size up front
+		if r.ContentLength < 0 {
+			apierror.Error(w, "Content-Length is required", http.StatusLengthRequired)
+			return
+		}
+		obj, err := h.client.PutObject(ctx, bucket, key, r.Body, r.ContentLength, r.Header.Get("Content-Type"))
+		if err != nil {
+			writeRPCError(w, storageError(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&forgev1.PutObjectResponse{Bucket: bucket, Object: objectInfo(obj)})
+
+	case "DELETE":
+		if err := h.client.DeleteObject(ctx, bucket, key); err != nil {
+			writeRPCError(w, storageError(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": key})
+	}
+}
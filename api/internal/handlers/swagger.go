@@ -40,17 +40,20 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
   "info": {
     "title": "Forge API",
     "version": "0.1.0",
-    "description": "Self-hosted infrastructure API"
+    "description": "Self-hosted infrastructure API. Errors are JSON: {\"error\": {\"code\", \"message\", \"details\"}} with Connect code names (invalid_argument, not_found, unavailable, ...), see the Error schema. Request bodies are limited to 1MB, 8MB for ingest endpoints (413 beyond). Administrative endpoints (routes, log sources, system, API keys, setup, certificates, audit) answer 403 to clients outside ADMIN_ALLOWED_CIDRS."
   },
   "servers": [
     {"url": "/api/v1"}
   ],
+  "security": [
+    {"bearerAuth": []}
+  ],
   "paths": {
     "/health": {
       "get": {
         "summary": "Service health check",
         "tags": ["System"],
-        "description": "Returns health status of all services",
+        "description": "Returns health status of all services: MySQL, Redis and the HTTP services in HEALTH_TARGETS, checked concurrently within HEALTH_TIMEOUT. Results are reused for HEALTH_CACHE_TTL. Services apps registered at /api/v1/services are reported under registered.",
         "responses": {
           "200": {
             "description": "Health status",
@@ -71,6 +74,17 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
                           "message": {"type": "string"}
                         }
                       }
+                    },
+                    "registered": {
+                      "type": "object",
+                      "description": "Health of each registered service: healthy when all its instances pass, degraded when some do, unhealthy when none does",
+                      "additionalProperties": {
+                        "type": "object",
+                        "properties": {
+                          "status": {"type": "string", "enum": ["healthy", "degraded", "unhealthy"]},
+                          "message": {"type": "string", "example": "1 of 3 instances passing"}
+                        }
+                      }
                     }
                   }
                 }
@@ -80,6 +94,327 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
         }
       }
     },
+    "/version": {
+      "get": {
+        "summary": "Build and runtime info",
+        "tags": ["System"],
+        "description": "Returns the build identity stamped at build time and the optional features enabled in this instance",
+        "responses": {
+          "200": {
+            "description": "Build info",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "version": {"type": "string", "example": "1.2.0", "description": "\"dev\" for unstamped builds"},
+                    "commit": {"type": "string", "description": "Git commit the API was built from"},
+                    "build_date": {"type": "string", "format": "date-time"},
+                    "modified": {"type": "boolean", "description": "Built from a checkout with uncommitted changes"},
+                    "go_version": {"type": "string", "example": "go1.21.13"},
+                    "os": {"type": "string", "example": "linux"},
+                    "arch": {"type": "string", "example": "amd64"},
+                    "features": {"type": "array", "items": {"type": "string"}, "example": ["audit_log", "docker_discovery", "idempotency", "proxy_nginx", "rate_limits"]},
+                    "uptime": {"type": "string", "example": "3h12m5s"}
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/operations": {
+      "get": {
+        "summary": "List background operations",
+        "tags": ["System"],
+        "parameters": [
+          {"name": "kind", "in": "query", "schema": {"type": "string"}},
+          {"name": "state", "in": "query", "schema": {"type": "string", "enum": ["pending", "running", "succeeded", "failed", "cancelled"]}}
+        ],
+        "responses": {
+          "200": {"description": "Operations, newest first"}
+        }
+      }
+    },
+    "/operations/{id}": {
+      "get": {
+        "summary": "Get operation status, progress and logs",
+        "tags": ["System"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Operation"},
+          "404": {"description": "Operation not found"}
+        }
+      },
+      "delete": {
+        "summary": "Cancel an operation",
+        "tags": ["System"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "202": {"description": "Cancellation requested"},
+          "409": {"description": "Operation already finished"}
+        }
+      }
+    },
+    "/resources": {
+      "get": {
+        "summary": "Search resources by label",
+        "tags": ["System"],
+        "description": "Cross-subsystem search of labeled resources (routes, log sources, secrets, snapshots, ...)",
+        "parameters": [
+          {"name": "label", "in": "query", "schema": {"type": "string"}, "example": "team=payments", "description": "Selector: k=v, k!=v, k, !k (comma-separated or repeated)"},
+          {"name": "kind", "in": "query", "schema": {"type": "string"}, "example": "route"}
+        ],
+        "responses": {
+          "200": {"description": "Matching resources"}
+        }
+      }
+    },
+    "/deprecations": {
+      "get": {
+        "summary": "Deprecated endpoints and their usage",
+        "tags": ["System"],
+        "description": "Lists endpoints marked deprecated with sunset dates, call counts and calling clients",
+        "responses": {
+          "200": {"description": "Deprecation report"}
+        }
+      }
+    },
+    "/setup": {
+      "get": {
+        "summary": "First-boot setup status",
+        "tags": ["System"],
+        "responses": {
+          "200": {"description": "Whether setup is required and the current setup state"}
+        }
+      },
+      "post": {
+        "summary": "Complete first-boot setup",
+        "tags": ["System"],
+        "description": "Creates the first admin and API key. Only available until setup has completed.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "admin_user": {"type": "string", "example": "admin"},
+                  "external_host": {"type": "string", "example": "forge.example.com"},
+                  "subsystems": {"type": "array", "items": {"type": "string", "enum": ["db", "cache", "observability"]}},
+                  "seed_dashboards": {"type": "boolean"}
+                },
+                "required": ["admin_user"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Setup complete; response contains the admin API key"},
+          "409": {"description": "Setup already completed"}
+        }
+      }
+    },
+    "/auth/keys": {
+      "get": {
+        "summary": "List API keys",
+        "tags": ["Auth"],
+        "description": "Issued keys without their secret (id, name, role, prefix, created_at, expires_at, last_used_at) and whether authentication is enforced. Needs the admin role.",
+        "responses": {
+          "200": {"description": "API keys"},
+          "401": {"description": "Missing or invalid API key"},
+          "403": {"description": "Key is not an admin key"}
+        }
+      },
+      "post": {
+        "summary": "Issue an API key",
+        "tags": ["Auth"],
+        "description": "Creates a key with the read, write or admin role. The key is only returned in this response; Forge stores its SHA-256 hash. Authentication is enforced from the first key on. Needs the admin role once enforced.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["name", "role"],
+                "properties": {
+                  "name": {"type": "string", "example": "ci"},
+                  "role": {"type": "string", "enum": ["read", "write", "admin"]},
+                  "expires_in": {"type": "string", "example": "720h", "description": "Go duration; the key never expires when omitted"},
+                  "rate_limits": {"type": "object", "additionalProperties": {"type": "integer"}, "example": {"query": 300}, "description": "Requests per minute by endpoint class (read, write, query, ingest), replacing the per-client limits; 0 is unlimited"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Key issued (key, api_key)"},
+          "400": {"description": "Invalid name, role, expires_in or rate_limits"},
+          "401": {"description": "Missing or invalid API key"},
+          "403": {"description": "Key is not an admin key"}
+        }
+      }
+    },
+    "/auth/keys/{id}": {
+      "delete": {
+        "summary": "Revoke an API key",
+        "tags": ["Auth"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Key revoked"},
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/auth/login": {
+      "post": {
+        "summary": "Sign in to the web UI",
+        "tags": ["Auth"],
+        "description": "Checks a username and password (bcrypt, stored in MySQL) and sets an HttpOnly, SameSite=Strict forge_session cookie and a readable forge_csrf cookie; both are Secure over HTTPS. Requests authenticated by the cookie that are not GET or HEAD must send the csrf_token in X-CSRF-Token. Repeated failures lock out the username and source IP (429 with Retry-After). Needs no API key; requires Content-Type: application/json. Only available with MySQL.",
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["username", "password"],
+                "properties": {
+                  "username": {"type": "string", "example": "admin"},
+                  "password": {"type": "string", "format": "password"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Signed in (user, csrf_token, expires_at)"},
+          "401": {"description": "Invalid username or password"},
+          "415": {"description": "Body is not JSON"},
+          "429": {"description": "Locked out after repeated failed logins"}
+        }
+      }
+    },
+    "/auth/logout": {
+      "post": {
+        "summary": "Sign out of the web UI",
+        "tags": ["Auth"],
+        "description": "Ends the session of the forge_session cookie and clears the session cookies. Needs the X-CSRF-Token header.",
+        "responses": {
+          "200": {"description": "Signed out"}
+        }
+      }
+    },
+    "/auth/session": {
+      "get": {
+        "summary": "Current web UI session",
+        "tags": ["Auth"],
+        "description": "The signed-in user, the session's csrf_token and expires_at, so the UI can resume after a reload",
+        "responses": {
+          "200": {"description": "Session"},
+          "401": {"description": "Not signed in, or the session expired"}
+        }
+      }
+    },
+    "/auth/users": {
+      "get": {
+        "summary": "List web UI users",
+        "tags": ["Auth"],
+        "description": "Users (username, role, created_at, updated_at, last_login_at), never their passwords. Needs the admin role.",
+        "responses": {
+          "200": {"description": "Users"}
+        }
+      },
+      "post": {
+        "summary": "Create a web UI user",
+        "tags": ["Auth"],
+        "description": "Needs the admin role. Authentication is enforced from the first user on.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/UserRequest"}
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "User created"},
+          "400": {"description": "Invalid username, password (12-72 characters) or role, or the user exists"}
+        }
+      }
+    },
+    "/auth/users/{username}": {
+      "put": {
+        "summary": "Change a user's password or role",
+        "tags": ["Auth"],
+        "description": "Fields left out are unchanged. The user's sessions end. Needs the admin role.",
+        "parameters": [
+          {"name": "username", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/UserRequest"}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "User updated"},
+          "400": {"description": "Invalid password or role"},
+          "404": {"description": "User not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a web UI user",
+        "tags": ["Auth"],
+        "description": "Deletes the user and ends their sessions. Needs the admin role.",
+        "parameters": [
+          {"name": "username", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "User deleted"},
+          "404": {"description": "User not found"}
+        }
+      }
+    },
+    "/auth/whoami": {
+      "get": {
+        "summary": "Caller identity",
+        "tags": ["Auth"],
+        "description": "The key, OIDC token, client certificate or session the request authenticated with (key_id, name, role, method, subject) and whether authentication is enforced",
+        "responses": {
+          "200": {"description": "Identity"}
+        }
+      }
+    },
+    "/audit": {
+      "get": {
+        "summary": "Audit log of write operations",
+        "tags": ["Auth"],
+        "description": "Mutating REST requests and RPCs, newest first: time, actor, key_id, role, remote_addr, method, path, procedure, payload (redacted summary), status, duration_ms, request_id. Refused writes are included. Telemetry ingestion is not audited. Needs the admin role.",
+        "parameters": [
+          {"name": "from", "in": "query", "schema": {"type": "integer"}, "description": "Start, ms since epoch (default 7 days ago)"},
+          {"name": "to", "in": "query", "schema": {"type": "integer"}, "description": "End, ms since epoch (default now)"},
+          {"name": "actor", "in": "query", "schema": {"type": "string"}, "description": "Key or user name, or key ID"},
+          {"name": "path", "in": "query", "schema": {"type": "string"}, "example": "/api/v1/routes", "description": "Path prefix"},
+          {"name": "failed", "in": "query", "schema": {"type": "boolean"}, "description": "Only requests with a status of 400 or more"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 100, "maximum": 1000}}
+        ],
+        "responses": {
+          "200": {"description": "Audit entries"},
+          "400": {"description": "Invalid range or limit"},
+          "403": {"description": "Caller is not an admin"}
+        }
+      }
+    },
     "/db/query": {
       "post": {
         "summary": "Execute SQL query",
@@ -119,10 +454,41 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
         }
       }
     },
+    "/db/hints": {
+      "post": {
+        "summary": "Visualization hints for query results",
+        "tags": ["Database"],
+        "description": "Infers column types and suggests visualizations (timeseries, bar, stat, table) for a /db/query response",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "columns": {"type": "array", "items": {"type": "string"}},
+                  "rows": {"type": "array"}
+                },
+                "required": ["columns", "rows"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Column types and ranked visualization suggestions"}
+        }
+      }
+    },
     "/db/info": {
       "get": {
         "summary": "Get database connection info",
+        "description": "Issues a short-lived MySQL account scoped to one database, never the root credentials. Requires an API key or token even while authentication is not enforced; the read role gets read-only accounts. Expired accounts are dropped and their connections closed.",
         "tags": ["Database"],
+        "parameters": [
+          {"name": "database", "in": "query", "schema": {"type": "string"}, "description": "Database to grant access to (default from CREDENTIALS_DATABASE, app); system databases and Forge's own are refused"},
+          {"name": "access", "in": "query", "schema": {"type": "string", "enum": ["read", "write"]}, "description": "Default: the most the caller's role allows"},
+          {"name": "ttl", "in": "query", "schema": {"type": "integer", "minimum": 60, "maximum": 86400, "default": 3600}, "description": "Lifetime in seconds"}
+        ],
         "responses": {
           "200": {
             "description": "Connection info for SQLAlchemy",
@@ -135,12 +501,19 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
                     "port": {"type": "integer"},
                     "user": {"type": "string"},
                     "password": {"type": "string"},
-                    "url": {"type": "string"}
+                    "url": {"type": "string"},
+                    "database": {"type": "string"},
+                    "access": {"type": "string"},
+                    "expires_at": {"type": "integer", "description": "Unix seconds"}
                   }
                 }
               }
             }
-          }
+          },
+          "400": {"description": "Invalid database, access or ttl"},
+          "401": {"description": "No API key or token"},
+          "403": {"description": "Write access requested with the read role"},
+          "503": {"description": "MySQL unavailable"}
         }
       }
     },
@@ -172,7 +545,8 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
         "summary": "Set cached value",
         "tags": ["Cache"],
         "parameters": [
-          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}}
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/IdempotencyKey"}
         ],
         "requestBody": {
           "required": true,
@@ -207,7 +581,13 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
     "/cache/info": {
       "get": {
         "summary": "Get cache connection info",
+        "description": "Issues a short-lived Redis ACL user limited to a key prefix, without admin or dangerous commands. Requires an API key or token even while authentication is not enforced; the read role gets read-only users. Only meaningful when the Redis default user is password protected.",
         "tags": ["Cache"],
+        "parameters": [
+          {"name": "prefix", "in": "query", "schema": {"type": "string"}, "description": "Key prefix the user may access (default: all keys)"},
+          {"name": "access", "in": "query", "schema": {"type": "string", "enum": ["read", "write"]}, "description": "Default: the most the caller's role allows"},
+          {"name": "ttl", "in": "query", "schema": {"type": "integer", "minimum": 60, "maximum": 86400, "default": 3600}, "description": "Lifetime in seconds"}
+        ],
         "responses": {
           "200": {
             "description": "Connection info for Redis client",
@@ -218,70 +598,2705 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
                   "properties": {
                     "host": {"type": "string"},
                     "port": {"type": "integer"},
-                    "url": {"type": "string"}
+                    "user": {"type": "string"},
+                    "password": {"type": "string"},
+                    "url": {"type": "string"},
+                    "key_prefix": {"type": "string"},
+                    "access": {"type": "string"},
+                    "expires_at": {"type": "integer", "description": "Unix seconds"}
                   }
                 }
               }
             }
-          }
+          },
+          "400": {"description": "Invalid prefix, access or ttl"},
+          "401": {"description": "No API key or token"},
+          "403": {"description": "Write access requested with the read role"},
+          "503": {"description": "Redis unavailable"}
         }
       }
     },
-    "/logs": {
-      "post": {
-        "summary": "Push log entry",
-        "tags": ["Observability"],
-        "requestBody": {
-          "required": true,
-          "content": {
-            "application/json": {
-              "schema": {
-                "type": "object",
-                "properties": {
-                  "message": {"type": "string"},
-                  "level": {"type": "string", "enum": ["debug", "info", "warn", "error"]},
-                  "labels": {"type": "object"}
-                },
-                "required": ["message"]
+    "/storage/info": {
+      "get": {
+        "summary": "Get object storage connection info",
+        "description": "Issues temporary S3 keys from the store's STS API, limited by a session policy to one bucket and key prefix. Requires an API key or token even while authentication is not enforced; the read role gets read-only keys.",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "bucket", "in": "query", "schema": {"type": "string"}, "description": "Bucket to grant access to (default from STORAGE_BUCKET, forge)"},
+          {"name": "prefix", "in": "query", "schema": {"type": "string"}, "description": "Key prefix the keys may access (default: the whole bucket)"},
+          {"name": "access", "in": "query", "schema": {"type": "string", "enum": ["read", "write"]}, "description": "Default: the most the caller's role allows"},
+          {"name": "ttl", "in": "query", "schema": {"type": "integer", "minimum": 900, "maximum": 43200, "default": 3600}, "description": "Lifetime in seconds"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Connection info for S3 clients such as boto3",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "endpoint": {"type": "string"},
+                    "region": {"type": "string"},
+                    "access_key_id": {"type": "string"},
+                    "secret_access_key": {"type": "string"},
+                    "session_token": {"type": "string"},
+                    "bucket": {"type": "string"},
+                    "prefix": {"type": "string"},
+                    "path_style": {"type": "boolean", "description": "Address buckets in the path rather than the host name"},
+                    "access": {"type": "string"},
+                    "expires_at": {"type": "integer", "description": "Unix seconds"}
+                  }
+                }
               }
             }
-          }
-        },
-        "responses": {
-          "200": {"description": "Log pushed"}
+          },
+          "400": {"description": "Invalid bucket, prefix, access or ttl"},
+          "401": {"description": "No API key or token"},
+          "403": {"description": "Write access requested with the read role"},
+          "503": {"description": "Object storage unavailable"}
         }
       }
     },
-    "/metrics": {
-      "post": {
-        "summary": "Push metric",
-        "tags": ["Observability"],
-        "requestBody": {
-          "required": true,
-          "content": {
-            "application/json": {
-              "schema": {
-                "type": "object",
-                "properties": {
+    "/storage/presign": {
+      "get": {
+        "summary": "Get a presigned object URL",
+        "description": "Returns a URL that downloads or uploads one object directly from the store, for files larger than the API accepts. Upload URLs require the write role.",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "key", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "bucket", "in": "query", "schema": {"type": "string"}, "description": "Default from STORAGE_BUCKET"},
+          {"name": "method", "in": "query", "schema": {"type": "string", "enum": ["GET", "PUT"], "default": "GET"}},
+          {"name": "expires", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 604800, "default": 900}, "description": "Lifetime in seconds"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Presigned URL",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "url": {"type": "string"},
+                    "method": {"type": "string"},
+                    "expires_at": {"type": "integer", "description": "Unix seconds"}
+                  }
+                }
+              }
+            }
+          },
+          "400": {"description": "Invalid bucket, key, method or expiry"},
+          "403": {"description": "Upload URL requested with the read role"}
+        }
+      }
+    },
+    "/storage/buckets": {
+      "get": {
+        "summary": "List buckets",
+        "tags": ["Storage"],
+        "responses": {
+          "200": {
+            "description": "Buckets",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "buckets": {
+                      "type": "array",
+                      "items": {
+                        "type": "object",
+                        "properties": {
+                          "name": {"type": "string"},
+                          "created_at": {"type": "integer", "description": "Unix seconds"}
+                        }
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "503": {"description": "Object storage unavailable"}
+        }
+      },
+      "post": {
+        "summary": "Create bucket",
+        "tags": ["Storage"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "bucket": {"type": "string", "description": "3-63 lowercase letters, digits, '.' or '-'"}
+                },
+                "required": ["bucket"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Bucket created"},
+          "400": {"description": "Invalid bucket name"},
+          "409": {"description": "Bucket already exists"},
+          "503": {"description": "Object storage unavailable"}
+        }
+      }
+    },
+    "/storage/buckets/{bucket}": {
+      "delete": {
+        "summary": "Delete bucket",
+        "description": "Only empty buckets can be deleted.",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "bucket", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Bucket deleted"},
+          "404": {"description": "Bucket not found"},
+          "409": {"description": "Bucket not empty"},
+          "503": {"description": "Object storage unavailable"}
+        }
+      }
+    },
+    "/storage/buckets/{bucket}/objects": {
+      "get": {
+        "summary": "List objects",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "bucket", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "prefix", "in": "query", "schema": {"type": "string"}},
+          {"name": "delimiter", "in": "query", "schema": {"type": "string"}, "description": "Group keys sharing a prefix up to this, usually /"},
+          {"name": "max_keys", "in": "query", "schema": {"type": "integer", "maximum": 1000, "default": 1000}},
+          {"name": "continuation_token", "in": "query", "schema": {"type": "string"}, "description": "next_continuation_token of the previous page"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Objects",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "objects": {"type": "array", "items": {"$ref": "#/components/schemas/StorageObject"}},
+                    "prefixes": {"type": "array", "items": {"type": "string"}},
+                    "truncated": {"type": "boolean"},
+                    "next_continuation_token": {"type": "string"}
+                  }
+                }
+              }
+            }
+          },
+          "404": {"description": "Bucket not found"},
+          "503": {"description": "Object storage unavailable"}
+        }
+      }
+    },
+    "/storage/buckets/{bucket}/objects/{key}": {
+      "get": {
+        "summary": "Download object",
+        "description": "Streams the object with its Content-Type, ETag and Last-Modified.",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "bucket", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}, "description": "May contain /"}
+        ],
+        "responses": {
+          "200": {"description": "Object contents", "content": {"application/octet-stream": {"schema": {"type": "string", "format": "binary"}}}},
+          "404": {"description": "Object not found"},
+          "503": {"description": "Object storage unavailable"}
+        }
+      },
+      "put": {
+        "summary": "Upload object",
+        "description": "Stores the request body as the object, up to 8MB; use a presigned URL for larger files. Content-Length is required.",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "bucket", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}, "description": "May contain /"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/octet-stream": {"schema": {"type": "string", "format": "binary"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "Object stored",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "bucket": {"type": "string"},
+                    "object": {"$ref": "#/components/schemas/StorageObject"}
+                  }
+                }
+              }
+            }
+          },
+          "411": {"description": "No Content-Length"},
+          "413": {"description": "Object larger than 8MB"},
+          "503": {"description": "Object storage unavailable"}
+        }
+      },
+      "delete": {
+        "summary": "Delete object",
+        "description": "Deleting a missing object succeeds.",
+        "tags": ["Storage"],
+        "parameters": [
+          {"name": "bucket", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}, "description": "May contain /"}
+        ],
+        "responses": {
+          "200": {"description": "Object deleted"},
+          "503": {"description": "Object storage unavailable"}
+        }
+      }
+    },
+    "/files": {
+      "get": {
+        "summary": "List files",
+        "description": "Lists files kept on the API's local volume, with how much of the quota they use.",
+        "tags": ["Files"],
+        "parameters": [
+          {"name": "prefix", "in": "query", "schema": {"type": "string"}, "description": "Only paths starting with this"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Files and usage",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "items": {"type": "array", "items": {"$ref": "#/components/schemas/File"}},
+                    "count": {"type": "integer"},
+                    "usage": {
+                      "type": "object",
+                      "properties": {
+                        "files": {"type": "integer"},
+                        "used_bytes": {"type": "integer"},
+                        "quota_bytes": {"type": "integer", "description": "FILES_QUOTA_MB in bytes; 0 is unlimited"},
+                        "max_file_bytes": {"type": "integer", "description": "FILES_MAX_SIZE_MB in bytes"}
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/files/{path}": {
+      "get": {
+        "summary": "Download file",
+        "description": "Serves the file with its content type, Last-Modified and its SHA-256 as ETag and X-Checksum-Sha256. Range, If-Range and If-None-Match are honoured.",
+        "tags": ["Files"],
+        "parameters": [
+          {"name": "path", "in": "path", "required": true, "schema": {"type": "string"}, "description": "May contain /"},
+          {"name": "Range", "in": "header", "schema": {"type": "string"}, "description": "e.g. bytes=0-1023"}
+        ],
+        "responses": {
+          "200": {"description": "File contents", "content": {"application/octet-stream": {"schema": {"type": "string", "format": "binary"}}}},
+          "206": {"description": "Requested range of the file"},
+          "304": {"description": "Unchanged since the ETag in If-None-Match"},
+          "404": {"description": "File not found"},
+          "416": {"description": "Range outside the file"}
+        }
+      },
+      "put": {
+        "summary": "Upload file",
+        "description": "Stores the request body at path, replacing any file there. The content type is taken from Content-Type, or detected from the name and contents when it is missing or application/octet-stream.",
+        "tags": ["Files"],
+        "parameters": [
+          {"name": "path", "in": "path", "required": true, "schema": {"type": "string"}, "description": "May contain /; segments may not be empty or start with '.'"},
+          {"name": "X-Checksum-Sha256", "in": "header", "schema": {"type": "string"}, "description": "Hex SHA-256 the upload must match"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/octet-stream": {"schema": {"type": "string", "format": "binary"}}}
+        },
+        "responses": {
+          "200": {"description": "File replaced", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/File"}}}},
+          "201": {"description": "File created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/File"}}}},
+          "400": {"description": "Invalid path or checksum mismatch"},
+          "409": {"description": "Path is a directory of other files, or below a file"},
+          "413": {"description": "Larger than FILES_MAX_SIZE_MB"},
+          "507": {"description": "Would exceed FILES_QUOTA_MB"}
+        }
+      },
+      "delete": {
+        "summary": "Delete file",
+        "tags": ["Files"],
+        "parameters": [
+          {"name": "path", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "File deleted"},
+          "404": {"description": "File not found"}
+        }
+      }
+    },
+    "/notify": {
+      "post": {
+        "summary": "Send a notification",
+        "tags": ["Notifications"],
+        "description": "Sends a message to a notification channel by name and waits for its service to accept it. Needs the write role. Exports forge_notifications_total per channel and result.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "channel": {"type": "string"},
+                  "title": {"type": "string", "description": "Up to 256 characters"},
+                  "text": {"type": "string", "description": "Up to 4000 characters; Discord messages are cut at 2000"},
+                  "priority": {"type": "string", "enum": ["low", "normal", "high"], "default": "normal"}
+                },
+                "required": ["channel", "text"]
+              },
+              "example": {"channel": "ops", "title": "Deploy finished", "text": "shop v2.4.1 is live", "priority": "low"}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Sent"},
+          "400": {"description": "Invalid message"},
+          "404": {"description": "Channel not found"},
+          "409": {"description": "Channel disabled"},
+          "502": {"description": "The channel's service refused the message or could not be reached"}
+        }
+      }
+    },
+    "/notify/channels": {
+      "get": {
+        "summary": "List notification channels",
+        "tags": ["Notifications"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Channels with send counts; tokens are never listed"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace a notification channel",
+        "tags": ["Notifications"],
+        "description": "Admin only. Channels are sent what apps post to /notify and, when events are set, a message for every matching stack event: route.changed, container.unhealthy, container.died, alert.fired, alert.resolved, backup.finished, job.failed, watchdog.incident and mqtt.message, or a family such as container.* or *. Event messages are sent once, without retries. A channel replaced without a token keeps its current one.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/NotificationChannel"},
+              "example": {"name": "ops", "type": "telegram", "chat_id": "-1001234567890", "token": "123456:ABC-DEF", "events": ["alert.fired", "container.died", "watchdog.incident"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Channel replaced"},
+          "201": {"description": "Channel created"},
+          "400": {"description": "Invalid channel"}
+        }
+      }
+    },
+    "/notify/channels/{name}": {
+      "get": {
+        "summary": "Get a notification channel",
+        "tags": ["Notifications"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Channel with send counts"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "patch": {
+        "summary": "Disable or enable a notification channel",
+        "tags": ["Notifications"],
+        "description": "Disabled channels are sent no events and refuse messages from apps",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"disabled": {"type": "boolean"}}, "required": ["disabled"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Updated channel"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a notification channel",
+        "tags": ["Notifications"],
+        "description": "Removes its metrics",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/notify/channels/{name}/test": {
+      "post": {
+        "summary": "Send a test notification",
+        "tags": ["Notifications"],
+        "description": "Sends a test message, even to a disabled channel, and waits for the result",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Sent"},
+          "404": {"description": "Not found"},
+          "502": {"description": "The channel's service refused the message or could not be reached"}
+        }
+      }
+    },
+    "/messaging/publish/{subject}": {
+      "post": {
+        "summary": "Publish a message",
+        "tags": ["Messaging"],
+        "description": "Publishes the raw request body (up to 1MB, and no more than the server's max payload) on a subject without wildcards, and waits for the message bus to take it. Needs the write role. Exports forge_messaging_messages_total per direction.",
+        "parameters": [
+          {"name": "subject", "in": "path", "required": true, "schema": {"type": "string"}, "description": "Dot-separated tokens, such as orders.created; subjects starting with $ are reserved"},
+          {"name": "reply_to", "in": "query", "schema": {"type": "string"}, "description": "Subject for subscribers to reply on"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/octet-stream": {"schema": {"type": "string", "format": "binary"}}
+          }
+        },
+        "responses": {
+          "200": {"description": "Published"},
+          "400": {"description": "Invalid subject or reply_to"},
+          "429": {"description": "Message larger than the server accepts"},
+          "503": {"description": "Message bus unavailable"}
+        }
+      }
+    },
+    "/messaging/subscribe/{subject}": {
+      "get": {
+        "summary": "Subscribe to a subject",
+        "tags": ["Messaging"],
+        "description": "Streams messages as Server-Sent Events: a \"message\" event per message, with its data base64-encoded, and an \"error\" event ending the stream when the connection to the bus is lost. Subscriptions in the same queue group share the subject's messages, each going to one of them. Messages are dropped for subscribers that fall 256 behind.",
+        "parameters": [
+          {"name": "subject", "in": "path", "required": true, "schema": {"type": "string"}, "description": "May hold the wildcards * (one token) and > (the rest), such as orders.>"},
+          {"name": "queue", "in": "query", "schema": {"type": "string"}, "description": "Queue group to join"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Event stream",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "subject": {"type": "string"},
+                    "data": {"type": "string", "format": "byte"},
+                    "reply_to": {"type": "string"},
+                    "received_at": {"type": "integer", "description": "Unix milliseconds"}
+                  }
+                }
+              }
+            }
+          },
+          "400": {"description": "Invalid subject or queue group"},
+          "429": {"description": "Too many subscriptions open"},
+          "503": {"description": "Message bus unavailable"}
+        }
+      }
+    },
+    "/messaging/info": {
+      "get": {
+        "summary": "Get message bus connection info",
+        "tags": ["Messaging"],
+        "description": "Returns the bus's address and an account for NATS clients: one that may publish and subscribe for the write role, subscribe only for the read role. Requires an API key or token even while authentication is not enforced.",
+        "parameters": [
+          {"name": "access", "in": "query", "schema": {"type": "string", "enum": ["read", "write"]}, "description": "Default: the most the caller's role allows"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Connection info",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "url": {"type": "string"},
+                    "user": {"type": "string"},
+                    "password": {"type": "string"},
+                    "access": {"type": "string"},
+                    "max_payload": {"type": "integer"},
+                    "server_version": {"type": "string"}
+                  }
+                }
+              }
+            }
+          },
+          "400": {"description": "Invalid access"},
+          "401": {"description": "No API key or token"},
+          "403": {"description": "Write access requested with the read role"},
+          "503": {"description": "Message bus unavailable"}
+        }
+      }
+    },
+    "/mqtt/status": {
+      "get": {
+        "summary": "Get MQTT connection status",
+        "tags": ["MQTT"],
+        "description": "The broker's address, whether the API is connected and the topic filters it is subscribed to",
+        "responses": {
+          "200": {
+            "description": "Connection status",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "url": {"type": "string"},
+                    "client_id": {"type": "string"},
+                    "connected": {"type": "boolean"},
+                    "subscriptions": {"type": "array", "items": {"type": "string"}}
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/mqtt/publish/{topic}": {
+      "post": {
+        "summary": "Publish an MQTT message",
+        "tags": ["MQTT"],
+        "description": "Publishes the raw request body (up to 1MB) to a topic without wildcards. At QoS 1 it waits for the broker to take the message. Exports forge_mqtt_published_total.",
+        "parameters": [
+          {"name": "topic", "in": "path", "required": true, "schema": {"type": "string"}, "description": "Slash-separated levels, such as home/living/light/set"},
+          {"name": "qos", "in": "query", "schema": {"type": "integer", "enum": [0, 1]}, "description": "Default: 0"},
+          {"name": "retain", "in": "query", "schema": {"type": "boolean"}, "description": "Have the broker keep the message for later subscribers"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/octet-stream": {"schema": {"type": "string", "format": "binary"}}
+          }
+        },
+        "responses": {
+          "200": {"description": "Published"},
+          "400": {"description": "Invalid topic or qos"},
+          "413": {"description": "Body over 1MB"},
+          "503": {"description": "MQTT broker unavailable"},
+          "504": {"description": "The broker did not acknowledge the message in time"}
+        }
+      }
+    },
+    "/mqtt/bridges": {
+      "get": {
+        "summary": "List MQTT bridges",
+        "tags": ["MQTT"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Bridges with message counts"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace an MQTT bridge",
+        "tags": ["MQTT"],
+        "description": "Admin only. A bridge subscribes to a topic filter and forwards every message it receives to its targets: a log line in Loki (labelled source=mqtt and the bridge), a counter incremented or a gauge set from the payload, or an mqtt.message event for outbound webhooks and notification channels. Messages are handled in order; those arriving over 1000 behind are dropped. Exports forge_mqtt_messages_total per bridge and result.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/MQTTBridge"},
+              "example": {"name": "temperatures", "topic": "home/+/temperature", "targets": [{"type": "metric", "metric": "home_temperature_celsius", "kind": "gauge", "value": "payload.temperature", "labels": {"room": "${topic.1}"}}, {"type": "log", "level": "debug"}]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Bridge replaced"},
+          "201": {"description": "Bridge created"},
+          "400": {"description": "Invalid bridge"}
+        }
+      }
+    },
+    "/mqtt/bridges/{name}": {
+      "get": {
+        "summary": "Get an MQTT bridge",
+        "tags": ["MQTT"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Bridge with message counts"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "patch": {
+        "summary": "Disable or enable an MQTT bridge",
+        "tags": ["MQTT"],
+        "description": "Disabled bridges unsubscribe from their topic",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"disabled": {"type": "boolean"}}, "required": ["disabled"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Updated bridge"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete an MQTT bridge",
+        "tags": ["MQTT"],
+        "description": "Removes its metrics",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/services": {
+      "get": {
+        "summary": "List registered services",
+        "tags": ["Services"],
+        "responses": {
+          "200": {"description": "Services with their instances"}
+        }
+      },
+      "post": {
+        "summary": "Register a service instance",
+        "tags": ["Services"],
+        "description": "Adds an instance, or replaces the one with the same name and id. Its health URL is checked in the background; a new instance is critical until that check answers. The instance is dropped unless it sends a heartbeat within its TTL. Its health URL is checked every 10s; instances without one pass while they send heartbeats. A route, if asked for, is named after the service and points at its first passing instance. Instances with a metrics_path are scraped by Prometheus. Exports forge_service_instances per service and status.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/ServiceRegistration"},
+              "example": {"name": "orders", "address": "http://orders-1:8000", "health_url": "/healthz", "metadata": {"version": "1.4.2"}, "ttl": "30s", "route": {"path": "/orders/", "strip_prefix": true}, "metrics_path": "/metrics"}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Instance replaced"},
+          "201": {"description": "Instance registered"},
+          "400": {"description": "Invalid registration or route"},
+          "409": {"description": "The route name is taken by a route not made for the service"},
+          "429": {"description": "The registry holds 1000 instances"}
+        }
+      }
+    },
+    "/services/{name}": {
+      "get": {
+        "summary": "Look up a service",
+        "tags": ["Services"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "passing", "in": "query", "schema": {"type": "boolean"}, "description": "Only list instances whose health check passes"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Service with its instances",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "name": {"type": "string"},
+                    "status": {"type": "string", "enum": ["passing", "warning", "critical"]},
+                    "passing": {"type": "integer"},
+                    "instances": {"type": "array", "items": {"$ref": "#/components/schemas/ServiceInstance"}},
+                    "route_error": {"type": "string", "description": "Why the service's route cannot be made"}
+                  }
+                }
+              }
+            }
+          },
+          "404": {"description": "No instance registered"}
+        }
+      }
+    },
+    "/services/{name}/{id}": {
+      "get": {
+        "summary": "Get a service instance",
+        "tags": ["Services"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Instance", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ServiceInstance"}}}},
+          "404": {"description": "Not registered"}
+        }
+      },
+      "delete": {
+        "summary": "Deregister a service instance",
+        "tags": ["Services"],
+        "description": "Removes the service's route with its last instance asking for one",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Deregistered"},
+          "404": {"description": "Not registered"}
+        }
+      }
+    },
+    "/services/{name}/{id}/heartbeat": {
+      "post": {
+        "summary": "Send a heartbeat",
+        "tags": ["Services"],
+        "description": "Keeps the instance for another TTL. A 404 means it expired: register again.",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Heartbeat taken",
+            "content": {
+              "application/json": {
+                "schema": {"type": "object", "properties": {"ok": {"type": "boolean"}, "expires": {"type": "string", "format": "date-time"}}}
+              }
+            }
+          },
+          "404": {"description": "Not registered"}
+        }
+      }
+    },
+    "/logs": {
+      "post": {
+        "summary": "Push log entry",
+        "tags": ["Observability"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "message": {"type": "string"},
+                  "level": {"type": "string", "enum": ["debug", "info", "warn", "error"]},
+                  "labels": {"type": "object"},
+                  "timestamp_ms": {"type": "integer", "description": "Defaults to now"},
+                  "fields": {"type": "object", "description": "Structured fields; the line is stored as JSON {msg, level, ...fields} and can be filtered with '| json'"},
+                  "metadata": {"type": "array", "items": {"type": "string"}, "description": "Field names also attached as Loki structured metadata"}
+                },
+                "required": ["message"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Log pushed"}
+        }
+      }
+    },
+    "/logs/query": {
+      "get": {
+        "summary": "Query logs with LogQL",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}, "example": "{job=\"forge\"}"},
+          {"name": "start", "in": "query", "schema": {"type": "integer"}, "description": "Range start (ms since epoch, default 1h ago)"},
+          {"name": "end", "in": "query", "schema": {"type": "integer"}, "description": "Range end (ms since epoch, default now)"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 50}},
+          {"name": "direction", "in": "query", "schema": {"type": "string", "enum": ["backward", "forward"]}},
+          {"name": "instant", "in": "query", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {"description": "Matching log streams"}
+        }
+      }
+    },
+    "/logs/tail": {
+      "get": {
+        "summary": "Live tail logs (Server-Sent Events)",
+        "tags": ["Observability"],
+        "description": "Streams new entries matching a LogQL selector as 'log' events",
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}, "example": "{job=\"forge\"}"},
+          {"name": "start", "in": "query", "schema": {"type": "integer"}, "description": "Replay from this time (ms since epoch, default now)"}
+        ],
+        "responses": {
+          "200": {"description": "text/event-stream of log entries"}
+        }
+      }
+    },
+    "/logs/ingest/fluent": {
+      "post": {
+        "summary": "Ingest Fluent Bit / Fluentd HTTP output",
+        "tags": ["Observability"],
+        "description": "Accepts the json (array) or json_lines format, optionally gzip-encoded, and relays records to Loki with job=fluent. The message is read from log/message/msg, level from level/severity, time from date/time/timestamp; remaining keys become structured fields.",
+        "parameters": [
+          {"name": "X-Fluent-Tag", "in": "header", "schema": {"type": "string"}, "description": "Fluent tag, added as the tag label (set header_tag in Fluent Bit)"},
+          {"name": "tag", "in": "query", "schema": {"type": "string"}, "description": "Tag used when the header is absent"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "array", "items": {"type": "object"}},
+              "example": [{"date": 1718000000.5, "log": "GET /healthz 200", "level": "info", "pod": "web-1"}]
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Counts of accepted, rejected and dropped records"},
+          "400": {"description": "Malformed payload or invalid tag"},
+          "415": {"description": "msgpack format is not supported"},
+          "503": {"description": "Log queue full, retry later"}
+        }
+      }
+    },
+    "/logs/pipelines": {
+      "get": {
+        "summary": "List log pipelines",
+        "tags": ["Observability"],
+        "responses": {
+          "200": {"description": "Pipelines in the order they are applied"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace a log pipeline",
+        "tags": ["Observability"],
+        "description": "Pipelines run on entries pushed through Forge (log RPC, fluent and syslog ingestion) whose stream labels include all of match. Stages: regex (named groups), json (top-level keys), labels and fields (promote extracted values), level, output (replace the line) and drop.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string"},
+                  "match": {"type": "object", "additionalProperties": {"type": "string"}},
+                  "stages": {
+                    "type": "array",
+                    "items": {
+                      "type": "object",
+                      "properties": {
+                        "type": {"type": "string", "enum": ["regex", "json", "labels", "fields", "level", "output", "drop"]},
+                        "source": {"type": "string", "description": "Extracted value to read (default the log line)"},
+                        "expression": {"type": "string", "description": "Regular expression for regex and drop"},
+                        "values": {"type": "object", "additionalProperties": {"type": "string"}, "description": "labels/fields: name to extracted key"}
+                      },
+                      "required": ["type"]
+                    }
+                  }
+                },
+                "required": ["name", "stages"]
+              },
+              "example": {"name": "nginx", "match": {"app": "nginx"}, "stages": [{"type": "regex", "expression": " (?P<status>\\d{3}) "}, {"type": "labels", "values": {"status": ""}}]}
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Pipeline saved"},
+          "400": {"description": "Invalid pipeline"}
+        }
+      }
+    },
+    "/logs/pipelines/{name}": {
+      "get": {
+        "summary": "Get a log pipeline",
+        "tags": ["Observability"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Pipeline"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a log pipeline",
+        "tags": ["Observability"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/logs/sampling": {
+      "get": {
+        "summary": "List log sampling rules",
+        "tags": ["Observability"],
+        "responses": {
+          "200": {"description": "Rules in evaluation order, with matched and dropped counts"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace a log sampling rule",
+        "tags": ["Observability"],
+        "description": "Rules apply to entries pushed through Forge after pipelines and log metrics have run. The first rule (by name) whose match labels and levels fit an entry decides: drop discards it, sample keeps a rate fraction.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string"},
+                  "match": {"type": "object", "additionalProperties": {"type": "string"}},
+                  "levels": {"type": "array", "items": {"type": "string"}, "description": "Levels the rule applies to (default all)"},
+                  "action": {"type": "string", "enum": ["drop", "sample"]},
+                  "rate": {"type": "number", "description": "sample: fraction of entries kept, between 0 and 1"}
+                },
+                "required": ["name", "action"]
+              },
+              "example": {"name": "worker-info", "match": {"app": "worker"}, "levels": ["info"], "action": "sample", "rate": 0.1}
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Rule saved"},
+          "400": {"description": "Invalid rule"}
+        }
+      }
+    },
+    "/logs/sampling/{name}": {
+      "get": {
+        "summary": "Get a log sampling rule",
+        "tags": ["Observability"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Rule"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a log sampling rule",
+        "tags": ["Observability"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/observe/log-metrics": {
+      "get": {
+        "summary": "List log-derived metrics",
+        "tags": ["Observability"],
+        "responses": {
+          "200": {"description": "Log metrics with match counts and last error"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace a log-derived counter",
+        "tags": ["Observability"],
+        "description": "Increments counter name for every pushed entry whose stream labels include match and whose line matches pattern. Named groups in pattern become metric labels. Label names are fixed once the counter is recorded.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "shop_http_responses_total"},
+                  "match": {"type": "object", "additionalProperties": {"type": "string"}, "example": {"app": "shop"}},
+                  "pattern": {"type": "string", "example": "status=(?P<status>\\d{3})"},
+                  "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+                },
+                "required": ["name"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Log metric saved"},
+          "400": {"description": "Invalid name, pattern or labels"}
+        }
+      }
+    },
+    "/observe/log-metrics/{name}": {
+      "get": {
+        "summary": "Get a log-derived metric",
+        "tags": ["Observability"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Log metric"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a log-derived metric",
+        "tags": ["Observability"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/metrics": {
+      "post": {
+        "summary": "Push metric",
+        "tags": ["Observability"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string"},
+                  "value": {"type": "number"},
+                  "type": {"type": "string", "enum": ["counter", "gauge", "histogram"]},
+                  "labels": {"type": "object"},
+                  "buckets": {"type": "array", "items": {"type": "number"}, "description": "Histogram upper bounds, fixed on first push"}
+                },
+                "required": ["name", "value"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Metric pushed"}
+        }
+      }
+    },
+    "/metrics/timing": {
+      "post": {
+        "summary": "Record a duration into a histogram",
+        "tags": ["Observability"],
+        "description": "Observes duration_ms / 1000 into a histogram measured in seconds",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "checkout_duration_seconds"},
+                  "duration_ms": {"type": "number", "example": 182.5},
+                  "labels": {"type": "object"},
+                  "buckets": {"type": "array", "items": {"type": "number"}, "example": [0.05, 0.1, 0.25, 0.5, 1, 2.5], "description": "Upper bounds in seconds, fixed when the histogram is created (default Prometheus buckets)"}
+                },
+                "required": ["name", "duration_ms"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Duration recorded"},
+          "400": {"description": "Invalid name, labels or buckets"}
+        }
+      }
+    },
+    "/metrics/query": {
+      "get": {
+        "summary": "Instant PromQL query",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}, "example": "up"},
+          {"name": "time", "in": "query", "schema": {"type": "integer"}, "description": "Evaluation time (ms since epoch, default now)"}
+        ],
+        "responses": {
+          "200": {"description": "Series with a single sample each"}
+        }
+      }
+    },
+    "/metrics/query_range": {
+      "get": {
+        "summary": "Range PromQL query",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}, "example": "rate(forge_http_requests_total[5m])"},
+          {"name": "start", "in": "query", "schema": {"type": "integer"}, "description": "ms since epoch (default 1h ago)"},
+          {"name": "end", "in": "query", "schema": {"type": "integer"}, "description": "ms since epoch (default now)"},
+          {"name": "step", "in": "query", "schema": {"type": "string", "default": "1m"}}
+        ],
+        "responses": {
+          "200": {"description": "Series with samples at each step"}
+        }
+      }
+    },
+    "/profiles/ingest": {
+      "post": {
+        "summary": "Ingest a profile (Pyroscope API)",
+        "tags": ["Observability"],
+        "description": "Relays profiles to Pyroscope using its /ingest API, so profiler agents can use http://<forge>/api/v1/profiles as their server address. Requires COMPOSE_PROFILES to include profiling.",
+        "parameters": [
+          {"name": "name", "in": "query", "required": true, "schema": {"type": "string"}, "example": "checkout.cpu{env=prod}", "description": "Application name with optional labels"},
+          {"name": "from", "in": "query", "schema": {"type": "integer"}, "description": "Start of the profile, unix seconds"},
+          {"name": "until", "in": "query", "schema": {"type": "integer"}, "description": "End of the profile, unix seconds"},
+          {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["folded", "lines", "trie", "tree", "pprof", "jfr"]}},
+          {"name": "sampleRate", "in": "query", "schema": {"type": "integer"}},
+          {"name": "spyName", "in": "query", "schema": {"type": "string"}},
+          {"name": "units", "in": "query", "schema": {"type": "string"}},
+          {"name": "aggregationType", "in": "query", "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "text/plain": {
+              "schema": {"type": "string"},
+              "example": "main;handleRequest;queryDB 42"
+            },
+            "multipart/form-data": {
+              "schema": {"type": "object"}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Profile forwarded"},
+          "400": {"description": "Missing name or profile rejected by Pyroscope"},
+          "413": {"description": "Payload too large (8MB)"},
+          "503": {"description": "Pyroscope unavailable"}
+        }
+      }
+    },
+    "/metrics/snapshots": {
+      "get": {
+        "summary": "List metric snapshot definitions",
+        "tags": ["Observability"],
+        "responses": {
+          "200": {"description": "Snapshot series definitions"}
+        }
+      },
+      "post": {
+        "summary": "Add a metric snapshot",
+        "tags": ["Observability"],
+        "description": "Periodically stores the result of a PromQL query in MySQL for long-term, low-resolution history",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "api_request_rate"},
+                  "query": {"type": "string", "example": "sum(rate(forge_http_requests_total[1h]))"},
+                  "interval": {"type": "string", "example": "1h", "description": "Snapshot interval (min 5m, default 1h)"}
+                },
+                "required": ["name", "query"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Snapshot series added"}
+        }
+      }
+    },
+    "/metrics/snapshots/{name}": {
+      "get": {
+        "summary": "Get stored snapshot points",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "from", "in": "query", "schema": {"type": "integer"}, "description": "ms since epoch (default 30 days ago)"},
+          {"name": "to", "in": "query", "schema": {"type": "integer"}, "description": "ms since epoch (default now)"}
+        ],
+        "responses": {
+          "200": {"description": "Stored points grouped by labels"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a snapshot definition (stored points are kept)",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Snapshot series deleted"}
+        }
+      }
+    },
+    "/errors": {
+      "get": {
+        "summary": "List error groups",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "service", "in": "query", "schema": {"type": "string"}},
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["unresolved", "resolved", "ignored"]}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 50}}
+        ],
+        "responses": {
+          "200": {"description": "Error groups, most recently seen first"}
+        }
+      },
+      "post": {
+        "summary": "Capture an exception event",
+        "tags": ["Observability"],
+        "description": "Events are grouped by fingerprint: the service, exception type and top stack frames, or the message when there is no stack trace. A new event reopens a resolved group.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "message": {"type": "string", "example": "division by zero"},
+                  "type": {"type": "string", "example": "ZeroDivisionError"},
+                  "stacktrace": {"type": "string"},
+                  "release": {"type": "string", "example": "1.4.2"},
+                  "environment": {"type": "string", "example": "production"},
+                  "service": {"type": "string", "example": "checkout"},
+                  "level": {"type": "string", "example": "error"},
+                  "user": {"type": "object", "additionalProperties": {"type": "string"}},
+                  "tags": {"type": "object", "additionalProperties": {"type": "string"}},
+                  "fingerprint": {"type": "array", "items": {"type": "string"}, "description": "Custom grouping key"},
+                  "timestamp_ms": {"type": "integer"}
+                },
+                "required": ["message"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Event stored; returns the group fingerprint"},
+          "400": {"description": "Invalid event"}
+        }
+      }
+    },
+    "/errors/{fingerprint}": {
+      "get": {
+        "summary": "Get an error group with its recent events",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "fingerprint", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 50}}
+        ],
+        "responses": {
+          "200": {"description": "Group and events"},
+          "404": {"description": "Group not found"}
+        }
+      },
+      "patch": {
+        "summary": "Resolve, ignore or reopen an error group",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "fingerprint", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "status": {"type": "string", "enum": ["unresolved", "resolved", "ignored"]}
+                },
+                "required": ["status"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Status updated"},
+          "404": {"description": "Group not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete an error group and its events",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "fingerprint", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Group deleted"},
+          "404": {"description": "Group not found"}
+        }
+      }
+    },
+    "/flags": {
+      "get": {
+        "summary": "List feature flags",
+        "tags": ["Flags"],
+        "responses": {
+          "200": {"description": "Flags ordered by key"}
+        }
+      },
+      "post": {
+        "summary": "Create a feature flag",
+        "tags": ["Flags"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Flag"}
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Flag created"},
+          "400": {"description": "Invalid flag"},
+          "409": {"description": "A flag with this key exists"}
+        }
+      }
+    },
+    "/flags/evaluate": {
+      "get": {
+        "summary": "Evaluate feature flags",
+        "tags": ["Flags"],
+        "description": "Every query parameter other than key and flags is a targeting attribute, e.g. ?key=user-42&country=DE&plan=pro. Rules are checked in order and the first match decides; otherwise percentage and variant flags split keys by a stable hash. With Accept: text/event-stream the results come as a \"snapshot\" event, followed by a \"change\" event ({action, flag}) with the new result of each flag changed afterwards.",
+        "parameters": [
+          {"name": "key", "in": "query", "schema": {"type": "string"}, "example": "user-42", "description": "User or entity the flags are evaluated for; percentage and variant flags need it"},
+          {"name": "flags", "in": "query", "schema": {"type": "string"}, "example": "new-checkout,theme", "description": "Comma-separated flags to evaluate (default: all)"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Results by flag key: enabled, variant, reason (rule, rollout, default, disabled, missing_key, not_found) and version",
+            "content": {
+              "application/json": {},
+              "text/event-stream": {}
+            }
+          }
+        }
+      }
+    },
+    "/flags/{key}": {
+      "get": {
+        "summary": "Get a feature flag",
+        "tags": ["Flags"],
+        "parameters": [
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Flag"},
+          "404": {"description": "Flag not found"}
+        }
+      },
+      "put": {
+        "summary": "Replace a feature flag",
+        "tags": ["Flags"],
+        "parameters": [
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Flag"}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Flag updated; its version is incremented"},
+          "400": {"description": "Invalid flag"},
+          "404": {"description": "Flag not found"}
+        }
+      },
+      "patch": {
+        "summary": "Turn a feature flag on or off",
+        "tags": ["Flags"],
+        "parameters": [
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "enabled": {"type": "boolean"}
+                },
+                "required": ["enabled"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Flag updated"},
+          "404": {"description": "Flag not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a feature flag",
+        "tags": ["Flags"],
+        "parameters": [
+          {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Flag deleted"},
+          "404": {"description": "Flag not found"}
+        }
+      }
+    },
+    "/alerts": {
+      "get": {
+        "summary": "List currently firing alerts",
+        "tags": ["Alerting"],
+        "parameters": [
+          {"name": "filter", "in": "query", "schema": {"type": "string"}, "description": "Label matcher, e.g. severity=\"critical\" (repeatable)"},
+          {"name": "silenced", "in": "query", "schema": {"type": "boolean"}, "description": "Include silenced and inhibited alerts"}
+        ],
+        "responses": {
+          "200": {"description": "Alerts from Alertmanager"}
+        }
+      }
+    },
+    "/alerts/receivers": {
+      "get": {
+        "summary": "List notification receivers",
+        "tags": ["Alerting"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Configured receivers"}
+        }
+      },
+      "post": {
+        "summary": "Add or update a notification receiver",
+        "tags": ["Alerting"],
+        "description": "Admin only. Regenerates the Alertmanager config and reloads it. Email receivers require SMTP_SMARTHOST and SMTP_FROM.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "ops-slack"},
+                  "type": {"type": "string", "enum": ["slack", "discord", "email", "webhook"]},
+                  "url": {"type": "string", "description": "Slack/Discord/webhook URL"},
+                  "channel": {"type": "string", "description": "Slack channel (optional)"},
+                  "to": {"type": "string", "description": "Email recipient"},
+                  "matchers": {"type": "array", "items": {"type": "string"}, "example": ["severity=\"critical\""]},
+                  "send_resolved": {"type": "boolean"}
+                },
+                "required": ["name", "type"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Receiver saved"},
+          "400": {"description": "Invalid receiver"},
+          "502": {"description": "Receiver saved but Alertmanager reload failed"}
+        }
+      }
+    },
+    "/alerts/receivers/{name}": {
+      "get": {
+        "summary": "Get a notification receiver",
+        "tags": ["Alerting"],
+        "description": "Admin only",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Receiver"},
+          "404": {"description": "Receiver not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a notification receiver",
+        "tags": ["Alerting"],
+        "description": "Admin only",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Receiver deleted"}
+        }
+      }
+    },
+    "/alerts/silences": {
+      "get": {
+        "summary": "List silences",
+        "tags": ["Alerting"],
+        "responses": {
+          "200": {"description": "Silences from Alertmanager"}
+        }
+      },
+      "post": {
+        "summary": "Create a silence",
+        "tags": ["Alerting"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "matchers": {
+                    "type": "array",
+                    "items": {
+                      "type": "object",
+                      "properties": {
+                        "name": {"type": "string"},
+                        "value": {"type": "string"},
+                        "isRegex": {"type": "boolean"},
+                        "isEqual": {"type": "boolean"}
+                      }
+                    }
+                  },
+                  "startsAt": {"type": "string", "format": "date-time", "description": "Default now"},
+                  "endsAt": {"type": "string", "format": "date-time"},
+                  "duration": {"type": "string", "example": "2h", "description": "Used when endsAt is omitted (default 1h)"},
+                  "createdBy": {"type": "string"},
+                  "comment": {"type": "string"}
+                },
+                "required": ["matchers"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Silence created"}
+        }
+      }
+    },
+    "/alerts/silences/{id}": {
+      "delete": {
+        "summary": "Expire a silence",
+        "tags": ["Alerting"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Silence expired"}
+        }
+      }
+    },
+    "/alerts/log-rules": {
+      "get": {
+        "summary": "List log-based alert rules with their state",
+        "tags": ["Alerting"],
+        "responses": {
+          "200": {"description": "Rules with state (inactive, pending, firing)"}
+        }
+      },
+      "post": {
+        "summary": "Add or replace a log-based alert rule",
+        "tags": ["Alerting"],
+        "description": "Forge evaluates the LogQL metric expression on a schedule and sends firing/resolved alerts to Alertmanager, which routes them to the configured receivers",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "shop-errors"},
+                  "expr": {"type": "string", "example": "sum(rate({service=\"shop\"} |= \"error\" [5m]))"},
+                  "op": {"type": "string", "enum": [">", ">=", "<", "<=", "==", "!="], "default": ">"},
+                  "threshold": {"type": "number", "example": 0.5},
+                  "for": {"type": "string", "example": "5m", "description": "How long the condition must hold before firing"},
+                  "interval": {"type": "string", "example": "1m", "description": "Evaluation interval (min 15s, default 1m)"},
+                  "severity": {"type": "string", "default": "warning"},
+                  "summary": {"type": "string"},
+                  "description": {"type": "string"},
+                  "labels": {"type": "object"}
+                },
+                "required": ["name", "expr", "threshold"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Rule saved"},
+          "400": {"description": "Invalid rule"}
+        }
+      }
+    },
+    "/alerts/log-rules/{name}": {
+      "get": {
+        "summary": "Get a log-based alert rule",
+        "tags": ["Alerting"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Rule with state"},
+          "404": {"description": "Rule not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a log-based alert rule (resolves its alerts)",
+        "tags": ["Alerting"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Rule deleted"}
+        }
+      }
+    },
+    "/traces": {
+      "post": {
+        "summary": "Push trace span",
+        "tags": ["Observability"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "description": "Span name"},
+                  "trace_id": {"type": "string", "description": "Trace ID (auto-generated if empty)"},
+                  "span_id": {"type": "string", "description": "Span ID (auto-generated if empty)"},
+                  "parent_span_id": {"type": "string", "description": "Parent span ID"},
+                  "start_time_ms": {"type": "integer", "description": "Start time in milliseconds"},
+                  "duration_ms": {"type": "integer", "description": "Duration in milliseconds"},
+                  "attributes": {"type": "object", "description": "Span attributes"}
+                },
+                "required": ["name"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Trace pushed",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "ok": {"type": "boolean"},
+                    "trace_id": {"type": "string"},
+                    "span_id": {"type": "string"}
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/traces/search": {
+      "get": {
+        "summary": "Search traces",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "service", "in": "query", "schema": {"type": "string"}, "description": "Root or span service.name"},
+          {"name": "tag", "in": "query", "schema": {"type": "string"}, "description": "Span attribute as key=value (repeatable)"},
+          {"name": "q", "in": "query", "schema": {"type": "string"}, "description": "TraceQL query, overrides service and tag"},
+          {"name": "start", "in": "query", "schema": {"type": "integer"}, "description": "ms since epoch"},
+          {"name": "end", "in": "query", "schema": {"type": "integer"}, "description": "ms since epoch"},
+          {"name": "min_duration", "in": "query", "schema": {"type": "string"}, "example": "250ms"},
+          {"name": "max_duration", "in": "query", "schema": {"type": "string"}, "example": "5s"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 20, "maximum": 100}}
+        ],
+        "responses": {
+          "200": {"description": "Matching traces"}
+        }
+      }
+    },
+    "/traces/{trace_id}": {
+      "get": {
+        "summary": "Get all spans of a trace",
+        "tags": ["Observability"],
+        "parameters": [
+          {"name": "trace_id", "in": "path", "required": true, "schema": {"type": "string"}, "description": "32 hex characters"}
+        ],
+        "responses": {
+          "200": {"description": "Spans ordered by start time"},
+          "400": {"description": "Invalid trace ID"},
+          "404": {"description": "Trace not found"}
+        }
+      }
+    },
+    "/monitors": {
+      "get": {
+        "summary": "List uptime monitors",
+        "tags": ["Alerting"],
+        "responses": {
+          "200": {"description": "Monitors with state (pending, up, down), last check and uptime over recent results"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace an uptime monitor",
+        "tags": ["Alerting"],
+        "description": "Forge runs the check every interval, exports forge_monitor_up and forge_monitor_response_seconds, and sends a MonitorDown alert to Alertmanager after failure_threshold consecutive failures.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
                   "name": {"type": "string"},
-                  "value": {"type": "number"},
-                  "type": {"type": "string", "enum": ["counter", "gauge", "histogram"]},
-                  "labels": {"type": "object"}
+                  "type": {"type": "string", "enum": ["http", "tcp", "ping"]},
+                  "target": {"type": "string", "description": "URL (http), host:port (tcp) or host (ping)"},
+                  "interval": {"type": "string", "example": "1m"},
+                  "timeout": {"type": "string", "example": "10s"},
+                  "expect_status": {"type": "integer", "description": "http only; default any status below 400"},
+                  "keyword": {"type": "string", "description": "http only; must appear in the response body"},
+                  "failure_threshold": {"type": "integer", "default": 1},
+                  "severity": {"type": "string", "default": "critical"},
+                  "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+                },
+                "required": ["name", "type", "target"]
+              },
+              "example": {"name": "blog", "type": "http", "target": "https://blog.example.com/health", "interval": "30s", "failure_threshold": 2}
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Monitor saved"},
+          "400": {"description": "Invalid monitor"}
+        }
+      }
+    },
+    "/monitors/{name}": {
+      "get": {
+        "summary": "Get a monitor with its recent results",
+        "tags": ["Alerting"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Monitor state and up to 100 results, newest first"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a monitor",
+        "tags": ["Alerting"],
+        "description": "Resolves its alert and removes its metrics",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/jobs": {
+      "get": {
+        "summary": "List scheduled jobs",
+        "tags": ["Jobs"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Jobs with next run time, runs in progress and last run"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace a scheduled job",
+        "tags": ["Jobs"],
+        "description": "Admin only. Runs an action on a cron schedule (five fields, or @hourly, @daily, @weekly, @monthly, @yearly) in timezone (default UTC). When a run is still going at the next one, overlap decides: skip records the new run as skipped, allow runs both, queue starts it when the current one ends, replace cancels the current one. With Redis up, one replica runs each scheduled run. Exports forge_job_runs_total, forge_job_duration_seconds, forge_job_last_success_timestamp_seconds and forge_job_running per job. Replacing a job keeps its run history.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Job"},
+              "examples": {
+                "backup": {"value": {"name": "nightly-backup", "schedule": "30 2 * * *", "timezone": "Europe/Berlin", "action": {"type": "backup", "database": "app", "keep": 14}}},
+                "http": {"value": {"name": "warm-cache", "schedule": "*/10 * * * *", "timeout": "1m", "action": {"type": "http", "method": "POST", "url": "http://myapp:3000/internal/warm", "expect_status": 204}}},
+                "sql": {"value": {"name": "purge-sessions", "schedule": "@hourly", "action": {"type": "sql", "database": "app", "statement": "DELETE FROM sessions WHERE expires_at < NOW()"}}}
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Job saved"},
+          "400": {"description": "Invalid job"}
+        }
+      }
+    },
+    "/jobs/{name}": {
+      "get": {
+        "summary": "Get a scheduled job",
+        "tags": ["Jobs"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Job with next run time, runs in progress and last run"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "patch": {
+        "summary": "Pause or resume a job",
+        "tags": ["Jobs"],
+        "description": "Paused jobs keep their history and can still be run by hand; resuming schedules from now without catching up",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"paused": {"type": "boolean"}}, "required": ["paused"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Updated job"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a job",
+        "tags": ["Jobs"],
+        "description": "Cancels its runs in progress and removes its metrics",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/jobs/{name}/run": {
+      "post": {
+        "summary": "Run a job now",
+        "tags": ["Jobs"],
+        "description": "Starts a manual run in the background, subject to the job's overlap policy",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "202": {"description": "The run: running, queued or skipped", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/JobRun"}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/jobs/{name}/runs": {
+      "get": {
+        "summary": "List the recent runs of a job",
+        "tags": ["Jobs"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Up to 50 runs, newest first", "content": {"application/json": {"schema": {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/JobRun"}}, "count": {"type": "integer"}}}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/webhooks": {
+      "get": {
+        "summary": "List webhooks",
+        "tags": ["Webhooks"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Webhooks with pending deliveries and last delivery; secrets are never listed"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace a webhook",
+        "tags": ["Webhooks"],
+        "description": "Admin only. POSTs every event matching one of events as JSON {id, type, time, data}. Event types: route.changed, container.unhealthy, container.died, alert.fired, alert.resolved, backup.finished, job.failed, watchdog.incident, mqtt.message and webhook.test; a family such as container.* or * subscribes to several. Each delivery carries X-Forge-Event, X-Forge-Delivery and X-Forge-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of \"<t>.<body>\" keyed with the secret>. Non-2xx responses and errors are retried after 10s, 1m, 5m, 30m and 2h, then the delivery fails. Exports forge_webhook_deliveries_total per webhook and result. The secret is generated unless given, returned only when the webhook is created, and kept when it is replaced.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Webhook"},
+              "example": {"name": "ops-chat", "url": "https://hooks.example.com/forge", "events": ["container.*", "alert.fired", "backup.finished"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Webhook replaced"},
+          "201": {"description": "Webhook created, with its secret"},
+          "400": {"description": "Invalid webhook"}
+        }
+      }
+    },
+    "/webhooks/{name}": {
+      "get": {
+        "summary": "Get a webhook",
+        "tags": ["Webhooks"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Webhook with pending deliveries and last delivery"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "patch": {
+        "summary": "Disable or enable a webhook",
+        "tags": ["Webhooks"],
+        "description": "Disabled webhooks receive no new events; deliveries already pending are still attempted",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"disabled": {"type": "boolean"}}, "required": ["disabled"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Updated webhook"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a webhook",
+        "tags": ["Webhooks"],
+        "description": "Drops its pending deliveries and removes its metrics",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/webhooks/{name}/test": {
+      "post": {
+        "summary": "Send a test event",
+        "tags": ["Webhooks"],
+        "description": "Queues a webhook.test event for this webhook, even if it is disabled or not subscribed to it",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "202": {"description": "The pending delivery", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WebhookDelivery"}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/webhooks/{name}/rotate-secret": {
+      "post": {
+        "summary": "Rotate the signing secret",
+        "tags": ["Webhooks"],
+        "description": "Generates a new secret, used from the next attempt on, and returns it",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "The new secret", "content": {"application/json": {"schema": {"type": "object", "properties": {"ok": {"type": "boolean"}, "secret": {"type": "string"}}}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/webhooks/{name}/deliveries": {
+      "get": {
+        "summary": "List the recent deliveries of a webhook",
+        "tags": ["Webhooks"],
+        "description": "Kept in memory, so the log starts over when Forge restarts",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Up to 100 deliveries, newest first", "content": {"application/json": {"schema": {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/WebhookDelivery"}}, "count": {"type": "integer"}}}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/webhooks/{name}/deliveries/{id}/redeliver": {
+      "post": {
+        "summary": "Redeliver an event",
+        "tags": ["Webhooks"],
+        "description": "Queues a new delivery of the same payload, with a fresh retry schedule",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "202": {"description": "The new pending delivery", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WebhookDelivery"}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/hooks": {
+      "get": {
+        "summary": "List inbound hooks",
+        "tags": ["Hooks"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Hooks with their last receipt; secrets are never listed"}
+        }
+      },
+      "post": {
+        "summary": "Create or replace an inbound hook",
+        "tags": ["Hooks"],
+        "description": "Admin only. A hook receives webhooks from a third party at POST /hooks/{name} (outside /api/v1, through nginx or Caddy, without an API key) and fans them out to its targets: queue pushes the request as JSON {id, hook, received, headers, body} onto a Redis list, http forwards it to an internal service with the sender's headers plus X-Forge-Hook and X-Forge-Receipt, log writes it to Loki, metric increments a pushed counter. Verification: github checks X-Hub-Signature-256, stripe checks Stripe-Signature and its timestamp, hmac checks an HMAC of the body in a configurable header, none accepts anyone who knows the URL. Bad signatures are answered 401, unknown and disabled hooks 404. The sender gets 200 once every matching target succeeded and 502 when one failed, so it retries; a retry runs every matching target again, so consumers should deduplicate by the sender's delivery ID. Exports forge_hook_requests_total per hook and result. The secret of an hmac hook is generated unless given and returned only then; a hook replaced without a secret keeps its own.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/Hook"},
+              "examples": {
+                "github": {"value": {"name": "github", "verify": {"type": "github", "secret": "change-me-please"}, "targets": [
+                  {"type": "http", "when": {"header.X-GitHub-Event": "push", "body.ref": "refs/heads/main"}, "url": "http://deployer:8000/deploy"},
+                  {"type": "metric", "metric": "github_events_total", "labels": {"event": "${header.X-GitHub-Event}"}}
+                ]}},
+                "stripe": {"value": {"name": "stripe", "verify": {"type": "stripe", "secret": "whsec_..."}, "targets": [
+                  {"type": "queue", "when": {"body.type": "invoice.*"}, "key": "stripe:invoices"},
+                  {"type": "log", "message": "Stripe ${body.type}"}
+                ]}}
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Hook replaced"},
+          "201": {"description": "Hook created, with its URL and any generated secret"},
+          "400": {"description": "Invalid hook"}
+        }
+      }
+    },
+    "/hooks/{name}": {
+      "get": {
+        "summary": "Get an inbound hook",
+        "tags": ["Hooks"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Hook with its last receipt"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "patch": {
+        "summary": "Disable or enable an inbound hook",
+        "tags": ["Hooks"],
+        "description": "Disabled hooks answer 404 to their senders",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"disabled": {"type": "boolean"}}, "required": ["disabled"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Updated hook"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete an inbound hook",
+        "tags": ["Hooks"],
+        "description": "Drops its receipts and removes its metrics",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/hooks/{name}/receipts": {
+      "get": {
+        "summary": "List the recent requests of an inbound hook",
+        "tags": ["Hooks"],
+        "description": "Rejected requests included. Kept in memory, so the log starts over when Forge restarts.",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Up to 100 receipts, newest first", "content": {"application/json": {"schema": {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/HookReceipt"}}, "count": {"type": "integer"}}}}}},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/grafana/datasources": {
+      "get": {
+        "summary": "List Grafana datasources",
+        "tags": ["Observability"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "Datasources"}
+        }
+      },
+      "post": {
+        "summary": "Create a Grafana datasource",
+        "tags": ["Observability"],
+        "description": "Admin only. Provision a datasource for a registered database or an external Prometheus/Loki/Tempo instance",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "uid": {"type": "string", "example": "orders-db"},
+                  "name": {"type": "string", "example": "Orders DB"},
+                  "type": {"type": "string", "enum": ["prometheus", "loki", "tempo", "mysql", "postgres"]},
+                  "url": {"type": "string", "example": "mysql:3306"},
+                  "access": {"type": "string", "default": "proxy"},
+                  "database": {"type": "string"},
+                  "user": {"type": "string"},
+                  "password": {"type": "string", "description": "Stored in secureJsonData, never returned"},
+                  "isDefault": {"type": "boolean"},
+                  "jsonData": {"type": "object"}
+                },
+                "required": ["uid", "name", "type", "url"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Datasource created"},
+          "400": {"description": "Invalid datasource"},
+          "502": {"description": "Grafana rejected the request or is unreachable"}
+        }
+      }
+    },
+    "/grafana/datasources/{uid}": {
+      "get": {
+        "summary": "Get a Grafana datasource",
+        "tags": ["Observability"],
+        "description": "Admin only",
+        "parameters": [
+          {"name": "uid", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Datasource"},
+          "404": {"description": "Datasource not found"}
+        }
+      },
+      "put": {
+        "summary": "Update a Grafana datasource",
+        "tags": ["Observability"],
+        "description": "Admin only",
+        "parameters": [
+          {"name": "uid", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Datasource updated"},
+          "404": {"description": "Datasource not found"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a Grafana datasource",
+        "tags": ["Observability"],
+        "description": "Admin only",
+        "parameters": [
+          {"name": "uid", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Datasource deleted"},
+          "404": {"description": "Datasource not found"}
+        }
+      }
+    },
+    "/routes": {
+      "get": {
+        "summary": "List all dynamic routes",
+        "tags": ["Routes"],
+        "responses": {
+          "200": {
+            "description": "List of routes"
+          }
+        }
+      },
+      "post": {
+        "summary": "Add a dynamic route",
+        "tags": ["Routes"],
+        "description": "Creates or updates a route and reloads nginx. Routes referencing ${secret.NAME} need the admin role, as the resolved value is sent to the target; this also applies to bulk apply and import.",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "my-backend"},
+                  "path": {"type": "string", "example": "/myapp/"},
+                  "match_type": {"type": "string", "enum": ["prefix", "exact", "regex"], "default": "prefix", "description": "exact emits location = path; regex emits location ~ \"path\", e.g. ^/api/v2/users/[0-9]+$ (strip_prefix and probes unsupported)"},
+                  "target": {"type": "string", "example": "http://my-service:8000", "description": "May reference ${secret.NAME} (admin role only) or ${service.NAME.FIELD} (address, scheme, host, port or id of the service's first passing instance), e.g. http://${service.orders.host}:${service.orders.port}"},
+                  "strip_prefix": {"type": "boolean"},
+                  "protocol": {"type": "string", "enum": ["http", "grpc"], "default": "http", "description": "grpc proxies gRPC/Connect over HTTP/2 with grpc_pass; target may be grpc://, grpcs://, http:// or https://"},
+                  "host": {"type": "string", "example": "app.home.lan", "description": "Serve the route only on this host name (virtual host, own nginx server block); HTTPS is used if a certificate covering it exists"},
+                  "domain": {"type": "string", "example": "app.example.com", "description": "Serve the route only on this host; a Let's Encrypt certificate is requested automatically and the route moves to HTTPS once issued"},
+                  "headers": {"type": "object", "example": {"Authorization": "Bearer ${secret.api_token}"}, "description": "Extra request headers sent upstream; values are literal apart from ${...} variables and must not contain control characters once resolved"},
+                  "labels": {"type": "object", "example": {"team": "payments"}},
+                  "probe": {
+                    "type": "object",
+                    "description": "Health-check the route through nginx; results appear in /system, forge_route_up and /monitors (as route-{name})",
+                    "properties": {
+                      "path": {"type": "string", "example": "health"},
+                      "interval": {"type": "string", "example": "1m"},
+                      "expect_status": {"type": "integer"}
+                    }
+                  },
+                  "canary": {
+                    "type": "object",
+                    "description": "Send a share of clients (by IP, nginx split_clients) to a second target for gradual rollouts; neither target may include a path",
+                    "properties": {
+                      "target": {"type": "string", "example": "http://my-service-v2:8000"},
+                      "weight": {"type": "integer", "example": 10, "description": "Percent of clients, 0-100"}
+                    },
+                    "required": ["target", "weight"]
+                  },
+                  "read_timeout": {"type": "string", "example": "5m", "description": "nginx proxy_read_timeout (1s to 24h) for long-polling or slow backends"},
+                  "max_body_size": {"type": "string", "example": "100m", "description": "nginx client_max_body_size; 0 disables the limit"},
+                  "buffering": {"type": "boolean", "description": "nginx proxy_buffering; false streams responses (SSE, long polling)"},
+                  "access_log": {"type": "boolean", "description": "Write a JSON access log for the route, shipped to Loki with a route label (nginx backend only)"},
+                  "websocket": {
+                    "type": "object",
+                    "description": "Allow WebSocket upgrades; without it the Upgrade/Connection headers are not passed upstream. Routes saved before this option existed get an empty websocket object when loaded.",
+                    "properties": {
+                      "read_timeout": {"type": "string", "example": "1h", "description": "nginx proxy_read_timeout for open sockets (1s to 24h, default 60s)"},
+                      "send_timeout": {"type": "string", "example": "1h", "description": "nginx proxy_send_timeout for open sockets (1s to 24h, default 60s)"}
+                    }
+                  },
+                  "rate_limit": {
+                    "type": "object",
+                    "description": "Throttle requests per client IP (nginx limit_req); excess requests get 429",
+                    "properties": {
+                      "rps": {"type": "integer", "example": 10, "description": "Sustained requests per second"},
+                      "burst": {"type": "integer", "example": 20, "description": "Extra requests accepted in a spike"}
+                    },
+                    "required": ["rps"]
+                  }
                 },
-                "required": ["name", "value"]
+                "required": ["name", "path", "target"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "Route added and nginx reloaded"}
+        }
+      }
+    },
+    "/routes/{name}": {
+      "delete": {
+        "summary": "Delete a route",
+        "tags": ["Routes"],
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Route deleted"}
+        }
+      }
+    },
+    "/routes/{name}/canary": {
+      "put": {
+        "summary": "Shift canary traffic",
+        "tags": ["Routes"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"weight": {"type": "integer", "description": "Percent of clients, 0-100"}}, "required": ["weight"]},
+              "example": {"weight": 50}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Weight saved and nginx reloaded"},
+          "400": {"description": "Invalid weight or route has no canary"},
+          "404": {"description": "Route not found"}
+        }
+      },
+      "delete": {
+        "summary": "Remove a canary",
+        "tags": ["Routes"],
+        "description": "Sends all traffic back to the primary target",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Canary removed"},
+          "404": {"description": "Route not found"}
+        }
+      }
+    },
+    "/routes/{name}/canary/promote": {
+      "post": {
+        "summary": "Promote a canary",
+        "tags": ["Routes"],
+        "description": "Makes the canary target the route's only target",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Canary promoted"},
+          "404": {"description": "Route not found"}
+        }
+      }
+    },
+    "/routes/bulk": {
+      "post": {
+        "summary": "Apply many routes as a background operation",
+        "tags": ["Routes"],
+        "description": "Validates all routes, then applies them with a single nginx reload. Poll the returned operation for progress.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "routes": {"type": "array", "items": {"type": "object"}}
+                },
+                "required": ["routes"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": {"description": "Operation started (see Location header)"}
+        }
+      }
+    },
+    "/routes/export": {
+      "get": {
+        "summary": "Export all routes",
+        "tags": ["Routes"],
+        "description": "Returns the full routes file for backup or migration to another Forge host. Variable references are exported unresolved.",
+        "parameters": [
+          {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["yaml", "json"], "default": "yaml"}}
+        ],
+        "responses": {
+          "200": {"description": "Routes file ({version, routes: [...]})"}
+        }
+      }
+    },
+    "/routes/import": {
+      "post": {
+        "summary": "Import a routes file",
+        "tags": ["Routes"],
+        "description": "Validates every route, then applies them with a single save and nginx reload. Nothing is applied if any route is invalid or has an unknown field.",
+        "parameters": [
+          {"name": "mode", "in": "query", "schema": {"type": "string", "enum": ["merge", "replace"], "default": "merge"}, "description": "replace removes routes not in the file"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/yaml": {"schema": {"type": "string"}},
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "version": {"type": "integer", "description": "Routes file format, as exported"},
+                  "routes": {"type": "array", "items": {"type": "object"}}
+                },
+                "required": ["routes"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Routes imported and nginx reloaded"},
+          "400": {"description": "Invalid routes file"}
+        }
+      }
+    },
+    "/routes/preview": {
+      "post": {
+        "summary": "Preview the nginx config of a route",
+        "tags": ["Routes"],
+        "description": "Validates a proposed route (same body as POST /routes) and returns the location block it would generate, plus the current block of a route with the same name. Nothing is saved and nginx is not reloaded; ${secret.X} references are shown unresolved.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object"},
+              "example": {"name": "my-backend", "path": "/myapp/", "target": "http://my-service:8000", "rate_limit": {"rps": 10}}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "valid, error, normalized route, host, config and current"}
+        }
+      }
+    },
+    "/routes/reload": {
+      "post": {
+        "summary": "Force nginx reload",
+        "tags": ["Routes"],
+        "responses": {
+          "200": {"description": "nginx reloaded"}
+        }
+      }
+    },
+    "/certs": {
+      "get": {
+        "summary": "List managed TLS certificates",
+        "tags": ["Routes"],
+        "responses": {
+          "200": {"description": "Certificates with status (pending, valid, failed), expiry and last error"}
+        }
+      },
+      "post": {
+        "summary": "Request a TLS certificate",
+        "tags": ["Routes"],
+        "description": "Obtains a certificate from the ACME CA (Let's Encrypt by default) in the background and renews it 30 days before expiry. Routes with a matching domain are served over HTTPS once it is issued. Wildcards require dns-01.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "domain": {"type": "string", "example": "app.example.com"},
+                  "challenge": {"type": "string", "enum": ["http-01", "dns-01"], "default": "http-01", "description": "http-01 is answered through nginx on port 80; dns-01 needs CLOUDFLARE_API_TOKEN"}
+                },
+                "required": ["domain"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "202": {"description": "Certificate requested"},
+          "400": {"description": "Invalid domain or challenge"}
+        }
+      }
+    },
+    "/certs/{domain}": {
+      "get": {
+        "summary": "Get a managed certificate",
+        "tags": ["Routes"],
+        "parameters": [{"name": "domain", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Certificate"},
+          "404": {"description": "Not found"}
+        }
+      },
+      "delete": {
+        "summary": "Stop managing a certificate",
+        "tags": ["Routes"],
+        "description": "Deletes the certificate files; routes on the domain fall back to HTTP",
+        "parameters": [{"name": "domain", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Deleted"},
+          "404": {"description": "Not found"}
+        }
+      }
+    },
+    "/certs/{domain}/renew": {
+      "post": {
+        "summary": "Renew a certificate now",
+        "tags": ["Routes"],
+        "parameters": [{"name": "domain", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Certificate issued"},
+          "404": {"description": "Not found"},
+          "502": {"description": "The ACME order failed"}
+        }
+      }
+    },
+    "/system": {
+      "get": {
+        "summary": "Get detailed system status",
+        "tags": ["System"],
+        "description": "Returns container and host stats including CPU, memory, load, disk, network, uptime, and recommendations. Forge containers are always included (forge: true); others per SYSTEM_CONTAINERS and SYSTEM_CONTAINER_LABELS unless overridden by containers or label.",
+        "parameters": [
+          {"name": "containers", "in": "query", "schema": {"type": "string", "enum": ["forge", "all"]}, "description": "all to include every container"},
+          {"name": "label", "in": "query", "schema": {"type": "string"}, "example": "com.docker.compose.project=myapp", "description": "Also include containers matching this label selector; repeatable"}
+        ],
+        "responses": {
+          "200": {
+            "description": "System information",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "timestamp": {"type": "string"},
+                    "total_containers": {"type": "integer"},
+                    "running_count": {"type": "integer"},
+                    "containers": {"type": "object", "description": "Container stats by name, with health (status starting/healthy/unhealthy, failing_streak, last_check, last_exit_code, last_output) when the container has a HEALTHCHECK, restart_count, and gpus (index, uuid, utilization_percent of the device, memory_mb of the container's processes) for containers using NVIDIA GPUs"},
+                    "host": {
+                      "type": "object",
+                      "description": "Host usage, refreshed every 15s",
+                      "properties": {
+                        "cpu_percent": {"type": "number"},
+                        "cpus": {"type": "integer"},
+                        "memory_total_mb": {"type": "number"},
+                        "memory_used_mb": {"type": "number"},
+                        "memory_percent": {"type": "number"},
+                        "load1": {"type": "number"},
+                        "load5": {"type": "number"},
+                        "load15": {"type": "number"},
+                        "disks": {"type": "array", "items": {"type": "object", "properties": {"mount": {"type": "string"}, "fs_type": {"type": "string"}, "total_gb": {"type": "number"}, "used_gb": {"type": "number"}, "percent": {"type": "number"}}}},
+                        "network": {"type": "array", "items": {"type": "object", "properties": {"interface": {"type": "string"}, "rx_bytes_per_sec": {"type": "number"}, "tx_bytes_per_sec": {"type": "number"}}}}
+                      }
+                    },
+                    "gpus": {
+                      "type": "object",
+                      "description": "NVIDIA GPUs, when GPU_DCGM_URL is set or nvidia-smi is available",
+                      "properties": {
+                        "source": {"type": "string", "enum": ["dcgm", "nvidia-smi"]},
+                        "devices": {"type": "array", "items": {"type": "object", "properties": {"index": {"type": "integer"}, "uuid": {"type": "string"}, "name": {"type": "string"}, "utilization_percent": {"type": "number"}, "memory_used_mb": {"type": "number"}, "memory_total_mb": {"type": "number"}, "temperature_c": {"type": "number"}}}}
+                      }
+                    },
+                    "recommendations": {
+                      "type": "array",
+                      "items": {
+                        "type": "object",
+                        "properties": {
+                          "severity": {"type": "string", "enum": ["info", "warning", "critical"]},
+                          "resource": {"type": "string", "enum": ["state", "health", "memory", "cpu", "gpu", "disk", "clock", "route"]},
+                          "container": {"type": "string", "description": "Container concerned; empty for the host"},
+                          "target": {"type": "string", "description": "Disk mount, route, time source or GPU UUID concerned"},
+                          "action": {"type": "string", "enum": ["none", "start_container", "check_logs", "increase_memory_limit", "monitor", "scale", "free_disk", "enable_ntp", "check_time_sync", "check_route", "check_memory_growth"]},
+                          "value": {"type": "number", "description": "Measured value (percent, ms of clock skew or failed checks)"},
+                          "threshold": {"type": "number", "description": "Threshold the value crossed"},
+                          "message": {"type": "string", "example": "🔴 forge-mysql memory usage is critical (85%). Consider increasing memory limit (PUT /api/v1/system/limits/forge-mysql)."}
+                        }
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/system/clock": {
+      "get": {
+        "summary": "Clock skew diagnostics",
+        "tags": ["System"],
+        "description": "Host NTP sync status and measured clock skew against MySQL, Loki and Tempo",
+        "responses": {
+          "200": {"description": "Clock report"}
+        }
+      }
+    },
+    "/system/disk": {
+      "get": {
+        "summary": "Docker disk usage",
+        "tags": ["System"],
+        "description": "Sizes of images, containers, volumes and build cache from Docker's /system/df, with the space a prune could reclaim. Volumes and images are sorted largest first.",
+        "responses": {
+          "200": {"description": "Disk usage (images_mb, volumes_mb, *_reclaimable_mb, images, volumes)"},
+          "502": {"description": "Docker API error"}
+        }
+      }
+    },
+    "/system/disk/prune": {
+      "post": {
+        "summary": "Prune unused Docker objects",
+        "tags": ["System"],
+        "description": "Admin only. Removes stopped containers, dangling images, unused anonymous volumes, unused build cache or unused networks. With all, unused tagged images and unused named volumes are removed too; named volumes of stopped services lose their data.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "targets": {"type": "array", "items": {"type": "string", "enum": ["containers", "images", "volumes", "build_cache", "networks"]}},
+                  "all": {"type": "boolean", "default": false}
+                },
+                "required": ["targets"]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "Prune results per target and total reclaimed_mb and reclaimed_bytes"},
+          "400": {"description": "No or invalid targets"},
+          "409": {"description": "An upgrade is running"},
+          "502": {"description": "Docker API error; results of earlier targets are included"}
+        }
+      }
+    },
+    "/system/prune": {
+      "post": {
+        "summary": "Clean up unused Docker objects",
+        "tags": ["System"],
+        "description": "Admin only. Removes stopped containers, then dangling images and unused networks. Unused anonymous volumes are removed only with volumes: true. Refused while an upgrade runs, since it keeps the old containers stopped for rollback.",
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "volumes": {"type": "boolean", "default": false, "description": "Also prune unused anonymous volumes"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "results per target (target, deleted, reclaimed_mb, reclaimed_bytes), reclaimed_mb and reclaimed_bytes"},
+          "409": {"description": "An upgrade is running"},
+          "502": {"description": "Docker API error; results of earlier targets are included"}
+        }
+      }
+    },
+    "/system/events": {
+      "get": {
+        "summary": "Container events",
+        "tags": ["System"],
+        "description": "Recent start, stop, die, oom, healthy and unhealthy events of forge containers, oldest first. With Accept: text/event-stream the recent events are sent as Server-Sent Events named event, followed by new ones as they happen. Events are also pushed to Loki as {job=\"docker_events\", container, event, service}.",
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 100}, "description": "Maximum recent events (0 for all kept, up to 500)"}
+        ],
+        "responses": {
+          "200": {"description": "Events (time, action, container, service, image, exit_code), or an SSE stream"},
+          "400": {"description": "Invalid limit"}
+        }
+      }
+    },
+    "/system/watchdog": {
+      "get": {
+        "summary": "Get the container watchdog",
+        "tags": ["System"],
+        "description": "Admin only. The watchdog restarts forge containers that exit after running or report unhealthy, waiting backoff (doubling up to max_backoff) between attempts and giving up after max_restarts in a row. Containers stopped through Docker are left alone until they start again. Returns the policy, containers being restarted and the latest 200 incidents, newest first.",
+        "responses": {
+          "200": {"description": "Policy, containers (name, restarts, last_restart, next_attempt, gave_up) and incidents (time, container, reason, action, attempt, error)"},
+          "503": {"description": "Watchdog failed to start"}
+        }
+      },
+      "put": {
+        "summary": "Set the watchdog policy",
+        "tags": ["System"],
+        "description": "Admin only. Replaces and saves the policy. Containers the watchdog gave up on are tried again.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "enabled": {"type": "boolean"},
+                  "interval": {"type": "string", "default": "30s"},
+                  "user_containers": {"type": "boolean", "description": "Also watch containers selected by SYSTEM_CONTAINERS and SYSTEM_CONTAINER_LABELS"},
+                  "ignore_unhealthy": {"type": "boolean", "description": "Only restart exited containers"},
+                  "max_restarts": {"type": "integer", "default": 5, "description": "Restarts in a row before giving up"},
+                  "backoff": {"type": "string", "default": "10s"},
+                  "max_backoff": {"type": "string", "default": "5m"},
+                  "exclude": {"type": "array", "items": {"type": "string"}}
+                }
               }
             }
           }
         },
         "responses": {
-          "200": {"description": "Metric pushed"}
+          "200": {"description": "Watchdog status"},
+          "400": {"description": "Invalid policy"}
         }
       }
     },
-    "/traces": {
-      "post": {
-        "summary": "Push trace span",
-        "tags": ["Observability"],
+    "/system/history": {
+      "get": {
+        "summary": "Get usage history",
+        "tags": ["System"],
+        "description": "Host and container CPU and memory usage sampled every 5 minutes into MySQL and kept for 90 days, averaged per step. Requires MySQL.",
+        "parameters": [
+          {"name": "from", "in": "query", "schema": {"type": "integer"}, "description": "Start, ms since epoch (default 24 hours ago)"},
+          {"name": "to", "in": "query", "schema": {"type": "integer"}, "description": "End, ms since epoch (default now)"},
+          {"name": "step", "in": "query", "schema": {"type": "string"}, "example": "1h", "description": "Averaging step, at least 5m; by default at most 500 points per series"},
+          {"name": "kind", "in": "query", "schema": {"type": "string", "enum": ["host", "container"]}},
+          {"name": "name", "in": "query", "schema": {"type": "string"}, "example": "forge-mysql", "description": "Container name, or host"}
+        ],
+        "responses": {
+          "200": {"description": "from, to and series (kind, name, points of timestamp_ms, cpu_percent, memory_mb, memory_percent)"},
+          "400": {"description": "Invalid range, step or kind"}
+        }
+      }
+    },
+    "/system/history/trends": {
+      "get": {
+        "summary": "Get usage trends",
+        "tags": ["System"],
+        "description": "Compares the average memory and CPU usage on the first and last day of the window for the host and every container sampled over the whole window. The 7-day trends are also added to system info, with a recommendation when memory grew 20% or more or CPU usage rose by half.",
+        "parameters": [
+          {"name": "days", "in": "query", "schema": {"type": "integer", "default": 7, "minimum": 2, "maximum": 90}}
+        ],
+        "responses": {
+          "200": {"description": "days, count and trends (kind, name, days, memory_start_mb, memory_end_mb, memory_change_percent, cpu_start_percent, cpu_end_percent)"},
+          "400": {"description": "Invalid days"}
+        }
+      }
+    },
+    "/system/limits": {
+      "get": {
+        "summary": "List container resource limits",
+        "tags": ["System"],
+        "description": "Admin only. Memory and CPU limits of the forge containers, the containers selected by SYSTEM_CONTAINERS and SYSTEM_CONTAINER_LABELS, and any container with stored limits. current is what the container runs with (empty when unlimited); stored is reapplied whenever the container starts, so limits survive recreation.",
+        "responses": {
+          "200": {"description": "containers (container, state, current, stored) and count"},
+          "502": {"description": "Docker error"},
+          "503": {"description": "Resource limits failed to start"}
+        }
+      }
+    },
+    "/system/limits/{container}": {
+      "parameters": [
+        {"name": "container", "in": "path", "required": true, "schema": {"type": "string"}, "example": "forge-grafana"}
+      ],
+      "get": {
+        "summary": "Get a container's resource limits",
+        "tags": ["System"],
+        "description": "Admin only",
+        "responses": {
+          "200": {"description": "container, state, current and stored limits"},
+          "404": {"description": "Container not found"}
+        }
+      },
+      "put": {
+        "summary": "Set a container's resource limits",
+        "tags": ["System"],
+        "description": "Admin only. Updates the limits of the container in place (no restart) and stores them. An omitted field keeps the current value. Swap is allowed up to the memory limit again, as with docker run --memory.",
         "requestBody": {
           "required": true,
           "content": {
@@ -289,52 +3304,70 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
               "schema": {
                 "type": "object",
                 "properties": {
-                  "name": {"type": "string", "description": "Span name"},
-                  "trace_id": {"type": "string", "description": "Trace ID (auto-generated if empty)"},
-                  "span_id": {"type": "string", "description": "Span ID (auto-generated if empty)"},
-                  "parent_span_id": {"type": "string", "description": "Parent span ID"},
-                  "start_time_ms": {"type": "integer", "description": "Start time in milliseconds"},
-                  "duration_ms": {"type": "integer", "description": "Duration in milliseconds"},
-                  "attributes": {"type": "object", "description": "Span attributes"}
-                },
-                "required": ["name"]
+                  "memory": {"type": "string", "example": "512m", "description": "Size with unit b, k, m or g; at least 6m"},
+                  "cpus": {"type": "number", "example": 1.5}
+                }
               }
             }
           }
         },
         "responses": {
-          "200": {
-            "description": "Trace pushed",
-            "content": {
-              "application/json": {
-                "schema": {
-                  "type": "object",
-                  "properties": {
-                    "ok": {"type": "boolean"},
-                    "trace_id": {"type": "string"},
-                    "span_id": {"type": "string"}
-                  }
+          "200": {"description": "Updated limits"},
+          "400": {"description": "Invalid limits"},
+          "404": {"description": "Container not found"},
+          "502": {"description": "Docker rejected the update"}
+        }
+      },
+      "delete": {
+        "summary": "Forget a container's stored limits",
+        "tags": ["System"],
+        "description": "Admin only. Stops reapplying the limits. Docker cannot lift a limit in place, so the container keeps it until it is recreated.",
+        "responses": {
+          "200": {"description": "Stored limits removed"},
+          "404": {"description": "No stored limits for the container"}
+        }
+      }
+    },
+    "/system/upgrade": {
+      "post": {
+        "summary": "Upgrade the stack",
+        "tags": ["System"],
+        "description": "Admin only. Pulls the images of running forge services, then recreates the containers whose image changed one at a time, dependencies first. Each new container must pass its healthcheck (or stay running when it has none); otherwise the old container is restored and the upgrade stops. The API container is not recreated. The operation result lists each service with status upgraded, current, skipped, rolled_back, failed or not_run.",
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "services": {"type": "array", "items": {"type": "string"}, "description": "Compose services or container names to upgrade; all when omitted"}
                 }
               }
             }
           }
+        },
+        "responses": {
+          "202": {"description": "Operation started (see Location header)"},
+          "400": {"description": "Invalid JSON"},
+          "409": {"description": "An upgrade is already running"}
         }
       }
     },
-    "/routes": {
+    "/images": {
       "get": {
-        "summary": "List all dynamic routes",
-        "tags": ["Routes"],
+        "summary": "List images",
+        "tags": ["Images"],
+        "description": "Local Docker images, newest first, with the forge containers using each. Admin only, like the rest of /images.",
         "responses": {
-          "200": {
-            "description": "List of routes"
-          }
+          "200": {"description": "Images (id, tags, digests, size_mb, created, containers)"}
         }
-      },
+      }
+    },
+    "/images/pull": {
       "post": {
-        "summary": "Add a dynamic route",
-        "tags": ["Routes"],
-        "description": "Creates or updates a route and reloads nginx",
+        "summary": "Pull an image",
+        "tags": ["Images"],
+        "description": "Pulls a public image from its registry; waits until the pull completes",
         "requestBody": {
           "required": true,
           "content": {
@@ -342,65 +3375,167 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
               "schema": {
                 "type": "object",
                 "properties": {
-                  "name": {"type": "string", "example": "my-backend"},
-                  "path": {"type": "string", "example": "/myapp/"},
-                  "target": {"type": "string", "example": "http://my-service:8000"},
-                  "strip_prefix": {"type": "boolean"}
+                  "image": {"type": "string", "example": "grafana/loki:2.9.0"}
                 },
-                "required": ["name", "path", "target"]
+                "required": ["image"]
               }
             }
           }
         },
         "responses": {
-          "201": {"description": "Route added and nginx reloaded"}
+          "200": {"description": "Image pulled"},
+          "502": {"description": "Pull failed"}
         }
       }
     },
-    "/routes/{name}": {
+    "/images/updates": {
+      "get": {
+        "summary": "Check for image updates",
+        "tags": ["Images"],
+        "description": "Compares the image digest of each running forge container with the digest its tag has in the registry",
+        "responses": {
+          "200": {"description": "Per-container local_digest, remote_digest and update_available, plus updates_available count"}
+        }
+      }
+    },
+    "/images/{ref}": {
       "delete": {
-        "summary": "Delete a route",
-        "tags": ["Routes"],
+        "summary": "Remove an image",
+        "tags": ["Images"],
         "parameters": [
-          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+          {"name": "ref", "in": "path", "required": true, "description": "Image ID or reference, e.g. grafana/loki:2.9.0", "schema": {"type": "string"}},
+          {"name": "force", "in": "query", "description": "Remove even if containers use it", "schema": {"type": "boolean"}}
         ],
         "responses": {
-          "200": {"description": "Route deleted"}
+          "200": {"description": "Image removed"},
+          "404": {"description": "Image not found"},
+          "409": {"description": "Image in use or removal failed"}
         }
       }
     },
-    "/routes/reload": {
+    "/apps": {
+      "get": {
+        "summary": "List apps",
+        "tags": ["Apps"],
+        "description": "Containers deployed through /apps, with their route and log source. Admin only, like the rest of /apps.",
+        "responses": {
+          "200": {"description": "Apps (name, container, image, state, status, route, log_source)"}
+        }
+      },
       "post": {
-        "summary": "Force nginx reload",
-        "tags": ["Routes"],
+        "summary": "Deploy an app",
+        "tags": ["Apps"],
+        "description": "Pulls the image if missing, then creates and starts a container named after the app on forge-net. route registers a route named after the app to http://<name>:<port>; logs ships its container logs to Loki as {app=\"<name>\"} through a log source named app-<name>. Anything created is removed again if a step fails.",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "example": "whoami"},
+                  "image": {"type": "string", "example": "traefik/whoami:latest"},
+                  "command": {"type": "array", "items": {"type": "string"}},
+                  "env": {"type": "object", "additionalProperties": {"type": "string"}},
+                  "ports": {"type": "array", "items": {"type": "object", "properties": {"container": {"type": "integer"}, "host": {"type": "integer", "description": "Publish on this host port"}, "protocol": {"type": "string", "enum": ["tcp", "udp"]}}}},
+                  "volumes": {"type": "array", "items": {"type": "object", "properties": {"source": {"type": "string", "description": "Volume name, or an absolute host path under APPS_VOLUME_ROOT (host paths are refused when it is unset)"}, "target": {"type": "string"}, "read_only": {"type": "boolean"}}}},
+                  "restart": {"type": "string", "enum": ["no", "always", "unless-stopped", "on-failure"], "default": "unless-stopped"},
+                  "route": {"type": "object", "properties": {"path": {"type": "string", "example": "/whoami/"}, "port": {"type": "integer", "description": "Container port; default the first port or 80"}, "host": {"type": "string"}, "domain": {"type": "string"}, "strip_prefix": {"type": "boolean"}, "websocket": {"type": "boolean"}}},
+                  "logs": {"type": "boolean"}
+                },
+                "required": ["name", "image"]
+              }
+            }
+          }
+        },
         "responses": {
-          "200": {"description": "nginx reloaded"}
+          "202": {"description": "Operation started (see Location header); its result is the deployed app"},
+          "400": {"description": "Invalid app"},
+          "409": {"description": "App, route or log source already exists"}
         }
       }
     },
-    "/system": {
+    "/apps/templates": {
       "get": {
-        "summary": "Get detailed system status",
-        "tags": ["System"],
-        "description": "Returns container stats including CPU, memory, network, uptime, and recommendations",
+        "summary": "List app templates",
+        "tags": ["Apps"],
+        "description": "The one-click catalog: YAML files in data/apps/templates, each an app with defaults. {name} in env values, volume sources and the route path is replaced by the app name.",
         "responses": {
-          "200": {
-            "description": "System information",
-            "content": {
-              "application/json": {
-                "schema": {
-                  "type": "object",
-                  "properties": {
-                    "timestamp": {"type": "string"},
-                    "total_containers": {"type": "integer"},
-                    "running_count": {"type": "integer"},
-                    "containers": {"type": "object"},
-                    "recommendations": {"type": "array", "items": {"type": "string"}}
-                  }
+          "200": {"description": "Templates (name, description, website, app)"}
+        }
+      }
+    },
+    "/apps/templates/{name}": {
+      "get": {
+        "summary": "Get an app template",
+        "tags": ["Apps"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "Template"},
+          "404": {"description": "Template not found"}
+        }
+      }
+    },
+    "/apps/templates/{name}/deploy": {
+      "post": {
+        "summary": "Deploy an app template",
+        "tags": ["Apps"],
+        "description": "Deploys the template like POST /apps. The body is optional. Templates serving from / need route.host or route.domain.",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "requestBody": {
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string", "description": "App name; default the template name"},
+                  "image": {"type": "string", "description": "Replaces the template image, e.g. to pin a tag"},
+                  "env": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Merged over the template env"},
+                  "route": {"type": "object", "properties": {"path": {"type": "string"}, "host": {"type": "string"}, "domain": {"type": "string"}}},
+                  "logs": {"type": "boolean"}
                 }
               }
             }
           }
+        },
+        "responses": {
+          "202": {"description": "Operation started (see Location header); its result is the deployed app"},
+          "400": {"description": "Invalid app"},
+          "404": {"description": "Template not found"},
+          "409": {"description": "App, route or log source already exists"}
+        }
+      }
+    },
+    "/apps/{name}": {
+      "get": {
+        "summary": "Get an app",
+        "tags": ["Apps"],
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "App"},
+          "404": {"description": "App not found"}
+        }
+      },
+      "delete": {
+        "summary": "Remove an app",
+        "tags": ["Apps"],
+        "description": "Stops and removes the container with its route and log source. Volumes are kept.",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "App removed"},
+          "404": {"description": "App not found"}
+        }
+      }
+    },
+    "/system/stream": {
+      "get": {
+        "summary": "Stream container stats",
+        "tags": ["System"],
+        "description": "Server-Sent Events: a snapshot event with the same body as GET /system, then a container event for each stats sample (about every second per running container) or state change. Removed containers are sent with state removed.",
+        "responses": {
+          "200": {"description": "text/event-stream of snapshot and container events"}
         }
       }
     },
@@ -429,7 +3564,7 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
       "post": {
         "summary": "Add a log source",
         "tags": ["Log Sources"],
-        "description": "Adds a custom log file path to Promtail and reloads",
+        "description": "Adds a custom log file path, or a set of Docker containers, to Promtail and reloads",
         "requestBody": {
           "required": true,
           "content": {
@@ -438,10 +3573,46 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
                 "type": "object",
                 "properties": {
                   "name": {"type": "string", "example": "myapp"},
-                  "path": {"type": "string", "example": "/var/log/myapp/*.log"},
+                  "type": {"type": "string", "enum": ["file", "docker", "kubernetes"], "default": "file", "description": "file reads path; docker reads container stdout/stderr via the Docker socket; kubernetes reads pod logs from /var/log/pods of a node on this host"},
+                  "path": {"type": "string", "example": "/var/log/myapp/*.log", "description": "Required for file sources"},
+                  "containers": {"type": "array", "items": {"type": "string"}, "example": ["nextcloud"], "description": "Docker sources: container names (substring or regex); any may match"},
+                  "container_labels": {"type": "object", "example": {"com.docker.compose.project": "media"}, "description": "Docker sources: container labels that must all match"},
+                  "namespaces": {"type": "array", "items": {"type": "string"}, "example": ["default"], "description": "Kubernetes sources: namespaces to read; default all"},
+                  "pod_labels": {"type": "object", "example": {"app": "web"}, "description": "Kubernetes sources: pod labels that must all match"},
+                  "kubeconfig": {"type": "string", "example": "/etc/promtail/kubeconfig", "description": "Kubernetes sources: kubeconfig file as seen by Promtail"},
+                  "relabel": {
+                    "type": "array",
+                    "description": "Label rules applied in order; streams may carry at most 15 labels (Loki default)",
+                    "items": {
+                      "type": "object",
+                      "properties": {
+                        "action": {"type": "string", "enum": ["add", "drop", "rename"]},
+                        "label": {"type": "string", "example": "team"},
+                        "value": {"type": "string", "example": "payments", "description": "add: label value"},
+                        "from": {"type": "string", "example": "__meta_docker_container_label_app", "description": "rename: label to move, including discovered __meta_ labels"}
+                      },
+                      "required": ["action", "label"]
+                    }
+                  },
+                  "stages": {
+                    "type": "array",
+                    "description": "Promtail pipeline stages run in order; multiline must come first",
+                    "items": {
+                      "type": "object",
+                      "properties": {
+                        "type": {"type": "string", "enum": ["multiline", "json", "regex", "labels", "timestamp"]},
+                        "source": {"type": "string", "description": "Extracted value to read; default the log line"},
+                        "expression": {"type": "string", "example": "^\\d{4}-\\d{2}-\\d{2}", "description": "regex: pattern with named groups; multiline: first line of an entry"},
+                        "values": {"type": "object", "example": {"level": ""}, "description": "json: name -> JMESPath; labels: name -> extracted key (empty = same name)"},
+                        "format": {"type": "string", "example": "RFC3339", "description": "timestamp: Go layout or RFC3339, Unix, UnixMs, ..."},
+                        "max_wait": {"type": "string", "example": "3s", "description": "multiline: flush a pending entry after this long"}
+                      },
+                      "required": ["type"]
+                    }
+                  },
                   "labels": {"type": "object", "example": {"app": "myapp", "env": "prod"}}
                 },
-                "required": ["name", "path"]
+                "required": ["name"]
               }
             }
           }
@@ -473,6 +3644,22 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
         }
       }
     },
+    "/logs/sources/{name}/status": {
+      "get": {
+        "summary": "Log source tailing status",
+        "tags": ["Log Sources"],
+        "description": "Reports the files Promtail tails for a file source, with read positions and lag, from Promtail's metrics. No files usually means the path matches nothing.",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Tailing status (name, path, tailing, files, lag_bytes)"},
+          "400": {"description": "Not a file source"},
+          "404": {"description": "Source not found"},
+          "502": {"description": "Promtail unreachable"}
+        }
+      }
+    },
     "/logs/sources/reload": {
       "post": {
         "summary": "Force Promtail reload",
@@ -482,6 +3669,359 @@ func OpenAPISpec(w http.ResponseWriter, r *http.Request) {
         }
       }
     }
+  },
+  "components": {
+    "parameters": {
+      "IdempotencyKey": {
+        "name": "Idempotency-Key",
+        "in": "header",
+        "schema": {"type": "string", "maxLength": 255, "example": "3f0c8a7e-2d4b-4a51-9c1e-7b6d0f2e9a10"},
+        "description": "Unique key (e.g. a UUID) making a retried POST run once: a retry with the same key and body gets the first response again, with Idempotent-Replayed: true, for IDEMPOTENCY_TTL (default 24h). Honoured by routes, apps, /db/execute and /cache/{key}. A retry while the first request runs gets 409; reusing the key for a different request gets 422. 5xx, 401 and 429 answers are not kept."
+      }
+    },
+    "securitySchemes": {
+      "bearerAuth": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "Forge API key, or a JWT from the configured OIDC provider (role mapped from its claims). On the HTTPS port a client certificate signed by TLS_CLIENT_CA_FILE authenticates requests without a token (mutual TLS). The web UI authenticates with the session cookie from /auth/login instead, sending X-CSRF-Token on writes. Health, /metrics and the docs need none; the read role may only make GET requests, write everything but key management. Requests are rate limited per client and endpoint class (read, write, query, ingest); over a limit the API answers 429 with Retry-After. Clients whose keys or logins repeatedly fail are locked out for a growing time, also with 429."
+      }
+    },
+    "schemas": {
+      "Flag": {
+        "type": "object",
+        "properties": {
+          "key": {"type": "string", "example": "new-checkout", "description": "Lowercase letters, digits and ._-; taken from the path on PUT"},
+          "description": {"type": "string"},
+          "type": {"type": "string", "enum": ["boolean", "percentage", "variant"], "default": "boolean"},
+          "enabled": {"type": "boolean", "description": "Disabled flags serve false, or off_variant"},
+          "percentage": {"type": "number", "minimum": 0, "maximum": 100, "example": 25, "description": "Percentage flags: share of keys served true"},
+          "variants": {
+            "type": "array",
+            "description": "Variant flags: variants served to a share of keys proportional to their weight",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string", "example": "blue"},
+                "weight": {"type": "integer", "example": 50}
+              }
+            }
+          },
+          "off_variant": {"type": "string", "description": "Variant served while disabled (default: the first)"},
+          "rules": {
+            "type": "array",
+            "description": "Checked in order; the first match decides",
+            "items": {
+              "type": "object",
+              "properties": {
+                "attribute": {"type": "string", "example": "country", "description": "Evaluation attribute, or key for the evaluation key"},
+                "operator": {"type": "string", "enum": ["in", "not_in", "prefix", "suffix", "contains"]},
+                "values": {"type": "array", "items": {"type": "string"}, "example": ["DE", "AT"]},
+                "serve": {"type": "string", "example": "true", "description": "\"true\" or \"false\", or a variant name for variant flags"}
+              }
+            }
+          },
+          "version": {"type": "integer", "readOnly": true},
+          "updated_at": {"type": "string", "format": "date-time", "readOnly": true}
+        },
+        "required": ["key"]
+      },
+      "Job": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "Letters, digits, _ and -"},
+          "schedule": {"type": "string", "example": "0 3 * * mon-fri", "description": "Cron expression (minute hour day-of-month month day-of-week) or @hourly, @daily, @weekly, @monthly, @yearly"},
+          "timezone": {"type": "string", "example": "Europe/Berlin", "default": "UTC"},
+          "overlap": {"type": "string", "enum": ["skip", "allow", "queue", "replace"], "default": "skip"},
+          "timeout": {"type": "string", "default": "10m", "description": "At most 24h; runs past it fail"},
+          "paused": {"type": "boolean"},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "action": {
+            "type": "object",
+            "properties": {
+              "type": {"type": "string", "enum": ["http", "sql", "cache", "restart", "backup"]},
+              "method": {"type": "string", "description": "http; default GET, or POST with a body"},
+              "url": {"type": "string", "description": "http"},
+              "headers": {"type": "object", "additionalProperties": {"type": "string"}, "description": "http"},
+              "body": {"type": "string", "description": "http"},
+              "expect_status": {"type": "integer", "description": "http; default any 2xx"},
+              "database": {"type": "string", "description": "sql (optional) and backup (required)"},
+              "statement": {"type": "string", "description": "sql"},
+              "op": {"type": "string", "enum": ["set", "delete"], "description": "cache"},
+              "key": {"type": "string", "description": "cache"},
+              "value": {"type": "string", "description": "cache set"},
+              "ttl": {"type": "string", "description": "cache set; no expiry when empty"},
+              "container": {"type": "string", "description": "restart; container name or ID"},
+              "keep": {"type": "integer", "default": 7, "description": "backup; newest backups of this job kept in BACKUP_DIR as <job>-<database>-<time>.sql.gz"}
+            },
+            "required": ["type"]
+          },
+          "next_run": {"type": "string", "format": "date-time", "readOnly": true},
+          "running": {"type": "integer", "readOnly": true},
+          "queued": {"type": "boolean", "readOnly": true},
+          "last_run": {"allOf": [{"$ref": "#/components/schemas/JobRun"}], "readOnly": true}
+        },
+        "required": ["name", "schedule", "action"]
+      },
+      "JobRun": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "job": {"type": "string"},
+          "trigger": {"type": "string", "enum": ["schedule", "manual"]},
+          "started": {"type": "string", "format": "date-time"},
+          "finished": {"type": "string", "format": "date-time"},
+          "duration_ms": {"type": "number"},
+          "status": {"type": "string", "enum": ["running", "queued", "succeeded", "failed", "skipped", "cancelled"]},
+          "output": {"type": "string", "description": "First 4 KB of the output"},
+          "error": {"type": "string"}
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "Letters, digits, _ and -"},
+          "url": {"type": "string", "example": "https://hooks.example.com/forge", "description": "http or https; redirects are not followed"},
+          "events": {"type": "array", "items": {"type": "string"}, "example": ["container.*", "alert.fired"], "description": "1-20 event types, families such as container.*, or *"},
+          "headers": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Sent with every delivery; Content-Type, User-Agent and X-Forge-* are reserved"},
+          "secret": {"type": "string", "writeOnly": true, "description": "At least 16 characters; generated when empty"},
+          "disabled": {"type": "boolean"},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "pending": {"type": "integer", "readOnly": true},
+          "last_delivery": {"allOf": [{"$ref": "#/components/schemas/WebhookDelivery"}], "readOnly": true}
+        },
+        "required": ["name", "url", "events"]
+      },
+      "WebhookDelivery": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "webhook": {"type": "string"},
+          "event_id": {"type": "string"},
+          "event": {"type": "string", "description": "Event type"},
+          "created": {"type": "string", "format": "date-time"},
+          "status": {"type": "string", "enum": ["pending", "delivered", "failed"]},
+          "attempts": {"type": "integer"},
+          "last_attempt": {"type": "string", "format": "date-time"},
+          "next_attempt": {"type": "string", "format": "date-time"},
+          "status_code": {"type": "integer", "description": "Of the last attempt"},
+          "duration_ms": {"type": "number", "description": "Of the last attempt"},
+          "response": {"type": "string", "description": "First 1 KB of the last response body"},
+          "error": {"type": "string"}
+        }
+      },
+      "Hook": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "Letters, digits, _ and -; the hook receives at /hooks/{name}"},
+          "verify": {
+            "type": "object",
+            "properties": {
+              "type": {"type": "string", "enum": ["github", "stripe", "hmac", "none"]},
+              "secret": {"type": "string", "writeOnly": true, "description": "At least 8 characters; generated for hmac when empty"},
+              "header": {"type": "string", "default": "X-Signature", "description": "hmac"},
+              "algorithm": {"type": "string", "enum": ["sha256", "sha1", "sha512"], "default": "sha256", "description": "hmac"},
+              "encoding": {"type": "string", "enum": ["hex", "base64"], "default": "hex", "description": "hmac"},
+              "prefix": {"type": "string", "example": "sha256=", "description": "hmac; text before the signature"},
+              "tolerance": {"type": "string", "default": "5m", "description": "stripe; how far the signed timestamp may be from now"}
+            },
+            "required": ["type"]
+          },
+          "targets": {
+            "type": "array",
+            "description": "1-10 targets",
+            "items": {
+              "type": "object",
+              "properties": {
+                "type": {"type": "string", "enum": ["queue", "http", "log", "metric"]},
+                "when": {"type": "object", "additionalProperties": {"type": "string"}, "example": {"header.X-GitHub-Event": "push"}, "description": "Run only when every selector (header.<name>, body or body.<path>, with dot-separated keys and array indexes) matches its pattern, in which * matches any text"},
+                "key": {"type": "string", "description": "queue; Redis list"},
+                "max_len": {"type": "integer", "default": 10000, "description": "queue; newest items kept"},
+                "url": {"type": "string", "description": "http; receives a POST with the request's headers and body"},
+                "headers": {"type": "object", "additionalProperties": {"type": "string"}, "description": "http; added to the forwarded request"},
+                "timeout": {"type": "string", "default": "10s", "description": "http; at most 30s, and below the sender's own timeout"},
+                "level": {"type": "string", "enum": ["debug", "info", "warn", "error"], "default": "info", "description": "log"},
+                "message": {"type": "string", "description": "log; default \"<hook> hook received\", the body is a field"},
+                "metric": {"type": "string", "description": "metric; counter incremented by one"},
+                "labels": {"type": "object", "additionalProperties": {"type": "string"}, "description": "log and metric. Message and label values may hold ${selector} templates."}
+              },
+              "required": ["type"]
+            }
+          },
+          "disabled": {"type": "boolean"},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "last_receipt": {"allOf": [{"$ref": "#/components/schemas/HookReceipt"}], "readOnly": true}
+        },
+        "required": ["name", "verify", "targets"]
+      },
+      "HookReceipt": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string", "description": "Also sent to targets, in X-Forge-Receipt and the queued id"},
+          "hook": {"type": "string"},
+          "received": {"type": "string", "format": "date-time"},
+          "status": {"type": "string", "enum": ["delivered", "ignored", "failed", "rejected"]},
+          "size": {"type": "integer", "description": "Body size in bytes"},
+          "duration_ms": {"type": "number"},
+          "error": {"type": "string"},
+          "targets": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "index": {"type": "integer"},
+                "type": {"type": "string"},
+                "status": {"type": "string", "enum": ["ok", "failed", "skipped"]},
+                "output": {"type": "string"},
+                "error": {"type": "string"},
+                "duration_ms": {"type": "number"}
+              }
+            }
+          }
+        }
+      },
+      "NotificationChannel": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "Letters, digits, _ and -"},
+          "type": {"type": "string", "enum": ["slack", "discord", "telegram", "gotify", "ntfy", "webhook"]},
+          "url": {"type": "string", "description": "Slack or Discord incoming webhook URL, Gotify server, ntfy topic URL (https://ntfy.sh/<topic>) or webhook endpoint; for telegram, an optional Bot API server"},
+          "chat_id": {"type": "string", "description": "Telegram chat to send to"},
+          "token": {"type": "string", "writeOnly": true, "description": "Telegram bot token or Gotify application token (required), or ntfy access token (optional)"},
+          "headers": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Webhook channels only. Webhooks are POSTed JSON {channel, title, text, priority, event, time}"},
+          "events": {"type": "array", "items": {"type": "string"}, "example": ["alert.fired", "container.*"], "description": "Up to 20 event types or families sent to the channel; none for channels only apps send to"},
+          "disabled": {"type": "boolean"},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "has_token": {"type": "boolean", "readOnly": true},
+          "sent": {"type": "integer", "readOnly": true},
+          "failed": {"type": "integer", "readOnly": true},
+          "last_sent": {"type": "string", "format": "date-time", "readOnly": true},
+          "last_error": {"type": "string", "readOnly": true}
+        },
+        "required": ["name", "type"]
+      },
+      "MQTTBridge": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "Letters, digits, _ and -"},
+          "topic": {"type": "string", "description": "Topic filter: + matches one level and # the rest, such as zigbee2mqtt/#"},
+          "qos": {"type": "integer", "enum": [0, 1]},
+          "targets": {
+            "type": "array",
+            "description": "1 to 10 targets. Message and label values may hold ${topic}, ${topic.N} (levels from 0), ${payload} and ${payload.path} into a JSON payload.",
+            "items": {
+              "type": "object",
+              "properties": {
+                "type": {"type": "string", "enum": ["log", "metric", "webhook"]},
+                "level": {"type": "string", "enum": ["debug", "info", "warn", "error"], "description": "log: default info"},
+                "message": {"type": "string", "description": "log: default \"MQTT message on ${topic}\""},
+                "metric": {"type": "string", "description": "metric: name of the counter or gauge"},
+                "kind": {"type": "string", "enum": ["counter", "gauge"], "description": "metric: counters count messages, gauges take a value from each"},
+                "value": {"type": "string", "description": "gauge: payload, payload.path or topic.N (default payload); on/off and true/false count as 1 and 0"},
+                "labels": {"type": "object", "additionalProperties": {"type": "string"}, "description": "log and metric"}
+              },
+              "required": ["type"]
+            }
+          },
+          "disabled": {"type": "boolean"},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "subscribed": {"type": "boolean", "readOnly": true},
+          "stats": {
+            "type": "object",
+            "readOnly": true,
+            "properties": {
+              "forwarded": {"type": "integer"},
+              "failed": {"type": "integer"},
+              "dropped": {"type": "integer"},
+              "last_message": {"type": "string", "format": "date-time"},
+              "last_topic": {"type": "string"},
+              "last_error": {"type": "string"}
+            }
+          }
+        },
+        "required": ["name", "topic", "targets"]
+      },
+      "ServiceRegistration": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "description": "Letters, digits, _ and -"},
+          "id": {"type": "string", "description": "Tells instances apart; default the host and port of address, such as orders-1-8000"},
+          "address": {"type": "string", "description": "Base URL without a path, such as http://orders-1:8000"},
+          "health_url": {"type": "string", "description": "Path relative to address, such as /healthz, or an http(s) URL on the host of address; statuses under 400 pass"},
+          "metadata": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Up to 32 entries"},
+          "ttl": {"type": "string", "description": "Heartbeat deadline, 5s to 1h (default 30s)"},
+          "route": {
+            "type": "object",
+            "description": "Route named after the service",
+            "properties": {
+              "path": {"type": "string"},
+              "strip_prefix": {"type": "boolean"},
+              "host": {"type": "string"},
+              "domain": {"type": "string"}
+            },
+            "required": ["path"]
+          },
+          "metrics_path": {"type": "string", "description": "Prometheus scrapes address + metrics_path"}
+        },
+        "required": ["name", "address"]
+      },
+      "ServiceInstance": {
+        "allOf": [
+          {"$ref": "#/components/schemas/ServiceRegistration"},
+          {
+            "type": "object",
+            "properties": {
+              "status": {"type": "string", "enum": ["passing", "critical"]},
+              "output": {"type": "string", "description": "Why the last health check failed"},
+              "registered": {"type": "string", "format": "date-time"},
+              "last_heartbeat": {"type": "string", "format": "date-time"},
+              "expires": {"type": "string", "format": "date-time"}
+            }
+          }
+        ]
+      },
+      "File": {
+        "type": "object",
+        "properties": {
+          "path": {"type": "string"},
+          "size": {"type": "integer"},
+          "sha256": {"type": "string"},
+          "content_type": {"type": "string"},
+          "modified": {"type": "string", "format": "date-time"}
+        }
+      },
+      "StorageObject": {
+        "type": "object",
+        "properties": {
+          "key": {"type": "string"},
+          "size": {"type": "integer"},
+          "etag": {"type": "string"},
+          "content_type": {"type": "string"},
+          "last_modified": {"type": "integer", "description": "Unix seconds"}
+        }
+      },
+      "UserRequest": {
+        "type": "object",
+        "properties": {
+          "username": {"type": "string", "example": "alice", "description": "Letters, digits and _.@-; required to create"},
+          "password": {"type": "string", "format": "password", "description": "12-72 characters"},
+          "role": {"type": "string", "enum": ["read", "write", "admin"]}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "description": "Body of every REST error response",
+        "properties": {
+          "error": {
+            "type": "object",
+            "properties": {
+              "code": {"type": "string", "description": "Connect code name", "enum": ["canceled", "unknown", "invalid_argument", "deadline_exceeded", "not_found", "already_exists", "permission_denied", "resource_exhausted", "failed_precondition", "aborted", "out_of_range", "unimplemented", "internal", "unavailable", "data_loss", "unauthenticated"]},
+              "message": {"type": "string"},
+              "details": {"type": "object", "additionalProperties": true, "description": "Extra context, e.g. request_id for internal errors, or dependency, reason, affected and hint when a dependency is unavailable"}
+            }
+          }
+        }
+      }
+    }
   }
 }`
 	w.Header().Set("Content-Type", "application/json")
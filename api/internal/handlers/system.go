@@ -1,39 +1,536 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/apierror"
 	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/operations"
 	"github.com/forge/api/internal/system"
 )
 
 // SystemHandler handles system information requests
 type SystemHandler struct {
 	docker *system.DockerClient
+	stats  *system.StatsHub
+	clock  *system.ClockChecker
+	routes func() []system.RouteAvailability
+	host   func() *system.HostStats
+	trends func() []system.UsageTrend
+	gpu    func() *system.GPUStats
+	ops    *operations.Manager
+	events *system.EventWatcher
+	filter system.ContainerFilter
+	watch  *system.Watchdog
+	limits *system.LimitManager
 }
 
 // NewSystemHandler creates a new system handler
-func NewSystemHandler() *SystemHandler {
+func NewSystemHandler(docker *system.DockerClient, clock *system.ClockChecker) *SystemHandler {
 	return &SystemHandler{
-		docker: system.NewDockerClient(),
+		docker: docker,
+		stats:  system.NewStatsHub(docker),
+		clock:  clock,
 	}
 }
 
-// GetSystemInfo returns detailed system and container information
+// SetRouteAvailability adds route probe results from fn to system info
+func (h *SystemHandler) SetRouteAvailability(fn func() []system.RouteAvailability) {
+	h.routes = fn
+}
+
+// SetHostStats adds host resource usage from fn to system info
+func (h *SystemHandler) SetHostStats(fn func() *system.HostStats) {
+	h.host = fn
+}
+
+// SetGPUStats adds GPU usage from fn to system info
+func (h *SystemHandler) SetGPUStats(fn func() *system.GPUStats) {
+	h.gpu = fn
+}
+
+// SetUsageTrends adds usage trends from fn to system info
+func (h *SystemHandler) SetUsageTrends(fn func() []system.UsageTrend) {
+	h.trends = fn
+}
+
+// SetContainerFilter reports the containers selected by filter next to
+// the forge containers unless a request asks otherwise
+func (h *SystemHandler) SetContainerFilter(filter system.ContainerFilter) {
+	h.filter = filter
+	h.stats.SetFilter(filter)
+}
+
+// SetWatchdog exposes the container watchdog policy and incidents
+func (h *SystemHandler) SetWatchdog(watchdog *system.Watchdog) {
+	h.watch = watchdog
+}
+
+// SetLimits exposes container resource limits
+func (h *SystemHandler) SetLimits(limits *system.LimitManager) {
+	h.limits = limits
+}
+
+// SetOperations enables stack upgrades, which run as background operations
+func (h *SystemHandler) SetOperations(ops *operations.Manager) {
+	h.ops = ops
+}
+
+// SetEvents enables the container event feed
+func (h *SystemHandler) SetEvents(events *system.EventWatcher) {
+	h.events = events
+}
+
+// GetSystemInfo returns detailed system and container information.
+// ?containers=all includes every container and ?label= (repeatable) those
+// matching a label selector; either replaces the configured selection.
 func (h *SystemHandler) GetSystemInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	info, err := h.docker.GetSystemInfo(r.Context())
+	filter := h.filter
+	q := r.URL.Query()
+	if q.Has("containers") || q.Has("label") {
+		var err error
+		filter, err = system.ParseContainerFilter(q.Get("containers"), strings.Join(q["label"], ","))
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	info, err := h.docker.GetSystemInfo(r.Context(), filter)
 	if err != nil {
 		logger.Error("Failed to get system info", err)
-		http.Error(w, "Failed to get system info", http.StatusInternalServerError)
+		apierror.Error(w, "Failed to get system info", http.StatusInternalServerError)
 		return
 	}
+	info.AddClockReport(h.clock.Check(r.Context()))
+	if h.routes != nil {
+		info.AddRouteAvailability(h.routes())
+	}
+	if h.host != nil {
+		info.AddHostStats(h.host())
+	}
+	if h.trends != nil {
+		info.AddUsageTrends(h.trends())
+	}
+	if h.gpu != nil {
+		info.AddGPUStats(h.gpu())
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(info)
 }
+
+// StreamSystemInfo streams container stats as Server-Sent Events: a
+// "snapshot" event with the full system info, then a "container" event
+// for each stats sample or state change of a container
+func (h *SystemHandler) StreamSystemInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before the snapshot so no change falls between them
+	updates, unsubscribe := h.stats.Subscribe()
+	defer unsubscribe()
+
+	info, err := h.docker.GetSystemInfo(r.Context(), h.filter)
+	if err != nil {
+		logger.Error("Failed to get system info", err)
+		apierror.Error(w, "Failed to get system info", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if h.host != nil {
+		info.AddHostStats(h.host())
+	}
+	if h.trends != nil {
+		info.AddUsageTrends(h.trends())
+	}
+	if h.gpu != nil {
+		info.AddGPUStats(h.gpu())
+	}
+	writeSSE(w, "snapshot", info)
+	flusher.Flush()
+
+	ticker := time.NewTicker(tailKeepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		case stats := <-updates:
+			writeSSE(w, "container", stats)
+			flusher.Flush()
+		}
+	}
+}
+
+// defaultEventLimit is how many recent events are returned by default
+const defaultEventLimit = 100
+
+// GetEvents returns recent container start, stop, die, OOM and health
+// events as JSON, or as Server-Sent Events when the client accepts
+// text/event-stream: the recent events first, then an "event" for each
+// new one. ?limit= caps the recent events (default 100).
+func (h *SystemHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.events == nil {
+		apierror.Error(w, "Container events are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := defaultEventLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			apierror.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"events": h.events.Recent(limit)})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before reading the history so no event falls between them
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+	recent := h.events.Recent(limit)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	var last time.Time
+	for _, e := range recent {
+		writeSSE(w, "event", e)
+		last = e.Time
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(tailKeepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			w.Write([]byte(": keepalive\n\n"))
+			flusher.Flush()
+		case e := <-events:
+			if !e.Time.After(last) {
+				continue // already sent with the history
+			}
+			writeSSE(w, "event", e)
+			flusher.Flush()
+		}
+	}
+}
+
+// HandleDisk handles /api/v1/system/disk: GET reports Docker disk usage
+// and POST /prune removes unused objects
+func (h *SystemHandler) HandleDisk(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/system/disk")
+	path = strings.Trim(path, "/")
+
+	switch {
+	case path == "" && r.Method == "GET":
+		usage, err := h.docker.DiskUsage(r.Context())
+		if err != nil {
+			logger.Error("Failed to get disk usage", err)
+			apierror.Error(w, "Failed to get disk usage: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(usage)
+	case path == "prune" && r.Method == "POST":
+		h.pruneDisk(w, r)
+	case path == "" || path == "prune":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// pruneDisk removes unused Docker objects of the requested targets
+func (h *SystemHandler) pruneDisk(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	var req struct {
+		Targets []string `json:"targets"`
+		All     bool     `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Targets) == 0 {
+		apierror.Error(w, "targets is required", http.StatusBadRequest)
+		return
+	}
+	for _, target := range req.Targets {
+		if !system.ValidPruneTarget(target) {
+			apierror.Error(w, "Invalid target: "+target, http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.prune(w, r, req.Targets, req.All)
+}
+
+// Prune handles POST /api/v1/system/prune: removes stopped containers,
+// dangling images and unused networks, and unused volumes only when the
+// body opts in with "volumes": true
+func (h *SystemHandler) Prune(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The body is optional; without it volumes are kept
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	var req struct {
+		Volumes bool `json:"volumes"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Containers first, so the images, networks and volumes they held
+	// become unused
+	targets := []string{system.PruneContainers, system.PruneImages, system.PruneNetworks}
+	if req.Volumes {
+		targets = append(targets, system.PruneVolumes)
+	}
+	h.prune(w, r, targets, false)
+}
+
+// prune removes unused Docker objects of targets in order and writes the
+// results. It refuses while an upgrade runs, since the old containers it
+// keeps for rollback are stopped.
+func (h *SystemHandler) prune(w http.ResponseWriter, r *http.Request, targets []string, all bool) {
+	if h.ops != nil {
+		for _, state := range []string{operations.StatePending, operations.StateRunning} {
+			if running := h.ops.List("system.upgrade", state); len(running) > 0 {
+				apierror.Error(w, "An upgrade is running: /api/v1/operations/"+running[0].ID, http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	results := make([]*system.PruneResult, 0, len(targets))
+	var reclaimed float64
+	var reclaimedBytes int64
+	for _, target := range targets {
+		result, err := h.docker.Prune(r.Context(), target, all)
+		if err != nil {
+			apierror.Write(w, http.StatusBadGateway, connect.CodeUnavailable, "Failed to prune "+target+": "+err.Error(), map[string]any{
+				"results": results,
+			})
+			return
+		}
+		logger.Info("Pruned Docker " + target)
+		results = append(results, result)
+		reclaimed += result.ReclaimedMB
+		reclaimedBytes += result.ReclaimedBytes
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results":         results,
+		"reclaimed_mb":    reclaimed,
+		"reclaimed_bytes": reclaimedBytes,
+	})
+}
+
+// Upgrade pulls newer images for forge services and recreates the changed
+// containers one at a time as a background operation. Only one upgrade
+// runs at a time.
+func (h *SystemHandler) Upgrade(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.ops == nil {
+		apierror.Error(w, "Upgrades are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The body is optional; without services every forge service is upgraded
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	var req struct {
+		Services []string `json:"services"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, state := range []string{operations.StatePending, operations.StateRunning} {
+		if running := h.ops.List("system.upgrade", state); len(running) > 0 {
+			apierror.Error(w, "An upgrade is already running: /api/v1/operations/"+running[0].ID, http.StatusConflict)
+			return
+		}
+	}
+
+	target := "all services"
+	if len(req.Services) > 0 {
+		target = strings.Join(req.Services, ", ")
+	}
+	op := h.ops.Start("system.upgrade", target, func(ctx context.Context, op *operations.Operation) error {
+		results, err := h.docker.Upgrade(ctx, req.Services, op)
+		op.SetResult(map[string]any{"services": results})
+		if err != nil {
+			return err
+		}
+		logger.Info("Stack upgrade finished")
+		return nil
+	})
+
+	writeOperationAccepted(w, op)
+}
+
+// HandleWatchdog handles /api/v1/system/watchdog: GET returns the policy,
+// containers being restarted and recent incidents; PUT replaces the policy
+func (h *SystemHandler) HandleWatchdog(w http.ResponseWriter, r *http.Request) {
+	if h.watch == nil {
+		apierror.Error(w, "Watchdog is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+		var policy system.WatchdogPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.watch.SetPolicy(policy); err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Info("Updated watchdog policy")
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.watch.Status())
+}
+
+// HandleLimits handles /api/v1/system/limits: GET lists container memory
+// and CPU limits; GET, PUT and DELETE on /api/v1/system/limits/{container}
+// show, apply and forget the limits of one container
+func (h *SystemHandler) HandleLimits(w http.ResponseWriter, r *http.Request) {
+	if h.limits == nil {
+		apierror.Error(w, "Resource limits are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/system/limits"), "/")
+
+	var (
+		result any
+		err    error
+	)
+	switch {
+	case name == "" && r.Method == "GET":
+		var list []system.ContainerLimits
+		if list, err = h.limits.List(r.Context()); err == nil {
+			result = map[string]any{"containers": list, "count": len(list)}
+		}
+	case name == "":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	case r.Method == "GET":
+		result, err = h.limits.Get(r.Context(), name)
+	case r.Method == "PUT":
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+		var limits system.ResourceLimits
+		if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+			apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if result, err = h.limits.Set(r.Context(), name, limits); err == nil {
+			logger.Info("Updated resource limits of " + name)
+		}
+	case r.Method == "DELETE":
+		if err = h.limits.Forget(name); err == nil {
+			logger.Info("Removed stored resource limits of " + name)
+			result = map[string]any{"ok": true, "deleted": name}
+		}
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, system.ErrInvalidLimits):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, system.ErrContainerNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		apierror.Error(w, "Failed to update limits: "+err.Error(), http.StatusBadGateway)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// GetClock returns host NTP status and clock skew against downstream services
+func (h *SystemHandler) GetClock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.clock.Check(r.Context()))
+}
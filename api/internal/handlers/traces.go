@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/observe"
+)
+
+// maxTraceSearchLimit caps the number of traces returned by a search
+const maxTraceSearchLimit = 100
+
+// TraceSummary is a trace search hit
+type TraceSummary struct {
+	TraceID     string `json:"trace_id"`
+	RootService string `json:"root_service"`
+	RootName    string `json:"root_name"`
+	StartMs     int64  `json:"start_ms"`
+	DurationMs  int64  `json:"duration_ms"`
+}
+
+// TraceSpan is a span of a retrieved trace
+type TraceSpan struct {
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Name         string            `json:"name"`
+	Service      string            `json:"service"`
+	StartMs      int64             `json:"start_ms"`
+	DurationUs   int64             `json:"duration_us"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// TraceLookupREST handles /api/v1/traces/search and /api/v1/traces/{trace_id}.
+// Search parameters: service, tag (key=value, repeatable), q (TraceQL),
+// start, end (ms since epoch), min_duration, max_duration (e.g. 250ms), limit.
+func TraceLookupREST(h *ObserveHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/traces"), "/")
+		switch id {
+		case "":
+			apierror.Error(w, "Not found", http.StatusNotFound)
+		case "search":
+			searchTraces(w, r, h.tempoClient)
+		default:
+			getTrace(w, r, h.tempoClient, strings.ToLower(id))
+		}
+	}
+}
+
+func searchTraces(w http.ResponseWriter, r *http.Request, tempo *observe.TempoClient) {
+	q := r.URL.Query()
+	opts := observe.TraceSearchOptions{
+		Service: q.Get("service"),
+		Query:   q.Get("q"),
+		Limit:   20,
+	}
+
+	for _, tag := range q["tag"] {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok || k == "" {
+			apierror.Error(w, "tag must be key=value", http.StatusBadRequest)
+			return
+		}
+		if opts.Tags == nil {
+			opts.Tags = make(map[string]string)
+		}
+		opts.Tags[k] = v
+	}
+	if ms, err := strconv.ParseInt(q.Get("start"), 10, 64); err == nil {
+		opts.Start = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(q.Get("end"), 10, 64); err == nil {
+		opts.End = time.UnixMilli(ms)
+	}
+	for param, dst := range map[string]*time.Duration{"min_duration": &opts.MinDuration, "max_duration": &opts.MaxDuration} {
+		if v := q.Get(param); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				apierror.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*dst = d
+		}
+	}
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 {
+		opts.Limit = min(limit, maxTraceSearchLimit)
+	}
+
+	traces, err := tempo.SearchTraces(r.Context(), opts)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	items := make([]TraceSummary, 0, len(traces))
+	for _, t := range traces {
+		items = append(items, TraceSummary{
+			TraceID:     t.TraceID,
+			RootService: t.RootService,
+			RootName:    t.RootName,
+			StartMs:     t.Start.UnixMilli(),
+			DurationMs:  t.Duration.Milliseconds(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"items": items,
+		"count": len(items),
+	})
+}
+
+func getTrace(w http.ResponseWriter, r *http.Request, tempo *observe.TempoClient, traceID string) {
+	spans, err := tempo.GetTrace(r.Context(), traceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, observe.ErrTraceNotFound):
+			apierror.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, observe.ErrInvalidTraceID):
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			apierror.Error(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
+	items := make([]TraceSpan, 0, len(spans))
+	for _, s := range spans {
+		items = append(items, TraceSpan{
+			TraceID:      s.TraceID,
+			SpanID:       s.SpanID,
+			ParentSpanID: s.ParentSpanID,
+			Name:         s.Name,
+			Service:      s.Service,
+			StartMs:      s.Start.UnixMilli(),
+			DurationUs:   s.Duration.Microseconds(),
+			Attributes:   s.Attributes,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"trace_id": traceID,
+		"spans":    items,
+		"count":    len(items),
+	})
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/version"
+)
+
+// VersionREST handles GET /api/v1/version: the build identity (version,
+// commit, build date, Go version, platform), enabled features and uptime
+func VersionREST(startTime time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			version.Info
+			Uptime string `json:"uptime"`
+		}{version.Get(), time.Since(startTime).Round(time.Second).String()})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/webhooks"
+)
+
+// WebhooksHandler manages outbound webhook subscriptions
+type WebhooksHandler struct {
+	manager *webhooks.Manager
+}
+
+// NewWebhooksHandler creates a new webhooks handler
+func NewWebhooksHandler(manager *webhooks.Manager) *WebhooksHandler {
+	return &WebhooksHandler{manager: manager}
+}
+
+// HandleWebhooks handles /api/v1/webhooks requests
+func (h *WebhooksHandler) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks"), "/")
+	parts := strings.Split(path, "/")
+	name := parts[0]
+
+	switch {
+	case path == "" && r.Method == "GET":
+		list := h.manager.List()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case path == "" && r.Method == "POST":
+		h.saveWebhook(w, r)
+	case len(parts) == 1 && r.Method == "GET":
+		st, err := h.manager.Get(name)
+		if err != nil {
+			writeWebhooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case len(parts) == 1 && r.Method == "PATCH":
+		h.toggleWebhook(w, r, name)
+	case len(parts) == 1 && r.Method == "DELETE":
+		if err := h.manager.Remove(name); err != nil {
+			writeWebhooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "deleted": name})
+	case len(parts) == 2 && parts[1] == "test" && r.Method == "POST":
+		d, err := h.manager.Test(name)
+		if err != nil {
+			writeWebhooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(d)
+	case len(parts) == 2 && parts[1] == "rotate-secret" && r.Method == "POST":
+		secret, err := h.manager.RotateSecret(name)
+		if err != nil {
+			writeWebhooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "secret": secret})
+	case len(parts) == 2 && parts[1] == "deliveries" && r.Method == "GET":
+		list, err := h.manager.Deliveries(name)
+		if err != nil {
+			writeWebhooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": list,
+			"count": len(list),
+		})
+	case len(parts) == 4 && parts[1] == "deliveries" && parts[3] == "redeliver" && r.Method == "POST":
+		d, err := h.manager.Redeliver(name, parts[2])
+		if err != nil {
+			writeWebhooksError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(d)
+	case len(parts) > 1 && r.Method != "GET" && r.Method != "POST":
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case len(parts) > 1:
+		apierror.Error(w, "Not found", http.StatusNotFound)
+	default:
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveWebhook creates or replaces a webhook. The secret is returned when
+// the webhook is created, and only then.
+func (h *WebhooksHandler) saveWebhook(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		webhooks.Webhook
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook := body.Webhook
+	hook.Secret = body.Secret
+
+	secret, created, err := h.manager.Save(hook)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	saved, _ := h.manager.Get(hook.Name)
+	resp := map[string]any{"ok": true, "webhook": saved}
+	status := http.StatusOK
+	if created {
+		resp["secret"] = secret
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// toggleWebhook disables or enables a webhook
+func (h *WebhooksHandler) toggleWebhook(w http.ResponseWriter, r *http.Request, name string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var body struct {
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Disabled == nil {
+		apierror.Error(w, "disabled is required", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.manager.SetDisabled(name, *body.Disabled)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// writeWebhooksError maps manager errors to HTTP statuses
+func writeWebhooksError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrWebhookNotFound), errors.Is(err, webhooks.ErrDeliveryNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, webhooks.ErrInvalidWebhook):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		apierror.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,316 @@
+// Package history stores periodic host and container usage samples in
+// MySQL and computes usage trends from them
+//
+// The live system info only shows the current usage. Samples taken every
+// few minutes and kept for months answer how usage changed over time, so
+// recommendations can point at slow growth such as a memory leak.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/system"
+)
+
+const (
+	// SampleInterval is how often usage is sampled
+	SampleInterval = 5 * time.Minute
+	// DefaultRetention is how long samples are kept
+	DefaultRetention = 90 * 24 * time.Hour
+	// DefaultTrendDays is the window of the trends added to system info
+	DefaultTrendDays = 7
+	// MaxTrendDays is the longest trend window
+	MaxTrendDays = 90
+
+	// maxPoints caps the points returned per series; longer ranges are
+	// averaged into coarser steps
+	maxPoints = 500
+	// trendRefresh is how often the system info trends are recomputed
+	trendRefresh = time.Hour
+)
+
+// Sample kinds
+const (
+	KindHost      = "host"
+	KindContainer = "container"
+)
+
+// Point is the average usage over one step
+type Point struct {
+	TimestampMs   int64   `json:"timestamp_ms"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryMB      float64 `json:"memory_mb"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// Series is the usage history of the host or a container
+type Series struct {
+	Kind   string  `json:"kind"`
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// Query selects stored samples; empty Kind and Name match all
+type Query struct {
+	From, To time.Time
+	Step     time.Duration // 0 picks one that keeps at most 500 points
+	Kind     string
+	Name     string
+}
+
+// Store samples usage into MySQL and answers range and trend queries
+type Store struct {
+	db        *sql.DB
+	table     string
+	docker    *system.DockerClient
+	filter    system.ContainerFilter
+	host      func() *system.HostStats
+	retention time.Duration
+
+	mu     sync.RWMutex
+	trends []system.UsageTrend
+}
+
+// NewStore prepares the history table. Containers selected by filter are
+// sampled next to the stack; host returns the latest host stats and may
+// be nil.
+func NewStore(db *sql.DB, database string, docker *system.DockerClient, filter system.ContainerFilter, host func() *system.HostStats) (*Store, error) {
+	s := &Store{
+		db:        db,
+		table:     fmt.Sprintf("`%s`.`usage_history`", database),
+		docker:    docker,
+		filter:    filter,
+		host:      host,
+		retention: DefaultRetention,
+	}
+	if err := s.migrate(context.Background(), database); err != nil {
+		return nil, fmt.Errorf("failed to create usage history table: %w", err)
+	}
+	return s, nil
+}
+
+// migrate creates the database and table if missing
+func (s *Store) migrate(ctx context.Context, database string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database)); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.table+` (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		kind VARCHAR(16) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		ts DATETIME(3) NOT NULL,
+		cpu_percent DOUBLE NOT NULL,
+		memory_mb DOUBLE NOT NULL,
+		memory_percent DOUBLE NOT NULL,
+		INDEX idx_kind_name_ts (kind, name, ts),
+		INDEX idx_ts (ts)
+	)`)
+	return err
+}
+
+// Run samples usage and refreshes trends until ctx is cancelled. Old
+// samples are pruned with each trend refresh.
+func (s *Store) Run(ctx context.Context) {
+	sample := time.NewTicker(SampleInterval)
+	defer sample.Stop()
+	refresh := time.NewTicker(trendRefresh)
+	defer refresh.Stop()
+
+	s.refresh(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sample.C:
+			if err := s.sample(ctx, time.Now()); err != nil {
+				logger.Error("Usage history sample failed", err)
+			}
+		case <-refresh.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// Latest returns the trends over the default window, as of the last refresh
+func (s *Store) Latest() []system.UsageTrend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.trends
+}
+
+// refresh prunes expired samples and recomputes the default trends
+func (s *Store) refresh(ctx context.Context) {
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE ts < ?", now.Add(-s.retention).UTC()); err != nil {
+		logger.Error("Usage history pruning failed", err)
+	}
+	trends, err := s.Trends(ctx, DefaultTrendDays, now)
+	if err != nil {
+		logger.Error("Usage trends failed", err)
+		return
+	}
+	s.mu.Lock()
+	s.trends = trends
+	s.mu.Unlock()
+}
+
+// sample stores the current usage of the host and running containers
+func (s *Store) sample(ctx context.Context, at time.Time) error {
+	type row struct {
+		kind, name              string
+		cpu, memory, memPercent float64
+	}
+	var rows []row
+	if s.host != nil {
+		if h := s.host(); h != nil {
+			rows = append(rows, row{KindHost, KindHost, h.CPUPercent, h.MemoryUsedMB, h.MemoryPercent})
+		}
+	}
+	info, err := s.docker.GetSystemInfo(ctx, s.filter)
+	if err != nil {
+		return err
+	}
+	for name, c := range info.Containers {
+		if c.State != "running" {
+			continue
+		}
+		rows = append(rows, row{KindContainer, name, c.CPUPercent, c.MemoryMB, c.MemoryPercent})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(rows))
+	args := make([]any, 0, 6*len(rows))
+	for _, r := range rows {
+		// MySQL DOUBLE cannot store NaN/Inf
+		if !finite(r.cpu) || !finite(r.memory) || !finite(r.memPercent) {
+			continue
+		}
+		values = append(values, "(?, ?, ?, ?, ?, ?)")
+		args = append(args, r.kind, r.name, at.UTC(), r.cpu, r.memory, r.memPercent)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO "+s.table+" (kind, name, ts, cpu_percent, memory_mb, memory_percent) VALUES "+strings.Join(values, ", "),
+		args...)
+	return err
+}
+
+// Series returns the usage between q.From and q.To averaged per step,
+// ordered by kind and name
+func (s *Store) Series(ctx context.Context, q Query) ([]Series, error) {
+	step := q.Step
+	if step <= 0 {
+		step = q.To.Sub(q.From) / maxPoints
+	}
+	if step < SampleInterval {
+		step = SampleInterval
+	}
+	stepSec := int64(step / time.Second)
+
+	where := "ts BETWEEN ? AND ?"
+	args := []any{stepSec, stepSec, q.From.UTC(), q.To.UTC()}
+	if q.Kind != "" {
+		where += " AND kind = ?"
+		args = append(args, q.Kind)
+	}
+	if q.Name != "" {
+		where += " AND name = ?"
+		args = append(args, q.Name)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT kind, name, CAST(FLOOR(UNIX_TIMESTAMP(ts) / ?) * ? * 1000 AS SIGNED) AS bucket,"+
+			" AVG(cpu_percent), AVG(memory_mb), AVG(memory_percent)"+
+			" FROM "+s.table+" WHERE "+where+
+			" GROUP BY kind, name, bucket ORDER BY kind, name, bucket",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Series
+	for rows.Next() {
+		var kind, name string
+		var p Point
+		if err := rows.Scan(&kind, &name, &p.TimestampMs, &p.CPUPercent, &p.MemoryMB, &p.MemoryPercent); err != nil {
+			return nil, err
+		}
+		if n := len(result); n == 0 || result[n-1].Kind != kind || result[n-1].Name != name {
+			result = append(result, Series{Kind: kind, Name: name})
+		}
+		last := &result[len(result)-1]
+		last.Points = append(last.Points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = []Series{}
+	}
+	return result, nil
+}
+
+// Trends compares the average usage on the first and the last day of the
+// days before now, for the host and every container with samples on both
+func (s *Store) Trends(ctx context.Context, days int, now time.Time) ([]system.UsageTrend, error) {
+	start := now.Add(-time.Duration(days) * 24 * time.Hour)
+	firstDayEnd := start.Add(24 * time.Hour)
+	lastDayStart := now.Add(-24 * time.Hour)
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT kind, name,"+
+			" AVG(CASE WHEN ts < ? THEN memory_mb END), AVG(CASE WHEN ts >= ? THEN memory_mb END),"+
+			" AVG(CASE WHEN ts < ? THEN cpu_percent END), AVG(CASE WHEN ts >= ? THEN cpu_percent END)"+
+			" FROM "+s.table+" WHERE ts BETWEEN ? AND ? GROUP BY kind, name ORDER BY kind, name",
+		firstDayEnd.UTC(), lastDayStart.UTC(), firstDayEnd.UTC(), lastDayStart.UTC(), start.UTC(), now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := []system.UsageTrend{}
+	for rows.Next() {
+		var kind, name string
+		var memStart, memEnd, cpuStart, cpuEnd sql.NullFloat64
+		if err := rows.Scan(&kind, &name, &memStart, &memEnd, &cpuStart, &cpuEnd); err != nil {
+			return nil, err
+		}
+		if !memStart.Valid || !memEnd.Valid {
+			continue // not sampled over the whole window
+		}
+		t := system.UsageTrend{
+			Kind:            kind,
+			Name:            name,
+			Days:            days,
+			MemoryStartMB:   round(memStart.Float64),
+			MemoryEndMB:     round(memEnd.Float64),
+			CPUStartPercent: round(cpuStart.Float64),
+			CPUEndPercent:   round(cpuEnd.Float64),
+		}
+		if memStart.Float64 > 0 {
+			t.MemoryChange = round((memEnd.Float64 - memStart.Float64) / memStart.Float64 * 100)
+		}
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// round rounds to two decimals
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,314 @@
+// Package hooks receives webhooks from third parties at /hooks/{name}, so
+// apps behind Forge do not each need a public webhook endpoint
+//
+// Each hook checks the sender's signature (GitHub, Stripe or a generic
+// HMAC) and fans the request out to its targets: a Redis list for workers
+// to consume, an internal service, a log line or a metric. Targets can be
+// limited to matching requests, such as GitHub push events:
+//
+//	when: {header.X-GitHub-Event: push, body.ref: refs/heads/main}
+//
+// The request is answered once every matching target is done: 200 when
+// they all succeeded, 502 when one failed, so the sender retries it. The
+// recent requests of each hook are kept in memory for inspection.
+package hooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+// Receipt statuses, also the results of forge_hook_requests_total
+const (
+	StatusDelivered = "delivered" // every matching target succeeded
+	StatusIgnored   = "ignored"   // no target matched
+	StatusFailed    = "failed"    // a target failed; the sender was answered 502
+	StatusRejected  = "rejected"  // the signature did not verify
+)
+
+var receiptStatuses = []string{StatusDelivered, StatusIgnored, StatusFailed, StatusRejected}
+
+const (
+	maxReceipts = 100
+	maxTargets  = 10
+)
+
+var (
+	// ErrInvalidHook is returned for hooks that cannot be stored
+	ErrInvalidHook = errors.New("invalid hook")
+	// ErrHookNotFound is returned for unknown hook names
+	ErrHookNotFound = errors.New("hook not found")
+	// ErrUnverified is returned for requests whose signature does not verify
+	ErrUnverified = errors.New("signature verification failed")
+	// ErrTargetsFailed is returned when a target of an accepted request failed
+	ErrTargetsFailed = errors.New("hook targets failed")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// Hook is an endpoint at /hooks/{name} and where its requests go
+type Hook struct {
+	Name     string            `json:"name" yaml:"name"`
+	Verify   Verify            `json:"verify" yaml:"verify"`
+	Targets  []Target          `json:"targets" yaml:"targets"`
+	Disabled bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Receipt records a request received by a hook
+type Receipt struct {
+	ID         string         `json:"id"`
+	Hook       string         `json:"hook"`
+	Received   time.Time      `json:"received"`
+	Status     string         `json:"status"`
+	Size       int            `json:"size"` // of the body in bytes
+	DurationMs float64        `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"`
+	Targets    []TargetResult `json:"targets,omitempty"`
+}
+
+// Status is a hook with its recent activity
+type Status struct {
+	Hook
+	LastReceipt *Receipt `json:"last_receipt,omitempty"`
+}
+
+type hooksFile struct {
+	Hooks []Hook `yaml:"hooks"`
+}
+
+// Manager stores hooks and handles the requests they receive
+type Manager struct {
+	mu         sync.RWMutex
+	hooks      map[string]Hook
+	receipts   map[string][]Receipt // by hook, oldest first
+	configPath string
+	targets    *Targets
+}
+
+// NewManager loads hooks from configPath; targets run their requests
+func NewManager(configPath string, targets *Targets) (*Manager, error) {
+	m := &Manager{
+		hooks:      make(map[string]Hook),
+		receipts:   make(map[string][]Receipt),
+		configPath: configPath,
+		targets:    targets,
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all hooks with their last receipt, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.hooks))
+	for name := range m.hooks {
+		list = append(list, m.status(name))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a hook with its last receipt
+func (m *Manager) Get(name string) (Status, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.hooks[name]; !ok {
+		return Status{}, ErrHookNotFound
+	}
+	return m.status(name), nil
+}
+
+// status builds a Status; m.mu must be held
+func (m *Manager) status(name string) Status {
+	st := Status{Hook: m.hooks[name]}
+	if list := m.receipts[name]; len(list) > 0 {
+		last := list[len(list)-1]
+		st.LastReceipt = &last
+	}
+	return st
+}
+
+// Receipts returns the recent requests of a hook, newest first
+func (m *Manager) Receipts(name string) ([]Receipt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.hooks[name]; !ok {
+		return nil, ErrHookNotFound
+	}
+	list := m.receipts[name]
+	out := make([]Receipt, len(list))
+	for i, rc := range list {
+		out[len(list)-1-i] = rc
+	}
+	return out, nil
+}
+
+// Save creates or replaces a hook. A hook saved without a secret keeps its
+// current one when verified the same way, or gets a generated one when
+// verified by HMAC; GitHub and Stripe hooks need the secret set at the
+// sender. It returns the generated secret, if any, and whether the hook
+// was created.
+func (m *Manager) Save(hook Hook) (string, bool, error) {
+	m.mu.RLock()
+	old, exists := m.hooks[hook.Name]
+	m.mu.RUnlock()
+	if hook.Verify.Secret == "" && old.Verify.Type == hook.Verify.Type {
+		hook.Verify.Secret = old.Verify.Secret
+	}
+	var generated string
+	if hook.Verify.Secret == "" && hook.Verify.Type == VerifyHMAC {
+		generated = newSecret()
+		hook.Verify.Secret = generated
+	}
+	if err := validateHook(&hook); err != nil {
+		return "", false, err
+	}
+
+	m.mu.Lock()
+	m.hooks[hook.Name] = hook
+	m.mu.Unlock()
+
+	return generated, !exists, m.save()
+}
+
+// SetDisabled stops or resumes accepting requests; a disabled hook
+// answers 404
+func (m *Manager) SetDisabled(name string, disabled bool) (Status, error) {
+	m.mu.Lock()
+	hook, ok := m.hooks[name]
+	if !ok {
+		m.mu.Unlock()
+		return Status{}, ErrHookNotFound
+	}
+	hook.Disabled = disabled
+	m.hooks[name] = hook
+	st := m.status(name)
+	m.mu.Unlock()
+	return st, m.save()
+}
+
+// Remove deletes a hook with its receipts and metrics
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.hooks[name]; !ok {
+		m.mu.Unlock()
+		return ErrHookNotFound
+	}
+	delete(m.hooks, name)
+	delete(m.receipts, name)
+	m.mu.Unlock()
+
+	for _, status := range receiptStatuses {
+		metrics.HookRequestsTotal.DeleteLabelValues(name, status)
+	}
+	return m.save()
+}
+
+// record appends a receipt to the log of its hook, evicting the oldest
+// beyond maxReceipts
+func (m *Manager) record(rc Receipt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.hooks[rc.Hook]; !ok {
+		return
+	}
+	list := append(m.receipts[rc.Hook], rc)
+	if over := len(list) - maxReceipts; over > 0 {
+		list = list[over:]
+	}
+	m.receipts[rc.Hook] = list
+	metrics.HookRequestsTotal.WithLabelValues(rc.Hook, rc.Status).Inc()
+}
+
+// validateHook checks a hook and fills in defaults
+func validateHook(hook *Hook) error {
+	if !namePattern.MatchString(hook.Name) {
+		return fmt.Errorf("%w: name must be 1-64 characters of letters, digits, '_' or '-'", ErrInvalidHook)
+	}
+	if err := validateVerify(&hook.Verify); err != nil {
+		return fmt.Errorf("%w: verify: %v", ErrInvalidHook, err)
+	}
+	if len(hook.Targets) == 0 || len(hook.Targets) > maxTargets {
+		return fmt.Errorf("%w: 1-%d targets are required", ErrInvalidHook, maxTargets)
+	}
+	for i := range hook.Targets {
+		if err := validateTarget(&hook.Targets[i]); err != nil {
+			return fmt.Errorf("%w: target %d: %v", ErrInvalidHook, i, err)
+		}
+	}
+	if err := resources.ValidateLabels(hook.Labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHook, err)
+	}
+	return nil
+}
+
+func newSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// load reads hooks from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f hooksFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, hook := range f.Hooks {
+		m.hooks[hook.Name] = hook
+	}
+	return nil
+}
+
+// save writes hooks to the config file, readable only by Forge as it holds
+// their secrets
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := hooksFile{Hooks: make([]Hook, 0, len(m.hooks))}
+	for _, hook := range m.hooks {
+		f.Hooks = append(f.Hooks, hook)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Hooks, func(i, j int) bool { return f.Hooks[i].Name < f.Hooks[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0600)
+}
@@ -0,0 +1,84 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Receive handles a request to a hook: it checks the signature, then runs
+// the matching targets in parallel and waits for them. The request is
+// recorded in the hook's receipts. It returns ErrHookNotFound for unknown
+// and disabled hooks, ErrUnverified for bad signatures and
+// ErrTargetsFailed when a target failed.
+func (m *Manager) Receive(ctx context.Context, name string, header http.Header, body []byte) (Receipt, error) {
+	start := time.Now()
+	m.mu.RLock()
+	hook, ok := m.hooks[name]
+	m.mu.RUnlock()
+	if !ok || hook.Disabled {
+		return Receipt{}, ErrHookNotFound
+	}
+
+	rc := Receipt{ID: newID("rcv_"), Hook: name, Received: start.UTC(), Size: len(body)}
+	if err := hook.Verify.check(header, body, start); err != nil {
+		rc.Status = StatusRejected
+		rc.Error = err.Error()
+		rc.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		m.record(rc)
+		return rc, fmt.Errorf("%w: %v", ErrUnverified, err)
+	}
+
+	// Targets finish even if the sender hangs up, so a retried request is
+	// not half delivered
+	ctx = context.WithoutCancel(ctx)
+	req := newRequest(rc.ID, name, rc.Received, header, body)
+	rc.Targets = make([]TargetResult, len(hook.Targets))
+	var wg sync.WaitGroup
+	for i, target := range hook.Targets {
+		res := &rc.Targets[i]
+		*res = TargetResult{Index: i, Type: target.Type, Status: ResultSkipped}
+		if !req.matches(target.When) {
+			continue
+		}
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			started := time.Now()
+			output, err := m.targets.run(ctx, target, req)
+			res.DurationMs = float64(time.Since(started).Microseconds()) / 1000
+			res.Output = output
+			res.Status = ResultOK
+			if err != nil {
+				res.Status = ResultFailed
+				res.Error = err.Error()
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	rc.Status = StatusIgnored
+	var failed []string
+	for _, res := range rc.Targets {
+		switch res.Status {
+		case ResultFailed:
+			failed = append(failed, fmt.Sprintf("target %d (%s): %s", res.Index, res.Type, res.Error))
+		case ResultOK:
+			rc.Status = StatusDelivered
+		}
+	}
+	if len(failed) > 0 {
+		rc.Status = StatusFailed
+		rc.Error = strings.Join(failed, "; ")
+	}
+	rc.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	m.record(rc)
+
+	if rc.Status == StatusFailed {
+		return rc, fmt.Errorf("%w: %s", ErrTargetsFailed, rc.Error)
+	}
+	return rc, nil
+}
@@ -0,0 +1,379 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/observe"
+	"github.com/forge/api/internal/targets"
+)
+
+// Target types
+const (
+	TargetQueue  = "queue"  // push the request onto a Redis list
+	TargetHTTP   = "http"   // forward the request to an internal service
+	TargetLog    = "log"    // write a log line to Loki
+	TargetMetric = "metric" // increment a counter
+)
+
+// Target result statuses
+const (
+	ResultOK      = "ok"
+	ResultFailed  = "failed"
+	ResultSkipped = "skipped" // the request did not match When
+)
+
+const (
+	defaultMaxLen  = 10000
+	maxQueueLen    = 1000000
+	defaultTimeout = "10s"
+	maxTimeout     = 30 * time.Second
+	queueTimeout   = 5 * time.Second
+	maxConditions  = 10
+	// maxResponse bounds the response body quoted when a forward fails
+	maxResponse = 512
+)
+
+// dropHeaders are not forwarded: hop-by-hop headers, those set by the
+// client and credentials meant for Forge
+var dropHeaders = map[string]bool{
+	"Connection": true, "Keep-Alive": true, "Te": true, "Trailer": true, "Transfer-Encoding": true, "Upgrade": true,
+	"Host": true, "Content-Length": true, "Accept-Encoding": true, "Authorization": true, "Cookie": true,
+}
+
+// selectors are those of a hook request
+var selectors = targets.Selectors{Valid: validSelector, Hint: "header.<name>, body or body.<path>"}
+
+// Target is where a hook sends the requests it accepts. Type selects which
+// of the other fields apply.
+type Target struct {
+	Type string `json:"type" yaml:"type"` // queue, http, log or metric
+
+	// When limits the target to requests where every selector matches its
+	// pattern, in which * matches any text. Selectors are header.<name>,
+	// body.<path> (dot-separated keys and array indexes into a JSON body)
+	// and body.
+	When map[string]string `json:"when,omitempty" yaml:"when,omitempty"`
+
+	// queue: Redis list the request is pushed onto as JSON, trimmed to its
+	// newest MaxLen items (default 10000)
+	Key    string `json:"key,omitempty" yaml:"key,omitempty"`
+	MaxLen int64  `json:"max_len,omitempty" yaml:"max_len,omitempty"`
+
+	// http: URL the request is POSTed to with its headers and body; any
+	// 2xx within Timeout (default 10s, at most 30s) is success
+	URL     string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Timeout string            `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// log: line written at Level (default info) with the body as a field
+	Level   string `json:"level,omitempty" yaml:"level,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // default "<hook> hook received"
+
+	// metric: counter incremented by one
+	Metric string `json:"metric,omitempty" yaml:"metric,omitempty"`
+
+	// log and metric: labels added. Message and label values may hold
+	// ${selector} templates, such as ${header.X-GitHub-Event}.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// TargetResult is what a target did with a request
+type TargetResult struct {
+	Index      int     `json:"index"` // of the target in the hook
+	Type       string  `json:"type"`
+	Status     string  `json:"status"`
+	Output     string  `json:"output,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms,omitempty"`
+}
+
+// Queue holds the lists queue targets push onto; *cache.RedisClient
+// implements it
+type Queue interface {
+	RPush(ctx context.Context, key, value string, maxLen int64) (int64, error)
+}
+
+// Targets sends requests to hook targets
+type Targets struct {
+	queue      Queue
+	queueUp    func() bool
+	logs       targets.LogSink
+	metrics    targets.MetricSink
+	httpClient *http.Client
+}
+
+// NewTargets creates a target runner. queue, when set, is used while
+// queueUp reports it reachable.
+func NewTargets(queue Queue, queueUp func() bool, logs targets.LogSink, metrics targets.MetricSink) *Targets {
+	return &Targets{
+		queue:   queue,
+		queueUp: queueUp,
+		logs:    logs,
+		metrics: metrics,
+		httpClient: &http.Client{
+			// Forwards are bounded by the target timeout instead
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// request is a request accepted by a hook
+type request struct {
+	ID       string
+	Hook     string
+	Received time.Time
+	Header   http.Header
+	Body     []byte
+	json     any // the decoded body, nil when it is not JSON
+}
+
+// queueMessage is what queue targets push: the request, with its body
+// embedded as JSON when it is JSON and as a string otherwise
+type queueMessage struct {
+	ID       string            `json:"id"`
+	Hook     string            `json:"hook"`
+	Received time.Time         `json:"received"`
+	Headers  map[string]string `json:"headers"`
+	Body     any               `json:"body"`
+}
+
+func newRequest(id, hook string, received time.Time, header http.Header, body []byte) *request {
+	return &request{ID: id, Hook: hook, Received: received, Header: header, Body: body, json: targets.DecodeJSON(body)}
+}
+
+// Lookup returns the value of a selector
+func (r *request) Lookup(sel string) (string, bool) {
+	if name, ok := strings.CutPrefix(sel, "header."); ok {
+		values := r.Header.Values(name)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	}
+	if sel == "body" {
+		return string(r.Body), true
+	}
+	p, _ := strings.CutPrefix(sel, "body.")
+	return targets.Path(r.json, p)
+}
+
+// matches reports whether the request matches every condition of when
+func (r *request) matches(when map[string]string) bool {
+	for sel, pattern := range when {
+		v, ok := r.Lookup(sel)
+		if !ok || !glob(pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// glob matches s against pattern, in which * matches any text
+func glob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// run sends a request to a target and returns what it did
+func (t *Targets) run(ctx context.Context, target Target, req *request) (string, error) {
+	switch target.Type {
+	case TargetQueue:
+		return t.runQueue(ctx, target, req)
+	case TargetHTTP:
+		return t.runHTTP(ctx, target, req)
+	case TargetLog:
+		if t.logs == nil {
+			return "", errors.New("logs are not available")
+		}
+		msg := target.Message
+		if msg == "" {
+			msg = req.Hook + " hook received"
+		}
+		fields := map[string]any{"hook": req.Hook, "receipt": req.ID, "body": string(req.Body)}
+		if req.json != nil {
+			fields["body"] = json.RawMessage(req.Body)
+		}
+		err := t.logs.PushEntry(ctx, observe.Entry{
+			Level:     target.Level,
+			Message:   targets.Expand(req, msg),
+			Labels:    targets.ExpandLabels(req, target.Labels, map[string]string{"source": "hook", "hook": req.Hook}),
+			Fields:    fields,
+			Timestamp: req.Received,
+		})
+		if err != nil {
+			return "", err
+		}
+		return "logged", nil
+	case TargetMetric:
+		if t.metrics == nil {
+			return "", errors.New("metrics are not available")
+		}
+		if err := t.metrics.Push(target.Metric, "counter", 1, targets.ExpandLabels(req, target.Labels, nil)); err != nil {
+			return "", err
+		}
+		return "incremented " + target.Metric, nil
+	}
+	return "", fmt.Errorf("unknown target type %q", target.Type)
+}
+
+func (t *Targets) runQueue(ctx context.Context, target Target, req *request) (string, error) {
+	if t.queue == nil || (t.queueUp != nil && !t.queueUp()) {
+		return "", errors.New("redis is not available")
+	}
+	msg := queueMessage{ID: req.ID, Hook: req.Hook, Received: req.Received, Headers: make(map[string]string), Body: string(req.Body)}
+	if req.json != nil {
+		msg.Body = json.RawMessage(req.Body)
+	}
+	for k, v := range req.Header {
+		if !dropHeaders[k] && len(v) > 0 {
+			msg.Headers[k] = v[0]
+		}
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queueTimeout)
+	defer cancel()
+	n, err := t.queue.RPush(ctx, target.Key, string(data), target.MaxLen)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pushed onto %s (length %d)", target.Key, n), nil
+}
+
+func (t *Targets) runHTTP(ctx context.Context, target Target, req *request) (string, error) {
+	timeout, _ := time.ParseDuration(target.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fwd, err := http.NewRequestWithContext(ctx, "POST", target.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return "", err
+	}
+	for k, v := range req.Header {
+		if !dropHeaders[k] {
+			fwd.Header[k] = v
+		}
+	}
+	for k, v := range target.Headers {
+		fwd.Header.Set(k, v)
+	}
+	fwd.Header.Set("X-Forge-Hook", req.Hook)
+	fwd.Header.Set("X-Forge-Receipt", req.ID)
+
+	resp, err := t.httpClient.Do(fwd)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	output := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return output, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return output, nil
+}
+
+// validSelector reports whether sel names a header or a part of the body
+func validSelector(sel string) bool {
+	if name, ok := strings.CutPrefix(sel, "header."); ok {
+		return name != "" && !strings.ContainsAny(name, " :")
+	}
+	if sel == "body" {
+		return true
+	}
+	p, ok := strings.CutPrefix(sel, "body.")
+	return ok && targets.ValidPath(p)
+}
+
+// validateTarget checks a target and fills in defaults
+func validateTarget(t *Target) error {
+	if len(t.When) > maxConditions {
+		return fmt.Errorf("too many conditions in when (max %d)", maxConditions)
+	}
+	for sel := range t.When {
+		if !validSelector(sel) {
+			return fmt.Errorf("invalid selector in when: %q (header.<name>, body or body.<path>)", sel)
+		}
+	}
+	if t.Type != TargetLog && t.Type != TargetMetric && len(t.Labels) > 0 {
+		return fmt.Errorf("labels are only valid for log and metric")
+	}
+	if err := selectors.ValidateLabels(t.Labels); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case TargetQueue:
+		if t.Key == "" {
+			return fmt.Errorf("key is required")
+		}
+		if t.MaxLen == 0 {
+			t.MaxLen = defaultMaxLen
+		}
+		if t.MaxLen < 1 || t.MaxLen > maxQueueLen {
+			return fmt.Errorf("max_len must be 1-%d", maxQueueLen)
+		}
+	case TargetHTTP:
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http(s) URL")
+		}
+		if t.Timeout == "" {
+			t.Timeout = defaultTimeout
+		}
+		if d, err := time.ParseDuration(t.Timeout); err != nil || d <= 0 || d > maxTimeout {
+			return fmt.Errorf("timeout must be a duration of at most %s", maxTimeout)
+		}
+	case TargetLog:
+		if err := selectors.ValidateLog(&t.Level, t.Message, t.Labels, "job", "level", "source", "hook"); err != nil {
+			return err
+		}
+	case TargetMetric:
+		if err := targets.ValidateMetric(t.Metric, "counter", t.Labels); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("type must be queue, http, log or metric")
+	}
+	if t.Type != TargetQueue && (t.Key != "" || t.MaxLen != 0) {
+		return fmt.Errorf("key and max_len are only valid for queue")
+	}
+	if t.Type != TargetHTTP && (t.URL != "" || len(t.Headers) > 0 || t.Timeout != "") {
+		return fmt.Errorf("url, headers and timeout are only valid for http")
+	}
+	if t.Type != TargetLog && (t.Level != "" || t.Message != "") {
+		return fmt.Errorf("level and message are only valid for log")
+	}
+	if t.Type != TargetMetric && t.Metric != "" {
+		return fmt.Errorf("metric is only valid for metric")
+	}
+	return nil
+}
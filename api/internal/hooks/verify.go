@@ -0,0 +1,180 @@
+package hooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verification schemes
+const (
+	VerifyGitHub = "github" // X-Hub-Signature-256: sha256=<hex HMAC-SHA256 of the body>
+	VerifyStripe = "stripe" // Stripe-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	VerifyHMAC   = "hmac"   // an HMAC of the body in a configurable header
+	VerifyNone   = "none"   // no check: anyone who knows the URL can post
+)
+
+const defaultStripeTolerance = "5m"
+
+var hmacAlgorithms = map[string]func() hash.Hash{"sha256": sha256.New, "sha1": sha1.New, "sha512": sha512.New}
+
+// Verify is how a hook checks that requests come from their sender
+type Verify struct {
+	Type string `json:"type" yaml:"type"` // github, stripe, hmac or none
+
+	// hmac: the header holding the signature (default X-Signature), its
+	// algorithm (sha256, sha1 or sha512; default sha256) and encoding (hex
+	// or base64; default hex), and the text before it, such as "sha256="
+	Header    string `json:"header,omitempty" yaml:"header,omitempty"`
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Encoding  string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	Prefix    string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+
+	// stripe: how far the signed timestamp may be from now (default 5m)
+	Tolerance string `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+
+	// Secret signs the requests; never returned by the API once set
+	Secret string `json:"-" yaml:"secret,omitempty"`
+}
+
+// check verifies the signature of a request
+func (v Verify) check(h http.Header, body []byte, now time.Time) error {
+	switch v.Type {
+	case VerifyNone:
+		return nil
+	case VerifyGitHub:
+		sig, ok := strings.CutPrefix(h.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return errors.New("missing X-Hub-Signature-256 header")
+		}
+		return compare(sig, hex.DecodeString, sign(sha256.New, v.Secret, body))
+	case VerifyStripe:
+		return v.checkStripe(h.Get("Stripe-Signature"), body, now)
+	case VerifyHMAC:
+		value := h.Get(v.Header)
+		if value == "" {
+			return fmt.Errorf("missing %s header", v.Header)
+		}
+		sig, ok := strings.CutPrefix(value, v.Prefix)
+		if !ok {
+			return fmt.Errorf("%s header does not start with %q", v.Header, v.Prefix)
+		}
+		decode := hex.DecodeString
+		if v.Encoding == "base64" {
+			decode = base64.StdEncoding.DecodeString
+		}
+		return compare(sig, decode, sign(hmacAlgorithms[v.Algorithm], v.Secret, body))
+	}
+	return fmt.Errorf("unknown verification type %q", v.Type)
+}
+
+// checkStripe verifies a Stripe-Signature header. It may carry several v1
+// signatures while the secret is being rolled; one must match.
+func (v Verify) checkStripe(header string, body []byte, now time.Time) error {
+	if header == "" {
+		return errors.New("missing Stripe-Signature header")
+	}
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = val
+		case "v1":
+			sigs = append(sigs, val)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return errors.New("malformed Stripe-Signature header")
+	}
+	tolerance, _ := time.ParseDuration(v.Tolerance)
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("timestamp outside the tolerance")
+	}
+
+	expected := sign(sha256.New, v.Secret, []byte(ts+"."), body)
+	for _, sig := range sigs {
+		if compare(sig, hex.DecodeString, expected) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
+
+// sign returns the HMAC of parts keyed with secret
+func sign(h func() hash.Hash, secret string, parts ...[]byte) []byte {
+	mac := hmac.New(h, []byte(secret))
+	for _, p := range parts {
+		mac.Write(p)
+	}
+	return mac.Sum(nil)
+}
+
+// compare decodes sig and compares it with expected in constant time
+func compare(sig string, decode func(string) ([]byte, error), expected []byte) error {
+	got, err := decode(strings.TrimSpace(sig))
+	if err != nil || !hmac.Equal(got, expected) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// validateVerify checks a verification scheme and fills in defaults
+func validateVerify(v *Verify) error {
+	if v.Type != VerifyHMAC && (v.Header != "" || v.Algorithm != "" || v.Encoding != "" || v.Prefix != "") {
+		return errors.New("header, algorithm, encoding and prefix are only valid for hmac")
+	}
+	if v.Type != VerifyStripe && v.Tolerance != "" {
+		return errors.New("tolerance is only valid for stripe")
+	}
+	switch v.Type {
+	case VerifyNone:
+		if v.Secret != "" {
+			return errors.New("secret is not used by none")
+		}
+		return nil
+	case VerifyGitHub:
+	case VerifyStripe:
+		if v.Tolerance == "" {
+			v.Tolerance = defaultStripeTolerance
+		}
+		if d, err := time.ParseDuration(v.Tolerance); err != nil || d <= 0 {
+			return fmt.Errorf("invalid tolerance: %q", v.Tolerance)
+		}
+	case VerifyHMAC:
+		if v.Header == "" {
+			v.Header = "X-Signature"
+		}
+		v.Header = http.CanonicalHeaderKey(v.Header)
+		if v.Algorithm == "" {
+			v.Algorithm = "sha256"
+		}
+		if hmacAlgorithms[v.Algorithm] == nil {
+			return fmt.Errorf("algorithm must be sha256, sha1 or sha512")
+		}
+		if v.Encoding == "" {
+			v.Encoding = "hex"
+		}
+		if v.Encoding != "hex" && v.Encoding != "base64" {
+			return fmt.Errorf("encoding must be hex or base64")
+		}
+	default:
+		return fmt.Errorf("type must be github, stripe, hmac or none")
+	}
+	if len(v.Secret) < 8 {
+		return errors.New("secret must be at least 8 characters")
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package hooks
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyCheck(t *testing.T) {
+	const secret = "s3cret-key"
+	body := []byte(`{"action":"opened"}`)
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	oldTS := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+
+	githubSig := "sha256=" + hex.EncodeToString(sign(sha256.New, secret, body))
+	stripeSig := hex.EncodeToString(sign(sha256.New, secret, []byte(ts+"."), body))
+	oldStripeSig := hex.EncodeToString(sign(sha256.New, secret, []byte(oldTS+"."), body))
+
+	tests := []struct {
+		name    string
+		verify  Verify
+		header  http.Header
+		body    []byte
+		wantErr bool
+	}{
+		{
+			name:   "none",
+			verify: Verify{Type: VerifyNone},
+			header: http.Header{},
+		},
+		{
+			name:   "github valid",
+			verify: Verify{Type: VerifyGitHub, Secret: secret},
+			header: http.Header{"X-Hub-Signature-256": {githubSig}},
+		},
+		{
+			name:    "github missing header",
+			verify:  Verify{Type: VerifyGitHub, Secret: secret},
+			header:  http.Header{},
+			wantErr: true,
+		},
+		{
+			name:    "github without prefix",
+			verify:  Verify{Type: VerifyGitHub, Secret: secret},
+			header:  http.Header{"X-Hub-Signature-256": {githubSig[len("sha256="):]}},
+			wantErr: true,
+		},
+		{
+			name:    "github wrong secret",
+			verify:  Verify{Type: VerifyGitHub, Secret: "other-secret"},
+			header:  http.Header{"X-Hub-Signature-256": {githubSig}},
+			wantErr: true,
+		},
+		{
+			name:    "github tampered body",
+			verify:  Verify{Type: VerifyGitHub, Secret: secret},
+			header:  http.Header{"X-Hub-Signature-256": {githubSig}},
+			body:    []byte(`{"action":"closed"}`),
+			wantErr: true,
+		},
+		{
+			name:   "stripe valid",
+			verify: Verify{Type: VerifyStripe, Secret: secret, Tolerance: "5m"},
+			header: http.Header{"Stripe-Signature": {"t=" + ts + ",v1=" + stripeSig}},
+		},
+		{
+			name:   "stripe one of several signatures",
+			verify: Verify{Type: VerifyStripe, Secret: secret, Tolerance: "5m"},
+			header: http.Header{"Stripe-Signature": {"t=" + ts + ",v1=00ff,v1=" + stripeSig}},
+		},
+		{
+			name:    "stripe outside tolerance",
+			verify:  Verify{Type: VerifyStripe, Secret: secret, Tolerance: "5m"},
+			header:  http.Header{"Stripe-Signature": {"t=" + oldTS + ",v1=" + oldStripeSig}},
+			wantErr: true,
+		},
+		{
+			name:    "stripe signature for another timestamp",
+			verify:  Verify{Type: VerifyStripe, Secret: secret, Tolerance: "5m"},
+			header:  http.Header{"Stripe-Signature": {"t=" + ts + ",v1=" + oldStripeSig}},
+			wantErr: true,
+		},
+		{
+			name:    "stripe malformed",
+			verify:  Verify{Type: VerifyStripe, Secret: secret, Tolerance: "5m"},
+			header:  http.Header{"Stripe-Signature": {"v1=" + stripeSig}},
+			wantErr: true,
+		},
+		{
+			name:   "hmac hex",
+			verify: Verify{Type: VerifyHMAC, Secret: secret, Header: "X-Signature", Algorithm: "sha256", Encoding: "hex"},
+			header: http.Header{"X-Signature": {hex.EncodeToString(sign(sha256.New, secret, body))}},
+		},
+		{
+			name:   "hmac base64 sha1 with prefix",
+			verify: Verify{Type: VerifyHMAC, Secret: secret, Header: "X-Sig", Algorithm: "sha1", Encoding: "base64", Prefix: "sha1="},
+			header: http.Header{"X-Sig": {"sha1=" + base64.StdEncoding.EncodeToString(sign(sha1.New, secret, body))}},
+		},
+		{
+			name:    "hmac missing prefix",
+			verify:  Verify{Type: VerifyHMAC, Secret: secret, Header: "X-Sig", Algorithm: "sha1", Encoding: "base64", Prefix: "sha1="},
+			header:  http.Header{"X-Sig": {base64.StdEncoding.EncodeToString(sign(sha1.New, secret, body))}},
+			wantErr: true,
+		},
+		{
+			name:    "hmac wrong algorithm",
+			verify:  Verify{Type: VerifyHMAC, Secret: secret, Header: "X-Signature", Algorithm: "sha512", Encoding: "hex"},
+			header:  http.Header{"X-Signature": {hex.EncodeToString(sign(sha256.New, secret, body))}},
+			wantErr: true,
+		},
+		{
+			name:    "hmac not hex",
+			verify:  Verify{Type: VerifyHMAC, Secret: secret, Header: "X-Signature", Algorithm: "sha256", Encoding: "hex"},
+			header:  http.Header{"X-Signature": {"not-hex"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			verify:  Verify{Type: "gitlab"},
+			header:  http.Header{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := body
+			if tt.body != nil {
+				b = tt.body
+			}
+			err := tt.verify.check(tt.header, b, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		verify  Verify
+		want    Verify
+		wantErr bool
+	}{
+		{
+			name:   "hmac defaults",
+			verify: Verify{Type: VerifyHMAC, Secret: "12345678", Header: "x-hook-signature"},
+			want:   Verify{Type: VerifyHMAC, Secret: "12345678", Header: "X-Hook-Signature", Algorithm: "sha256", Encoding: "hex"},
+		},
+		{
+			name:   "stripe default tolerance",
+			verify: Verify{Type: VerifyStripe, Secret: "12345678"},
+			want:   Verify{Type: VerifyStripe, Secret: "12345678", Tolerance: defaultStripeTolerance},
+		},
+		{name: "none", verify: Verify{Type: VerifyNone}, want: Verify{Type: VerifyNone}},
+		{name: "none with secret", verify: Verify{Type: VerifyNone, Secret: "12345678"}, wantErr: true},
+		{name: "short secret", verify: Verify{Type: VerifyGitHub, Secret: "short"}, wantErr: true},
+		{name: "header on github", verify: Verify{Type: VerifyGitHub, Secret: "12345678", Header: "X-Sig"}, wantErr: true},
+		{name: "tolerance on hmac", verify: Verify{Type: VerifyHMAC, Secret: "12345678", Tolerance: "1m"}, wantErr: true},
+		{name: "bad tolerance", verify: Verify{Type: VerifyStripe, Secret: "12345678", Tolerance: "-1m"}, wantErr: true},
+		{name: "bad algorithm", verify: Verify{Type: VerifyHMAC, Secret: "12345678", Algorithm: "md5"}, wantErr: true},
+		{name: "bad encoding", verify: Verify{Type: VerifyHMAC, Secret: "12345678", Encoding: "base32"}, wantErr: true},
+		{name: "unknown type", verify: Verify{Type: "gitlab", Secret: "12345678"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.verify
+			err := validateVerify(&v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateVerify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && v != tt.want {
+				t.Errorf("validateVerify() = %+v, want %+v", v, tt.want)
+			}
+		})
+	}
+}
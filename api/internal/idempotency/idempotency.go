@@ -0,0 +1,288 @@
+// Package idempotency replays the responses of retried mutating requests
+//
+// Clients send an Idempotency-Key header (any unique string, e.g. a UUID)
+// with a POST. The first request with a key runs and its response is kept
+// for TTL; retries with the same key and the same request get the kept
+// response instead of running again, marked with Idempotent-Replayed.
+// Keys are scoped to the caller, so callers cannot see each other's
+// responses. A retry while the first request still runs is refused with
+// 409, and reusing a key for a different request with 422. Server errors
+// (5xx), 401 and 429 answers are not kept, so a retry runs again.
+// Responses are kept in Redis when available, so replicas share them, and
+// in memory otherwise.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+const (
+	// Header carries the client's idempotency key
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set to "true" on replayed responses
+	ReplayedHeader = "Idempotent-Replayed"
+	// DefaultTTL is how long responses are kept
+	DefaultTTL = 24 * time.Hour
+	// MaxKeyLength bounds keys
+	MaxKeyLength = 255
+	// MaxResponseBytes bounds kept responses; larger ones are not kept
+	MaxResponseBytes = 1 << 20
+	// pendingTTL bounds how long a request that never finishes (e.g. the
+	// process died) blocks its key
+	pendingTTL = 5 * time.Minute
+	keyPrefix  = "forge:idempotency:"
+)
+
+var (
+	// ErrInProgress is returned while the first request with a key runs
+	ErrInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+	// ErrMismatch is returned when a key is reused for a different request
+	ErrMismatch = errors.New("Idempotency-Key was already used for a different request")
+	// ErrInvalidKey is returned for empty, overlong or non-printable keys
+	ErrInvalidKey = fmt.Errorf("Idempotency-Key must be 1-%d printable ASCII characters", MaxKeyLength)
+)
+
+// Prefixes are the endpoints whose POSTs honour Idempotency-Key: proxy
+// routes, apps, SQL statements, cache writes, and their RPCs
+var Prefixes = []string{
+	"/api/v1/routes",
+	"/api/v1/apps",
+	"/api/v1/db/execute",
+	"/api/v1/cache/",
+	"/forge.v1.DatabaseService/Execute",
+	"/forge.v1.CacheService/Set",
+}
+
+// Applies reports whether Idempotency-Key is honoured for a request
+func Applies(method, path string) bool {
+	if method != http.MethodPost {
+		return false
+	}
+	for _, p := range Prefixes {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidKey reports whether key may be used
+func ValidKey(key string) bool {
+	if key == "" || len(key) > MaxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Backend keeps records; *cache.RedisClient implements it
+type Backend interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+// Response is a kept response
+type Response struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    []byte              `json:"body,omitempty"`
+}
+
+// record is what is kept under a key: the request fingerprint and, once
+// the request finished, its response
+type record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+// Store keeps responses by caller and key
+type Store struct {
+	// shared, when set, is used while sharedUp reports it reachable;
+	// otherwise responses are kept in local
+	shared   Backend
+	sharedUp func() bool
+	local    Backend
+	ttl      time.Duration
+}
+
+// NewStore creates a store keeping responses for ttl, in shared while
+// sharedUp reports it reachable and in memory otherwise
+func NewStore(shared Backend, sharedUp func() bool, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{shared: shared, sharedUp: sharedUp, local: newMemoryBackend(), ttl: ttl}
+}
+
+// backend returns where responses are kept right now, so Redis is used
+// once it comes up even if it was down at startup
+func (s *Store) backend() Backend {
+	if s.shared != nil && s.sharedUp() {
+		return s.shared
+	}
+	return s.local
+}
+
+// FromConfig creates a store from cfg; nil when idempotency keys are
+// disabled. shared, when set, keeps the responses while sharedUp reports
+// it reachable.
+func FromConfig(cfg config.Idempotency, shared Backend, sharedUp func() bool) *Store {
+	if cfg.TTL == 0 {
+		return nil
+	}
+	return NewStore(shared, sharedUp, cfg.TTL)
+}
+
+// TTL returns how long responses are kept
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+// Fingerprint identifies a request by method, path, query and body
+func Fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func storeKey(scope, key string) string {
+	sum := sha256.Sum256([]byte(scope + "\x00" + key))
+	return keyPrefix + hex.EncodeToString(sum[:])
+}
+
+// Begin claims key for a request of scope (the caller). It returns the
+// kept response when the request already ran; otherwise the caller runs
+// the request and calls Finish or Abandon. ErrInProgress and ErrMismatch
+// are returned for concurrent and different requests with the key.
+func (s *Store) Begin(ctx context.Context, scope, key, fingerprint string) (*Response, error) {
+	k := storeKey(scope, key)
+	pending, _ := json.Marshal(record{Fingerprint: fingerprint})
+	ok, err := s.backend().SetNX(ctx, k, string(pending), pendingTTL)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, nil
+	}
+
+	v, found, err := s.backend().Get(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		// Expired between the two calls; claim it again
+		return s.Begin(ctx, scope, key, fingerprint)
+	}
+	var rec record
+	if err := json.Unmarshal([]byte(v), &rec); err != nil {
+		return nil, err
+	}
+	if rec.Fingerprint != fingerprint {
+		return nil, ErrMismatch
+	}
+	if rec.Response == nil {
+		return nil, ErrInProgress
+	}
+	return rec.Response, nil
+}
+
+// Finish keeps the response of a request claimed with Begin
+func (s *Store) Finish(ctx context.Context, scope, key, fingerprint string, resp Response) error {
+	data, err := json.Marshal(record{Fingerprint: fingerprint, Response: &resp})
+	if err != nil {
+		return err
+	}
+	return s.backend().Set(ctx, storeKey(scope, key), string(data), s.ttl)
+}
+
+// Abandon releases a key whose request should run again when retried
+func (s *Store) Abandon(ctx context.Context, scope, key string) error {
+	_, err := s.backend().Delete(ctx, storeKey(scope, key))
+	return err
+}
+
+// memoryBackend keeps records in this process, for single-replica setups
+// without Redis
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	lastGC  time.Time
+}
+
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{entries: make(map[string]memoryEntry)}
+}
+
+// getLocked returns the unexpired value of key, dropping expired entries
+// every minute; b.mu must be held
+func (b *memoryBackend) getLocked(key string, now time.Time) (string, bool) {
+	if now.Sub(b.lastGC) > time.Minute {
+		for k, e := range b.entries {
+			if !now.Before(e.expires) {
+				delete(b.entries, k)
+			}
+		}
+		b.lastGC = now
+	}
+	e, ok := b.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return "", false
+	}
+	return e.value, true
+}
+
+func (b *memoryBackend) Get(_ context.Context, key string) (string, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.getLocked(key, time.Now())
+	return v, ok, nil
+}
+
+func (b *memoryBackend) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (b *memoryBackend) SetNX(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if _, ok := b.getLocked(key, now); ok {
+		return false, nil
+	}
+	b.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (b *memoryBackend) Delete(_ context.Context, key string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.entries[key]
+	delete(b.entries, key)
+	return ok, nil
+}
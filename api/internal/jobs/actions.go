@@ -0,0 +1,267 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Action types
+const (
+	ActionHTTP    = "http"
+	ActionSQL     = "sql"
+	ActionCache   = "cache"
+	ActionRestart = "restart"
+	ActionBackup  = "backup"
+)
+
+// Cache operations
+const (
+	CacheSet    = "set"
+	CacheDelete = "delete"
+)
+
+const (
+	// DefaultBackupKeep is how many backups of a job are kept by default
+	DefaultBackupKeep = 7
+	maxStatement      = 64 << 10
+)
+
+var (
+	databasePattern  = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+	containerPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+	httpMethods      = map[string]bool{"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+)
+
+// Action is what a job does. Type selects which of the other fields apply.
+type Action struct {
+	Type string `json:"type" yaml:"type"` // http, sql, cache, restart or backup
+
+	// http: request to send; any 2xx is success unless ExpectStatus is set
+	Method       string            `json:"method,omitempty" yaml:"method,omitempty"` // default GET, or POST with a body
+	URL          string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body         string            `json:"body,omitempty" yaml:"body,omitempty"`
+	ExpectStatus int               `json:"expect_status,omitempty" yaml:"expect_status,omitempty"`
+
+	// sql: statement run in Database (optional); backup: database dumped
+	Database  string `json:"database,omitempty" yaml:"database,omitempty"`
+	Statement string `json:"statement,omitempty" yaml:"statement,omitempty"`
+
+	// cache: set Key to Value (expiring after TTL when set), or delete Key
+	Op    string `json:"op,omitempty" yaml:"op,omitempty"`
+	Key   string `json:"key,omitempty" yaml:"key,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	TTL   string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+
+	// restart: container name or ID
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+
+	// backup: number of backups of this job kept (default 7)
+	Keep int `json:"keep,omitempty" yaml:"keep,omitempty"`
+}
+
+// Cache is the shared cache jobs operate on and claim scheduled runs in;
+// *cache.RedisClient implements it
+type Cache interface {
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) (bool, error)
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+}
+
+// Restarter restarts containers; *system.DockerClient implements it
+type Restarter interface {
+	RestartContainer(ctx context.Context, name string) error
+}
+
+// Actions runs job actions against the stack's services
+type Actions struct {
+	db         *sql.DB
+	cache      Cache
+	cacheUp    func() bool
+	docker     Restarter
+	backupDir  string
+	httpClient *http.Client
+}
+
+// NewActions creates an action runner. cache, when set, is used while
+// cacheUp reports it reachable. Backups are written to backupDir.
+func NewActions(db *sql.DB, cache Cache, cacheUp func() bool, docker Restarter, backupDir string) *Actions {
+	return &Actions{
+		db:        db,
+		cache:     cache,
+		cacheUp:   cacheUp,
+		docker:    docker,
+		backupDir: backupDir,
+		// Runs are bounded by the job timeout instead
+		httpClient: &http.Client{},
+	}
+}
+
+// Run runs the action of job and returns its output
+func (a *Actions) Run(ctx context.Context, job Job) (string, error) {
+	act := job.Action
+	switch act.Type {
+	case ActionHTTP:
+		return a.runHTTP(ctx, act)
+	case ActionSQL:
+		return a.runSQL(ctx, act)
+	case ActionCache:
+		return a.runCache(ctx, act)
+	case ActionRestart:
+		if a.docker == nil {
+			return "", errors.New("docker is not available")
+		}
+		if err := a.docker.RestartContainer(ctx, act.Container); err != nil {
+			return "", err
+		}
+		return "restarted " + act.Container, nil
+	case ActionBackup:
+		return a.backup(ctx, job)
+	}
+	return "", fmt.Errorf("unknown action type %q", act.Type)
+}
+
+func (a *Actions) runHTTP(ctx context.Context, act Action) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, act.Method, act.URL, strings.NewReader(act.Body))
+	if err != nil {
+		return "", err
+	}
+	for k, v := range act.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("User-Agent", "Forge-Jobs/1.0")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+
+	output := fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, body)
+	if act.ExpectStatus != 0 && resp.StatusCode != act.ExpectStatus {
+		return output, fmt.Errorf("status %d, expected %d", resp.StatusCode, act.ExpectStatus)
+	}
+	if act.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return output, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return output, nil
+}
+
+func (a *Actions) runSQL(ctx context.Context, act Action) (string, error) {
+	if a.db == nil {
+		return "", errors.New("mysql is not available")
+	}
+	// A dedicated connection keeps USE from leaking into the pool
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if act.Database != "" {
+		if _, err := conn.ExecContext(ctx, "USE `"+act.Database+"`"); err != nil {
+			return "", err
+		}
+	}
+
+	res, err := conn.ExecContext(ctx, act.Statement)
+	if err != nil {
+		return "", err
+	}
+	affected, _ := res.RowsAffected()
+	return fmt.Sprintf("%d rows affected", affected), nil
+}
+
+func (a *Actions) runCache(ctx context.Context, act Action) (string, error) {
+	if a.cache == nil || (a.cacheUp != nil && !a.cacheUp()) {
+		return "", errors.New("redis is not available")
+	}
+	if act.Op == CacheDelete {
+		existed, err := a.cache.Delete(ctx, act.Key)
+		if err != nil {
+			return "", err
+		}
+		if !existed {
+			return "key " + act.Key + " did not exist", nil
+		}
+		return "deleted " + act.Key, nil
+	}
+
+	ttl, _ := parseDuration(act.TTL, 0)
+	if err := a.cache.Set(ctx, act.Key, act.Value, ttl); err != nil {
+		return "", err
+	}
+	return "set " + act.Key, nil
+}
+
+// validateAction checks an action and fills in defaults
+func validateAction(act *Action) error {
+	switch act.Type {
+	case ActionHTTP:
+		u, err := url.Parse(act.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http(s) URL")
+		}
+		act.Method = strings.ToUpper(act.Method)
+		if act.Method == "" {
+			act.Method = "GET"
+			if act.Body != "" {
+				act.Method = "POST"
+			}
+		}
+		if !httpMethods[act.Method] {
+			return fmt.Errorf("invalid method: %q", act.Method)
+		}
+		if act.ExpectStatus != 0 && (act.ExpectStatus < 100 || act.ExpectStatus > 599) {
+			return fmt.Errorf("invalid expect_status: %d", act.ExpectStatus)
+		}
+	case ActionSQL:
+		if strings.TrimSpace(act.Statement) == "" || len(act.Statement) > maxStatement {
+			return fmt.Errorf("statement is required (max %d bytes)", maxStatement)
+		}
+		if act.Database != "" && !databasePattern.MatchString(act.Database) {
+			return fmt.Errorf("invalid database name: %q", act.Database)
+		}
+	case ActionCache:
+		if act.Key == "" {
+			return fmt.Errorf("key is required")
+		}
+		switch act.Op {
+		case CacheSet:
+			if _, err := parseDuration(act.TTL, 0); err != nil {
+				return fmt.Errorf("invalid ttl: %w", err)
+			}
+		case CacheDelete:
+			if act.Value != "" || act.TTL != "" {
+				return fmt.Errorf("value and ttl are only valid for set")
+			}
+		default:
+			return fmt.Errorf("op must be set or delete")
+		}
+	case ActionRestart:
+		if !containerPattern.MatchString(act.Container) {
+			return fmt.Errorf("container must be a container name or ID")
+		}
+	case ActionBackup:
+		if !databasePattern.MatchString(act.Database) {
+			return fmt.Errorf("database must be a database name")
+		}
+		if act.Keep < 0 {
+			return fmt.Errorf("keep must not be negative")
+		}
+		if act.Keep == 0 {
+			act.Keep = DefaultBackupKeep
+		}
+	default:
+		return fmt.Errorf("invalid type: %q (must be http, sql, cache, restart or backup)", act.Type)
+	}
+	return nil
+}
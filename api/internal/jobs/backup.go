@@ -0,0 +1,231 @@
+package jobs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// insertBatch is how many rows go in one INSERT of a backup
+const insertBatch = 100
+
+// backup dumps the database of a backup job to a gzipped SQL file named
+// <job>-<database>-<time>.sql.gz and removes all but the newest Keep of
+// the job's backups. Tables are read in one consistent snapshot.
+func (a *Actions) backup(ctx context.Context, job Job) (string, error) {
+	if a.db == nil {
+		return "", errors.New("mysql is not available")
+	}
+	database := job.Action.Database
+	if err := os.MkdirAll(a.backupDir, 0755); err != nil {
+		return "", err
+	}
+
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "USE `"+database+"`"); err != nil {
+		return "", err
+	}
+	if _, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+		return "", err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+
+	prefix := job.Name + "-" + database + "-"
+	path := filepath.Join(a.backupDir, prefix+time.Now().UTC().Format("20060102T150405Z")+".sql.gz")
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	tables, rows, err := dump(ctx, conn, database, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	output := fmt.Sprintf("wrote %s: %d tables, %d rows, %d bytes", filepath.Base(path), tables, rows, size)
+	removed, err := prune(a.backupDir, prefix, job.Action.Keep)
+	if err != nil {
+		return output, fmt.Errorf("backup written, but removing old backups failed: %w", err)
+	}
+	if removed > 0 {
+		output += fmt.Sprintf("; removed %d old backups", removed)
+	}
+	return output, nil
+}
+
+// dump writes the tables of database as gzipped SQL to w
+func dump(ctx context.Context, conn *sql.Conn, database string, w io.Writer) (tables, rows int, err error) {
+	gz := gzip.NewWriter(w)
+	bw := bufio.NewWriter(gz)
+
+	names, err := tableNames(ctx, conn)
+	if err != nil {
+		return 0, 0, err
+	}
+	fmt.Fprintf(bw, "-- Forge backup of `%s`, %s\n\nSET FOREIGN_KEY_CHECKS=0;\n\n", database, time.Now().UTC().Format(time.RFC3339))
+	for _, table := range names {
+		var name, create string
+		if err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE `"+table+"`").Scan(&name, &create); err != nil {
+			return tables, rows, fmt.Errorf("table %s: %w", table, err)
+		}
+		fmt.Fprintf(bw, "DROP TABLE IF EXISTS `%s`;\n%s;\n\n", table, create)
+
+		n, err := dumpRows(ctx, conn, table, bw)
+		if err != nil {
+			return tables, rows, fmt.Errorf("table %s: %w", table, err)
+		}
+		tables++
+		rows += n
+	}
+	bw.WriteString("SET FOREIGN_KEY_CHECKS=1;\n")
+
+	if err := bw.Flush(); err != nil {
+		return tables, rows, err
+	}
+	return tables, rows, gz.Close()
+}
+
+// tableNames lists the base tables (not views) of the current database
+func tableNames(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW FULL TABLES WHERE Table_type = 'BASE TABLE'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// dumpRows writes the rows of table as batched INSERT statements
+func dumpRows(ctx context.Context, conn *sql.Conn, table string, w *bufio.Writer) (int, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT * FROM `"+table+"`")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		if n%insertBatch == 0 {
+			if n > 0 {
+				w.WriteString(";\n")
+			}
+			fmt.Fprintf(w, "INSERT INTO `%s` VALUES ", table)
+		} else {
+			w.WriteString(",")
+		}
+		w.WriteString("(")
+		for i, v := range values {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			writeValue(w, v)
+		}
+		w.WriteString(")")
+		n++
+	}
+	if n > 0 {
+		w.WriteString(";\n\n")
+	}
+	return n, rows.Err()
+}
+
+// writeValue writes v as a quoted, escaped SQL literal, or NULL
+func writeValue(w *bufio.Writer, v sql.RawBytes) {
+	if v == nil {
+		w.WriteString("NULL")
+		return
+	}
+	w.WriteByte('\'')
+	for _, c := range v {
+		switch c {
+		case 0:
+			w.WriteString(`\0`)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		case 0x1a:
+			w.WriteString(`\Z`)
+		case '\\', '\'', '"':
+			w.WriteByte('\\')
+			w.WriteByte(c)
+		default:
+			w.WriteByte(c)
+		}
+	}
+	w.WriteByte('\'')
+}
+
+// prune removes all but the newest keep backups starting with prefix and
+// reports how many it removed
+func prune(dir, prefix string, keep int) (int, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*.sql.gz"))
+	if err != nil {
+		return 0, err
+	}
+	// Names end in a sortable UTC timestamp
+	var backups []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), prefix), ".sql.gz")
+		if _, err := time.Parse("20060102T150405Z", stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+
+	removed := 0
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return removed, err
+		}
+		backups = backups[1:]
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,592 @@
+// Package jobs runs scheduled jobs: HTTP calls, SQL statements, cache
+// operations, container restarts and database backups on cron schedules,
+// with run history and Prometheus metrics per job
+//
+// A job whose previous run is still going when it comes due again follows
+// its overlap policy:
+//
+//   - skip: the new run is recorded as skipped (the default)
+//   - allow: both run
+//   - queue: the new run starts when the current one ends; at most one waits
+//   - replace: the current run is cancelled and the new one starts
+//
+// While Redis is up, replicas sharing it claim each scheduled run, so a job
+// runs once per schedule however many replicas there are.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	// Timezones work in images without /usr/share/zoneinfo
+	_ "time/tzdata"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+// Overlap policies
+const (
+	OverlapSkip    = "skip"
+	OverlapAllow   = "allow"
+	OverlapQueue   = "queue"
+	OverlapReplace = "replace"
+)
+
+// Run statuses
+const (
+	StatusRunning   = "running"
+	StatusQueued    = "queued" // waiting for the current run; not kept in history
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCancelled = "cancelled"
+)
+
+// Run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+const (
+	// DefaultTimeout is used when a job does not set one
+	DefaultTimeout = 10 * time.Minute
+	maxTimeout     = 24 * time.Hour
+
+	schedulerTick = time.Second
+	maxRuns       = 50
+	// maxOutput bounds the output kept per run
+	maxOutput = 4096
+	// claimPrefix keys the Redis claims of scheduled runs
+	claimPrefix = "forge:jobs:claim:"
+)
+
+var (
+	// ErrInvalidJob is returned for jobs that cannot be stored
+	ErrInvalidJob = errors.New("invalid job")
+	// ErrJobNotFound is returned for unknown job names
+	ErrJobNotFound = errors.New("job not found")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// Job is an action run on a cron schedule
+type Job struct {
+	Name     string `json:"name" yaml:"name"`
+	Schedule string `json:"schedule" yaml:"schedule"`                     // cron expression, e.g. "0 3 * * *" or "@hourly"
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"` // IANA name, default UTC
+	Overlap  string `json:"overlap,omitempty" yaml:"overlap,omitempty"`   // skip (default), allow, queue or replace
+	Timeout  string `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // default 10m, at most 24h
+	Paused   bool   `json:"paused,omitempty" yaml:"paused,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Action Action            `json:"action" yaml:"action"`
+}
+
+// Run is one run of a job
+type Run struct {
+	ID         string    `json:"id"`
+	Job        string    `json:"job"`
+	Trigger    string    `json:"trigger"` // schedule or manual
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	Status     string    `json:"status"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is a job with its schedule and latest run
+type Status struct {
+	Job
+	NextRun time.Time `json:"next_run,omitempty"`
+	Running int       `json:"running"`
+	Queued  bool      `json:"queued,omitempty"`
+	LastRun *Run      `json:"last_run,omitempty"`
+}
+
+type jobState struct {
+	schedule *Schedule
+	location *time.Location
+	next     time.Time
+	running  map[string]context.CancelFunc // by run ID
+	queued   string                        // trigger of the run waiting for the current one
+	runs     []*Run                        // oldest first
+}
+
+type jobsFile struct {
+	Jobs []Job `yaml:"jobs"`
+}
+
+// Manager stores jobs and runs them on their schedules
+type Manager struct {
+	mu         sync.RWMutex
+	jobs       map[string]Job
+	state      map[string]*jobState
+	configPath string
+	actions    *Actions
+	base       context.Context // parent of runs, cancelled at shutdown
+	onRun      []func(Job, Run)
+}
+
+// NewManager loads jobs from configPath. actions runs them.
+func NewManager(configPath string, actions *Actions) (*Manager, error) {
+	m := &Manager{
+		jobs:       make(map[string]Job),
+		state:      make(map[string]*jobState),
+		configPath: configPath,
+		actions:    actions,
+		base:       context.Background(),
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all jobs with their state, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.jobs))
+	for name := range m.jobs {
+		list = append(list, m.status(name))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a job with its state
+func (m *Manager) Get(name string) (Status, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.jobs[name]; !ok {
+		return Status{}, ErrJobNotFound
+	}
+	return m.status(name), nil
+}
+
+// Runs returns the recent runs of a job, newest first
+func (m *Manager) Runs(name string) ([]Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	js, ok := m.state[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	runs := make([]Run, len(js.runs))
+	for i, r := range js.runs {
+		runs[len(js.runs)-1-i] = *r
+	}
+	return runs, nil
+}
+
+// status builds a Status; m.mu must be held
+func (m *Manager) status(name string) Status {
+	st := Status{Job: m.jobs[name]}
+	js := m.state[name]
+	if !st.Paused {
+		st.NextRun = js.next
+	}
+	st.Running = len(js.running)
+	st.Queued = js.queued != ""
+	if n := len(js.runs); n > 0 {
+		last := *js.runs[n-1]
+		st.LastRun = &last
+	}
+	return st
+}
+
+// Add creates or replaces a job. Replacing a job keeps its run history and
+// lets runs in progress finish.
+func (m *Manager) Add(job Job) error {
+	if err := validateJob(&job); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.jobs[job.Name] = job
+	m.schedule(job, time.Now())
+	m.mu.Unlock()
+	return m.save()
+}
+
+// SetPaused pauses or resumes the schedule of a job. Paused jobs can still
+// be run by hand.
+func (m *Manager) SetPaused(name string, paused bool) (Status, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[name]
+	if !ok {
+		m.mu.Unlock()
+		return Status{}, ErrJobNotFound
+	}
+	job.Paused = paused
+	m.jobs[name] = job
+	if !paused {
+		// Resume from now rather than firing a run missed while paused
+		m.schedule(job, time.Now())
+	}
+	st := m.status(name)
+	m.mu.Unlock()
+	return st, m.save()
+}
+
+// Remove deletes a job, cancelling its runs in progress and dropping its
+// metrics
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	js, ok := m.state[name]
+	if !ok {
+		m.mu.Unlock()
+		return ErrJobNotFound
+	}
+	delete(m.jobs, name)
+	delete(m.state, name)
+	for _, cancel := range js.running {
+		cancel()
+	}
+	m.mu.Unlock()
+
+	metrics.JobDurationSeconds.DeleteLabelValues(name)
+	metrics.JobLastSuccess.DeleteLabelValues(name)
+	metrics.JobRunning.DeleteLabelValues(name)
+	for _, status := range []string{StatusSucceeded, StatusFailed, StatusSkipped, StatusCancelled} {
+		metrics.JobRunsTotal.DeleteLabelValues(name, status)
+	}
+	return m.save()
+}
+
+// OnRun registers fn to be called after every run that started ends. It
+// must be called before Run.
+func (m *Manager) OnRun(fn func(Job, Run)) {
+	m.onRun = append(m.onRun, fn)
+}
+
+// Trigger runs a job now, subject to its overlap policy. The returned run
+// is still going, or skipped or queued behind the current one.
+func (m *Manager) Trigger(name string) (Run, error) {
+	return m.start(name, TriggerManual)
+}
+
+// schedule (re)computes when a job next runs; m.mu must be held and the
+// job valid
+func (m *Manager) schedule(job Job, now time.Time) {
+	js, ok := m.state[job.Name]
+	if !ok {
+		js = &jobState{running: make(map[string]context.CancelFunc)}
+		m.state[job.Name] = js
+	}
+	js.schedule, _ = ParseSchedule(job.Schedule)
+	js.location, _ = loadLocation(job.Timezone)
+	js.next = js.schedule.Next(now.In(js.location))
+}
+
+// Run starts due jobs until ctx is cancelled, then cancels runs in progress
+func (m *Manager) Run(ctx context.Context) {
+	m.mu.Lock()
+	m.base = ctx
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		m.runDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue starts every unpaused job whose next run time has passed. A job
+// that missed several runs (e.g. while the host slept) runs once.
+func (m *Manager) runDue(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, job := range m.jobs {
+		js := m.state[name]
+		if js.next.IsZero() || now.Before(js.next) {
+			continue
+		}
+		due := js.next
+		js.next = js.schedule.Next(now.In(js.location))
+		if job.Paused {
+			continue
+		}
+		go func(name string, due time.Time) {
+			if !m.claim(ctx, name, due) {
+				return
+			}
+			if _, err := m.start(name, TriggerSchedule); err != nil && !errors.Is(err, ErrJobNotFound) {
+				logger.Error("Failed to start job: "+name, err)
+			}
+		}(name, due)
+	}
+}
+
+// claim reports whether this replica takes the run of a job due at due.
+// Without Redis every replica runs it; when Redis fails the run goes ahead.
+func (m *Manager) claim(ctx context.Context, name string, due time.Time) bool {
+	a := m.actions
+	if a.cache == nil || (a.cacheUp != nil && !a.cacheUp()) {
+		return true
+	}
+	key := fmt.Sprintf("%s%s:%d", claimPrefix, name, due.Unix())
+	ok, err := a.cache.SetNX(ctx, key, "1", time.Hour)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to claim run of job %s, running it here: %v", name, err))
+		return true
+	}
+	return ok
+}
+
+// start runs a job per its overlap policy
+func (m *Manager) start(name, trigger string) (Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[name]
+	if !ok {
+		return Run{}, ErrJobNotFound
+	}
+	js := m.state[name]
+
+	if len(js.running) > 0 {
+		switch job.Overlap {
+		case OverlapSkip:
+			return m.skip(js, name, trigger, "previous run still in progress"), nil
+		case OverlapQueue:
+			if js.queued != "" {
+				return m.skip(js, name, trigger, "a run is already queued"), nil
+			}
+			js.queued = trigger
+			return Run{Job: name, Trigger: trigger, Started: time.Now().UTC(), Status: StatusQueued}, nil
+		case OverlapReplace:
+			for _, cancel := range js.running {
+				cancel()
+			}
+		}
+	}
+	return m.launch(job, js, trigger), nil
+}
+
+// skip records a run that did not start; m.mu must be held
+func (m *Manager) skip(js *jobState, name, trigger, reason string) Run {
+	now := time.Now().UTC()
+	run := &Run{ID: newRunID(), Job: name, Trigger: trigger, Started: now, Finished: now, Status: StatusSkipped, Error: reason}
+	m.record(js, run)
+	metrics.JobRunsTotal.WithLabelValues(name, StatusSkipped).Inc()
+	return *run
+}
+
+// launch starts a run in the background; m.mu must be held
+func (m *Manager) launch(job Job, js *jobState, trigger string) Run {
+	run := &Run{ID: newRunID(), Job: job.Name, Trigger: trigger, Started: time.Now().UTC(), Status: StatusRunning}
+	timeout, _ := parseDuration(job.Timeout, DefaultTimeout)
+	ctx, cancel := context.WithTimeout(m.base, timeout)
+	js.running[run.ID] = cancel
+	m.record(js, run)
+	metrics.JobRunning.WithLabelValues(job.Name).Inc()
+
+	go m.execute(ctx, cancel, job, js, run)
+	return *run
+}
+
+// execute runs the action of a job and records the outcome
+func (m *Manager) execute(ctx context.Context, cancel context.CancelFunc, job Job, js *jobState, run *Run) {
+	output, err := m.actions.Run(ctx, job)
+	ctxErr := ctx.Err()
+	cancel()
+	finished := time.Now().UTC()
+
+	m.mu.Lock()
+	delete(js.running, run.ID)
+	run.Finished = finished
+	run.DurationMs = float64(finished.Sub(run.Started).Microseconds()) / 1000
+	run.Output = truncate(output)
+	switch {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		run.Status = StatusFailed
+		run.Error = "timed out"
+	case errors.Is(ctxErr, context.Canceled):
+		run.Status = StatusCancelled
+		run.Error = "cancelled"
+	case err != nil:
+		run.Status = StatusFailed
+		run.Error = truncate(err.Error())
+	default:
+		run.Status = StatusSucceeded
+	}
+	if run.Status == StatusFailed {
+		logger.Warn(fmt.Sprintf("Job %s failed: %s", job.Name, run.Error))
+	}
+
+	result := *run
+	defer func() {
+		for _, fn := range m.onRun {
+			fn(job, result)
+		}
+	}()
+	defer m.mu.Unlock()
+
+	if m.state[job.Name] != js {
+		// Job was removed during the run
+		return
+	}
+	metrics.JobRunning.WithLabelValues(job.Name).Dec()
+	metrics.JobRunsTotal.WithLabelValues(job.Name, run.Status).Inc()
+	metrics.JobDurationSeconds.WithLabelValues(job.Name).Set(run.DurationMs / 1000)
+	if run.Status == StatusSucceeded {
+		metrics.JobLastSuccess.WithLabelValues(job.Name).Set(float64(finished.Unix()))
+	}
+
+	if js.queued != "" && len(js.running) == 0 && m.base.Err() == nil {
+		trigger := js.queued
+		js.queued = ""
+		m.launch(m.jobs[job.Name], js, trigger)
+	}
+}
+
+// record adds a run to the history of a job; m.mu must be held
+func (m *Manager) record(js *jobState, run *Run) {
+	js.runs = append(js.runs, run)
+	if len(js.runs) > maxRuns {
+		js.runs = js.runs[len(js.runs)-maxRuns:]
+	}
+}
+
+// validateJob checks a job and fills in defaults
+func validateJob(job *Job) error {
+	if !namePattern.MatchString(job.Name) {
+		return fmt.Errorf("%w: name must be 1-64 characters of letters, digits, '_' or '-'", ErrInvalidJob)
+	}
+	if _, err := ParseSchedule(job.Schedule); err != nil {
+		return fmt.Errorf("%w: schedule: %v", ErrInvalidJob, err)
+	}
+	if _, err := loadLocation(job.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidJob, job.Timezone)
+	}
+
+	switch job.Overlap {
+	case "":
+		job.Overlap = OverlapSkip
+	case OverlapSkip, OverlapAllow, OverlapQueue, OverlapReplace:
+	default:
+		return fmt.Errorf("%w: overlap must be skip, allow, queue or replace", ErrInvalidJob)
+	}
+
+	timeout, err := parseDuration(job.Timeout, DefaultTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timeout: %v", ErrInvalidJob, err)
+	}
+	if timeout > maxTimeout {
+		return fmt.Errorf("%w: timeout must not exceed %s", ErrInvalidJob, maxTimeout)
+	}
+
+	if err := validateAction(&job.Action); err != nil {
+		return fmt.Errorf("%w: action: %v", ErrInvalidJob, err)
+	}
+	if err := resources.ValidateLabels(job.Labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	return nil
+}
+
+// loadLocation returns the location of a timezone name, UTC when empty
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// parseDuration parses an optional positive duration
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// truncate bounds s to maxOutput bytes
+func truncate(s string) string {
+	if len(s) <= maxOutput {
+		return s
+	}
+	return s[:maxOutput] + "\n[truncated]"
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// load reads jobs from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f jobsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, job := range f.Jobs {
+		if err := validateJob(&job); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		m.jobs[job.Name] = job
+		m.schedule(job, now)
+	}
+	return nil
+}
+
+// save writes jobs to the config file
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := jobsFile{Jobs: make([]Job, 0, len(m.jobs))}
+	for _, job := range m.jobs {
+		f.Jobs = append(f.Jobs, job)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Jobs, func(i, j int) bool { return f.Jobs[i].Name < f.Jobs[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
@@ -7,6 +7,7 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -33,6 +34,15 @@ func Get() zerolog.Logger {
 	return log
 }
 
+// FromContext returns the request-scoped logger stored in ctx (carrying
+// request_id and trace_id), or the base logger if there is none
+func FromContext(ctx context.Context) zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return *l
+	}
+	return log
+}
+
 // WithEndpoint returns a logger with endpoint context
 func WithEndpoint(endpoint string) zerolog.Logger {
 	return log.With().Str("endpoint", endpoint).Logger()
@@ -67,12 +77,13 @@ func Debug(msg string) {
 }
 
 // RequestLog logs an HTTP request with standard fields
-func RequestLog(method, endpoint string, status int, duration time.Duration, err error) {
-	event := log.Info()
+func RequestLog(ctx context.Context, method, endpoint string, status int, duration time.Duration, err error) {
+	l := FromContext(ctx)
+	event := l.Info()
 	if status >= 500 {
-		event = log.Error()
+		event = l.Error()
 	} else if status >= 400 {
-		event = log.Warn()
+		event = l.Warn()
 	}
 
 	event.
@@ -0,0 +1,250 @@
+// Package logmetrics derives Prometheus counters from log entries pushed
+// through Forge, for apps that only emit logs
+package logmetrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/forge/api/internal/observe"
+	"gopkg.in/yaml.v3"
+)
+
+const metricType = "counter"
+
+// LogMetric increments counter Name for every pushed entry whose stream
+// labels include all of Match and whose line matches Pattern. Named groups
+// in Pattern become metric labels alongside the static Labels.
+type LogMetric struct {
+	Name    string            `json:"name" yaml:"name"`
+	Match   map[string]string `json:"match,omitempty" yaml:"match,omitempty"`
+	Pattern string            `json:"pattern,omitempty" yaml:"pattern,omitempty"` // empty counts every entry
+	Labels  map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Status is a log metric with its match counters
+type Status struct {
+	LogMetric
+	Matches   uint64 `json:"matches"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// rule is a compiled log metric
+type rule struct {
+	LogMetric
+	re      *regexp.Regexp
+	matches atomic.Uint64
+	lastErr atomic.Value // string
+}
+
+type logMetricsFile struct {
+	Metrics []LogMetric `yaml:"metrics"`
+}
+
+// Manager stores log metrics and records them into a MetricsRegistry as
+// entries are pushed
+type Manager struct {
+	mu         sync.RWMutex
+	rules      map[string]*rule
+	configPath string
+	registry   *observe.MetricsRegistry
+}
+
+// NewManager loads log metrics from configPath
+func NewManager(configPath string, registry *observe.MetricsRegistry) (*Manager, error) {
+	m := &Manager{
+		rules:      make(map[string]*rule),
+		configPath: configPath,
+		registry:   registry,
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all log metrics sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.rules))
+	for _, r := range m.rules {
+		list = append(list, r.status())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a log metric by name
+func (m *Manager) Get(name string) (Status, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.rules[name]
+	if !ok {
+		return Status{}, false
+	}
+	return r.status(), true
+}
+
+// Add creates or replaces a log metric. The label names of a counter are
+// fixed once it has been recorded, so changing them requires a new name.
+func (m *Manager) Add(lm LogMetric) error {
+	r, err := compile(lm)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.rules[lm.Name] = r
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Remove deletes a log metric. Series already exported keep their values.
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.rules[name]; !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("log metric not found: %s", name)
+	}
+	delete(m.rules, name)
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Process records matching log metrics. It implements observe.EntryProcessor
+// and never drops entries.
+func (m *Manager) Process(e *observe.Entry) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.rules {
+		labels, ok := r.match(e)
+		if !ok {
+			continue
+		}
+		r.matches.Add(1)
+		if err := m.registry.Push(r.Name, metricType, 1, labels); err != nil {
+			r.lastErr.Store(err.Error())
+		}
+	}
+	return true
+}
+
+// match returns the metric labels when e matches the rule
+func (r *rule) match(e *observe.Entry) (map[string]string, bool) {
+	for k, v := range r.Match {
+		if e.Labels[k] != v {
+			return nil, false
+		}
+	}
+
+	labels := make(map[string]string, len(r.Labels))
+	for k, v := range r.Labels {
+		labels[k] = v
+	}
+	if r.re == nil {
+		return labels, true
+	}
+
+	groups := r.re.FindStringSubmatch(e.Message)
+	if groups == nil {
+		return nil, false
+	}
+	for i, name := range r.re.SubexpNames() {
+		if name != "" {
+			labels[name] = groups[i]
+		}
+	}
+	return labels, true
+}
+
+func (r *rule) status() Status {
+	st := Status{LogMetric: r.LogMetric, Matches: r.matches.Load()}
+	st.LastError, _ = r.lastErr.Load().(string)
+	return st
+}
+
+// compile validates a log metric and compiles its pattern
+func compile(lm LogMetric) (*rule, error) {
+	r := &rule{LogMetric: lm}
+
+	labels := make(map[string]string, len(lm.Labels))
+	for k, v := range lm.Labels {
+		labels[k] = v
+	}
+	if lm.Pattern != "" {
+		re, err := regexp.Compile(lm.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		for _, name := range re.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if _, dup := lm.Labels[name]; dup {
+				return nil, fmt.Errorf("label %q is set both statically and by the pattern", name)
+			}
+			labels[name] = ""
+		}
+		r.re = re
+	}
+
+	if err := observe.ValidateMetric(lm.Name, metricType, labels); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads log metrics from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f logMetricsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, lm := range f.Metrics {
+		r, err := compile(lm)
+		if err != nil {
+			return fmt.Errorf("log metric %s: %w", lm.Name, err)
+		}
+		m.rules[lm.Name] = r
+	}
+	return nil
+}
+
+// save writes log metrics to the config file
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := logMetricsFile{Metrics: make([]LogMetric, 0, len(m.rules))}
+	for _, r := range m.rules {
+		f.Metrics = append(f.Metrics, r.LogMetric)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Metrics, func(i, j int) bool { return f.Metrics[i].Name < f.Metrics[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
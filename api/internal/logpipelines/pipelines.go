@@ -0,0 +1,390 @@
+// Package logpipelines applies user-defined parsing and labelling rules to
+// log entries pushed through Forge before they reach Loki
+package logpipelines
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/forge/api/internal/observe"
+	"gopkg.in/yaml.v3"
+)
+
+// Stage types
+const (
+	StageRegex  = "regex"  // extract named groups from source
+	StageJSON   = "json"   // extract top-level keys of a JSON object in source
+	StageLabels = "labels" // promote extracted values to stream labels
+	StageFields = "fields" // add extracted values as structured fields
+	StageLevel  = "level"  // set the level from an extracted value
+	StageOutput = "output" // replace the log line with an extracted value
+	StageDrop   = "drop"   // drop entries whose source matches expression
+)
+
+const (
+	maxStages           = 20
+	maxLabelValueLength = 256
+)
+
+var (
+	namePattern      = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,63}$`)
+	fieldNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+)
+
+// Stage is one processing step. Source names an extracted value to read;
+// when empty the log line is used.
+type Stage struct {
+	Type       string            `json:"type" yaml:"type"`
+	Source     string            `json:"source,omitempty" yaml:"source,omitempty"`
+	Expression string            `json:"expression,omitempty" yaml:"expression,omitempty"` // regex and drop
+	Values     map[string]string `json:"values,omitempty" yaml:"values,omitempty"`         // labels and fields: name -> extracted key ("" = same name)
+
+	re *regexp.Regexp
+}
+
+// Pipeline runs its stages on entries whose stream labels include all of Match
+type Pipeline struct {
+	Name   string            `json:"name" yaml:"name"`
+	Match  map[string]string `json:"match,omitempty" yaml:"match,omitempty"`
+	Stages []Stage           `json:"stages" yaml:"stages"`
+}
+
+type pipelinesFile struct {
+	Pipelines []Pipeline `yaml:"pipelines"`
+}
+
+// Manager stores pipelines and applies them to pushed entries. Matching
+// pipelines run in name order.
+type Manager struct {
+	mu         sync.RWMutex
+	pipelines  []Pipeline // sorted by name, stages compiled
+	configPath string
+}
+
+// NewManager loads pipelines from configPath
+func NewManager(configPath string) (*Manager, error) {
+	m := &Manager{configPath: configPath}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all pipelines sorted by name
+func (m *Manager) List() []Pipeline {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Pipeline(nil), m.pipelines...)
+}
+
+// Get returns a pipeline by name
+func (m *Manager) Get(name string) (Pipeline, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.pipelines {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Pipeline{}, false
+}
+
+// Add creates or replaces a pipeline
+func (m *Manager) Add(p Pipeline) error {
+	if err := validatePipeline(&p); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	list := make([]Pipeline, 0, len(m.pipelines)+1)
+	for _, existing := range m.pipelines {
+		if existing.Name != p.Name {
+			list = append(list, existing)
+		}
+	}
+	list = append(list, p)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	m.pipelines = list
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Remove deletes a pipeline
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	list := make([]Pipeline, 0, len(m.pipelines))
+	for _, p := range m.pipelines {
+		if p.Name != name {
+			list = append(list, p)
+		}
+	}
+	if len(list) == len(m.pipelines) {
+		m.mu.Unlock()
+		return fmt.Errorf("pipeline not found: %s", name)
+	}
+	m.pipelines = list
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Process runs every matching pipeline on e. It implements
+// observe.EntryProcessor; false means a drop stage matched.
+func (m *Manager) Process(e *observe.Entry) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var extracted map[string]any
+	for i := range m.pipelines {
+		p := &m.pipelines[i]
+		if !matches(p.Match, e.Labels) {
+			continue
+		}
+		if extracted == nil {
+			// Copy so stages never mutate the caller's fields
+			extracted = make(map[string]any, len(e.Fields))
+			for k, v := range e.Fields {
+				extracted[k] = v
+			}
+		}
+		for j := range p.Stages {
+			if !p.Stages[j].apply(e, extracted) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matches(match, labels map[string]string) bool {
+	for k, v := range match {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// apply runs a stage; it returns false when the entry should be dropped.
+// Stages whose source is missing or does not parse are skipped.
+func (s *Stage) apply(e *observe.Entry, extracted map[string]any) bool {
+	switch s.Type {
+	case StageRegex:
+		src, ok := source(s.Source, e, extracted)
+		if !ok {
+			return true
+		}
+		match := s.re.FindStringSubmatch(src)
+		if match == nil {
+			return true
+		}
+		for i, name := range s.re.SubexpNames() {
+			if name != "" && i < len(match) {
+				extracted[name] = match[i]
+			}
+		}
+	case StageJSON:
+		src, ok := source(s.Source, e, extracted)
+		if !ok {
+			return true
+		}
+		var obj map[string]any
+		if json.Unmarshal([]byte(src), &obj) != nil {
+			return true
+		}
+		for k, v := range obj {
+			extracted[k] = v
+		}
+	case StageLabels:
+		for name, key := range s.Values {
+			if v, ok := lookup(extracted, name, key); ok && v != "" && len(v) <= maxLabelValueLength {
+				e.Labels[name] = v
+			}
+		}
+	case StageFields:
+		fields := make(map[string]any, len(e.Fields)+len(s.Values))
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		for name, key := range s.Values {
+			if key == "" {
+				key = name
+			}
+			if v, ok := extracted[key]; ok {
+				fields[name] = v
+			}
+		}
+		e.Fields = fields
+	case StageLevel:
+		if v, ok := source(s.Source, e, extracted); ok && v != "" {
+			e.Level = strings.ToLower(v)
+		}
+	case StageOutput:
+		if v, ok := source(s.Source, e, extracted); ok {
+			e.Message = v
+		}
+	case StageDrop:
+		if src, ok := source(s.Source, e, extracted); ok && s.re.MatchString(src) {
+			return false
+		}
+	}
+	return true
+}
+
+// source returns the log line or the named extracted value as a string
+func source(key string, e *observe.Entry, extracted map[string]any) (string, bool) {
+	if key == "" {
+		return e.Message, true
+	}
+	return lookup(extracted, key, "")
+}
+
+// lookup returns extracted[key], or extracted[name] when key is empty
+func lookup(extracted map[string]any, name, key string) (string, bool) {
+	if key == "" {
+		key = name
+	}
+	v, ok := extracted[key]
+	if !ok || v == nil {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// validatePipeline checks a pipeline and compiles its expressions
+func validatePipeline(p *Pipeline) error {
+	if !namePattern.MatchString(p.Name) {
+		return fmt.Errorf("name must be 1-64 characters of letters, digits, '_' or '-'")
+	}
+	for k := range p.Match {
+		if !labelNamePattern.MatchString(k) {
+			return fmt.Errorf("invalid match label: %q", k)
+		}
+	}
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("at least one stage is required")
+	}
+	if len(p.Stages) > maxStages {
+		return fmt.Errorf("too many stages (max %d)", maxStages)
+	}
+	for i := range p.Stages {
+		if err := compileStage(&p.Stages[i]); err != nil {
+			return fmt.Errorf("stage %d (%s): %w", i+1, p.Stages[i].Type, err)
+		}
+	}
+	return nil
+}
+
+func compileStage(s *Stage) error {
+	switch s.Type {
+	case StageRegex, StageDrop:
+		if s.Expression == "" {
+			return fmt.Errorf("expression is required")
+		}
+		re, err := regexp.Compile(s.Expression)
+		if err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+		if s.Type == StageRegex && !hasNamedGroup(re) {
+			return fmt.Errorf("expression must contain named groups, e.g. (?P<status>\\d+)")
+		}
+		s.re = re
+	case StageJSON, StageOutput:
+	case StageLevel:
+		if s.Source == "" {
+			s.Source = "level"
+		}
+	case StageLabels:
+		if len(s.Values) == 0 {
+			return fmt.Errorf("values are required")
+		}
+		for name := range s.Values {
+			if !labelNamePattern.MatchString(name) {
+				return fmt.Errorf("invalid label name: %q", name)
+			}
+			if name == "level" {
+				return fmt.Errorf("use a level stage to set the level")
+			}
+		}
+	case StageFields:
+		if len(s.Values) == 0 {
+			return fmt.Errorf("values are required")
+		}
+		for name := range s.Values {
+			if !fieldNamePattern.MatchString(name) || name == "msg" || name == "level" {
+				return fmt.Errorf("invalid field name: %q", name)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown stage type (must be regex, json, labels, fields, level, output or drop)")
+	}
+	return nil
+}
+
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads pipelines from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f pipelinesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	list := make([]Pipeline, 0, len(f.Pipelines))
+	for _, p := range f.Pipelines {
+		if err := validatePipeline(&p); err != nil {
+			return fmt.Errorf("pipeline %s: %w", p.Name, err)
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	m.mu.Lock()
+	m.pipelines = list
+	m.mu.Unlock()
+	return nil
+}
+
+// save writes pipelines to the config file
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := pipelinesFile{Pipelines: append([]Pipeline(nil), m.pipelines...)}
+	m.mu.RUnlock()
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
@@ -0,0 +1,255 @@
+// Package logsampling drops or samples log entries pushed through Forge by
+// label set and level, to keep ingestion volume within what Loki can take
+package logsampling
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/forge/api/internal/observe"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule actions
+const (
+	ActionDrop   = "drop"   // drop every matching entry
+	ActionSample = "sample" // keep a Rate fraction of matching entries
+)
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Rule applies Action to entries whose stream labels include all of Match
+// and whose level is one of Levels (any level when empty)
+type Rule struct {
+	Name   string            `json:"name" yaml:"name"`
+	Match  map[string]string `json:"match,omitempty" yaml:"match,omitempty"`
+	Levels []string          `json:"levels,omitempty" yaml:"levels,omitempty"`
+	Action string            `json:"action" yaml:"action"`
+	Rate   float64           `json:"rate,omitempty" yaml:"rate,omitempty"` // sample: fraction kept, between 0 and 1
+}
+
+// Status is a rule with its counters since startup
+type Status struct {
+	Rule
+	Matched uint64 `json:"matched"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// rule is a validated rule with its counters
+type rule struct {
+	Rule
+	matched atomic.Uint64
+	dropped atomic.Uint64
+}
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Manager stores ingestion rules and applies them to pushed entries. Rules
+// are evaluated in name order; the first matching rule decides.
+type Manager struct {
+	mu         sync.RWMutex
+	rules      []*rule // sorted by name
+	configPath string
+}
+
+// NewManager loads rules from configPath
+func NewManager(configPath string) (*Manager, error) {
+	m := &Manager{configPath: configPath}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all rules sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.rules))
+	for _, r := range m.rules {
+		list = append(list, r.status())
+	}
+	return list
+}
+
+// Get returns a rule by name
+func (m *Manager) Get(name string) (Status, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, r := range m.rules {
+		if r.Name == name {
+			return r.status(), true
+		}
+	}
+	return Status{}, false
+}
+
+// Add creates or replaces a rule. Replacing a rule resets its counters.
+func (m *Manager) Add(r Rule) error {
+	if err := validateRule(&r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	list := make([]*rule, 0, len(m.rules)+1)
+	for _, existing := range m.rules {
+		if existing.Name != r.Name {
+			list = append(list, existing)
+		}
+	}
+	list = append(list, &rule{Rule: r})
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	m.rules = list
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Remove deletes a rule
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	list := make([]*rule, 0, len(m.rules))
+	for _, r := range m.rules {
+		if r.Name != name {
+			list = append(list, r)
+		}
+	}
+	if len(list) == len(m.rules) {
+		m.mu.Unlock()
+		return fmt.Errorf("sampling rule not found: %s", name)
+	}
+	m.rules = list
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Process applies the first matching rule to e. It implements
+// observe.EntryProcessor; false means the entry was dropped or sampled out.
+func (m *Manager) Process(e *observe.Entry) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.rules {
+		if !r.matches(e) {
+			continue
+		}
+		r.matched.Add(1)
+		if r.Action == ActionSample && rand.Float64() < r.Rate {
+			return true
+		}
+		r.dropped.Add(1)
+		return false
+	}
+	return true
+}
+
+// DropReason labels entries dropped by the manager in forge_loki_entries_dropped_total
+func (m *Manager) DropReason() string {
+	return "sampling"
+}
+
+func (r *rule) matches(e *observe.Entry) bool {
+	for k, v := range r.Match {
+		if e.Labels[k] != v {
+			return false
+		}
+	}
+	if len(r.Levels) == 0 {
+		return true
+	}
+	for _, level := range r.Levels {
+		if strings.EqualFold(level, e.Level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) status() Status {
+	return Status{Rule: r.Rule, Matched: r.matched.Load(), Dropped: r.dropped.Load()}
+}
+
+// validateRule checks a rule and normalizes its levels
+func validateRule(r *Rule) error {
+	if !namePattern.MatchString(r.Name) {
+		return fmt.Errorf("invalid rule name %q: use letters, digits, '_' or '-' (max 64)", r.Name)
+	}
+	switch r.Action {
+	case ActionDrop:
+		r.Rate = 0
+	case ActionSample:
+		if r.Rate <= 0 || r.Rate >= 1 {
+			return fmt.Errorf("sample rate must be between 0 and 1 (exclusive), got %g", r.Rate)
+		}
+	default:
+		return fmt.Errorf("invalid action %q: must be %s or %s", r.Action, ActionDrop, ActionSample)
+	}
+	if len(r.Match) == 0 && len(r.Levels) == 0 {
+		return fmt.Errorf("rule must set match labels or levels")
+	}
+	for i, level := range r.Levels {
+		if level == "" {
+			return fmt.Errorf("empty level")
+		}
+		r.Levels[i] = strings.ToLower(level)
+	}
+	return nil
+}
+
+// load reads rules from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f rulesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	list := make([]*rule, 0, len(f.Rules))
+	for _, r := range f.Rules {
+		if err := validateRule(&r); err != nil {
+			return fmt.Errorf("sampling rule %s: %w", r.Name, err)
+		}
+		list = append(list, &rule{Rule: r})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	m.mu.Lock()
+	m.rules = list
+	m.mu.Unlock()
+	return nil
+}
+
+// save writes rules to the config file
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := rulesFile{Rules: make([]Rule, 0, len(m.rules))}
+	for _, r := range m.rules {
+		f.Rules = append(f.Rules, r.Rule)
+	}
+	m.mu.RUnlock()
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
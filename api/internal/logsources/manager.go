@@ -6,16 +6,88 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Source types
+const (
+	TypeFile       = "file"       // log files matching Path (default)
+	TypeDocker     = "docker"     // stdout/stderr of containers, via the Docker socket
+	TypeKubernetes = "kubernetes" // pod logs of a cluster whose node shares this host
+)
+
+// dockerHost is the Docker socket as mounted into Promtail
+const dockerHost = "unix:///var/run/docker.sock"
+
+// podLogPattern locates a container's log files under /var/log/pods, which
+// must be mounted into Promtail, from "<pod uid>/<container name>"
+const podLogPattern = "/var/log/pods/*$1/*.log"
+
 // LogSource represents a log source configuration
 type LogSource struct {
 	Name   string            `json:"name" yaml:"name"`
-	Path   string            `json:"path" yaml:"path"`
+	Type   string            `json:"type,omitempty" yaml:"type,omitempty"` // file (default) or docker
+	Path   string            `json:"path,omitempty" yaml:"path,omitempty"`
 	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Docker sources select containers matching any of Containers (Docker
+	// name filter, so a substring or regex) and all of ContainerLabels
+	Containers      []string          `json:"containers,omitempty" yaml:"containers,omitempty"`
+	ContainerLabels map[string]string `json:"container_labels,omitempty" yaml:"container_labels,omitempty"`
+
+	// Kubernetes sources select pods in Namespaces (default all) matching
+	// all of PodLabels. KubeConfig is a kubeconfig file as seen by Promtail.
+	Namespaces []string          `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	PodLabels  map[string]string `json:"pod_labels,omitempty" yaml:"pod_labels,omitempty"`
+	KubeConfig string            `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+
+	// Relabel adds, drops or renames stream labels, in order
+	Relabel []RelabelRule `json:"relabel,omitempty" yaml:"relabel,omitempty"`
+
+	// Stages parse entries before they are shipped, e.g. joining stack
+	// traces into one entry or extracting labels
+	Stages []Stage `json:"stages,omitempty" yaml:"stages,omitempty"`
+}
+
+// Validate checks that the source has what its type needs
+func (s LogSource) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch s.Type {
+	case "", TypeFile:
+		if s.Path == "" {
+			return fmt.Errorf("path is required")
+		}
+		if len(s.Containers) > 0 || len(s.ContainerLabels) > 0 {
+			return fmt.Errorf("containers and container_labels require type %s", TypeDocker)
+		}
+	case TypeDocker:
+		if s.Path != "" {
+			return fmt.Errorf("path is not used by %s sources", TypeDocker)
+		}
+		if len(s.Containers) == 0 && len(s.ContainerLabels) == 0 {
+			return fmt.Errorf("containers or container_labels is required")
+		}
+	case TypeKubernetes:
+		if s.Path != "" || len(s.Containers) > 0 || len(s.ContainerLabels) > 0 {
+			return fmt.Errorf("path, containers and container_labels are not used by %s sources", TypeKubernetes)
+		}
+	default:
+		return fmt.Errorf("invalid type %q: must be %s, %s or %s", s.Type, TypeFile, TypeDocker, TypeKubernetes)
+	}
+	if s.Type != TypeKubernetes && (len(s.Namespaces) > 0 || len(s.PodLabels) > 0 || s.KubeConfig != "") {
+		return fmt.Errorf("namespaces, pod_labels and kubeconfig require type %s", TypeKubernetes)
+	}
+	if err := validateStages(s.Stages); err != nil {
+		return err
+	}
+	return validateLabels(s)
 }
 
 // sourcesFile is the YAML structure for storing sources
@@ -25,9 +97,12 @@ type sourcesFile struct {
 
 // promtailScrapeConfig represents a Promtail scrape config
 type promtailScrapeConfig struct {
-	JobName        string           `yaml:"job_name"`
-	StaticConfigs  []promtailStatic `yaml:"static_configs"`
-	PipelineStages []map[string]any `yaml:"pipeline_stages,omitempty"`
+	JobName        string            `yaml:"job_name"`
+	StaticConfigs  []promtailStatic  `yaml:"static_configs,omitempty"`
+	DockerSDConfig []promtailDocker  `yaml:"docker_sd_configs,omitempty"`
+	KubernetesSD   []promtailK8s     `yaml:"kubernetes_sd_configs,omitempty"`
+	RelabelConfigs []promtailRelabel `yaml:"relabel_configs,omitempty"`
+	PipelineStages []map[string]any  `yaml:"pipeline_stages,omitempty"`
 }
 
 type promtailStatic struct {
@@ -35,6 +110,42 @@ type promtailStatic struct {
 	Labels  map[string]string `yaml:"labels"`
 }
 
+type promtailDocker struct {
+	Host            string                 `yaml:"host"`
+	RefreshInterval string                 `yaml:"refresh_interval"`
+	Filters         []promtailDockerFilter `yaml:"filters"`
+}
+
+type promtailDockerFilter struct {
+	Name   string   `yaml:"name"`
+	Values []string `yaml:"values"`
+}
+
+type promtailK8s struct {
+	Role           string                `yaml:"role"`
+	KubeConfigFile string                `yaml:"kubeconfig_file,omitempty"`
+	Namespaces     *promtailK8sNamespace `yaml:"namespaces,omitempty"`
+	Selectors      []promtailK8sSelector `yaml:"selectors,omitempty"`
+}
+
+type promtailK8sNamespace struct {
+	Names []string `yaml:"names"`
+}
+
+type promtailK8sSelector struct {
+	Role  string `yaml:"role"`
+	Label string `yaml:"label"`
+}
+
+type promtailRelabel struct {
+	SourceLabels []string `yaml:"source_labels,omitempty"`
+	Separator    string   `yaml:"separator,omitempty"`
+	Regex        string   `yaml:"regex,omitempty"`
+	Action       string   `yaml:"action,omitempty"`
+	TargetLabel  string   `yaml:"target_label,omitempty"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+}
+
 // promtailDynamicConfig is the structure for the dynamic config file
 type promtailDynamicConfig struct {
 	ScrapeConfigs []promtailScrapeConfig `yaml:"scrape_configs"`
@@ -96,6 +207,15 @@ func (m *Manager) generatePromtailContent() ([]byte, error) {
 	}
 
 	for _, source := range m.sources {
+		switch source.Type {
+		case TypeDocker:
+			config.ScrapeConfigs = append(config.ScrapeConfigs, dockerScrapeConfig(source))
+			continue
+		case TypeKubernetes:
+			config.ScrapeConfigs = append(config.ScrapeConfigs, kubernetesScrapeConfig(source))
+			continue
+		}
+
 		labels := make(map[string]string)
 		labels["__path__"] = source.Path
 		labels["source"] = source.Name
@@ -113,6 +233,8 @@ func (m *Manager) generatePromtailContent() ([]byte, error) {
 					Labels:  labels,
 				},
 			},
+			RelabelConfigs: relabelConfigs(source.Relabel),
+			PipelineStages: pipelineStages(source.Stages),
 		}
 
 		config.ScrapeConfigs = append(config.ScrapeConfigs, scrapeConfig)
@@ -128,6 +250,101 @@ func (m *Manager) generatePromtailContent() ([]byte, error) {
 	return append(header, data...), nil
 }
 
+// dockerScrapeConfig creates a Promtail job reading the logs of the
+// containers a docker source selects
+func dockerScrapeConfig(source LogSource) promtailScrapeConfig {
+	var filters []promtailDockerFilter
+	if len(source.Containers) > 0 {
+		filters = append(filters, promtailDockerFilter{Name: "name", Values: source.Containers})
+	}
+	if len(source.ContainerLabels) > 0 {
+		keys := make([]string, 0, len(source.ContainerLabels))
+		for k := range source.ContainerLabels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(keys))
+		for _, k := range keys {
+			values = append(values, k+"="+source.ContainerLabels[k])
+		}
+		filters = append(filters, promtailDockerFilter{Name: "label", Values: values})
+	}
+
+	relabel := []promtailRelabel{
+		{SourceLabels: []string{"__meta_docker_container_name"}, Regex: "/(.*)", TargetLabel: "instance"},
+	}
+
+	return promtailScrapeConfig{
+		JobName: fmt.Sprintf("custom_%s", source.Name),
+		DockerSDConfig: []promtailDocker{{
+			Host:            dockerHost,
+			RefreshInterval: "5s",
+			Filters:         filters,
+		}},
+		RelabelConfigs: append(relabel, staticRelabels(source)...),
+		PipelineStages: append([]map[string]any{{"docker": map[string]any{}}}, pipelineStages(source.Stages)...),
+	}
+}
+
+// kubernetesScrapeConfig creates a Promtail job reading the container log
+// files of the pods a kubernetes source selects
+func kubernetesScrapeConfig(source LogSource) promtailScrapeConfig {
+	sd := promtailK8s{Role: "pod", KubeConfigFile: source.KubeConfig}
+	if len(source.Namespaces) > 0 {
+		sd.Namespaces = &promtailK8sNamespace{Names: source.Namespaces}
+	}
+	if len(source.PodLabels) > 0 {
+		keys := make([]string, 0, len(source.PodLabels))
+		for k := range source.PodLabels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		selector := make([]string, 0, len(keys))
+		for _, k := range keys {
+			selector = append(selector, k+"="+source.PodLabels[k])
+		}
+		sd.Selectors = []promtailK8sSelector{{Role: "pod", Label: strings.Join(selector, ",")}}
+	}
+
+	relabel := []promtailRelabel{
+		{SourceLabels: []string{"__meta_kubernetes_pod_uid", "__meta_kubernetes_pod_container_name"}, Separator: "/", TargetLabel: "__path__", Replacement: podLogPattern},
+		{SourceLabels: []string{"__meta_kubernetes_namespace"}, TargetLabel: "namespace"},
+		{SourceLabels: []string{"__meta_kubernetes_pod_name"}, TargetLabel: "pod"},
+		{SourceLabels: []string{"__meta_kubernetes_pod_container_name"}, TargetLabel: "container"},
+	}
+
+	return promtailScrapeConfig{
+		JobName:        fmt.Sprintf("custom_%s", source.Name),
+		KubernetesSD:   []promtailK8s{sd},
+		RelabelConfigs: append(relabel, staticRelabels(source)...),
+		PipelineStages: append([]map[string]any{{"cri": map[string]any{}}}, pipelineStages(source.Stages)...),
+	}
+}
+
+// staticRelabels sets the source name and custom labels of a discovered
+// target; service discovery jobs have no static labels
+func staticRelabels(source LogSource) []promtailRelabel {
+	relabel := []promtailRelabel{{TargetLabel: "source", Replacement: source.Name}}
+	keys := make([]string, 0, len(source.Labels))
+	for k := range source.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		relabel = append(relabel, promtailRelabel{TargetLabel: k, Replacement: source.Labels[k]})
+	}
+	return append(relabel, relabelConfigs(source.Relabel)...)
+}
+
+// pipelineStages renders the stages of a source for Promtail
+func pipelineStages(stages []Stage) []map[string]any {
+	var out []map[string]any
+	for _, s := range stages {
+		out = append(out, promtailStage(s))
+	}
+	return out
+}
+
 // atomicPersist writes both sources and Promtail config atomically using temp files.
 // If any step fails, temp files are cleaned up and the original files remain unchanged.
 // The rename order prioritizes promtail config first (the service config), then sources.
@@ -359,6 +576,62 @@ func (m *Manager) Delete(name string) error {
 	return nil
 }
 
+// Sync makes the sources labelled with owner match desired, replacing or
+// removing only those that differ. Each desired source must carry the owner
+// label. It reports whether anything changed, in which case Promtail needs
+// a reload.
+func (m *Manager) Sync(owner string, desired []LogSource) (bool, error) {
+	want := make(map[string]LogSource, len(desired))
+	for _, src := range desired {
+		if src.Labels[owner] == "" {
+			return false, fmt.Errorf("source %s is missing the %s label", src.Name, owner)
+		}
+		want[src.Name] = src
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	originalSources := make([]LogSource, len(m.sources))
+	copy(originalSources, m.sources)
+
+	changed := false
+	next := make([]LogSource, 0, len(m.sources)+len(want))
+	for _, src := range m.sources {
+		w, wanted := want[src.Name]
+		switch {
+		case wanted && src.Labels[owner] == "":
+			return false, fmt.Errorf("source %s already exists", src.Name)
+		case wanted:
+			if !reflect.DeepEqual(w, src) {
+				changed = true
+			}
+			next = append(next, w)
+			delete(want, src.Name)
+		case src.Labels[owner] != "":
+			changed = true
+		default:
+			next = append(next, src)
+		}
+	}
+	for _, src := range desired {
+		if _, ok := want[src.Name]; ok {
+			next = append(next, src)
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	m.sources = next
+	if err := m.atomicPersist(); err != nil {
+		m.sources = originalSources
+		return false, err
+	}
+	return true, nil
+}
+
 // ReloadPromtail sends SIGHUP to Promtail to reload config
 func (m *Manager) ReloadPromtail() error {
 	// Use docker kill to send signal from outside the container
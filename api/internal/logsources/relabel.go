@@ -0,0 +1,133 @@
+package logsources
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relabel actions
+const (
+	RelabelAdd    = "add"    // set label to value
+	RelabelDrop   = "drop"   // remove label
+	RelabelRename = "rename" // move from into label
+)
+
+// Loki's default stream label limits; streams over them are rejected at push
+const (
+	maxLabelNames       = 15
+	maxLabelValueLength = 2048
+)
+
+// builtinLabels are the stream labels each source type gets without rules
+var builtinLabels = map[string][]string{
+	TypeFile:       {"source", "filename"},
+	TypeDocker:     {"source", "instance", "stream"},
+	TypeKubernetes: {"source", "namespace", "pod", "container", "stream"},
+}
+
+// lateLabels are set by Promtail after relabeling, so rules cannot remove them
+var lateLabels = map[string]bool{"filename": true, "stream": true}
+
+// RelabelRule changes the labels of a source's streams. From may name a
+// discovered label such as __meta_docker_container_label_app.
+type RelabelRule struct {
+	Action string `json:"action" yaml:"action"`
+	Label  string `json:"label" yaml:"label"`
+	Value  string `json:"value,omitempty" yaml:"value,omitempty"` // add
+	From   string `json:"from,omitempty" yaml:"from,omitempty"`   // rename
+}
+
+// validateLabels checks static labels and relabel rules, and that the
+// resulting streams stay within Loki's label limits
+func validateLabels(s LogSource) error {
+	typ := s.Type
+	if typ == "" {
+		typ = TypeFile
+	}
+	names := make(map[string]bool)
+	for _, name := range builtinLabels[typ] {
+		names[name] = true
+	}
+
+	for name, value := range s.Labels {
+		if err := validateLabel(name, value); err != nil {
+			return err
+		}
+		names[name] = true
+	}
+
+	for i, r := range s.Relabel {
+		if !labelNamePattern.MatchString(r.Label) {
+			return fmt.Errorf("relabel %d: invalid label name: %q", i+1, r.Label)
+		}
+		switch r.Action {
+		case RelabelAdd:
+			if err := validateLabel(r.Label, r.Value); err != nil {
+				return fmt.Errorf("relabel %d: %w", i+1, err)
+			}
+			names[r.Label] = true
+		case RelabelDrop:
+			if lateLabels[r.Label] {
+				return fmt.Errorf("relabel %d: %s is set after relabeling and cannot be dropped", i+1, r.Label)
+			}
+			delete(names, r.Label)
+		case RelabelRename:
+			if r.From == "" || !strings.HasPrefix(r.From, "__meta_") && !labelNamePattern.MatchString(r.From) {
+				return fmt.Errorf("relabel %d: invalid from label: %q", i+1, r.From)
+			}
+			if lateLabels[r.From] {
+				return fmt.Errorf("relabel %d: %s is set after relabeling and cannot be renamed", i+1, r.From)
+			}
+			delete(names, r.From)
+			names[r.Label] = true
+		default:
+			return fmt.Errorf("relabel %d: invalid action %q: must be %s, %s or %s", i+1, r.Action, RelabelAdd, RelabelDrop, RelabelRename)
+		}
+	}
+
+	// Labels stages add extracted values after relabeling
+	for _, st := range s.Stages {
+		if st.Type == StageLabels {
+			for name := range st.Values {
+				names[name] = true
+			}
+		}
+	}
+	if len(names) > maxLabelNames {
+		return fmt.Errorf("streams would have %d labels; Loki accepts at most %d", len(names), maxLabelNames)
+	}
+	return nil
+}
+
+func validateLabel(name, value string) error {
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid label name: %q", name)
+	}
+	if value == "" {
+		return fmt.Errorf("label %s needs a value", name)
+	}
+	if len(value) > maxLabelValueLength {
+		return fmt.Errorf("label %s value exceeds %d characters", name, maxLabelValueLength)
+	}
+	return nil
+}
+
+// relabelConfigs renders relabel rules for Promtail
+func relabelConfigs(rules []RelabelRule) []promtailRelabel {
+	var out []promtailRelabel
+	for _, r := range rules {
+		switch r.Action {
+		case RelabelAdd:
+			out = append(out, promtailRelabel{TargetLabel: r.Label, Replacement: r.Value})
+		case RelabelDrop:
+			out = append(out, promtailRelabel{Action: "labeldrop", Regex: r.Label})
+		case RelabelRename:
+			out = append(out, promtailRelabel{SourceLabels: []string{r.From}, TargetLabel: r.Label})
+			// Discovered __ labels are dropped once relabeling ends
+			if !strings.HasPrefix(r.From, "__") {
+				out = append(out, promtailRelabel{Action: "labeldrop", Regex: r.From})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package logsources
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Stage types, rendered as Promtail pipeline stages
+const (
+	StageMultiline = "multiline" // join lines until the next match of expression
+	StageJSON      = "json"      // extract keys of a JSON log line
+	StageRegex     = "regex"     // extract named groups from source
+	StageLabels    = "labels"    // promote extracted values to stream labels
+	StageTimestamp = "timestamp" // use an extracted value as the entry time
+)
+
+const maxStages = 20
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,63}$`)
+
+// Stage is one Promtail pipeline step. Source names an extracted value to
+// read; when empty the log line is used.
+type Stage struct {
+	Type       string            `json:"type" yaml:"type"`
+	Source     string            `json:"source,omitempty" yaml:"source,omitempty"`
+	Expression string            `json:"expression,omitempty" yaml:"expression,omitempty"` // regex, and the first line of a multiline entry
+	Values     map[string]string `json:"values,omitempty" yaml:"values,omitempty"`         // json: name -> JMESPath; labels: name -> extracted key ("" = same name)
+	Format     string            `json:"format,omitempty" yaml:"format,omitempty"`         // timestamp: Go layout or RFC3339, Unix, UnixMs, ...
+	MaxWait    string            `json:"max_wait,omitempty" yaml:"max_wait,omitempty"`     // multiline: flush after this long, default 3s
+}
+
+// validateStages checks stages before they reach Promtail, which would
+// otherwise refuse to load the whole dynamic config
+func validateStages(stages []Stage) error {
+	if len(stages) > maxStages {
+		return fmt.Errorf("at most %d stages are allowed", maxStages)
+	}
+	for i, s := range stages {
+		if err := validateStage(s); err != nil {
+			return fmt.Errorf("stage %d (%s): %w", i+1, s.Type, err)
+		}
+		if s.Type == StageMultiline && i != 0 {
+			return fmt.Errorf("stage %d: multiline must be the first stage", i+1)
+		}
+	}
+	return nil
+}
+
+func validateStage(s Stage) error {
+	switch s.Type {
+	case StageMultiline:
+		if s.Source != "" {
+			return fmt.Errorf("source is not supported")
+		}
+		if s.Expression == "" {
+			return fmt.Errorf("expression is required")
+		}
+		if _, err := regexp.Compile(s.Expression); err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+		if s.MaxWait != "" {
+			if _, err := time.ParseDuration(s.MaxWait); err != nil {
+				return fmt.Errorf("invalid max_wait: %q", s.MaxWait)
+			}
+		}
+	case StageRegex:
+		if s.Expression == "" {
+			return fmt.Errorf("expression is required")
+		}
+		re, err := regexp.Compile(s.Expression)
+		if err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			named = named || name != ""
+		}
+		if !named {
+			return fmt.Errorf("expression must contain named groups, e.g. (?P<status>\\d+)")
+		}
+	case StageJSON:
+		if len(s.Values) == 0 {
+			return fmt.Errorf("values are required")
+		}
+	case StageLabels:
+		if len(s.Values) == 0 {
+			return fmt.Errorf("values are required")
+		}
+		for name := range s.Values {
+			if !labelNamePattern.MatchString(name) {
+				return fmt.Errorf("invalid label name: %q", name)
+			}
+		}
+	case StageTimestamp:
+		if s.Source == "" || s.Format == "" {
+			return fmt.Errorf("source and format are required")
+		}
+	default:
+		return fmt.Errorf("unknown stage type")
+	}
+	return nil
+}
+
+// promtailStage renders a stage in Promtail's pipeline_stages format
+func promtailStage(s Stage) map[string]any {
+	var body map[string]any
+	switch s.Type {
+	case StageMultiline:
+		body = map[string]any{"firstline": s.Expression}
+		if s.MaxWait != "" {
+			body["max_wait_time"] = s.MaxWait
+		}
+	case StageRegex:
+		body = map[string]any{"expression": s.Expression}
+	case StageJSON:
+		body = map[string]any{"expressions": s.Values}
+	case StageLabels:
+		// Promtail reads a null value as "same name"
+		labels := make(map[string]any, len(s.Values))
+		for name, key := range s.Values {
+			if key == "" {
+				labels[name] = nil
+			} else {
+				labels[name] = key
+			}
+		}
+		return map[string]any{StageLabels: labels}
+	case StageTimestamp:
+		body = map[string]any{"format": s.Format}
+	}
+	if s.Source != "" {
+		body["source"] = s.Source
+	}
+	return map[string]any{s.Type: body}
+}
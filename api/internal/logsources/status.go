@@ -0,0 +1,112 @@
+package logsources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+// Promtail file target metrics, labelled with the tailed file's path
+const (
+	metricFileBytes = "promtail_file_bytes_total" // file size
+	metricReadBytes = "promtail_read_bytes_total" // read position
+	metricReadLines = "promtail_read_lines_total"
+)
+
+// Status reports what Promtail is tailing for a file source
+type Status struct {
+	Name    string       `json:"name"`
+	Path    string       `json:"path"`
+	Tailing bool         `json:"tailing"`   // at least one file matches the path
+	Files   []FileStatus `json:"files"`     // files Promtail has open
+	Lag     int64        `json:"lag_bytes"` // bytes written but not yet read, over all files
+}
+
+// FileStatus is Promtail's read position in one file
+type FileStatus struct {
+	Path     string  `json:"path"`
+	Size     int64   `json:"size_bytes"`
+	Position int64   `json:"position_bytes"`
+	Lag      int64   `json:"lag_bytes"`
+	Lines    float64 `json:"lines_read"`
+}
+
+// PromtailStatus reads Promtail's metrics at promtailURL to report the
+// files it tails for a file source. Only the file name may contain
+// wildcards for matching; ** patterns report no files.
+func PromtailStatus(ctx context.Context, promtailURL string, source LogSource) (*Status, error) {
+	if source.Type != "" && source.Type != TypeFile {
+		return nil, fmt.Errorf("status is only reported for %s sources", TypeFile)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", promtailURL+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("promtail unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("promtail metrics returned %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse promtail metrics: %w", err)
+	}
+
+	files := make(map[string]*FileStatus)
+	for _, name := range []string{metricFileBytes, metricReadBytes, metricReadLines} {
+		family, ok := families[name]
+		if !ok {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			file := ""
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "path" {
+					file = l.GetValue()
+				}
+			}
+			if ok, _ := path.Match(source.Path, file); !ok {
+				continue
+			}
+			fs := files[file]
+			if fs == nil {
+				fs = &FileStatus{Path: file}
+				files[file] = fs
+			}
+			value := m.GetGauge().GetValue() + m.GetCounter().GetValue()
+			switch name {
+			case metricFileBytes:
+				fs.Size = int64(value)
+			case metricReadBytes:
+				fs.Position = int64(value)
+			case metricReadLines:
+				fs.Lines = value
+			}
+		}
+	}
+
+	st := &Status{Name: source.Name, Path: source.Path, Files: []FileStatus{}}
+	for _, fs := range files {
+		if fs.Size > fs.Position {
+			fs.Lag = fs.Size - fs.Position
+		}
+		st.Lag += fs.Lag
+		st.Files = append(st.Files, *fs)
+	}
+	sort.Slice(st.Files, func(i, j int) bool { return st.Files[i].Path < st.Files[j].Path })
+	st.Tailing = len(st.Files) > 0
+	return st, nil
+}
@@ -0,0 +1,300 @@
+package messaging
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// handshakeTimeout bounds reading INFO and the answer to CONNECT
+	handshakeTimeout = 5 * time.Second
+	// writeTimeout bounds writes to a server that stopped reading
+	writeTimeout = 10 * time.Second
+	// readBuffer bounds protocol lines other than payloads
+	readBuffer = 32 * 1024
+)
+
+// serverInfo is the INFO the server sends when a client connects
+type serverInfo struct {
+	ServerID    string `json:"server_id"`
+	Version     string `json:"version"`
+	MaxPayload  int64  `json:"max_payload"`
+	TLSRequired bool   `json:"tls_required"`
+}
+
+// connectOptions is the CONNECT sent after INFO
+type connectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	Echo     bool   `json:"echo"`
+}
+
+// conn is one connection to the server, speaking the NATS client
+// protocol: text control lines, each MSG followed by its payload
+type conn struct {
+	nc   net.Conn
+	info serverInfo
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu    sync.Mutex
+	pongs []chan struct{} // waiting for PONG, oldest first
+	err   error           // why the connection closed
+	done  chan struct{}
+}
+
+// dial connects to the server at u and completes the handshake. The
+// reader is handed to readLoop.
+func dial(ctx context.Context, u *url.URL, user, password string) (*conn, *bufio.Reader, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deadline := time.Now().Add(handshakeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	nc.SetDeadline(deadline)
+
+	r := bufio.NewReaderSize(nc, readBuffer)
+	line, err := readLine(r)
+	if err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("reading INFO: %w", err)
+	}
+	op, args, _ := strings.Cut(line, " ")
+	if !strings.EqualFold(op, "INFO") {
+		nc.Close()
+		return nil, nil, fmt.Errorf("expected INFO from server, got %q", op)
+	}
+	var info serverInfo
+	if err := json.Unmarshal([]byte(args), &info); err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("parsing INFO: %w", err)
+	}
+	if info.MaxPayload <= 0 {
+		info.MaxPayload = defaultMaxPayload
+	}
+
+	if u.Scheme == "tls" || info.TLSRequired {
+		tc := tls.Client(nc, &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, nil, fmt.Errorf("tls handshake: %w", err)
+		}
+		nc = tc
+		r = bufio.NewReaderSize(nc, readBuffer)
+	}
+
+	c := &conn{nc: nc, info: info, w: bufio.NewWriter(nc), done: make(chan struct{})}
+	opts, _ := json.Marshal(connectOptions{
+		User: user, Pass: password,
+		Name: "forge-api", Lang: "go", Version: "1.0", Protocol: 1, Echo: true,
+	})
+	// PONG confirms CONNECT was accepted; a bad password gets -ERR instead
+	if err := c.write([]byte("CONNECT " + string(opts) + "\r\nPING\r\n")); err != nil {
+		nc.Close()
+		return nil, nil, err
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			nc.Close()
+			return nil, nil, fmt.Errorf("connecting: %w", err)
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "PONG":
+			nc.SetReadDeadline(time.Time{})
+			return c, r, nil
+		case "-ERR":
+			nc.Close()
+			return nil, nil, serverError(args)
+		case "PING":
+			c.write([]byte("PONG\r\n"))
+		}
+	}
+}
+
+// readLoop reads from the server until the connection fails, handing
+// messages to deliver
+func (c *conn) readLoop(r *bufio.Reader, deliver func(sid uint64, m Msg)) {
+	var err error
+	defer func() { c.close(err) }()
+
+	for {
+		var line string
+		if line, err = readLine(r); err != nil {
+			return
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "MSG":
+			var sid uint64
+			var m Msg
+			if sid, m, err = readMsg(r, args); err != nil {
+				return
+			}
+			deliver(sid, m)
+		case "PING":
+			if err = c.write([]byte("PONG\r\n")); err != nil {
+				return
+			}
+		case "PONG":
+			c.mu.Lock()
+			if len(c.pongs) > 0 {
+				close(c.pongs[0])
+				c.pongs = c.pongs[1:]
+			}
+			c.mu.Unlock()
+		case "-ERR":
+			// Permission violations leave the connection open; anything
+			// else is followed by the server closing it
+			if e := serverError(args); !strings.Contains(strings.ToLower(args), "permissions violation") {
+				err = e
+				return
+			}
+		case "+OK", "INFO":
+		default:
+			err = fmt.Errorf("unexpected %q from server", op)
+			return
+		}
+	}
+}
+
+// readMsg reads the payload of a MSG whose arguments are
+// "<subject> <sid> [reply-to] <#bytes>"
+func readMsg(r *bufio.Reader, args string) (uint64, Msg, error) {
+	f := strings.Fields(args)
+	if len(f) != 3 && len(f) != 4 {
+		return 0, Msg{}, fmt.Errorf("malformed MSG %q", args)
+	}
+	sid, err := strconv.ParseUint(f[1], 10, 64)
+	if err != nil {
+		return 0, Msg{}, fmt.Errorf("malformed MSG %q", args)
+	}
+	size, err := strconv.Atoi(f[len(f)-1])
+	if err != nil || size < 0 {
+		return 0, Msg{}, fmt.Errorf("malformed MSG %q", args)
+	}
+	m := Msg{Subject: f[0], Received: time.Now().UTC()}
+	if len(f) == 4 {
+		m.Reply = f[2]
+	}
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, Msg{}, err
+	}
+	m.Data = buf[:size]
+	return sid, m, nil
+}
+
+// write sends protocol data and flushes it
+func (c *conn) write(data ...[]byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	for _, d := range data {
+		if _, err := c.w.Write(d); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+// flush sends a PING and waits for its PONG, so everything written before
+// has been processed by the server
+func (c *conn) flush(ctx context.Context) error {
+	pong := make(chan struct{})
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pongs = append(c.pongs, pong)
+	c.mu.Unlock()
+
+	if err := c.write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+	select {
+	case <-pong:
+		return nil
+	case <-c.done:
+		return c.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close shuts the connection, recording why
+func (c *conn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	c.err = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	c.nc.Close()
+	close(c.done)
+}
+
+// closed reports whether the connection is closed
+func (c *conn) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *conn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// readLine reads one control line without its CRLF
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errors.New("protocol line too long")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// serverError turns the argument of -ERR into an error
+func serverError(args string) error {
+	return fmt.Errorf("%w: %s", ErrServer, strings.Trim(strings.TrimSpace(args), "'"))
+}
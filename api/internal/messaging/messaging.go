@@ -0,0 +1,407 @@
+// Package messaging is a client for NATS, the message bus behind the
+// MessagingService: the bundled forge-nats or any other NATS server
+//
+// The API holds one connection, made by Ping so a deps.Supervisor keeps it
+// up. Messages are published and subscribed to on the API's behalf;
+// subscriptions in a queue group share the messages of their subject, each
+// going to one member. When the connection is lost, open subscriptions end
+// with ErrConnectionLost and callers subscribe again.
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/metrics"
+)
+
+const (
+	// defaultMaxPayload is used when the server does not announce one
+	defaultMaxPayload = 1 << 20
+	// subscriptionBuffer bounds messages waiting for a slow subscriber;
+	// beyond it they are dropped
+	subscriptionBuffer = 256
+	// MaxSubscriptions bounds the subscriptions open through the API
+	MaxSubscriptions = 1000
+
+	maxSubjectLength = 256
+	maxQueueLength   = 64
+)
+
+var (
+	// ErrInvalidSubject is returned for malformed or reserved subjects and
+	// queue groups
+	ErrInvalidSubject = errors.New("invalid subject")
+	// ErrTooLarge is returned for messages beyond the server's max payload
+	ErrTooLarge = errors.New("message too large")
+	// ErrTooManySubscriptions is returned beyond MaxSubscriptions
+	ErrTooManySubscriptions = errors.New("too many subscriptions")
+	// ErrNotConnected is returned while the server is not reached
+	ErrNotConnected = errors.New("not connected to the message bus")
+	// ErrConnectionLost ends subscriptions when the connection fails
+	ErrConnectionLost = errors.New("connection to the message bus lost")
+	// ErrServer wraps errors reported by the server
+	ErrServer = errors.New("message bus error")
+)
+
+// Msg is a message received on a subscription
+type Msg struct {
+	Subject  string    `json:"subject"`
+	Reply    string    `json:"reply_to,omitempty"`
+	Data     []byte    `json:"data"`
+	Received time.Time `json:"received"`
+}
+
+// Client talks to one NATS server
+type Client struct {
+	url          *url.URL
+	user         string
+	password     string
+	public       string
+	clientUser   string
+	clientPass   string
+	readUser     string
+	readPassword string
+
+	mu      sync.Mutex
+	conn    *conn
+	subs    map[uint64]*Subscription
+	nextSID uint64
+}
+
+// NewClient creates a client for the server in cfg. It does not connect; a
+// deps.Supervisor calls Ping, which does.
+func NewClient(cfg config.Messaging) (*Client, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("messaging url: %w", err)
+	}
+	return &Client{
+		url:          u,
+		user:         cfg.User,
+		password:     cfg.Password,
+		public:       cfg.ClientURL(),
+		clientUser:   cfg.ClientUser,
+		clientPass:   cfg.ClientPassword,
+		readUser:     cfg.ReadUser,
+		readPassword: cfg.ReadPassword,
+		subs:         make(map[uint64]*Subscription),
+	}, nil
+}
+
+// PublicURL returns the URL clients reach the server at
+func (c *Client) PublicURL() string {
+	return c.public
+}
+
+// Account returns the user and password handed to clients with write
+// (publish and subscribe) or read (subscribe only) access
+func (c *Client) Account(write bool) (user, password string) {
+	if write {
+		return c.clientUser, c.clientPass
+	}
+	return c.readUser, c.readPassword
+}
+
+// MaxPayload returns the largest message the server accepts
+func (c *Client) MaxPayload() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return defaultMaxPayload
+	}
+	return c.conn.info.MaxPayload
+}
+
+// ServerVersion returns the version of the server last connected to
+func (c *Client) ServerVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ""
+	}
+	return c.conn.info.Version
+}
+
+// Ping checks the connection, connecting first if there is none
+func (c *Client) Ping(ctx context.Context) error {
+	cn, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	return cn.flush(ctx)
+}
+
+// connect returns the open connection or makes a new one
+func (c *Client) connect(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil && !c.conn.closed() {
+		return c.conn, nil
+	}
+
+	user, password := c.user, c.password
+	if c.url.User != nil && user == "" {
+		user = c.url.User.Username()
+		password, _ = c.url.User.Password()
+	}
+	cn, r, err := dial(ctx, c.url, user, password)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = cn
+	go func() {
+		cn.readLoop(r, c.deliver)
+		c.dropSubscriptions(cn)
+	}()
+	return cn, nil
+}
+
+// current returns the open connection, without connecting
+func (c *Client) current() (*conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil || c.conn.closed() {
+		return nil, ErrNotConnected
+	}
+	return c.conn, nil
+}
+
+// Publish sends a message, with an optional subject to reply to, and waits
+// for the server to take it
+func (c *Client) Publish(ctx context.Context, subject, reply string, data []byte) error {
+	if err := ValidateSubject(subject, false); err != nil {
+		return err
+	}
+	if reply != "" {
+		if err := ValidateSubject(reply, false); err != nil {
+			return fmt.Errorf("reply_to: %w", err)
+		}
+	}
+	cn, err := c.current()
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > cn.info.MaxPayload {
+		return fmt.Errorf("%w: %d bytes, the server accepts at most %d", ErrTooLarge, len(data), cn.info.MaxPayload)
+	}
+
+	line := "PUB " + subject
+	if reply != "" {
+		line += " " + reply
+	}
+	line += " " + strconv.Itoa(len(data)) + "\r\n"
+	if err := cn.write([]byte(line), data, []byte("\r\n")); err != nil {
+		cn.close(err)
+		return cn.closedErr()
+	}
+	if err := cn.flush(ctx); err != nil {
+		return err
+	}
+	metrics.MessagingMessagesTotal.WithLabelValues("published").Inc()
+	return nil
+}
+
+// Subscribe starts receiving messages on subject, which may hold the
+// wildcards "*" (one token) and ">" (the rest). With a queue group, each
+// message goes to one of the group's subscriptions.
+func (c *Client) Subscribe(subject, queue string) (*Subscription, error) {
+	if err := ValidateSubject(subject, true); err != nil {
+		return nil, err
+	}
+	if err := validateQueue(queue); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.conn == nil || c.conn.closed() {
+		c.mu.Unlock()
+		return nil, ErrNotConnected
+	}
+	if len(c.subs) >= MaxSubscriptions {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w (max %d)", ErrTooManySubscriptions, MaxSubscriptions)
+	}
+	c.nextSID++
+	s := &Subscription{
+		Subject: subject,
+		Queue:   queue,
+		client:  c,
+		sid:     c.nextSID,
+		conn:    c.conn,
+		ch:      make(chan Msg, subscriptionBuffer),
+	}
+	c.subs[s.sid] = s
+	cn := c.conn
+	c.mu.Unlock()
+
+	line := "SUB " + subject
+	if queue != "" {
+		line += " " + queue
+	}
+	line += " " + strconv.FormatUint(s.sid, 10) + "\r\n"
+	if err := cn.write([]byte(line)); err != nil {
+		cn.close(err)
+		s.Close()
+		return nil, cn.closedErr()
+	}
+	return s, nil
+}
+
+// deliver hands a message to its subscription, dropping it when the
+// subscriber is too far behind
+func (c *Client) deliver(sid uint64, m Msg) {
+	c.mu.Lock()
+	s := c.subs[sid]
+	c.mu.Unlock()
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- m:
+		metrics.MessagingMessagesTotal.WithLabelValues("delivered").Inc()
+	default:
+		s.dropped++
+		metrics.MessagingMessagesTotal.WithLabelValues("dropped").Inc()
+	}
+}
+
+// dropSubscriptions ends the subscriptions of a connection that closed
+func (c *Client) dropSubscriptions(cn *conn) {
+	c.mu.Lock()
+	var ended []*Subscription
+	for sid, s := range c.subs {
+		if s.conn == cn {
+			ended = append(ended, s)
+			delete(c.subs, sid)
+		}
+	}
+	c.mu.Unlock()
+	for _, s := range ended {
+		s.end(cn.closedErr())
+	}
+}
+
+// Subscriptions returns how many subscriptions are open
+func (c *Client) Subscriptions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.subs)
+}
+
+// Subscription is an open subscription
+type Subscription struct {
+	Subject string
+	Queue   string
+
+	client *Client
+	sid    uint64
+	conn   *conn
+	ch     chan Msg
+
+	mu      sync.Mutex
+	closed  bool
+	err     error
+	dropped int
+}
+
+// Messages returns the channel messages arrive on. It is closed when the
+// subscription ends; Err tells why.
+func (s *Subscription) Messages() <-chan Msg {
+	return s.ch
+}
+
+// Err returns why the subscription ended: nil after Close
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped returns how many messages were dropped because the subscriber
+// fell behind
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.client.mu.Lock()
+	_, open := s.client.subs[s.sid]
+	delete(s.client.subs, s.sid)
+	s.client.mu.Unlock()
+
+	if open && !s.conn.closed() {
+		s.conn.write([]byte("UNSUB " + strconv.FormatUint(s.sid, 10) + "\r\n"))
+	}
+	s.end(nil)
+}
+
+// end closes the message channel once, recording err
+func (s *Subscription) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+	close(s.ch)
+}
+
+// ValidateSubject checks a subject: dot-separated tokens without
+// whitespace, the wildcards "*" and ">" (last token only) where allowed.
+// Subjects starting with "$" belong to the server and are refused.
+func ValidateSubject(subject string, wildcards bool) error {
+	if subject == "" {
+		return fmt.Errorf("%w: subject is required", ErrInvalidSubject)
+	}
+	if len(subject) > maxSubjectLength {
+		return fmt.Errorf("%w: subject is longer than %d characters", ErrInvalidSubject, maxSubjectLength)
+	}
+	if strings.HasPrefix(subject, "$") {
+		return fmt.Errorf("%w: subjects starting with $ are reserved", ErrInvalidSubject)
+	}
+	tokens := strings.Split(subject, ".")
+	for i, t := range tokens {
+		switch {
+		case t == "":
+			return fmt.Errorf("%w: %q has an empty token", ErrInvalidSubject, subject)
+		case strings.IndexFunc(t, unicode.IsSpace) >= 0:
+			return fmt.Errorf("%w: %q contains whitespace", ErrInvalidSubject, subject)
+		case t == "*" || (t == ">" && i == len(tokens)-1):
+			if !wildcards {
+				return fmt.Errorf("%w: wildcards are only allowed when subscribing", ErrInvalidSubject)
+			}
+		case strings.ContainsAny(t, "*>"):
+			return fmt.Errorf("%w: wildcards must be whole tokens, > only the last", ErrInvalidSubject)
+		}
+	}
+	return nil
+}
+
+// validateQueue checks a queue group name; empty means none
+func validateQueue(queue string) error {
+	if len(queue) > maxQueueLength {
+		return fmt.Errorf("%w: queue group is longer than %d characters", ErrInvalidSubject, maxQueueLength)
+	}
+	if strings.IndexFunc(queue, unicode.IsSpace) >= 0 || strings.ContainsAny(queue, "*>") {
+		return fmt.Errorf("%w: queue group must not contain whitespace or wildcards", ErrInvalidSubject)
+	}
+	return nil
+}
@@ -4,6 +4,39 @@
 //   - forge_http_requests_total (counter) - Total HTTP requests by endpoint, method, status
 //   - forge_http_request_duration_seconds (histogram) - Request latency by endpoint, method
 //   - forge_http_requests_in_flight (gauge) - Current in-flight requests
+//   - forge_deprecated_requests_total (counter) - Calls to deprecated endpoints
+//   - forge_loki_queue_depth (gauge) - Log entries buffered for Loki
+//   - forge_loki_entries_dropped_total (counter) - Log entries dropped, by reason
+//   - forge_loki_batches_total (counter) - Loki batch pushes, by result
+//   - forge_syslog_messages_total (counter) - Syslog messages received, by transport and result
+//   - forge_fluent_records_total (counter) - Fluent HTTP records received, by result
+//   - forge_monitor_up (gauge) - Whether the last check of a monitor succeeded
+//   - forge_monitor_response_seconds (gauge) - Duration of the last check of a monitor
+//   - forge_monitor_checks_total (counter) - Monitor checks run, by type and result
+//   - forge_route_up (gauge) - Whether the last health probe of a route succeeded
+//   - forge_job_runs_total (counter) - Scheduled job runs, by job and status
+//   - forge_job_duration_seconds (gauge) - Duration of the last run of a job
+//   - forge_job_last_success_timestamp_seconds (gauge) - When a job last succeeded
+//   - forge_job_running (gauge) - Runs of a job in progress
+//   - forge_webhook_deliveries_total (counter) - Webhook delivery attempts, by webhook and result
+//   - forge_hook_requests_total (counter) - Inbound hook requests, by hook and result
+//   - forge_notifications_total (counter) - Notifications sent, by channel and result
+//   - forge_messaging_messages_total (counter) - Messages published and received through the API, by direction
+//   - forge_mqtt_messages_total (counter) - MQTT messages handled by bridges, by bridge and result
+//   - forge_mqtt_published_total (counter) - MQTT messages published through the API, by result
+//   - forge_service_instances (gauge) - Instances in the service registry, by service and status
+//   - forge_error_events_total (counter) - Exception events captured, by level
+//   - forge_profiles_total (counter) - Profile uploads relayed to Pyroscope, by result
+//   - forge_certificate_expiry_timestamp_seconds (gauge) - Expiry of managed TLS certificates
+//   - forge_host_cpu_percent (gauge) - Host CPU usage
+//   - forge_host_memory_bytes (gauge) - Host memory, by type (total, used)
+//   - forge_host_load (gauge) - Host load average, by period
+//   - forge_host_disk_bytes (gauge) - Host filesystem space, by mount and type (total, used)
+//   - forge_host_network_bytes_per_second (gauge) - Host interface throughput, by interface and direction
+//   - forge_watchdog_restarts_total (counter) - Container restarts by the watchdog, by container, reason and result
+//   - forge_rate_limited_total (counter) - Requests refused by rate limits, by endpoint class and scope
+//   - forge_service_up (gauge) - Whether MySQL and Redis answer pings, by service
+//   - forge_service_reconnects_total (counter) - Connections regained after they were lost, by service
 package metrics
 
 import (
@@ -59,6 +92,328 @@ var (
 		[]string{"operation"},
 	)
 
+	// DeprecatedRequestsTotal counts calls to deprecated endpoints
+	DeprecatedRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_deprecated_requests_total",
+			Help: "Total number of requests to deprecated endpoints",
+		},
+		[]string{"endpoint"},
+	)
+
+	// LokiQueueDepth tracks log entries buffered for Loki
+	LokiQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "forge_loki_queue_depth",
+			Help: "Log entries buffered waiting to be pushed to Loki",
+		},
+	)
+
+	// LokiEntriesDropped counts log entries that never reached Loki
+	LokiEntriesDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_loki_entries_dropped_total",
+			Help: "Log entries dropped before reaching Loki, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	// SyslogMessagesTotal counts syslog messages received by the listener
+	SyslogMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_syslog_messages_total",
+			Help: "Syslog messages received, by transport (udp, tcp) and result (forwarded, invalid, dropped)",
+		},
+		[]string{"transport", "result"},
+	)
+
+	// FluentRecordsTotal counts records received on the fluent ingest endpoint
+	FluentRecordsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_fluent_records_total",
+			Help: "Fluent HTTP records received, by result (forwarded, invalid, dropped)",
+		},
+		[]string{"result"},
+	)
+
+	// MonitorUp is 1 when the last check of a monitor succeeded
+	MonitorUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_monitor_up",
+			Help: "Whether the last check of a monitor succeeded (1) or failed (0)",
+		},
+		[]string{"monitor", "type"},
+	)
+
+	// MonitorResponseSeconds is the duration of the last check of a monitor
+	MonitorResponseSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_monitor_response_seconds",
+			Help: "Duration of the last check of a monitor in seconds",
+		},
+		[]string{"monitor"},
+	)
+
+	// MonitorChecksTotal counts monitor checks
+	MonitorChecksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_monitor_checks_total",
+			Help: "Monitor checks run, by type (http, tcp, ping) and result (success, failure)",
+		},
+		[]string{"type", "result"},
+	)
+
+	// RouteUp is 1 when the last health probe of a route succeeded
+	RouteUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_route_up",
+			Help: "Whether the last health probe of a route through nginx succeeded (1) or failed (0)",
+		},
+		[]string{"route"},
+	)
+
+	// ErrorEventsTotal counts captured exception events
+	ErrorEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_error_events_total",
+			Help: "Exception events captured by error tracking, by level",
+		},
+		[]string{"level"},
+	)
+
+	// FlagEvaluationsTotal counts feature flag evaluations
+	FlagEvaluationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_flag_evaluations_total",
+			Help: "Feature flag evaluations, by reason (rule, rollout, default, disabled, missing_key, not_found)",
+		},
+		[]string{"reason"},
+	)
+
+	// JobRunsTotal counts scheduled job runs
+	JobRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_job_runs_total",
+			Help: "Scheduled job runs, by job and status (succeeded, failed, skipped, cancelled)",
+		},
+		[]string{"job", "status"},
+	)
+
+	// JobDurationSeconds is the duration of the last run of a job
+	JobDurationSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_job_duration_seconds",
+			Help: "Duration of the last finished run of a job in seconds",
+		},
+		[]string{"job"},
+	)
+
+	// JobLastSuccess is when a job last succeeded
+	JobLastSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_job_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful run of a job",
+		},
+		[]string{"job"},
+	)
+
+	// JobRunning is the number of runs of a job in progress
+	JobRunning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_job_running",
+			Help: "Runs of a job in progress",
+		},
+		[]string{"job"},
+	)
+
+	// WebhookDeliveriesTotal counts webhook delivery attempts
+	WebhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_webhook_deliveries_total",
+			Help: "Webhook delivery attempts, by webhook and result (delivered, retry, failed, dropped)",
+		},
+		[]string{"webhook", "result"},
+	)
+
+	// HookRequestsTotal counts requests received by inbound hooks
+	HookRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_hook_requests_total",
+			Help: "Inbound hook requests, by hook and result (delivered, ignored, failed, rejected)",
+		},
+		[]string{"hook", "result"},
+	)
+
+	// NotificationsTotal counts messages sent to notification channels
+	NotificationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_notifications_total",
+			Help: "Notifications sent, by channel and result (sent, failed, dropped)",
+		},
+		[]string{"channel", "result"},
+	)
+
+	// MessagingMessagesTotal counts messages passing through the API's
+	// message bus connection
+	MessagingMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_messaging_messages_total",
+			Help: "Messages published and received through the API, by direction (published, delivered, dropped)",
+		},
+		[]string{"direction"},
+	)
+
+	// MQTTMessagesTotal counts MQTT messages handled by bridges
+	MQTTMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_mqtt_messages_total",
+			Help: "MQTT messages handled by bridges, by bridge and result (forwarded, failed, dropped)",
+		},
+		[]string{"bridge", "result"},
+	)
+
+	// MQTTPublishedTotal counts MQTT messages published through the API
+	MQTTPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_mqtt_published_total",
+			Help: "MQTT messages published through the API, by result (published, failed)",
+		},
+		[]string{"result"},
+	)
+
+	// ServiceInstances is the number of registered instances of a service
+	ServiceInstances = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_service_instances",
+			Help: "Instances in the service registry, by service and status (passing, critical)",
+		},
+		[]string{"service", "status"},
+	)
+
+	// ProfilesTotal counts profile uploads received on the profiles ingest endpoint
+	ProfilesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_profiles_total",
+			Help: "Profile uploads relayed to Pyroscope, by result (forwarded, invalid, failed)",
+		},
+		[]string{"result"},
+	)
+
+	// CertificateExpiry is the NotAfter time of each managed certificate
+	CertificateExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_certificate_expiry_timestamp_seconds",
+			Help: "Expiry time of managed TLS certificates as a unix timestamp",
+		},
+		[]string{"domain"},
+	)
+
+	// HostCPUPercent is the host CPU usage over the last collection interval
+	HostCPUPercent = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "forge_host_cpu_percent",
+			Help: "Host CPU usage in percent over the last collection interval",
+		},
+	)
+
+	// HostMemoryBytes is the host memory total and in use
+	HostMemoryBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_host_memory_bytes",
+			Help: "Host memory in bytes by type (total, used)",
+		},
+		[]string{"type"},
+	)
+
+	// HostLoad is the host load average
+	HostLoad = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_host_load",
+			Help: "Host load average by period (1m, 5m, 15m)",
+		},
+		[]string{"period"},
+	)
+
+	// HostDiskBytes is the size and used space of host filesystems
+	HostDiskBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_host_disk_bytes",
+			Help: "Host filesystem space in bytes by mount and type (total, used)",
+		},
+		[]string{"mount", "type"},
+	)
+
+	// HostNetworkBytesPerSecond is the throughput of host network interfaces
+	HostNetworkBytesPerSecond = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "forge_host_network_bytes_per_second",
+			Help: "Host network interface throughput by interface and direction (rx, tx)",
+		},
+		[]string{"interface", "direction"},
+	)
+
+	// WatchdogRestartsTotal counts container restarts by the watchdog
+	WatchdogRestartsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_watchdog_restarts_total",
+			Help: "Container restarts by the watchdog, by container, reason (exited, unhealthy) and result (success, failure)",
+		},
+		[]string{"container", "reason", "result"},
+	)
+
+	// RateLimitedTotal counts requests refused by rate limits
+	RateLimitedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_rate_limited_total",
+			Help: "Requests refused with 429, by endpoint class (read, write, query, ingest) and scope (client, global)",
+		},
+		[]string{"class", "scope"},
+	)
+
+	// PanicsTotal counts handler panics turned into 500 responses
+	PanicsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_panics_total",
+			Help: "Handler panics recovered, by endpoint (REST path or Connect procedure)",
+		},
+		[]string{"endpoint"},
+	)
+
+	// AuthFailuresTotal counts failed authentication attempts
+	AuthFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_auth_failures_total",
+			Help: "Failed authentication attempts, by kind (key, login)",
+		},
+		[]string{"kind"},
+	)
+
+	// AuthLockoutsTotal counts clients locked out after repeated failures
+	AuthLockoutsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_auth_lockouts_total",
+			Help: "Lockouts after repeated authentication failures, by client kind (ip, key, user)",
+		},
+		[]string{"client"},
+	)
+
+	// AuthLockedTotal counts requests refused during a lockout
+	AuthLockedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "forge_auth_locked_requests_total",
+			Help: "Requests refused because their client is locked out",
+		},
+	)
+
+	// LokiBatchesTotal counts batch push attempts to Loki
+	LokiBatchesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_loki_batches_total",
+			Help: "Batches pushed to Loki, by result",
+		},
+		[]string{"result"},
+	)
+
 	// ServiceUp tracks service health (1 = up, 0 = down)
 	ServiceUp = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -67,12 +422,27 @@ var (
 		},
 		[]string{"service"},
 	)
+
+	// ServiceReconnectsTotal counts recoveries of lost dependencies
+	ServiceReconnectsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forge_service_reconnects_total",
+			Help: "Connections to MySQL or Redis regained after they were lost, by service",
+		},
+		[]string{"service"},
+	)
 )
 
-// RecordRequest records metrics for an HTTP request
-func RecordRequest(endpoint, method, status string, durationSeconds float64) {
+// RecordRequest records metrics for an HTTP request. A non-empty traceID
+// is attached to the duration observation as an exemplar.
+func RecordRequest(endpoint, method, status string, durationSeconds float64, traceID string) {
 	HTTPRequestsTotal.WithLabelValues(endpoint, method, status).Inc()
-	HTTPRequestDuration.WithLabelValues(endpoint, method).Observe(durationSeconds)
+	observer := HTTPRequestDuration.WithLabelValues(endpoint, method)
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && traceID != "" {
+		eo.ObserveWithExemplar(durationSeconds, prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	observer.Observe(durationSeconds)
 }
 
 // RecordDBQuery records metrics for a database query
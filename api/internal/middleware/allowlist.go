@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+// AdminPrefixes are the administrative endpoints AdminAllowlist restricts:
+// every path needing the admin role (auth.AdminPaths, so the two cannot
+// drift apart), plus reverse proxy routes, log sources, system control,
+// setup, certificates and the service registry (whose services get
+// routes), which are not admin-only
+var AdminPrefixes = append([]string{
+	"/api/v1/routes",
+	"/api/v1/logs/sources",
+	"/api/v1/services",
+	"/api/v1/system",
+	"/api/v1/setup",
+	"/api/v1/certs",
+}, auth.AdminPaths...)
+
+// privateNetworks are loopback, private (Docker networks, LANs) and
+// link-local addresses
+const privateNetworks = "127.0.0.0/8,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,169.254.0.0/16,::1/128,fc00::/7,fe80::/10"
+
+// IPAllowlist is the set of networks allowed to reach administrative
+// endpoints
+type IPAllowlist struct {
+	allowed []netip.Prefix
+	// proxies are trusted to report the client in X-Forwarded-For
+	proxies []netip.Prefix
+}
+
+// NewIPAllowlist returns the networks of cfg.AllowedCIDRs (default:
+// loopback and private networks; "off" disables), behind
+// cfg.TrustedProxies; nil when disabled
+func NewIPAllowlist(cfg config.Auth) (*IPAllowlist, error) {
+	allowed := cfg.AllowedCIDRs
+	if allowed == "off" {
+		return nil, nil
+	}
+	if allowed == "" {
+		allowed = privateNetworks
+	}
+
+	list := &IPAllowlist{}
+	var err error
+	if list.allowed, err = parsePrefixes(allowed); err != nil {
+		return nil, fmt.Errorf("auth.allowed_cidrs: %w", err)
+	}
+	if list.proxies, err = TrustedProxies(cfg); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// TrustedProxies returns cfg.TrustedProxies, the proxies trusted to report
+// the client in X-Forwarded-For (default: private networks; "none" trusts
+// no proxy)
+func TrustedProxies(cfg config.Auth) ([]netip.Prefix, error) {
+	proxies := cfg.TrustedProxies
+	switch proxies {
+	case "none":
+		return nil, nil
+	case "":
+		proxies = privateNetworks
+	}
+	prefixes, err := parsePrefixes(proxies)
+	if err != nil {
+		return nil, fmt.Errorf("auth.trusted_proxies: %w", err)
+	}
+	return prefixes, nil
+}
+
+// String lists the allowed networks
+func (l *IPAllowlist) String() string {
+	parts := make([]string, len(l.allowed))
+	for i, p := range l.allowed {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// parsePrefixes parses comma-separated CIDRs or single addresses
+func parsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr is the request's client: the connection's peer, or when that
+// is one of the trusted proxies, the nearest X-Forwarded-For hop that is
+// not one. Hops added before the first untrusted one could be forged.
+func clientAddr(r *http.Request, proxies []netip.Prefix) (netip.Addr, bool) {
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr := ap.Addr().Unmap()
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0 && contains(proxies, addr); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		next, err := netip.ParseAddr(hop)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = next.Unmap()
+	}
+	return addr, true
+}
+
+// ClientAddr resolves the client's address behind the trusted proxies
+// (clientAddr) and keeps it in the request context, where rate limits and
+// idempotency scopes read it. Requests whose X-Forwarded-For cannot be
+// parsed keep the connection's peer.
+func ClientAddr(proxies []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if addr, ok := clientAddr(r, proxies); ok {
+			r = r.WithContext(ratelimit.WithClientAddr(r.Context(), addr))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminAllowlist refuses administrative endpoints (AdminPrefixes) to clients
+// outside the allowed networks with 403, whatever their credentials. It is
+// a safety net for instances exposed publicly by accident.
+func AdminAllowlist(list *IPAllowlist, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !adminPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		addr, ok := clientAddr(r, list.proxies)
+		if !ok || !contains(list.allowed, addr) {
+			apierror.Write(w, http.StatusForbidden, connect.CodePermissionDenied,
+				"administrative endpoints are not reachable from this address",
+				map[string]any{"client": addr.String(), "setting": "ADMIN_ALLOWED_CIDRS"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func adminPath(path string) bool {
+	for _, p := range AdminPrefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/forge/api/internal/ratelimit"
+)
+
+func TestAdminPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/api/v1/routes", true},
+		{"/api/v1/routes/api", true},
+		{"/api/v1/services/orders/orders-1-8000", true},
+		{"/api/v1/notify/channels/alerts", true},
+		{"/api/v1/mqtt/bridges", true},
+		{"/api/v1/apps/web", true},
+		{"/api/v1/images", true},
+		{"/api/v1/routesx", false},
+		{"/api/v1/notify/send", false},
+		{"/api/v1/mqtt/publish", false},
+		{"/api/v1/health", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := adminPath(tt.path); got != tt.want {
+				t.Errorf("adminPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	proxies, err := parsePrefixes("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+		wantOK     bool
+	}{
+		{name: "direct", remoteAddr: "203.0.113.5:1234", want: "203.0.113.5", wantOK: true},
+		{
+			name:       "untrusted peer's header ignored",
+			remoteAddr: "203.0.113.5:1234",
+			forwarded:  []string{"10.0.0.9"},
+			want:       "203.0.113.5",
+			wantOK:     true,
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"198.51.100.7"},
+			want:       "198.51.100.7",
+			wantOK:     true,
+		},
+		{
+			name:       "forged hop before an untrusted one",
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"10.0.0.9, 198.51.100.7"},
+			want:       "198.51.100.7",
+			wantOK:     true,
+		},
+		{
+			name:       "chained trusted proxies",
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"198.51.100.7", "10.0.0.3"},
+			want:       "198.51.100.7",
+			wantOK:     true,
+		},
+		{
+			name:       "mapped IPv4",
+			remoteAddr: "[::ffff:10.0.0.2]:1234",
+			forwarded:  []string{"198.51.100.7"},
+			want:       "198.51.100.7",
+			wantOK:     true,
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"not-an-ip"},
+			wantOK:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/routes", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			addr, ok := clientAddr(r, proxies)
+			if ok != tt.wantOK {
+				t.Fatalf("clientAddr() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && addr.String() != tt.want {
+				t.Errorf("clientAddr() = %s, want %s", addr, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminAllowlist(t *testing.T) {
+	allowed, err := parsePrefixes("127.0.0.1,192.168.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := AdminAllowlist(&IPAllowlist{allowed: allowed}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		want       int
+	}{
+		{name: "admin path from allowed network", path: "/api/v1/apps", remoteAddr: "192.168.1.20:5000", want: http.StatusOK},
+		{name: "admin path from elsewhere", path: "/api/v1/apps", remoteAddr: "203.0.113.5:5000", want: http.StatusForbidden},
+		{name: "other path from elsewhere", path: "/api/v1/health", remoteAddr: "203.0.113.5:5000", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientAddrScopesRateLimits(t *testing.T) {
+	proxies, err := parsePrefixes("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.5:1234", want: "ip:203.0.113.5"},
+		{name: "forged header from a client", remoteAddr: "203.0.113.5:1234", forwarded: "198.51.100.99", want: "ip:203.0.113.5"},
+		{name: "behind a trusted proxy", remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.7", want: "ip:198.51.100.7"},
+		{name: "forged hop behind a trusted proxy", remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.99, 198.51.100.7", want: "ip:198.51.100.7"},
+		{name: "malformed hop keeps the peer", remoteAddr: "10.0.0.2:1234", forwarded: "nonsense", want: "ip:10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientAddr(proxies, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ratelimit.ClientID(r.Context(), nil, r.RemoteAddr)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("ClientID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
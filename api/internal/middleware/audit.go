@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/audit"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/tracing"
+)
+
+// Audit records mutating requests in the audit log. It runs outside Auth so
+// refused writes are recorded too, and learns the caller through
+// auth.Track, which also sees identities set by the Connect interceptor.
+func Audit(log *audit.Log, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !audit.Audited(r.Method, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+
+		// Keep the start of the body for the summary and hand the whole
+		// body on to the handler
+		var payload map[string]any
+		if r.Body != nil && r.Body != http.NoBody {
+			head, _ := io.ReadAll(io.LimitReader(r.Body, audit.MaxSummaryBody+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+			if len(head) > audit.MaxSummaryBody {
+				payload = map[string]any{"_bytes": r.ContentLength}
+			} else {
+				payload = audit.Summarize(head, r.Header.Get("Content-Type"))
+			}
+		}
+
+		ctx, identity := auth.Track(r.Context())
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		e := audit.Entry{
+			Time:       start.UTC(),
+			Actor:      "anonymous",
+			RemoteAddr: clientIP(r),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Payload:    payload,
+			Status:     rw.statusCode,
+			DurationMs: time.Since(start).Milliseconds(),
+			RequestID:  tracing.RequestID(r.Context()),
+		}
+		if strings.HasPrefix(r.URL.Path, "/forge.") {
+			e.Procedure = r.URL.Path
+		}
+		if id := identity(); id != nil {
+			e.Actor, e.KeyID, e.Subject, e.Role = id.Name, id.KeyID, id.Subject, id.Role
+		}
+		if err := log.Record(e); err != nil {
+			l := logger.FromContext(r.Context())
+			l.Error().Err(err).Str("path", r.URL.Path).Msg("Failed to write audit log")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+)
+
+// Auth checks the API key of REST requests and puts the caller's identity
+// in the request context. A verified TLS client certificate, or else a web
+// UI session cookie, identifies requests without a key. Connect RPCs are
+// checked by auth.Interceptor, which can answer with Connect error codes;
+// the certificate or session identity is passed on to it in the context.
+func Auth(store *auth.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.Public(r.URL.Path) || r.URL.Path == auth.LoginPath || strings.HasPrefix(r.URL.Path, auth.HooksPrefix) || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		peer, err := store.CertIdentity(r.TLS)
+		if err != nil {
+			apierror.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if peer == nil && auth.BearerToken(r.Header.Get("Authorization")) == "" {
+			peer, err = store.SessionIdentity(r)
+			switch {
+			case errors.Is(err, auth.ErrForbidden):
+				apierror.Error(w, err.Error(), http.StatusForbidden)
+				return
+			case errors.Is(err, auth.ErrUnauthenticated):
+				apierror.Error(w, "session expired or signed out", http.StatusUnauthorized)
+				return
+			case err != nil:
+				apierror.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if peer != nil {
+			r = r.WithContext(auth.WithPeerIdentity(r.Context(), peer))
+		}
+		if strings.HasPrefix(r.URL.Path, "/forge.") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := auth.BearerToken(r.Header.Get("Authorization"))
+		id := peer
+		if token != "" || peer == nil {
+			if token == "" && !store.Enforced() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, err = store.Authenticate(r.Context(), token)
+			if errors.Is(err, auth.ErrForbidden) {
+				apierror.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="forge"`)
+				apierror.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		if !auth.Allows(id.Role, auth.RequiredRole(r)) {
+			apierror.Error(w, auth.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), id)))
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/authguard"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+// AuthGuard refuses clients locked out after repeated authentication
+// failures with 429 and Retry-After, and counts failures: 401 answers to
+// requests that presented a key or token, and to logins. It runs outside
+// Auth so it sees Auth's refusals. Clients are the source IP (behind
+// trusted proxies, from X-Forwarded-For) and the key presented.
+func AuthGuard(guard *authguard.Guard, proxies []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.Public(r.URL.Path) || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var clients []authguard.Client
+		if addr, ok := clientAddr(r, proxies); ok {
+			clients = append(clients, authguard.Client{Kind: authguard.KindIP, ID: addr.String()})
+		}
+		token := auth.BearerToken(r.Header.Get("Authorization"))
+		if token != "" {
+			clients = append(clients, authguard.Client{Kind: authguard.KindKey, ID: authguard.KeyClient(token)})
+		}
+		if locked, until := guard.Locked(r.Context(), clients...); locked {
+			retry := time.Until(until)
+			w.Header().Set("Retry-After", ratelimit.RetryAfter(retry))
+			apierror.Write(w, http.StatusTooManyRequests, connect.CodeResourceExhausted,
+				"too many failed authentication attempts, try again later",
+				map[string]any{"retry_after_seconds": int(retry.Seconds()) + 1})
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		// Expired session cookies and missing keys are not guesses
+		if rw.statusCode == http.StatusUnauthorized {
+			switch {
+			case r.URL.Path == auth.LoginPath:
+				guard.Fail(r.Context(), "login", clients...)
+			case token != "":
+				guard.Fail(r.Context(), "key", clients...)
+			}
+		}
+	})
+}
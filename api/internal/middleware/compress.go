@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// minCompressSize is the smallest response worth compressing
+const minCompressSize = 1024
+
+var (
+	gzipWriters  = sync.Pool{New: func() any { w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression); return w }}
+	flateWriters = sync.Pool{New: func() any { w, _ := flate.NewWriter(nil, flate.DefaultCompression); return w }}
+)
+
+// Compress gzip- or deflate-encodes responses under the given path
+// prefixes for clients that accept it. Only text and JSON bodies of at
+// least 1KB are compressed; event streams, HEAD and range requests, and
+// responses the handler already encoded pass through unchanged.
+func Compress(prefixes []string, next http.Handler) http.Handler {
+	if len(prefixes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasPrefix(r.URL.Path, prefixes) || r.Method == http.MethodHead || r.Header.Get("Range") != "" || eventStream(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding picks gzip, else deflate, if the client accepts it
+func acceptedEncoding(header string) string {
+	var deflate bool
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(params, " ", "") == "q=0" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			deflate = true
+		}
+	}
+	if deflate {
+		return "deflate"
+	}
+	return ""
+}
+
+// compressible reports text and JSON content types
+func compressible(contentType string) bool {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	return strings.HasPrefix(ct, "text/") && ct != "text/event-stream" ||
+		strings.HasSuffix(ct, "json") || strings.HasSuffix(ct, "+xml") ||
+		ct == "application/javascript" || ct == "application/xml"
+}
+
+// compressWriter buffers the start of a response to decide whether to
+// compress it, then streams through the encoder
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+
+	wroteHeader bool // WriteHeader was called by the handler
+	decided     bool // headers are sent, compressing or not
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if code < 200 {
+		cw.ResponseWriter.WriteHeader(code) // informational, the final status follows
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+	// Bodiless responses go out as they are
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= minCompressSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start sends the headers and the buffered bytes, compressed if asked and
+// the content allows it
+func (cw *compressWriter) start(compress bool) error {
+	cw.decide(compress)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) decide(compress bool) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+	h := cw.ResponseWriter.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			gz := gzipWriters.Get().(*gzip.Writer)
+			gz.Reset(cw.ResponseWriter)
+			cw.enc = gz
+		} else {
+			fw := flateWriters.Get().(*flate.Writer)
+			fw.Reset(cw.ResponseWriter)
+			cw.enc = fw
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// Flush sends what is buffered; a response flushed before it reached the
+// minimum size is treated as a stream and not compressed
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.start(false)
+	}
+	if gz, ok := cw.enc.(*gzip.Writer); ok {
+		gz.Flush()
+	} else if fw, ok := cw.enc.(*flate.Writer); ok {
+		fw.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the connection
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the response: small bodies are sent as they are, the
+// encoder is flushed and returned to its pool
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			return nil // nothing written, net/http sends its default response
+		}
+		cw.start(false)
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	case *flate.Writer:
+		flateWriters.Put(enc)
+	}
+	cw.enc = nil
+	return err
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// RequestIDHeader carries the request correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// Correlation accepts or generates an X-Request-ID, attaches it and the
+// current trace ID to the request's logger and context, and returns both
+// (X-Request-ID and traceparent) in the response. It must run inside
+// Tracing so the server span is already in the context.
+func Correlation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx := tracing.WithRequestID(r.Context(), id)
+		fields := logger.Get().With().Str("request_id", id)
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			fields = fields.Str("trace_id", traceID)
+		}
+		l := fields.Logger()
+		ctx = l.WithContext(ctx)
+
+		w.Header().Set(RequestIDHeader, id)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
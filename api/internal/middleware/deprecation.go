@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/deprecation"
+	"github.com/forge/api/internal/metrics"
+)
+
+// Deprecation adds deprecation headers to, and records usage of, endpoints
+// registered as deprecated
+func Deprecation(registry *deprecation.Registry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, ok := registry.Lookup(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Deprecation", fmt.Sprintf("@%d", e.Since.Unix()))
+		if !e.Sunset.IsZero() {
+			h.Set("Sunset", e.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if e.Replacement != "" {
+			h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", e.Replacement))
+		}
+
+		client := clientID(r)
+		registry.Record(e.Path, client)
+		metrics.DeprecatedRequestsTotal.WithLabelValues(e.Path).Inc()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientID identifies the caller for usage reports: user agent and source IP
+func clientID(r *http.Request) string {
+	ip := clientIP(r)
+	ua := r.UserAgent()
+	if ua == "" {
+		return ip
+	}
+	return ua + " (" + ip + ")"
+}
+
+// clientIP returns the source IP, preferring the first X-Forwarded-For hop
+func clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		ip = strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	return ip
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/idempotency"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+// replayedHeaders are the response headers kept with a response
+var replayedHeaders = []string{"Content-Type", "Location", "Cache-Control"}
+
+// Idempotency replays the kept response of POSTs retried with the same
+// Idempotency-Key (see package idempotency). It runs inside Auth, so
+// only authenticated requests are kept and REST callers are known; RPCs,
+// authenticated later by the interceptor, are scoped by their token.
+func Idempotency(store *idempotency.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotency.Header)
+		if key == "" || !idempotency.Applies(r.Method, r.URL.Path) ||
+			strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !idempotency.ValidKey(key) {
+			apierror.Error(w, idempotency.ErrInvalidKey.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				apierror.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			apierror.Error(w, "Failed to read request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(r)
+		fingerprint := idempotency.Fingerprint(r, body)
+		kept, err := store.Begin(r.Context(), scope, key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			apierror.Write(w, http.StatusConflict, connect.CodeAborted, err.Error(), nil)
+			return
+		case errors.Is(err, idempotency.ErrMismatch):
+			apierror.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			// Without the store the request runs unprotected rather than failing
+			log := logger.FromContext(r.Context())
+			log.Warn().Err(err).Msg("Idempotency store unavailable")
+			next.ServeHTTP(w, r)
+			return
+		case kept != nil:
+			for name, values := range kept.Headers {
+				w.Header()[name] = values
+			}
+			w.Header().Set(idempotency.ReplayedHeader, "true")
+			w.WriteHeader(kept.Status)
+			w.Write(kept.Body)
+			return
+		}
+
+		rec := &recordingWriter{responseWriter: responseWriter{ResponseWriter: w, statusCode: http.StatusOK}}
+		next.ServeHTTP(rec, r)
+
+		// Server errors, requests refused before they ran (RPCs are
+		// authenticated and rate limited inside) and responses too large to
+		// keep run again when retried
+		if rec.statusCode >= 500 || rec.statusCode == http.StatusUnauthorized ||
+			rec.statusCode == http.StatusTooManyRequests || rec.overflow {
+			if err := store.Abandon(r.Context(), scope, key); err != nil {
+				log := logger.FromContext(r.Context())
+				log.Warn().Err(err).Msg("Failed to release Idempotency-Key")
+			}
+			return
+		}
+		resp := idempotency.Response{Status: rec.statusCode, Body: rec.body.Bytes(), Headers: map[string][]string{}}
+		for _, name := range replayedHeaders {
+			if values := w.Header().Values(name); len(values) > 0 {
+				resp.Headers[name] = values
+			}
+		}
+		if err := store.Finish(r.Context(), scope, key, fingerprint, resp); err != nil {
+			log := logger.FromContext(r.Context())
+			log.Warn().Err(err).Msg("Failed to keep idempotent response")
+		}
+	})
+}
+
+// idempotencyScope is the caller keys are scoped to: the authenticated
+// identity, else the token presented, else the client address
+func idempotencyScope(r *http.Request) string {
+	if id := auth.FromContext(r.Context()); id != nil {
+		return ratelimit.ClientID(r.Context(), id, r.RemoteAddr)
+	}
+	if id := auth.PeerIdentity(r.Context()); id != nil {
+		return ratelimit.ClientID(r.Context(), id, r.RemoteAddr)
+	}
+	// Scopes are hashed into the stored key, so the token is not kept
+	if token := auth.BearerToken(r.Header.Get("Authorization")); token != "" {
+		return "token:" + token
+	}
+	return ratelimit.ClientID(r.Context(), nil, r.RemoteAddr)
+}
+
+// recordingWriter passes a response through and keeps a copy of its body
+// up to idempotency.MaxResponseBytes
+type recordingWriter struct {
+	responseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	if !rw.overflow {
+		if rw.body.Len()+len(p) > idempotency.MaxResponseBytes {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(p)
+		}
+	}
+	return rw.responseWriter.Write(p)
+}
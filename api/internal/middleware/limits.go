@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+const (
+	// MaxBodySize bounds request bodies (1MB)
+	MaxBodySize = 1 << 20
+	// MaxIngestBodySize bounds log, metric, trace, error and profile
+	// uploads (8MB as sent, possibly compressed), inbound hooks and object
+	// uploads; larger objects go to presigned URLs
+	MaxIngestBodySize = 8 << 20
+	// WriteTimeout bounds how long a handler may take to write its
+	// response. Event streams are exempt; it is not set on the server for
+	// that reason.
+	WriteTimeout = 5 * time.Minute
+)
+
+// Limits caps request bodies, with a larger cap for ingest endpoints, and
+// sets a write deadline on every response but event streams. Bodies
+// declared larger than the cap are refused with 413 before being read.
+// Uploads to the files API are left to its own configured size limit.
+func Limits(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !fileUpload(r) {
+			limit := int64(MaxBodySize)
+			if ingestRequest(r) {
+				limit = MaxIngestBodySize
+			}
+			if r.ContentLength > limit {
+				apierror.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+
+		if !eventStream(r) {
+			// Ignored by writers without deadline support
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(WriteTimeout))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ingestRequest(r *http.Request) bool {
+	if r.URL.Path == "/forge.v1.StorageService/PutObject" || (r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/v1/storage/buckets/")) {
+		return true // object uploads
+	}
+	if strings.HasPrefix(r.URL.Path, "/forge.") {
+		return ratelimit.ProcedureClass(r.URL.Path) == ratelimit.ClassIngest
+	}
+	if strings.HasPrefix(r.URL.Path, auth.HooksPrefix) {
+		return true
+	}
+	return r.URL.Path == "/api/v1/profiles/ingest" || ratelimit.Class(r.Method, r.URL.Path) == ratelimit.ClassIngest
+}
+
+func fileUpload(r *http.Request) bool {
+	return r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/v1/files/")
+}
+
+// eventStream reports requests for server-sent events and message bus
+// subscriptions, which stay open
+func eventStream(r *http.Request) bool {
+	if r.URL.Path == "/forge.v1.MessagingService/Subscribe" || strings.HasPrefix(r.URL.Path, "/api/v1/messaging/subscribe/") {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") || strings.HasSuffix(r.URL.Path, "/stream")
+}
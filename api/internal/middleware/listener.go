@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/config"
+)
+
+// ListenerScope answers 404 for what a listener does not serve: public
+// listeners hide the administrative endpoints (AdminPrefixes), and admin
+// listeners serve only those, health checks, metrics and the docs
+func ListenerScope(serves string, next http.Handler) http.Handler {
+	if serves == config.ServeAll {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admin := adminPath(r.URL.Path)
+		if (serves == config.ServePublic && admin) || (serves == config.ServeAdmin && !admin && !auth.Public(r.URL.Path)) {
+			apierror.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
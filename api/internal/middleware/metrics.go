@@ -9,6 +9,7 @@ import (
 
 	"github.com/forge/api/internal/logger"
 	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/tracing"
 )
 
 // responseWriter wraps http.ResponseWriter to capture status code
@@ -22,6 +23,18 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap gives http.ResponseController access to the connection
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Flush lets streaming handlers (SSE) flush through the wrapper
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // Metrics wraps an http.Handler with Prometheus metrics and structured logging
 func Metrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -49,11 +62,12 @@ func Metrics(next http.Handler) http.Handler {
 			r.Method,
 			strconv.Itoa(rw.statusCode),
 			duration.Seconds(),
+			tracing.TraceID(r.Context()),
 		)
 
 		// Log request (skip health checks and metrics to reduce noise)
 		if !isHealthOrMetrics(r.URL.Path) {
-			logger.RequestLog(r.Method, r.URL.Path, rw.statusCode, duration, nil)
+			logger.RequestLog(r.Context(), r.Method, r.URL.Path, rw.statusCode, duration, nil)
 		}
 	})
 }
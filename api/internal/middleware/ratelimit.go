@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/auth"
+	"github.com/forge/api/internal/ratelimit"
+)
+
+// RateLimit answers REST requests over their client's or the global limit
+// with 429 and Retry-After. It must run inside Auth so the caller's key is
+// known, and inside ClientAddr so anonymous callers are told apart by
+// address; Connect RPCs are limited by ratelimit.Interceptor, which sees the
+// identities set by the auth interceptor.
+func RateLimit(limiter *ratelimit.Limiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.Public(r.URL.Path) || strings.HasPrefix(r.URL.Path, "/forge.") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := auth.FromContext(r.Context())
+		class := ratelimit.Class(r.Method, r.URL.Path)
+		ok, retry := limiter.Allow(r.Context(), class, ratelimit.ClientID(r.Context(), id, r.RemoteAddr), ratelimit.Overrides(id))
+		if !ok {
+			w.Header().Set("Retry-After", ratelimit.RetryAfter(retry))
+			apierror.Error(w, ratelimit.ErrRateLimited.Error(), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/apierror"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/tracing"
+)
+
+// Recover turns handler panics into a 500 JSON error envelope, logging the
+// stack and counting them in forge_panics_total. It must run inside
+// Correlation so the log and the response carry the request ID.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &headerTracker{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// net/http aborts the response quietly for ErrAbortHandler
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logPanic(r.Context(), p, normalizeEndpoint(r.URL.Path))
+			if tw.wroteHeader {
+				return // the response has started, the connection is cut short
+			}
+
+			var details map[string]any
+			if id := tracing.RequestID(r.Context()); id != "" {
+				details = map[string]any{"request_id": id}
+			}
+			apierror.Write(tw, http.StatusInternalServerError, connect.CodeInternal, "internal server error", details)
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// ConnectRecover is the Connect handler option for the same recovery, so
+// RPC clients receive a Connect internal error instead of a reset stream
+func ConnectRecover() connect.HandlerOption {
+	return connect.WithRecover(func(ctx context.Context, spec connect.Spec, _ http.Header, p any) error {
+		logPanic(ctx, p, spec.Procedure)
+		return connect.NewError(connect.CodeInternal, errors.New("internal server error"))
+	})
+}
+
+func logPanic(ctx context.Context, p any, endpoint string) {
+	metrics.PanicsTotal.WithLabelValues(endpoint).Inc()
+	log := logger.FromContext(ctx)
+	log.Error().
+		Str("panic", fmt.Sprint(p)).
+		Str("endpoint", endpoint).
+		Str("stack", string(debug.Stack())).
+		Msg("Recovered from handler panic")
+}
+
+// headerTracker records whether the response has started
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(code int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap gives http.ResponseController access to the connection
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Flush lets streaming handlers (SSE) flush through the wrapper
+func (t *headerTracker) Flush() {
+	t.wroteHeader = true
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
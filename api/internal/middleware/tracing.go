@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/forge/api/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request (REST and Connect),
+// continuing any incoming W3C traceparent. Health checks and /metrics
+// scrapes are not traced.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isHealthOrMetrics(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := normalizeEndpoint(r.URL.Path)
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(r.URL.Path),
+			semconv.UserAgentOriginal(r.UserAgent()),
+		}
+		if strings.HasPrefix(r.URL.Path, "/forge.v1.") {
+			attrs = append(attrs, semconv.RPCSystemKey.String("connect_rpc"))
+		}
+
+		ctx, span := tracing.StartKind(ctx, r.Method+" "+route, trace.SpanKindServer, attrs...)
+		defer span.End()
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.statusCode))
+		if rw.statusCode >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", rw.statusCode))
+		}
+	})
+}
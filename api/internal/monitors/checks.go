@@ -0,0 +1,128 @@
+package monitors
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxKeywordBody bounds how much of an HTTP response is searched for a keyword
+const maxKeywordBody = 1 << 20
+
+// checkClient is shared by HTTP checks; timeouts come from the check context
+var checkClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12},
+		DisableKeepAlives: true,
+	},
+}
+
+// runCheck performs one check of mon
+func runCheck(ctx context.Context, mon Monitor) Result {
+	start := time.Now()
+	var status int
+	var err error
+	switch mon.Type {
+	case TypeHTTP:
+		status, err = checkHTTP(ctx, mon)
+	case TypeTCP:
+		err = checkTCP(ctx, mon.Target)
+	case TypePing:
+		err = checkPing(ctx, mon.Target)
+	}
+
+	r := Result{
+		Time:      start,
+		Up:        err == nil,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Status:    status,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// checkHTTP requests the target and verifies status and keyword
+func checkHTTP(ctx context.Context, mon Monitor) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", mon.Target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "forge-monitor/1.0")
+
+	resp, err := checkClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if mon.ExpectStatus != 0 {
+		if resp.StatusCode != mon.ExpectStatus {
+			return resp.StatusCode, fmt.Errorf("status %d, expected %d", resp.StatusCode, mon.ExpectStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if mon.Keyword != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeywordBody))
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		if !strings.Contains(string(body), mon.Keyword) {
+			return resp.StatusCode, fmt.Errorf("keyword %q not found", mon.Keyword)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// checkTCP opens a connection to host:port
+func checkTCP(ctx context.Context, target string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkPing sends one ICMP echo using the system ping binary, which has
+// the privileges raw sockets need
+func checkPing(ctx context.Context, host string) error {
+	wait := 1
+	if deadline, ok := ctx.Deadline(); ok {
+		if secs := int(time.Until(deadline).Seconds()); secs > 1 {
+			wait = secs
+		}
+	}
+
+	out, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(wait), host).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// Report the last line of ping's output, e.g. "1 packets transmitted, 0 received"
+	msg := ""
+	sc := bufio.NewScanner(strings.NewReader(string(out)))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			msg = line
+		}
+	}
+	if msg == "" {
+		return err
+	}
+	return fmt.Errorf("ping failed: %s", msg)
+}
@@ -0,0 +1,549 @@
+// Package monitors runs scheduled HTTP, TCP and ping checks, exports their
+// status as Prometheus metrics and raises Alertmanager alerts on failures
+package monitors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/alerting"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+// Check types
+const (
+	TypeHTTP = "http"
+	TypeTCP  = "tcp"
+	TypePing = "ping"
+)
+
+// Monitor states
+const (
+	StatePending = "pending" // not checked yet
+	StateUp      = "up"
+	StateDown    = "down"
+)
+
+const (
+	// MinInterval is the shortest allowed check interval
+	MinInterval = 10 * time.Second
+	// DefaultInterval is used when a monitor does not set one
+	DefaultInterval = time.Minute
+	// DefaultTimeout is used when a monitor does not set one
+	DefaultTimeout = 10 * time.Second
+	maxTimeout     = time.Minute
+
+	schedulerTick = time.Second
+	maxResults    = 100
+	// alertSourceLabel marks alerts raised by Forge monitors
+	alertSourceLabel = "forge_monitor"
+	alertName        = "MonitorDown"
+)
+
+var (
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	hostPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.:-]{0,252}$`)
+)
+
+// Monitor is a scheduled availability check
+type Monitor struct {
+	Name   string `json:"name" yaml:"name"`
+	Type   string `json:"type" yaml:"type"`     // http, tcp or ping
+	Target string `json:"target" yaml:"target"` // URL, host:port or host
+
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"` // default 1m
+	Timeout  string `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // default 10s
+
+	// HTTP only: expected status (default any 2xx/3xx) and body keyword
+	ExpectStatus int    `json:"expect_status,omitempty" yaml:"expect_status,omitempty"`
+	Keyword      string `json:"keyword,omitempty" yaml:"keyword,omitempty"`
+
+	// Consecutive failures before the monitor is down and alerted (default 1)
+	FailureThreshold int               `json:"failure_threshold,omitempty" yaml:"failure_threshold,omitempty"`
+	Severity         string            `json:"severity,omitempty" yaml:"severity,omitempty"` // default "critical"
+	Labels           map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`     // added to alerts
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Time      time.Time `json:"time"`
+	Up        bool      `json:"up"`
+	LatencyMs float64   `json:"latency_ms"`
+	Status    int       `json:"status,omitempty"` // HTTP status code
+	Error     string    `json:"error,omitempty"`
+}
+
+// Status is a monitor with its current state
+type Status struct {
+	Monitor
+	State               string    `json:"state"`
+	LastCheck           time.Time `json:"last_check,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DownSince           time.Time `json:"down_since,omitempty"`
+	Uptime              float64   `json:"uptime"` // fraction of recent results that were up
+	Results             []Result  `json:"results,omitempty"`
+}
+
+type monitorState struct {
+	lastRun   time.Time
+	running   bool
+	results   []Result
+	failures  int
+	down      bool
+	downSince time.Time
+}
+
+type monitorsFile struct {
+	Monitors []Monitor `yaml:"monitors"`
+}
+
+// Manager stores monitors and runs their checks on a schedule
+type Manager struct {
+	mu         sync.RWMutex
+	monitors   map[string]Monitor
+	state      map[string]*monitorState
+	configPath string
+	client     *alerting.Client
+	onResult   []func(Monitor, Result)
+}
+
+// NewManager loads monitors from configPath. client receives alerts for
+// monitors that go down.
+func NewManager(configPath string, client *alerting.Client) (*Manager, error) {
+	m := &Manager{
+		monitors:   make(map[string]Monitor),
+		state:      make(map[string]*monitorState),
+		configPath: configPath,
+		client:     client,
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all monitors with their state, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.monitors))
+	for name := range m.monitors {
+		list = append(list, m.status(name, false))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a monitor with its state and recent results (newest first)
+func (m *Manager) Get(name string) (Status, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.monitors[name]; !ok {
+		return Status{}, false
+	}
+	return m.status(name, true), true
+}
+
+// status builds a Status; m.mu must be held
+func (m *Manager) status(name string, withResults bool) Status {
+	st := Status{Monitor: m.monitors[name], State: StatePending}
+	ms, ok := m.state[name]
+	if !ok || len(ms.results) == 0 {
+		return st
+	}
+
+	last := ms.results[len(ms.results)-1]
+	st.LastCheck = last.Time
+	st.LastError = last.Error
+	st.ConsecutiveFailures = ms.failures
+	st.State = StateUp
+	if ms.down {
+		st.State = StateDown
+		st.DownSince = ms.downSince
+	}
+
+	up := 0
+	for _, r := range ms.results {
+		if r.Up {
+			up++
+		}
+	}
+	st.Uptime = float64(up) / float64(len(ms.results))
+
+	if withResults {
+		st.Results = make([]Result, len(ms.results))
+		for i, r := range ms.results {
+			st.Results[len(ms.results)-1-i] = r
+		}
+	}
+	return st
+}
+
+// Add creates or replaces a monitor. Replacing a monitor resets its state.
+func (m *Manager) Add(mon Monitor) error {
+	if err := validateMonitor(&mon); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	old, replaced := m.monitors[mon.Name]
+	oldState := m.state[mon.Name]
+	m.monitors[mon.Name] = mon
+	delete(m.state, mon.Name)
+	m.mu.Unlock()
+
+	if replaced {
+		m.forget(old, oldState)
+	}
+	return m.save()
+}
+
+// Sync makes the monitors labelled with owner match desired, adding,
+// replacing or removing only those that differ. Each desired monitor must
+// carry the owner label. Other monitors are left alone.
+func (m *Manager) Sync(owner string, desired []Monitor) error {
+	want := make(map[string]Monitor, len(desired))
+	for _, mon := range desired {
+		if mon.Labels[owner] == "" {
+			return fmt.Errorf("monitor %s is missing the %s label", mon.Name, owner)
+		}
+		if err := validateMonitor(&mon); err != nil {
+			return fmt.Errorf("monitor %s: %w", mon.Name, err)
+		}
+		want[mon.Name] = mon
+	}
+
+	type change struct {
+		old      Monitor
+		oldState *monitorState
+	}
+	var forgotten []change
+
+	m.mu.Lock()
+	for name := range want {
+		if existing, ok := m.monitors[name]; ok && existing.Labels[owner] == "" {
+			m.mu.Unlock()
+			return fmt.Errorf("monitor %s already exists", name)
+		}
+	}
+	changed := false
+	for name, mon := range m.monitors {
+		if mon.Labels[owner] == "" {
+			continue
+		}
+		if w, ok := want[name]; !ok || !reflect.DeepEqual(w, mon) {
+			forgotten = append(forgotten, change{mon, m.state[name]})
+			delete(m.monitors, name)
+			delete(m.state, name)
+			changed = true
+		}
+	}
+	for name, mon := range want {
+		if _, ok := m.monitors[name]; ok {
+			continue
+		}
+		m.monitors[name] = mon
+		changed = true
+	}
+	m.mu.Unlock()
+
+	for _, c := range forgotten {
+		m.forget(c.old, c.oldState)
+	}
+	if !changed {
+		return nil
+	}
+	return m.save()
+}
+
+// OnResult registers fn to be called after every check. It must be called
+// before Run.
+func (m *Manager) OnResult(fn func(Monitor, Result)) {
+	m.onResult = append(m.onResult, fn)
+}
+
+// Remove deletes a monitor, resolving its alert and dropping its metrics
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	mon, ok := m.monitors[name]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("monitor not found: %s", name)
+	}
+	delete(m.monitors, name)
+	old := m.state[name]
+	delete(m.state, name)
+	m.mu.Unlock()
+
+	m.forget(mon, old)
+	return m.save()
+}
+
+// forget resolves any open alert and removes the metrics of a monitor
+func (m *Manager) forget(mon Monitor, ms *monitorState) {
+	metrics.MonitorUp.DeleteLabelValues(mon.Name, mon.Type)
+	metrics.MonitorResponseSeconds.DeleteLabelValues(mon.Name)
+
+	if ms == nil || !ms.down {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	alert := alertFor(mon, ms, "", time.Now())
+	if err := m.client.PostAlerts(ctx, []alerting.PostableAlert{alert}); err != nil {
+		logger.Error("Failed to resolve monitor alert: "+mon.Name, err)
+	}
+}
+
+// Run checks due monitors until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		m.runDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue starts a check for every monitor whose interval has elapsed.
+// Checks run concurrently; a monitor is never checked twice at once.
+func (m *Manager) runDue(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, mon := range m.monitors {
+		ms, ok := m.state[name]
+		if !ok {
+			ms = &monitorState{}
+			m.state[name] = ms
+		}
+		interval, _ := parseDuration(mon.Interval, DefaultInterval)
+		if ms.running || now.Sub(ms.lastRun) < interval {
+			continue
+		}
+		ms.lastRun = now
+		ms.running = true
+		go m.check(ctx, mon, ms)
+	}
+}
+
+// check runs one check, records the result and updates alerts
+func (m *Manager) check(ctx context.Context, mon Monitor, ms *monitorState) {
+	timeout, _ := parseDuration(mon.Timeout, DefaultTimeout)
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	result := runCheck(checkCtx, mon)
+	cancel()
+
+	m.mu.Lock()
+	ms.running = false
+	if m.state[mon.Name] != ms {
+		// Monitor was removed or replaced during the check
+		m.mu.Unlock()
+		return
+	}
+
+	up := 0.0
+	outcome := "failure"
+	if result.Up {
+		up = 1
+		outcome = "success"
+	}
+	metrics.MonitorUp.WithLabelValues(mon.Name, mon.Type).Set(up)
+	metrics.MonitorResponseSeconds.WithLabelValues(mon.Name).Set(result.LatencyMs / 1000)
+	metrics.MonitorChecksTotal.WithLabelValues(mon.Type, outcome).Inc()
+
+	ms.results = append(ms.results, result)
+	if len(ms.results) > maxResults {
+		ms.results = ms.results[len(ms.results)-maxResults:]
+	}
+
+	var alerts []alerting.PostableAlert
+	interval, _ := parseDuration(mon.Interval, DefaultInterval)
+	if result.Up {
+		if ms.down {
+			alerts = append(alerts, alertFor(mon, ms, "", result.Time))
+		}
+		ms.failures = 0
+		ms.down = false
+	} else {
+		ms.failures++
+		if ms.failures >= mon.FailureThreshold {
+			if !ms.down {
+				ms.down = true
+				ms.downSince = result.Time
+			}
+			// Alertmanager resolves the alert itself if we stop refreshing it
+			alerts = append(alerts, alertFor(mon, ms, result.Error, result.Time.Add(3*interval)))
+		}
+	}
+	m.mu.Unlock()
+
+	for _, fn := range m.onResult {
+		fn(mon, result)
+	}
+	if err := m.client.PostAlerts(ctx, alerts); err != nil {
+		logger.Error("Failed to send monitor alert: "+mon.Name, err)
+	}
+}
+
+// alertFor builds the Alertmanager alert of a down monitor
+func alertFor(mon Monitor, ms *monitorState, reason string, endsAt time.Time) alerting.PostableAlert {
+	labels := make(map[string]string, len(mon.Labels)+5)
+	for k, v := range mon.Labels {
+		labels[k] = v
+	}
+	labels["alertname"] = alertName
+	labels["monitor"] = mon.Name
+	labels["type"] = mon.Type
+	labels["severity"] = mon.Severity
+	labels[alertSourceLabel] = "true"
+
+	annotations := map[string]string{
+		"summary": fmt.Sprintf("%s is down", mon.Name),
+		"target":  mon.Target,
+	}
+	if reason != "" {
+		annotations["description"] = reason
+	}
+
+	return alerting.PostableAlert{
+		Labels:      labels,
+		Annotations: annotations,
+		StartsAt:    ms.downSince,
+		EndsAt:      endsAt,
+	}
+}
+
+// validateMonitor checks a monitor and fills in defaults
+func validateMonitor(mon *Monitor) error {
+	if !namePattern.MatchString(mon.Name) {
+		return fmt.Errorf("name must be 1-64 characters of letters, digits, '_' or '-'")
+	}
+
+	switch mon.Type {
+	case TypeHTTP:
+		u, err := url.Parse(mon.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target must be an http(s) URL")
+		}
+		if mon.ExpectStatus != 0 && (mon.ExpectStatus < 100 || mon.ExpectStatus > 599) {
+			return fmt.Errorf("invalid expect_status: %d", mon.ExpectStatus)
+		}
+	case TypeTCP:
+		host, port, err := net.SplitHostPort(mon.Target)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("target must be host:port")
+		}
+	case TypePing:
+		if !hostPattern.MatchString(mon.Target) {
+			return fmt.Errorf("target must be a hostname or IP address")
+		}
+	default:
+		return fmt.Errorf("invalid type: %q (must be http, tcp or ping)", mon.Type)
+	}
+	if mon.Type != TypeHTTP && (mon.ExpectStatus != 0 || mon.Keyword != "") {
+		return fmt.Errorf("expect_status and keyword are only valid for http monitors")
+	}
+
+	interval, err := parseDuration(mon.Interval, DefaultInterval)
+	if err != nil {
+		return fmt.Errorf("invalid interval: %w", err)
+	}
+	if interval < MinInterval {
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+	timeout, err := parseDuration(mon.Timeout, DefaultTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+	if timeout > maxTimeout || timeout > interval {
+		return fmt.Errorf("timeout must not exceed the interval or %s", maxTimeout)
+	}
+
+	if mon.FailureThreshold < 0 {
+		return fmt.Errorf("failure_threshold must not be negative")
+	}
+	if mon.FailureThreshold == 0 {
+		mon.FailureThreshold = 1
+	}
+	if mon.Severity == "" {
+		mon.Severity = "critical"
+	}
+	return resources.ValidateLabels(mon.Labels)
+}
+
+// parseDuration parses an optional positive duration
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// load reads monitors from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f monitorsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mon := range f.Monitors {
+		m.monitors[mon.Name] = mon
+	}
+	return nil
+}
+
+// save writes monitors to the config file
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := monitorsFile{Monitors: make([]Monitor, 0, len(m.monitors))}
+	for _, mon := range m.monitors {
+		f.Monitors = append(f.Monitors, mon)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Monitors, func(i, j int) bool { return f.Monitors[i].Name < f.Monitors[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
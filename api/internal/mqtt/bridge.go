@@ -0,0 +1,382 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+// Results of forge_mqtt_messages_total
+const (
+	resultForwarded = "forwarded"
+	resultFailed    = "failed"
+	resultDropped   = "dropped"
+)
+
+var messageResults = []string{resultForwarded, resultFailed, resultDropped}
+
+const (
+	maxTargets = 10
+	// queueSize bounds messages waiting for their targets; beyond it they
+	// are dropped
+	queueSize        = 1000
+	subscribeTimeout = 10 * time.Second
+)
+
+var (
+	// ErrInvalidBridge is returned for bridges that cannot be stored
+	ErrInvalidBridge = errors.New("invalid bridge")
+	// ErrBridgeNotFound is returned for unknown bridge names
+	ErrBridgeNotFound = errors.New("bridge not found")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// Bridge subscribes to a topic filter and forwards the messages it
+// receives to its targets
+type Bridge struct {
+	Name string `json:"name" yaml:"name"`
+	// Topic is a topic filter: "+" matches one level and "#" the rest,
+	// such as home/+/temperature or zigbee2mqtt/#
+	Topic    string            `json:"topic" yaml:"topic"`
+	QoS      byte              `json:"qos" yaml:"qos"` // 0 or 1
+	Targets  []Target          `json:"targets" yaml:"targets"`
+	Disabled bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Stats counts the messages a bridge handled since the API started
+type Stats struct {
+	Forwarded   int64      `json:"forwarded"`
+	Failed      int64      `json:"failed"`
+	Dropped     int64      `json:"dropped"` // the queue was full or the payload too large
+	LastMessage *time.Time `json:"last_message,omitempty"`
+	LastTopic   string     `json:"last_topic,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// Status is a bridge with its activity
+type Status struct {
+	Bridge
+	// Subscribed is set while the broker has accepted the bridge's topic
+	Subscribed bool  `json:"subscribed"`
+	Stats      Stats `json:"stats"`
+}
+
+type bridgesFile struct {
+	Bridges []Bridge `yaml:"bridges"`
+}
+
+// Manager stores bridges and forwards the messages they receive
+type Manager struct {
+	mu         sync.RWMutex
+	bridges    map[string]Bridge
+	stats      map[string]*Stats
+	configPath string
+	client     *Client
+	targets    *Targets
+	queue      chan Message
+}
+
+// NewManager loads bridges from configPath and hands their topics to
+// client, which subscribes to them once connected
+func NewManager(configPath string, client *Client, targets *Targets) (*Manager, error) {
+	m := &Manager{
+		bridges:    make(map[string]Bridge),
+		stats:      make(map[string]*Stats),
+		configPath: configPath,
+		client:     client,
+		targets:    targets,
+		queue:      make(chan Message, queueSize),
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	client.OnMessage(m.enqueue)
+	m.subscribe()
+	return m, nil
+}
+
+// List returns all bridges with their activity, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.bridges))
+	for name := range m.bridges {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	list := make([]Status, 0, len(names))
+	for _, name := range names {
+		if st, err := m.Get(name); err == nil {
+			list = append(list, st)
+		}
+	}
+	return list
+}
+
+// Get returns a bridge with its activity
+func (m *Manager) Get(name string) (Status, error) {
+	m.mu.RLock()
+	bridge, ok := m.bridges[name]
+	var stats Stats
+	if s := m.stats[name]; s != nil {
+		stats = *s
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return Status{}, ErrBridgeNotFound
+	}
+	return Status{
+		Bridge:     bridge,
+		Subscribed: !bridge.Disabled && m.client.Subscribed(bridge.Topic),
+		Stats:      stats,
+	}, nil
+}
+
+// Save creates or replaces a bridge and subscribes to its topic. It
+// reports whether the bridge was created.
+func (m *Manager) Save(bridge Bridge) (bool, error) {
+	if err := validateBridge(&bridge); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	_, exists := m.bridges[bridge.Name]
+	m.bridges[bridge.Name] = bridge
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return false, err
+	}
+	m.subscribe()
+	return !exists, nil
+}
+
+// SetDisabled stops or resumes a bridge, unsubscribing from its topic
+// while it is stopped
+func (m *Manager) SetDisabled(name string, disabled bool) (Status, error) {
+	m.mu.Lock()
+	bridge, ok := m.bridges[name]
+	if !ok {
+		m.mu.Unlock()
+		return Status{}, ErrBridgeNotFound
+	}
+	bridge.Disabled = disabled
+	m.bridges[name] = bridge
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return Status{}, err
+	}
+	m.subscribe()
+	return m.Get(name)
+}
+
+// Remove deletes a bridge with its metrics
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.bridges[name]; !ok {
+		m.mu.Unlock()
+		return ErrBridgeNotFound
+	}
+	delete(m.bridges, name)
+	delete(m.stats, name)
+	m.mu.Unlock()
+
+	for _, result := range messageResults {
+		metrics.MQTTMessagesTotal.DeleteLabelValues(name, result)
+	}
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.subscribe()
+	return nil
+}
+
+// subscribe hands the topics of the enabled bridges to the client. Topics
+// the broker refuses are logged; their bridges report not subscribed.
+func (m *Manager) subscribe() {
+	m.mu.RLock()
+	filters := make(map[string]byte)
+	for _, bridge := range m.bridges {
+		if !bridge.Disabled && bridge.QoS >= filters[bridge.Topic] {
+			filters[bridge.Topic] = bridge.QoS
+		}
+	}
+	m.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+	if err := m.client.SetSubscriptions(ctx, filters); err != nil {
+		logger.Warn(fmt.Sprintf("MQTT: %v", err))
+	}
+}
+
+// enqueue queues a message for the bridges matching its topic, dropping
+// it when they are too far behind
+func (m *Manager) enqueue(msg Message) {
+	select {
+	case m.queue <- msg:
+	default:
+		for _, bridge := range m.matching(msg.Topic) {
+			m.record(bridge.Name, msg, resultDropped, "queue full")
+		}
+	}
+}
+
+// Run forwards queued messages until ctx is cancelled. Messages are
+// handled in order, so a gauge ends at the last value received.
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-m.queue:
+			m.handle(ctx, msg)
+		}
+	}
+}
+
+// matching returns the enabled bridges whose topic matches topic
+func (m *Manager) matching(topic string) []Bridge {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []Bridge
+	for _, bridge := range m.bridges {
+		if !bridge.Disabled && Match(bridge.Topic, topic) {
+			list = append(list, bridge)
+		}
+	}
+	return list
+}
+
+// handle sends a message to the targets of every matching bridge
+func (m *Manager) handle(ctx context.Context, msg Message) {
+	for _, bridge := range m.matching(msg.Topic) {
+		if msg.Skipped {
+			m.record(bridge.Name, msg, resultDropped, fmt.Sprintf("payload larger than %d bytes", maxPacketSize))
+			continue
+		}
+		bm := newMessage(bridge.Name, msg)
+		var failed []string
+		for i, target := range bridge.Targets {
+			if err := m.targets.run(ctx, target, bm); err != nil {
+				failed = append(failed, fmt.Sprintf("target %d (%s): %v", i, target.Type, err))
+			}
+		}
+		if len(failed) > 0 {
+			m.record(bridge.Name, msg, resultFailed, strings.Join(failed, "; "))
+			continue
+		}
+		m.record(bridge.Name, msg, resultForwarded, "")
+	}
+}
+
+// record counts a message handled by a bridge
+func (m *Manager) record(name string, msg Message, result, errMsg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.bridges[name]; !ok {
+		return
+	}
+	s := m.stats[name]
+	if s == nil {
+		s = &Stats{}
+		m.stats[name] = s
+	}
+	switch result {
+	case resultForwarded:
+		s.Forwarded++
+	case resultFailed:
+		s.Failed++
+	case resultDropped:
+		s.Dropped++
+	}
+	received := msg.Received
+	s.LastMessage = &received
+	s.LastTopic = msg.Topic
+	if errMsg != "" {
+		s.LastError = errMsg
+	}
+	metrics.MQTTMessagesTotal.WithLabelValues(name, result).Inc()
+}
+
+// validateBridge checks a bridge and fills in defaults
+func validateBridge(bridge *Bridge) error {
+	if !namePattern.MatchString(bridge.Name) {
+		return fmt.Errorf("%w: name must be 1-64 characters of letters, digits, '_' or '-'", ErrInvalidBridge)
+	}
+	if err := ValidateTopic(bridge.Topic, true); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBridge, err)
+	}
+	if bridge.QoS > 1 {
+		return fmt.Errorf("%w: %v", ErrInvalidBridge, ErrInvalidQoS)
+	}
+	if len(bridge.Targets) == 0 || len(bridge.Targets) > maxTargets {
+		return fmt.Errorf("%w: 1-%d targets are required", ErrInvalidBridge, maxTargets)
+	}
+	for i := range bridge.Targets {
+		if err := validateTarget(&bridge.Targets[i]); err != nil {
+			return fmt.Errorf("%w: target %d: %v", ErrInvalidBridge, i, err)
+		}
+	}
+	if err := resources.ValidateLabels(bridge.Labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBridge, err)
+	}
+	return nil
+}
+
+// load reads bridges from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f bridgesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, bridge := range f.Bridges {
+		m.bridges[bridge.Name] = bridge
+	}
+	return nil
+}
+
+// save writes bridges to the config file
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := bridgesFile{Bridges: make([]Bridge, 0, len(m.bridges))}
+	for _, bridge := range m.bridges {
+		f.Bridges = append(f.Bridges, bridge)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Bridges, func(i, j int) bool { return f.Bridges[i].Name < f.Bridges[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
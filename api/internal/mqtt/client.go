@@ -0,0 +1,374 @@
+// Package mqtt bridges an MQTT broker into Forge's observability stack, so
+// sensors and home-automation devices need no integration of their own
+//
+// Bridges subscribe to topic filters and forward each message they receive
+// to their targets: a log line in Loki, a counter or a gauge read from the
+// payload, or an mqtt.message event for outbound webhooks. Messages are
+// published to the broker through the API as well.
+//
+// The API holds one connection, made by Ping so a deps.Supervisor keeps it
+// up. Sessions are clean: the bridges' subscriptions are made again on
+// every connect, and messages sent while the connection was down are lost.
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/logger"
+)
+
+const maxTopicLength = 1024
+
+var (
+	// ErrInvalidTopic is returned for malformed topics and topic filters
+	ErrInvalidTopic = errors.New("invalid topic")
+	// ErrInvalidQoS is returned for QoS levels other than 0 and 1
+	ErrInvalidQoS = errors.New("qos must be 0 or 1")
+	// ErrTooLarge is returned for payloads over 1MB
+	ErrTooLarge = errors.New("message too large")
+	// ErrNotConnected is returned while the broker is not reached
+	ErrNotConnected = errors.New("not connected to the MQTT broker")
+	// ErrConnectionLost is returned when the connection fails mid-request
+	ErrConnectionLost = errors.New("connection to the MQTT broker lost")
+	// ErrRefused is returned when the broker refuses to connect or to
+	// subscribe
+	ErrRefused = errors.New("refused by the MQTT broker")
+)
+
+// Message is a message received from the broker
+type Message struct {
+	Topic    string    `json:"topic"`
+	Payload  []byte    `json:"payload"`
+	QoS      byte      `json:"qos"`
+	Retained bool      `json:"retained,omitempty"`
+	Received time.Time `json:"received"`
+	// Skipped is set for messages too large to read, whose payload is
+	// empty
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Info describes the connection to the broker
+type Info struct {
+	URL           string   `json:"url"` // without credentials
+	ClientID      string   `json:"client_id"`
+	Connected     bool     `json:"connected"`
+	Subscriptions []string `json:"subscriptions"`
+}
+
+// Client talks to one MQTT broker
+type Client struct {
+	url      *url.URL
+	user     string
+	password string
+	clientID string
+
+	mu        sync.Mutex
+	conn      *conn
+	filters   map[string]byte // subscriptions wanted, with their QoS
+	onMessage func(Message)
+
+	// subMu serializes subscribing, so a connect and a change of filters
+	// do not interleave
+	subMu sync.Mutex
+}
+
+// NewClient creates a client for the broker in cfg. It does not connect; a
+// deps.Supervisor calls Ping, which does.
+func NewClient(cfg config.MQTT) (*Client, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt url: %w", err)
+	}
+	user, password := cfg.User, cfg.Password
+	if u.User != nil && user == "" {
+		user = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	return &Client{
+		url:      u,
+		user:     user,
+		password: password,
+		clientID: cfg.ClientID,
+		filters:  make(map[string]byte),
+	}, nil
+}
+
+// OnMessage sets the function messages are handed to. It runs on the
+// connection's goroutine and must not block.
+func (c *Client) OnMessage(fn func(Message)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onMessage = fn
+}
+
+// Info returns the broker's address and the subscriptions made
+func (c *Client) Info() Info {
+	u := *c.url
+	u.User = nil
+	info := Info{URL: u.String(), ClientID: c.clientID, Subscriptions: []string{}}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if cn, err := c.current(); err == nil {
+		info.Connected = true
+		for f := range cn.subscribed {
+			info.Subscriptions = append(info.Subscriptions, f)
+		}
+		sort.Strings(info.Subscriptions)
+	}
+	return info
+}
+
+// Subscribed reports whether filter is subscribed on the current
+// connection
+func (c *Client) Subscribed(filter string) bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	cn, err := c.current()
+	if err != nil {
+		return false
+	}
+	_, ok := cn.subscribed[filter]
+	return ok
+}
+
+// Ping checks the connection, connecting first if there is none
+func (c *Client) Ping(ctx context.Context) error {
+	cn, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	return cn.ping(ctx)
+}
+
+// connect returns the open connection or makes a new one and subscribes
+// to the filters on it
+func (c *Client) connect(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.conn != nil && !c.conn.closed() {
+		cn := c.conn
+		c.mu.Unlock()
+		return cn, nil
+	}
+	cn, r, err := dial(ctx, c.url, c.clientID, c.user, c.password)
+	if err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.conn = cn
+	c.mu.Unlock()
+
+	go cn.readLoop(r, c.deliver)
+	// A refused filter leaves the connection usable; a lost connection
+	// fails the ping that follows
+	if err := c.sync(ctx); err != nil {
+		logger.Warn(fmt.Sprintf("MQTT: %v", err))
+	}
+	return cn, nil
+}
+
+// current returns the open connection, without connecting
+func (c *Client) current() (*conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil || c.conn.closed() {
+		return nil, ErrNotConnected
+	}
+	return c.conn, nil
+}
+
+// deliver hands a received message to the OnMessage function
+func (c *Client) deliver(p publish, skipped bool) {
+	c.mu.Lock()
+	fn := c.onMessage
+	c.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	fn(Message{
+		Topic:    p.topic,
+		Payload:  p.payload,
+		QoS:      p.qos,
+		Retained: p.retained,
+		Received: time.Now().UTC(),
+		Skipped:  skipped,
+	})
+}
+
+// Publish sends a message at QoS 0 or 1. At QoS 1 it waits for the broker
+// to take it; retained messages are kept by the broker for later
+// subscribers.
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	if err := ValidateTopic(topic, false); err != nil {
+		return err
+	}
+	if qos > 1 {
+		return ErrInvalidQoS
+	}
+	if len(payload) > maxPacketSize {
+		return fmt.Errorf("%w: payloads are at most %d bytes", ErrTooLarge, maxPacketSize)
+	}
+	cn, err := c.current()
+	if err != nil {
+		return err
+	}
+
+	flags := qos << 1
+	if retain {
+		flags |= 0x01
+	}
+	if qos == 0 {
+		if err := cn.write(encodePacket(packetPublish, flags, append(appendString(nil, topic), payload...))); err != nil {
+			cn.close(err)
+			return cn.closedErr()
+		}
+		return nil
+	}
+	_, err = cn.request(ctx, packetPublish, flags, func(id uint16) []byte {
+		b := appendString(nil, topic)
+		b = append(b, byte(id>>8), byte(id))
+		return append(b, payload...)
+	})
+	return err
+}
+
+// SetSubscriptions replaces the filters subscribed to, each with its QoS.
+// While connected the change is made right away; otherwise on connect. A
+// filter the broker refuses is reported and tried again on the next
+// change or connect.
+func (c *Client) SetSubscriptions(ctx context.Context, filters map[string]byte) error {
+	want := make(map[string]byte, len(filters))
+	for f, qos := range filters {
+		want[f] = qos
+	}
+	c.mu.Lock()
+	c.filters = want
+	c.mu.Unlock()
+	return c.sync(ctx)
+}
+
+// sync makes the subscriptions of the current connection match the
+// filters wanted
+func (c *Client) sync(ctx context.Context) error {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	cn, err := c.current()
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	var add []string
+	for f, qos := range c.filters {
+		if have, ok := cn.subscribed[f]; !ok || have != qos {
+			add = append(add, f)
+		}
+	}
+	var remove []string
+	for f := range cn.subscribed {
+		if _, ok := c.filters[f]; !ok {
+			remove = append(remove, f)
+		}
+	}
+	qos := make([]byte, len(add))
+	for i, f := range add {
+		qos[i] = c.filters[f]
+	}
+	c.mu.Unlock()
+
+	var refused []string
+	if len(add) > 0 {
+		p, err := cn.request(ctx, packetSubscribe, 0x02, func(id uint16) []byte {
+			b := []byte{byte(id >> 8), byte(id)}
+			for i, f := range add {
+				b = append(appendString(b, f), qos[i])
+			}
+			return b
+		})
+		if err != nil {
+			return err
+		}
+		codes := p.body[2:]
+		for i, f := range add {
+			if i >= len(codes) || codes[i] == subackFailure {
+				refused = append(refused, f)
+				delete(cn.subscribed, f)
+				continue
+			}
+			cn.subscribed[f] = qos[i]
+		}
+	}
+	if len(remove) > 0 {
+		_, err := cn.request(ctx, packetUnsubscribe, 0x02, func(id uint16) []byte {
+			b := []byte{byte(id >> 8), byte(id)}
+			for _, f := range remove {
+				b = appendString(b, f)
+			}
+			return b
+		})
+		if err != nil {
+			return err
+		}
+		for _, f := range remove {
+			delete(cn.subscribed, f)
+		}
+	}
+	if len(refused) > 0 {
+		return fmt.Errorf("%w: subscribing to %s", ErrRefused, strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+// ValidateTopic checks a topic, or with wildcards a topic filter: "+"
+// matches one level and "#" the rest, as the last level only
+func ValidateTopic(topic string, wildcards bool) error {
+	if topic == "" {
+		return fmt.Errorf("%w: topic is required", ErrInvalidTopic)
+	}
+	if len(topic) > maxTopicLength {
+		return fmt.Errorf("%w: topic is longer than %d bytes", ErrInvalidTopic, maxTopicLength)
+	}
+	if !utf8.ValidString(topic) || strings.ContainsRune(topic, 0) {
+		return fmt.Errorf("%w: topic must be UTF-8 without NUL characters", ErrInvalidTopic)
+	}
+	levels := strings.Split(topic, "/")
+	for i, level := range levels {
+		switch {
+		case level == "+" || (level == "#" && i == len(levels)-1):
+			if !wildcards {
+				return fmt.Errorf("%w: wildcards are only allowed in topic filters", ErrInvalidTopic)
+			}
+		case strings.ContainsAny(level, "+#"):
+			return fmt.Errorf("%w: wildcards must be whole levels, # only the last", ErrInvalidTopic)
+		}
+	}
+	return nil
+}
+
+// Match reports whether topic matches filter. Wildcards at the first level
+// do not match topics starting with "$", which belong to the broker.
+func Match(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) || (level != "+" && level != t[i]) {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
@@ -0,0 +1,448 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Packet types of MQTT 3.1.1
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+const (
+	// handshakeTimeout bounds connecting and waiting for CONNACK
+	handshakeTimeout = 5 * time.Second
+	// writeTimeout bounds writes to a broker that stopped reading
+	writeTimeout = 10 * time.Second
+	// keepAlive is announced to the broker; the supervisor pings more often
+	keepAlive = 60
+	// maxPacketSize bounds packets read whole; larger messages are skipped
+	maxPacketSize = 1 << 20
+	// subackFailure is the SUBACK return code of a refused subscription
+	subackFailure = 0x80
+)
+
+// connackErrors are the reasons of CONNACK return codes 1-5
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// packet is a control packet: its type, the flags of its fixed header and
+// the rest
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+	// skipped is set for PUBLISH packets over maxPacketSize, whose body
+	// holds only the topic and packet identifier
+	skipped bool
+}
+
+// publish is a PUBLISH packet taken apart
+type publish struct {
+	topic    string
+	id       uint16
+	qos      byte
+	retained bool
+	payload  []byte
+}
+
+// conn is one connection to the broker
+type conn struct {
+	nc net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu      sync.Mutex
+	nextID  uint16
+	pending map[uint16]chan packet // waiting for PUBACK, SUBACK or UNSUBACK
+	pings   []chan struct{}        // waiting for PINGRESP, oldest first
+	err     error                  // why the connection closed
+	done    chan struct{}
+
+	// subscribed holds the filters subscribed on this connection with
+	// their QoS; guarded by Client.subMu
+	subscribed map[string]byte
+}
+
+// dial connects to the broker at u and logs in. The reader is handed to
+// readLoop.
+func dial(ctx context.Context, u *url.URL, clientID, user, password string) (*conn, *bufio.Reader, error) {
+	host := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if u.Scheme == "mqtts" {
+			port = "8883"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deadline := time.Now().Add(handshakeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	nc.SetDeadline(deadline)
+
+	if u.Scheme == "mqtts" {
+		tc := tls.Client(nc, &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, nil, fmt.Errorf("tls handshake: %w", err)
+		}
+		nc = tc
+	}
+
+	c := &conn{
+		nc:         nc,
+		w:          bufio.NewWriter(nc),
+		pending:    make(map[uint16]chan packet),
+		done:       make(chan struct{}),
+		subscribed: make(map[string]byte),
+	}
+	// Clean session: the broker keeps nothing for us between connections,
+	// subscriptions are made again on every connect
+	flags := byte(0x02)
+	body := appendString([]byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0, 0, keepAlive}, clientID)
+	if user != "" {
+		flags |= 0x80
+		body = appendString(body, user)
+		if password != "" {
+			flags |= 0x40
+			body = appendString(body, password)
+		}
+	}
+	body[7] = flags
+	if err := c.write(encodePacket(packetConnect, 0, body)); err != nil {
+		nc.Close()
+		return nil, nil, err
+	}
+
+	r := bufio.NewReader(nc)
+	p, err := readPacket(r)
+	if err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("connecting: %w", err)
+	}
+	if p.typ != packetConnack || len(p.body) != 2 {
+		nc.Close()
+		return nil, nil, fmt.Errorf("expected CONNACK from broker, got packet type %d", p.typ)
+	}
+	if code := p.body[1]; code != 0 {
+		nc.Close()
+		reason := connackErrors[code]
+		if reason == "" {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return nil, nil, fmt.Errorf("%w: %s", ErrRefused, reason)
+	}
+	nc.SetReadDeadline(time.Time{})
+	return c, r, nil
+}
+
+// readLoop reads from the broker until the connection fails, handing
+// messages to deliver
+func (c *conn) readLoop(r *bufio.Reader, deliver func(publish, bool)) {
+	var err error
+	defer func() { c.close(err) }()
+
+	for {
+		var p packet
+		if p, err = readPacket(r); err != nil {
+			return
+		}
+		switch p.typ {
+		case packetPublish:
+			var pub publish
+			if pub, err = parsePublish(p); err != nil {
+				return
+			}
+			deliver(pub, p.skipped)
+			switch pub.qos {
+			case 1:
+				err = c.write(encodePacket(packetPuback, 0, binary.BigEndian.AppendUint16(nil, pub.id)))
+			case 2:
+				err = c.write(encodePacket(packetPubrec, 0, binary.BigEndian.AppendUint16(nil, pub.id)))
+			}
+			if err != nil {
+				return
+			}
+		case packetPubrel:
+			if len(p.body) < 2 {
+				err = errors.New("malformed PUBREL")
+				return
+			}
+			if err = c.write(encodePacket(packetPubcomp, 0, p.body[:2])); err != nil {
+				return
+			}
+		case packetPuback, packetSuback, packetUnsuback:
+			if len(p.body) < 2 {
+				err = fmt.Errorf("malformed packet of type %d", p.typ)
+				return
+			}
+			id := binary.BigEndian.Uint16(p.body)
+			c.mu.Lock()
+			ch := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- p
+			}
+		case packetPingresp:
+			c.mu.Lock()
+			if len(c.pings) > 0 {
+				close(c.pings[0])
+				c.pings = c.pings[1:]
+			}
+			c.mu.Unlock()
+		default:
+			err = fmt.Errorf("unexpected packet of type %d from broker", p.typ)
+			return
+		}
+	}
+}
+
+// request sends a packet carrying a new packet identifier and waits for
+// the broker's acknowledgement
+func (c *conn) request(ctx context.Context, typ, flags byte, build func(id uint16) []byte) (packet, error) {
+	ack := make(chan packet, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return packet{}, c.err
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	c.pending[id] = ack
+	c.mu.Unlock()
+
+	if err := c.write(encodePacket(typ, flags, build(id))); err != nil {
+		c.close(err)
+		return packet{}, c.closedErr()
+	}
+	select {
+	case p := <-ack:
+		return p, nil
+	case <-c.done:
+		return packet{}, c.closedErr()
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return packet{}, ctx.Err()
+	}
+}
+
+// ping sends PINGREQ and waits for PINGRESP
+func (c *conn) ping(ctx context.Context) error {
+	resp := make(chan struct{})
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pings = append(c.pings, resp)
+	c.mu.Unlock()
+
+	if err := c.write(encodePacket(packetPingreq, 0, nil)); err != nil {
+		c.close(err)
+		return c.closedErr()
+	}
+	select {
+	case <-resp:
+		return nil
+	case <-c.done:
+		return c.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// write sends data and flushes it
+func (c *conn) write(data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.w.Write(data); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// close shuts the connection, recording why
+func (c *conn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	c.err = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	c.nc.Close()
+	close(c.done)
+}
+
+// closed reports whether the connection is closed
+func (c *conn) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *conn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// encodePacket builds a packet: fixed header, remaining length, body
+func encodePacket(typ, flags byte, body []byte) []byte {
+	b := make([]byte, 0, len(body)+5)
+	b = append(b, typ<<4|flags)
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+// appendString appends s with its two-byte length
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readPacket reads one packet. PUBLISH packets over maxPacketSize are
+// skipped past, keeping only what is needed to acknowledge them.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	p := packet{typ: header >> 4, flags: header & 0x0f}
+
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		n += int(digit&0x7f) * mult
+		mult *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	if n > maxPacketSize {
+		if p.typ != packetPublish {
+			return packet{}, fmt.Errorf("packet of %d bytes is too large", n)
+		}
+		return readSkipped(r, p, n)
+	}
+	p.body = make([]byte, n)
+	if _, err := io.ReadFull(r, p.body); err != nil {
+		return packet{}, err
+	}
+	return p, nil
+}
+
+// readSkipped reads the topic and packet identifier of an oversized
+// PUBLISH of n bytes and discards its payload
+func readSkipped(r *bufio.Reader, p packet, n int) (packet, error) {
+	head := 2
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return packet{}, err
+	}
+	head += int(binary.BigEndian.Uint16(size[:]))
+	if (p.flags>>1)&0x03 > 0 {
+		head += 2
+	}
+	if head > n {
+		return packet{}, errors.New("malformed PUBLISH")
+	}
+	p.body = make([]byte, head)
+	copy(p.body, size[:])
+	if _, err := io.ReadFull(r, p.body[2:]); err != nil {
+		return packet{}, err
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(n-head)); err != nil {
+		return packet{}, err
+	}
+	p.skipped = true
+	return p, nil
+}
+
+// parsePublish takes a PUBLISH packet apart
+func parsePublish(p packet) (publish, error) {
+	pub := publish{qos: (p.flags >> 1) & 0x03, retained: p.flags&0x01 != 0}
+	if pub.qos > 2 || len(p.body) < 2 {
+		return publish{}, errors.New("malformed PUBLISH")
+	}
+	size := int(binary.BigEndian.Uint16(p.body))
+	rest := p.body[2:]
+	if len(rest) < size {
+		return publish{}, errors.New("malformed PUBLISH")
+	}
+	pub.topic, rest = string(rest[:size]), rest[size:]
+	if pub.qos > 0 {
+		if len(rest) < 2 {
+			return publish{}, errors.New("malformed PUBLISH")
+		}
+		pub.id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	pub.payload = rest
+	return pub, nil
+}
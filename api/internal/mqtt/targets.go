@@ -0,0 +1,290 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/forge/api/internal/events"
+	"github.com/forge/api/internal/observe"
+	"github.com/forge/api/internal/targets"
+)
+
+// Target types
+const (
+	TargetLog     = "log"     // write a log line to Loki
+	TargetMetric  = "metric"  // increment a counter or set a gauge
+	TargetWebhook = "webhook" // publish an mqtt.message event for outbound webhooks
+)
+
+// Metric kinds of metric targets
+const (
+	KindCounter = "counter"
+	KindGauge   = "gauge"
+)
+
+const (
+	logTimeout = 10 * time.Second
+	// maxLogPayload bounds the payload written to a log line
+	maxLogPayload = 64 * 1024
+)
+
+// selectors are those of an MQTT message
+var selectors = targets.Selectors{Valid: validSelector, Hint: "topic, topic.<level>, payload or payload.<path>"}
+
+// Target is where a bridge sends the messages it receives. Type selects
+// which of the other fields apply.
+type Target struct {
+	Type string `json:"type" yaml:"type"` // log, metric or webhook
+
+	// log: line written at Level (default info) with the topic and payload
+	// as fields
+	Level   string `json:"level,omitempty" yaml:"level,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // default "MQTT message on ${topic}"
+
+	// metric: a counter incremented by one, or a gauge set to the number
+	// Value selects (default payload). Payloads of on/off and true/false
+	// count as 1 and 0.
+	Metric string `json:"metric,omitempty" yaml:"metric,omitempty"`
+	Kind   string `json:"kind,omitempty" yaml:"kind,omitempty"` // counter (default) or gauge
+	Value  string `json:"value,omitempty" yaml:"value,omitempty"`
+
+	// log and metric: labels added. Message and label values may hold
+	// ${selector} templates: topic, topic.<level> (from 0), payload and
+	// payload.<path> into a JSON payload, such as ${topic.1}.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Event is the data of mqtt.message events
+type Event struct {
+	Bridge   string    `json:"bridge"`
+	Topic    string    `json:"topic"`
+	Payload  any       `json:"payload"` // JSON when the payload is JSON, a string when text, base64 otherwise
+	Retained bool      `json:"retained,omitempty"`
+	Received time.Time `json:"received"`
+}
+
+// Message summarizes the event for notification channels
+func (e Event) Message() string {
+	var payload string
+	switch p := e.Payload.(type) {
+	case string:
+		payload = p
+	case json.RawMessage:
+		payload = string(p)
+	default:
+		payload = "(binary)"
+	}
+	return e.Topic + ": " + payload
+}
+
+// EventSink receives the events of webhook targets; *events.Bus
+// implements it
+type EventSink interface {
+	Publish(typ string, data any)
+}
+
+// Targets sends messages to bridge targets
+type Targets struct {
+	logs    targets.LogSink
+	metrics targets.MetricSink
+	events  EventSink
+}
+
+// NewTargets creates a target runner
+func NewTargets(logs targets.LogSink, metrics targets.MetricSink, events EventSink) *Targets {
+	return &Targets{logs: logs, metrics: metrics, events: events}
+}
+
+// message is a message received by a bridge
+type message struct {
+	Message
+	bridge string
+	levels []string
+	json   any // the decoded payload, nil when it is not JSON
+}
+
+func newMessage(bridge string, m Message) *message {
+	return &message{Message: m, bridge: bridge, levels: strings.Split(m.Topic, "/"), json: targets.DecodeJSON(m.Payload)}
+}
+
+// payload returns the payload as it is put in logs and events
+func (m *message) payload() any {
+	switch {
+	case m.json != nil:
+		return json.RawMessage(m.Payload)
+	case utf8.Valid(m.Payload):
+		return string(m.Payload)
+	}
+	return m.Payload
+}
+
+// Lookup returns the value of a selector
+func (m *message) Lookup(sel string) (string, bool) {
+	switch sel {
+	case "topic":
+		return m.Topic, true
+	case "payload":
+		return string(m.Payload), true
+	}
+	if level, ok := strings.CutPrefix(sel, "topic."); ok {
+		i, err := strconv.Atoi(level)
+		if err != nil || i < 0 || i >= len(m.levels) {
+			return "", false
+		}
+		return m.levels[i], true
+	}
+	p, _ := strings.CutPrefix(sel, "payload.")
+	return targets.Path(m.json, p)
+}
+
+// run sends a message to a target
+func (t *Targets) run(ctx context.Context, target Target, msg *message) error {
+	switch target.Type {
+	case TargetLog:
+		if t.logs == nil {
+			return errors.New("logs are not available")
+		}
+		text := target.Message
+		if text == "" {
+			text = "MQTT message on ${topic}"
+		}
+		fields := map[string]any{"bridge": msg.bridge, "topic": msg.Topic, "payload": msg.payload()}
+		if len(msg.Payload) > maxLogPayload {
+			fields["payload"] = fmt.Sprintf("(%d bytes)", len(msg.Payload))
+		}
+		if msg.Retained {
+			fields["retained"] = true
+		}
+		ctx, cancel := context.WithTimeout(ctx, logTimeout)
+		defer cancel()
+		return t.logs.PushEntry(ctx, observe.Entry{
+			Level:     target.Level,
+			Message:   targets.Expand(msg, text),
+			Labels:    targets.ExpandLabels(msg, target.Labels, map[string]string{"source": "mqtt", "bridge": msg.bridge}),
+			Fields:    fields,
+			Timestamp: msg.Received,
+		})
+	case TargetMetric:
+		if t.metrics == nil {
+			return errors.New("metrics are not available")
+		}
+		value := 1.0
+		if target.Kind == KindGauge {
+			s, ok := msg.Lookup(target.Value)
+			if !ok {
+				return fmt.Errorf("no value at %s", target.Value)
+			}
+			v, err := number(s)
+			if err != nil {
+				return fmt.Errorf("value at %s: %w", target.Value, err)
+			}
+			value = v
+		}
+		return t.metrics.Push(target.Metric, target.Kind, value, targets.ExpandLabels(msg, target.Labels, nil))
+	case TargetWebhook:
+		if t.events == nil {
+			return errors.New("webhooks are not available")
+		}
+		t.events.Publish(events.MQTTMessage, Event{
+			Bridge:   msg.bridge,
+			Topic:    msg.Topic,
+			Payload:  msg.payload(),
+			Retained: msg.Retained,
+			Received: msg.Received,
+		})
+		return nil
+	}
+	return fmt.Errorf("unknown target type %q", target.Type)
+}
+
+// number parses a gauge value: a number, on/off or true/false
+func number(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "on", "true":
+		return 1, nil
+	case "off", "false":
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", truncate(s, 32))
+	}
+	return v, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// validSelector reports whether sel names the topic, one of its levels,
+// the payload or a part of it
+func validSelector(sel string) bool {
+	if sel == "topic" || sel == "payload" {
+		return true
+	}
+	if level, ok := strings.CutPrefix(sel, "topic."); ok {
+		i, err := strconv.Atoi(level)
+		return err == nil && i >= 0
+	}
+	p, ok := strings.CutPrefix(sel, "payload.")
+	return ok && targets.ValidPath(p)
+}
+
+// validateTarget checks a target and fills in defaults
+func validateTarget(t *Target) error {
+	if t.Type == TargetWebhook && len(t.Labels) > 0 {
+		return fmt.Errorf("labels are only valid for log and metric")
+	}
+	if err := selectors.ValidateLabels(t.Labels); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case TargetLog:
+		if err := selectors.ValidateLog(&t.Level, t.Message, t.Labels, "job", "level", "source", "bridge"); err != nil {
+			return err
+		}
+	case TargetMetric:
+		if t.Kind == "" {
+			t.Kind = KindCounter
+		}
+		switch t.Kind {
+		case KindCounter:
+			if t.Value != "" {
+				return fmt.Errorf("value is only valid for gauges")
+			}
+		case KindGauge:
+			if t.Value == "" {
+				t.Value = "payload"
+			}
+			if !validSelector(t.Value) {
+				return fmt.Errorf("invalid value selector: %q (topic.<level>, payload or payload.<path>)", t.Value)
+			}
+		default:
+			return fmt.Errorf("kind must be counter or gauge")
+		}
+		if err := targets.ValidateMetric(t.Metric, t.Kind, t.Labels); err != nil {
+			return err
+		}
+	case TargetWebhook:
+	default:
+		return fmt.Errorf("type must be log, metric or webhook")
+	}
+	if t.Type != TargetLog && (t.Level != "" || t.Message != "") {
+		return fmt.Errorf("level and message are only valid for log")
+	}
+	if t.Type != TargetMetric && (t.Metric != "" || t.Kind != "" || t.Value != "") {
+		return fmt.Errorf("metric, kind and value are only valid for metric")
+	}
+	return nil
+}
@@ -0,0 +1,534 @@
+// Package notify sends messages to named notification channels: Slack,
+// Discord, Telegram, Gotify, ntfy and generic webhooks
+//
+// Channels are stored in channels.yaml. Apps send to a channel by name
+// through the API, and channels subscribed to events of the internal bus
+// (alerts raised by Forge, container failures, watchdog incidents, failed
+// jobs) are sent a message for each, so no subsystem hardcodes an
+// integration.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/forge/api/internal/events"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+// Channel types
+const (
+	TypeSlack    = "slack"
+	TypeDiscord  = "discord"
+	TypeTelegram = "telegram"
+	TypeGotify   = "gotify"
+	TypeNtfy     = "ntfy"
+	TypeWebhook  = "webhook"
+)
+
+// Message priorities
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+const (
+	maxEvents  = 20
+	maxHeaders = 20
+	// MaxTitleLength and MaxTextLength bound messages in characters
+	MaxTitleLength = 256
+	MaxTextLength  = 4000
+	// queueSize bounds event messages waiting to be sent
+	queueSize = 256
+)
+
+var (
+	// ErrInvalidChannel is returned for channels that cannot be stored
+	ErrInvalidChannel = errors.New("invalid channel")
+	// ErrChannelNotFound is returned for unknown channel names
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrChannelDisabled is returned when sending to a disabled channel
+	ErrChannelDisabled = errors.New("channel disabled")
+	// ErrInvalidMessage is returned for messages that cannot be sent
+	ErrInvalidMessage = errors.New("invalid message")
+	// ErrSendFailed is returned when the service behind a channel did not
+	// accept a message
+	ErrSendFailed = errors.New("send failed")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	// reservedHeaders are set by Forge on every webhook message
+	reservedHeaders = map[string]bool{"Content-Type": true, "Content-Length": true, "Host": true, "User-Agent": true}
+)
+
+// Channel is a named destination for notifications
+type Channel struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+
+	// URL is the Slack or Discord incoming webhook, the Gotify server, the
+	// ntfy topic (https://ntfy.sh/<topic>) or the webhook endpoint; for
+	// Telegram it overrides the Bot API server
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// ChatID is the Telegram chat messages go to
+	ChatID string `json:"chat_id,omitempty" yaml:"chat_id,omitempty"`
+	// Headers are sent with webhook messages
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Events are the bus events sent to the channel: types, families such
+	// as "container.*", or "*"; none for channels only apps send to
+	Events   []string          `json:"events,omitempty" yaml:"events,omitempty"`
+	Disabled bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Token is the Telegram bot token, Gotify application token or ntfy
+	// access token; it is never returned
+	Token string `json:"-" yaml:"token,omitempty"`
+}
+
+// Message is a notification
+type Message struct {
+	Title    string `json:"title,omitempty"`
+	Text     string `json:"text"`
+	Priority string `json:"priority,omitempty"` // low, normal (default) or high
+	// Event is the type of the bus event the message is about, if any
+	Event string `json:"event,omitempty"`
+}
+
+// Status is a channel with its send counts
+type Status struct {
+	Channel
+	HasToken  bool      `json:"has_token"`
+	Sent      int       `json:"sent"`
+	Failed    int       `json:"failed"`
+	LastSent  time.Time `json:"last_sent,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// counts are the send results of a channel since the API started
+type counts struct {
+	sent, failed int
+	lastSent     time.Time
+	lastError    string
+}
+
+// queued is an event message waiting to be sent to a channel
+type queued struct {
+	channel Channel
+	msg     Message
+}
+
+type channelsFile struct {
+	Channels []Channel `yaml:"channels"`
+}
+
+// Manager stores channels and sends messages to them
+type Manager struct {
+	mu         sync.RWMutex
+	channels   map[string]Channel
+	counts     map[string]*counts
+	configPath string
+	client     *http.Client
+	queue      chan queued
+}
+
+// NewManager loads channels from configPath
+func NewManager(configPath string) (*Manager, error) {
+	m := &Manager{
+		channels:   make(map[string]Channel),
+		counts:     make(map[string]*counts),
+		configPath: configPath,
+		client:     &http.Client{Timeout: sendTimeout},
+		queue:      make(chan queued, queueSize),
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all channels with their send counts, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.channels))
+	for name := range m.channels {
+		list = append(list, m.status(name))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a channel with its send counts
+func (m *Manager) Get(name string) (Status, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.channels[name]; !ok {
+		return Status{}, ErrChannelNotFound
+	}
+	return m.status(name), nil
+}
+
+// status builds a Status; m.mu must be held
+func (m *Manager) status(name string) Status {
+	ch := m.channels[name]
+	st := Status{Channel: ch, HasToken: ch.Token != ""}
+	if c := m.counts[name]; c != nil {
+		st.Sent, st.Failed, st.LastSent, st.LastError = c.sent, c.failed, c.lastSent, c.lastError
+	}
+	return st
+}
+
+// Save creates or replaces a channel. A channel saved without a token
+// keeps its current one. It reports whether the channel was created.
+func (m *Manager) Save(ch Channel) (bool, error) {
+	m.mu.Lock()
+	old, exists := m.channels[ch.Name]
+	if ch.Token == "" && old.Type == ch.Type {
+		ch.Token = old.Token
+	}
+	m.mu.Unlock()
+
+	if err := validateChannel(&ch); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	m.channels[ch.Name] = ch
+	m.mu.Unlock()
+	return !exists, m.save()
+}
+
+// SetDisabled stops or resumes sending to a channel
+func (m *Manager) SetDisabled(name string, disabled bool) (Status, error) {
+	m.mu.Lock()
+	ch, ok := m.channels[name]
+	if !ok {
+		m.mu.Unlock()
+		return Status{}, ErrChannelNotFound
+	}
+	ch.Disabled = disabled
+	m.channels[name] = ch
+	st := m.status(name)
+	m.mu.Unlock()
+	return st, m.save()
+}
+
+// Remove deletes a channel and its metrics
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.channels[name]; !ok {
+		m.mu.Unlock()
+		return ErrChannelNotFound
+	}
+	delete(m.channels, name)
+	delete(m.counts, name)
+	m.mu.Unlock()
+
+	for _, result := range sendResults {
+		metrics.NotificationsTotal.DeleteLabelValues(name, result)
+	}
+	return m.save()
+}
+
+// Send sends msg to the named channel and waits for the service behind
+// it to accept the message
+func (m *Manager) Send(ctx context.Context, name string, msg Message) error {
+	if err := validateMessage(&msg); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	ch, ok := m.channels[name]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChannelNotFound, name)
+	}
+	if ch.Disabled {
+		return fmt.Errorf("%w: %s", ErrChannelDisabled, name)
+	}
+	return m.send(ctx, ch, msg)
+}
+
+// Test sends a test message to a channel, even a disabled one
+func (m *Manager) Test(ctx context.Context, name string) error {
+	m.mu.RLock()
+	ch, ok := m.channels[name]
+	m.mu.RUnlock()
+	if !ok {
+		return ErrChannelNotFound
+	}
+	return m.send(ctx, ch, Message{
+		Title:    "Forge test notification",
+		Text:     fmt.Sprintf("Channel %s (%s) is working.", ch.Name, ch.Type),
+		Priority: PriorityLow,
+	})
+}
+
+// Dispatch queues a message about e for every enabled channel subscribed
+// to its type. It does not block, so it can be registered on the event
+// bus; messages beyond the queue's capacity are dropped.
+func (m *Manager) Dispatch(e events.Event) {
+	if e.Type == events.WebhookTest {
+		return
+	}
+	msg := EventMessage(e)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for name, ch := range m.channels {
+		if ch.Disabled || !subscribed(ch, e.Type) {
+			continue
+		}
+		select {
+		case m.queue <- queued{channel: ch, msg: msg}:
+		default:
+			metrics.NotificationsTotal.WithLabelValues(name, resultDropped).Inc()
+			logger.Warn(fmt.Sprintf("Notify %s: dropped message about %s, queue full", name, e.Type))
+		}
+	}
+}
+
+// Run sends queued event messages until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < maxConcurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case q := <-m.queue:
+					if err := m.send(ctx, q.channel, q.msg); err != nil {
+						logger.Warn(fmt.Sprintf("Notify %s: failed to send message about %s: %v", q.channel.Name, q.msg.Event, err))
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// send delivers msg to ch once and records the result
+func (m *Manager) send(ctx context.Context, ch Channel, msg Message) error {
+	err := m.deliver(ctx, ch, msg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.channels[ch.Name]; !ok {
+		return err
+	}
+	c := m.counts[ch.Name]
+	if c == nil {
+		c = &counts{}
+		m.counts[ch.Name] = c
+	}
+	result := resultSent
+	if err != nil {
+		c.failed++
+		c.lastError = err.Error()
+		result = resultFailed
+	} else {
+		c.sent++
+		c.lastSent = time.Now().UTC()
+		c.lastError = ""
+	}
+	metrics.NotificationsTotal.WithLabelValues(ch.Name, result).Inc()
+	return err
+}
+
+// EventMessage renders a bus event as a notification
+func EventMessage(e events.Event) Message {
+	msg := Message{Title: eventTitles[e.Type], Priority: eventPriorities[e.Type], Event: e.Type}
+	if msg.Title == "" {
+		msg.Title = e.Type
+	}
+	if msg.Priority == "" {
+		msg.Priority = PriorityNormal
+	}
+	if m, ok := e.Data.(interface{ Message() string }); ok {
+		msg.Text = m.Message()
+	} else if data, err := json.Marshal(e.Data); err == nil {
+		msg.Text = string(data)
+	}
+	msg.Text = truncate(msg.Text, MaxTextLength)
+	return msg
+}
+
+var eventTitles = map[string]string{
+	events.RouteChanged:       "Route changed",
+	events.ContainerUnhealthy: "Container unhealthy",
+	events.ContainerDied:      "Container died",
+	events.AlertFired:         "Alert firing",
+	events.AlertResolved:      "Alert resolved",
+	events.BackupFinished:     "Backup finished",
+	events.JobFailed:          "Job failed",
+	events.WatchdogIncident:   "Watchdog",
+	events.MQTTMessage:        "MQTT message",
+}
+
+var eventPriorities = map[string]string{
+	events.RouteChanged:     PriorityLow,
+	events.AlertResolved:    PriorityLow,
+	events.BackupFinished:   PriorityLow,
+	events.AlertFired:       PriorityHigh,
+	events.ContainerDied:    PriorityHigh,
+	events.JobFailed:        PriorityHigh,
+	events.WatchdogIncident: PriorityHigh,
+}
+
+// subscribed reports whether ch wants events of type typ
+func subscribed(ch Channel, typ string) bool {
+	for _, p := range ch.Events {
+		if events.Matches(p, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateChannel checks a channel's type-specific fields
+func validateChannel(ch *Channel) error {
+	if !namePattern.MatchString(ch.Name) {
+		return fmt.Errorf("%w: name must be 1-64 characters of letters, digits, '_' or '-'", ErrInvalidChannel)
+	}
+	switch ch.Type {
+	case TypeSlack, TypeDiscord, TypeGotify, TypeNtfy, TypeWebhook:
+		if !httpURL(ch.URL) {
+			return fmt.Errorf("%w: %s channel requires an http(s) url", ErrInvalidChannel, ch.Type)
+		}
+	case TypeTelegram:
+		if ch.URL != "" && !httpURL(ch.URL) {
+			return fmt.Errorf("%w: url must be an http(s) URL of a Bot API server", ErrInvalidChannel)
+		}
+		if ch.ChatID == "" {
+			return fmt.Errorf("%w: telegram channel requires a chat_id", ErrInvalidChannel)
+		}
+	default:
+		return fmt.Errorf("%w: type must be slack, discord, telegram, gotify, ntfy or webhook, got %q", ErrInvalidChannel, ch.Type)
+	}
+	if (ch.Type == TypeTelegram || ch.Type == TypeGotify) && ch.Token == "" {
+		return fmt.Errorf("%w: %s channel requires a token", ErrInvalidChannel, ch.Type)
+	}
+	if ch.Type == TypeNtfy {
+		if u, _ := url.Parse(ch.URL); strings.Trim(u.Path, "/") == "" {
+			return fmt.Errorf("%w: ntfy url must name a topic, e.g. https://ntfy.sh/forge", ErrInvalidChannel)
+		}
+	}
+	if len(ch.Headers) > 0 && ch.Type != TypeWebhook {
+		return fmt.Errorf("%w: headers are only sent to webhook channels", ErrInvalidChannel)
+	}
+	if len(ch.Headers) > maxHeaders {
+		return fmt.Errorf("%w: too many headers (max %d)", ErrInvalidChannel, maxHeaders)
+	}
+	for k := range ch.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(k)] {
+			return fmt.Errorf("%w: header %s is set by Forge", ErrInvalidChannel, k)
+		}
+	}
+	if len(ch.Events) > maxEvents {
+		return fmt.Errorf("%w: too many events (max %d)", ErrInvalidChannel, maxEvents)
+	}
+	for _, p := range ch.Events {
+		if !events.ValidPattern(p) {
+			return fmt.Errorf("%w: unknown event %q (one of %s, a family such as container.*, or *)", ErrInvalidChannel, p, strings.Join(events.Types, ", "))
+		}
+	}
+	if err := resources.ValidateLabels(ch.Labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidChannel, err)
+	}
+	return nil
+}
+
+// validateMessage checks a message and fills in the default priority
+func validateMessage(msg *Message) error {
+	if strings.TrimSpace(msg.Text) == "" {
+		return fmt.Errorf("%w: text is required", ErrInvalidMessage)
+	}
+	if utf8.RuneCountInString(msg.Text) > MaxTextLength {
+		return fmt.Errorf("%w: text is longer than %d characters", ErrInvalidMessage, MaxTextLength)
+	}
+	if utf8.RuneCountInString(msg.Title) > MaxTitleLength {
+		return fmt.Errorf("%w: title is longer than %d characters", ErrInvalidMessage, MaxTitleLength)
+	}
+	switch msg.Priority {
+	case "":
+		msg.Priority = PriorityNormal
+	case PriorityLow, PriorityNormal, PriorityHigh:
+	default:
+		return fmt.Errorf("%w: priority must be low, normal or high", ErrInvalidMessage)
+	}
+	return nil
+}
+
+func httpURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+// load reads channels from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f channelsFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ch := range f.Channels {
+		m.channels[ch.Name] = ch
+	}
+	return nil
+}
+
+// save writes channels to the config file, readable only by Forge as it
+// holds their tokens and webhook URLs
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := channelsFile{Channels: make([]Channel, 0, len(m.channels))}
+	for _, ch := range m.channels {
+		f.Channels = append(f.Channels, ch)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Channels, func(i, j int) bool { return f.Channels[i].Name < f.Channels[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0600)
+}
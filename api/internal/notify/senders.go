@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sendTimeout   = 10 * time.Second
+	maxConcurrent = 4
+	// maxResponse bounds the response body quoted in send errors
+	maxResponse = 256
+	// discordLimit is the longest message content Discord accepts
+	discordLimit = 2000
+	// telegramAPI is the default Telegram Bot API server
+	telegramAPI = "https://api.telegram.org"
+)
+
+// Send metric results
+const (
+	resultSent    = "sent"
+	resultFailed  = "failed"
+	resultDropped = "dropped" // event messages that did not fit the queue
+)
+
+var sendResults = []string{resultSent, resultFailed, resultDropped}
+
+// gotifyPriorities and ntfyPriorities map message priorities onto each
+// service's scale
+var (
+	gotifyPriorities = map[string]int{PriorityLow: 2, PriorityNormal: 5, PriorityHigh: 8}
+	ntfyPriorities   = map[string]string{PriorityLow: "low", PriorityNormal: "default", PriorityHigh: "high"}
+)
+
+// deliver sends msg to the service behind ch in its own format
+func (m *Manager) deliver(ctx context.Context, ch Channel, msg Message) error {
+	var (
+		target  = ch.URL
+		body    any
+		headers = map[string]string{}
+	)
+	switch ch.Type {
+	case TypeSlack:
+		body = map[string]string{"text": plainText(msg, "*")}
+	case TypeDiscord:
+		body = map[string]string{"content": truncate(plainText(msg, "**"), discordLimit)}
+	case TypeTelegram:
+		base := ch.URL
+		if base == "" {
+			base = telegramAPI
+		}
+		target = strings.TrimRight(base, "/") + "/bot" + ch.Token + "/sendMessage"
+		body = map[string]any{"chat_id": ch.ChatID, "text": plainText(msg, ""), "disable_web_page_preview": true}
+	case TypeGotify:
+		target = strings.TrimRight(ch.URL, "/") + "/message"
+		headers["X-Gotify-Key"] = ch.Token
+		body = map[string]any{"title": msg.Title, "message": msg.Text, "priority": gotifyPriorities[msg.Priority]}
+	case TypeNtfy:
+		// ntfy takes the text as the body and everything else as headers
+		if msg.Title != "" {
+			headers["Title"] = msg.Title
+		}
+		headers["Priority"] = ntfyPriorities[msg.Priority]
+		if msg.Event != "" {
+			headers["Tags"] = msg.Event
+		}
+		if ch.Token != "" {
+			headers["Authorization"] = "Bearer " + ch.Token
+		}
+		return m.post(ctx, ch, target, "text/plain; charset=utf-8", []byte(msg.Text), headers)
+	case TypeWebhook:
+		for k, v := range ch.Headers {
+			headers[k] = v
+		}
+		body = map[string]any{
+			"channel":  ch.Name,
+			"title":    msg.Title,
+			"text":     msg.Text,
+			"priority": msg.Priority,
+			"event":    msg.Event,
+			"time":     time.Now().UTC(),
+		}
+	default:
+		return fmt.Errorf("%w: unknown channel type %q", ErrSendFailed, ch.Type)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return m.post(ctx, ch, target, "application/json", data, headers)
+}
+
+// post sends one request and turns anything but a 2xx response into an
+// error quoting the start of the response body
+func (m *Manager) post(ctx context.Context, ch Channel, target, contentType string, data []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSendFailed, err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "Forge-Notify/1.0")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		// The Telegram URL carries the bot token, which must not end up
+		// in errors returned to clients or logged
+		return fmt.Errorf("%w: %s", ErrSendFailed, redact(err.Error(), ch.Token))
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if text := strings.TrimSpace(string(body)); text != "" {
+			return fmt.Errorf("%w: %s returned status %d: %s", ErrSendFailed, ch.Type, resp.StatusCode, redact(text, ch.Token))
+		}
+		return fmt.Errorf("%w: %s returned status %d", ErrSendFailed, ch.Type, resp.StatusCode)
+	}
+	return nil
+}
+
+// plainText renders a message for chat services that have no title field,
+// with the title on its own line wrapped in mark (bold in their markup)
+func plainText(msg Message, mark string) string {
+	if msg.Title == "" {
+		return msg.Text
+	}
+	return mark + msg.Title + mark + "\n" + msg.Text
+}
+
+// redact removes token from s
+func redact(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, "[redacted]")
+}
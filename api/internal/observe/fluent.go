@@ -0,0 +1,161 @@
+package observe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFluentPayload is returned when a fluent HTTP payload cannot be decoded
+var ErrInvalidFluentPayload = errors.New("invalid fluent payload")
+
+// Record keys consumed when converting a fluent record to an Entry, in
+// order of preference. Everything else is kept as a structured field.
+var (
+	fluentMessageKeys = []string{"log", "message", "msg"}
+	fluentLevelKeys   = []string{"level", "severity", "lvl"}
+	fluentTimeKeys    = []string{"date", "time", "timestamp", "@timestamp"}
+)
+
+// fluentTagPattern limits tags to what Fluent Bit/Fluentd produce (e.g. kube.var.log.app)
+var fluentTagPattern = regexp.MustCompile(`^[a-zA-Z0-9_.\-]{1,128}$`)
+
+// ParseFluentRecords decodes the body of a Fluent Bit/Fluentd HTTP output.
+// Both the json (array or single object) and json_lines formats are accepted.
+func ParseFluentRecords(body []byte) ([]map[string]any, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	if trimmed[0] == '[' {
+		var records []map[string]any
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFluentPayload, err)
+		}
+		return records, nil
+	}
+
+	// A single object or newline-delimited objects
+	var records []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFluentPayload, err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// ValidFluentTag reports whether tag can be used as the Loki tag label
+func ValidFluentTag(tag string) bool {
+	return fluentTagPattern.MatchString(tag)
+}
+
+// FluentEntry converts a decoded fluent record to a Loki entry. The tag
+// becomes a label; a "tag" key in the record is used when tag is empty.
+func FluentEntry(tag string, rec map[string]any) (Entry, error) {
+	fields := make(map[string]any, len(rec))
+	for k, v := range rec {
+		fields[k] = v
+	}
+
+	if t, ok := fields["tag"].(string); ok {
+		if tag == "" {
+			tag = t
+		}
+		delete(fields, "tag")
+	}
+
+	e := Entry{
+		Labels: map[string]string{"job": "fluent"},
+	}
+	if tag != "" {
+		if !ValidFluentTag(tag) {
+			return Entry{}, fmt.Errorf("%w: invalid tag %q", ErrInvalidLogEntry, tag)
+		}
+		e.Labels["tag"] = tag
+	}
+
+	if key, v := takeField(fields, fluentMessageKeys); key != "" {
+		e.Message = fluentString(v)
+	}
+	if e.Message == "" {
+		return Entry{}, fmt.Errorf("%w: record has no log message", ErrInvalidLogEntry)
+	}
+	if key, v := takeField(fields, fluentLevelKeys); key != "" {
+		e.Level = strings.ToLower(fluentString(v))
+	}
+	if key, v := takeField(fields, fluentTimeKeys); key != "" {
+		ts, err := parseFluentTime(v)
+		if err != nil {
+			return Entry{}, fmt.Errorf("%w: %s: %v", ErrInvalidLogEntry, key, err)
+		}
+		e.Timestamp = ts
+	}
+
+	// Keep leftover keys that collide with the reserved line keys
+	for _, k := range []string{logMessageField, logLevelField} {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			fields["record_"+k] = v
+		}
+	}
+
+	if len(fields) > 0 {
+		e.Fields = fields
+	}
+	return e, nil
+}
+
+// takeField removes and returns the first of keys present in fields
+func takeField(fields map[string]any, keys []string) (string, any) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			return k, v
+		}
+	}
+	return "", nil
+}
+
+func fluentString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimRight(s, "\r\n")
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+}
+
+// parseFluentTime accepts epoch seconds (Fluent Bit's default double date)
+// or an RFC 3339 / ISO 8601 string
+func parseFluentTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case float64:
+		if t <= 0 || math.IsInf(t, 0) || math.IsNaN(t) {
+			return time.Time{}, fmt.Errorf("invalid epoch %v", t)
+		}
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unrecognised time %q", t)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time type %T", v)
+	}
+}
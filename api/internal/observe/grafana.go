@@ -0,0 +1,167 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+// ErrDatasourceNotFound is returned when Grafana has no datasource with the given UID
+var ErrDatasourceNotFound = errors.New("datasource not found")
+
+// DatasourceTypes are the Grafana datasource types Forge can provision
+var DatasourceTypes = map[string]bool{
+	"prometheus": true,
+	"loki":       true,
+	"tempo":      true,
+	"mysql":      true,
+	"postgres":   true,
+}
+
+var datasourceUIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,40}$`)
+
+// GrafanaClient manages Grafana datasources via the HTTP API
+type GrafanaClient struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+}
+
+func NewGrafanaClient(cfg config.Grafana) *GrafanaClient {
+	return &GrafanaClient{
+		url:      cfg.URL,
+		user:     cfg.User,
+		password: cfg.Password,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Datasource is a Grafana datasource definition
+type Datasource struct {
+	ID             int64             `json:"id,omitempty"`
+	UID            string            `json:"uid"`
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	URL            string            `json:"url"`
+	Access         string            `json:"access"`
+	Database       string            `json:"database,omitempty"`
+	User           string            `json:"user,omitempty"`
+	IsDefault      bool              `json:"isDefault"`
+	ReadOnly       bool              `json:"readOnly,omitempty"`
+	JSONData       map[string]any    `json:"jsonData,omitempty"`
+	SecureJSONData map[string]string `json:"secureJsonData,omitempty"`
+}
+
+// Validate checks a datasource before it is sent to Grafana
+func (d *Datasource) Validate() error {
+	if !datasourceUIDPattern.MatchString(d.UID) {
+		return fmt.Errorf("uid must be 1-40 characters of letters, digits, '_' or '-'")
+	}
+	if d.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !DatasourceTypes[d.Type] {
+		return fmt.Errorf("unsupported datasource type: %q (must be prometheus, loki, tempo, mysql or postgres)", d.Type)
+	}
+	if d.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if d.Access == "" {
+		d.Access = "proxy"
+	}
+	return nil
+}
+
+// ListDatasources returns all Grafana datasources
+func (c *GrafanaClient) ListDatasources(ctx context.Context) ([]Datasource, error) {
+	var list []Datasource
+	if err := c.do(ctx, "GET", "/api/datasources", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetDatasource returns a datasource by UID
+func (c *GrafanaClient) GetDatasource(ctx context.Context, uid string) (*Datasource, error) {
+	var ds Datasource
+	if err := c.do(ctx, "GET", "/api/datasources/uid/"+url.PathEscape(uid), nil, &ds); err != nil {
+		return nil, err
+	}
+	return &ds, nil
+}
+
+// CreateDatasource creates a new datasource
+func (c *GrafanaClient) CreateDatasource(ctx context.Context, ds Datasource) error {
+	if err := ds.Validate(); err != nil {
+		return err
+	}
+	return c.do(ctx, "POST", "/api/datasources", ds, nil)
+}
+
+// UpdateDatasource replaces the datasource with the given UID
+func (c *GrafanaClient) UpdateDatasource(ctx context.Context, ds Datasource) error {
+	if err := ds.Validate(); err != nil {
+		return err
+	}
+	return c.do(ctx, "PUT", "/api/datasources/uid/"+url.PathEscape(ds.UID), ds, nil)
+}
+
+// DeleteDatasource deletes a datasource by UID
+func (c *GrafanaClient) DeleteDatasource(ctx context.Context, uid string) error {
+	return c.do(ctx, "DELETE", "/api/datasources/uid/"+url.PathEscape(uid), nil, nil)
+}
+
+// do performs an authenticated JSON request against Grafana
+func (c *GrafanaClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.user, c.password)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("grafana request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrDatasourceNotFound
+	}
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("grafana %s %s failed: %d %s", method, path, resp.StatusCode, apiErr.Message)
+		}
+		return fmt.Errorf("grafana %s %s failed: %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
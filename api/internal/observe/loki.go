@@ -4,33 +4,93 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Loki push buffering defaults
+const (
+	defaultLokiQueueSize     = 10000
+	defaultLokiBatchSize     = 500
+	defaultLokiFlushInterval = time.Second
+	defaultLokiMaxRetries    = 5
+	lokiInitialBackoff       = 500 * time.Millisecond
+	lokiMaxBackoff           = 10 * time.Second
 )
 
+// ErrLogQueueFull is returned by Push when the buffer is full and the entry was dropped
+var ErrLogQueueFull = errors.New("log queue full, entry dropped")
+
+// ErrInvalidLogEntry is returned for entries with invalid fields or metadata
+var ErrInvalidLogEntry = errors.New("invalid log entry")
+
 type LokiClient struct {
 	url    string
 	client *http.Client
+
+	processors []EntryProcessor
+
+	queue         chan lokiEntry
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	startOnce     sync.Once
+	done          chan struct{}
+	stopped       chan struct{}
 }
 
-func NewLokiClient() *LokiClient {
-	url := os.Getenv("LOKI_URL")
-	if url == "" {
-		url = "http://localhost:3100"
-	}
-	
+// lokiEntry is a buffered log line waiting to be pushed
+type lokiEntry struct {
+	labels   map[string]string
+	ts       time.Time
+	line     string
+	metadata map[string]string
+}
+
+// Structured log limits
+const (
+	maxLogFields    = 100
+	logMessageField = "msg"
+	logLevelField   = "level"
+)
+
+func NewLokiClient(url string) *LokiClient {
 	return &LokiClient{
-		url:    url,
-		client: &http.Client{Timeout: 10 * time.Second},
+		url:           url,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		queue:         make(chan lokiEntry, defaultLokiQueueSize),
+		batchSize:     defaultLokiBatchSize,
+		flushInterval: defaultLokiFlushInterval,
+		maxRetries:    defaultLokiMaxRetries,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
 	}
 }
 
 // LokiPushRequest represents the Loki push API format
 type LokiPushRequest struct {
-	Streams []LokiStream `json:"streams"`
+	Streams []LokiPushStream `json:"streams"`
+}
+
+// LokiPushStream is a pushed stream. Each value is [ts, line] or
+// [ts, line, {structured metadata}].
+type LokiPushStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][]any           `json:"values"`
 }
 
 type LokiStream struct {
@@ -38,56 +98,528 @@ type LokiStream struct {
 	Values [][]string        `json:"values"`
 }
 
+// Entry is a log entry to push. When Fields is set the line is written
+// as a JSON object holding the message, level and fields.
+type Entry struct {
+	Level     string
+	Message   string
+	Labels    map[string]string
+	Fields    map[string]any
+	Metadata  []string // field names also sent as structured metadata
+	Timestamp time.Time
+}
+
+// EntryProcessor rewrites entries before they are queued for Loki. Labels
+// hold the final stream labels. Returning false drops the entry.
+type EntryProcessor interface {
+	Process(e *Entry) bool
+}
+
+// dropReasoner is implemented by processors that count their drops under
+// their own reason instead of "pipeline"
+type dropReasoner interface {
+	DropReason() string
+}
+
+// AddProcessor installs p for all pushed entries, after any processors
+// added before it. It must be called before the client receives traffic.
+func (c *LokiClient) AddProcessor(p EntryProcessor) {
+	c.processors = append(c.processors, p)
+}
+
+// Push buffers a plain log line. See PushEntry.
 func (c *LokiClient) Push(ctx context.Context, level, message string, labels map[string]string) error {
-	if level == "" {
-		level = "info"
+	return c.PushEntry(ctx, Entry{Level: level, Message: message, Labels: labels})
+}
+
+// PushEntry buffers a log entry for asynchronous delivery to Loki. It never
+// blocks on the network; if the buffer is full the entry is dropped and
+// ErrLogQueueFull is returned.
+func (c *LokiClient) PushEntry(ctx context.Context, e Entry) error {
+	if e.Level == "" {
+		e.Level = "info"
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
 	}
-	
+
 	// Build stream labels
 	streamLabels := map[string]string{
 		"job":   "forge",
-		"level": level,
+		"level": e.Level,
 	}
-	for k, v := range labels {
+	for k, v := range e.Labels {
 		streamLabels[k] = v
 	}
-	
-	// Current timestamp in nanoseconds
-	ts := strconv.FormatInt(time.Now().UnixNano(), 10)
-	
-	// Create push request
-	req := LokiPushRequest{
-		Streams: []LokiStream{
-			{
-				Stream: streamLabels,
-				Values: [][]string{
-					{ts, message},
-				},
-			},
-		},
-	}
-	
-	body, err := json.Marshal(req)
+
+	if len(c.processors) > 0 {
+		level := e.Level
+		e.Labels = streamLabels
+		for _, p := range c.processors {
+			if !p.Process(&e) {
+				reason := "pipeline"
+				if dr, ok := p.(dropReasoner); ok {
+					reason = dr.DropReason()
+				}
+				metrics.LokiEntriesDropped.WithLabelValues(reason).Inc()
+				return nil
+			}
+		}
+		streamLabels = e.Labels
+		if e.Level != level {
+			streamLabels["level"] = e.Level
+		}
+	}
+
+	line, metadata, err := formatEntry(e)
 	if err != nil {
 		return err
 	}
-	
+	metadata = withCorrelation(ctx, metadata)
+
+	select {
+	case c.queue <- lokiEntry{labels: streamLabels, ts: e.Timestamp, line: line, metadata: metadata}:
+		metrics.LokiQueueDepth.Inc()
+		return nil
+	default:
+		metrics.LokiEntriesDropped.WithLabelValues("queue_full").Inc()
+		return ErrLogQueueFull
+	}
+}
+
+// withCorrelation adds the request and trace IDs from ctx as structured
+// metadata so pushed logs can be joined with the request's traces
+func withCorrelation(ctx context.Context, metadata map[string]string) map[string]string {
+	ids := map[string]string{
+		"request_id": tracing.RequestID(ctx),
+		"trace_id":   tracing.TraceID(ctx),
+	}
+	for k, v := range ids {
+		if v == "" {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string, len(ids))
+		}
+		if _, set := metadata[k]; !set {
+			metadata[k] = v
+		}
+	}
+	return metadata
+}
+
+// formatEntry renders the log line and structured metadata for an entry
+func formatEntry(e Entry) (string, map[string]string, error) {
+	if len(e.Fields) == 0 {
+		if len(e.Metadata) > 0 {
+			return "", nil, fmt.Errorf("%w: metadata requires fields", ErrInvalidLogEntry)
+		}
+		return e.Message, nil, nil
+	}
+	if len(e.Fields) > maxLogFields {
+		return "", nil, fmt.Errorf("%w: too many fields (max %d)", ErrInvalidLogEntry, maxLogFields)
+	}
+
+	obj := make(map[string]any, len(e.Fields)+2)
+	for k, v := range e.Fields {
+		if k == "" || k == logMessageField || k == logLevelField {
+			return "", nil, fmt.Errorf("%w: field name %q is reserved", ErrInvalidLogEntry, k)
+		}
+		obj[k] = v
+	}
+	obj[logMessageField] = e.Message
+	obj[logLevelField] = e.Level
+
+	line, err := json.Marshal(obj)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidLogEntry, err)
+	}
+
+	var metadata map[string]string
+	for _, name := range e.Metadata {
+		v, ok := e.Fields[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: metadata field %q not in fields", ErrInvalidLogEntry, name)
+		}
+		if !labelNamePattern.MatchString(name) {
+			return "", nil, fmt.Errorf("%w: metadata field %q is not a valid label name", ErrInvalidLogEntry, name)
+		}
+		if metadata == nil {
+			metadata = make(map[string]string, len(e.Metadata))
+		}
+		if str, ok := v.(string); ok {
+			metadata[name] = str
+		} else {
+			b, _ := json.Marshal(v)
+			metadata[name] = string(b)
+		}
+	}
+
+	return string(line), metadata, nil
+}
+
+// Start launches the background batcher. Calling it more than once is a no-op.
+func (c *LokiClient) Start() {
+	c.startOnce.Do(func() {
+		go c.run()
+	})
+}
+
+// Close flushes buffered entries and stops the batcher, waiting up to ctx's deadline
+func (c *LokiClient) Close(ctx context.Context) error {
+	close(c.done)
+	select {
+	case <-c.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects entries into batches, flushing on size or interval
+func (c *LokiClient) run() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]lokiEntry, 0, c.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		c.pushBatch(batch)
+		batch = make([]lokiEntry, 0, c.batchSize)
+	}
+
+	for {
+		select {
+		case e := <-c.queue:
+			metrics.LokiQueueDepth.Dec()
+			batch = append(batch, e)
+			if len(batch) >= c.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-c.done:
+			// Drain whatever is still queued, then push once more
+			for {
+				select {
+				case e := <-c.queue:
+					metrics.LokiQueueDepth.Dec()
+					batch = append(batch, e)
+					if len(batch) >= c.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// pushBatch sends a batch to Loki, retrying transient failures with backoff
+func (c *LokiClient) pushBatch(batch []lokiEntry) {
+	body, err := json.Marshal(buildPushRequest(batch))
+	if err != nil {
+		metrics.LokiEntriesDropped.WithLabelValues("encode_failed").Add(float64(len(batch)))
+		return
+	}
+
+	backoff := lokiInitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := c.send(body)
+		if err == nil {
+			metrics.LokiBatchesTotal.WithLabelValues("success").Inc()
+			return
+		}
+
+		if !retryable || attempt >= c.maxRetries {
+			metrics.LokiBatchesTotal.WithLabelValues("failed").Inc()
+			metrics.LokiEntriesDropped.WithLabelValues("push_failed").Add(float64(len(batch)))
+			logger.Error("Dropping log batch after failed Loki push", err)
+			return
+		}
+
+		metrics.LokiBatchesTotal.WithLabelValues("retry").Inc()
+		select {
+		case <-time.After(backoff):
+		case <-c.done:
+			// Shutting down: one last immediate attempt happens on the next loop
+		}
+		backoff *= 2
+		if backoff > lokiMaxBackoff {
+			backoff = lokiMaxBackoff
+		}
+	}
+}
+
+// send performs one push. retryable reports whether a failure is transient.
+func (c *LokiClient) send(body []byte) (retryable bool, err error) {
+	ctx, span := tracing.StartKind(context.Background(), "loki push", trace.SpanKindClient,
+		attribute.Int("loki.push.bytes", len(body)))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.url+"/loki/api/v1/push", bytes.NewReader(body))
 	if err != nil {
-		return err
+		return false, err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	
+
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return err
+		return true, err
 	}
 	defer resp.Body.Close()
-	
+
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("loki push failed: %d", resp.StatusCode)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("loki push failed: %d %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return false, nil
+}
+
+// buildPushRequest groups entries into streams by label set
+func buildPushRequest(batch []lokiEntry) LokiPushRequest {
+	streams := make(map[string]*LokiPushStream)
+	var order []string
+
+	for _, e := range batch {
+		key := labelsKey(e.labels)
+		s, ok := streams[key]
+		if !ok {
+			s = &LokiPushStream{Stream: e.labels}
+			streams[key] = s
+			order = append(order, key)
+		}
+		value := []any{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+		if len(e.metadata) > 0 {
+			value = append(value, e.metadata)
+		}
+		s.Values = append(s.Values, value)
+	}
+
+	req := LokiPushRequest{Streams: make([]LokiPushStream, 0, len(order))}
+	for _, key := range order {
+		req.Streams = append(req.Streams, *streams[key])
 	}
-	
-	return nil
+	return req
+}
+
+// labelsKey returns a canonical string for a label set
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(labels[k])
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// QueryOptions controls a LogQL query against Loki
+type QueryOptions struct {
+	Query     string
+	Start     time.Time
+	End       time.Time
+	Limit     int
+	Direction string // "backward" or "forward"
+	Instant   bool
+}
+
+// QueryEntry is a single log line from a query result
+type QueryEntry struct {
+	Timestamp time.Time
+	Line      string
 }
 
+// QueryStream groups entries sharing the same label set
+type QueryStream struct {
+	Labels  map[string]string
+	Entries []QueryEntry
+}
+
+// lokiQueryResponse represents the Loki query API response format
+type lokiQueryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string       `json:"resultType"`
+		Result     []LokiStream `json:"result"`
+	} `json:"data"`
+}
+
+// Query runs a LogQL query against Loki and returns the matching streams.
+// Range queries are used unless opts.Instant is set. Only log (stream)
+// queries are supported; metric queries return an error.
+func (c *LokiClient) Query(ctx context.Context, opts QueryOptions) (_ []QueryStream, err error) {
+	ctx, span := tracing.StartKind(ctx, "loki query", trace.SpanKindClient)
+	defer func() { tracing.End(span, err) }()
+
+	if opts.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if opts.End.IsZero() {
+		opts.End = time.Now()
+	}
+	if opts.Start.IsZero() {
+		opts.Start = opts.End.Add(-time.Hour)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	if opts.Direction == "" {
+		opts.Direction = "backward"
+	}
+	if opts.Direction != "backward" && opts.Direction != "forward" {
+		return nil, fmt.Errorf("invalid direction: %s", opts.Direction)
+	}
+
+	params := url.Values{}
+	params.Set("query", opts.Query)
+	params.Set("limit", strconv.Itoa(opts.Limit))
+	params.Set("direction", opts.Direction)
+
+	endpoint := "/loki/api/v1/query_range"
+	if opts.Instant {
+		endpoint = "/loki/api/v1/query"
+		params.Set("time", strconv.FormatInt(opts.End.UnixNano(), 10))
+	} else {
+		params.Set("start", strconv.FormatInt(opts.Start.UnixNano(), 10))
+		params.Set("end", strconv.FormatInt(opts.End.UnixNano(), 10))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.url+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("loki query failed: %d %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var result lokiQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode loki response: %w", err)
+	}
+	if result.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("unsupported result type: %s (only log queries are supported)", result.Data.ResultType)
+	}
+
+	return parseStreams(result.Data.Result), nil
+}
+
+// QueryMetric evaluates an instant LogQL metric query (e.g.
+// sum(rate({app="x"} |= "error" [5m]))) at the given time, defaulting to now.
+func (c *LokiClient) QueryMetric(ctx context.Context, query string, at time.Time) (_ []Series, err error) {
+	ctx, span := tracing.StartKind(ctx, "loki query metric", trace.SpanKindClient)
+	defer func() { tracing.End(span, err) }()
+
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(at.UnixNano(), 10))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.url+"/loki/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("loki query failed: %d %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var result promResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode loki response: %w", err)
+	}
+
+	switch result.Data.ResultType {
+	case "vector":
+		var results []promVectorResult
+		if err := json.Unmarshal(result.Data.Result, &results); err != nil {
+			return nil, fmt.Errorf("failed to decode loki vector: %w", err)
+		}
+		series := make([]Series, 0, len(results))
+		for _, r := range results {
+			sample, err := parsePromSample(r.Value)
+			if err != nil {
+				return nil, err
+			}
+			series = append(series, Series{Labels: r.Metric, Samples: []Sample{sample}})
+		}
+		return series, nil
+	case "scalar":
+		var pair [2]any
+		if err := json.Unmarshal(result.Data.Result, &pair); err != nil {
+			return nil, fmt.Errorf("failed to decode loki scalar: %w", err)
+		}
+		sample, err := parsePromSample(pair)
+		if err != nil {
+			return nil, err
+		}
+		return []Series{{Labels: map[string]string{}, Samples: []Sample{sample}}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported result type: %s (a metric query is required)", result.Data.ResultType)
+	}
+}
+
+// parseStreams converts Loki's wire format into QueryStreams
+func parseStreams(raw []LokiStream) []QueryStream {
+	streams := make([]QueryStream, 0, len(raw))
+	for _, s := range raw {
+		stream := QueryStream{
+			Labels:  s.Stream,
+			Entries: make([]QueryEntry, 0, len(s.Values)),
+		}
+		for _, v := range s.Values {
+			if len(v) < 2 {
+				continue
+			}
+			ns, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			stream.Entries = append(stream.Entries, QueryEntry{
+				Timestamp: time.Unix(0, ns),
+				Line:      v[1],
+			})
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
@@ -0,0 +1,239 @@
+package observe
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cardinality limits for pushed metrics
+const (
+	DefaultMaxMetrics         = 500
+	DefaultMaxSeriesPerMetric = 1000
+	reservedMetricPrefix      = "forge_"
+	metricTypeCounter         = "counter"
+	metricTypeGauge           = "gauge"
+	metricTypeHistogram       = "histogram"
+	defaultMetricType         = metricTypeGauge
+	labelSeriesSeparator      = "\xff"
+	maxHistogramBuckets       = 50
+)
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// pushedMetric is a metric created on demand from a push
+type pushedMetric struct {
+	kind       string
+	labelNames []string
+	counter    *prometheus.CounterVec
+	gauge      *prometheus.GaugeVec
+	histogram  *prometheus.HistogramVec
+	buckets    []float64
+	series     map[string]struct{}
+}
+
+// MetricsRegistry creates Prometheus metrics on demand from pushed values
+// and exposes them through a prometheus.Registerer (normally the default
+// registry served on /metrics).
+type MetricsRegistry struct {
+	mu                 sync.Mutex
+	registerer         prometheus.Registerer
+	metrics            map[string]*pushedMetric
+	maxMetrics         int
+	maxSeriesPerMetric int
+}
+
+// NewMetricsRegistry creates a registry that registers metrics with reg
+func NewMetricsRegistry(reg prometheus.Registerer) *MetricsRegistry {
+	return &MetricsRegistry{
+		registerer:         reg,
+		metrics:            make(map[string]*pushedMetric),
+		maxMetrics:         DefaultMaxMetrics,
+		maxSeriesPerMetric: DefaultMaxSeriesPerMetric,
+	}
+}
+
+// SetLimits overrides the cardinality limits
+func (r *MetricsRegistry) SetLimits(maxMetrics, maxSeriesPerMetric int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxMetrics = maxMetrics
+	r.maxSeriesPerMetric = maxSeriesPerMetric
+}
+
+// Push records a value. Counters are incremented by value, gauges are set
+// to value and histograms observe value. The first push of a name fixes its
+// type and label names; later pushes must match.
+func (r *MetricsRegistry) Push(name, kind string, value float64, labels map[string]string) error {
+	return r.PushWithBuckets(name, kind, value, labels, nil)
+}
+
+// Observe records value into a histogram. buckets (upper bounds) are only
+// used when the histogram is created; nil means the Prometheus defaults.
+func (r *MetricsRegistry) Observe(name string, value float64, labels map[string]string, buckets []float64) error {
+	return r.PushWithBuckets(name, metricTypeHistogram, value, labels, buckets)
+}
+
+// PushWithBuckets is Push with explicit histogram buckets. Buckets given for
+// an existing histogram must match the ones it was created with.
+func (r *MetricsRegistry) PushWithBuckets(name, kind string, value float64, labels map[string]string, buckets []float64) error {
+	if kind == "" {
+		kind = defaultMetricType
+	}
+	if err := validateMetric(name, kind, labels); err != nil {
+		return err
+	}
+	if kind == metricTypeCounter && value < 0 {
+		return fmt.Errorf("counter %s cannot be decreased", name)
+	}
+	if len(buckets) > 0 {
+		if kind != metricTypeHistogram {
+			return fmt.Errorf("buckets are only valid for histograms")
+		}
+		if err := validateBuckets(buckets); err != nil {
+			return err
+		}
+	}
+
+	labelNames := sortedKeys(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.metrics[name]
+	if !ok {
+		if len(r.metrics) >= r.maxMetrics {
+			return fmt.Errorf("metric limit reached (%d metrics)", r.maxMetrics)
+		}
+		var err error
+		if m, err = r.create(name, kind, labelNames, buckets); err != nil {
+			return err
+		}
+		r.metrics[name] = m
+	}
+
+	if m.kind != kind {
+		return fmt.Errorf("metric %s is a %s, not a %s", name, m.kind, kind)
+	}
+	if strings.Join(m.labelNames, ",") != strings.Join(labelNames, ",") {
+		return fmt.Errorf("metric %s has labels [%s], got [%s]", name, strings.Join(m.labelNames, ", "), strings.Join(labelNames, ", "))
+	}
+	if len(buckets) > 0 && !slices.Equal(m.buckets, buckets) {
+		return fmt.Errorf("histogram %s was created with buckets %v", name, m.buckets)
+	}
+
+	values := make([]string, len(labelNames))
+	for i, k := range labelNames {
+		values[i] = labels[k]
+	}
+	seriesKey := strings.Join(values, labelSeriesSeparator)
+	if _, seen := m.series[seriesKey]; !seen {
+		if len(m.series) >= r.maxSeriesPerMetric {
+			return fmt.Errorf("series limit reached for %s (%d series)", name, r.maxSeriesPerMetric)
+		}
+		m.series[seriesKey] = struct{}{}
+	}
+
+	switch kind {
+	case metricTypeCounter:
+		m.counter.WithLabelValues(values...).Add(value)
+	case metricTypeGauge:
+		m.gauge.WithLabelValues(values...).Set(value)
+	case metricTypeHistogram:
+		m.histogram.WithLabelValues(values...).Observe(value)
+	}
+
+	return nil
+}
+
+// create builds and registers a new metric vector
+func (r *MetricsRegistry) create(name, kind string, labelNames []string, buckets []float64) (*pushedMetric, error) {
+	m := &pushedMetric{
+		kind:       kind,
+		labelNames: labelNames,
+		series:     make(map[string]struct{}),
+	}
+	help := "Pushed via Forge API"
+
+	var collector prometheus.Collector
+	switch kind {
+	case metricTypeCounter:
+		m.counter = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labelNames)
+		collector = m.counter
+	case metricTypeGauge:
+		m.gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labelNames)
+		collector = m.gauge
+	case metricTypeHistogram:
+		if len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+		m.buckets = buckets
+		m.histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labelNames)
+		collector = m.histogram
+	}
+
+	if err := r.registerer.Register(collector); err != nil {
+		return nil, fmt.Errorf("failed to register metric %s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ValidateMetric checks a metric definition without recording a value
+func ValidateMetric(name, kind string, labels map[string]string) error {
+	return validateMetric(name, kind, labels)
+}
+
+// validateMetric checks names, type and labels of a push
+func validateMetric(name, kind string, labels map[string]string) error {
+	if !metricNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid metric name: %q", name)
+	}
+	if strings.HasPrefix(name, reservedMetricPrefix) {
+		return fmt.Errorf("metric names starting with %q are reserved", reservedMetricPrefix)
+	}
+	switch kind {
+	case metricTypeCounter, metricTypeGauge, metricTypeHistogram:
+	default:
+		return fmt.Errorf("invalid metric type: %q (must be counter, gauge or histogram)", kind)
+	}
+	for k := range labels {
+		if !labelNamePattern.MatchString(k) || strings.HasPrefix(k, "__") {
+			return fmt.Errorf("invalid label name: %q", k)
+		}
+	}
+	return nil
+}
+
+// validateBuckets checks histogram upper bounds
+func validateBuckets(buckets []float64) error {
+	if len(buckets) > maxHistogramBuckets {
+		return fmt.Errorf("too many buckets (max %d)", maxHistogramBuckets)
+	}
+	for i, b := range buckets {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return fmt.Errorf("buckets must be finite")
+		}
+		if i > 0 && b <= buckets[i-1] {
+			return fmt.Errorf("buckets must be strictly increasing")
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,212 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PrometheusClient queries the bundled Prometheus HTTP API
+type PrometheusClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewPrometheusClient(url string) *PrometheusClient {
+	return &PrometheusClient{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Sample is a single value of a series at a point in time
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Series is a labeled series with one (instant) or more (range) samples
+type Series struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// promResponse represents the Prometheus query API response format
+type promResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type promVectorResult struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]any            `json:"value"`
+}
+
+// Query evaluates an instant PromQL query. at defaults to now.
+func (c *PrometheusClient) Query(ctx context.Context, query string, at time.Time) ([]Series, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	params := url.Values{}
+	params.Set("query", query)
+	if !at.IsZero() {
+		params.Set("time", formatPromTime(at))
+	}
+
+	resp, err := c.get(ctx, "/api/v1/query", params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Data.ResultType {
+	case "vector":
+		var results []promVectorResult
+		if err := json.Unmarshal(resp.Data.Result, &results); err != nil {
+			return nil, fmt.Errorf("failed to decode prometheus vector: %w", err)
+		}
+		series := make([]Series, 0, len(results))
+		for _, r := range results {
+			sample, err := parsePromSample(r.Value)
+			if err != nil {
+				return nil, err
+			}
+			series = append(series, Series{Labels: r.Metric, Samples: []Sample{sample}})
+		}
+		return series, nil
+	case "scalar":
+		var value [2]any
+		if err := json.Unmarshal(resp.Data.Result, &value); err != nil {
+			return nil, fmt.Errorf("failed to decode prometheus scalar: %w", err)
+		}
+		sample, err := parsePromSample(value)
+		if err != nil {
+			return nil, err
+		}
+		return []Series{{Labels: map[string]string{}, Samples: []Sample{sample}}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported result type: %s", resp.Data.ResultType)
+	}
+}
+
+type promMatrixResult struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]any          `json:"values"`
+}
+
+// QueryRange evaluates a PromQL query over [start, end] at the given step
+func (c *PrometheusClient) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]Series, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	// Prometheus rejects queries above 11000 points per series
+	if end.Sub(start)/step > 11000 {
+		return nil, fmt.Errorf("too many points: increase step or reduce range")
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", formatPromTime(start))
+	params.Set("end", formatPromTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	resp, err := c.get(ctx, "/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unsupported result type: %s", resp.Data.ResultType)
+	}
+
+	var results []promMatrixResult
+	if err := json.Unmarshal(resp.Data.Result, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode prometheus matrix: %w", err)
+	}
+
+	series := make([]Series, 0, len(results))
+	for _, r := range results {
+		s := Series{Labels: r.Metric, Samples: make([]Sample, 0, len(r.Values))}
+		for _, v := range r.Values {
+			sample, err := parsePromSample(v)
+			if err != nil {
+				return nil, err
+			}
+			s.Samples = append(s.Samples, sample)
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+// get performs a GET against the Prometheus API and checks the response status
+func (c *PrometheusClient) get(ctx context.Context, path string, params url.Values) (*promResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result promResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("prometheus query failed: %d %s", resp.StatusCode, bytes.TrimSpace(body))
+		}
+		return nil, fmt.Errorf("failed to decode prometheus response: %w", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s: %s", result.ErrorType, result.Error)
+	}
+
+	return &result, nil
+}
+
+// parsePromSample decodes a [<unix seconds>, "<value>"] pair
+func parsePromSample(pair [2]any) (Sample, error) {
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return Sample{}, fmt.Errorf("invalid sample timestamp")
+	}
+	str, ok := pair[1].(string)
+	if !ok {
+		return Sample{}, fmt.Errorf("invalid sample value")
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("invalid sample value: %w", err)
+	}
+	return Sample{
+		Timestamp: time.UnixMilli(int64(ts * 1000)),
+		Value:     value,
+	}, nil
+}
+
+// formatPromTime formats t as fractional unix seconds
+func formatPromTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+}
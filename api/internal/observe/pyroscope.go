@@ -0,0 +1,82 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// ErrPyroscopeUnavailable is returned when the Pyroscope server cannot be reached
+var ErrPyroscopeUnavailable = errors.New("pyroscope unavailable")
+
+// profileNamePattern matches a Pyroscope application name with optional
+// labels, e.g. checkout.cpu{env=prod,region=eu}
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,128}(\{[^{}]{0,512}\})?$`)
+
+// profileIngestParams are the ingest query parameters forwarded to Pyroscope
+var profileIngestParams = []string{
+	"name", "from", "until", "format", "sampleRate", "spyName", "units", "aggregationType",
+}
+
+// PyroscopeClient relays profiles to Pyroscope's ingest API
+type PyroscopeClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewPyroscopeClient(url string) *PyroscopeClient {
+	return &PyroscopeClient{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ValidProfileName reports whether name is a valid Pyroscope application name
+func ValidProfileName(name string) bool {
+	return profileNamePattern.MatchString(name)
+}
+
+// Ingest forwards one profile upload. query holds the Pyroscope ingest
+// parameters (name is required); contentType is passed through so pprof
+// multipart uploads and folded/collapsed text both work.
+func (c *PyroscopeClient) Ingest(ctx context.Context, query url.Values, contentType string, body []byte) error {
+	if !ValidProfileName(query.Get("name")) {
+		return fmt.Errorf("invalid profile name %q", query.Get("name"))
+	}
+
+	params := url.Values{}
+	for _, key := range profileIngestParams {
+		if v := query.Get(key); v != "" {
+			params.Set(key, v)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url+"/ingest?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPyroscopeUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("%w: status %d: %s", ErrPyroscopeUnavailable, resp.StatusCode, bytes.TrimSpace(msg))
+		}
+		return fmt.Errorf("pyroscope rejected profile: status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
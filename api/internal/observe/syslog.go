@@ -0,0 +1,339 @@
+package observe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+// Syslog listener limits
+const (
+	maxSyslogMessageSize = 64 * 1024
+	syslogIdleTimeout    = 5 * time.Minute
+	maxSyslogConnections = 256
+)
+
+// syslogSeverities maps syslog severities (0-7) to Forge log levels
+var syslogSeverities = [8]string{"critical", "critical", "critical", "error", "warn", "info", "info", "debug"}
+
+// syslogFacilities names the standard syslog facilities (0-23)
+var syslogFacilities = [24]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// SyslogMessage is a parsed RFC 3164 or RFC 5424 message
+type SyslogMessage struct {
+	Facility  int
+	Severity  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Message   string
+}
+
+// ParseSyslog parses an RFC 5424 message, falling back to RFC 3164 (BSD).
+// Missing timestamps default to now.
+func ParseSyslog(raw string) (SyslogMessage, error) {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+	if !strings.HasPrefix(raw, "<") {
+		return SyslogMessage{}, errors.New("missing priority")
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return SyslogMessage{}, errors.New("invalid priority")
+	}
+	pri, err := strconv.Atoi(raw[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return SyslogMessage{}, errors.New("invalid priority")
+	}
+
+	msg := SyslogMessage{Facility: pri / 8, Severity: pri % 8}
+	rest := raw[end+1:]
+
+	if strings.HasPrefix(rest, "1 ") {
+		parseRFC5424(&msg, rest[2:])
+	} else {
+		parseRFC3164(&msg, rest)
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	return msg, nil
+}
+
+// parseRFC5424 parses "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG"
+func parseRFC5424(msg *SyslogMessage, s string) {
+	fields := strings.SplitN(s, " ", 6)
+	for len(fields) < 6 {
+		fields = append(fields, "-")
+	}
+	nilValue := func(v string) string {
+		if v == "-" {
+			return ""
+		}
+		return v
+	}
+
+	if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		msg.Timestamp = ts
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+	msg.ProcID = nilValue(fields[3])
+	msg.MsgID = nilValue(fields[4])
+
+	// Skip structured data: "-" or one or more [id k="v"] elements
+	rest := fields[5]
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		for strings.HasPrefix(rest, "[") {
+			i := sdElementEnd(rest)
+			if i < 0 {
+				break
+			}
+			rest = rest[i+1:]
+		}
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	msg.Message = strings.TrimPrefix(rest, "\ufeff") // UTF-8 BOM
+}
+
+// sdElementEnd returns the index of the "]" closing the SD element at s[0],
+// honoring escaped characters inside quoted values
+func sdElementEnd(s string) int {
+	inQuotes := false
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case ']':
+			if !inQuotes {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseRFC3164 parses "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG"
+func parseRFC3164(msg *SyslogMessage, s string) {
+	if len(s) >= 16 && s[15] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, s[:15], time.Local); err == nil {
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// Messages from late December arriving in January
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts
+			s = s[16:]
+
+			if host, rest, ok := strings.Cut(s, " "); ok && !strings.HasSuffix(host, ":") {
+				msg.Hostname = host
+				s = rest
+			}
+		}
+	}
+
+	// TAG is up to 32 alphanumerics, optionally followed by [PID], then ":"
+	if colon := strings.Index(s, ":"); colon > 0 && colon <= 48 && !strings.ContainsAny(s[:colon], " ") {
+		tag := s[:colon]
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		msg.AppName = tag
+		s = strings.TrimPrefix(s[colon+1:], " ")
+	}
+	msg.Message = s
+}
+
+// SyslogListener receives syslog over UDP and TCP and forwards messages to Loki
+type SyslogListener struct {
+	addr  string
+	loki  *LokiClient
+	udp   net.PacketConn
+	tcp   net.Listener
+	conns chan struct{} // TCP connection slots
+	wg    sync.WaitGroup
+}
+
+// NewSyslogListener creates a listener for addr (e.g. ":1514")
+func NewSyslogListener(addr string, loki *LokiClient) *SyslogListener {
+	return &SyslogListener{
+		addr:  addr,
+		loki:  loki,
+		conns: make(chan struct{}, maxSyslogConnections),
+	}
+}
+
+// Start binds the UDP and TCP sockets and starts serving
+func (l *SyslogListener) Start() error {
+	udp, err := net.ListenPacket("udp", l.addr)
+	if err != nil {
+		return fmt.Errorf("syslog udp listen: %w", err)
+	}
+	tcp, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("syslog tcp listen: %w", err)
+	}
+	l.udp, l.tcp = udp, tcp
+
+	l.wg.Add(2)
+	go l.serveUDP()
+	go l.serveTCP()
+
+	log := logger.Get()
+	log.Info().Str("addr", l.addr).Msg("Syslog listener started (udp+tcp)")
+	return nil
+}
+
+// Close stops both listeners
+func (l *SyslogListener) Close() error {
+	errUDP := l.udp.Close()
+	errTCP := l.tcp.Close()
+	l.wg.Wait()
+	return errors.Join(errUDP, errTCP)
+}
+
+func (l *SyslogListener) serveUDP() {
+	defer l.wg.Done()
+
+	buf := make([]byte, maxSyslogMessageSize)
+	for {
+		n, _, err := l.udp.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		l.handle("udp", string(buf[:n]))
+	}
+}
+
+func (l *SyslogListener) serveTCP() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.tcp.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case l.conns <- struct{}{}:
+		default:
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer func() { <-l.conns }()
+			l.serveConn(conn)
+		}()
+	}
+}
+
+// serveConn reads messages framed by octet counting ("<len> <msg>", RFC 6587)
+// or by newlines
+func (l *SyslogListener) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReaderSize(conn, 4096)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(syslogIdleTimeout))
+
+		first, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+
+		var raw string
+		if first[0] >= '1' && first[0] <= '9' {
+			lenStr, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+			if err != nil || n <= 0 || n > maxSyslogMessageSize {
+				metrics.SyslogMessagesTotal.WithLabelValues("tcp", "invalid").Inc()
+				return
+			}
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			raw = string(buf)
+		} else {
+			line, err := r.ReadSlice('\n')
+			if errors.Is(err, bufio.ErrBufferFull) {
+				// Overlong line: keep what fits and discard the rest
+				raw = string(line)
+				for errors.Is(err, bufio.ErrBufferFull) {
+					_, err = r.ReadSlice('\n')
+				}
+			} else if err != nil && len(line) == 0 {
+				return
+			} else {
+				raw = string(line)
+			}
+		}
+
+		if strings.TrimSpace(raw) != "" {
+			l.handle("tcp", raw)
+		}
+	}
+}
+
+// handle parses a message and pushes it to Loki
+func (l *SyslogListener) handle(transport, raw string) {
+	msg, err := ParseSyslog(raw)
+	if err != nil {
+		metrics.SyslogMessagesTotal.WithLabelValues(transport, "invalid").Inc()
+		return
+	}
+
+	labels := map[string]string{
+		"job":      "syslog",
+		"facility": syslogFacilities[msg.Facility],
+	}
+	if msg.Hostname != "" {
+		labels["host"] = msg.Hostname
+	}
+	if msg.AppName != "" {
+		labels["app"] = msg.AppName
+	}
+
+	err = l.loki.PushEntry(context.Background(), Entry{
+		Level:     syslogSeverities[msg.Severity],
+		Message:   msg.Message,
+		Labels:    labels,
+		Timestamp: msg.Timestamp,
+	})
+	if err != nil {
+		metrics.SyslogMessagesTotal.WithLabelValues(transport, "dropped").Inc()
+		return
+	}
+	metrics.SyslogMessagesTotal.WithLabelValues(transport, "forwarded").Inc()
+}
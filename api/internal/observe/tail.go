@@ -0,0 +1,69 @@
+package observe
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// lokiTailMessage represents a message on Loki's tail websocket
+type lokiTailMessage struct {
+	Streams        []LokiStream `json:"streams"`
+	DroppedEntries []struct {
+		Labels    map[string]string `json:"labels"`
+		Timestamp string            `json:"timestamp"`
+	} `json:"dropped_entries"`
+}
+
+// Tail follows a LogQL stream selector via Loki's tail websocket and calls fn
+// for every batch of new entries. It blocks until ctx is cancelled, the
+// connection fails, or fn returns an error.
+func (c *LokiClient) Tail(ctx context.Context, query string, start time.Time, fn func([]QueryStream) error) error {
+	if query == "" {
+		return fmt.Errorf("query is required")
+	}
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+
+	wsURL := strings.Replace(c.url, "http", "ws", 1) + "/loki/api/v1/tail?" + params.Encode()
+	config, err := websocket.NewConfig(wsURL, c.url)
+	if err != nil {
+		return err
+	}
+
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("loki tail connect failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop when the client goes away
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var msg lokiTailMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("loki tail read failed: %w", err)
+		}
+
+		if err := fn(parseStreams(msg.Streams)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TempoClient pushes spans to Tempo using OTLP/HTTP (JSON encoding)
+// and queries traces through the Tempo HTTP API
+type TempoClient struct {
+	url         string
+	queryURL    string
+	serviceName string
+	client      *http.Client
+}
+
+// NewTempoClient creates a client exporting spans to url (OTLP/HTTP) and
+// querying traces at queryURL
+func NewTempoClient(url, queryURL string) *TempoClient {
+	return &TempoClient{
+		url:         url,
+		queryURL:    queryURL,
+		serviceName: "forge",
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Span is a single span to export
+type Span struct {
+	Name         string
+	TraceID      string // 32 hex chars, generated if empty
+	SpanID       string // 16 hex chars, generated if empty
+	ParentSpanID string
+	Start        time.Time
+	Duration     time.Duration
+	Attributes   map[string]string
+}
+
+// OTLP JSON wire format (subset used by Forge)
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// spanKindInternal is OTLP SPAN_KIND_INTERNAL
+const spanKindInternal = 1
+
+// Push exports a span to Tempo and returns it with any generated IDs filled in.
+// The "service.name" attribute, if present, sets the resource service name.
+func (c *TempoClient) Push(ctx context.Context, span Span) (Span, error) {
+	if span.Name == "" {
+		return span, fmt.Errorf("span name is required")
+	}
+
+	var err error
+	if span.TraceID == "" {
+		if span.TraceID, err = randomHex(16); err != nil {
+			return span, err
+		}
+	} else if !isHexID(span.TraceID, 32) {
+		return span, fmt.Errorf("invalid trace_id: must be 32 hex characters")
+	}
+	if span.SpanID == "" {
+		if span.SpanID, err = randomHex(8); err != nil {
+			return span, err
+		}
+	} else if !isHexID(span.SpanID, 16) {
+		return span, fmt.Errorf("invalid span_id: must be 16 hex characters")
+	}
+	if span.ParentSpanID != "" && !isHexID(span.ParentSpanID, 16) {
+		return span, fmt.Errorf("invalid parent_span_id: must be 16 hex characters")
+	}
+	if span.Start.IsZero() {
+		span.Start = time.Now().Add(-span.Duration)
+	}
+
+	serviceName := c.serviceName
+	attrs := make([]otlpKeyValue, 0, len(span.Attributes))
+	keys := make([]string, 0, len(span.Attributes))
+	for k := range span.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k == "service.name" {
+			serviceName = span.Attributes[k]
+			continue
+		}
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpValue{StringValue: span.Attributes[k]}})
+	}
+
+	payload := otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "forge-api"},
+				Spans: []otlpSpan{{
+					TraceID:           span.TraceID,
+					SpanID:            span.SpanID,
+					ParentSpanID:      span.ParentSpanID,
+					Name:              span.Name,
+					Kind:              spanKindInternal,
+					StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+					EndTimeUnixNano:   strconv.FormatInt(span.Start.Add(span.Duration).UnixNano(), 10),
+					Attributes:        attrs,
+				}},
+			}},
+		}},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return span, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.url+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return span, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return span, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return span, fmt.Errorf("tempo push failed: %d %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return span, nil
+}
+
+// randomHex returns n random bytes hex-encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isHexID reports whether s is a non-zero hex ID of the given length
+func isHexID(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
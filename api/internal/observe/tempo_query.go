@@ -0,0 +1,253 @@
+package observe
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTraceNotFound is returned when Tempo has no trace with the given ID
+var ErrTraceNotFound = errors.New("trace not found")
+
+// ErrInvalidTraceID is returned for malformed trace IDs
+var ErrInvalidTraceID = errors.New("invalid trace_id: must be 32 hex characters")
+
+// TraceSearchOptions filters a trace search. Service and Tags are
+// combined into a tag search; Query (TraceQL) takes precedence if set.
+type TraceSearchOptions struct {
+	Service     string
+	Tags        map[string]string
+	Query       string
+	Start       time.Time
+	End         time.Time
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Limit       int
+}
+
+// TraceSummary is a search hit
+type TraceSummary struct {
+	TraceID     string
+	RootService string
+	RootName    string
+	Start       time.Time
+	Duration    time.Duration
+}
+
+// TraceSpan is a span of a retrieved trace
+type TraceSpan struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Service      string
+	Start        time.Time
+	Duration     time.Duration
+	Attributes   map[string]string
+}
+
+// SearchTraces finds recent traces matching the options
+func (c *TempoClient) SearchTraces(ctx context.Context, opts TraceSearchOptions) ([]TraceSummary, error) {
+	params := url.Values{}
+	if opts.Query != "" {
+		params.Set("q", opts.Query)
+	} else {
+		var tags []string
+		if opts.Service != "" {
+			tags = append(tags, "service.name="+opts.Service)
+		}
+		keys := make([]string, 0, len(opts.Tags))
+		for k := range opts.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if strings.ContainsAny(k, " =") || strings.Contains(opts.Tags[k], " ") {
+				return nil, fmt.Errorf("invalid tag %q: keys and values must not contain spaces", k)
+			}
+			tags = append(tags, k+"="+opts.Tags[k])
+		}
+		if len(tags) > 0 {
+			params.Set("tags", strings.Join(tags, " "))
+		}
+	}
+	if !opts.Start.IsZero() {
+		params.Set("start", strconv.FormatInt(opts.Start.Unix(), 10))
+	}
+	if !opts.End.IsZero() {
+		params.Set("end", strconv.FormatInt(opts.End.Unix(), 10))
+	}
+	if opts.MinDuration > 0 {
+		params.Set("minDuration", opts.MinDuration.String())
+	}
+	if opts.MaxDuration > 0 {
+		params.Set("maxDuration", opts.MaxDuration.String())
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	var resp struct {
+		Traces []struct {
+			TraceID           string `json:"traceID"`
+			RootServiceName   string `json:"rootServiceName"`
+			RootTraceName     string `json:"rootTraceName"`
+			StartTimeUnixNano string `json:"startTimeUnixNano"`
+			DurationMs        int64  `json:"durationMs"`
+		} `json:"traces"`
+	}
+	if err := c.query(ctx, "/api/search?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]TraceSummary, 0, len(resp.Traces))
+	for _, t := range resp.Traces {
+		startNs, _ := strconv.ParseInt(t.StartTimeUnixNano, 10, 64)
+		results = append(results, TraceSummary{
+			TraceID:     padTraceID(t.TraceID),
+			RootService: t.RootServiceName,
+			RootName:    t.RootTraceName,
+			Start:       time.Unix(0, startNs),
+			Duration:    time.Duration(t.DurationMs) * time.Millisecond,
+		})
+	}
+	return results, nil
+}
+
+// tempoTrace is the OTLP JSON returned by /api/traces/{id}
+type tempoTrace struct {
+	Batches []struct {
+		Resource struct {
+			Attributes []tempoKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []tempoSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"batches"`
+}
+
+type tempoSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []tempoKeyValue `json:"attributes"`
+}
+
+type tempoKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// GetTrace returns all spans of a trace ordered by start time
+func (c *TempoClient) GetTrace(ctx context.Context, traceID string) ([]TraceSpan, error) {
+	if !isHexID(traceID, 32) {
+		return nil, ErrInvalidTraceID
+	}
+
+	var trace tempoTrace
+	if err := c.query(ctx, "/api/traces/"+traceID, &trace); err != nil {
+		return nil, err
+	}
+
+	var spans []TraceSpan
+	for _, batch := range trace.Batches {
+		resourceAttrs := flattenAttributes(batch.Resource.Attributes)
+		service := resourceAttrs["service.name"]
+		for _, scope := range batch.ScopeSpans {
+			for _, s := range scope.Spans {
+				startNs, _ := strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+				endNs, _ := strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+				spans = append(spans, TraceSpan{
+					TraceID:      normalizeTraceID(s.TraceID),
+					SpanID:       normalizeTraceID(s.SpanID),
+					ParentSpanID: normalizeTraceID(s.ParentSpanID),
+					Name:         s.Name,
+					Service:      service,
+					Start:        time.Unix(0, startNs),
+					Duration:     time.Duration(endNs - startNs),
+					Attributes:   flattenAttributes(s.Attributes),
+				})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+	return spans, nil
+}
+
+// query performs a GET against the Tempo query API
+func (c *TempoClient) query(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.queryURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tempo request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrTraceNotFound
+	}
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("tempo query failed: %d %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// normalizeTraceID converts base64 IDs (as returned in Tempo's OTLP JSON) to hex
+func normalizeTraceID(id string) string {
+	if id == "" {
+		return ""
+	}
+	if _, err := hex.DecodeString(id); err == nil && (len(id) == 16 || len(id) == 32) {
+		return strings.ToLower(id)
+	}
+	if b, err := base64.StdEncoding.DecodeString(id); err == nil {
+		return hex.EncodeToString(b)
+	}
+	return id
+}
+
+// padTraceID restores leading zeros that Tempo trims from search results
+func padTraceID(id string) string {
+	if len(id) < 32 {
+		return strings.Repeat("0", 32-len(id)) + id
+	}
+	return id
+}
+
+// flattenAttributes converts OTLP key/values to strings
+func flattenAttributes(kvs []tempoKeyValue) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		for _, v := range kv.Value {
+			attrs[kv.Key] = fmt.Sprint(v)
+			break
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,287 @@
+// Package operations runs long-lived actions in the background with a
+// uniform status shape
+//
+// Subsystems start an operation with a function that reports progress and
+// log lines through the Operation handle. Clients poll
+// /api/v1/operations/{id} for state, progress and logs, and can cancel.
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operation states
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+const (
+	// maxLogLines bounds the log kept per operation (oldest lines are dropped)
+	maxLogLines = 500
+	// maxFinished is how many finished operations are retained for polling
+	maxFinished = 200
+)
+
+// LogLine is a timestamped operation log entry
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Operation is a background action and its progress
+type Operation struct {
+	mu         sync.RWMutex
+	id         string
+	kind       string
+	target     string
+	state      string
+	progress   float64
+	logs       []LogLine
+	err        string
+	result     any
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	cancel     context.CancelFunc
+}
+
+// Snapshot is the JSON view of an operation
+type Snapshot struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Target     string     `json:"target,omitempty"`
+	State      string     `json:"state"`
+	Progress   float64    `json:"progress"` // percentage 0-100
+	Error      string     `json:"error,omitempty"`
+	Result     any        `json:"result,omitempty"`
+	Logs       []LogLine  `json:"logs,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ID returns the operation ID
+func (o *Operation) ID() string {
+	return o.id
+}
+
+// SetProgress records completion as a percentage (clamped to 0-100)
+func (o *Operation) SetProgress(pct float64) {
+	if pct < 0 {
+		pct = 0
+	}
+	if pct > 100 {
+		pct = 100
+	}
+	o.mu.Lock()
+	o.progress = pct
+	o.mu.Unlock()
+}
+
+// Logf appends a line to the operation log
+func (o *Operation) Logf(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.logs = append(o.logs, LogLine{Time: time.Now().UTC(), Message: fmt.Sprintf(format, args...)})
+	if len(o.logs) > maxLogLines {
+		o.logs = o.logs[len(o.logs)-maxLogLines:]
+	}
+}
+
+// SetResult attaches a result returned to clients once finished
+func (o *Operation) SetResult(result any) {
+	o.mu.Lock()
+	o.result = result
+	o.mu.Unlock()
+}
+
+// Done reports whether the operation has finished
+func (o *Operation) Done() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return isFinal(o.state)
+}
+
+// Snapshot returns the current state; logs are included when withLogs is set
+func (o *Operation) Snapshot(withLogs bool) Snapshot {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	s := Snapshot{
+		ID:        o.id,
+		Kind:      o.kind,
+		Target:    o.target,
+		State:     o.state,
+		Progress:  o.progress,
+		Error:     o.err,
+		Result:    o.result,
+		CreatedAt: o.createdAt,
+	}
+	if !o.startedAt.IsZero() {
+		t := o.startedAt
+		s.StartedAt = &t
+	}
+	if !o.finishedAt.IsZero() {
+		t := o.finishedAt
+		s.FinishedAt = &t
+	}
+	if withLogs {
+		s.Logs = append([]LogLine(nil), o.logs...)
+	}
+	return s
+}
+
+// Func is the body of an operation. Returning nil marks it succeeded;
+// returning after ctx is cancelled marks it cancelled.
+type Func func(ctx context.Context, op *Operation) error
+
+// Manager starts and tracks operations
+type Manager struct {
+	mu  sync.RWMutex
+	ops map[string]*Operation
+}
+
+// NewManager creates an empty operations manager
+func NewManager() *Manager {
+	return &Manager{ops: make(map[string]*Operation)}
+}
+
+// Start runs fn in the background and returns its operation immediately
+func (m *Manager) Start(kind, target string, fn Func) *Operation {
+	ctx, cancel := context.WithCancel(context.Background())
+	op := &Operation{
+		id:        newID(),
+		kind:      kind,
+		target:    target,
+		state:     StatePending,
+		createdAt: time.Now().UTC(),
+		cancel:    cancel,
+	}
+
+	m.mu.Lock()
+	m.ops[op.id] = op
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	go m.run(ctx, op, fn)
+	return op
+}
+
+// run executes fn and records the final state
+func (m *Manager) run(ctx context.Context, op *Operation, fn Func) {
+	defer op.cancel()
+
+	op.mu.Lock()
+	op.state = StateRunning
+	op.startedAt = time.Now().UTC()
+	op.mu.Unlock()
+
+	err := safeRun(ctx, op, fn)
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.finishedAt = time.Now().UTC()
+	switch {
+	case ctx.Err() != nil && (err == nil || errors.Is(err, context.Canceled)):
+		op.state = StateCancelled
+	case err != nil:
+		op.state = StateFailed
+		op.err = err.Error()
+	default:
+		op.state = StateSucceeded
+		op.progress = 100
+	}
+}
+
+// safeRun turns a panic in fn into a failed operation
+func safeRun(ctx context.Context, op *Operation, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("operation panicked: %v", r)
+		}
+	}()
+	return fn(ctx, op)
+}
+
+// Get returns an operation by ID
+func (m *Manager) Get(id string) (*Operation, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	op, ok := m.ops[id]
+	return op, ok
+}
+
+// List returns operations, newest first, optionally filtered by kind and state
+func (m *Manager) List(kind, state string) []Snapshot {
+	m.mu.RLock()
+	ops := make([]*Operation, 0, len(m.ops))
+	for _, op := range m.ops {
+		ops = append(ops, op)
+	}
+	m.mu.RUnlock()
+
+	list := make([]Snapshot, 0, len(ops))
+	for _, op := range ops {
+		s := op.Snapshot(false)
+		if (kind == "" || s.Kind == kind) && (state == "" || s.State == state) {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Cancel requests cancellation of a running operation
+func (m *Manager) Cancel(id string) error {
+	op, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("operation not found: %s", id)
+	}
+	if op.Done() {
+		return fmt.Errorf("operation already finished: %s", id)
+	}
+	op.Logf("cancellation requested")
+	op.cancel()
+	return nil
+}
+
+// pruneLocked drops the oldest finished operations beyond maxFinished; caller holds m.mu
+func (m *Manager) pruneLocked() {
+	var finished []*Operation
+	for _, op := range m.ops {
+		if op.Done() {
+			finished = append(finished, op)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool { return finished[i].createdAt.Before(finished[j].createdAt) })
+	for _, op := range finished[:len(finished)-maxFinished] {
+		delete(m.ops, op.id)
+	}
+}
+
+func isFinal(state string) bool {
+	return state == StateSucceeded || state == StateFailed || state == StateCancelled
+}
+
+// newID returns a random operation ID
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "op_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+
+	"connectrpc.com/connect"
+	"github.com/forge/api/internal/auth"
+)
+
+// ErrRateLimited is returned for requests over a limit
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+type clientAddrKey struct{}
+
+// WithClientAddr returns ctx carrying the client's address, resolved behind
+// trusted proxies, for ClientID
+func WithClientAddr(ctx context.Context, addr netip.Addr) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, addr)
+}
+
+// ClientID identifies the caller: its key, its OIDC or client certificate
+// subject or, for anonymous requests, its address from WithClientAddr, else
+// the connection's peer. X-Forwarded-For is not read here: any client can
+// set it, so only hops added by trusted proxies count.
+func ClientID(ctx context.Context, id *auth.Identity, remoteAddr string) string {
+	if id != nil {
+		if id.Subject != "" {
+			return id.Method + ":" + id.Subject
+		}
+		return "key:" + id.KeyID
+	}
+	if addr, ok := ctx.Value(clientAddrKey{}).(netip.Addr); ok {
+		return "ip:" + addr.String()
+	}
+	ip := remoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return "ip:" + ip
+}
+
+// Overrides returns the caller's own limits
+func Overrides(id *auth.Identity) Limits {
+	if id == nil {
+		return nil
+	}
+	return Limits(id.RateLimits)
+}
+
+// Interceptor rate limits Connect RPCs. It must come after the auth
+// interceptor so the caller's identity is known.
+type Interceptor struct {
+	limiter *Limiter
+}
+
+// NewInterceptor creates an interceptor enforcing limiter
+func NewInterceptor(limiter *Limiter) *Interceptor {
+	return &Interceptor{limiter: limiter}
+}
+
+// WrapUnary limits unary RPCs
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := i.allow(ctx, req.Spec().Procedure, req.Peer().Addr); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient leaves outgoing streams alone
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler limits streaming RPCs when they start
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := i.allow(ctx, conn.Spec().Procedure, conn.Peer().Addr); err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// allow returns a resource exhausted error (HTTP 429) with Retry-After
+// for RPCs over a limit
+func (i *Interceptor) allow(ctx context.Context, procedure, addr string) error {
+	id := auth.FromContext(ctx)
+	ok, retry := i.limiter.Allow(ctx, ProcedureClass(procedure), ClientID(ctx, id, addr), Overrides(id))
+	if ok {
+		return nil
+	}
+	err := connect.NewError(connect.CodeResourceExhausted, ErrRateLimited)
+	err.Meta().Set("Retry-After", RetryAfter(retry))
+	return err
+}
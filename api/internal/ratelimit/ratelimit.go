@@ -0,0 +1,296 @@
+// Package ratelimit limits request rates per client and globally
+//
+// Requests fall into endpoint classes with their own limits, so a client
+// flooding log ingestion does not use up its budget for configuration
+// changes, and expensive queries (SQL, LogQL, PromQL, trace lookups) that
+// load MySQL, Loki and Prometheus are limited tighter than plain reads:
+//
+//   - read: GET requests and read-only RPCs
+//   - write: other mutating requests
+//   - query: database, log, metric and trace queries
+//   - ingest: logs, metrics, traces and error events sent by applications
+//
+// Clients are API keys or OIDC users, or the source IP of anonymous
+// requests. Limits are requests per minute in fixed one-minute windows,
+// counted in memory or, to share them between API replicas, in Redis.
+// Per-key limits set when a key is issued override the per-client ones.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+// Endpoint classes
+const (
+	ClassRead   = "read"
+	ClassWrite  = "write"
+	ClassQuery  = "query"
+	ClassIngest = "ingest"
+)
+
+// Classes lists the endpoint classes
+var Classes = []string{ClassRead, ClassWrite, ClassQuery, ClassIngest}
+
+// ValidClass reports whether class is an endpoint class
+func ValidClass(class string) bool {
+	for _, c := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Window is the length of a rate limit window; limits are per Window
+const Window = time.Minute
+
+// DefaultClientLimits are the per-client limits per minute
+var DefaultClientLimits = Limits{ClassRead: 600, ClassWrite: 120, ClassQuery: 120, ClassIngest: 6000}
+
+// Limits are requests per minute by endpoint class; a missing or zero
+// class is unlimited
+type Limits map[string]int
+
+// ParseLimits parses "read=600,query=60"
+func ParseLimits(s string) (Limits, error) {
+	limits := make(Limits)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		class, value, ok := strings.Cut(pair, "=")
+		class = strings.TrimSpace(class)
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || !ValidClass(class) || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid rate limit %q, want class=requests_per_minute with class %s", pair, strings.Join(Classes, ", "))
+		}
+		limits[class] = n
+	}
+	return limits, nil
+}
+
+// String formats limits as ParseLimits reads them
+func (l Limits) String() string {
+	parts := make([]string, 0, len(l))
+	for class, n := range l {
+		parts = append(parts, fmt.Sprintf("%s=%d", class, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Counter counts requests in a window shared between API replicas
+type Counter interface {
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// Limiter enforces per-client and global limits
+type Limiter struct {
+	client Limits
+	global Limits
+	// shared, when set, counts while sharedUp reports it reachable;
+	// otherwise requests are counted in memory
+	shared   Counter
+	sharedUp func() bool
+
+	mu      sync.Mutex
+	windows map[string]*window
+	lastGC  time.Time
+}
+
+type window struct {
+	start time.Time
+	count int64
+}
+
+// NewLimiter creates a limiter; shared, when set, counts in Redis while
+// sharedUp reports it reachable
+func NewLimiter(client, global Limits, shared Counter, sharedUp func() bool) *Limiter {
+	return &Limiter{client: client, global: global, shared: shared, sharedUp: sharedUp, windows: make(map[string]*window)}
+}
+
+// FromConfig creates a limiter from cfg; nil when rate limiting is off.
+// shared is used for the redis backend while sharedUp reports it
+// reachable, so Redis down at startup is used once it comes up.
+func FromConfig(cfg config.RateLimits, shared Counter, sharedUp func() bool) (*Limiter, error) {
+	if cfg.Client == "off" {
+		return nil, nil
+	}
+	client := DefaultClientLimits
+	if cfg.Client != "" {
+		var err error
+		if client, err = ParseLimits(cfg.Client); err != nil {
+			return nil, fmt.Errorf("rate_limits.client: %w", err)
+		}
+	}
+	global, err := ParseLimits(cfg.Global)
+	if err != nil {
+		return nil, fmt.Errorf("rate_limits.global: %w", err)
+	}
+
+	switch cfg.Backend {
+	case "", "memory":
+		shared = nil
+	case "redis":
+		if shared == nil {
+			return nil, fmt.Errorf("rate_limits.backend is redis but Redis is not configured")
+		}
+	default:
+		return nil, fmt.Errorf("rate_limits.backend: unknown backend %q (memory, redis)", cfg.Backend)
+	}
+	return NewLimiter(client, global, shared, sharedUp), nil
+}
+
+// Limits returns the per-client and global limits
+func (l *Limiter) Limits() (client, global Limits) {
+	return l.client, l.global
+}
+
+// Allow counts a request of client in class and reports whether it is
+// within the limits; if not, also when to retry. overrides are the
+// client's own limits, replacing the per-client ones for their classes.
+func (l *Limiter) Allow(ctx context.Context, class, client string, overrides Limits) (bool, time.Duration) {
+	limit := l.client[class]
+	if n, ok := overrides[class]; ok {
+		limit = n
+	}
+	if limit > 0 {
+		if ok, retry := l.take(ctx, "client:"+class+":"+client, limit); !ok {
+			metrics.RateLimitedTotal.WithLabelValues(class, "client").Inc()
+			return false, retry
+		}
+	}
+	if limit := l.global[class]; limit > 0 {
+		if ok, retry := l.take(ctx, "global:"+class, limit); !ok {
+			metrics.RateLimitedTotal.WithLabelValues(class, "global").Inc()
+			return false, retry
+		}
+	}
+	return true, 0
+}
+
+// take counts a request against key, in memory while the shared counter
+// is down; a shared counter that fails lets the request through rather
+// than failing the API with Redis
+func (l *Limiter) take(ctx context.Context, key string, limit int) (bool, time.Duration) {
+	now := time.Now()
+	start := now.Truncate(Window)
+	retry := start.Add(Window).Sub(now)
+
+	if l.shared != nil && l.sharedUp() {
+		n, err := l.shared.Incr(ctx, fmt.Sprintf("forge:ratelimit:%s:%d", key, start.Unix()), Window)
+		if err != nil {
+			lg := logger.Get()
+			lg.Warn().Err(err).Msg("Rate limit counter unavailable")
+			return true, 0
+		}
+		return n <= int64(limit), retry
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastGC) > Window {
+		for k, w := range l.windows {
+			if w.start.Before(start) {
+				delete(l.windows, k)
+			}
+		}
+		l.lastGC = now
+	}
+	w, ok := l.windows[key]
+	if !ok || w.start != start {
+		w = &window{start: start}
+		l.windows[key] = w
+	}
+	w.count++
+	return w.count <= int64(limit), retry
+}
+
+// queryPaths are the REST endpoints of the query class
+var queryPaths = map[string]bool{
+	"/api/v1/db/query":            true,
+	"/api/v1/db/execute":          true,
+	"/api/v1/logs/query":          true,
+	"/api/v1/logs/tail":           true,
+	"/api/v1/metrics/query":       true,
+	"/api/v1/metrics/query_range": true,
+	"/api/v1/system/history":      true,
+}
+
+// ingestPaths are the REST endpoints of the ingest class (for POST)
+var ingestPaths = map[string]bool{
+	"/api/v1/logs":               true,
+	"/api/v1/logs/ingest/fluent": true,
+	"/api/v1/metrics":            true,
+	"/api/v1/metrics/timing":     true,
+	"/api/v1/traces":             true,
+	"/api/v1/errors":             true,
+}
+
+// Class returns the endpoint class of a REST request
+func Class(method, path string) string {
+	if queryPaths[path] || (method == http.MethodGet && strings.HasPrefix(path, "/api/v1/traces/")) {
+		return ClassQuery
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ClassRead
+	}
+	if ingestPaths[path] {
+		return ClassIngest
+	}
+	return ClassWrite
+}
+
+// procedureClasses are the classes of RPCs other than writes
+var procedureClasses = map[string]string{
+	"/forge.v1.ForgeService/Health":     ClassRead,
+	"/forge.v1.ForgeService/Info":       ClassRead,
+	"/forge.v1.CacheService/Get":        ClassRead,
+	"/forge.v1.CacheService/GetInfo":    ClassRead,
+	"/forge.v1.DatabaseService/GetInfo": ClassRead,
+	"/forge.v1.DatabaseService/Query":   ClassQuery,
+	"/forge.v1.DatabaseService/Execute": ClassQuery,
+	"/forge.v1.ObserveService/Query":    ClassQuery,
+	"/forge.v1.ObserveService/Log":      ClassIngest,
+	"/forge.v1.ObserveService/Metric":   ClassIngest,
+	"/forge.v1.ObserveService/Trace":    ClassIngest,
+	"/forge.v1.ObserveService/Timing":   ClassIngest,
+	"/forge.v1.ErrorsService/Capture":   ClassIngest,
+	"/forge.v1.FlagsService/Evaluate":   ClassRead,
+
+	"/forge.v1.StorageService/ListBuckets": ClassRead,
+	"/forge.v1.StorageService/GetObject":   ClassRead,
+	"/forge.v1.StorageService/ListObjects": ClassRead,
+	"/forge.v1.StorageService/PresignURL":  ClassRead,
+	"/forge.v1.StorageService/GetInfo":     ClassRead,
+
+	"/forge.v1.MessagingService/Subscribe": ClassRead,
+	"/forge.v1.MessagingService/GetInfo":   ClassRead,
+}
+
+// ProcedureClass returns the endpoint class of an RPC
+func ProcedureClass(procedure string) string {
+	if class, ok := procedureClasses[procedure]; ok {
+		return class
+	}
+	return ClassWrite
+}
+
+// RetryAfter formats a retry delay for the Retry-After header, in whole
+// seconds rounded up
+func RetryAfter(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
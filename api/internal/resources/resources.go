@@ -0,0 +1,191 @@
+// Package resources provides label-based search across all Forge objects
+//
+// Subsystems (routes, log sources, secrets, ...) register a lister with the
+// Index. Each resource carries key/value labels, and the index answers
+// Kubernetes-style selectors such as "team=payments,env!=dev".
+package resources
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Resource is a labeled Forge object
+type Resource struct {
+	Kind   string            `json:"kind"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+// Lister returns the current resources of one kind
+type Lister func() []Resource
+
+// Index aggregates resources from all registered subsystems
+type Index struct {
+	mu      sync.RWMutex
+	listers map[string]Lister
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{listers: make(map[string]Lister)}
+}
+
+// Register adds a lister for kind. Registering an existing kind replaces it.
+func (idx *Index) Register(kind string, lister Lister) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.listers[kind] = lister
+}
+
+// Kinds returns the registered resource kinds
+func (idx *Index) Kinds() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	kinds := make([]string, 0, len(idx.listers))
+	for k := range idx.listers {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Search returns resources of the given kinds (all kinds if empty) matching
+// every requirement, sorted by kind then name
+func (idx *Index) Search(kinds []string, selector Selector) []Resource {
+	idx.mu.RLock()
+	listers := make(map[string]Lister, len(idx.listers))
+	for k, l := range idx.listers {
+		listers[k] = l
+	}
+	idx.mu.RUnlock()
+
+	if len(kinds) == 0 {
+		for k := range listers {
+			kinds = append(kinds, k)
+		}
+	}
+
+	var result []Resource
+	for _, kind := range kinds {
+		lister, ok := listers[kind]
+		if !ok {
+			continue
+		}
+		for _, r := range lister() {
+			if r.Labels == nil {
+				r.Labels = map[string]string{}
+			}
+			if selector.Matches(r.Labels) {
+				result = append(result, r)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Requirement operators
+const (
+	OpEquals    = "="
+	OpNotEquals = "!="
+	OpExists    = "exists"
+	OpNotExists = "!exists"
+)
+
+// Requirement is a single label condition
+type Requirement struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// Selector is a set of requirements that must all match
+type Selector []Requirement
+
+// Matches reports whether labels satisfy every requirement
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		v, ok := labels[req.Key]
+		switch req.Op {
+		case OpEquals:
+			if !ok || v != req.Value {
+				return false
+			}
+		case OpNotEquals:
+			if ok && v == req.Value {
+				return false
+			}
+		case OpExists:
+			if !ok {
+				return false
+			}
+		case OpNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseSelector parses comma-separated requirements: "k=v", "k!=v", "k" or "!k"
+func ParseSelector(expr string) (Selector, error) {
+	var sel Selector
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var req Requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			req = Requirement{Key: kv[0], Op: OpNotEquals, Value: kv[1]}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			req = Requirement{Key: kv[0], Op: OpEquals, Value: kv[1]}
+		case strings.HasPrefix(part, "!"):
+			req = Requirement{Key: part[1:], Op: OpNotExists}
+		default:
+			req = Requirement{Key: part, Op: OpExists}
+		}
+
+		req.Key = strings.TrimSpace(req.Key)
+		req.Value = strings.TrimSpace(req.Value)
+		if !labelKeyPattern.MatchString(req.Key) {
+			return nil, fmt.Errorf("invalid label key in selector: %q", req.Key)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_./-]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[a-zA-Z0-9_./:-]{0,63}$`)
+)
+
+// ValidateLabels checks label keys and values used as resource tags
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("invalid label key: %q", k)
+		}
+		if !labelValuePattern.MatchString(v) {
+			return fmt.Errorf("invalid label value for %s: %q", k, v)
+		}
+	}
+	return nil
+}
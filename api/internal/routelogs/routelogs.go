@@ -0,0 +1,63 @@
+// Package routelogs keeps a Promtail log source for every route with an
+// access log, so its requests reach Loki labelled with the route
+package routelogs
+
+import (
+	"path"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/logsources"
+	"github.com/forge/api/internal/routes"
+)
+
+const (
+	// ownerLabel marks log sources managed for routes; its value is the route name
+	ownerLabel   = "forge_route"
+	sourcePrefix = "route-"
+)
+
+// Shipper syncs route access logs into the log sources manager
+type Shipper struct {
+	routes  *routes.Manager
+	sources *logsources.Manager
+	logDir  string // route access log directory, as seen by Promtail
+}
+
+// New creates a shipper. Call Sync once at startup; it re-syncs on every
+// route change.
+func New(rm *routes.Manager, lm *logsources.Manager, logDir string) *Shipper {
+	s := &Shipper{routes: rm, sources: lm, logDir: logDir}
+	rm.OnChange(s.Sync)
+	return s
+}
+
+// Sync creates, updates or removes log sources to match the routes and
+// reloads Promtail when they changed
+func (s *Shipper) Sync() {
+	var desired []logsources.LogSource
+	for _, r := range s.routes.List() {
+		if !r.AccessLog {
+			continue
+		}
+		desired = append(desired, logsources.LogSource{
+			Name: sourcePrefix + r.Name,
+			Path: path.Join(s.logDir, routes.AccessLogFile(r.Name)),
+			Labels: map[string]string{
+				ownerLabel: r.Name,
+				"route":    r.Name,
+				"service":  "nginx",
+			},
+		})
+	}
+
+	changed, err := s.sources.Sync(ownerLabel, desired)
+	if err != nil {
+		logger.Error("Failed to sync route access logs", err)
+		return
+	}
+	if changed {
+		if err := s.sources.ReloadPromtail(); err != nil {
+			logger.Error("Failed to reload Promtail for route access logs", err)
+		}
+	}
+}
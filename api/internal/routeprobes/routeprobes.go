@@ -0,0 +1,101 @@
+// Package routeprobes keeps an uptime monitor for every route that asks
+// for a health probe and reports per-route availability
+package routeprobes
+
+import (
+	"sort"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/monitors"
+	"github.com/forge/api/internal/routes"
+	"github.com/forge/api/internal/system"
+)
+
+const (
+	// ownerLabel marks monitors managed for routes; its value is the route name
+	ownerLabel    = "forge_route"
+	monitorPrefix = "route-"
+)
+
+// Prober syncs route probes into the monitors manager
+type Prober struct {
+	routes   *routes.Manager
+	monitors *monitors.Manager
+	baseURL  string // nginx as seen from the API, e.g. http://nginx
+}
+
+// New creates a prober. Call Sync once at startup; it re-syncs on every
+// route change.
+func New(rm *routes.Manager, mm *monitors.Manager, baseURL string) *Prober {
+	p := &Prober{routes: rm, monitors: mm, baseURL: baseURL}
+	rm.OnChange(p.Sync)
+	mm.OnResult(p.record)
+	return p
+}
+
+// Sync creates, updates or removes probe monitors to match the routes
+func (p *Prober) Sync() {
+	var desired []monitors.Monitor
+	probed := make(map[string]bool)
+	for _, r := range p.routes.List() {
+		if r.Probe == nil {
+			continue
+		}
+		probed[r.Name] = true
+		desired = append(desired, monitors.Monitor{
+			Name:             monitorPrefix + r.Name,
+			Type:             monitors.TypeHTTP,
+			Target:           r.Probe.URL(p.baseURL, r),
+			Interval:         r.Probe.Interval,
+			ExpectStatus:     r.Probe.ExpectStatus,
+			FailureThreshold: 2,
+			Labels:           map[string]string{ownerLabel: r.Name},
+		})
+	}
+
+	// Drop the route metric of probes that no longer exist
+	for _, st := range p.monitors.List() {
+		if route := st.Labels[ownerLabel]; route != "" && !probed[route] {
+			metrics.RouteUp.DeleteLabelValues(route)
+		}
+	}
+
+	if err := p.monitors.Sync(ownerLabel, desired); err != nil {
+		logger.Error("Failed to sync route probes", err)
+	}
+}
+
+// record exports the result of a route probe
+func (p *Prober) record(mon monitors.Monitor, result monitors.Result) {
+	route := mon.Labels[ownerLabel]
+	if route == "" {
+		return
+	}
+	up := 0.0
+	if result.Up {
+		up = 1
+	}
+	metrics.RouteUp.WithLabelValues(route).Set(up)
+}
+
+// Availability returns the probe status of every probed route
+func (p *Prober) Availability() []system.RouteAvailability {
+	var list []system.RouteAvailability
+	for _, st := range p.monitors.List() {
+		route := st.Labels[ownerLabel]
+		if route == "" {
+			continue
+		}
+		list = append(list, system.RouteAvailability{
+			Route:     route,
+			URL:       st.Target,
+			State:     st.State,
+			Uptime:    st.Uptime,
+			LastCheck: st.LastCheck,
+			LastError: st.LastError,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Route < list[j].Route })
+	return list
+}
@@ -0,0 +1,300 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+// caddyRoutePrefix tags routes owned by Forge so routes from the base
+// config are kept on every apply
+const caddyRoutePrefix = "forge_route_"
+
+const caddyTimeout = 10 * time.Second
+
+// CaddyConfig configures the Caddy backend
+type CaddyConfig struct {
+	AdminURL  string // Caddy admin API, e.g. http://caddy:2019
+	Server    string // server listening on :80 that receives path routes
+	TLSServer string // server listening on :443 that receives host routes
+}
+
+// NewCaddyConfig returns the Caddy settings of cfg
+func NewCaddyConfig(cfg config.Proxy) CaddyConfig {
+	return CaddyConfig{
+		AdminURL:  cfg.CaddyAdminURL,
+		Server:    cfg.CaddyServer,
+		TLSServer: cfg.CaddyTLSServer,
+	}
+}
+
+// Caddy manages routes through Caddy's admin API. Host routes go to the
+// TLS server, where Caddy obtains certificates automatically.
+type Caddy struct {
+	cfg    CaddyConfig
+	client *http.Client
+	mu     sync.Mutex // one apply at a time
+}
+
+// NewCaddy creates a Caddy backend
+func NewCaddy(cfg CaddyConfig) *Caddy {
+	return &Caddy{cfg: cfg, client: &http.Client{Timeout: caddyTimeout}}
+}
+
+// Name returns "caddy"
+func (c *Caddy) Name() string {
+	return "caddy"
+}
+
+// Validate rejects settings without a Caddy equivalent
+func (c *Caddy) Validate(route Route) error {
+	if route.RateLimit != nil {
+		return fmt.Errorf("rate_limit is not supported by the caddy backend")
+	}
+	if route.AccessLog {
+		return fmt.Errorf("access_log is not supported by the caddy backend")
+	}
+	if ws := route.WebSocket; ws != nil && (ws.ReadTimeout != "" || ws.SendTimeout != "") {
+		// Caddy proxies upgrades on every route and has no idle timeout
+		// for open sockets
+		return fmt.Errorf("websocket timeouts are not supported by the caddy backend")
+	}
+	if route.Canary != nil {
+		primary, _ := url.Parse(route.Target)
+		canary, _ := url.Parse(route.Canary.Target)
+		if primary != nil && canary != nil && primary.Scheme != canary.Scheme {
+			return fmt.Errorf("canary and primary targets must use the same scheme with the caddy backend")
+		}
+	}
+	return nil
+}
+
+// Apply replaces the Forge routes of both servers, keeping base routes
+func (c *Caddy) Apply(routes []Route) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var pathRoutes, hostRoutes []Route
+	for _, r := range routes {
+		if r.ServerName() == "" {
+			pathRoutes = append(pathRoutes, r)
+		} else {
+			hostRoutes = append(hostRoutes, r)
+		}
+	}
+
+	if err := c.replaceRoutes(c.cfg.Server, pathRoutes); err != nil {
+		return err
+	}
+	return c.replaceRoutes(c.cfg.TLSServer, hostRoutes)
+}
+
+// Reload makes Caddy reload its current config
+func (c *Caddy) Reload() error {
+	var cfg json.RawMessage
+	if err := c.do("GET", "/config/", nil, &cfg); err != nil {
+		return err
+	}
+	return c.do("POST", "/load", cfg, nil)
+}
+
+// Render returns the Caddy JSON route generated for route
+func (c *Caddy) Render(route Route, others []Route) string {
+	data, _ := json.MarshalIndent(caddyRoute(route), "", "  ")
+	return string(data) + "\n"
+}
+
+// replaceRoutes swaps the Forge-owned routes of server for routes
+func (c *Caddy) replaceRoutes(server string, routes []Route) error {
+	path := "/config/apps/http/servers/" + url.PathEscape(server) + "/routes"
+
+	var current []map[string]any
+	if err := c.do("GET", path, nil, &current); err != nil {
+		return fmt.Errorf("caddy server %s: %w", server, err)
+	}
+
+	// Forge routes first, ordered like nginx picks locations: exact, then
+	// regex, then the longest prefix; base routes are the fallback
+	sorted := append([]Route(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := caddyMatchRank(sorted[i]), caddyMatchRank(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return len(sorted[i].Path) > len(sorted[j].Path)
+	})
+
+	next := make([]any, 0, len(current)+len(sorted))
+	for _, r := range sorted {
+		next = append(next, caddyRoute(r))
+	}
+	for _, r := range current {
+		if id, _ := r["@id"].(string); strings.HasPrefix(id, caddyRoutePrefix) {
+			continue
+		}
+		next = append(next, r)
+	}
+
+	body, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	return c.do("PATCH", path, body, nil)
+}
+
+// caddyMatchRank orders match types by nginx precedence
+func caddyMatchRank(r Route) int {
+	switch r.MatchType {
+	case MatchExact:
+		return 0
+	case MatchRegex:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// caddyRoute converts a route to a Caddy HTTP route
+func caddyRoute(r Route) map[string]any {
+	match := map[string]any{}
+	switch r.MatchType {
+	case MatchExact:
+		match["path"] = []string{r.Path}
+	case MatchRegex:
+		match["path_regexp"] = map[string]string{"pattern": r.Path}
+	default:
+		match["path"] = []string{r.Path + "*"}
+	}
+	if host := r.ServerName(); host != "" {
+		match["host"] = []string{host}
+	}
+
+	var handle []any
+	if r.MaxBodySize != "" && r.MaxBodySize != "0" {
+		handle = append(handle, map[string]any{"handler": "request_body", "max_size": sizeBytes(r.MaxBodySize)})
+	}
+
+	target, _ := url.Parse(r.Target)
+	if target == nil {
+		target = &url.URL{}
+	}
+	if r.StripPrefix && r.MatchType != MatchRegex {
+		handle = append(handle, map[string]any{"handler": "rewrite", "strip_path_prefix": strings.TrimSuffix(r.Path, "/")})
+	}
+	if p := strings.TrimSuffix(target.Path, "/"); p != "" {
+		handle = append(handle, map[string]any{"handler": "rewrite", "uri": p + "{http.request.uri}"})
+	}
+
+	proxy := map[string]any{
+		"handler":   "reverse_proxy",
+		"upstreams": []map[string]string{{"dial": targetHostPort(target)}},
+	}
+	if c := r.Canary; c != nil && c.Weight > 0 {
+		canary, _ := url.Parse(c.Target)
+		if canary == nil {
+			canary = &url.URL{}
+		}
+		if c.Weight == maxCanaryWeight {
+			proxy["upstreams"] = []map[string]string{{"dial": targetHostPort(canary)}}
+		} else {
+			// Unlike nginx split_clients this is per request, not per client
+			proxy["upstreams"] = []map[string]string{{"dial": targetHostPort(target)}, {"dial": targetHostPort(canary)}}
+			proxy["load_balancing"] = map[string]any{
+				"selection_policy": map[string]any{
+					"policy":  "weighted_round_robin",
+					"weights": []int{maxCanaryWeight - c.Weight, c.Weight},
+				},
+			}
+		}
+	}
+	transport := map[string]any{"protocol": "http"}
+	if target.Scheme == "https" || target.Scheme == "grpcs" {
+		transport["tls"] = map[string]any{}
+	}
+	if r.Protocol == ProtocolGRPC {
+		if transport["tls"] != nil {
+			transport["versions"] = []string{"2"}
+		} else {
+			transport["versions"] = []string{"h2c", "2"}
+		}
+	}
+	if r.ReadTimeout != "" {
+		transport["read_timeout"] = r.ReadTimeout
+	}
+	proxy["transport"] = transport
+	if r.Buffering != nil && !*r.Buffering {
+		proxy["flush_interval"] = -1
+	}
+	if len(r.Headers) > 0 {
+		set := make(map[string][]string, len(r.Headers))
+		for k, v := range r.Headers {
+			set[k] = []string{v}
+		}
+		proxy["headers"] = map[string]any{"request": map[string]any{"set": set}}
+	}
+	handle = append(handle, proxy)
+
+	return map[string]any{
+		"@id":      caddyRoutePrefix + r.Name,
+		"match":    []any{match},
+		"handle":   handle,
+		"terminal": true,
+	}
+}
+
+// sizeBytes converts an nginx size such as 100m to bytes
+func sizeBytes(size string) int64 {
+	mult := int64(1)
+	switch strings.ToLower(size[len(size)-1:]) {
+	case "k":
+		mult = 1 << 10
+	case "m":
+		mult = 1 << 20
+	case "g":
+		mult = 1 << 30
+	}
+	n, _ := strconv.ParseInt(strings.TrimRight(size, "kKmMgG"), 10, 64)
+	return n * mult
+}
+
+// do calls the Caddy admin API and decodes a JSON response into out
+func (c *Caddy) do(method, path string, body []byte, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.cfg.AdminURL, "/")+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Reload even when the config is unchanged
+	req.Header.Set("Cache-Control", "must-revalidate")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("caddy admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("caddy admin API: %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
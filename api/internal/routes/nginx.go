@@ -0,0 +1,445 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// zoneNameUnsafe matches characters not allowed in nginx zone names
+var zoneNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// CertificateLookup returns the nginx paths of a usable certificate for a domain
+type CertificateLookup func(domain string) (cert, key string, ok bool)
+
+// dollarVariable is defined at http level as a literal "$"
+const dollarVariable = "forge_dollar"
+
+// DefaultAccessLogDir is where nginx writes route access logs
+const DefaultAccessLogDir = "/var/log/nginx/routes"
+
+// Nginx writes routes as nginx config files included by nginx.conf and
+// reloads nginx
+type Nginx struct {
+	logDir      string // access log directory of routes, as seen by nginx
+	conf        string // generated locations, included in the main server
+	zonesConf   string // generated limit_req zones, included at http level
+	serversConf string // generated per-host server blocks, included at http level
+	certs       CertificateLookup
+	reloader    Reloader
+}
+
+// NewNginx creates an nginx backend writing to confPath and siblings with
+// .zones and .servers extensions
+func NewNginx(confPath string) *Nginx {
+	base := strings.TrimSuffix(confPath, filepath.Ext(confPath))
+	return &Nginx{
+		conf:        confPath,
+		zonesConf:   base + ".zones",
+		serversConf: base + ".servers",
+		logDir:      DefaultAccessLogDir,
+		reloader:    NewDockerReloader(defaultNginxContainer),
+	}
+}
+
+// SetAccessLogDir sets where nginx writes route access logs. It must be
+// called before the manager receives traffic.
+func (n *Nginx) SetAccessLogDir(dir string) {
+	n.logDir = dir
+}
+
+// SetCertificates sets the lookup used to serve host routes over HTTPS.
+// It must be called before the manager receives traffic.
+func (n *Nginx) SetCertificates(lookup CertificateLookup) {
+	n.certs = lookup
+}
+
+// SetReloader sets how nginx is reloaded (default: SIGHUP via the Docker
+// API). It must be called before the manager receives traffic.
+func (n *Nginx) SetReloader(r Reloader) {
+	n.reloader = r
+}
+
+// Name returns "nginx"
+func (n *Nginx) Name() string {
+	return "nginx"
+}
+
+// Validate accepts every route; nginx supports all route settings
+func (n *Nginx) Validate(route Route) error {
+	return nil
+}
+
+// Apply writes the config files and reloads nginx
+func (n *Nginx) Apply(routes []Route) error {
+	zones := zoneNames(routes)
+
+	// Ensure directory exists
+	if err := os.MkdirAll(filepath.Dir(n.conf), 0755); err != nil {
+		return err
+	}
+
+	// Write zones before the locations that reference them; each file is
+	// replaced whole so nginx never reads a partial config
+	if err := writeFileAtomic(n.zonesConf, []byte(generateZonesConfig(routes, zones))); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(n.conf, []byte(n.generateLocationsConfig(routes, zones))); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(n.serversConf, []byte(n.generateServersConfig(routes, zones))); err != nil {
+		return err
+	}
+
+	return n.Reload()
+}
+
+// Reload makes nginx load the current config
+func (n *Nginx) Reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+	return n.reloader.Reload(ctx)
+}
+
+// Render returns the location block of route as generated alongside
+// others, which determine its rate limit zone name
+func (n *Nginx) Render(route Route, others []Route) string {
+	all := append([]Route{route}, others...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+
+	var sb strings.Builder
+	writeLocation(&sb, route, zoneNames(all), n.logDir, "")
+	return sb.String()
+}
+
+// writeFileAtomic replaces path with data via a rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// zoneNames assigns a unique name to each route needing http-level
+// directives: its limit_req zone, or the prefix of its canary upstreams
+func zoneNames(routes []Route) map[string]string {
+	zones := make(map[string]string)
+	used := make(map[string]bool)
+	for _, r := range routes {
+		if r.RateLimit == nil && r.Canary == nil {
+			continue
+		}
+		base := "route_" + zoneNameUnsafe.ReplaceAllString(r.Name, "_")
+		zone := base
+		for i := 2; used[zone]; i++ {
+			zone = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[zone] = true
+		zones[r.Name] = zone
+	}
+	return zones
+}
+
+// generateZonesConfig creates the http-level directives for routes:
+// limit_req zones, and upstreams with a split_clients map for canaries
+func generateZonesConfig(routes []Route, zones map[string]string) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Route rate limit zones and canary splits - auto-generated, do not edit\n")
+	sb.WriteString("# Managed by Forge API\n\n")
+
+	// nginx strings cannot escape "$", so header values spell it with this
+	sb.WriteString(fmt.Sprintf("geo $%s {\n    default \"$\";\n}\n\n", dollarVariable))
+
+	for _, r := range routes {
+		zone, ok := zones[r.Name]
+		if !ok {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("# Route: %s\n", r.Name))
+		if r.RateLimit != nil {
+			sb.WriteString(fmt.Sprintf("limit_req_zone $binary_remote_addr zone=%s:1m rate=%dr/s;\n", zone, r.RateLimit.RPS))
+		}
+		if c := r.Canary; c != nil {
+			primary, _ := url.Parse(r.Target)
+			canary, _ := url.Parse(c.Target)
+			sb.WriteString(fmt.Sprintf("upstream %s_primary {\n    server %s;\n}\n", zone, targetHostPort(primary)))
+			sb.WriteString(fmt.Sprintf("upstream %s_canary {\n    server %s;\n}\n", zone, targetHostPort(canary)))
+			sb.WriteString(fmt.Sprintf("split_clients \"${remote_addr}\" $%s_upstream {\n", zone))
+			switch c.Weight {
+			case 0:
+				sb.WriteString(fmt.Sprintf("    * \"%s://%s_primary\";\n", primary.Scheme, zone))
+			case maxCanaryWeight:
+				sb.WriteString(fmt.Sprintf("    * \"%s://%s_canary\";\n", canary.Scheme, zone))
+			default:
+				sb.WriteString(fmt.Sprintf("    %d%% \"%s://%s_canary\";\n", c.Weight, canary.Scheme, zone))
+				sb.WriteString(fmt.Sprintf("    * \"%s://%s_primary\";\n", primary.Scheme, zone))
+			}
+			sb.WriteString("}\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// generateLocationsConfig creates nginx location blocks for routes without a
+// host or domain; they are included in the main server
+func (n *Nginx) generateLocationsConfig(routes []Route, zones map[string]string) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Dynamic routes - auto-generated, do not edit\n")
+	sb.WriteString("# Managed by Forge API\n\n")
+
+	for _, r := range routes {
+		if r.ServerName() == "" {
+			writeLocation(&sb, r, zones, n.logDir, "")
+		}
+	}
+
+	return sb.String()
+}
+
+// generateServersConfig creates a server block per route host or domain.
+// Names with a certificate redirect HTTP to an HTTPS server; the rest are
+// served over HTTP. Both answer ACME http-01 challenges through the API.
+func (n *Nginx) generateServersConfig(routes []Route, zones map[string]string) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Host routes - auto-generated, do not edit\n")
+	sb.WriteString("# Managed by Forge API\n\n")
+
+	byName := make(map[string][]Route)
+	var names []string
+	for _, r := range routes {
+		name := r.ServerName()
+		if name == "" {
+			continue
+		}
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], r)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var cert, key string
+		tls := false
+		if n.certs != nil {
+			cert, key, tls = n.certs(name)
+		}
+
+		sb.WriteString(fmt.Sprintf("# Host: %s\n", name))
+		sb.WriteString("server {\n")
+		sb.WriteString("    listen 80;\n")
+		sb.WriteString("    http2 on;\n")
+		sb.WriteString(fmt.Sprintf("    server_name %s;\n\n", name))
+		sb.WriteString("    location /.well-known/acme-challenge/ {\n")
+		sb.WriteString("        proxy_pass http://forge-api;\n")
+		sb.WriteString("    }\n\n")
+		if tls {
+			sb.WriteString("    location / {\n")
+			sb.WriteString("        return 301 https://$host$request_uri;\n")
+			sb.WriteString("    }\n")
+			sb.WriteString("}\n\n")
+
+			sb.WriteString("server {\n")
+			sb.WriteString("    listen 443 ssl;\n")
+			sb.WriteString("    http2 on;\n")
+			sb.WriteString(fmt.Sprintf("    server_name %s;\n", name))
+			sb.WriteString(fmt.Sprintf("    ssl_certificate %s;\n", cert))
+			sb.WriteString(fmt.Sprintf("    ssl_certificate_key %s;\n", key))
+			sb.WriteString("    ssl_protocols TLSv1.2 TLSv1.3;\n\n")
+		}
+		for _, r := range byName[name] {
+			writeLocation(&sb, r, zones, n.logDir, "    ")
+		}
+		sb.WriteString("}\n\n")
+	}
+
+	return sb.String()
+}
+
+// writeLocation writes the location block of a route, each line prefixed
+// with indent
+func writeLocation(sb *strings.Builder, r Route, zones map[string]string, logDir, indent string) {
+	line := func(format string, args ...any) {
+		sb.WriteString(indent)
+		sb.WriteString(fmt.Sprintf(format, args...))
+		sb.WriteString("\n")
+	}
+
+	line("# Route: %s", r.Name)
+	switch r.MatchType {
+	case MatchExact:
+		line("location = %s {", r.Path)
+	case MatchRegex:
+		line("location ~ \"%s\" {", r.Path)
+	default:
+		line("location %s {", r.Path)
+	}
+
+	if r.AccessLog {
+		// Keep the shared stdout log, which a location-level access_log
+		// would otherwise replace
+		line("    access_log /dev/stdout json_combined;")
+		line("    access_log %s json_combined;", path.Join(logDir, AccessLogFile(r.Name)))
+	}
+
+	if zone, ok := zones[r.Name]; ok && r.RateLimit != nil {
+		line("    limit_req zone=%s burst=%d nodelay;", zone, r.RateLimit.Burst)
+		line("    limit_req_status 429;")
+	}
+
+	if r.Protocol == ProtocolGRPC {
+		writeGRPC(line, r)
+		line("}")
+		sb.WriteString("\n")
+		return
+	}
+
+	if r.Canary != nil {
+		// proxy_pass with a variable passes the request URI unchanged, so
+		// the prefix is stripped with a rewrite
+		if r.StripPrefix {
+			if r.MatchType == MatchExact {
+				line("    rewrite ^ / break;")
+			} else {
+				line("    rewrite ^%s(.*)$ /$1 break;", regexp.QuoteMeta(r.Path))
+			}
+		}
+		line("    proxy_pass $%s_upstream;", zones[r.Name])
+	} else if r.StripPrefix {
+		// Strip the path prefix (add trailing slash to target)
+		target := r.Target
+		if !strings.HasSuffix(target, "/") {
+			target = target + "/"
+		}
+		line("    proxy_pass %s;", target)
+	} else {
+		// Keep the path (no trailing slash)
+		target := strings.TrimSuffix(r.Target, "/")
+		line("    proxy_pass %s;", target)
+	}
+
+	readTimeout, sendTimeout := r.ReadTimeout, ""
+	if ws := r.WebSocket; ws != nil {
+		if ws.ReadTimeout != "" {
+			readTimeout = ws.ReadTimeout
+		}
+		sendTimeout = ws.SendTimeout
+	}
+	if readTimeout != "" {
+		d, _ := time.ParseDuration(readTimeout)
+		line("    proxy_read_timeout %ds;", int(d.Seconds()))
+	}
+	if sendTimeout != "" {
+		d, _ := time.ParseDuration(sendTimeout)
+		line("    proxy_send_timeout %ds;", int(d.Seconds()))
+	}
+	if r.MaxBodySize != "" {
+		line("    client_max_body_size %s;", r.MaxBodySize)
+	}
+	if r.Buffering != nil {
+		if *r.Buffering {
+			line("    proxy_buffering on;")
+		} else {
+			line("    proxy_buffering off;")
+		}
+	}
+
+	line("    proxy_http_version 1.1;")
+	line("    proxy_set_header Host $host;")
+	line("    proxy_set_header X-Real-IP $remote_addr;")
+	line("    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;")
+	line("    proxy_set_header X-Forwarded-Proto $scheme;")
+	if r.WebSocket != nil {
+		line("    proxy_set_header Upgrade $http_upgrade;")
+		line("    proxy_set_header Connection \"upgrade\";")
+	} else {
+		// Clear the client's Connection header so upstream keepalive works
+		line("    proxy_set_header Connection \"\";")
+	}
+
+	headerNames := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		headerNames = append(headerNames, k)
+	}
+	sort.Strings(headerNames)
+	for _, k := range headerNames {
+		line("    proxy_set_header %s %s;", k, nginxQuote(r.Headers[k]))
+	}
+
+	line("}")
+	sb.WriteString("\n")
+}
+
+// writeGRPC writes the body of a grpc_pass location
+func writeGRPC(line func(format string, args ...any), r Route) {
+	line("    grpc_pass %s;", grpcTarget(r.Target))
+
+	if r.ReadTimeout != "" {
+		d, _ := time.ParseDuration(r.ReadTimeout)
+		line("    grpc_read_timeout %ds;", int(d.Seconds()))
+		line("    grpc_send_timeout %ds;", int(d.Seconds()))
+	}
+	if r.MaxBodySize != "" {
+		line("    client_max_body_size %s;", r.MaxBodySize)
+	}
+
+	line("    grpc_set_header X-Real-IP $remote_addr;")
+	line("    grpc_set_header X-Forwarded-For $proxy_add_x_forwarded_for;")
+	line("    grpc_set_header X-Forwarded-Proto $scheme;")
+
+	headerNames := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		headerNames = append(headerNames, k)
+	}
+	sort.Strings(headerNames)
+	for _, k := range headerNames {
+		line("    grpc_set_header %s %s;", k, nginxQuote(r.Headers[k]))
+	}
+}
+
+// nginxQuote returns s as a double-quoted nginx string with the value s:
+// quotes and backslashes are escaped, and "$" is written as a variable
+// holding it, so values such as resolved secrets never expand nginx
+// variables. s must not hold control characters (resolve rejects them).
+func nginxQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(c)
+		case '$':
+			sb.WriteString("${" + dollarVariable + "}")
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// grpcTarget maps http(s) targets to the grpc(s) schemes grpc_pass expects
+func grpcTarget(target string) string {
+	target = strings.TrimSuffix(target, "/")
+	switch {
+	case strings.HasPrefix(target, "http://"):
+		return "grpc://" + strings.TrimPrefix(target, "http://")
+	case strings.HasPrefix(target, "https://"):
+		return "grpcs://" + strings.TrimPrefix(target, "https://")
+	}
+	return target
+}
@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNginxQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Bearer abc", `"Bearer abc"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`},
+		{"pa$$word", `"pa${forge_dollar}${forge_dollar}word"`},
+		{"$host", `"${forge_dollar}host"`},
+		{"ünïcode", `"ünïcode"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := nginxQuote(tt.in); got != tt.want {
+				t.Errorf("nginxQuote(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteLocationHeaders(t *testing.T) {
+	var sb strings.Builder
+	writeLocation(&sb, Route{
+		Name:    "api",
+		Path:    "/api/",
+		Target:  "http://backend:8080",
+		Headers: map[string]string{"Authorization": `Bearer a$b"c`},
+	}, nil, "", "")
+
+	want := `proxy_set_header Authorization "Bearer a${forge_dollar}b\"c";`
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("location block does not contain %s:\n%s", want, sb.String())
+	}
+}
+
+func TestZonesConfigDefinesDollar(t *testing.T) {
+	conf := generateZonesConfig(nil, nil)
+	if !strings.Contains(conf, "geo $forge_dollar {\n    default \"$\";\n}") {
+		t.Errorf("zones config does not define $forge_dollar:\n%s", conf)
+	}
+}
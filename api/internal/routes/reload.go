@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+const (
+	defaultNginxContainer = "forge-nginx"
+	dockerSocket          = "/var/run/docker.sock"
+	reloadTimeout         = 10 * time.Second
+)
+
+// Reloader makes nginx load the regenerated config
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// NewReloader selects the reload method from cfg.NginxReload:
+//   - docker (default): send SIGHUP through the Docker API socket
+//   - exec: run "nginx -s reload" with the docker CLI
+//   - an http(s) URL: POST to a reload endpoint of an nginx sidecar
+//
+// cfg.NginxContainer names the nginx container for docker and exec.
+func NewReloader(cfg config.Proxy) (Reloader, error) {
+	container := cfg.NginxContainer
+	if container == "" {
+		container = defaultNginxContainer
+	}
+
+	mode := cfg.NginxReload
+	switch {
+	case mode == "" || mode == "docker":
+		return NewDockerReloader(container), nil
+	case mode == "exec":
+		return ExecReloader{Container: container}, nil
+	case strings.HasPrefix(mode, "http://") || strings.HasPrefix(mode, "https://"):
+		if _, err := url.Parse(mode); err != nil {
+			return nil, fmt.Errorf("invalid proxy.nginx_reload URL: %w", err)
+		}
+		return HTTPReloader{URL: mode, Client: &http.Client{Timeout: reloadTimeout}}, nil
+	default:
+		return nil, fmt.Errorf("invalid proxy.nginx_reload %q: must be docker, exec or an http(s) URL", mode)
+	}
+}
+
+// DockerReloader sends SIGHUP to the nginx container through the Docker
+// Engine API, which only needs the socket mounted (no docker CLI)
+type DockerReloader struct {
+	container string
+	client    *http.Client
+}
+
+// NewDockerReloader creates a reloader for container using the Docker socket
+func NewDockerReloader(container string) *DockerReloader {
+	return &DockerReloader{
+		container: container,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", dockerSocket)
+				},
+			},
+			Timeout: reloadTimeout,
+		},
+	}
+}
+
+// Reload signals the nginx master process, which re-reads its config
+func (d *DockerReloader) Reload(ctx context.Context) error {
+	u := "http://docker/containers/" + url.PathEscape(d.container) + "/kill?signal=HUP"
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nginx reload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("nginx reload failed: docker API status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// ExecReloader runs "nginx -s reload" in the container with the docker CLI
+type ExecReloader struct {
+	Container string
+}
+
+// Reload runs docker exec
+func (e ExecReloader) Reload(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "exec", e.Container, "nginx", "-s", "reload")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("nginx reload failed: %s - %v", string(output), err)
+	}
+	return nil
+}
+
+// HTTPReloader asks a sidecar next to nginx to reload it. Any 2xx response
+// counts as success.
+type HTTPReloader struct {
+	URL    string
+	Client *http.Client
+}
+
+// Reload POSTs to the sidecar reload endpoint
+func (h HTTPReloader) Reload(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nginx reload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("nginx reload failed: sidecar status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
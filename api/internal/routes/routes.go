@@ -1,50 +1,235 @@
-// Package routes manages dynamic nginx routes
+// Package routes manages dynamic proxy routes, rendered for nginx or Caddy
 package routes
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/forge/api/internal/certs"
+	"github.com/forge/api/internal/monitors"
+	"github.com/forge/api/internal/resources"
 	"gopkg.in/yaml.v3"
 )
 
-// Route represents a dynamic nginx route
+// ErrRouteNotFound is returned for operations on unknown routes
+var ErrRouteNotFound = errors.New("route not found")
+
+// headerNamePattern restricts header names to characters safe in nginx config
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// hostPattern matches a lowercase host name, optionally a wildcard
+// (*.example.com); single labels such as "nas" are allowed for LAN names
+var hostPattern = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// accessLogUnsafe matches characters not used in access log file names
+var accessLogUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// bodySizePattern matches nginx sizes such as 512k, 100m or 0 (unlimited)
+var bodySizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+
+// maxCanaryWeight is the share of traffic, in percent, that sends every
+// request to the canary
+const maxCanaryWeight = 100
+
+// Rate limit bounds
+const (
+	maxRateLimitRPS   = 10000
+	maxRateLimitBurst = 100000
+)
+
+// maxReadTimeout bounds proxy timeouts (long-polling, streaming, WebSockets)
+const maxReadTimeout = 24 * time.Hour
+
+// Location match types
+const (
+	MatchPrefix = "prefix" // location /path/ (default)
+	MatchExact  = "exact"  // location = /path
+	MatchRegex  = "regex"  // location ~ "pattern"
+)
+
+// Upstream protocols
+const (
+	ProtocolHTTP = "http" // HTTP/1.1 proxying (default)
+	ProtocolGRPC = "grpc" // gRPC over HTTP/2 (nginx grpc_pass)
+)
+
+// Route represents a dynamic proxy route
 type Route struct {
 	Name        string `json:"name" yaml:"name"`
-	Path        string `json:"path" yaml:"path"`               // e.g., "/myapp/"
-	Target      string `json:"target" yaml:"target"`           // e.g., "http://service:8000" or "https://api.example.com"
-	StripPrefix bool   `json:"strip_prefix" yaml:"strip_prefix"` // Remove path prefix before forwarding
+	Path        string `json:"path" yaml:"path"`                                 // e.g., "/myapp/", or a pattern for regex routes
+	MatchType   string `json:"match_type,omitempty" yaml:"match_type,omitempty"` // prefix (default), exact or regex
+	Target      string `json:"target" yaml:"target"`                             // e.g., "http://service:8000" or "https://api.example.com"
+	StripPrefix bool   `json:"strip_prefix" yaml:"strip_prefix"`                 // Remove path prefix before forwarding
+	Protocol    string `json:"protocol,omitempty" yaml:"protocol,omitempty"`     // http (default) or grpc; grpc targets may use grpc:// or grpcs://
+
+	// Host, if set, serves the route only on this host name (virtual host).
+	// HTTPS is used when a certificate covering the host exists, but none is
+	// requested automatically.
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+
+	// Domain, if set, serves the route only on this host name, over HTTPS
+	// once a certificate for it has been issued
+	Domain string `json:"domain,omitempty" yaml:"domain,omitempty"`
+
+	// Headers are extra request headers sent upstream. Values, like Target,
+	// may reference variables such as ${secret.X} or ${service.X.port}.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// Labels are resource tags (e.g. team=payments) used for search and bulk operations
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Probe, if set, health-checks the route through nginx
+	Probe *Probe `json:"probe,omitempty" yaml:"probe,omitempty"`
+
+	// RateLimit, if set, throttles requests per client IP
+	RateLimit *RateLimit `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+
+	// AccessLog writes a JSON access log for the route to its own file,
+	// shipped to Loki with a route label
+	AccessLog bool `json:"access_log,omitempty" yaml:"access_log,omitempty"`
+
+	// WebSocket, if set, passes Upgrade/Connection headers so clients can
+	// open WebSockets, with idle timeouts for long-lived sockets
+	WebSocket *WebSocket `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+
+	// Canary, if set, sends a share of clients to a second target
+	Canary *Canary `json:"canary,omitempty" yaml:"canary,omitempty"`
+
+	// Proxy tuning; unset fields keep the nginx defaults
+	ReadTimeout string `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"`   // proxy_read_timeout, e.g. "5m"
+	MaxBodySize string `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"` // client_max_body_size, e.g. "100m" or "0" for unlimited
+	Buffering   *bool  `json:"buffering,omitempty" yaml:"buffering,omitempty"`         // proxy_buffering; false streams responses as they arrive
+}
+
+// RateLimit is an nginx limit_req zone for a route. Requests over the rate
+// and burst are rejected with 429.
+type RateLimit struct {
+	RPS   int `json:"rps" yaml:"rps"`                         // sustained requests per second per client
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"` // extra requests accepted in a spike
+}
+
+// WebSocket enables WebSocket upgrades on a route. Timeouts close sockets
+// idle for longer; unset fields keep the nginx default of 60s.
+type WebSocket struct {
+	ReadTimeout string `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"` // proxy_read_timeout, e.g. "1h"
+	SendTimeout string `json:"send_timeout,omitempty" yaml:"send_timeout,omitempty"` // proxy_send_timeout, e.g. "1h"
+}
+
+// Canary is a second target receiving Weight percent of clients. Clients
+// are assigned by IP, so each one keeps seeing the same version.
+type Canary struct {
+	Target string `json:"target" yaml:"target"`
+	Weight int    `json:"weight" yaml:"weight"` // 0-100
+}
+
+// Probe is a health check against a route's public path
+type Probe struct {
+	Path         string `json:"path,omitempty" yaml:"path,omitempty"`                   // relative to the route path, e.g. "health"
+	Interval     string `json:"interval,omitempty" yaml:"interval,omitempty"`           // default 1m
+	ExpectStatus int    `json:"expect_status,omitempty" yaml:"expect_status,omitempty"` // default any status below 400
+}
+
+// ServerName returns the host name the route is served on, or "" for routes
+// in the main server
+func (r Route) ServerName() string {
+	if r.Domain != "" {
+		return r.Domain
+	}
+	return r.Host
 }
 
+// URL returns the probe URL of route under the nginx base URL
+func (p *Probe) URL(baseURL string, route Route) string {
+	return strings.TrimSuffix(baseURL, "/") + route.Path + strings.TrimPrefix(p.Path, "/")
+}
+
+// routesFileVersion is the format of the routes file. Version 1 made
+// WebSocket an explicit option; routes saved before it had upgrades on.
+const routesFileVersion = 1
+
 // RoutesConfig is the persisted routes file structure
 type RoutesConfig struct {
-	Routes []Route `yaml:"routes"`
+	Version int     `json:"version,omitempty" yaml:"version,omitempty"`
+	Routes  []Route `json:"routes" yaml:"routes"`
+}
+
+// Backend renders routes into a reverse proxy's configuration
+type Backend interface {
+	Name() string
+
+	// Validate rejects route settings the proxy cannot express
+	Validate(route Route) error
+
+	// Apply replaces the proxy's dynamic routes; routes are resolved and
+	// sorted by name
+	Apply(routes []Route) error
+
+	// Reload makes the proxy load its current config
+	Reload() error
+
+	// Render returns the config generated for route alongside others,
+	// with variable references left as they are
+	Render(route Route, others []Route) string
 }
 
-// Manager handles route storage and nginx configuration
+// Manager handles route storage and proxy configuration
 type Manager struct {
 	mu         sync.RWMutex
 	routes     map[string]Route
 	configPath string // Path to routes.yaml
-	nginxConf  string // Path to generated nginx routes config
+	backend    Backend
+	variables  map[string]VariableSource
+	onChange   []func()
+
+	// notifiedMu guards notified, the routes as last reported to
+	// onRouteChange handlers
+	notifiedMu    sync.Mutex
+	notified      map[string]Route
+	onRouteChange []func(RouteChange)
 }
 
-// NewManager creates a new route manager
-func NewManager(configPath, nginxConfPath string) (*Manager, error) {
+// Route change actions
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// RouteChange is a route created, updated or deleted
+type RouteChange struct {
+	Action string `json:"action"`
+	Route  Route  `json:"route"` // the route as it was, for deletions
+}
+
+// NewManager creates a new route manager applying routes through backend
+func NewManager(configPath string, backend Backend) (*Manager, error) {
 	m := &Manager{
 		routes:     make(map[string]Route),
 		configPath: configPath,
-		nginxConf:  nginxConfPath,
+		backend:    backend,
+		variables:  make(map[string]VariableSource),
 	}
 
 	// Load existing routes
-	if err := m.load(); err != nil && !os.IsNotExist(err) {
+	migrated, err := m.load()
+	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	if migrated {
+		if err := m.save(); err != nil {
+			return nil, err
+		}
+	}
 
 	return m, nil
 }
@@ -70,37 +255,521 @@ func (m *Manager) Get(name string) (Route, bool) {
 	return r, ok
 }
 
+// Domains returns the distinct domains of all routes, sorted
+func (m *Manager) Domains() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var domains []string
+	for _, r := range m.routes {
+		if r.Domain != "" && !seen[r.Domain] {
+			seen[r.Domain] = true
+			domains = append(domains, r.Domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// Backend returns the proxy backend routes are applied through
+func (m *Manager) Backend() Backend {
+	return m.backend
+}
+
+// OnChange registers fn to be called after routes are saved. It must be
+// called before the manager receives traffic.
+func (m *Manager) OnChange(fn func()) {
+	m.onChange = append(m.onChange, fn)
+}
+
+// OnRouteChange registers fn to be called for each route created, updated
+// or deleted. It must be called before the manager receives traffic.
+func (m *Manager) OnRouteChange(fn func(RouteChange)) {
+	m.notifiedMu.Lock()
+	defer m.notifiedMu.Unlock()
+	if m.notified == nil {
+		m.notified = m.snapshot()
+	}
+	m.onRouteChange = append(m.onRouteChange, fn)
+}
+
+func (m *Manager) notify() {
+	if len(m.onRouteChange) > 0 {
+		m.notifyRouteChanges()
+	}
+	for _, fn := range m.onChange {
+		fn()
+	}
+}
+
+// notifyRouteChanges reports the differences between the routes and those
+// last reported
+func (m *Manager) notifyRouteChanges() {
+	m.notifiedMu.Lock()
+	defer m.notifiedMu.Unlock()
+
+	current := m.snapshot()
+	var changes []RouteChange
+	for name, r := range current {
+		old, ok := m.notified[name]
+		switch {
+		case !ok:
+			changes = append(changes, RouteChange{Action: ChangeCreated, Route: r})
+		case !reflect.DeepEqual(old, r):
+			changes = append(changes, RouteChange{Action: ChangeUpdated, Route: r})
+		}
+	}
+	for name, r := range m.notified {
+		if _, ok := current[name]; !ok {
+			changes = append(changes, RouteChange{Action: ChangeDeleted, Route: r})
+		}
+	}
+	m.notified = current
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Route.Name < changes[j].Route.Name })
+
+	for _, c := range changes {
+		for _, fn := range m.onRouteChange {
+			fn(c)
+		}
+	}
+}
+
+// snapshot copies the routes by name
+func (m *Manager) snapshot() map[string]Route {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	routes := make(map[string]Route, len(m.routes))
+	for name, r := range m.routes {
+		routes[name] = r
+	}
+	return routes
+}
+
 // Add creates or updates a route
 func (m *Manager) Add(route Route) error {
-	// Validate
-	if route.Name == "" {
-		return fmt.Errorf("route name is required")
+	route, err := m.normalize(route)
+	if err != nil {
+		return err
 	}
-	if route.Path == "" {
-		return fmt.Errorf("route path is required")
+
+	m.mu.Lock()
+	m.routes[route.Name] = route
+	m.mu.Unlock()
+
+	// Save and regenerate proxy config
+	if err := m.save(); err != nil {
+		return err
 	}
-	if route.Target == "" {
-		return fmt.Errorf("route target is required")
+	m.notify()
+
+	return m.apply()
+}
+
+// AddAll validates every route first and then applies them together with a
+// single save and proxy reload. Nothing is applied if any route is invalid.
+// progress, if set, is called after each route is validated.
+func (m *Manager) AddAll(routes []Route, progress func(done, total int)) error {
+	normalized, err := m.normalizeAll(routes, progress)
+	if err != nil {
+		return err
 	}
 
-	// Ensure path starts with / and ends with /
-	if !strings.HasPrefix(route.Path, "/") {
-		route.Path = "/" + route.Path
+	m.mu.Lock()
+	for _, r := range normalized {
+		m.routes[r.Name] = r
 	}
-	if !strings.HasSuffix(route.Path, "/") {
-		route.Path = route.Path + "/"
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return err
 	}
+	m.notify()
+
+	return m.apply()
+}
 
+// ReplaceAll validates every route first and then makes them the complete
+// route set, removing routes not listed, with a single save and proxy
+// reload. Nothing is applied if any route is invalid.
+func (m *Manager) ReplaceAll(routes []Route) error {
+	normalized, err := m.normalizeAll(routes, nil)
+	if err != nil {
+		return err
+	}
+
+	replaced := make(map[string]Route, len(normalized))
+	for _, r := range normalized {
+		replaced[r.Name] = r
+	}
 	m.mu.Lock()
-	m.routes[route.Name] = route
+	m.routes = replaced
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.notify()
+
+	return m.apply()
+}
+
+// Sync makes the routes labelled with owner match desired, with one save
+// and reload if anything changed. Each desired route must carry the owner
+// label and must not replace a route without it. Other routes are left alone.
+func (m *Manager) Sync(owner string, desired []Route) error {
+	for _, r := range desired {
+		if r.Labels[owner] == "" {
+			return fmt.Errorf("route %s is missing the %s label", r.Name, owner)
+		}
+	}
+	normalized, err := m.normalizeAll(desired, nil)
+	if err != nil {
+		return err
+	}
+	want := make(map[string]Route, len(normalized))
+	for _, r := range normalized {
+		want[r.Name] = r
+	}
+
+	m.mu.Lock()
+	for name := range want {
+		if existing, ok := m.routes[name]; ok && existing.Labels[owner] == "" {
+			m.mu.Unlock()
+			return fmt.Errorf("route %s already exists", name)
+		}
+	}
+	changed := false
+	for name, r := range m.routes {
+		if r.Labels[owner] == "" {
+			continue
+		}
+		if _, ok := want[name]; !ok {
+			delete(m.routes, name)
+			changed = true
+		}
+	}
+	for name, r := range want {
+		if existing, ok := m.routes[name]; !ok || !reflect.DeepEqual(existing, r) {
+			m.routes[name] = r
+			changed = true
+		}
+	}
 	m.mu.Unlock()
 
-	// Save and regenerate nginx config
+	if !changed {
+		return nil
+	}
 	if err := m.save(); err != nil {
 		return err
 	}
+	m.notify()
+
+	return m.apply()
+}
+
+// Export returns all routes sorted by name in the routes file structure
+func (m *Manager) Export() RoutesConfig {
+	routes := m.List()
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return RoutesConfig{Version: routesFileVersion, Routes: routes}
+}
+
+// normalizeAll validates routes and rejects duplicate names
+func (m *Manager) normalizeAll(routes []Route, progress func(done, total int)) ([]Route, error) {
+	normalized := make([]Route, 0, len(routes))
+	seen := make(map[string]bool, len(routes))
+	for i, r := range routes {
+		nr, err := m.normalize(r)
+		if err != nil {
+			return nil, fmt.Errorf("route %d (%s): %w", i, r.Name, err)
+		}
+		if seen[nr.Name] {
+			return nil, fmt.Errorf("duplicate route name: %s", nr.Name)
+		}
+		seen[nr.Name] = true
+		normalized = append(normalized, nr)
+		if progress != nil {
+			progress(i+1, len(routes))
+		}
+	}
+	return normalized, nil
+}
+
+// Preview is the result of validating a proposed route without applying it
+type Preview struct {
+	Valid  bool   `json:"valid"`
+	Error  string `json:"error,omitempty"`
+	Route  Route  `json:"route"`            // normalized route as it would be saved
+	Host   string `json:"host,omitempty"`   // server block the location is placed in; empty for the main server
+	Config string `json:"config,omitempty"` // generated proxy config, e.g. the nginx location block
+
+	// Current is the location block of the existing route with the same
+	// name, if any, so callers can show a diff
+	Current string `json:"current,omitempty"`
+}
+
+// Preview validates route and returns the proxy config it would generate.
+// Nothing is saved and the proxy is not reloaded. Variable references are
+// shown unresolved so secrets never appear in the output.
+func (m *Manager) Preview(route Route) Preview {
+	normalized, err := m.normalize(route)
+	if err != nil {
+		return Preview{Error: err.Error(), Route: route}
+	}
+
+	m.mu.RLock()
+	others := make([]Route, 0, len(m.routes))
+	for _, r := range m.routes {
+		if r.Name != normalized.Name {
+			others = append(others, r)
+		}
+	}
+	existing, exists := m.routes[normalized.Name]
+	m.mu.RUnlock()
+
+	p := Preview{
+		Valid:  true,
+		Route:  normalized,
+		Host:   normalized.ServerName(),
+		Config: m.backend.Render(normalized, others),
+	}
+	if exists {
+		p.Current = m.backend.Render(existing, others)
+	}
+	return p
+}
+
+// normalize validates a route and fills in canonical values
+func (m *Manager) normalize(route Route) (Route, error) {
+	// Validate
+	if route.Name == "" {
+		return route, fmt.Errorf("route name is required")
+	}
+	if route.Path == "" {
+		return route, fmt.Errorf("route path is required")
+	}
+	if route.Target == "" {
+		return route, fmt.Errorf("route target is required")
+	}
+	for k := range route.Headers {
+		if !headerNamePattern.MatchString(k) {
+			return route, fmt.Errorf("invalid header name: %s", k)
+		}
+	}
+	if err := resources.ValidateLabels(route.Labels); err != nil {
+		return route, err
+	}
+	if route.Probe != nil {
+		if strings.Contains(route.Probe.Path, "://") || strings.ContainsAny(route.Probe.Path, " \t\n") {
+			return route, fmt.Errorf("probe path must be a path relative to the route")
+		}
+		if route.Probe.Interval != "" {
+			d, err := time.ParseDuration(route.Probe.Interval)
+			if err != nil || d < monitors.MinInterval {
+				return route, fmt.Errorf("probe interval must be a duration of at least %s", monitors.MinInterval)
+			}
+		}
+		if route.Probe.ExpectStatus != 0 && (route.Probe.ExpectStatus < 100 || route.Probe.ExpectStatus > 599) {
+			return route, fmt.Errorf("invalid probe expect_status: %d", route.Probe.ExpectStatus)
+		}
+	}
+	if route.Domain != "" {
+		route.Domain = strings.ToLower(strings.TrimSpace(route.Domain))
+		if !certs.ValidDomain(route.Domain) {
+			return route, fmt.Errorf("invalid domain: %q", route.Domain)
+		}
+	}
+	if route.Host != "" {
+		route.Host = strings.ToLower(strings.TrimSpace(route.Host))
+		if len(route.Host) > 253 || !hostPattern.MatchString(route.Host) {
+			return route, fmt.Errorf("invalid host: %q", route.Host)
+		}
+		if route.Domain != "" && route.Domain != route.Host {
+			return route, fmt.Errorf("host and domain must match when both are set")
+		}
+	}
+	if rl := route.RateLimit; rl != nil {
+		if rl.RPS < 1 || rl.RPS > maxRateLimitRPS {
+			return route, fmt.Errorf("rate_limit rps must be between 1 and %d", maxRateLimitRPS)
+		}
+		if rl.Burst < 0 || rl.Burst > maxRateLimitBurst {
+			return route, fmt.Errorf("rate_limit burst must be between 0 and %d", maxRateLimitBurst)
+		}
+	}
+
+	switch route.Protocol {
+	case "", ProtocolHTTP:
+		route.Protocol = ""
+	case ProtocolGRPC:
+		u, err := url.Parse(route.Target)
+		if err == nil && !variablePattern.MatchString(route.Target) {
+			switch u.Scheme {
+			case "grpc", "grpcs", "http", "https":
+			default:
+				return route, fmt.Errorf("grpc target must use grpc://, grpcs://, http:// or https://")
+			}
+			if strings.Trim(u.Path, "/") != "" {
+				return route, fmt.Errorf("grpc target must not include a path")
+			}
+		}
+		if route.StripPrefix {
+			return route, fmt.Errorf("strip_prefix is not supported for grpc routes")
+		}
+		if route.Canary != nil {
+			return route, fmt.Errorf("canary is not supported for grpc routes")
+		}
+		if route.Buffering != nil {
+			return route, fmt.Errorf("buffering is not supported for grpc routes")
+		}
+		if route.WebSocket != nil {
+			return route, fmt.Errorf("websocket is not supported for grpc routes")
+		}
+	default:
+		return route, fmt.Errorf("invalid protocol %q: must be %s or %s", route.Protocol, ProtocolHTTP, ProtocolGRPC)
+	}
+	if c := route.Canary; c != nil {
+		if c.Target == "" {
+			return route, fmt.Errorf("canary target is required")
+		}
+		if c.Weight < 0 || c.Weight > maxCanaryWeight {
+			return route, fmt.Errorf("canary weight must be between 0 and %d", maxCanaryWeight)
+		}
+		// Both targets are upstream groups, which carry no path
+		for _, t := range []string{route.Target, c.Target} {
+			if u, err := url.Parse(t); err == nil && strings.Trim(u.Path, "/") != "" {
+				return route, fmt.Errorf("targets of a canary route must not include a path")
+			}
+		}
+	}
+	if err := validateTimeout("read_timeout", route.ReadTimeout); err != nil {
+		return route, err
+	}
+	if ws := route.WebSocket; ws != nil {
+		if ws.ReadTimeout != "" && route.ReadTimeout != "" {
+			return route, fmt.Errorf("set either read_timeout or websocket.read_timeout, not both")
+		}
+		if err := validateTimeout("websocket.read_timeout", ws.ReadTimeout); err != nil {
+			return route, err
+		}
+		if err := validateTimeout("websocket.send_timeout", ws.SendTimeout); err != nil {
+			return route, err
+		}
+	}
+	if route.MaxBodySize != "" && !bodySizePattern.MatchString(route.MaxBodySize) {
+		return route, fmt.Errorf("invalid max_body_size %q: use a size such as 512k, 100m, 1g or 0 for unlimited", route.MaxBodySize)
+	}
+
+	if err := m.backend.Validate(route); err != nil {
+		return route, err
+	}
+
+	switch route.MatchType {
+	case "", MatchPrefix:
+		route.MatchType = ""
+		// Ensure path starts with / and ends with /
+		if !strings.HasPrefix(route.Path, "/") {
+			route.Path = "/" + route.Path
+		}
+		if !strings.HasSuffix(route.Path, "/") {
+			route.Path = route.Path + "/"
+		}
+	case MatchExact:
+		if !strings.HasPrefix(route.Path, "/") {
+			route.Path = "/" + route.Path
+		}
+		if strings.ContainsAny(route.Path, " \t\n;{}") {
+			return route, fmt.Errorf("invalid exact path: %q", route.Path)
+		}
+		if route.Probe != nil && route.Probe.Path != "" {
+			return route, fmt.Errorf("probe path must be empty for exact routes")
+		}
+	case MatchRegex:
+		// nginx uses PCRE; requiring RE2 syntax catches malformed patterns
+		// before they break the reload
+		if strings.ContainsAny(route.Path, "\"\n") {
+			return route, fmt.Errorf("regex path must not contain quotes or newlines")
+		}
+		if _, err := regexp.Compile(route.Path); err != nil {
+			return route, fmt.Errorf("invalid regex path: %w", err)
+		}
+		// nginx rejects a URI in proxy_pass inside regex locations
+		if route.StripPrefix {
+			return route, fmt.Errorf("strip_prefix is not supported for regex routes")
+		}
+		if u, err := url.Parse(route.Target); err == nil && strings.Trim(u.Path, "/") != "" {
+			return route, fmt.Errorf("target of a regex route must not include a path")
+		}
+		if route.Probe != nil {
+			return route, fmt.Errorf("probes are not supported for regex routes")
+		}
+	default:
+		return route, fmt.Errorf("invalid match_type %q: must be %s, %s or %s", route.MatchType, MatchPrefix, MatchExact, MatchRegex)
+	}
 
-	return m.regenerateNginx()
+	// Variables must resolve now so a bad reference is rejected up front
+	if _, err := m.resolve(route); err != nil {
+		return route, err
+	}
+
+	return route, nil
+}
+
+// SetCanaryWeight changes the share of clients sent to a route's canary
+func (m *Manager) SetCanaryWeight(name string, weight int) (Route, error) {
+	return m.update(name, func(r *Route) error {
+		if r.Canary == nil {
+			return fmt.Errorf("route %s has no canary", name)
+		}
+		if weight < 0 || weight > maxCanaryWeight {
+			return fmt.Errorf("canary weight must be between 0 and %d", maxCanaryWeight)
+		}
+		r.Canary.Weight = weight
+		return nil
+	})
+}
+
+// PromoteCanary makes the canary target the route's only target
+func (m *Manager) PromoteCanary(name string) (Route, error) {
+	return m.update(name, func(r *Route) error {
+		if r.Canary == nil {
+			return fmt.Errorf("route %s has no canary", name)
+		}
+		r.Target = r.Canary.Target
+		r.Canary = nil
+		return nil
+	})
+}
+
+// RemoveCanary drops the canary, sending all clients to the primary target
+func (m *Manager) RemoveCanary(name string) (Route, error) {
+	return m.update(name, func(r *Route) error {
+		if r.Canary == nil {
+			return fmt.Errorf("route %s has no canary", name)
+		}
+		r.Canary = nil
+		return nil
+	})
+}
+
+// update applies fn to a copy of a route and saves it like Add
+func (m *Manager) update(name string, fn func(r *Route) error) (Route, error) {
+	r, ok := m.Get(name)
+	if !ok {
+		return Route{}, ErrRouteNotFound
+	}
+	if r.Canary != nil {
+		c := *r.Canary
+		r.Canary = &c
+	}
+	if err := fn(&r); err != nil {
+		return Route{}, err
+	}
+	if err := m.Add(r); err != nil {
+		return Route{}, err
+	}
+	saved, _ := m.Get(name)
+	return saved, nil
 }
 
 // Remove deletes a route
@@ -116,40 +785,49 @@ func (m *Manager) Remove(name string) error {
 	if err := m.save(); err != nil {
 		return err
 	}
+	m.notify()
 
-	return m.regenerateNginx()
+	return m.apply()
 }
 
-// Reload sends reload signal to nginx
+// Regenerate reapplies the current routes to the proxy, e.g. after a
+// certificate was issued
+func (m *Manager) Regenerate() error {
+	return m.apply()
+}
+
+// Reload makes the proxy load its current config
 func (m *Manager) Reload() error {
-	cmd := exec.Command("docker", "exec", "forge-nginx", "nginx", "-s", "reload")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("nginx reload failed: %s - %v", string(output), err)
-	}
-	return nil
+	return m.backend.Reload()
 }
 
-// load reads routes from config file
-func (m *Manager) load() error {
+// load reads routes from config file, reporting whether routes from an
+// older file format were migrated and should be saved
+func (m *Manager) load() (bool, error) {
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	var cfg RoutesConfig
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return err
+		return false, err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	migrated := false
 	for _, r := range cfg.Routes {
+		// HTTP routes saved before version 1 passed upgrades implicitly
+		if cfg.Version < 1 && r.WebSocket == nil && r.Protocol != ProtocolGRPC {
+			r.WebSocket = &WebSocket{}
+			migrated = true
+		}
 		m.routes[r.Name] = r
 	}
 
-	return nil
+	return migrated, nil
 }
 
 // save writes routes to config file
@@ -161,7 +839,7 @@ func (m *Manager) save() error {
 	}
 	m.mu.RUnlock()
 
-	cfg := RoutesConfig{Routes: routes}
+	cfg := RoutesConfig{Version: routesFileVersion, Routes: routes}
 	data, err := yaml.Marshal(&cfg)
 	if err != nil {
 		return err
@@ -175,8 +853,8 @@ func (m *Manager) save() error {
 	return os.WriteFile(m.configPath, data, 0644)
 }
 
-// regenerateNginx creates nginx config and reloads
-func (m *Manager) regenerateNginx() error {
+// apply resolves the current routes and hands them to the backend
+func (m *Manager) apply() error {
 	m.mu.RLock()
 	routes := make([]Route, 0, len(m.routes))
 	for _, r := range m.routes {
@@ -184,57 +862,45 @@ func (m *Manager) regenerateNginx() error {
 	}
 	m.mu.RUnlock()
 
-	// Generate nginx config
-	config := m.generateNginxConfig(routes)
-
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(m.nginxConf), 0755); err != nil {
-		return err
-	}
-
-	// Write config
-	if err := os.WriteFile(m.nginxConf, []byte(config), 0644); err != nil {
-		return err
+	// Resolve template variables; routes.yaml keeps the raw references
+	for i, r := range routes {
+		resolved, err := m.resolve(r)
+		if err != nil {
+			return err
+		}
+		routes[i] = resolved
 	}
 
-	// Reload nginx
-	return m.Reload()
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return m.backend.Apply(routes)
 }
 
-// generateNginxConfig creates nginx location blocks for routes
-func (m *Manager) generateNginxConfig(routes []Route) string {
-	var sb strings.Builder
-
-	sb.WriteString("# Dynamic routes - auto-generated, do not edit\n")
-	sb.WriteString("# Managed by Forge API\n\n")
-
-	for _, r := range routes {
-		sb.WriteString(fmt.Sprintf("# Route: %s\n", r.Name))
-		sb.WriteString(fmt.Sprintf("location %s {\n", r.Path))
-
-		if r.StripPrefix {
-			// Strip the path prefix (add trailing slash to target)
-			target := r.Target
-			if !strings.HasSuffix(target, "/") {
-				target = target + "/"
-			}
-			sb.WriteString(fmt.Sprintf("    proxy_pass %s;\n", target))
-		} else {
-			// Keep the path (no trailing slash)
-			target := strings.TrimSuffix(r.Target, "/")
-			sb.WriteString(fmt.Sprintf("    proxy_pass %s;\n", target))
-		}
-
-		sb.WriteString("    proxy_http_version 1.1;\n")
-		sb.WriteString("    proxy_set_header Host $host;\n")
-		sb.WriteString("    proxy_set_header X-Real-IP $remote_addr;\n")
-		sb.WriteString("    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n")
-		sb.WriteString("    proxy_set_header X-Forwarded-Proto $scheme;\n")
-		sb.WriteString("    proxy_set_header Upgrade $http_upgrade;\n")
-		sb.WriteString("    proxy_set_header Connection \"upgrade\";\n")
-		sb.WriteString("}\n\n")
+// targetHostPort returns host:port of a target URL, defaulting the port
+// from the scheme
+func targetHostPort(target *url.URL) string {
+	if target.Port() != "" {
+		return target.Host
 	}
+	port := "80"
+	if target.Scheme == "https" || target.Scheme == "grpcs" {
+		port = "443"
+	}
+	return net.JoinHostPort(target.Hostname(), port)
+}
 
-	return sb.String()
+// validateTimeout checks an optional proxy timeout setting
+func validateTimeout(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Second || d > maxReadTimeout {
+		return fmt.Errorf("%s must be a duration between 1s and %s", field, maxReadTimeout)
+	}
+	return nil
 }
 
+// AccessLogFile is the file name of a route's access log
+func AccessLogFile(name string) string {
+	return accessLogUnsafe.ReplaceAllString(name, "_") + ".log"
+}
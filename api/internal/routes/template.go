@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// variablePattern matches ${namespace.key} references in route targets and headers
+var variablePattern = regexp.MustCompile(`\$\{([a-zA-Z0-9_]+)\.([a-zA-Z0-9_.\-]+)\}`)
+
+// SecretNamespace is the namespace of stored secrets, ${secret.NAME}
+const SecretNamespace = "secret"
+
+// VariableSource resolves keys within a single namespace (e.g. "secret")
+type VariableSource func(key string) (string, bool)
+
+// RegisterVariables adds a namespace that route templates can reference.
+// Registering an existing namespace replaces it.
+func (m *Manager) RegisterVariables(namespace string, source VariableSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.variables[namespace] = source
+}
+
+// interpolate replaces all ${namespace.key} references in s.
+// Unknown namespaces and unresolvable keys are returned as errors so a
+// broken reference never reaches the generated nginx config.
+func (m *Manager) interpolate(s string) (string, error) {
+	var firstErr error
+
+	result := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := variablePattern.FindStringSubmatch(match)
+		namespace, key := parts[1], parts[2]
+
+		m.mu.RLock()
+		source, ok := m.variables[namespace]
+		m.mu.RUnlock()
+
+		if !ok {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unknown variable namespace: %s", namespace)
+			}
+			return match
+		}
+
+		value, ok := source(key)
+		if !ok {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unresolved variable: %s", strings.Trim(match, "${}"))
+			}
+			return match
+		}
+		return value
+	})
+
+	return result, firstErr
+}
+
+// References reports whether route's target or headers reference a
+// variable in namespace
+func References(route Route, namespace string) bool {
+	values := []string{route.Target}
+	for _, v := range route.Headers {
+		values = append(values, v)
+	}
+	for _, v := range values {
+		for _, match := range variablePattern.FindAllStringSubmatch(v, -1) {
+			if match[1] == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns a copy of route with all variables in its target and
+// headers interpolated. Resolved values must still be safe in the proxy
+// config: the target is written unquoted, and header values cannot hold
+// control characters.
+func (m *Manager) resolve(route Route) (Route, error) {
+	target, err := m.interpolate(route.Target)
+	if err != nil {
+		return route, fmt.Errorf("route %s: %w", route.Name, err)
+	}
+	if strings.ContainsAny(target, " \t\r\n\"';{}") || strings.IndexFunc(target, unicode.IsControl) >= 0 {
+		return route, fmt.Errorf("route %s: target must not contain spaces, quotes, ';', braces or control characters", route.Name)
+	}
+	route.Target = target
+
+	if len(route.Headers) > 0 {
+		headers := make(map[string]string, len(route.Headers))
+		for k, v := range route.Headers {
+			resolved, err := m.interpolate(v)
+			if err != nil {
+				return route, fmt.Errorf("route %s header %s: %w", route.Name, k, err)
+			}
+			if strings.IndexFunc(resolved, unicode.IsControl) >= 0 {
+				return route, fmt.Errorf("route %s header %s: value must not contain control characters", route.Name, k)
+			}
+			headers[k] = resolved
+		}
+		route.Headers = headers
+	}
+
+	return route, nil
+}
@@ -0,0 +1,141 @@
+package routes
+
+import "testing"
+
+func mapSource(values map[string]string) VariableSource {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	m := &Manager{variables: make(map[string]VariableSource)}
+	m.RegisterVariables("secret", mapSource(map[string]string{"API_TOKEN": "t0ken", "db.password": "pw"}))
+	m.RegisterVariables("service", mapSource(map[string]string{"orders.host": "orders-1", "orders.port": "8000"}))
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "no variables", in: "http://backend:8080", want: "http://backend:8080"},
+		{name: "secret", in: "Bearer ${secret.API_TOKEN}", want: "Bearer t0ken"},
+		{name: "dotted key", in: "${secret.db.password}", want: "pw"},
+		{
+			name: "several namespaces",
+			in:   "http://${service.orders.host}:${service.orders.port}/?t=${secret.API_TOKEN}",
+			want: "http://orders-1:8000/?t=t0ken",
+		},
+		{name: "unknown namespace", in: "${vault.API_TOKEN}", want: "${vault.API_TOKEN}", wantErr: true},
+		{name: "no env namespace", in: "${env.HOME}", want: "${env.HOME}", wantErr: true},
+		{name: "unresolved key", in: "${secret.MISSING}", want: "${secret.MISSING}", wantErr: true},
+		{
+			name:    "unresolved key keeps resolved ones",
+			in:      "${secret.API_TOKEN}:${service.billing.port}",
+			want:    "t0ken:${service.billing.port}",
+			wantErr: true,
+		},
+		{name: "not a reference", in: "${secret}", want: "${secret}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.interpolate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("interpolate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("interpolate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateReplacedNamespace(t *testing.T) {
+	m := &Manager{variables: make(map[string]VariableSource)}
+	m.RegisterVariables("secret", mapSource(map[string]string{"KEY": "old"}))
+	m.RegisterVariables("secret", mapSource(map[string]string{"KEY": "new"}))
+
+	got, err := m.interpolate("${secret.KEY}")
+	if err != nil || got != "new" {
+		t.Errorf("interpolate() = %q, %v; want %q", got, err, "new")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	m := &Manager{variables: make(map[string]VariableSource)}
+	m.RegisterVariables("secret", mapSource(map[string]string{"API_TOKEN": "t0ken"}))
+
+	route := Route{
+		Name:    "api",
+		Target:  "http://backend:8080",
+		Headers: map[string]string{"Authorization": "Bearer ${secret.API_TOKEN}"},
+	}
+	resolved, err := m.resolve(route)
+	if err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+	if got := resolved.Headers["Authorization"]; got != "Bearer t0ken" {
+		t.Errorf("resolved header = %q, want %q", got, "Bearer t0ken")
+	}
+	if got := route.Headers["Authorization"]; got != "Bearer ${secret.API_TOKEN}" {
+		t.Errorf("resolve() changed the stored route's header to %q", got)
+	}
+
+	route.Headers["X-Other"] = "${secret.MISSING}"
+	if _, err := m.resolve(route); err == nil {
+		t.Error("resolve() with an unresolved header succeeded")
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		want  bool
+	}{
+		{name: "none", route: Route{Target: "http://backend:8080"}, want: false},
+		{name: "target", route: Route{Target: "http://${secret.HOST}:8080"}, want: true},
+		{name: "header", route: Route{Target: "http://backend", Headers: map[string]string{"Authorization": "Bearer ${secret.API_TOKEN}"}}, want: true},
+		{name: "other namespace", route: Route{Target: "http://${service.orders.host}"}, want: false},
+		{name: "namespace as key", route: Route{Target: "http://${service.secret.host}"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := References(tt.route, SecretNamespace); got != tt.want {
+				t.Errorf("References() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRejectsUnsafeValues(t *testing.T) {
+	m := &Manager{variables: make(map[string]VariableSource)}
+	m.RegisterVariables("secret", mapSource(map[string]string{
+		"DOLLAR":  "pa$$word",
+		"NEWLINE": "token\nX-Injected: 1",
+		"HOST":    "backend; return 200",
+	}))
+
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr bool
+	}{
+		{name: "dollar in header", route: Route{Name: "a", Target: "http://backend", Headers: map[string]string{"X-Key": "${secret.DOLLAR}"}}},
+		{name: "newline in header", route: Route{Name: "a", Target: "http://backend", Headers: map[string]string{"X-Key": "${secret.NEWLINE}"}}, wantErr: true},
+		{name: "directive in target", route: Route{Name: "a", Target: "http://${secret.HOST}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.resolve(tt.route)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
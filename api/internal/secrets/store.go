@@ -0,0 +1,85 @@
+// Package secrets provides a file-backed secrets store
+//
+// Each secret is a single file in the secrets directory, named after the
+// secret (the same layout Docker and Kubernetes use for mounted secrets).
+// Trailing newlines are trimmed from values. Optional resource labels are
+// read from a .labels.yaml file in the same directory:
+//
+//	api_token:
+//	  team: payments
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Store reads secrets from a directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a secrets store backed by dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Get returns the value of a secret by name
+func (s *Store) Get(name string) (string, bool) {
+	if !validName(name) {
+		return "", false
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimRight(string(data), "\r\n"), true
+}
+
+// Names returns the names of all stored secrets (never their values)
+func (s *Store) Names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read secrets dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+// labelsFile holds optional per-secret labels; dotfiles are never secrets
+const labelsFile = ".labels.yaml"
+
+// Labels returns the resource labels of every secret that has any
+func (s *Store) Labels() map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+
+	data, err := os.ReadFile(filepath.Join(s.dir, labelsFile))
+	if err != nil {
+		return labels
+	}
+	yaml.Unmarshal(data, &labels)
+	return labels
+}
+
+// validName rejects names that could escape the secrets directory
+func validName(name string) bool {
+	if name == "" || strings.HasPrefix(name, ".") {
+		return false
+	}
+	return !strings.ContainsAny(name, `/\`)
+}
@@ -0,0 +1,159 @@
+// Package server runs the API's HTTP and HTTPS listeners
+//
+// The plaintext listener serves HTTP/1.1 and h2c, as used by nginx, Caddy,
+// Prometheus and the compose healthcheck on the internal network. With a
+// certificate configured (files, or a generated self-signed one) an HTTPS
+// listener serves HTTP/1.1 and HTTP/2, so the API can be exposed without a
+// proxy in front. Both have read and idle timeouts; there is no write
+// timeout because log tailing and operation polling stream responses.
+//
+// Further plaintext listeners can serve a subset of the API on other
+// addresses, e.g. the administrative endpoints on a private port, and on
+// Unix sockets for a proxy on the same host.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+const (
+	// ReadHeaderTimeout bounds how long a client may take to send headers
+	ReadHeaderTimeout = 10 * time.Second
+	// ReadTimeout bounds reading a whole request, body included
+	ReadTimeout = time.Minute
+	// IdleTimeout closes keep-alive connections without requests
+	IdleTimeout = 2 * time.Minute
+	// ShutdownTimeout is how long in-flight requests get on shutdown
+	ShutdownTimeout = 15 * time.Second
+
+	maxHeaderBytes = 1 << 20
+)
+
+// SocketMode is the file mode Unix sockets are created with
+var SocketMode os.FileMode = 0o660
+
+// New creates a server with the API's timeouts. addr is host:port, or
+// unix: and a socket path.
+func New(addr string, handler http.Handler) *http.Server {
+	if strings.HasPrefix(addr, config.UnixPrefix) {
+		handler = localPeer(handler)
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: ReadHeaderTimeout,
+		ReadTimeout:       ReadTimeout,
+		IdleTimeout:       IdleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+}
+
+// Run serves on all servers, HTTPS for those with a TLSConfig, until one
+// fails or ctx is done, then shuts them all down gracefully. All addresses
+// are bound before any is served, so a taken one fails startup.
+func Run(ctx context.Context, servers ...*http.Server) error {
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, srv := range servers {
+		l, err := listen(srv.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+
+	errs := make(chan error, len(servers))
+	for i, srv := range servers {
+		go func(srv *http.Server, l net.Listener) {
+			var err error
+			if srv.TLSConfig != nil {
+				err = srv.ServeTLS(l, "", "")
+			} else {
+				err = srv.Serve(l)
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			errs <- err
+		}(srv, listeners[i])
+	}
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		srv.Shutdown(shutdownCtx)
+	}
+	return err
+}
+
+// listen binds addr. A socket left behind by a process that did not shut
+// down cleanly is replaced; the socket is removed when the listener closes.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, config.UnixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, SocketMode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("chmod %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// localPeer reports clients of a Unix socket, which have no address, as
+// loopback, so client address checks treat them as local (and a proxy on
+// the socket as a trusted one)
+func localPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = "127.0.0.1:0"
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Redirect sends direct clients of the plaintext listener to HTTPS on
+// httpsPort. Requests forwarded by a proxy (X-Forwarded-Proto), health
+// checks and Prometheus scrapes are served as before.
+func Redirect(httpsPort string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Forwarded-Proto") != "" || r.URL.Path == "/api/v1/health" || r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 literal
+		}
+		if httpsPort != "443" {
+			host += ":" + httpsPort
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+const (
+	// selfSignedValidity is how long generated certificates are valid
+	selfSignedValidity = 365 * 24 * time.Hour
+	// selfSignedRenewBefore regenerates certificates this close to expiry
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+	// certReloadInterval is how often certificate files are checked for
+	// changes, so renewed certificates are used without a restart
+	certReloadInterval = time.Minute
+)
+
+// TLSConfig configures the HTTPS listener
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// SelfSigned generates CertFile and KeyFile when they are missing or
+	// about to expire, for Hosts
+	SelfSigned bool
+	Hosts      []string
+	// ClientCAFile enables mutual TLS: client certificates are verified
+	// against these CAs, and required with RequireClientCert
+	ClientCAFile      string
+	RequireClientCert bool
+}
+
+// NewTLSConfig returns the HTTPS settings of cfg, with externalHost named
+// in self-signed certificates; nil when TLS is not configured
+func NewTLSConfig(cfg config.TLS, externalHost string) *TLSConfig {
+	var c *TLSConfig
+	switch {
+	case cfg.CertFile != "":
+		c = &TLSConfig{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile}
+	case cfg.SelfSigned:
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if name, err := os.Hostname(); err == nil {
+			hosts = append(hosts, name)
+		}
+		if externalHost != "" {
+			hosts = append(hosts, externalHost)
+		}
+		c = &TLSConfig{
+			CertFile:   filepath.Join(cfg.Dir, "cert.pem"),
+			KeyFile:    filepath.Join(cfg.Dir, "key.pem"),
+			SelfSigned: true,
+			Hosts:      append(hosts, cfg.SelfSignedHosts...),
+		}
+	default:
+		return nil
+	}
+	c.ClientCAFile = cfg.ClientCAFile
+	c.RequireClientCert = cfg.ClientCAFile != "" && cfg.ClientAuth == "require"
+	return c
+}
+
+// Load prepares the certificate (generating a self-signed one if needed)
+// and returns a tls.Config that reloads it when the files change
+func (c *TLSConfig) Load() (*tls.Config, error) {
+	if c.SelfSigned {
+		if err := ensureSelfSigned(c.CertFile, c.KeyFile, c.Hosts); err != nil {
+			return nil, fmt.Errorf("self-signed certificate: %w", err)
+		}
+	}
+	r := &certReloader{certFile: c.CertFile, keyFile: c.KeyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.get,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA: no certificates in %s", c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		// Clients without a certificate can still use API keys unless
+		// certificates are required
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.RequireClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, nil
+}
+
+// certReloader serves a certificate pair and reloads it when the files
+// are modified
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func (r *certReloader) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) >= certReloadInterval {
+		r.checkedAt = time.Now()
+		if info, err := os.Stat(r.certFile); err == nil && info.ModTime().After(r.modTime) {
+			r.reloadLocked() // keep serving the old certificate if the new one is broken
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *certReloader) reloadLocked() error {
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	r.cert, r.modTime, r.checkedAt = &cert, info.ModTime(), time.Now()
+	return nil
+}
+
+// ensureSelfSigned writes a self-signed certificate for hosts unless a
+// valid one exists
+func ensureSelfSigned(certFile, keyFile string, hosts []string) error {
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(pair.Certificate[0]); err == nil && time.Until(leaf.NotAfter) > selfSignedRenewBefore {
+			return nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[0], Organization: []string{"Forge self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+)
+
+const (
+	checkInterval  = 10 * time.Second
+	checkTimeout   = 5 * time.Second
+	expiryInterval = time.Second
+)
+
+var checkClient = &http.Client{
+	Timeout: checkTimeout,
+	// A redirect is an answer; following it could leave the instance
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// healthURL returns the URL checked for an instance, or "" for none
+func healthURL(reg Registration) string {
+	if strings.HasPrefix(reg.HealthURL, "/") {
+		return reg.Address + reg.HealthURL
+	}
+	return reg.HealthURL
+}
+
+// check runs the health check of an instance, returning its status and
+// why it failed
+func check(reg Registration) (string, string) {
+	target := healthURL(reg)
+	if target == "" {
+		return StatusPassing, ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return StatusCritical, err.Error()
+	}
+	req.Header.Set("User-Agent", "forge-registry/1.0")
+	resp, err := checkClient.Do(req)
+	if err != nil {
+		return StatusCritical, err.Error()
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return StatusCritical, fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return StatusPassing, ""
+}
+
+// Run expires instances that missed their TTL and checks the health of
+// the others until ctx is cancelled
+func (r *Registry) Run(ctx context.Context) {
+	expiry := time.NewTicker(expiryInterval)
+	defer expiry.Stop()
+	checks := time.NewTicker(checkInterval)
+	defer checks.Stop()
+
+	r.checkAll()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expiry.C:
+			r.expire()
+		case <-checks.C:
+			r.checkAll()
+		}
+	}
+}
+
+// expire drops instances past their TTL
+func (r *Registry) expire() {
+	now := time.Now()
+	r.mu.Lock()
+	var expired []string
+	for k, inst := range r.instances {
+		if now.After(inst.Expires) {
+			expired = append(expired, inst.ID+" of "+inst.Name)
+			delete(r.instances, k)
+		}
+	}
+	r.mu.Unlock()
+	if len(expired) == 0 {
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Service registry: expired %s", strings.Join(expired, ", ")))
+	if err := r.save(); err != nil {
+		logger.Warn(fmt.Sprintf("Service registry: save: %v", err))
+	}
+	r.applyAndLog()
+}
+
+// checkAll checks the health of every instance concurrently
+func (r *Registry) checkAll() {
+	r.mu.RLock()
+	regs := make([]Registration, 0, len(r.instances))
+	for _, inst := range r.instances {
+		if inst.HealthURL != "" {
+			regs = append(regs, inst.Registration)
+		}
+	}
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	changed := false
+	for _, reg := range regs {
+		wg.Add(1)
+		go func(reg Registration) {
+			defer wg.Done()
+			if r.checkInstance(reg) {
+				mu.Lock()
+				changed = true
+				mu.Unlock()
+			}
+		}(reg)
+	}
+	wg.Wait()
+
+	if changed {
+		r.applyAndLog()
+	}
+}
+
+// checkInstance checks the health of an instance and stores the result,
+// reporting whether its status changed
+func (r *Registry) checkInstance(reg Registration) bool {
+	status, output := check(reg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inst, ok := r.instances[key(reg.Name, reg.ID)]
+	// Skip instances replaced while the check ran
+	if !ok || inst.Address != reg.Address || inst.HealthURL != reg.HealthURL {
+		return false
+	}
+	changed := inst.Status != status
+	inst.Status, inst.Output = status, output
+	return changed
+}
@@ -0,0 +1,437 @@
+// Package services is a registry apps announce themselves in: a name, an
+// address, a health URL and metadata, kept while they send heartbeats
+//
+// Instances that miss their TTL are dropped. Instances with a health URL
+// are checked by the API as well. The registry feeds the registered
+// services of GET /api/v1/health and, when instances ask for them, a route
+// per service through the proxy and Prometheus scrape targets.
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/routes"
+	"gopkg.in/yaml.v3"
+)
+
+// Instance statuses
+const (
+	StatusPassing  = "passing"
+	StatusCritical = "critical"
+	// StatusWarning is the status of a service with some of its instances
+	// critical
+	StatusWarning = "warning"
+)
+
+const (
+	// DefaultTTL is how long an instance is kept without a heartbeat
+	DefaultTTL = 30 * time.Second
+	MinTTL     = 5 * time.Second
+	MaxTTL     = time.Hour
+
+	maxInstances = 1000
+	maxMetadata  = 32
+	maxValue     = 256
+)
+
+var (
+	// ErrInvalid is returned for registrations that cannot be stored
+	ErrInvalid = errors.New("invalid registration")
+	// ErrNotFound is returned for unknown services and instances
+	ErrNotFound = errors.New("not registered")
+	// ErrRouteConflict is returned when a service's route name is taken by
+	// a route Forge does not manage for it
+	ErrRouteConflict = errors.New("route already exists")
+	// ErrTooMany is returned when the registry is full
+	ErrTooMany = errors.New("too many instances")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	idPattern   = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+	keyPattern  = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_./-]{0,62}$`)
+)
+
+// Registration is what an instance sends to register
+type Registration struct {
+	Name string `json:"name" yaml:"name"`
+	// ID tells the instances of a service apart; default the host and port
+	// of Address, such as orders-1-8000
+	ID string `json:"id" yaml:"id"`
+	// Address is the instance's base URL, such as http://orders-1:8000
+	Address string `json:"address" yaml:"address"`
+	// HealthURL, if set, is checked every 10s; a path is relative to
+	// Address and a URL must be on its host. Instances without one pass
+	// while they send heartbeats.
+	HealthURL string            `json:"health_url,omitempty" yaml:"health_url,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// TTL is how long the instance is kept without a heartbeat (default 30s)
+	TTL string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+
+	// Route, if set, has the proxy serve the service at a path
+	Route *Route `json:"route,omitempty" yaml:"route,omitempty"`
+	// MetricsPath, if set, adds the instance to Prometheus scrape targets
+	MetricsPath string `json:"metrics_path,omitempty" yaml:"metrics_path,omitempty"`
+}
+
+// Route is the proxy route of a service, named after it. It points at the
+// first passing instance asking for it, by ID.
+type Route struct {
+	Path        string `json:"path" yaml:"path"`
+	StripPrefix bool   `json:"strip_prefix,omitempty" yaml:"strip_prefix,omitempty"`
+	Host        string `json:"host,omitempty" yaml:"host,omitempty"`
+	Domain      string `json:"domain,omitempty" yaml:"domain,omitempty"`
+}
+
+// Instance is a registered instance with its state
+type Instance struct {
+	Registration
+	Status        string    `json:"status"`
+	Output        string    `json:"output,omitempty"` // why the last health check failed
+	Registered    time.Time `json:"registered"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Expires       time.Time `json:"expires"`
+
+	ttl time.Duration
+}
+
+// Service is a name with its instances
+type Service struct {
+	Name string `json:"name"`
+	// Status is passing when every instance passes, warning when some do
+	// and critical when none does
+	Status    string     `json:"status"`
+	Passing   int        `json:"passing"`
+	Instances []Instance `json:"instances"`
+	// RouteError is set while the service's route cannot be made
+	RouteError string `json:"route_error,omitempty"`
+}
+
+type storedInstance struct {
+	Registration `yaml:",inline"`
+	Registered   time.Time `yaml:"registered"`
+}
+
+type registryFile struct {
+	Instances []storedInstance `yaml:"instances"`
+}
+
+// Registry keeps registered instances
+type Registry struct {
+	mu          sync.RWMutex
+	instances   map[string]*Instance // by name/id
+	routeErrors map[string]string    // by service
+	configPath  string
+	targetsPath string
+	routes      *routes.Manager
+
+	// variables are the ${service.<name>.<field>} route variables, by
+	// service; guarded by mu
+	variables map[string]map[string]string
+
+	// applyMu serializes writing the targets file and syncing routes
+	applyMu     sync.Mutex
+	lastTargets []byte
+}
+
+// NewRegistry loads instances from configPath, which keeps them across
+// restarts, and writes scrape targets to targetsPath. rm is nil when
+// routes are disabled; otherwise routes may reference services as
+// ${service.<name>.<field>}.
+func NewRegistry(configPath, targetsPath string, rm *routes.Manager) (*Registry, error) {
+	r := &Registry{
+		instances:   make(map[string]*Instance),
+		routeErrors: make(map[string]string),
+		variables:   make(map[string]map[string]string),
+		configPath:  configPath,
+		targetsPath: targetsPath,
+		routes:      rm,
+	}
+	if err := r.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if rm != nil {
+		rm.RegisterVariables("service", r.variable)
+	}
+	r.applyAndLog()
+	return r, nil
+}
+
+func key(name, id string) string {
+	return name + "/" + id
+}
+
+// List returns all services, sorted by name
+func (r *Registry) List() []Service {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	byName := make(map[string][]Instance)
+	for _, inst := range r.instances {
+		byName[inst.Name] = append(byName[inst.Name], *inst)
+	}
+	list := make([]Service, 0, len(byName))
+	for name, instances := range byName {
+		list = append(list, r.service(name, instances))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a service with its instances
+func (r *Registry) Get(name string) (Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var instances []Instance
+	for _, inst := range r.instances {
+		if inst.Name == name {
+			instances = append(instances, *inst)
+		}
+	}
+	if len(instances) == 0 {
+		return Service{}, fmt.Errorf("service %s: %w", name, ErrNotFound)
+	}
+	return r.service(name, instances), nil
+}
+
+// service summarizes instances of one service; r.mu must be held
+func (r *Registry) service(name string, instances []Instance) Service {
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	s := Service{Name: name, Instances: instances, RouteError: r.routeErrors[name]}
+	for _, inst := range instances {
+		if inst.Status == StatusPassing {
+			s.Passing++
+		}
+	}
+	switch s.Passing {
+	case len(instances):
+		s.Status = StatusPassing
+	case 0:
+		s.Status = StatusCritical
+	default:
+		s.Status = StatusWarning
+	}
+	return s
+}
+
+// Instance returns one instance of a service
+func (r *Registry) Instance(name, id string) (Instance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	inst, ok := r.instances[key(name, id)]
+	if !ok {
+		return Instance{}, fmt.Errorf("instance %s of %s: %w", id, name, ErrNotFound)
+	}
+	return *inst, nil
+}
+
+// Register adds an instance, or replaces one with the same name and ID.
+// Its health URL is checked in the background: until that check answers,
+// a new instance is critical. It reports whether the instance was added.
+func (r *Registry) Register(reg Registration) (Instance, bool, error) {
+	ttl, err := validateRegistration(&reg)
+	if err != nil {
+		return Instance{}, false, err
+	}
+	if err := r.validateRoute(reg); err != nil {
+		return Instance{}, false, err
+	}
+
+	now := time.Now().UTC()
+	inst := &Instance{
+		Registration:  reg,
+		Registered:    now,
+		LastHeartbeat: now,
+		Expires:       now.Add(ttl),
+		ttl:           ttl,
+	}
+	inst.Status = StatusPassing
+	if reg.HealthURL != "" {
+		inst.Status, inst.Output = StatusCritical, "waiting for the first health check"
+	}
+
+	r.mu.Lock()
+	existing, exists := r.instances[key(reg.Name, reg.ID)]
+	if !exists && len(r.instances) >= maxInstances {
+		r.mu.Unlock()
+		return Instance{}, false, fmt.Errorf("%w: at most %d are registered", ErrTooMany, maxInstances)
+	}
+	if exists {
+		inst.Registered = existing.Registered
+		// Registering again keeps the last result of the same check
+		if existing.Address == reg.Address && existing.HealthURL == reg.HealthURL {
+			inst.Status, inst.Output = existing.Status, existing.Output
+		}
+	}
+	r.instances[key(reg.Name, reg.ID)] = inst
+	saved := *inst
+	r.mu.Unlock()
+
+	if err := r.save(); err != nil {
+		return Instance{}, false, err
+	}
+	r.applyAndLog()
+	if reg.HealthURL != "" {
+		go func() {
+			if r.checkInstance(reg) {
+				r.applyAndLog()
+			}
+		}()
+	}
+	return saved, !exists, nil
+}
+
+// Heartbeat keeps an instance for another TTL
+func (r *Registry) Heartbeat(name, id string) (Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inst, ok := r.instances[key(name, id)]
+	if !ok {
+		return Instance{}, fmt.Errorf("instance %s of %s: %w", id, name, ErrNotFound)
+	}
+	now := time.Now().UTC()
+	inst.LastHeartbeat = now
+	inst.Expires = now.Add(inst.ttl)
+	return *inst, nil
+}
+
+// Deregister removes an instance
+func (r *Registry) Deregister(name, id string) error {
+	r.mu.Lock()
+	if _, ok := r.instances[key(name, id)]; !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("instance %s of %s: %w", id, name, ErrNotFound)
+	}
+	delete(r.instances, key(name, id))
+	r.mu.Unlock()
+
+	if err := r.save(); err != nil {
+		return err
+	}
+	r.applyAndLog()
+	return nil
+}
+
+// validateRegistration checks a registration, fills in defaults and
+// returns its TTL
+func validateRegistration(reg *Registration) (time.Duration, error) {
+	if !namePattern.MatchString(reg.Name) {
+		return 0, fmt.Errorf("%w: name must be 1-64 characters of letters, digits, '_' or '-'", ErrInvalid)
+	}
+	u, err := url.Parse(reg.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return 0, fmt.Errorf("%w: address must be an http(s) URL without a path, such as http://orders-1:8000", ErrInvalid)
+	}
+	reg.Address = u.Scheme + "://" + u.Host
+	if reg.ID == "" {
+		reg.ID = strings.NewReplacer(":", "-", "[", "", "]", "").Replace(u.Host)
+	}
+	if !idPattern.MatchString(reg.ID) {
+		return 0, fmt.Errorf("%w: id must be 1-64 characters of letters, digits, '_', '.' or '-'", ErrInvalid)
+	}
+
+	if reg.HealthURL != "" {
+		if strings.HasPrefix(reg.HealthURL, "/") {
+			if strings.ContainsAny(reg.HealthURL, " \t\n") {
+				return 0, fmt.Errorf("%w: invalid health_url", ErrInvalid)
+			}
+		} else if h, err := url.Parse(reg.HealthURL); err != nil || (h.Scheme != "http" && h.Scheme != "https") || h.Host == "" {
+			return 0, fmt.Errorf("%w: health_url must be a path such as /healthz or an http(s) URL", ErrInvalid)
+		} else if !strings.EqualFold(h.Hostname(), u.Hostname()) {
+			// The API makes the request: other hosts would let callers
+			// probe whatever it can reach
+			return 0, fmt.Errorf("%w: health_url must be on the host of address", ErrInvalid)
+		}
+	}
+
+	if len(reg.Metadata) > maxMetadata {
+		return 0, fmt.Errorf("%w: at most %d metadata entries", ErrInvalid, maxMetadata)
+	}
+	for k, v := range reg.Metadata {
+		if !keyPattern.MatchString(k) {
+			return 0, fmt.Errorf("%w: invalid metadata key: %q", ErrInvalid, k)
+		}
+		if len(v) > maxValue {
+			return 0, fmt.Errorf("%w: metadata %s is longer than %d bytes", ErrInvalid, k, maxValue)
+		}
+	}
+
+	ttl := DefaultTTL
+	if reg.TTL != "" {
+		if ttl, err = time.ParseDuration(reg.TTL); err != nil || ttl < MinTTL || ttl > MaxTTL {
+			return 0, fmt.Errorf("%w: ttl must be a duration from %s to %s", ErrInvalid, MinTTL, MaxTTL)
+		}
+	}
+
+	if reg.MetricsPath != "" && (!strings.HasPrefix(reg.MetricsPath, "/") || strings.ContainsAny(reg.MetricsPath, "?# \t\n")) {
+		return 0, fmt.Errorf("%w: metrics_path must be a path such as /metrics", ErrInvalid)
+	}
+	if reg.Route != nil && !strings.HasPrefix(reg.Route.Path, "/") {
+		return 0, fmt.Errorf("%w: route path must start with /", ErrInvalid)
+	}
+	return ttl, nil
+}
+
+// load reads instances from the config file. They are kept for a TTL from
+// now, so instances that stopped while the API was down expire.
+func (r *Registry) load() error {
+	data, err := os.ReadFile(r.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f registryFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range f.Instances {
+		reg := s.Registration
+		ttl, err := validateRegistration(&reg)
+		if err != nil {
+			continue
+		}
+		r.instances[key(reg.Name, reg.ID)] = &Instance{
+			Registration:  reg,
+			Status:        StatusPassing,
+			Registered:    s.Registered,
+			LastHeartbeat: now,
+			Expires:       now.Add(ttl),
+			ttl:           ttl,
+		}
+	}
+	return nil
+}
+
+// save writes instances to the config file
+func (r *Registry) save() error {
+	r.mu.RLock()
+	f := registryFile{Instances: make([]storedInstance, 0, len(r.instances))}
+	for _, inst := range r.instances {
+		f.Instances = append(f.Instances, storedInstance{Registration: inst.Registration, Registered: inst.Registered})
+	}
+	r.mu.RUnlock()
+	sort.Slice(f.Instances, func(i, j int) bool {
+		return key(f.Instances[i].Name, f.Instances[i].ID) < key(f.Instances[j].Name, f.Instances[j].ID)
+	})
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.configPath, data, 0644)
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/routes"
+)
+
+// ownerLabel marks the routes of registered services; its value is the
+// service name
+const ownerLabel = "forge_service"
+
+// targetGroup is an entry of a Prometheus file_sd file
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// routeFor builds the proxy route of a service pointing at an instance
+func routeFor(inst Instance) routes.Route {
+	return routes.Route{
+		Name:        inst.Name,
+		Path:        inst.Route.Path,
+		Target:      inst.Address,
+		StripPrefix: inst.Route.StripPrefix,
+		Host:        inst.Route.Host,
+		Domain:      inst.Route.Domain,
+		Labels:      map[string]string{ownerLabel: inst.Name},
+	}
+}
+
+// validateRoute checks the route a registration asks for against the
+// proxy, before it is stored
+func (r *Registry) validateRoute(reg Registration) error {
+	if reg.Route == nil {
+		return nil
+	}
+	if r.routes == nil {
+		return fmt.Errorf("%w: routes are disabled", ErrInvalid)
+	}
+	if existing, ok := r.routes.Get(reg.Name); ok && existing.Labels[ownerLabel] == "" {
+		return fmt.Errorf("%w: %s", ErrRouteConflict, reg.Name)
+	}
+	if p := r.routes.Preview(routeFor(Instance{Registration: reg})); !p.Valid {
+		return fmt.Errorf("%w: route: %s", ErrInvalid, p.Error)
+	}
+	return nil
+}
+
+// apply brings the instance metrics, route variables, scrape targets and
+// routes in line with the registry
+func (r *Registry) apply() error {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+
+	r.mu.RLock()
+	instances := make([]Instance, 0, len(r.instances))
+	for _, inst := range r.instances {
+		instances = append(instances, *inst)
+	}
+	r.mu.RUnlock()
+	sort.Slice(instances, func(i, j int) bool {
+		return key(instances[i].Name, instances[i].ID) < key(instances[j].Name, instances[j].ID)
+	})
+
+	metrics.ServiceInstances.Reset()
+	for _, inst := range instances {
+		metrics.ServiceInstances.WithLabelValues(inst.Name, StatusPassing).Add(0)
+		metrics.ServiceInstances.WithLabelValues(inst.Name, StatusCritical).Add(0)
+		metrics.ServiceInstances.WithLabelValues(inst.Name, inst.Status).Inc()
+	}
+
+	changed := r.updateVariables(instances)
+	err := errors.Join(r.writeTargets(instances), r.syncRoutes(instances))
+	if r.routes != nil && len(changed) > 0 {
+		if rerr := r.regenerateFor(changed); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("routes: %w", rerr))
+		}
+	}
+	return err
+}
+
+// applyAndLog applies the registry, logging failures: the instances stay
+// registered, and route failures are reported on their services
+func (r *Registry) applyAndLog() {
+	if err := r.apply(); err != nil {
+		logger.Warn(fmt.Sprintf("Service registry: %v", err))
+	}
+}
+
+// writeTargets writes the instances with a metrics path as a Prometheus
+// file_sd file, when it changed
+func (r *Registry) writeTargets(instances []Instance) error {
+	groups := []targetGroup{}
+	for _, inst := range instances {
+		if inst.MetricsPath == "" {
+			continue
+		}
+		u, _ := url.Parse(inst.Address)
+		groups = append(groups, targetGroup{
+			Targets: []string{u.Host},
+			Labels: map[string]string{
+				"service":          inst.Name,
+				"instance":         inst.ID,
+				"__scheme__":       u.Scheme,
+				"__metrics_path__": inst.MetricsPath,
+			},
+		})
+	}
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, r.lastTargets) {
+		return nil
+	}
+
+	// Written whole and renamed, as Prometheus watches the file
+	if err := os.MkdirAll(filepath.Dir(r.targetsPath), 0755); err != nil {
+		return fmt.Errorf("scrape targets: %w", err)
+	}
+	tmp := r.targetsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("scrape targets: %w", err)
+	}
+	if err := os.Rename(tmp, r.targetsPath); err != nil {
+		return fmt.Errorf("scrape targets: %w", err)
+	}
+	r.lastTargets = data
+	return nil
+}
+
+// syncRoutes makes a route for every service with an instance asking for
+// one, pointing at the first passing instance or, with none passing, the
+// first. Services whose route name is taken by another route are skipped.
+func (r *Registry) syncRoutes(instances []Instance) error {
+	if r.routes == nil {
+		return nil
+	}
+	chosen := preferred(instances, func(inst Instance) bool { return inst.Route != nil })
+
+	routeErrors := make(map[string]string)
+	var desired []routes.Route
+	for name, inst := range chosen {
+		if existing, ok := r.routes.Get(name); ok && existing.Labels[ownerLabel] == "" {
+			routeErrors[name] = fmt.Sprintf("%v: %s", ErrRouteConflict, name)
+			continue
+		}
+		desired = append(desired, routeFor(inst))
+	}
+	sort.Slice(desired, func(i, j int) bool { return desired[i].Name < desired[j].Name })
+
+	err := r.routes.Sync(ownerLabel, desired)
+	if err != nil {
+		for _, route := range desired {
+			routeErrors[route.Name] = err.Error()
+		}
+		err = fmt.Errorf("routes: %w", err)
+	}
+	r.mu.Lock()
+	r.routeErrors = routeErrors
+	r.mu.Unlock()
+	return err
+}
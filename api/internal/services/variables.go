@@ -0,0 +1,94 @@
+package services
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// preferred picks an instance per service among those keep accepts: the
+// first passing one by ID or, with none passing, the first. instances
+// must be sorted.
+func preferred(instances []Instance, keep func(Instance) bool) map[string]Instance {
+	chosen := make(map[string]Instance)
+	for _, inst := range instances {
+		if !keep(inst) {
+			continue
+		}
+		if current, ok := chosen[inst.Name]; !ok || (current.Status != StatusPassing && inst.Status == StatusPassing) {
+			chosen[inst.Name] = inst
+		}
+	}
+	return chosen
+}
+
+// instanceVariables are the fields routes can reference for an instance:
+// ${service.orders.port} is 8000 for http://orders-1:8000
+func instanceVariables(inst Instance) map[string]string {
+	u, _ := url.Parse(inst.Address)
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return map[string]string{
+		"address": inst.Address,
+		"scheme":  u.Scheme,
+		"host":    u.Hostname(),
+		"port":    port,
+		"id":      inst.ID,
+	}
+}
+
+// variable resolves the "<name>.<field>" of a ${service.<name>.<field>}
+// route variable
+func (r *Registry) variable(key string) (string, bool) {
+	name, field, ok := strings.Cut(key, ".")
+	if !ok {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	value, ok := r.variables[name][field]
+	return value, ok
+}
+
+// updateVariables sets the route variables of each service from its
+// preferred instance and returns the services whose values changed.
+// Services left without instances keep their last values, so routes
+// referencing them still generate (and fail upstream) instead of blocking
+// the config of every route.
+func (r *Registry) updateVariables(instances []Instance) []string {
+	var changed []string
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, inst := range preferred(instances, func(Instance) bool { return true }) {
+		vars := instanceVariables(inst)
+		if !reflect.DeepEqual(r.variables[name], vars) {
+			r.variables[name] = vars
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// regenerateFor regenerates the proxy config when a route references one
+// of the services
+func (r *Registry) regenerateFor(names []string) error {
+	for _, route := range r.routes.List() {
+		values := []string{route.Target}
+		for _, v := range route.Headers {
+			values = append(values, v)
+		}
+		for _, v := range values {
+			for _, name := range names {
+				if strings.Contains(v, "${service."+name+".") {
+					return r.routes.Regenerate()
+				}
+			}
+		}
+	}
+	return nil
+}
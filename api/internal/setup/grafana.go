@@ -0,0 +1,32 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+// SeedDashboards asks the Grafana at cfg to (re)load the provisioned Forge
+// dashboards as its admin user
+func SeedDashboards(ctx context.Context, cfg config.Grafana) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.URL+"/api/admin/provisioning/dashboards/reload", nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(cfg.User, cfg.Password)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("grafana dashboard reload failed: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Package setup manages the first-boot setup state of a Forge install
+//
+// Until setup is completed the API exposes a one-time flow that creates the
+// first admin and its API key, records the external hostname and the chosen
+// subsystems. The state is persisted as YAML so it survives restarts.
+package setup
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Subsystems that can be enabled during setup (match docker compose profiles)
+var Subsystems = []string{"db", "cache", "observability"}
+
+// usernamePattern restricts admin usernames to simple identifiers
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,64}$`)
+
+// Admin is the first administrator created during setup
+type Admin struct {
+	Username  string    `json:"username" yaml:"username"`
+	KeyHash   string    `json:"-" yaml:"key_hash"` // sha256 of the API key, never the key itself
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// State is the persisted setup state
+type State struct {
+	Completed    bool      `json:"completed" yaml:"completed"`
+	CompletedAt  time.Time `json:"completed_at,omitempty" yaml:"completed_at,omitempty"`
+	ExternalHost string    `json:"external_host,omitempty" yaml:"external_host,omitempty"`
+	Subsystems   []string  `json:"subsystems,omitempty" yaml:"subsystems,omitempty"`
+	Admin        *Admin    `json:"admin,omitempty" yaml:"admin,omitempty"`
+}
+
+// Request is the input for completing setup
+type Request struct {
+	AdminUser      string   `json:"admin_user"`
+	ExternalHost   string   `json:"external_host"`
+	Subsystems     []string `json:"subsystems"`
+	SeedDashboards bool     `json:"seed_dashboards"`
+}
+
+// Manager handles setup state
+type Manager struct {
+	mu    sync.RWMutex
+	path  string
+	state State
+}
+
+// NewManager creates a setup manager backed by the given state file
+func NewManager(path string) (*Manager, error) {
+	m := &Manager{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &m.state); err != nil {
+		return nil, fmt.Errorf("failed to parse setup state: %w", err)
+	}
+
+	return m, nil
+}
+
+// Required reports whether first-boot setup still needs to run
+func (m *Manager) Required() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.state.Completed
+}
+
+// State returns a copy of the current setup state
+func (m *Manager) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	st := m.state
+	st.Subsystems = append([]string(nil), m.state.Subsystems...)
+	if m.state.Admin != nil {
+		admin := *m.state.Admin
+		st.Admin = &admin
+	}
+	return st
+}
+
+// VerifyAdminKey reports whether key is the admin API key created during setup
+func (m *Manager) VerifyAdminKey(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.state.Admin == nil || key == "" {
+		return false
+	}
+	return hashKey(key) == m.state.Admin.KeyHash
+}
+
+// Complete validates the request, creates the admin and persists the state.
+// It returns the generated admin API key, which is only ever shown once.
+func (m *Manager) Complete(req Request) (string, error) {
+	if !usernamePattern.MatchString(req.AdminUser) {
+		return "", fmt.Errorf("admin_user must be 3-64 characters of letters, digits, '.', '_' or '-'")
+	}
+	for _, s := range req.Subsystems {
+		if !isSubsystem(s) {
+			return "", fmt.Errorf("unknown subsystem: %s", s)
+		}
+	}
+	if len(req.Subsystems) == 0 {
+		req.Subsystems = Subsystems
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state.Completed {
+		return "", ErrAlreadyCompleted
+	}
+
+	now := time.Now().UTC()
+	next := State{
+		Completed:    true,
+		CompletedAt:  now,
+		ExternalHost: req.ExternalHost,
+		Subsystems:   req.Subsystems,
+		Admin: &Admin{
+			Username:  req.AdminUser,
+			KeyHash:   hashKey(key),
+			CreatedAt: now,
+		},
+	}
+
+	if err := m.save(next); err != nil {
+		return "", err
+	}
+	m.state = next
+
+	return key, nil
+}
+
+// ErrAlreadyCompleted is returned when setup is attempted a second time
+var ErrAlreadyCompleted = fmt.Errorf("setup has already been completed")
+
+// save writes the state file
+func (m *Manager) save(st State) error {
+	data, err := yaml.Marshal(&st)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+
+	// State contains the admin key hash, keep it private
+	return os.WriteFile(m.path, data, 0600)
+}
+
+func isSubsystem(name string) bool {
+	for _, s := range Subsystems {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// generateKey creates a random API key with a recognizable prefix
+func generateKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return "forge_" + hex.EncodeToString(b), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,322 @@
+// Package snapshots periodically stores coarse PromQL results in MySQL
+//
+// Prometheus keeps high-resolution data for a short retention window.
+// Snapshots sample selected queries at a low rate (e.g. hourly) into a
+// MySQL table so months of history survive a Prometheus wipe, which is
+// enough for capacity planning.
+package snapshots
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/observe"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MinInterval is the shortest allowed snapshot interval
+	MinInterval = 5 * time.Minute
+	// DefaultInterval is used when a series does not set one
+	DefaultInterval = time.Hour
+	// DefaultRetention is how long snapshots are kept
+	DefaultRetention = 365 * 24 * time.Hour
+
+	schedulerTick = time.Minute
+)
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,128}$`)
+
+// Series is a PromQL query to snapshot periodically
+type Series struct {
+	Name     string `json:"name" yaml:"name"`
+	Query    string `json:"query" yaml:"query"`
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"` // Go duration, default 1h
+
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"` // resource tags
+}
+
+// Point is a stored snapshot value
+type Point struct {
+	TimestampMs int64   `json:"timestamp_ms"`
+	Value       float64 `json:"value"`
+}
+
+// StoredSeries is a labeled set of stored points
+type StoredSeries struct {
+	Labels map[string]string `json:"labels"`
+	Points []Point           `json:"points"`
+}
+
+type seriesFile struct {
+	Series []Series `yaml:"series"`
+}
+
+// Manager holds snapshot definitions and runs the snapshot job
+type Manager struct {
+	mu         sync.RWMutex
+	series     map[string]Series
+	lastRun    map[string]time.Time
+	configPath string
+	table      string
+	db         *sql.DB
+	prom       *observe.PrometheusClient
+	retention  time.Duration
+}
+
+// NewManager loads snapshot definitions and prepares the storage table
+func NewManager(configPath string, db *sql.DB, database string, prom *observe.PrometheusClient) (*Manager, error) {
+	m := &Manager{
+		series:     make(map[string]Series),
+		lastRun:    make(map[string]time.Time),
+		configPath: configPath,
+		table:      fmt.Sprintf("`%s`.`metric_snapshots`", database),
+		db:         db,
+		prom:       prom,
+		retention:  DefaultRetention,
+	}
+
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := m.migrate(context.Background(), database); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot table: %w", err)
+	}
+
+	return m, nil
+}
+
+// migrate creates the database and table if missing
+func (m *Manager) migrate(ctx context.Context, database string) error {
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database)); err != nil {
+		return err
+	}
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(128) NOT NULL,
+		labels JSON NOT NULL,
+		ts DATETIME(3) NOT NULL,
+		value DOUBLE NOT NULL,
+		INDEX idx_name_ts (name, ts)
+	)`)
+	return err
+}
+
+// List returns all snapshot definitions sorted by name
+func (m *Manager) List() []Series {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Series, 0, len(m.series))
+	for _, s := range m.series {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Add creates or updates a snapshot definition
+func (m *Manager) Add(s Series) error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("name must be 1-128 characters of letters, digits, '_' or '-'")
+	}
+	if s.Query == "" {
+		return fmt.Errorf("query is required")
+	}
+	if _, err := parseInterval(s.Interval); err != nil {
+		return err
+	}
+	if err := resources.ValidateLabels(s.Labels); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.series[s.Name] = s
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Remove deletes a snapshot definition. Stored points are kept.
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.series[name]; !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("snapshot series not found: %s", name)
+	}
+	delete(m.series, name)
+	delete(m.lastRun, name)
+	m.mu.Unlock()
+
+	return m.save()
+}
+
+// Points returns stored points for a series between from and to
+func (m *Manager) Points(ctx context.Context, name string, from, to time.Time) ([]StoredSeries, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT labels, CAST(UNIX_TIMESTAMP(ts) * 1000 AS SIGNED), value FROM "+m.table+" WHERE name = ? AND ts BETWEEN ? AND ? ORDER BY ts",
+		name, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byLabels := make(map[string]*StoredSeries)
+	var order []string
+	for rows.Next() {
+		var rawLabels string
+		var tsMs int64
+		var value float64
+		if err := rows.Scan(&rawLabels, &tsMs, &value); err != nil {
+			return nil, err
+		}
+
+		s, ok := byLabels[rawLabels]
+		if !ok {
+			s = &StoredSeries{Labels: map[string]string{}}
+			json.Unmarshal([]byte(rawLabels), &s.Labels)
+			byLabels[rawLabels] = s
+			order = append(order, rawLabels)
+		}
+		s.Points = append(s.Points, Point{TimestampMs: tsMs, Value: value})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]StoredSeries, 0, len(order))
+	for _, k := range order {
+		result = append(result, *byLabels[k])
+	}
+	return result, nil
+}
+
+// Run snapshots due series until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		m.runDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue snapshots every series whose interval has elapsed and prunes old points
+func (m *Manager) runDue(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	var due []Series
+	for name, s := range m.series {
+		interval, _ := parseInterval(s.Interval)
+		if now.Sub(m.lastRun[name]) >= interval {
+			due = append(due, s)
+			m.lastRun[name] = now
+		}
+	}
+	m.mu.Unlock()
+
+	for _, s := range due {
+		if err := m.snapshot(ctx, s, now); err != nil {
+			logger.Error("Metric snapshot failed: "+s.Name, err)
+		}
+	}
+
+	if len(due) > 0 {
+		if _, err := m.db.ExecContext(ctx, "DELETE FROM "+m.table+" WHERE ts < ?", now.Add(-m.retention).UTC()); err != nil {
+			logger.Error("Metric snapshot pruning failed", err)
+		}
+	}
+}
+
+// snapshot evaluates a series' query and stores the result
+func (m *Manager) snapshot(ctx context.Context, s Series, at time.Time) error {
+	result, err := m.prom.Query(ctx, s.Query, at)
+	if err != nil {
+		return err
+	}
+
+	for _, series := range result {
+		labels, err := json.Marshal(series.Labels)
+		if err != nil {
+			return err
+		}
+		for _, sample := range series.Samples {
+			// MySQL DOUBLE cannot store NaN/Inf
+			if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+				continue
+			}
+			_, err := m.db.ExecContext(ctx,
+				"INSERT INTO "+m.table+" (name, labels, ts, value) VALUES (?, ?, ?, ?)",
+				s.Name, string(labels), sample.Timestamp.UTC(), sample.Value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseInterval parses a series interval, applying the default and minimum
+func parseInterval(s string) (time.Duration, error) {
+	if s == "" {
+		return DefaultInterval, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval: %w", err)
+	}
+	if d < MinInterval {
+		return 0, fmt.Errorf("interval must be at least %s", MinInterval)
+	}
+	return d, nil
+}
+
+// load reads definitions from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f seriesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range f.Series {
+		m.series[s.Name] = s
+	}
+	return nil
+}
+
+// save writes definitions to the config file
+func (m *Manager) save() error {
+	f := seriesFile{Series: m.List()}
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
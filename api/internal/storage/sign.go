@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version 4, as S3, MinIO and STS expect it
+// (https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_sigv.html)
+
+const (
+	signAlgorithm   = "AWS4-HMAC-SHA256"
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// signer signs requests with one key pair for one region
+type signer struct {
+	accessKey string
+	secretKey string
+	region    string
+}
+
+// sign adds the date, payload hash and Authorization headers to req.
+// payloadHash is the hex SHA-256 of the body, or unsignedPayload for
+// streamed object bodies.
+func (s signer) sign(req *http.Request, service, payloadHash string, now time.Time) {
+	date := now.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names, canonicalHeaders := canonicalHeaders(req)
+	canonical := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		names,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(date, service)
+	signature := s.signature(date, service, canonical)
+	req.Header.Set("Authorization", signAlgorithm+" Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+names+", Signature="+signature)
+}
+
+// presign returns u with a query-string signature that lets anyone holding
+// it make a method request until expires has passed
+func (s signer) presign(method string, u *url.URL, expires time.Duration, now time.Time) string {
+	date := now.UTC().Format(amzDateFormat)
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", signAlgorithm)
+	q.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(date, "s3"))
+	q.Set("X-Amz-Date", date)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		method,
+		canonicalPath(u),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	q.Set("X-Amz-Signature", s.signature(date, "s3", canonical))
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(q)
+	return signed.String()
+}
+
+func (s signer) scope(date, service string) string {
+	return date[:8] + "/" + s.region + "/" + service + "/aws4_request"
+}
+
+// signature signs a canonical request with the key derived for the day,
+// region and service
+func (s signer) signature(date, service, canonical string) string {
+	toSign := signAlgorithm + "\n" + date + "\n" + s.scope(date, service) + "\n" + hashHex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// canonicalHeaders returns the signed header names and their canonical
+// form: the host, the content type and every X-Amz-* header
+func canonicalHeaders(req *http.Request) (names, canonical string) {
+	values := map[string]string{"host": req.URL.Host}
+	for name, v := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			values[lower] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + ":" + values[k] + "\n")
+	}
+	return strings.Join(keys, ";"), b.String()
+}
+
+// canonicalPath URI-encodes each segment of the path once
+func canonicalPath(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return uriEncode(u.Path, false)
+}
+
+// canonicalQuery sorts and URI-encodes query parameters
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and '/'
+// unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,480 @@
+// Package storage is a client for S3-compatible object stores: the MinIO
+// bundled with Forge or any S3 endpoint
+//
+// It covers what the StorageService offers: buckets, objects and presigned
+// URLs that let clients upload and download without passing the bytes
+// through the API, plus temporary credentials from the store's STS API.
+// Requests are signed with AWS Signature Version 4; MinIO expects buckets
+// addressed by path (endpoint/bucket), AWS by host (bucket.endpoint).
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forge/api/internal/config"
+)
+
+const (
+	// DefaultPresignExpiry is how long presigned URLs work unless asked
+	// otherwise
+	DefaultPresignExpiry = 15 * time.Minute
+	// MaxPresignExpiry is the longest lifetime Signature Version 4 allows
+	MaxPresignExpiry = 7 * 24 * time.Hour
+
+	maxKeyLength = 1024
+	maxListKeys  = 1000
+)
+
+var (
+	// ErrInvalidRequest is returned for bad bucket names, keys and options
+	ErrInvalidRequest = errors.New("invalid storage request")
+	// ErrNotFound is returned for missing buckets and objects
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a bucket exists or is not empty
+	ErrConflict = errors.New("conflict")
+	// ErrDenied is returned when the store refuses the credentials
+	ErrDenied = errors.New("access denied")
+)
+
+// Bucket is a bucket in the store
+type Bucket struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+// Object describes a stored object
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// ListOptions selects the objects ListObjects returns
+type ListOptions struct {
+	Prefix string
+	// Delimiter groups keys sharing a prefix up to it, e.g. "/" for a
+	// directory-like listing
+	Delimiter string
+	// MaxKeys bounds one page; 0 means the maximum of 1000
+	MaxKeys int
+	// ContinuationToken continues a truncated listing
+	ContinuationToken string
+}
+
+// Listing is a page of objects
+type Listing struct {
+	Objects []Object `json:"objects"`
+	// Prefixes are the groups collapsed by the delimiter
+	Prefixes              []string `json:"prefixes,omitempty"`
+	Truncated             bool     `json:"truncated"`
+	NextContinuationToken string   `json:"next_continuation_token,omitempty"`
+}
+
+// Client talks to one S3-compatible endpoint
+type Client struct {
+	endpoint  *url.URL
+	public    *url.URL
+	bucket    string
+	pathStyle bool
+	roleARN   string
+	signer    signer
+	client    *http.Client
+}
+
+// NewClient creates a client for the store in cfg. It does not connect; a
+// deps.Supervisor tracks whether the store is reachable.
+func NewClient(cfg config.Storage) (*Client, error) {
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("storage endpoint: %w", err)
+	}
+	public, err := url.Parse(strings.TrimRight(cfg.ClientURL(), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("storage public url: %w", err)
+	}
+	return &Client{
+		endpoint:  endpoint,
+		public:    public,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		roleARN:   cfg.RoleARN,
+		signer:    signer{accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, region: cfg.Region},
+		// Transfers are bounded by the caller's context, not a timeout
+		client: &http.Client{},
+	}, nil
+}
+
+// DefaultBucket returns the bucket used when a request names none
+func (c *Client) DefaultBucket() string {
+	return c.bucket
+}
+
+// Bucket returns name, or the default bucket when it is empty
+func (c *Client) Bucket(name string) string {
+	if name == "" {
+		return c.bucket
+	}
+	return name
+}
+
+// PublicURL returns the URL clients reach the store at
+func (c *Client) PublicURL() string {
+	return c.public.String()
+}
+
+// Region returns the region requests are signed for
+func (c *Client) Region() string {
+	return c.signer.region
+}
+
+// PathStyle reports whether buckets are addressed by path
+func (c *Client) PathStyle() bool {
+	return c.pathStyle
+}
+
+// Ping lists buckets to check the store is reachable and accepts the
+// credentials
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.ListBuckets(ctx)
+	return err
+}
+
+// EnsureBucket creates the default bucket unless it exists
+func (c *Client) EnsureBucket(ctx context.Context) error {
+	err := c.CreateBucket(ctx, c.bucket)
+	if errors.Is(err, ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+// ListBuckets returns every bucket the credentials can see
+func (c *Client) ListBuckets(ctx context.Context) ([]Bucket, error) {
+	resp, err := c.do(ctx, "GET", "", "", nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Buckets []struct {
+			Name         string    `xml:"Name"`
+			CreationDate time.Time `xml:"CreationDate"`
+		} `xml:"Buckets>Bucket"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode bucket list: %w", err)
+	}
+	buckets := make([]Bucket, 0, len(result.Buckets))
+	for _, b := range result.Buckets {
+		buckets = append(buckets, Bucket{Name: b.Name, Created: b.CreationDate})
+	}
+	return buckets, nil
+}
+
+// CreateBucket creates a bucket in the client's region; ErrConflict when
+// it already exists
+func (c *Client) CreateBucket(ctx context.Context, name string) error {
+	if err := checkBucket(name); err != nil {
+		return err
+	}
+	var body []byte
+	if region := c.signer.region; region != "us-east-1" {
+		body = []byte(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+			`<LocationConstraint>` + region + `</LocationConstraint></CreateBucketConfiguration>`)
+	}
+	resp, err := c.do(ctx, "PUT", name, "", nil, nil, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteBucket deletes an empty bucket; ErrConflict when it is not empty
+func (c *Client) DeleteBucket(ctx context.Context, name string) error {
+	if err := checkBucket(name); err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, "DELETE", name, "", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PutObject stores size bytes read from body under key, replacing any
+// object there
+func (c *Client) PutObject(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) (Object, error) {
+	if err := checkObject(bucket, key); err != nil {
+		return Object{}, err
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := http.Header{"Content-Type": {contentType}}
+	req, err := c.newRequest(ctx, "PUT", bucket, key, nil, header, body)
+	if err != nil {
+		return Object{}, err
+	}
+	req.ContentLength = size
+	resp, err := c.send(req, unsignedPayload)
+	if err != nil {
+		return Object{}, err
+	}
+	resp.Body.Close()
+
+	return Object{
+		Key:          key,
+		Size:         size,
+		ETag:         strings.Trim(resp.Header.Get("ETag"), `"`),
+		ContentType:  contentType,
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// GetObject opens an object for reading; the caller closes the body
+func (c *Client) GetObject(ctx context.Context, bucket, key string) (Object, io.ReadCloser, error) {
+	if err := checkObject(bucket, key); err != nil {
+		return Object{}, nil, err
+	}
+	resp, err := c.do(ctx, "GET", bucket, key, nil, nil, nil)
+	if err != nil {
+		return Object{}, nil, err
+	}
+	obj := Object{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	obj.LastModified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return obj, resp.Body, nil
+}
+
+// ListObjects returns a page of the objects in a bucket, sorted by key
+func (c *Client) ListObjects(ctx context.Context, bucket string, opts ListOptions) (*Listing, error) {
+	if err := checkBucket(bucket); err != nil {
+		return nil, err
+	}
+	if opts.MaxKeys < 0 || opts.MaxKeys > maxListKeys {
+		return nil, fmt.Errorf("%w: max_keys must be between 1 and %d", ErrInvalidRequest, maxListKeys)
+	}
+	q := url.Values{"list-type": {"2"}}
+	if opts.Prefix != "" {
+		q.Set("prefix", opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		q.Set("delimiter", opts.Delimiter)
+	}
+	if opts.MaxKeys > 0 {
+		q.Set("max-keys", strconv.Itoa(opts.MaxKeys))
+	}
+	if opts.ContinuationToken != "" {
+		q.Set("continuation-token", opts.ContinuationToken)
+	}
+	resp, err := c.do(ctx, "GET", bucket, "", q, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+		Contents              []struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			ETag         string    `xml:"ETag"`
+			LastModified time.Time `xml:"LastModified"`
+		} `xml:"Contents"`
+		CommonPrefixes []struct {
+			Prefix string `xml:"Prefix"`
+		} `xml:"CommonPrefixes"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode object list: %w", err)
+	}
+
+	listing := &Listing{
+		Objects:               make([]Object, 0, len(result.Contents)),
+		Truncated:             result.IsTruncated,
+		NextContinuationToken: result.NextContinuationToken,
+	}
+	for _, o := range result.Contents {
+		listing.Objects = append(listing.Objects, Object{
+			Key:          o.Key,
+			Size:         o.Size,
+			ETag:         strings.Trim(o.ETag, `"`),
+			LastModified: o.LastModified,
+		})
+	}
+	for _, p := range result.CommonPrefixes {
+		listing.Prefixes = append(listing.Prefixes, p.Prefix)
+	}
+	return listing, nil
+}
+
+// DeleteObject deletes an object; deleting a missing one succeeds
+func (c *Client) DeleteObject(ctx context.Context, bucket, key string) error {
+	if err := checkObject(bucket, key); err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, "DELETE", bucket, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Presign returns a URL on the public endpoint that allows one GET or PUT
+// of an object until it expires, and when that is
+func (c *Client) Presign(method, bucket, key string, expires time.Duration) (string, time.Time, error) {
+	if err := checkObject(bucket, key); err != nil {
+		return "", time.Time{}, err
+	}
+	if method != "GET" && method != "PUT" {
+		return "", time.Time{}, fmt.Errorf("%w: method must be GET or PUT", ErrInvalidRequest)
+	}
+	if expires == 0 {
+		expires = DefaultPresignExpiry
+	}
+	if expires < time.Second || expires > MaxPresignExpiry {
+		return "", time.Time{}, fmt.Errorf("%w: expiry must be between 1s and %s", ErrInvalidRequest, MaxPresignExpiry)
+	}
+	now := time.Now()
+	return c.signer.presign(method, c.objectURL(c.public, bucket, key), expires, now), now.Add(expires).Truncate(time.Second), nil
+}
+
+// objectURL addresses a bucket, or an object when key is set, at base
+func (c *Client) objectURL(base *url.URL, bucket, key string) *url.URL {
+	u := *base
+	path := "/" + key
+	switch {
+	case bucket == "":
+	case c.pathStyle && key == "":
+		path = "/" + bucket
+	case c.pathStyle:
+		path = "/" + bucket + "/" + key
+	default:
+		u.Host = bucket + "." + u.Host
+	}
+	// The path is sent exactly as it is signed
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	return &u
+}
+
+func (c *Client) newRequest(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body io.Reader) (*http.Request, error) {
+	u := c.objectURL(c.endpoint, bucket, key)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return req, nil
+}
+
+// do sends a request with an in-memory body and checks its status
+func (c *Client) do(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, bucket, key, query, header, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return c.send(req, hashHex(body))
+}
+
+// send signs req for S3, sends it and turns error responses into errors
+func (c *Client) send(req *http.Request, payloadHash string) (*http.Response, error) {
+	c.signer.sign(req, "s3", payloadHash, time.Now())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// responseError reads an S3 error response
+func responseError(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	xml.Unmarshal(data, &body)
+	msg := body.Code
+	if body.Message != "" {
+		msg += ": " + body.Message
+	}
+	if msg == "" {
+		// HEAD requests and some proxies answer without a body
+		msg = resp.Status
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, msg)
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", ErrConflict, msg)
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrDenied, msg)
+	case http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, msg)
+	}
+	return fmt.Errorf("storage: %s", msg)
+}
+
+// ValidBucket reports whether name is a valid bucket name: 3-63 lowercase
+// letters, digits, '.' and '-', starting and ending with a letter or digit
+func ValidBucket(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case (c == '-' || c == '.') && i > 0 && i < len(name)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func checkBucket(name string) error {
+	if !ValidBucket(name) {
+		return fmt.Errorf("%w: bucket names are 3-63 lowercase letters, digits, '.' or '-'", ErrInvalidRequest)
+	}
+	return nil
+}
+
+func checkObject(bucket, key string) error {
+	if err := checkBucket(bucket); err != nil {
+		return err
+	}
+	if key == "" || len(key) > maxKeyLength {
+		return fmt.Errorf("%w: keys are 1-%d bytes", ErrInvalidRequest, maxKeyLength)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// clockSkewWarn is the skew above which a time source is flagged.
+// HTTP Date headers only have one-second resolution, so anything tighter
+// would produce false positives for Loki/Tempo.
+const clockSkewWarn = 2 * time.Second
+
+// TimeSource returns the current time as seen by a remote service
+type TimeSource func(ctx context.Context) (time.Time, error)
+
+// SourceSkew is the measured skew of one remote service against the API host
+type SourceSkew struct {
+	Source     string  `json:"source"`
+	SkewMS     float64 `json:"skew_ms"` // remote minus local; positive means remote is ahead
+	RTTMS      float64 `json:"rtt_ms"`
+	Resolution string  `json:"resolution"`
+	OK         bool    `json:"ok"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// ClockReport summarizes host time sync and skew against downstream services
+type ClockReport struct {
+	HostTime string       `json:"host_time"`
+	NTP      *NTPStatus   `json:"ntp,omitempty"`
+	Sources  []SourceSkew `json:"sources"`
+	MaxSkew  float64      `json:"max_skew_ms"`
+}
+
+// ClockChecker measures clock skew between the API host and its dependencies
+type ClockChecker struct {
+	mu      sync.RWMutex
+	sources map[string]timeSourceEntry
+}
+
+type timeSourceEntry struct {
+	source     TimeSource
+	resolution time.Duration
+}
+
+// NewClockChecker creates a checker with no sources registered
+func NewClockChecker() *ClockChecker {
+	return &ClockChecker{sources: make(map[string]timeSourceEntry)}
+}
+
+// AddSource registers a named time source. resolution is the precision of
+// the remote clock reading and widens the skew tolerance accordingly.
+func (c *ClockChecker) AddSource(name string, resolution time.Duration, source TimeSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources[name] = timeSourceEntry{source: source, resolution: resolution}
+}
+
+// Check measures all sources concurrently
+func (c *ClockChecker) Check(ctx context.Context) *ClockReport {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.sources))
+	for name := range c.sources {
+		names = append(names, name)
+	}
+	sources := c.sources
+	c.mu.RUnlock()
+	sort.Strings(names)
+
+	report := &ClockReport{
+		HostTime: time.Now().UTC().Format(time.RFC3339Nano),
+		Sources:  make([]SourceSkew, len(names)),
+	}
+	if ntp, err := GetNTPStatus(); err == nil {
+		report.NTP = ntp
+	}
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, entry timeSourceEntry) {
+			defer wg.Done()
+			report.Sources[i] = measure(ctx, name, entry)
+		}(i, name, sources[name])
+	}
+	wg.Wait()
+
+	for _, s := range report.Sources {
+		if s.Error == "" && math.Abs(s.SkewMS) > math.Abs(report.MaxSkew) {
+			report.MaxSkew = s.SkewMS
+		}
+	}
+
+	return report
+}
+
+// measure reads a remote clock and compares it with the local midpoint of the request
+func measure(ctx context.Context, name string, entry timeSourceEntry) SourceSkew {
+	result := SourceSkew{Source: name, Resolution: entry.resolution.String()}
+
+	before := time.Now()
+	remote, err := entry.source(ctx)
+	after := time.Now()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	rtt := after.Sub(before)
+	local := before.Add(rtt / 2)
+	skew := remote.Sub(local)
+
+	// A truncated reading (HTTP Date) is on average half a unit behind
+	if entry.resolution > 0 {
+		skew += entry.resolution / 2
+	}
+
+	result.SkewMS = float64(skew) / float64(time.Millisecond)
+	result.RTTMS = float64(rtt) / float64(time.Millisecond)
+
+	tolerance := clockSkewWarn + entry.resolution + rtt/2
+	result.OK = skew.Abs() <= tolerance
+	return result
+}
+
+// HTTPDateSource reads a service's clock from the Date header of a GET to url
+func HTTPDateSource(url string) TimeSource {
+	client := &http.Client{Timeout: 3 * time.Second}
+	return func(ctx context.Context) (time.Time, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp.Body.Close()
+
+		date := resp.Header.Get("Date")
+		if date == "" {
+			return time.Time{}, fmt.Errorf("no Date header in response")
+		}
+		return http.ParseTime(date)
+	}
+}
+
+// clockRecommendations turns a clock report into recommendations
+func clockRecommendations(report *ClockReport) []Recommendation {
+	var recs []Recommendation
+	if report == nil {
+		return recs
+	}
+
+	if report.NTP != nil && !report.NTP.Synchronized {
+		recs = append(recs, Recommendation{
+			Severity: SeverityCritical,
+			Resource: ResourceClock,
+			Action:   SuggestEnableNTP,
+			Message:  "🔴 Host clock is not NTP-synchronized. TTLs, log ordering and trace durations may be wrong; enable chrony/systemd-timesyncd on the host.",
+		})
+	}
+	for _, s := range report.Sources {
+		if s.Error == "" && !s.OK {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityCritical,
+				Resource:  ResourceClock,
+				Target:    s.Source,
+				Action:    SuggestCheckTimeSync,
+				Value:     s.SkewMS,
+				Threshold: float64(clockSkewWarn / time.Millisecond),
+				Message:   fmt.Sprintf("🔴 Clock skew of %.0fms between API and %s. Check time sync on both hosts.", s.SkewMS, s.Source),
+			})
+		}
+	}
+	return recs
+}
+
+// AddClockReport attaches a clock report to the system info and merges its
+// recommendations with the container ones
+func (info *SystemInfo) AddClockReport(report *ClockReport) {
+	info.Clock = report
+	info.addRecommendations(clockRecommendations(report))
+}
@@ -0,0 +1,166 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrContainerNotFound is returned for containers Docker does not have
+var ErrContainerNotFound = errors.New("container not found")
+
+// ContainerSpec describes a container to create
+type ContainerSpec struct {
+	Image   string
+	Cmd     []string
+	Env     map[string]string
+	Labels  map[string]string
+	Ports   []PortBinding
+	Binds   []string // source:target[:ro]
+	Restart string   // no, always, unless-stopped or on-failure
+	Network string   // network to join, with the container name as alias
+}
+
+// PortBinding publishes a container port, on HostPort when set
+type PortBinding struct {
+	Container int    `json:"container" yaml:"container"`
+	Host      int    `json:"host,omitempty" yaml:"host,omitempty"`
+	Protocol  string `json:"protocol,omitempty" yaml:"protocol,omitempty"` // tcp (default) or udp
+}
+
+// ContainerSummary is a container as listed by label
+type ContainerSummary struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	State   string            `json:"state"`
+	Status  string            `json:"status"`
+	Created time.Time         `json:"created"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// CreateContainer creates and starts a container named name. If starting
+// fails the container is removed again.
+func (c *DockerClient) CreateContainer(ctx context.Context, name string, spec ContainerSpec) (string, error) {
+	env := make([]string, 0, len(spec.Env))
+	for k, v := range spec.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+
+	exposed := make(map[string]any, len(spec.Ports))
+	bindings := make(map[string][]map[string]string, len(spec.Ports))
+	for _, p := range spec.Ports {
+		proto := p.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		key := fmt.Sprintf("%d/%s", p.Container, proto)
+		exposed[key] = struct{}{}
+		if p.Host > 0 {
+			bindings[key] = append(bindings[key], map[string]string{"HostPort": fmt.Sprint(p.Host)})
+		}
+	}
+
+	restart := spec.Restart
+	if restart == "" {
+		restart = "no"
+	}
+	body := map[string]any{
+		"Image":        spec.Image,
+		"Env":          env,
+		"Labels":       spec.Labels,
+		"ExposedPorts": exposed,
+		"HostConfig": map[string]any{
+			"PortBindings":  bindings,
+			"Binds":         spec.Binds,
+			"RestartPolicy": map[string]string{"Name": restart},
+		},
+	}
+	if len(spec.Cmd) > 0 {
+		body["Cmd"] = spec.Cmd
+	}
+	if spec.Network != "" {
+		body["NetworkingConfig"] = map[string]any{
+			"EndpointsConfig": map[string]any{spec.Network: map[string]any{"Aliases": []string{name}}},
+		}
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.postJSON(ctx, "/containers/create?name="+url.QueryEscape(name), body, &created); err != nil {
+		return "", fmt.Errorf("create container: %w", err)
+	}
+	if err := c.post(ctx, "/containers/"+created.ID+"/start", nil); err != nil {
+		c.deleteContainer(context.WithoutCancel(ctx), created.ID)
+		return "", fmt.Errorf("start container: %w", err)
+	}
+	return created.ID, nil
+}
+
+// RemoveContainer stops and removes a container by name or ID
+func (c *DockerClient) RemoveContainer(ctx context.Context, name string) error {
+	return c.deleteContainer(ctx, url.PathEscape(name))
+}
+
+// RestartContainer restarts a container by name or ID, giving it 10
+// seconds to stop
+func (c *DockerClient) RestartContainer(ctx context.Context, name string) error {
+	return c.post(ctx, "/containers/"+url.PathEscape(name)+"/restart?t=10", nil)
+}
+
+// ContainersByLabel lists containers in any state with label set
+func (c *DockerClient) ContainersByLabel(ctx context.Context, label string) ([]ContainerSummary, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {label}})
+	if err != nil {
+		return nil, err
+	}
+	var containers []dockerContainer
+	if err := c.getJSON(ctx, "/containers/json?all=true&filters="+url.QueryEscape(string(filters)), &containers); err != nil {
+		return nil, err
+	}
+
+	list := make([]ContainerSummary, 0, len(containers))
+	for _, ct := range containers {
+		if len(ct.Names) == 0 {
+			continue
+		}
+		list = append(list, ContainerSummary{
+			ID:      ct.ID,
+			Name:    strings.TrimPrefix(ct.Names[0], "/"),
+			Image:   ct.Image,
+			State:   ct.State,
+			Status:  ct.Status,
+			Created: time.Unix(ct.Created, 0).UTC(),
+			Labels:  ct.Labels,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// ComposeNetwork returns the name of the network compose created for
+// network (e.g. forge-net becomes forge_forge-net), or network itself when
+// there is none
+func (c *DockerClient) ComposeNetwork(ctx context.Context, network string) (string, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {"com.docker.compose.network=" + network}})
+	if err != nil {
+		return "", err
+	}
+	var networks []struct {
+		Name string `json:"Name"`
+	}
+	if err := c.getJSON(ctx, "/networks?filters="+url.QueryEscape(string(filters)), &networks); err != nil {
+		return "", err
+	}
+	if len(networks) == 0 {
+		return network, nil
+	}
+	return networks[0].Name, nil
+}
@@ -0,0 +1,250 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// diskUsageTimeout bounds /system/df and prunes, which walk every volume
+const diskUsageTimeout = 2 * time.Minute
+
+// Prune targets
+const (
+	PruneContainers = "containers" // stopped containers
+	PruneImages     = "images"     // dangling images, or all unused with all
+	PruneVolumes    = "volumes"    // unused anonymous volumes, or named ones too with all
+	PruneBuildCache = "build_cache"
+	PruneNetworks   = "networks" // unused user-defined networks
+)
+
+// ValidPruneTarget reports whether target can be pruned
+func ValidPruneTarget(target string) bool {
+	switch target {
+	case PruneContainers, PruneImages, PruneVolumes, PruneBuildCache, PruneNetworks:
+		return true
+	}
+	return false
+}
+
+// DockerDiskUsage reports the space Docker uses, by object
+type DockerDiskUsage struct {
+	ImagesMB                float64       `json:"images_mb"`
+	ImagesReclaimableMB     float64       `json:"images_reclaimable_mb"`
+	ContainersMB            float64       `json:"containers_mb"`
+	VolumesMB               float64       `json:"volumes_mb"`
+	VolumesReclaimableMB    float64       `json:"volumes_reclaimable_mb"`
+	BuildCacheMB            float64       `json:"build_cache_mb"`
+	BuildCacheReclaimableMB float64       `json:"build_cache_reclaimable_mb"`
+	Images                  []ImageUsage  `json:"images"`
+	Volumes                 []VolumeUsage `json:"volumes"`
+}
+
+// ImageUsage is the size of one image
+type ImageUsage struct {
+	ID         string   `json:"id"`
+	Tags       []string `json:"tags"`
+	SizeMB     float64  `json:"size_mb"`
+	SharedMB   float64  `json:"shared_mb"` // layers shared with other images
+	Containers int      `json:"containers"`
+}
+
+// VolumeUsage is the size of one volume
+type VolumeUsage struct {
+	Name     string  `json:"name"`
+	Project  string  `json:"project,omitempty"` // compose project
+	SizeMB   float64 `json:"size_mb"`
+	RefCount int     `json:"ref_count"` // containers using the volume
+}
+
+// PruneResult is what a prune removed
+type PruneResult struct {
+	Target         string   `json:"target"`
+	Deleted        []string `json:"deleted"`
+	ReclaimedMB    float64  `json:"reclaimed_mb"`
+	ReclaimedBytes int64    `json:"reclaimed_bytes"`
+}
+
+// dockerDF represents the Docker /system/df response
+type dockerDF struct {
+	LayersSize int64 `json:"LayersSize"`
+	Images     []struct {
+		ID         string   `json:"Id"`
+		RepoTags   []string `json:"RepoTags"`
+		Size       int64    `json:"Size"`
+		SharedSize int64    `json:"SharedSize"`
+		Containers int      `json:"Containers"`
+	} `json:"Images"`
+	Containers []struct {
+		SizeRw int64 `json:"SizeRw"`
+	} `json:"Containers"`
+	Volumes []struct {
+		Name      string            `json:"Name"`
+		Labels    map[string]string `json:"Labels"`
+		UsageData struct {
+			Size     int64 `json:"Size"`
+			RefCount int   `json:"RefCount"`
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+	BuildCache []struct {
+		Size   int64 `json:"Size"`
+		InUse  bool  `json:"InUse"`
+		Shared bool  `json:"Shared"`
+	} `json:"BuildCache"`
+}
+
+func mb(bytes int64) float64 {
+	return float64(bytes) / 1024 / 1024
+}
+
+// DiskUsage reports image, container, volume and build cache sizes, and
+// how much of each a prune could reclaim
+func (c *DockerClient) DiskUsage(ctx context.Context) (*DockerDiskUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, diskUsageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://docker/system/df", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+
+	var df dockerDF
+	if err := json.NewDecoder(resp.Body).Decode(&df); err != nil {
+		return nil, err
+	}
+
+	usage := &DockerDiskUsage{ImagesMB: mb(df.LayersSize), Images: []ImageUsage{}, Volumes: []VolumeUsage{}}
+	for _, img := range df.Images {
+		usage.Images = append(usage.Images, ImageUsage{
+			ID:         strings.TrimPrefix(img.ID, "sha256:"),
+			Tags:       img.RepoTags,
+			SizeMB:     mb(img.Size),
+			SharedMB:   mb(max(img.SharedSize, 0)),
+			Containers: img.Containers,
+		})
+		// An unused image frees the layers it does not share
+		if img.Containers == 0 {
+			usage.ImagesReclaimableMB += mb(img.Size - max(img.SharedSize, 0))
+		}
+	}
+	for _, ct := range df.Containers {
+		usage.ContainersMB += mb(ct.SizeRw)
+	}
+	for _, vol := range df.Volumes {
+		size := max(vol.UsageData.Size, 0) // -1 when Docker cannot size it
+		usage.Volumes = append(usage.Volumes, VolumeUsage{
+			Name:     vol.Name,
+			Project:  vol.Labels["com.docker.compose.project"],
+			SizeMB:   mb(size),
+			RefCount: vol.UsageData.RefCount,
+		})
+		usage.VolumesMB += mb(size)
+		if vol.UsageData.RefCount == 0 {
+			usage.VolumesReclaimableMB += mb(size)
+		}
+	}
+	for _, bc := range df.BuildCache {
+		usage.BuildCacheMB += mb(bc.Size)
+		if !bc.InUse && !bc.Shared {
+			usage.BuildCacheReclaimableMB += mb(bc.Size)
+		}
+	}
+
+	sort.Slice(usage.Images, func(i, j int) bool { return usage.Images[i].SizeMB > usage.Images[j].SizeMB })
+	sort.Slice(usage.Volumes, func(i, j int) bool { return usage.Volumes[i].SizeMB > usage.Volumes[j].SizeMB })
+	return usage, nil
+}
+
+// Prune removes unused Docker objects of target. With all, images that
+// are unused but tagged and named volumes are removed too.
+func (c *DockerClient) Prune(ctx context.Context, target string, all bool) (*PruneResult, error) {
+	var endpoint string
+	filters := map[string][]string{}
+	switch target {
+	case PruneContainers:
+		endpoint = "/containers/prune"
+	case PruneImages:
+		endpoint = "/images/prune"
+		if all {
+			filters["dangling"] = []string{"false"}
+		}
+	case PruneVolumes:
+		endpoint = "/volumes/prune"
+		if all {
+			filters["all"] = []string{"true"}
+		}
+	case PruneBuildCache:
+		endpoint = "/build/prune"
+		if all {
+			endpoint += "?all=true"
+		}
+	case PruneNetworks:
+		endpoint = "/networks/prune"
+	default:
+		return nil, fmt.Errorf("invalid prune target %q: must be %s, %s, %s, %s or %s", target, PruneContainers, PruneImages, PruneVolumes, PruneBuildCache, PruneNetworks)
+	}
+	if len(filters) > 0 {
+		data, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		endpoint += "?filters=" + url.QueryEscape(string(data))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diskUsageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://docker"+endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+
+	// Each endpoint names its list differently
+	var body struct {
+		ContainersDeleted []string `json:"ContainersDeleted"`
+		VolumesDeleted    []string `json:"VolumesDeleted"`
+		CachesDeleted     []string `json:"CachesDeleted"`
+		NetworksDeleted   []string `json:"NetworksDeleted"`
+		ImagesDeleted     []struct {
+			Untagged string `json:"Untagged"`
+			Deleted  string `json:"Deleted"`
+		} `json:"ImagesDeleted"`
+		SpaceReclaimed int64 `json:"SpaceReclaimed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{Target: target, Deleted: []string{}, ReclaimedMB: mb(body.SpaceReclaimed), ReclaimedBytes: body.SpaceReclaimed}
+	result.Deleted = append(result.Deleted, body.ContainersDeleted...)
+	result.Deleted = append(result.Deleted, body.VolumesDeleted...)
+	result.Deleted = append(result.Deleted, body.CachesDeleted...)
+	result.Deleted = append(result.Deleted, body.NetworksDeleted...)
+	for _, img := range body.ImagesDeleted {
+		if img.Deleted != "" {
+			result.Deleted = append(result.Deleted, strings.TrimPrefix(img.Deleted, "sha256:"))
+		}
+	}
+	return result, nil
+}
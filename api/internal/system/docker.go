@@ -7,8 +7,14 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/resources"
 )
 
 // ContainerStats holds stats for a single container
@@ -25,6 +31,97 @@ type ContainerStats struct {
 	Uptime        string   `json:"uptime"`
 	Endpoints     []string `json:"endpoints"`
 	Image         string   `json:"image"`
+	Forge         bool     `json:"forge"` // part of the forge stack
+
+	// Health is the Docker HEALTHCHECK state; nil without a healthcheck
+	Health *ContainerHealth `json:"health,omitempty"`
+	// RestartCount is how often Docker restarted the container under its
+	// restart policy
+	RestartCount int `json:"restart_count"`
+	// GPUs are the GPUs the container was given or runs processes on
+	GPUs []ContainerGPU `json:"gpus,omitempty"`
+
+	id   string
+	gpus gpuRequest
+}
+
+// Container health states
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// maxHealthOutput caps the healthcheck output reported
+const maxHealthOutput = 1024
+
+// ContainerHealth is the result of a container's healthcheck
+type ContainerHealth struct {
+	Status        string    `json:"status"`         // starting, healthy or unhealthy
+	FailingStreak int       `json:"failing_streak"` // consecutive failed checks
+	LastCheck     time.Time `json:"last_check,omitempty"`
+	LastExitCode  int       `json:"last_exit_code"`
+	LastOutput    string    `json:"last_output,omitempty"`
+}
+
+// dockerHealth represents State.Health of a container inspect
+type dockerHealth struct {
+	Status        string `json:"Status"`
+	FailingStreak int    `json:"FailingStreak"`
+	Log           []struct {
+		End      time.Time `json:"End"`
+		ExitCode int       `json:"ExitCode"`
+		Output   string    `json:"Output"`
+	} `json:"Log"`
+}
+
+// containerHealth converts a Docker health state; nil without a healthcheck
+func containerHealth(h *dockerHealth) *ContainerHealth {
+	if h == nil || h.Status == "" || h.Status == "none" {
+		return nil
+	}
+	health := &ContainerHealth{Status: h.Status, FailingStreak: h.FailingStreak}
+	if n := len(h.Log); n > 0 {
+		last := h.Log[n-1]
+		health.LastCheck = last.End
+		health.LastExitCode = last.ExitCode
+		health.LastOutput = strings.TrimSpace(last.Output)
+		if len(health.LastOutput) > maxHealthOutput {
+			health.LastOutput = health.LastOutput[:maxHealthOutput] + "..."
+		}
+	}
+	return health
+}
+
+// ContainerFilter selects other containers to report next to the forge
+// stack's own. The zero value reports forge containers only.
+type ContainerFilter struct {
+	All      bool               // every container
+	Selector resources.Selector // containers whose labels match
+}
+
+// ParseContainerFilter parses a containers mode (forge or all) and a
+// label selector such as "com.docker.compose.project=shop"
+func ParseContainerFilter(mode, selector string) (ContainerFilter, error) {
+	var f ContainerFilter
+	switch mode {
+	case "", "forge":
+	case "all":
+		f.All = true
+	default:
+		return f, fmt.Errorf("invalid containers %q: must be forge or all", mode)
+	}
+	sel, err := resources.ParseSelector(selector)
+	if err != nil {
+		return f, err
+	}
+	f.Selector = sel
+	return f, nil
+}
+
+// includes reports whether a container outside the stack is reported
+func (f ContainerFilter) includes(labels map[string]string) bool {
+	return f.All || len(f.Selector) > 0 && f.Selector.Matches(labels)
 }
 
 // SystemInfo holds overall system information
@@ -33,25 +130,64 @@ type SystemInfo struct {
 	Containers      map[string]*ContainerStats `json:"containers"`
 	TotalContainers int                        `json:"total_containers"`
 	RunningCount    int                        `json:"running_count"`
-	Recommendations []string                   `json:"recommendations,omitempty"`
+	Recommendations []Recommendation           `json:"recommendations,omitempty"`
+	Clock           *ClockReport               `json:"clock,omitempty"`
+	Host            *HostStats                 `json:"host,omitempty"`
+	Routes          []RouteAvailability        `json:"routes,omitempty"`
+	Trends          []UsageTrend               `json:"trends,omitempty"`
+	GPUs            *GPUStats                  `json:"gpus,omitempty"`
 }
 
 // DockerClient communicates with Docker via socket
 type DockerClient struct {
-	httpClient *http.Client
+	httpClient   *http.Client
+	streamClient *http.Client // no timeout, for long-lived streams
+
+	statsMu    sync.Mutex
+	statsCache map[string]cachedStats // by container ID
+}
+
+// cachedStats is a stats sample kept for statsCacheTTL
+type cachedStats struct {
+	stats   *dockerStats
+	fetched time.Time
 }
 
-// NewDockerClient creates a Docker client using the Unix socket
-func NewDockerClient() *DockerClient {
+const (
+	// statsWorkers bounds concurrent stats requests; each blocks about a
+	// second while Docker samples CPU usage
+	statsWorkers = 8
+	// statsCacheTTL is how long a container's stats sample is reused
+	statsCacheTTL = 5 * time.Second
+)
+
+// NewDockerClient creates a Docker client for the endpoint cfg describes
+// (see NewDockerEndpoint). A configuration error is returned by every
+// request.
+func NewDockerClient(cfg config.Docker) *DockerClient {
+	endpoint, err := NewDockerEndpoint(cfg)
+	if err != nil {
+		return newDockerClient(errDockerEndpoint(err))
+	}
+	return NewDockerClientFor(endpoint)
+}
+
+// NewDockerClientFor creates a Docker client for endpoint
+func NewDockerClientFor(endpoint DockerEndpoint) *DockerClient {
+	return newDockerClient(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return endpoint.dial(ctx)
+	})
+}
+
+func newDockerClient(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *DockerClient {
+	transport := &http.Transport{DialContext: dial}
 	return &DockerClient{
 		httpClient: &http.Client{
-			Transport: &http.Transport{
-				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return net.Dial("unix", "/var/run/docker.sock")
-				},
-			},
-			Timeout: 10 * time.Second,
+			Transport: transport,
+			Timeout:   10 * time.Second,
 		},
+		streamClient: &http.Client{Transport: transport},
+		statsCache:   make(map[string]cachedStats),
 	}
 }
 
@@ -60,6 +196,7 @@ type dockerContainer struct {
 	ID      string            `json:"Id"`
 	Names   []string          `json:"Names"`
 	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
 	State   string            `json:"State"`
 	Status  string            `json:"Status"`
 	Created int64             `json:"Created"`
@@ -99,82 +236,59 @@ type dockerStats struct {
 	} `json:"networks"`
 }
 
-// GetSystemInfo retrieves system and container information
-func (c *DockerClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+// GetSystemInfo retrieves system and container information for the forge
+// containers and those selected by filter
+func (c *DockerClient) GetSystemInfo(ctx context.Context, filter ContainerFilter) (*SystemInfo, error) {
 	info := &SystemInfo{
 		Timestamp:  time.Now().Format(time.RFC3339),
 		Containers: make(map[string]*ContainerStats),
 	}
 
-	// Get list of containers (filter by forge- prefix)
 	containers, err := c.listContainers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
+	// Fetch live stats of running containers concurrently
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, statsWorkers)
 	for _, container := range containers {
-		// Skip containers with no names
-		if len(container.Names) == 0 {
-			continue
-		}
-
-		name := strings.TrimPrefix(container.Names[0], "/")
-
-		// Only include forge containers
-		if !strings.HasPrefix(name, "forge-") {
+		stats := filteredContainerStats(container, filter)
+		if stats == nil {
 			continue
 		}
 
 		info.TotalContainers++
+		info.Containers[stats.Name] = stats
 
-		stats := &ContainerStats{
-			Name:   name,
-			Status: container.Status,
-			State:  container.State,
-			Image:  container.Image,
+		running := container.State == "running"
+		if running {
+			info.RunningCount++
 		}
 
-		// Build endpoints from ports
-		for _, port := range container.Ports {
-			if port.PublicPort > 0 {
-				stats.Endpoints = append(stats.Endpoints, fmt.Sprintf("localhost:%d", port.PublicPort))
+		wg.Add(1)
+		go func(id string, stats *ContainerStats) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Health and restarts are only in the inspect output
+			var inspect containerInspect
+			if err := c.getJSON(ctx, "/containers/"+id+"/json", &inspect); err == nil {
+				stats.Health = containerHealth(inspect.State.Health)
+				stats.RestartCount = inspect.RestartCount
+				stats.gpus = requestedGPUs(&inspect)
 			}
-		}
-
-		// Calculate uptime from Created timestamp
-		created := time.Unix(container.Created, 0)
-		stats.Uptime = formatDuration(time.Since(created))
-
-		// Get live stats if container is running
-		if container.State == "running" {
-			info.RunningCount++
-
-			liveStats, err := c.getContainerStats(ctx, container.ID)
+			if !running {
+				return
+			}
+			liveStats, err := c.cachedContainerStats(ctx, id)
 			if err == nil {
-				// Calculate CPU percentage
-				cpuDelta := float64(liveStats.CPUStats.CPUUsage.TotalUsage - liveStats.PreCPUStats.CPUUsage.TotalUsage)
-				systemDelta := float64(liveStats.CPUStats.SystemCPUUsage - liveStats.PreCPUStats.SystemCPUUsage)
-				if systemDelta > 0 && liveStats.CPUStats.OnlineCPUs > 0 {
-					stats.CPUPercent = (cpuDelta / systemDelta) * float64(liveStats.CPUStats.OnlineCPUs) * 100
-				}
-
-				// Memory stats
-				stats.MemoryMB = float64(liveStats.MemoryStats.Usage) / 1024 / 1024
-				stats.MemoryLimitMB = float64(liveStats.MemoryStats.Limit) / 1024 / 1024
-				if stats.MemoryLimitMB > 0 {
-					stats.MemoryPercent = (stats.MemoryMB / stats.MemoryLimitMB) * 100
-				}
-
-				// Network stats
-				for _, net := range liveStats.Networks {
-					stats.NetworkRxMB += float64(net.RxBytes) / 1024 / 1024
-					stats.NetworkTxMB += float64(net.TxBytes) / 1024 / 1024
-				}
+				stats.apply(liveStats)
 			}
-		}
-
-		info.Containers[name] = stats
+		}(container.ID, stats)
 	}
+	wg.Wait()
 
 	// Generate recommendations
 	info.Recommendations = c.generateRecommendations(info.Containers)
@@ -182,6 +296,72 @@ func (c *DockerClient) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
 	return info, nil
 }
 
+// forgeContainerStats returns the listing fields of a forge container, or
+// nil for other containers
+func forgeContainerStats(container dockerContainer) *ContainerStats {
+	return filteredContainerStats(container, ContainerFilter{})
+}
+
+// filteredContainerStats returns the listing fields of a forge container
+// or one selected by filter, or nil for other containers
+func filteredContainerStats(container dockerContainer, filter ContainerFilter) *ContainerStats {
+	// Skip containers with no names
+	if len(container.Names) == 0 {
+		return nil
+	}
+
+	name := strings.TrimPrefix(container.Names[0], "/")
+	forge := strings.HasPrefix(name, "forge-")
+	if !forge && !filter.includes(container.Labels) {
+		return nil
+	}
+
+	stats := &ContainerStats{
+		id:     container.ID,
+		Name:   name,
+		Status: container.Status,
+		State:  container.State,
+		Image:  container.Image,
+		Forge:  forge,
+	}
+
+	// Build endpoints from ports
+	for _, port := range container.Ports {
+		if port.PublicPort > 0 {
+			stats.Endpoints = append(stats.Endpoints, fmt.Sprintf("localhost:%d", port.PublicPort))
+		}
+	}
+
+	// Calculate uptime from Created timestamp
+	created := time.Unix(container.Created, 0)
+	stats.Uptime = formatDuration(time.Since(created))
+	return stats
+}
+
+// apply sets the usage fields of stats from a Docker stats sample
+func (stats *ContainerStats) apply(s *dockerStats) {
+	// Calculate CPU percentage
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage - s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemCPUUsage - s.PreCPUStats.SystemCPUUsage)
+	if systemDelta > 0 && s.CPUStats.OnlineCPUs > 0 {
+		stats.CPUPercent = (cpuDelta / systemDelta) * float64(s.CPUStats.OnlineCPUs) * 100
+	}
+
+	// Memory stats
+	stats.MemoryMB = float64(s.MemoryStats.Usage) / 1024 / 1024
+	stats.MemoryLimitMB = float64(s.MemoryStats.Limit) / 1024 / 1024
+	if stats.MemoryLimitMB > 0 {
+		stats.MemoryPercent = (stats.MemoryMB / stats.MemoryLimitMB) * 100
+	}
+
+	// Network stats
+	stats.NetworkRxMB, stats.NetworkTxMB = 0, 0
+	for _, net := range s.Networks {
+		stats.NetworkRxMB += float64(net.RxBytes) / 1024 / 1024
+		stats.NetworkTxMB += float64(net.TxBytes) / 1024 / 1024
+	}
+}
+
 func (c *DockerClient) listContainers(ctx context.Context) ([]dockerContainer, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", "http://docker/containers/json?all=true", nil)
 	if err != nil {
@@ -202,6 +382,74 @@ func (c *DockerClient) listContainers(ctx context.Context) ([]dockerContainer, e
 	return containers, nil
 }
 
+// LabeledContainer is a running container carrying a given label
+type LabeledContainer struct {
+	Name   string
+	Labels map[string]string
+}
+
+// ContainersWithLabel lists running containers that have label set
+func (c *DockerClient) ContainersWithLabel(ctx context.Context, label string) ([]LabeledContainer, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {label}, "status": {"running"}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://docker/containers/json?filters="+url.QueryEscape(string(filters)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+
+	var containers []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+
+	list := make([]LabeledContainer, 0, len(containers))
+	for _, ct := range containers {
+		if len(ct.Names) == 0 {
+			continue
+		}
+		list = append(list, LabeledContainer{Name: strings.TrimPrefix(ct.Names[0], "/"), Labels: ct.Labels})
+	}
+	return list, nil
+}
+
+// cachedContainerStats returns a stats sample at most statsCacheTTL old
+func (c *DockerClient) cachedContainerStats(ctx context.Context, containerID string) (*dockerStats, error) {
+	c.statsMu.Lock()
+	cached, ok := c.statsCache[containerID]
+	c.statsMu.Unlock()
+	if ok && time.Since(cached.fetched) < statsCacheTTL {
+		return cached.stats, nil
+	}
+
+	stats, err := c.getContainerStats(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	now := time.Now()
+	// Drop samples of containers that are gone
+	for id, s := range c.statsCache {
+		if now.Sub(s.fetched) >= statsCacheTTL {
+			delete(c.statsCache, id)
+		}
+	}
+	c.statsCache[containerID] = cachedStats{stats: stats, fetched: now}
+	return stats, nil
+}
+
 func (c *DockerClient) getContainerStats(ctx context.Context, containerID string) (*dockerStats, error) {
 	url := fmt.Sprintf("http://docker/containers/%s/stats?stream=false", containerID)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -223,30 +471,83 @@ func (c *DockerClient) getContainerStats(ctx context.Context, containerID string
 	return &stats, nil
 }
 
-func (c *DockerClient) generateRecommendations(containers map[string]*ContainerStats) []string {
-	var recs []string
+// Container usage thresholds in percent
+const (
+	memoryCritical = 80
+	memoryElevated = 60
+	cpuHigh        = 80
+)
+
+func (c *DockerClient) generateRecommendations(containers map[string]*ContainerStats) []Recommendation {
+	names := make([]string, 0, len(containers))
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	for name, stats := range containers {
+	var recs []Recommendation
+	for _, name := range names {
+		stats := containers[name]
 		if stats.State != "running" {
-			recs = append(recs, fmt.Sprintf("⚠️  %s is not running (state: %s)", name, stats.State))
+			recs = append(recs, Recommendation{
+				Severity:  SeverityWarning,
+				Resource:  ResourceState,
+				Container: name,
+				Action:    SuggestStartContainer,
+				Message:   fmt.Sprintf("⚠️  %s is not running (state: %s)", name, stats.State),
+			})
 			continue
 		}
+		if stats.Health != nil && stats.Health.Status == HealthUnhealthy {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityCritical,
+				Resource:  ResourceHealth,
+				Container: name,
+				Action:    SuggestCheckLogs,
+				Value:     float64(stats.Health.FailingStreak),
+				Message:   fmt.Sprintf("🔴 %s is unhealthy (%d failed checks in a row). Check its logs.", name, stats.Health.FailingStreak),
+			})
+		}
 
 		// Memory warnings
-		if stats.MemoryPercent > 80 {
-			recs = append(recs, fmt.Sprintf("🔴 %s memory usage is critical (%.0f%%). Consider increasing memory limit.", name, stats.MemoryPercent))
-		} else if stats.MemoryPercent > 60 {
-			recs = append(recs, fmt.Sprintf("🟡 %s memory usage is elevated (%.0f%%). Monitor closely.", name, stats.MemoryPercent))
+		if stats.MemoryPercent > memoryCritical {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityCritical,
+				Resource:  ResourceMemory,
+				Container: name,
+				Action:    SuggestIncreaseMemoryLimit,
+				Value:     stats.MemoryPercent,
+				Threshold: memoryCritical,
+				Message:   fmt.Sprintf("🔴 %s memory usage is critical (%.0f%%). Consider increasing memory limit (PUT /api/v1/system/limits/%s).", name, stats.MemoryPercent, name),
+			})
+		} else if stats.MemoryPercent > memoryElevated {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityWarning,
+				Resource:  ResourceMemory,
+				Container: name,
+				Action:    SuggestMonitor,
+				Value:     stats.MemoryPercent,
+				Threshold: memoryElevated,
+				Message:   fmt.Sprintf("🟡 %s memory usage is elevated (%.0f%%). Monitor closely.", name, stats.MemoryPercent),
+			})
 		}
 
 		// CPU warnings
-		if stats.CPUPercent > 80 {
-			recs = append(recs, fmt.Sprintf("🔴 %s CPU usage is high (%.1f%%). Consider scaling.", name, stats.CPUPercent))
+		if stats.CPUPercent > cpuHigh {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityCritical,
+				Resource:  ResourceCPU,
+				Container: name,
+				Action:    SuggestScale,
+				Value:     stats.CPUPercent,
+				Threshold: cpuHigh,
+				Message:   fmt.Sprintf("🔴 %s CPU usage is high (%.1f%%). Consider scaling.", name, stats.CPUPercent),
+			})
 		}
 	}
 
 	if len(recs) == 0 {
-		recs = append(recs, "✅ All services are healthy")
+		recs = append(recs, allHealthyRecommendation)
 	}
 
 	return recs
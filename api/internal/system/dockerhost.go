@@ -0,0 +1,156 @@
+package system
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/forge/api/internal/config"
+)
+
+// defaultDockerSocket is used when no engine socket is found
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// DockerEndpoint is where the Docker Engine API (or a compatible one such
+// as Podman's) is reached
+type DockerEndpoint struct {
+	Network string      // unix or tcp
+	Address string      // socket path or host:port
+	TLS     *tls.Config // tcp only; nil for plain HTTP
+}
+
+// String returns the endpoint in DOCKER_HOST form
+func (e DockerEndpoint) String() string {
+	if e.Network == "unix" {
+		return "unix://" + e.Address
+	}
+	return "tcp://" + e.Address
+}
+
+// dial connects to the endpoint, completing the TLS handshake if needed
+func (e DockerEndpoint) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, e.Network, e.Address)
+	if err != nil || e.TLS == nil {
+		return conn, err
+	}
+	tlsConn := tls.Client(conn, e.TLS)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// NewDockerEndpoint resolves the endpoint the way the docker CLI does:
+// cfg.Host (unix:///path or tcp://host:port), with TLS when TLSVerify or
+// CertPath is set. Without a host the first existing Docker or Podman
+// socket is used, rootful then rootless.
+func NewDockerEndpoint(cfg config.Docker) (DockerEndpoint, error) {
+	if cfg.Host == "" {
+		return DockerEndpoint{Network: "unix", Address: findDockerSocket(cfg.RuntimeDir)}, nil
+	}
+	certPath, verify := cfg.CertPath, cfg.TLSVerify
+	if certPath == "" && verify {
+		if home, err := os.UserHomeDir(); err == nil {
+			certPath = filepath.Join(home, ".docker")
+		}
+	}
+	return ParseDockerHost(cfg.Host, verify, certPath)
+}
+
+// findDockerSocket returns the first engine socket that exists, looking in
+// runtimeDir for rootless ones
+func findDockerSocket(runtimeDir string) string {
+	candidates := []string{defaultDockerSocket, "/run/podman/podman.sock"}
+	if runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "docker.sock"), filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return path
+		}
+	}
+	return defaultDockerSocket
+}
+
+// ParseDockerHost parses a DOCKER_HOST value. TCP endpoints use TLS when
+// certPath is set, loading ca.pem, cert.pem and key.pem from it, and
+// verify the server certificate when verify is set.
+func ParseDockerHost(host string, verify bool, certPath string) (DockerEndpoint, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return DockerEndpoint{}, fmt.Errorf("invalid DOCKER_HOST %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return DockerEndpoint{}, fmt.Errorf("invalid DOCKER_HOST %q: missing socket path", host)
+		}
+		return DockerEndpoint{Network: "unix", Address: path}, nil
+	case "tcp":
+	default:
+		return DockerEndpoint{}, fmt.Errorf("invalid DOCKER_HOST %q: scheme must be unix or tcp", host)
+	}
+
+	if u.Hostname() == "" {
+		return DockerEndpoint{}, fmt.Errorf("invalid DOCKER_HOST %q: missing host", host)
+	}
+	useTLS := verify || certPath != ""
+	port := u.Port()
+	if port == "" {
+		port = "2375"
+		if useTLS {
+			port = "2376"
+		}
+	}
+	e := DockerEndpoint{Network: "tcp", Address: net.JoinHostPort(u.Hostname(), port)}
+	if !useTLS {
+		return e, nil
+	}
+
+	e.TLS = &tls.Config{
+		ServerName:         u.Hostname(),
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !verify,
+	}
+	if certPath == "" {
+		return e, nil
+	}
+	if ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem")); err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return DockerEndpoint{}, fmt.Errorf("no certificates in %s", filepath.Join(certPath, "ca.pem"))
+		}
+		e.TLS.RootCAs = pool
+	} else if verify && !os.IsNotExist(err) {
+		return DockerEndpoint{}, err
+	}
+	// A client certificate is needed by daemons started with --tlsverify
+	certFile, keyFile := filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return DockerEndpoint{}, fmt.Errorf("load client certificate: %w", err)
+		}
+		e.TLS.Certificates = []tls.Certificate{cert}
+	}
+	return e, nil
+}
+
+// errDockerEndpoint makes every request fail with the configuration error
+func errDockerEndpoint(err error) func(context.Context, string, string) (net.Conn, error) {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, err
+	}
+}
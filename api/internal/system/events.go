@@ -0,0 +1,234 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+)
+
+const (
+	// eventHistorySize is how many recent events are kept for new clients
+	eventHistorySize = 500
+	// eventRetryMax caps the delay between reconnects to the event stream
+	eventRetryMax = 30 * time.Second
+)
+
+// eventActions are the container events followed; health_status events
+// are reported as healthy or unhealthy
+var eventActions = []string{"start", "stop", "die", "oom", "health_status"}
+
+// ContainerEvent is a lifecycle event of a forge container
+type ContainerEvent struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"` // start, stop, die, oom, healthy or unhealthy
+	Container string    `json:"container"`
+	Service   string    `json:"service,omitempty"` // compose service
+	Image     string    `json:"image,omitempty"`
+	ExitCode  *int      `json:"exit_code,omitempty"` // die only
+}
+
+// Level is the log level of the event: error for OOM kills and non-zero
+// exits, warn for other exits, stops and failing healthchecks, info
+// otherwise
+func (e ContainerEvent) Level() string {
+	switch {
+	case e.Action == "oom", e.ExitCode != nil && *e.ExitCode != 0:
+		return "error"
+	case e.Action == "die", e.Action == "stop", e.Action == "unhealthy":
+		return "warn"
+	}
+	return "info"
+}
+
+// Message describes the event in one line
+func (e ContainerEvent) Message() string {
+	switch {
+	case e.Action == "oom":
+		return fmt.Sprintf("container %s was killed for running out of memory", e.Container)
+	case e.ExitCode != nil:
+		return fmt.Sprintf("container %s exited with code %d", e.Container, *e.ExitCode)
+	case e.Action == "stop":
+		return fmt.Sprintf("container %s stopped", e.Container)
+	case e.Action == "unhealthy", e.Action == "healthy":
+		return fmt.Sprintf("container %s is %s", e.Container, e.Action)
+	}
+	return fmt.Sprintf("container %s started", e.Container)
+}
+
+// dockerEvent represents a message of the Docker /events stream
+type dockerEvent struct {
+	Type     string `json:"Type"`
+	Action   string `json:"Action"`
+	TimeNano int64  `json:"timeNano"`
+	Actor    struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// EventWatcher follows Docker container events, keeps the most recent
+// ones and fans new ones out to subscribers and handlers. Slow
+// subscribers miss events rather than block the others.
+type EventWatcher struct {
+	docker *DockerClient
+
+	mu       sync.Mutex
+	history  []ContainerEvent // oldest first, at most eventHistorySize
+	subs     map[chan ContainerEvent]struct{}
+	handlers []func(ContainerEvent)
+}
+
+// NewEventWatcher creates a watcher reading events through docker
+func NewEventWatcher(docker *DockerClient) *EventWatcher {
+	return &EventWatcher{
+		docker: docker,
+		subs:   make(map[chan ContainerEvent]struct{}),
+	}
+}
+
+// OnEvent calls fn for every event. It must be called before Run.
+func (w *EventWatcher) OnEvent(fn func(ContainerEvent)) {
+	w.handlers = append(w.handlers, fn)
+}
+
+// Recent returns up to limit of the latest events, oldest first; all kept
+// events when limit is 0
+func (w *EventWatcher) Recent(limit int) []ContainerEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	start := 0
+	if limit > 0 && len(w.history) > limit {
+		start = len(w.history) - limit
+	}
+	return append([]ContainerEvent{}, w.history[start:]...)
+}
+
+// Subscribe returns a channel of new events. Call unsubscribe when done.
+func (w *EventWatcher) Subscribe() (events <-chan ContainerEvent, unsubscribe func()) {
+	ch := make(chan ContainerEvent, 64)
+	w.mu.Lock()
+	w.subs[ch] = struct{}{}
+	w.mu.Unlock()
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subs, ch)
+	}
+}
+
+// Run follows the event stream until ctx is cancelled, reconnecting with
+// backoff. Events missed while disconnected are replayed from the time of
+// the last one seen.
+func (w *EventWatcher) Run(ctx context.Context) {
+	since := time.Now()
+	delay := time.Second
+	for {
+		connected := time.Now()
+		err := w.follow(ctx, &since)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(connected) > eventRetryMax {
+			delay = time.Second
+		}
+		logger.Warn(fmt.Sprintf("Docker event stream closed, reconnecting in %s: %v", delay, err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, eventRetryMax)
+	}
+}
+
+// follow reads the event stream from since, advancing it with each event
+func (w *EventWatcher) follow(ctx context.Context, since *time.Time) error {
+	filters, err := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": eventActions,
+	})
+	if err != nil {
+		return err
+	}
+	q := url.Values{
+		"since":   {strconv.FormatInt(since.Unix(), 10)},
+		"filters": {string(filters)},
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://docker/events?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := w.docker.streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dockerError(resp)
+	}
+
+	// since has second precision, so a replay can repeat events already
+	// seen in that second
+	last := *since
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev dockerEvent
+		if err := dec.Decode(&ev); err != nil {
+			return err
+		}
+		at := time.Unix(0, ev.TimeNano)
+		if !at.After(last) {
+			continue
+		}
+		last, *since = at, at
+
+		name := ev.Actor.Attributes["name"]
+		if !strings.HasPrefix(name, "forge-") {
+			continue
+		}
+		// e.g. "health_status: unhealthy"
+		action := ev.Action
+		if status, ok := strings.CutPrefix(action, "health_status:"); ok {
+			action = strings.TrimSpace(status)
+		}
+		e := ContainerEvent{
+			Time:      at.UTC(),
+			Action:    action,
+			Container: name,
+			Service:   ev.Actor.Attributes["com.docker.compose.service"],
+			Image:     ev.Actor.Attributes["image"],
+		}
+		if code, err := strconv.Atoi(ev.Actor.Attributes["exitCode"]); err == nil && ev.Action == "die" {
+			e.ExitCode = &code
+		}
+		w.publish(e)
+	}
+}
+
+// publish records e and hands it to subscribers and handlers
+func (w *EventWatcher) publish(e ContainerEvent) {
+	w.mu.Lock()
+	w.history = append(w.history, e)
+	if len(w.history) > eventHistorySize {
+		w.history = w.history[len(w.history)-eventHistorySize:]
+	}
+	for ch := range w.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	w.mu.Unlock()
+
+	for _, fn := range w.handlers {
+		fn(e)
+	}
+}
@@ -0,0 +1,440 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/config"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// gpuTimeout bounds one nvidia-smi run or DCGM scrape
+	gpuTimeout = 10 * time.Second
+	// gpuMemoryWarn is the device memory use in percent worth a
+	// recommendation
+	gpuMemoryWarn = 90
+)
+
+// GPU sources
+const (
+	GPUSourceNvidiaSMI = "nvidia-smi"
+	GPUSourceDCGM      = "dcgm"
+)
+
+// GPUDevice is the usage of one NVIDIA GPU
+type GPUDevice struct {
+	Index              int     `json:"index"`
+	UUID               string  `json:"uuid"`
+	Name               string  `json:"name"`
+	UtilizationPercent float64 `json:"utilization_percent"`
+	MemoryUsedMB       float64 `json:"memory_used_mb"`
+	MemoryTotalMB      float64 `json:"memory_total_mb"`
+	TemperatureC       float64 `json:"temperature_c,omitempty"`
+}
+
+// GPUStats are the host's GPUs as last collected
+type GPUStats struct {
+	Source  string      `json:"source"` // nvidia-smi or dcgm
+	Devices []GPUDevice `json:"devices"`
+
+	processes []gpuProcess
+}
+
+// gpuProcess is GPU memory used by a process of a container
+type gpuProcess struct {
+	uuid        string
+	containerID string
+	memoryMB    float64
+}
+
+// ContainerGPU is a GPU used by a container. Utilization is that of the
+// whole device; MemoryMB is what the container's own processes use, known
+// only when nvidia-smi is available.
+type ContainerGPU struct {
+	Index              int     `json:"index"`
+	UUID               string  `json:"uuid"`
+	UtilizationPercent float64 `json:"utilization_percent"`
+	MemoryMB           float64 `json:"memory_mb,omitempty"`
+}
+
+// containerIDPattern finds a container ID in a /proc/<pid>/cgroup line,
+// e.g. 0::/system.slice/docker-<id>.scope
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// GPUCollector reads NVIDIA GPU usage from a DCGM exporter or nvidia-smi.
+// Processes are mapped to containers through nvidia-smi and procPath, so
+// per-container memory needs nvidia-smi even with DCGM.
+type GPUCollector struct {
+	smiPath  string
+	dcgmURL  string
+	procPath string
+	client   *http.Client
+
+	mu     sync.Mutex
+	latest *GPUStats
+}
+
+// NewGPUCollector creates a collector running smiPath and/or scraping
+// dcgmURL (e.g. http://dcgm-exporter:9400/metrics); either may be empty
+func NewGPUCollector(smiPath, dcgmURL, procPath string) *GPUCollector {
+	return &GPUCollector{
+		smiPath:  smiPath,
+		dcgmURL:  dcgmURL,
+		procPath: procPath,
+		client:   &http.Client{Timeout: gpuTimeout},
+	}
+}
+
+// GPUCollectorFromConfig creates a collector from cfg.GPUDCGMURL and
+// cfg.GPUNvidiaSMI, looking for nvidia-smi in PATH when the latter is
+// unset. It returns nil when there is neither.
+func GPUCollectorFromConfig(cfg config.System) *GPUCollector {
+	smi := cfg.GPUNvidiaSMI
+	if smi == "" {
+		smi, _ = exec.LookPath("nvidia-smi")
+	}
+	if smi == "" && cfg.GPUDCGMURL == "" {
+		return nil
+	}
+	return NewGPUCollector(smi, cfg.GPUDCGMURL, cfg.HostProc)
+}
+
+// Run collects every interval until ctx is cancelled
+func (c *GPUCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if stats, err := c.Collect(ctx); err == nil {
+			c.mu.Lock()
+			c.latest = stats
+			c.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Latest returns the most recent stats, or nil before the first collection
+func (c *GPUCollector) Latest() *GPUStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latest
+}
+
+// Collect reads current GPU usage, from DCGM when configured
+func (c *GPUCollector) Collect(ctx context.Context) (*GPUStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, gpuTimeout)
+	defer cancel()
+
+	stats := &GPUStats{}
+	var err error
+	if c.dcgmURL != "" {
+		stats.Source = GPUSourceDCGM
+		stats.Devices, err = c.dcgmDevices(ctx)
+	} else {
+		stats.Source = GPUSourceNvidiaSMI
+		stats.Devices, err = c.smiDevices(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.smiPath != "" {
+		// Without process info containers still get the GPUs they requested
+		stats.processes, _ = c.smiProcesses(ctx)
+	}
+	return stats, nil
+}
+
+// smiDevices queries devices with nvidia-smi
+func (c *GPUCollector) smiDevices(ctx context.Context) ([]GPUDevice, error) {
+	rows, err := c.smiQuery(ctx, "--query-gpu=index,uuid,name,utilization.gpu,memory.used,memory.total,temperature.gpu")
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]GPUDevice, 0, len(rows))
+	for _, f := range rows {
+		if len(f) < 7 {
+			continue
+		}
+		index, _ := strconv.Atoi(f[0])
+		devices = append(devices, GPUDevice{
+			Index:              index,
+			UUID:               f[1],
+			Name:               f[2],
+			UtilizationPercent: smiNumber(f[3]),
+			MemoryUsedMB:       smiNumber(f[4]),
+			MemoryTotalMB:      smiNumber(f[5]),
+			TemperatureC:       smiNumber(f[6]),
+		})
+	}
+	return devices, nil
+}
+
+// smiProcesses lists compute processes and the containers they run in
+func (c *GPUCollector) smiProcesses(ctx context.Context) ([]gpuProcess, error) {
+	rows, err := c.smiQuery(ctx, "--query-compute-apps=gpu_uuid,pid,used_memory")
+	if err != nil {
+		return nil, err
+	}
+	var procs []gpuProcess
+	for _, f := range rows {
+		if len(f) < 3 {
+			continue
+		}
+		id := c.containerOf(f[1])
+		if id == "" {
+			continue // a host process
+		}
+		procs = append(procs, gpuProcess{uuid: f[0], containerID: id, memoryMB: smiNumber(f[2])})
+	}
+	return procs, nil
+}
+
+// smiQuery runs an nvidia-smi query and splits its CSV output
+func (c *GPUCollector) smiQuery(ctx context.Context, query string) ([][]string, error) {
+	out, err := exec.CommandContext(ctx, c.smiPath, query, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	var rows [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		rows = append(rows, fields)
+	}
+	return rows, scanner.Err()
+}
+
+// smiNumber parses an nvidia-smi value; [N/A] and similar are 0
+func smiNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// containerOf returns the ID of the container running pid, or ""
+func (c *GPUCollector) containerOf(pid string) string {
+	if _, err := strconv.Atoi(pid); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(c.procPath, pid, "cgroup"))
+	if err != nil {
+		return ""
+	}
+	ids := containerIDPattern.FindAllString(string(data), -1)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[len(ids)-1]
+}
+
+// dcgmDevices scrapes device metrics from a DCGM exporter
+func (c *GPUCollector) dcgmDevices(ctx context.Context) ([]GPUDevice, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.dcgmURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("dcgm exporter unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dcgm exporter returned %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dcgm metrics: %w", err)
+	}
+
+	byUUID := make(map[string]*GPUDevice)
+	for _, name := range []string{"DCGM_FI_DEV_GPU_UTIL", "DCGM_FI_DEV_FB_USED", "DCGM_FI_DEV_FB_FREE", "DCGM_FI_DEV_GPU_TEMP"} {
+		family, ok := families[name]
+		if !ok {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			d := byUUID[labels["UUID"]]
+			if d == nil {
+				index, _ := strconv.Atoi(labels["gpu"])
+				d = &GPUDevice{Index: index, UUID: labels["UUID"], Name: labels["modelName"]}
+				byUUID[d.UUID] = d
+			}
+			value := m.GetGauge().GetValue() + m.GetCounter().GetValue()
+			switch name {
+			case "DCGM_FI_DEV_GPU_UTIL":
+				d.UtilizationPercent = value
+			case "DCGM_FI_DEV_FB_USED":
+				d.MemoryUsedMB = value
+				d.MemoryTotalMB += value
+			case "DCGM_FI_DEV_FB_FREE":
+				d.MemoryTotalMB += value
+			case "DCGM_FI_DEV_GPU_TEMP":
+				d.TemperatureC = value
+			}
+		}
+	}
+
+	devices := make([]GPUDevice, 0, len(byUUID))
+	for _, d := range byUUID {
+		devices = append(devices, *d)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Index < devices[j].Index })
+	return devices, nil
+}
+
+// gpuRequest is the GPUs a container was given: all, or those listed by
+// index or UUID
+type gpuRequest struct {
+	all     bool
+	devices []string
+	count   int // --gpus N: the first N devices
+}
+
+// requestedGPUs reads the GPUs of a container from its device requests
+// (--gpus) or NVIDIA_VISIBLE_DEVICES (runtime: nvidia)
+func requestedGPUs(inspect *containerInspect) gpuRequest {
+	var req gpuRequest
+	requests, _ := inspect.HostConfig["DeviceRequests"].([]any)
+	for _, r := range requests {
+		dr, _ := r.(map[string]any)
+		if !isGPURequest(dr) {
+			continue
+		}
+		ids, _ := dr["DeviceIDs"].([]any)
+		for _, id := range ids {
+			req.devices = append(req.devices, fmt.Sprint(id))
+		}
+		if count, _ := dr["Count"].(float64); count < 0 {
+			req.all = true
+		} else if len(ids) == 0 {
+			req.count += int(count)
+		}
+	}
+
+	env, _ := inspect.Config["Env"].([]any)
+	for _, e := range env {
+		v, ok := strings.CutPrefix(fmt.Sprint(e), "NVIDIA_VISIBLE_DEVICES=")
+		if !ok {
+			continue
+		}
+		switch v {
+		case "all":
+			req.all = true
+		case "", "none", "void":
+		default:
+			req.devices = append(req.devices, strings.Split(v, ",")...)
+		}
+	}
+	return req
+}
+
+// isGPURequest reports whether a device request is for NVIDIA GPUs
+func isGPURequest(dr map[string]any) bool {
+	if dr["Driver"] == "nvidia" {
+		return true
+	}
+	caps, _ := dr["Capabilities"].([]any)
+	for _, set := range caps {
+		list, _ := set.([]any)
+		for _, c := range list {
+			if c == "gpu" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uses reports whether the request covers a device
+func (r gpuRequest) uses(d GPUDevice) bool {
+	if r.all || d.Index < r.count {
+		return true
+	}
+	for _, id := range r.devices {
+		if id == d.UUID || id == strconv.Itoa(d.Index) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddGPUStats attaches GPU usage to the system info, adds the GPUs each
+// container uses to its stats and recommends action on full GPU memory
+func (info *SystemInfo) AddGPUStats(stats *GPUStats) {
+	info.GPUs = stats
+	if stats == nil {
+		return
+	}
+
+	for _, container := range info.Containers {
+		if container.State != "running" {
+			continue
+		}
+		memory := make(map[string]float64)
+		for _, p := range stats.processes {
+			if p.containerID == container.id {
+				memory[p.uuid] += p.memoryMB
+			}
+		}
+		for _, d := range stats.Devices {
+			used, running := memory[d.UUID]
+			if !running && !container.gpus.uses(d) {
+				continue
+			}
+			container.GPUs = append(container.GPUs, ContainerGPU{
+				Index:              d.Index,
+				UUID:               d.UUID,
+				UtilizationPercent: d.UtilizationPercent,
+				MemoryMB:           used,
+			})
+		}
+	}
+
+	var recs []Recommendation
+	for _, d := range stats.Devices {
+		if d.MemoryTotalMB == 0 {
+			continue
+		}
+		if percent := d.MemoryUsedMB / d.MemoryTotalMB * 100; percent > gpuMemoryWarn {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityWarning,
+				Resource:  ResourceGPU,
+				Target:    d.UUID,
+				Action:    SuggestMonitor,
+				Value:     percent,
+				Threshold: gpuMemoryWarn,
+				Message:   fmt.Sprintf("🟡 GPU %d (%s) memory is %.0f%% used. Models that do not fit will fail to load.", d.Index, d.Name, percent),
+			})
+		}
+	}
+	info.addRecommendations(recs)
+}
@@ -0,0 +1,403 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/metrics"
+)
+
+// HostStats holds host-wide resource usage
+type HostStats struct {
+	CPUPercent    float64           `json:"cpu_percent"`
+	CPUs          int               `json:"cpus"`
+	MemoryTotalMB float64           `json:"memory_total_mb"`
+	MemoryUsedMB  float64           `json:"memory_used_mb"`
+	MemoryPercent float64           `json:"memory_percent"`
+	Load1         float64           `json:"load1"`
+	Load5         float64           `json:"load5"`
+	Load15        float64           `json:"load15"`
+	Disks         []DiskUsage       `json:"disks"`
+	Network       []NetworkActivity `json:"network"`
+}
+
+// DiskUsage is the usage of one mounted filesystem
+type DiskUsage struct {
+	Mount   string  `json:"mount"`
+	FSType  string  `json:"fs_type"`
+	TotalGB float64 `json:"total_gb"`
+	UsedGB  float64 `json:"used_gb"`
+	Percent float64 `json:"percent"`
+}
+
+// NetworkActivity is the throughput of one network interface since the
+// previous sample
+type NetworkActivity struct {
+	Interface   string  `json:"interface"`
+	RxBytesPerS float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerS float64 `json:"tx_bytes_per_sec"`
+}
+
+// diskFSTypes are the filesystems reported as disks; others are virtual
+var diskFSTypes = map[string]bool{
+	"ext2": true, "ext3": true, "ext4": true, "xfs": true, "btrfs": true,
+	"zfs": true, "vfat": true, "exfat": true, "ntfs": true, "ntfs3": true, "f2fs": true,
+}
+
+// virtualInterfacePrefixes are skipped from network throughput
+var virtualInterfacePrefixes = []string{"lo", "veth", "docker", "br-", "virbr"}
+
+// hostSample is a reading of the cumulative counters throughput is
+// derived from
+type hostSample struct {
+	at       time.Time
+	cpuIdle  uint64
+	cpuTotal uint64
+	net      map[string][2]uint64 // interface -> rx, tx bytes
+}
+
+// HostCollector reads host resource usage from procfs and statfs. Inside
+// a container, procPath and rootPath point at the host's /proc and / as
+// mounted into it.
+type HostCollector struct {
+	procPath string
+	rootPath string
+
+	mu     sync.Mutex
+	last   *hostSample
+	latest *HostStats
+}
+
+// NewHostCollector creates a collector reading procPath (e.g. /host/proc)
+// and resolving mounts under rootPath (e.g. /host/root, or "" for /)
+func NewHostCollector(procPath, rootPath string) *HostCollector {
+	return &HostCollector{procPath: procPath, rootPath: rootPath}
+}
+
+// Run collects every interval and exports the results as metrics until
+// ctx is cancelled
+func (c *HostCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if stats, err := c.Collect(); err == nil {
+			exportHostStats(stats)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Latest returns the most recent stats, or nil before the first collection
+func (c *HostCollector) Latest() *HostStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latest
+}
+
+// Collect reads current usage. CPU and network rates cover the time since
+// the previous call; the first call reports CPU usage since boot.
+func (c *HostCollector) Collect() (*HostStats, error) {
+	stats := &HostStats{Disks: []DiskUsage{}, Network: []NetworkActivity{}}
+	sample := &hostSample{at: time.Now()}
+
+	var err error
+	sample.cpuIdle, sample.cpuTotal, stats.CPUs, err = c.readCPU()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.readMemory(stats); err != nil {
+		return nil, err
+	}
+	if err := c.readLoad(stats); err != nil {
+		return nil, err
+	}
+	sample.net, _ = c.readNetwork()
+	stats.Disks = c.readDisks()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.last
+	if prev == nil {
+		prev = &hostSample{}
+	}
+	if total := sample.cpuTotal - prev.cpuTotal; total > 0 {
+		stats.CPUPercent = (1 - float64(sample.cpuIdle-prev.cpuIdle)/float64(total)) * 100
+	}
+	if elapsed := sample.at.Sub(prev.at).Seconds(); c.last != nil && elapsed > 0 {
+		for iface, cur := range sample.net {
+			old, ok := prev.net[iface]
+			if !ok || cur[0] < old[0] || cur[1] < old[1] {
+				continue
+			}
+			stats.Network = append(stats.Network, NetworkActivity{
+				Interface:   iface,
+				RxBytesPerS: float64(cur[0]-old[0]) / elapsed,
+				TxBytesPerS: float64(cur[1]-old[1]) / elapsed,
+			})
+		}
+	}
+
+	c.last = sample
+	c.latest = stats
+	return stats, nil
+}
+
+// readCPU sums idle and total jiffies from /proc/stat and counts CPUs
+func (c *HostCollector) readCPU() (idle, total uint64, cpus int, err error) {
+	f, err := os.Open(filepath.Join(c.procPath, "stat"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			cpus++
+			continue
+		}
+		// user nice system idle iowait irq softirq steal; guest time is
+		// already included in user
+		for i, v := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			n, _ := strconv.ParseUint(v, 10, 64)
+			total += n
+			if i == 3 || i == 4 {
+				idle += n
+			}
+		}
+	}
+	if total == 0 {
+		return 0, 0, 0, fmt.Errorf("no cpu line in %s/stat", c.procPath)
+	}
+	return idle, total, cpus, scanner.Err()
+}
+
+// readMemory reads MemTotal and MemAvailable from /proc/meminfo
+func (c *HostCollector) readMemory(stats *HostStats) error {
+	data, err := os.ReadFile(filepath.Join(c.procPath, "meminfo"))
+	if err != nil {
+		return err
+	}
+
+	var totalKB, availableKB float64
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseFloat(fields[1], 64)
+		switch fields[0] {
+		case "MemTotal:":
+			totalKB = v
+		case "MemAvailable:":
+			availableKB = v
+		}
+	}
+
+	stats.MemoryTotalMB = totalKB / 1024
+	stats.MemoryUsedMB = (totalKB - availableKB) / 1024
+	if totalKB > 0 {
+		stats.MemoryPercent = (totalKB - availableKB) / totalKB * 100
+	}
+	return nil
+}
+
+// readLoad reads the load averages from /proc/loadavg
+func (c *HostCollector) readLoad(stats *HostStats) error {
+	data, err := os.ReadFile(filepath.Join(c.procPath, "loadavg"))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected loadavg: %q", data)
+	}
+	stats.Load1, _ = strconv.ParseFloat(fields[0], 64)
+	stats.Load5, _ = strconv.ParseFloat(fields[1], 64)
+	stats.Load15, _ = strconv.ParseFloat(fields[2], 64)
+	return nil
+}
+
+// readNetwork reads per-interface byte counters of the host network
+// namespace, which PID 1 is in
+func (c *HostCollector) readNetwork() (map[string][2]uint64, error) {
+	data, err := os.ReadFile(filepath.Join(c.procPath, "1", "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string][2]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		iface, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		iface = strings.TrimSpace(iface)
+		if virtualInterface(iface) {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		counters[iface] = [2]uint64{rx, tx}
+	}
+	return counters, nil
+}
+
+func virtualInterface(iface string) bool {
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(iface, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readDisks reports the usage of each disk filesystem mounted on the host,
+// once per device
+func (c *HostCollector) readDisks() []DiskUsage {
+	data, err := os.ReadFile(filepath.Join(c.procPath, "1", "mounts"))
+	if err != nil {
+		return []DiskUsage{}
+	}
+
+	disks := []DiskUsage{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !diskFSTypes[fields[2]] || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+
+		mount := unescapeMount(fields[1])
+		total, free, err := diskUsage(filepath.Join(c.rootPath, mount))
+		if err != nil || total == 0 {
+			continue
+		}
+		used := total - free
+		disks = append(disks, DiskUsage{
+			Mount:   mount,
+			FSType:  fields[2],
+			TotalGB: float64(total) / (1 << 30),
+			UsedGB:  float64(used) / (1 << 30),
+			Percent: float64(used) / float64(total) * 100,
+		})
+	}
+	return disks
+}
+
+// unescapeMount decodes the octal escapes (e.g. \040 for space) of a
+// /proc mounts path
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// exportHostStats sets the host metrics
+func exportHostStats(stats *HostStats) {
+	metrics.HostCPUPercent.Set(stats.CPUPercent)
+	metrics.HostMemoryBytes.WithLabelValues("total").Set(stats.MemoryTotalMB * 1024 * 1024)
+	metrics.HostMemoryBytes.WithLabelValues("used").Set(stats.MemoryUsedMB * 1024 * 1024)
+	metrics.HostLoad.WithLabelValues("1m").Set(stats.Load1)
+	metrics.HostLoad.WithLabelValues("5m").Set(stats.Load5)
+	metrics.HostLoad.WithLabelValues("15m").Set(stats.Load15)
+
+	metrics.HostDiskBytes.Reset()
+	for _, d := range stats.Disks {
+		metrics.HostDiskBytes.WithLabelValues(d.Mount, "total").Set(d.TotalGB * (1 << 30))
+		metrics.HostDiskBytes.WithLabelValues(d.Mount, "used").Set(d.UsedGB * (1 << 30))
+	}
+	metrics.HostNetworkBytesPerSecond.Reset()
+	for _, n := range stats.Network {
+		metrics.HostNetworkBytesPerSecond.WithLabelValues(n.Interface, "rx").Set(n.RxBytesPerS)
+		metrics.HostNetworkBytesPerSecond.WithLabelValues(n.Interface, "tx").Set(n.TxBytesPerS)
+	}
+}
+
+// Host usage thresholds in percent
+const (
+	hostDiskCritical   = 90
+	hostDiskWarn       = 80
+	hostMemoryCritical = 90
+)
+
+// AddHostStats attaches host usage to the system info and recommends
+// action on full disks and memory
+func (info *SystemInfo) AddHostStats(stats *HostStats) {
+	info.Host = stats
+	if stats == nil {
+		return
+	}
+
+	var recs []Recommendation
+	for _, d := range stats.Disks {
+		if d.Percent > hostDiskCritical {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityCritical,
+				Resource:  ResourceDisk,
+				Target:    d.Mount,
+				Action:    SuggestFreeDisk,
+				Value:     d.Percent,
+				Threshold: hostDiskCritical,
+				Message:   fmt.Sprintf("🔴 Host disk %s is %.0f%% full. Free space or prune Docker images and volumes.", d.Mount, d.Percent),
+			})
+		} else if d.Percent > hostDiskWarn {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityWarning,
+				Resource:  ResourceDisk,
+				Target:    d.Mount,
+				Action:    SuggestMonitor,
+				Value:     d.Percent,
+				Threshold: hostDiskWarn,
+				Message:   fmt.Sprintf("🟡 Host disk %s is %.0f%% full.", d.Mount, d.Percent),
+			})
+		}
+	}
+	if stats.MemoryPercent > hostMemoryCritical {
+		recs = append(recs, Recommendation{
+			Severity:  SeverityCritical,
+			Resource:  ResourceMemory,
+			Action:    SuggestMonitor,
+			Value:     stats.MemoryPercent,
+			Threshold: hostMemoryCritical,
+			Message:   fmt.Sprintf("🔴 Host memory usage is critical (%.0f%%).", stats.MemoryPercent),
+		})
+	}
+	info.addRecommendations(recs)
+}
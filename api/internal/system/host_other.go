@@ -0,0 +1,10 @@
+//go:build !linux
+
+package system
+
+import "errors"
+
+// diskUsage is only supported on Linux
+func diskUsage(path string) (total, free uint64, err error) {
+	return 0, 0, errors.New("disk usage not supported on this platform")
+}
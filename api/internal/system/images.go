@@ -0,0 +1,288 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imagePullTimeout bounds an image pull, which downloads every layer
+const imagePullTimeout = 10 * time.Minute
+
+// ErrImageNotFound is returned for images Docker does not have
+var ErrImageNotFound = errors.New("image not found")
+
+// ImageInfo describes a local image
+type ImageInfo struct {
+	ID         string    `json:"id"`
+	Tags       []string  `json:"tags"`
+	Digests    []string  `json:"digests,omitempty"`
+	SizeMB     float64   `json:"size_mb"`
+	Created    time.Time `json:"created"`
+	Containers []string  `json:"containers"` // forge containers using the image
+}
+
+// ImageUpdate compares the image a container runs with the registry
+type ImageUpdate struct {
+	Container       string `json:"container"`
+	Image           string `json:"image"`
+	LocalDigest     string `json:"local_digest,omitempty"`
+	RemoteDigest    string `json:"remote_digest,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+	Error           string `json:"error,omitempty"`
+}
+
+// dockerImage represents the Docker /images/json response
+type dockerImage struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Size        int64    `json:"Size"`
+	Created     int64    `json:"Created"`
+}
+
+// ListImages returns local images, newest first, with the forge
+// containers that use them
+func (c *DockerClient) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	var images []dockerImage
+	if err := c.getJSON(ctx, "/images/json", &images); err != nil {
+		return nil, err
+	}
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string][]string)
+	for _, ct := range containers {
+		if stats := forgeContainerStats(ct); stats != nil {
+			users[ct.ImageID] = append(users[ct.ImageID], stats.Name)
+		}
+	}
+
+	list := make([]ImageInfo, 0, len(images))
+	for _, img := range images {
+		tags := img.RepoTags
+		if tags == nil {
+			tags = []string{}
+		}
+		using := users[img.ID]
+		if using == nil {
+			using = []string{}
+		}
+		sort.Strings(using)
+		list = append(list, ImageInfo{
+			ID:         strings.TrimPrefix(img.ID, "sha256:"),
+			Tags:       tags,
+			Digests:    img.RepoDigests,
+			SizeMB:     mb(img.Size),
+			Created:    time.Unix(img.Created, 0).UTC(),
+			Containers: using,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+	return list, nil
+}
+
+// PullImage pulls ref (e.g. grafana/loki:2.9.0) from its registry. Only
+// public images are supported.
+func (c *DockerClient) PullImage(ctx context.Context, ref string) error {
+	ctx, cancel := context.WithTimeout(ctx, imagePullTimeout)
+	defer cancel()
+
+	name, tag := splitImageRef(ref)
+	q := url.Values{"fromImage": {name}, "tag": {tag}}
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://docker/images/create?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dockerError(resp)
+	}
+
+	// Progress is streamed as JSON messages; failures arrive as one of
+	// them with a 200 status
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull %s: %s", ref, msg.Error)
+		}
+	}
+}
+
+// EnsureImage pulls ref unless Docker already has it, so locally built
+// images can be used too
+func (c *DockerClient) EnsureImage(ctx context.Context, ref string) error {
+	var img dockerImage
+	err := c.getJSON(ctx, "/images/"+ref+"/json", &img)
+	if errors.Is(err, ErrImageNotFound) {
+		return c.PullImage(ctx, ref)
+	}
+	return err
+}
+
+// RemoveImage removes an image by ID or reference. Without force, images
+// used by containers are kept.
+func (c *DockerClient) RemoveImage(ctx context.Context, ref string, force bool) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", "http://docker/images/"+ref+"?force="+fmt.Sprint(force), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrImageNotFound
+	}
+	return dockerError(resp)
+}
+
+// CheckUpdates compares the image digest of each running forge container
+// with the digest its tag has in the registry now
+func (c *DockerClient) CheckUpdates(ctx context.Context) ([]ImageUpdate, error) {
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []dockerContainer
+	for _, ct := range containers {
+		if stats := forgeContainerStats(ct); stats != nil && ct.State == "running" {
+			running = append(running, ct)
+		}
+	}
+
+	updates := make([]ImageUpdate, len(running))
+	remote := make(map[string]string) // image ref -> registry digest
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, statsWorkers)
+	for i, ct := range running {
+		wg.Add(1)
+		go func(i int, ct dockerContainer) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			u := ImageUpdate{Container: strings.TrimPrefix(ct.Names[0], "/"), Image: ct.Image}
+			defer func() { updates[i] = u }()
+
+			var img dockerImage
+			if err := c.getJSON(ctx, "/images/"+ct.ImageID+"/json", &img); err != nil {
+				u.Error = err.Error()
+				return
+			}
+			if len(img.RepoDigests) == 0 {
+				u.Error = "image was built locally"
+				return
+			}
+
+			mu.Lock()
+			digest, ok := remote[ct.Image]
+			mu.Unlock()
+			if !ok {
+				var dist struct {
+					Descriptor struct {
+						Digest string `json:"digest"`
+					} `json:"Descriptor"`
+				}
+				if err := c.getJSON(ctx, "/distribution/"+ct.Image+"/json", &dist); err != nil {
+					u.Error = "registry lookup failed: " + err.Error()
+					return
+				}
+				digest = dist.Descriptor.Digest
+				mu.Lock()
+				remote[ct.Image] = digest
+				mu.Unlock()
+			}
+
+			u.RemoteDigest = digest
+			u.UpdateAvailable = true
+			for _, d := range img.RepoDigests {
+				_, local, _ := strings.Cut(d, "@")
+				if u.LocalDigest == "" {
+					u.LocalDigest = local
+				}
+				if local == digest {
+					u.LocalDigest = local
+					u.UpdateAvailable = false
+				}
+			}
+		}(i, ct)
+	}
+	wg.Wait()
+
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Container < updates[j].Container })
+	return updates, nil
+}
+
+// getJSON decodes the response of a Docker API GET
+func (c *DockerClient) getJSON(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://docker"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrImageNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return dockerError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// dockerError returns the message of a Docker API error response
+func dockerError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body) == nil && body.Message != "" {
+		return fmt.Errorf("docker API status %d: %s", resp.StatusCode, body.Message)
+	}
+	return fmt.Errorf("docker API status %d", resp.StatusCode)
+}
+
+// splitImageRef splits an image reference into name and tag, defaulting
+// the tag to latest. Digest references keep the digest in the name.
+func splitImageRef(ref string) (name, tag string) {
+	if strings.Contains(ref, "@") {
+		return ref, ""
+	}
+	// A colon after the last slash separates the tag; one before it is a
+	// registry port
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
@@ -0,0 +1,349 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/forge/api/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidLimits is returned for limits that cannot be applied
+var ErrInvalidLimits = errors.New("invalid limits")
+
+// minMemoryLimit is the smallest memory limit Docker accepts
+const minMemoryLimit = 6 << 20
+
+// ResourceLimits are the memory and CPU limits of a container. Zero values
+// mean unlimited.
+type ResourceLimits struct {
+	// Memory is a size such as 512m or 2g; swap is allowed up to the same
+	// amount again, as with docker run --memory
+	Memory string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	// CPUs is the number of CPUs, e.g. 1.5
+	CPUs float64 `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+}
+
+// ContainerLimits are the limits a container runs with and the ones
+// stored for it
+type ContainerLimits struct {
+	Container string          `json:"container"`
+	State     string          `json:"state"`
+	Current   ResourceLimits  `json:"current"`
+	Stored    *ResourceLimits `json:"stored,omitempty"` // reapplied when the container starts
+}
+
+// limitsFile is the stored limits by container name
+type limitsFile struct {
+	Containers map[string]ResourceLimits `yaml:"containers"`
+}
+
+// LimitManager updates container memory and CPU limits and stores them, so
+// they are applied again when a container is recreated (e.g. by compose).
+// Stored limits are applied on start and at startup; Docker cannot remove
+// a limit from an existing container, so a forgotten limit stays until
+// the container is recreated.
+type LimitManager struct {
+	docker     *DockerClient
+	events     *EventWatcher
+	users      ContainerFilter
+	configPath string
+
+	mu     sync.Mutex
+	limits map[string]ResourceLimits
+}
+
+// NewLimitManager creates a limit manager storing limits at configPath.
+// users selects the containers listed next to the stack.
+func NewLimitManager(docker *DockerClient, events *EventWatcher, users ContainerFilter, configPath string) (*LimitManager, error) {
+	m := &LimitManager{
+		docker:     docker,
+		events:     events,
+		users:      users,
+		configPath: configPath,
+		limits:     make(map[string]ResourceLimits),
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var file limitsFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("limits config: %w", err)
+		}
+		for name, l := range file.Containers {
+			if _, _, err := l.resources(); err != nil {
+				return nil, fmt.Errorf("limits config: %s: %w", name, err)
+			}
+			m.limits[name] = l
+		}
+	}
+	return m, nil
+}
+
+// List returns the limits of the stack containers, the user containers and
+// any other container with stored limits, sorted by name
+func (m *LimitManager) List(ctx context.Context) ([]ContainerLimits, error) {
+	containers, err := m.docker.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	stored := make(map[string]ResourceLimits, len(m.limits))
+	for name, l := range m.limits {
+		stored[name] = l
+	}
+	m.mu.Unlock()
+
+	var list []ContainerLimits
+	for _, ct := range containers {
+		if len(ct.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(ct.Names[0], "/")
+		if _, ok := stored[name]; !ok && filteredContainerStats(ct, m.users) == nil {
+			continue
+		}
+		limits, err := m.Get(ctx, name)
+		if errors.Is(err, ErrContainerNotFound) {
+			continue // removed since listing
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, limits)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Container < list[j].Container })
+	return list, nil
+}
+
+// Get returns the current and stored limits of a container
+func (m *LimitManager) Get(ctx context.Context, name string) (ContainerLimits, error) {
+	inspect, err := m.inspect(ctx, name)
+	if err != nil {
+		return ContainerLimits{}, err
+	}
+	limits := ContainerLimits{
+		Container: strings.TrimPrefix(inspect.Name, "/"),
+		State:     inspect.State.Status,
+	}
+	if v, ok := inspect.HostConfig["Memory"].(float64); ok && v > 0 {
+		limits.Current.Memory = formatMemory(int64(v))
+	}
+	if v, ok := inspect.HostConfig["NanoCpus"].(float64); ok && v > 0 {
+		limits.Current.CPUs = v / 1e9
+	}
+	m.mu.Lock()
+	if l, ok := m.limits[limits.Container]; ok {
+		limits.Stored = &l
+	}
+	m.mu.Unlock()
+	return limits, nil
+}
+
+// Set applies limits to a container and stores them. Fields left empty
+// keep the container's current value.
+func (m *LimitManager) Set(ctx context.Context, name string, l ResourceLimits) (ContainerLimits, error) {
+	if l.Memory == "" && l.CPUs == 0 {
+		return ContainerLimits{}, fmt.Errorf("%w: memory or cpus is required", ErrInvalidLimits)
+	}
+	inspect, err := m.inspect(ctx, name)
+	if err != nil {
+		return ContainerLimits{}, err
+	}
+	if err := m.apply(ctx, inspect.ID, l); err != nil {
+		return ContainerLimits{}, err
+	}
+
+	name = strings.TrimPrefix(inspect.Name, "/")
+	m.mu.Lock()
+	prev, had := m.limits[name]
+	m.limits[name] = l
+	err = m.saveLocked()
+	if err != nil {
+		if had {
+			m.limits[name] = prev
+		} else {
+			delete(m.limits, name)
+		}
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return ContainerLimits{}, fmt.Errorf("save limits: %w", err)
+	}
+	return m.Get(ctx, name)
+}
+
+// Forget removes the stored limits of a container. The container keeps
+// running with them until it is recreated.
+func (m *LimitManager) Forget(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev, ok := m.limits[name]
+	if !ok {
+		return ErrContainerNotFound
+	}
+	delete(m.limits, name)
+	if err := m.saveLocked(); err != nil {
+		m.limits[name] = prev
+		return fmt.Errorf("save limits: %w", err)
+	}
+	return nil
+}
+
+// Run applies the stored limits to existing containers, then to every
+// stack container that starts, until ctx is cancelled
+func (m *LimitManager) Run(ctx context.Context) {
+	var events <-chan ContainerEvent
+	if m.events != nil {
+		ch, unsubscribe := m.events.Subscribe()
+		defer unsubscribe()
+		events = ch
+	}
+
+	m.mu.Lock()
+	names := make([]string, 0, len(m.limits))
+	for name := range m.limits {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	for _, name := range names {
+		m.reapply(ctx, name)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			if e.Action == "start" {
+				m.reapply(ctx, e.Container)
+			}
+		}
+	}
+}
+
+// reapply applies the stored limits of a container, if any
+func (m *LimitManager) reapply(ctx context.Context, name string) {
+	m.mu.Lock()
+	l, ok := m.limits[name]
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	inspect, err := m.inspect(ctx, name)
+	if err == nil {
+		err = m.apply(ctx, inspect.ID, l)
+	}
+	switch {
+	case errors.Is(err, ErrContainerNotFound):
+		// Not created yet; applied when it starts
+	case err != nil:
+		logger.Error("Failed to apply resource limits to "+name, err)
+	}
+}
+
+// apply updates the limits of a running or stopped container
+func (m *LimitManager) apply(ctx context.Context, id string, l ResourceLimits) error {
+	memory, nanoCPUs, err := l.resources()
+	if err != nil {
+		return err
+	}
+	update := make(map[string]any)
+	if memory > 0 {
+		update["Memory"] = memory
+		update["MemorySwap"] = 2 * memory
+	}
+	if nanoCPUs > 0 {
+		update["NanoCpus"] = nanoCPUs
+	}
+	return m.docker.postJSON(ctx, "/containers/"+id+"/update", update, nil)
+}
+
+// inspect returns a container by name or ID
+func (m *LimitManager) inspect(ctx context.Context, name string) (*containerInspect, error) {
+	var inspect containerInspect
+	err := m.docker.getJSON(ctx, "/containers/"+url.PathEscape(name)+"/json", &inspect)
+	if errors.Is(err, ErrImageNotFound) {
+		return nil, ErrContainerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inspect, nil
+}
+
+// saveLocked writes the stored limits; the caller holds m.mu
+func (m *LimitManager) saveLocked() error {
+	data, err := yaml.Marshal(&limitsFile{Containers: m.limits})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0644)
+}
+
+// resources validates the limits and returns them in Docker's units
+func (l ResourceLimits) resources() (memory, nanoCPUs int64, err error) {
+	if l.Memory != "" {
+		if memory, err = parseMemory(l.Memory); err != nil {
+			return 0, 0, err
+		}
+		if memory < minMemoryLimit {
+			return 0, 0, fmt.Errorf("%w: memory must be at least 6m", ErrInvalidLimits)
+		}
+	}
+	if l.CPUs < 0 || math.IsNaN(l.CPUs) || math.IsInf(l.CPUs, 0) {
+		return 0, 0, fmt.Errorf("%w: cpus must be positive", ErrInvalidLimits)
+	}
+	if l.CPUs > 0 && l.CPUs < 0.01 {
+		return 0, 0, fmt.Errorf("%w: cpus must be at least 0.01", ErrInvalidLimits)
+	}
+	return memory, int64(l.CPUs * 1e9), nil
+}
+
+// memoryUnits are the size suffixes accepted, as in docker run --memory
+var memoryUnits = map[string]int64{"": 1, "b": 1, "k": 1 << 10, "m": 1 << 20, "g": 1 << 30}
+
+// parseMemory parses a size such as 512m, 1.5g or 1073741824
+func parseMemory(s string) (int64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	// Accept 512mb and 512mib as well
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "ib"), "b")
+	i := strings.IndexFunc(v, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	unit := ""
+	if i >= 0 {
+		v, unit = v[:i], v[i:]
+	}
+	mult, ok := memoryUnits[unit]
+	n, err := strconv.ParseFloat(v, 64)
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: memory %q is not a size like 512m or 2g", ErrInvalidLimits, s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// formatMemory formats a byte count in the largest unit dividing it
+func formatMemory(b int64) string {
+	switch {
+	case b%(1<<30) == 0:
+		return fmt.Sprintf("%dg", b>>30)
+	case b%(1<<20) == 0:
+		return fmt.Sprintf("%dm", b>>20)
+	case b%(1<<10) == 0:
+		return fmt.Sprintf("%dk", b>>10)
+	}
+	return strconv.FormatInt(b, 10)
+}
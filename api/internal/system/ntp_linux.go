@@ -0,0 +1,38 @@
+//go:build linux
+
+package system
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// NTPStatus is the kernel's view of time synchronization.
+// Containers share the host kernel clock, so this reflects the host.
+type NTPStatus struct {
+	Synchronized bool    `json:"synchronized"`
+	MaxErrorMS   float64 `json:"max_error_ms"`
+	EstErrorMS   float64 `json:"est_error_ms"`
+	OffsetMS     float64 `json:"offset_ms"`
+}
+
+// GetNTPStatus reads the kernel clock discipline state via adjtimex
+func GetNTPStatus() (*NTPStatus, error) {
+	var tx unix.Timex
+	state, err := unix.Adjtimex(&tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// STA_NANO means offset is in nanoseconds rather than microseconds
+	offset := float64(tx.Offset) / 1000
+	if tx.Status&unix.STA_NANO != 0 {
+		offset = float64(tx.Offset) / 1e6
+	}
+
+	return &NTPStatus{
+		Synchronized: state != unix.TIME_ERROR && tx.Status&unix.STA_UNSYNC == 0,
+		MaxErrorMS:   float64(tx.Maxerror) / 1000,
+		EstErrorMS:   float64(tx.Esterror) / 1000,
+		OffsetMS:     offset,
+	}, nil
+}
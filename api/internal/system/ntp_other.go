@@ -0,0 +1,18 @@
+//go:build !linux
+
+package system
+
+import "errors"
+
+// NTPStatus is the kernel's view of time synchronization
+type NTPStatus struct {
+	Synchronized bool    `json:"synchronized"`
+	MaxErrorMS   float64 `json:"max_error_ms"`
+	EstErrorMS   float64 `json:"est_error_ms"`
+	OffsetMS     float64 `json:"offset_ms"`
+}
+
+// GetNTPStatus is only supported on Linux
+func GetNTPStatus() (*NTPStatus, error) {
+	return nil, errors.New("ntp status not supported on this platform")
+}
@@ -0,0 +1,67 @@
+package system
+
+// Recommendation severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Recommendation resources: what a recommendation is about
+const (
+	ResourceState  = "state" // container not running
+	ResourceHealth = "health"
+	ResourceMemory = "memory"
+	ResourceCPU    = "cpu"
+	ResourceGPU    = "gpu"
+	ResourceDisk   = "disk"
+	ResourceClock  = "clock"
+	ResourceRoute  = "route"
+)
+
+// Suggested actions of recommendations
+const (
+	SuggestNone                = "none"
+	SuggestStartContainer      = "start_container"
+	SuggestCheckLogs           = "check_logs"
+	SuggestIncreaseMemoryLimit = "increase_memory_limit"
+	SuggestMonitor             = "monitor"
+	SuggestScale               = "scale"
+	SuggestFreeDisk            = "free_disk"
+	SuggestEnableNTP           = "enable_ntp"
+	SuggestCheckTimeSync       = "check_time_sync"
+	SuggestCheckRoute          = "check_route"
+	SuggestCheckMemoryGrowth   = "check_memory_growth"
+)
+
+// Recommendation is something in the system info that needs attention,
+// with a rendered Message for display. Value and Threshold are percent,
+// ms of clock skew or, for health, failed checks in a row.
+type Recommendation struct {
+	Severity  string  `json:"severity"` // info, warning or critical
+	Resource  string  `json:"resource,omitempty"`
+	Container string  `json:"container,omitempty"`
+	Target    string  `json:"target,omitempty"` // disk mount, route or time source
+	Action    string  `json:"action"`
+	Value     float64 `json:"value,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Message   string  `json:"message"`
+}
+
+// allHealthyRecommendation is reported when nothing needs attention
+var allHealthyRecommendation = Recommendation{
+	Severity: SeverityInfo,
+	Action:   SuggestNone,
+	Message:  "✅ All services are healthy",
+}
+
+// addRecommendations appends recs, dropping the all healthy entry
+func (info *SystemInfo) addRecommendations(recs []Recommendation) {
+	if len(recs) == 0 {
+		return
+	}
+	if len(info.Recommendations) == 1 && info.Recommendations[0] == allHealthyRecommendation {
+		info.Recommendations = nil
+	}
+	info.Recommendations = append(info.Recommendations, recs...)
+}
@@ -0,0 +1,36 @@
+package system
+
+import (
+	"fmt"
+	"time"
+)
+
+// RouteAvailability is the probe status of a dynamic route
+type RouteAvailability struct {
+	Route     string    `json:"route"`
+	URL       string    `json:"url"`
+	State     string    `json:"state"`  // pending, up or down
+	Uptime    float64   `json:"uptime"` // fraction of recent probes that succeeded
+	LastCheck time.Time `json:"last_check,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// AddRouteAvailability attaches route probe results to the system info and
+// adds a recommendation for every route that is down
+func (info *SystemInfo) AddRouteAvailability(routes []RouteAvailability) {
+	info.Routes = routes
+
+	var recs []Recommendation
+	for _, r := range routes {
+		if r.State == "down" {
+			recs = append(recs, Recommendation{
+				Severity: SeverityCritical,
+				Resource: ResourceRoute,
+				Target:   r.Route,
+				Action:   SuggestCheckRoute,
+				Message:  fmt.Sprintf("🔴 Route %s is failing its health probe (%s).", r.Route, r.LastError),
+			})
+		}
+	}
+	info.addRecommendations(recs)
+}
@@ -0,0 +1,192 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statsRefresh is how often the hub relists containers to follow starts,
+// stops and removals
+const statsRefresh = 10 * time.Second
+
+// StateRemoved is reported for containers that no longer exist
+const StateRemoved = "removed"
+
+// StatsHub keeps a Docker stats stream open for every running forge
+// container while anyone is subscribed, and fans the samples out.
+// Slow subscribers miss samples rather than block the others.
+type StatsHub struct {
+	docker *DockerClient
+	filter ContainerFilter
+
+	mu     sync.Mutex
+	subs   map[chan ContainerStats]struct{}
+	cancel context.CancelFunc // stops the current session; nil when idle
+}
+
+// statsSession is the state of the streams opened for one run of
+// subscribers; it is guarded by the hub's mu
+type statsSession struct {
+	stats     map[string]*ContainerStats // latest by container name
+	streaming map[string]bool            // container IDs with an open stream
+}
+
+// NewStatsHub creates a hub reading stats through docker
+func NewStatsHub(docker *DockerClient) *StatsHub {
+	return &StatsHub{
+		docker: docker,
+		subs:   make(map[chan ContainerStats]struct{}),
+	}
+}
+
+// SetFilter adds the containers selected by filter to the streamed ones.
+// It must be called before the first Subscribe.
+func (h *StatsHub) SetFilter(filter ContainerFilter) {
+	h.filter = filter
+}
+
+// Subscribe returns a channel of container updates, starting the streams
+// if needed. Call unsubscribe when done; the streams stop with the last
+// subscriber.
+func (h *StatsHub) Subscribe() (updates <-chan ContainerStats, unsubscribe func()) {
+	ch := make(chan ContainerStats, 64)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	if h.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancel = cancel
+		go h.run(ctx)
+	}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs, ch)
+		if len(h.subs) == 0 && h.cancel != nil {
+			h.cancel()
+			h.cancel = nil
+		}
+	}
+}
+
+// run follows containers until ctx is cancelled
+func (h *StatsHub) run(ctx context.Context) {
+	sess := &statsSession{
+		stats:     make(map[string]*ContainerStats),
+		streaming: make(map[string]bool),
+	}
+	ticker := time.NewTicker(statsRefresh)
+	defer ticker.Stop()
+	for {
+		h.refresh(ctx, sess)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh relists containers, publishes listing changes and opens streams
+// for running containers without one
+func (h *StatsHub) refresh(ctx context.Context, sess *statsSession) {
+	containers, err := h.docker.listContainers(ctx)
+	if err != nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, container := range containers {
+		listed := filteredContainerStats(container, h.filter)
+		if listed == nil {
+			continue
+		}
+		seen[listed.Name] = true
+
+		h.mu.Lock()
+		cur := sess.stats[listed.Name]
+		switch {
+		case cur == nil || listed.State != "running":
+			// New or stopped: usage is only known from the stream
+			if cur == nil || cur.State != listed.State || cur.Status != listed.Status {
+				sess.stats[listed.Name] = listed
+				h.publish(*listed)
+			}
+		case cur.State != listed.State || cur.Status != listed.Status:
+			cur.State, cur.Status, cur.Image = listed.State, listed.Status, listed.Image
+			cur.Uptime, cur.Endpoints = listed.Uptime, listed.Endpoints
+			h.publish(*cur)
+		}
+		start := listed.State == "running" && !sess.streaming[container.ID]
+		if start {
+			sess.streaming[container.ID] = true
+		}
+		h.mu.Unlock()
+
+		if start {
+			go h.stream(ctx, sess, container.ID, listed.Name)
+		}
+	}
+
+	h.mu.Lock()
+	for name := range sess.stats {
+		if !seen[name] {
+			delete(sess.stats, name)
+			h.publish(ContainerStats{Name: name, State: StateRemoved})
+		}
+	}
+	h.mu.Unlock()
+}
+
+// stream publishes the samples of one container until its stream ends
+func (h *StatsHub) stream(ctx context.Context, sess *statsSession, id, name string) {
+	defer func() {
+		h.mu.Lock()
+		delete(sess.streaming, id)
+		h.mu.Unlock()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://docker/containers/%s/stats?stream=true", id), nil)
+	if err != nil {
+		return
+	}
+	resp, err := h.docker.streamClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var sample dockerStats
+		if err := dec.Decode(&sample); err != nil {
+			return
+		}
+
+		h.mu.Lock()
+		if cur := sess.stats[name]; cur != nil {
+			cur.apply(&sample)
+			h.publish(*cur)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// publish sends an update to every subscriber; callers hold mu
+func (h *StatsHub) publish(stats ContainerStats) {
+	for ch := range h.subs {
+		select {
+		case ch <- stats:
+		default:
+		}
+	}
+}
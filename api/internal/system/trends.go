@@ -0,0 +1,65 @@
+package system
+
+import "fmt"
+
+// Thresholds for trend recommendations
+const (
+	// trendMemoryGrowth is the memory growth in percent worth reporting
+	trendMemoryGrowth = 20
+	// trendMemoryFloorMB ignores growth of containers using little memory
+	trendMemoryFloorMB = 64
+	// trendCPUFloor ignores CPU growth below this average usage in percent
+	trendCPUFloor = 20
+)
+
+// UsageTrend compares the average usage of a container or the host at the
+// start and the end of a window
+type UsageTrend struct {
+	Kind            string  `json:"kind"` // host or container
+	Name            string  `json:"name"`
+	Days            int     `json:"days"` // window length
+	MemoryStartMB   float64 `json:"memory_start_mb"`
+	MemoryEndMB     float64 `json:"memory_end_mb"`
+	MemoryChange    float64 `json:"memory_change_percent"`
+	CPUStartPercent float64 `json:"cpu_start_percent"`
+	CPUEndPercent   float64 `json:"cpu_end_percent"`
+}
+
+// AddUsageTrends attaches usage trends to the system info and adds a
+// recommendation for memory or CPU usage that grew markedly
+func (info *SystemInfo) AddUsageTrends(trends []UsageTrend) {
+	info.Trends = trends
+
+	var recs []Recommendation
+	for _, t := range trends {
+		name, container := t.Name, t.Name
+		if t.Kind == "host" {
+			name, container = "Host", ""
+		}
+		if t.MemoryChange >= trendMemoryGrowth && t.MemoryEndMB >= trendMemoryFloorMB {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityWarning,
+				Resource:  ResourceMemory,
+				Container: container,
+				Action:    SuggestCheckMemoryGrowth,
+				Value:     t.MemoryChange,
+				Threshold: trendMemoryGrowth,
+				Message: fmt.Sprintf("📈 %s memory grew %.0f%% in %d days (%.0f MB → %.0f MB). Check for leaks or raise its limit.",
+					name, t.MemoryChange, t.Days, t.MemoryStartMB, t.MemoryEndMB),
+			})
+		}
+		if t.CPUEndPercent >= trendCPUFloor && t.CPUEndPercent >= 1.5*t.CPUStartPercent {
+			recs = append(recs, Recommendation{
+				Severity:  SeverityWarning,
+				Resource:  ResourceCPU,
+				Container: container,
+				Action:    SuggestMonitor,
+				Value:     t.CPUEndPercent,
+				Threshold: trendCPUFloor,
+				Message: fmt.Sprintf("📈 %s CPU usage rose from %.1f%% to %.1f%% in %d days.",
+					name, t.CPUStartPercent, t.CPUEndPercent, t.Days),
+			})
+		}
+	}
+	info.addRecommendations(recs)
+}
@@ -0,0 +1,476 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// upgradeHealthTimeout bounds the wait for a recreated container with a
+	// healthcheck to report healthy
+	upgradeHealthTimeout = 3 * time.Minute
+	// upgradeSettle is how long a container without a healthcheck must stay
+	// running to count as started
+	upgradeSettle = 10 * time.Second
+	// upgradeStopTimeout is the grace period given to old containers
+	upgradeStopTimeout = 30
+)
+
+// Upgrade outcomes of a container
+const (
+	UpgradeUpgraded   = "upgraded"    // recreated on the new image
+	UpgradeCurrent    = "current"     // already on the latest image
+	UpgradeSkipped    = "skipped"     // not upgraded, see error
+	UpgradeRolledBack = "rolled_back" // new container failed, old one restored
+	UpgradeFailed     = "failed"      // failed and could not be restored
+	UpgradeNotRun     = "not_run"     // an earlier failure stopped the upgrade
+)
+
+// UpgradeResult is the upgrade outcome of one container
+type UpgradeResult struct {
+	Container  string `json:"container"`
+	Service    string `json:"service,omitempty"`
+	Image      string `json:"image"`
+	OldImageID string `json:"old_image_id,omitempty"`
+	NewImageID string `json:"new_image_id,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// UpgradeReporter receives upgrade progress
+type UpgradeReporter interface {
+	Logf(format string, args ...any)
+	SetProgress(pct float64)
+}
+
+// containerInspect holds what recreating a container and reporting its
+// health need from /containers/{id}/json
+type containerInspect struct {
+	ID              string         `json:"Id"`
+	Name            string         `json:"Name"`
+	Image           string         `json:"Image"` // image ID
+	Config          map[string]any `json:"Config"`
+	HostConfig      map[string]any `json:"HostConfig"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			Aliases    []string        `json:"Aliases"`
+			Links      []string        `json:"Links"`
+			IPAMConfig json.RawMessage `json:"IPAMConfig"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+	RestartCount int `json:"RestartCount"`
+	State        struct {
+		Status  string        `json:"Status"`
+		Running bool          `json:"Running"`
+		Health  *dockerHealth `json:"Health"`
+	} `json:"State"`
+}
+
+// upgradeTarget is a container considered for upgrade
+type upgradeTarget struct {
+	inspect   *containerInspect
+	result    *UpgradeResult
+	dependsOn []string // compose services
+}
+
+// Upgrade pulls the images of running forge containers and recreates the
+// containers whose image changed, one at a time with dependencies first.
+// Each recreated container must become healthy (or stay running when it
+// has no healthcheck); otherwise it is replaced by the old container again
+// and the upgrade stops. services limits the upgrade to these compose
+// services or container names. The API's own container is never recreated.
+func (c *DockerClient) Upgrade(ctx context.Context, services []string, report UpgradeReporter) ([]UpgradeResult, error) {
+	targets, err := c.upgradeTargets(ctx, services)
+	if err != nil {
+		return nil, err
+	}
+	results := func() []UpgradeResult {
+		list := make([]UpgradeResult, len(targets))
+		for i, t := range targets {
+			list[i] = *t.result
+		}
+		return list
+	}
+	if len(targets) == 0 {
+		return results(), nil
+	}
+	self, _ := os.Hostname()
+
+	// Pull everything first so a registry failure stops the upgrade before
+	// any container is touched
+	var pending []*upgradeTarget
+	for i, t := range targets {
+		r := t.result
+		switch {
+		case self != "" && strings.HasPrefix(t.inspect.ID, self):
+			r.Status = UpgradeSkipped
+			r.Error = "the API cannot recreate its own container; run docker compose pull api && docker compose up -d api"
+		case strings.HasPrefix(r.Image, "sha256:"):
+			r.Status = UpgradeSkipped
+			r.Error = "container runs an untagged image"
+		default:
+			report.Logf("pulling %s for %s", r.Image, r.Container)
+			if err := c.PullImage(ctx, r.Image); err != nil {
+				return results(), err
+			}
+			var img dockerImage
+			if err := c.getJSON(ctx, "/images/"+r.Image+"/json", &img); err != nil {
+				return results(), fmt.Errorf("inspect %s: %w", r.Image, err)
+			}
+			r.NewImageID = strings.TrimPrefix(img.ID, "sha256:")
+			if img.ID == t.inspect.Image {
+				r.Status = UpgradeCurrent
+			} else {
+				pending = append(pending, t)
+			}
+		}
+		report.SetProgress(float64(i+1) / float64(len(targets)) * 40)
+	}
+
+	for i, t := range pending {
+		r := t.result
+		report.Logf("recreating %s on %s", r.Container, r.Image)
+		if err := c.recreate(ctx, t.inspect, r.Image, report); err != nil {
+			r.Error = err.Error()
+			report.Logf("%s failed: %v; restoring the old container", r.Container, err)
+			if rbErr := c.restore(context.WithoutCancel(ctx), t.inspect); rbErr != nil {
+				r.Status = UpgradeFailed
+				r.Error += "; rollback failed: " + rbErr.Error()
+			} else {
+				r.Status = UpgradeRolledBack
+			}
+			return results(), fmt.Errorf("upgrade of %s failed: %s", r.Container, r.Error)
+		}
+		r.Status = UpgradeUpgraded
+		report.Logf("%s upgraded", r.Container)
+		report.SetProgress(40 + float64(i+1)/float64(len(pending))*60)
+	}
+	report.SetProgress(100)
+	return results(), nil
+}
+
+// upgradeTargets inspects running forge containers, optionally limited to
+// services, and orders them dependencies first
+func (c *DockerClient) upgradeTargets(ctx context.Context, services []string) ([]*upgradeTarget, error) {
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(services))
+	for _, s := range services {
+		wanted[s] = true
+	}
+
+	byService := make(map[string]*upgradeTarget)
+	var targets []*upgradeTarget
+	for _, ct := range containers {
+		stats := forgeContainerStats(ct)
+		if stats == nil || ct.State != "running" {
+			continue
+		}
+		service := ct.Labels["com.docker.compose.service"]
+		if len(wanted) > 0 && !wanted[service] && !wanted[stats.Name] {
+			continue
+		}
+
+		var inspect containerInspect
+		if err := c.getJSON(ctx, "/containers/"+ct.ID+"/json", &inspect); err != nil {
+			return nil, fmt.Errorf("inspect %s: %w", stats.Name, err)
+		}
+		image, _ := inspect.Config["Image"].(string)
+		t := &upgradeTarget{
+			inspect: &inspect,
+			result: &UpgradeResult{
+				Container:  stats.Name,
+				Service:    service,
+				Image:      image,
+				OldImageID: strings.TrimPrefix(inspect.Image, "sha256:"),
+				Status:     UpgradeNotRun,
+			},
+		}
+		// e.g. "mysql:service_healthy:false,redis:service_started:false"
+		for _, dep := range strings.Split(ct.Labels["com.docker.compose.depends_on"], ",") {
+			if name, _, _ := strings.Cut(dep, ":"); name != "" {
+				t.dependsOn = append(t.dependsOn, name)
+			}
+		}
+		targets = append(targets, t)
+		if service != "" {
+			byService[service] = t
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].result.Container < targets[j].result.Container })
+
+	// Depth-first topological order; a cycle falls back to name order
+	var ordered []*upgradeTarget
+	state := make(map[*upgradeTarget]int) // 1 visiting, 2 done
+	var visit func(t *upgradeTarget)
+	visit = func(t *upgradeTarget) {
+		if state[t] != 0 {
+			return
+		}
+		state[t] = 1
+		for _, dep := range t.dependsOn {
+			if d, ok := byService[dep]; ok {
+				visit(d)
+			}
+		}
+		state[t] = 2
+		ordered = append(ordered, t)
+	}
+	for _, t := range targets {
+		visit(t)
+	}
+	return ordered, nil
+}
+
+// recreate replaces old with a container of the same name and settings
+// running image, and waits for it to become healthy. The old container is
+// kept, renamed and stopped, until the new one is verified.
+func (c *DockerClient) recreate(ctx context.Context, old *containerInspect, image string, report UpgradeReporter) error {
+	name := strings.TrimPrefix(old.Name, "/")
+	backup := name + "-forge-old"
+
+	config, err := c.upgradeConfig(ctx, old, image)
+	if err != nil {
+		return err
+	}
+
+	if err := c.post(ctx, "/containers/"+old.ID+"/rename?name="+url.QueryEscape(backup), nil); err != nil {
+		return fmt.Errorf("rename old container: %w", err)
+	}
+	if err := c.post(ctx, fmt.Sprintf("/containers/%s/stop?t=%d", old.ID, upgradeStopTimeout), nil); err != nil {
+		return fmt.Errorf("stop old container: %w", err)
+	}
+
+	// Attach to one network at create time and the rest afterwards, which
+	// every API version supports
+	networks := make([]string, 0, len(old.NetworkSettings.Networks))
+	for n := range old.NetworkSettings.Networks {
+		networks = append(networks, n)
+	}
+	sort.Strings(networks)
+	endpoint := func(n string) map[string]any {
+		ep := old.NetworkSettings.Networks[n]
+		settings := map[string]any{"Aliases": ep.Aliases, "Links": ep.Links}
+		if len(ep.IPAMConfig) > 0 && string(ep.IPAMConfig) != "null" {
+			settings["IPAMConfig"] = ep.IPAMConfig
+		}
+		return settings
+	}
+
+	body := make(map[string]any, len(config)+2)
+	for k, v := range config {
+		body[k] = v
+	}
+	body["HostConfig"] = old.HostConfig
+	if len(networks) > 0 {
+		body["NetworkingConfig"] = map[string]any{"EndpointsConfig": map[string]any{networks[0]: endpoint(networks[0])}}
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.postJSON(ctx, "/containers/create?name="+url.QueryEscape(name), body, &created); err != nil {
+		return fmt.Errorf("create container: %w", err)
+	}
+	for _, n := range networks[min(1, len(networks)):] {
+		if err := c.postJSON(ctx, "/networks/"+url.PathEscape(n)+"/connect", map[string]any{"Container": created.ID, "EndpointConfig": endpoint(n)}, nil); err != nil {
+			return fmt.Errorf("connect network %s: %w", n, err)
+		}
+	}
+	if err := c.post(ctx, "/containers/"+created.ID+"/start", nil); err != nil {
+		return fmt.Errorf("start container: %w", err)
+	}
+
+	if err := c.waitHealthy(ctx, created.ID, report); err != nil {
+		return err
+	}
+	if err := c.deleteContainer(ctx, old.ID); err != nil {
+		report.Logf("could not remove old container %s: %v", backup, err)
+	}
+	return nil
+}
+
+// restore removes a failed replacement and brings the old container back
+// under its name
+func (c *DockerClient) restore(ctx context.Context, old *containerInspect) error {
+	name := strings.TrimPrefix(old.Name, "/")
+
+	var current containerInspect
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(name)+"/json", &current); err == nil && current.ID != old.ID {
+		if err := c.deleteContainer(ctx, current.ID); err != nil {
+			return fmt.Errorf("remove new container: %w", err)
+		}
+	}
+	if err := c.post(ctx, "/containers/"+old.ID+"/rename?name="+url.QueryEscape(name), nil); err != nil {
+		return fmt.Errorf("rename old container: %w", err)
+	}
+	if err := c.post(ctx, "/containers/"+old.ID+"/start", nil); err != nil {
+		return fmt.Errorf("start old container: %w", err)
+	}
+	return nil
+}
+
+// upgradeConfig returns the container config of old for image, dropping
+// values old inherited from its image so the new image's defaults apply
+func (c *DockerClient) upgradeConfig(ctx context.Context, old *containerInspect, image string) (map[string]any, error) {
+	var oldImage struct {
+		Config map[string]any `json:"Config"`
+	}
+	if err := c.getJSON(ctx, "/images/"+old.Image+"/json", &oldImage); err != nil {
+		return nil, fmt.Errorf("inspect old image: %w", err)
+	}
+
+	config := make(map[string]any, len(old.Config))
+	for k, v := range old.Config {
+		config[k] = v
+	}
+	config["Image"] = image
+	if h, _ := config["Hostname"].(string); h != "" && strings.HasPrefix(old.ID, h) {
+		delete(config, "Hostname")
+	}
+
+	for _, key := range []string{"Cmd", "Entrypoint", "WorkingDir", "User", "Healthcheck", "StopSignal"} {
+		if v, ok := config[key]; ok && reflect.DeepEqual(v, oldImage.Config[key]) {
+			delete(config, key)
+		}
+	}
+	// Env is a list; labels, ports and volumes are sets
+	if env, ok := config["Env"].([]any); ok {
+		inherited := make(map[string]bool)
+		if imgEnv, ok := oldImage.Config["Env"].([]any); ok {
+			for _, e := range imgEnv {
+				inherited[fmt.Sprint(e)] = true
+			}
+		}
+		var kept []any
+		for _, e := range env {
+			if !inherited[fmt.Sprint(e)] {
+				kept = append(kept, e)
+			}
+		}
+		config["Env"] = kept
+	}
+	for _, key := range []string{"Labels", "ExposedPorts", "Volumes"} {
+		set, ok := config[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		imgSet, _ := oldImage.Config[key].(map[string]any)
+		kept := make(map[string]any, len(set))
+		for k, v := range set {
+			if iv, ok := imgSet[k]; !ok || !reflect.DeepEqual(iv, v) {
+				kept[k] = v
+			}
+		}
+		config[key] = kept
+	}
+	return config, nil
+}
+
+// waitHealthy waits until a started container passes its healthcheck, or
+// stays running for upgradeSettle when it has none
+func (c *DockerClient) waitHealthy(ctx context.Context, id string, report UpgradeReporter) error {
+	start := time.Now()
+	for {
+		var ct containerInspect
+		if err := c.getJSON(ctx, "/containers/"+id+"/json", &ct); err != nil {
+			return err
+		}
+		if !ct.State.Running {
+			return fmt.Errorf("container exited (%s)", ct.State.Status)
+		}
+		elapsed := time.Since(start)
+		switch {
+		case ct.State.Health == nil && elapsed >= upgradeSettle:
+			return nil
+		case ct.State.Health != nil && ct.State.Health.Status == "healthy":
+			return nil
+		case ct.State.Health != nil && ct.State.Health.Status == "unhealthy":
+			return fmt.Errorf("container is unhealthy")
+		case elapsed >= upgradeHealthTimeout:
+			return fmt.Errorf("container not healthy after %s", upgradeHealthTimeout)
+		}
+		if ct.State.Health != nil {
+			report.Logf("waiting for healthcheck (%s)", ct.State.Health.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// deleteContainer force-removes a container
+func (c *DockerClient) deleteContainer(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", "http://docker/containers/"+id+"?force=true", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrContainerNotFound
+	}
+	return dockerError(resp)
+}
+
+// post sends a Docker API POST without a body; 304 (already in that
+// state) counts as success
+func (c *DockerClient) post(ctx context.Context, path string, out any) error {
+	return c.postJSON(ctx, path, nil, out)
+}
+
+// postJSON sends a Docker API POST with a JSON body and decodes the response
+func (c *DockerClient) postJSON(ctx context.Context, path string, in, out any) error {
+	var body *bytes.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://docker"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Stopping waits for the grace period, beyond the default timeout
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent, http.StatusNotModified:
+	default:
+		return dockerError(resp)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
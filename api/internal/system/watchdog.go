@@ -0,0 +1,388 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultWatchdogInterval = 30 * time.Second
+	defaultWatchdogBackoff  = 10 * time.Second
+	defaultWatchdogMaxDelay = 5 * time.Minute
+	defaultWatchdogRestarts = 5
+	// watchdogStable is how long a restarted container must stay up for
+	// its failures to be forgotten
+	watchdogStable = 10 * time.Minute
+	// watchdogIncidents is how many incidents are kept
+	watchdogIncidents = 200
+)
+
+// Watchdog restart reasons
+const (
+	ReasonExited    = "exited"
+	ReasonUnhealthy = "unhealthy"
+)
+
+// Watchdog incident actions
+const (
+	ActionRestarted     = "restarted"
+	ActionRestartFailed = "restart_failed"
+	ActionGaveUp        = "gave_up" // max_restarts reached; restarts stop until the policy is saved again
+)
+
+// WatchdogPolicy configures the watchdog
+type WatchdogPolicy struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Interval between container checks, default 30s
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// UserContainers also watches the containers reported next to the
+	// stack (SYSTEM_CONTAINERS, SYSTEM_CONTAINER_LABELS)
+	UserContainers bool `json:"user_containers,omitempty" yaml:"user_containers,omitempty"`
+	// IgnoreUnhealthy only restarts exited containers
+	IgnoreUnhealthy bool `json:"ignore_unhealthy,omitempty" yaml:"ignore_unhealthy,omitempty"`
+	// MaxRestarts is how many restarts in a row are tried before giving
+	// up, default 5
+	MaxRestarts int `json:"max_restarts,omitempty" yaml:"max_restarts,omitempty"`
+	// Backoff is the delay before the second restart, doubling up to
+	// MaxBackoff; defaults 10s and 5m
+	Backoff    string `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	MaxBackoff string `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+	// Exclude lists container names never restarted
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// watchdogSettings is a validated policy
+type watchdogSettings struct {
+	interval, backoff, maxBackoff time.Duration
+	maxRestarts                   int
+	exclude                       map[string]bool
+}
+
+// settings validates the policy and fills in defaults
+func (p WatchdogPolicy) settings() (watchdogSettings, error) {
+	s := watchdogSettings{maxRestarts: p.MaxRestarts, exclude: make(map[string]bool)}
+	var err error
+	if s.interval, err = watchdogDuration("interval", p.Interval, defaultWatchdogInterval); err != nil {
+		return s, err
+	}
+	if s.backoff, err = watchdogDuration("backoff", p.Backoff, defaultWatchdogBackoff); err != nil {
+		return s, err
+	}
+	if s.maxBackoff, err = watchdogDuration("max_backoff", p.MaxBackoff, defaultWatchdogMaxDelay); err != nil {
+		return s, err
+	}
+	if s.interval < 5*time.Second {
+		return s, fmt.Errorf("interval must be at least 5s")
+	}
+	if s.maxBackoff < s.backoff {
+		return s, fmt.Errorf("max_backoff must not be less than backoff")
+	}
+	switch {
+	case s.maxRestarts < 0:
+		return s, fmt.Errorf("max_restarts must not be negative")
+	case s.maxRestarts == 0:
+		s.maxRestarts = defaultWatchdogRestarts
+	}
+	for _, name := range p.Exclude {
+		s.exclude[name] = true
+	}
+	return s, nil
+}
+
+func watchdogDuration(field, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", field, value)
+	}
+	return d, nil
+}
+
+// Incident is a failure the watchdog acted on
+type Incident struct {
+	Time      time.Time `json:"time"`
+	Container string    `json:"container"`
+	Reason    string    `json:"reason"` // exited or unhealthy
+	Action    string    `json:"action"` // restarted, restart_failed or gave_up
+	Attempt   int       `json:"attempt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Message describes the incident in one line
+func (i Incident) Message() string {
+	msg := fmt.Sprintf("%s %s (%s, attempt %d)", i.Container, strings.ReplaceAll(i.Action, "_", " "), i.Reason, i.Attempt)
+	if i.Error != "" {
+		msg += ": " + i.Error
+	}
+	return msg
+}
+
+// WatchedContainer is the restart state of a container that failed
+type WatchedContainer struct {
+	Name        string    `json:"name"`
+	Restarts    int       `json:"restarts"` // in a row
+	LastRestart time.Time `json:"last_restart"`
+	NextAttempt time.Time `json:"next_attempt"`
+	GaveUp      bool      `json:"gave_up"`
+}
+
+// WatchdogStatus is the policy with the current state
+type WatchdogStatus struct {
+	Policy     WatchdogPolicy     `json:"policy"`
+	Containers []WatchedContainer `json:"containers"`
+	Incidents  []Incident         `json:"incidents"` // newest first
+}
+
+// Watchdog restarts exited or unhealthy containers with backoff. A
+// container stopped through Docker (docker stop, compose down, an
+// upgrade) is left alone until it starts again, which needs the event
+// feed.
+type Watchdog struct {
+	docker     *DockerClient
+	events     *EventWatcher
+	users      ContainerFilter
+	configPath string
+
+	mu        sync.Mutex
+	policy    WatchdogPolicy
+	settings  watchdogSettings
+	state     map[string]*WatchedContainer
+	stopped   map[string]bool // stopped on purpose
+	seen      map[string]bool // seen running, so an exit is a failure
+	incidents []Incident      // oldest first
+	changed   chan struct{}
+	handlers  []func(Incident)
+}
+
+// NewWatchdog creates a watchdog whose policy is kept in configPath.
+// users selects the user containers watched with user_containers.
+func NewWatchdog(docker *DockerClient, events *EventWatcher, users ContainerFilter, configPath string) (*Watchdog, error) {
+	w := &Watchdog{
+		docker:     docker,
+		events:     events,
+		users:      users,
+		configPath: configPath,
+		state:      make(map[string]*WatchedContainer),
+		stopped:    make(map[string]bool),
+		seen:       make(map[string]bool),
+		changed:    make(chan struct{}, 1),
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &w.policy); err != nil {
+			return nil, fmt.Errorf("watchdog config: %w", err)
+		}
+	}
+	if w.settings, err = w.policy.settings(); err != nil {
+		return nil, fmt.Errorf("watchdog config: %w", err)
+	}
+	return w, nil
+}
+
+// Status returns the policy, the containers being restarted and recent
+// incidents
+func (w *Watchdog) Status() WatchdogStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := WatchdogStatus{Policy: w.policy, Containers: []WatchedContainer{}, Incidents: make([]Incident, 0, len(w.incidents))}
+	for _, c := range w.state {
+		s.Containers = append(s.Containers, *c)
+	}
+	sort.Slice(s.Containers, func(i, j int) bool { return s.Containers[i].Name < s.Containers[j].Name })
+	for i := len(w.incidents) - 1; i >= 0; i-- {
+		s.Incidents = append(s.Incidents, w.incidents[i])
+	}
+	return s
+}
+
+// SetPolicy validates, saves and applies a policy. Containers the
+// watchdog gave up on are tried again.
+func (w *Watchdog) SetPolicy(p WatchdogPolicy) error {
+	settings, err := p.settings()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(&p)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.configPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(w.configPath, data, 0644); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.policy, w.settings = p, settings
+	w.state = make(map[string]*WatchedContainer)
+	w.mu.Unlock()
+
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run checks containers until ctx is cancelled
+func (w *Watchdog) Run(ctx context.Context) {
+	var events <-chan ContainerEvent
+	if w.events != nil {
+		ch, unsubscribe := w.events.Subscribe()
+		defer unsubscribe()
+		events = ch
+	}
+
+	for {
+		w.mu.Lock()
+		enabled, interval := w.policy.Enabled, w.settings.interval
+		w.mu.Unlock()
+		if enabled {
+			w.check(ctx)
+		}
+
+		timer := time.NewTimer(interval)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-w.changed:
+				timer.Stop()
+				break wait
+			case <-timer.C:
+				break wait
+			case e := <-events:
+				w.mu.Lock()
+				switch e.Action {
+				case "stop":
+					w.stopped[e.Container] = true
+				case "start":
+					delete(w.stopped, e.Container)
+				}
+				w.mu.Unlock()
+			}
+		}
+	}
+}
+
+// check restarts failed containers that are due
+func (w *Watchdog) check(ctx context.Context) {
+	containers, err := w.docker.listContainers(ctx)
+	if err != nil {
+		logger.Error("Watchdog failed to list containers", err)
+		return
+	}
+
+	w.mu.Lock()
+	filter := ContainerFilter{}
+	if w.policy.UserContainers {
+		filter = w.users
+	}
+	ignoreUnhealthy := w.policy.IgnoreUnhealthy
+	w.mu.Unlock()
+
+	now := time.Now()
+	for _, ct := range containers {
+		listed := filteredContainerStats(ct, filter)
+		if listed == nil {
+			continue
+		}
+		name := listed.Name
+
+		w.mu.Lock()
+		// Containers already down when the watchdog started may have been
+		// stopped on purpose, so only exits after running are failures
+		reason := ""
+		switch {
+		case (ct.State == "exited" || ct.State == "dead") && w.seen[name]:
+			reason = ReasonExited
+		case ct.State == "running" && strings.Contains(ct.Status, "(unhealthy)") && !ignoreUnhealthy:
+			reason = ReasonUnhealthy
+		}
+		if ct.State == "running" {
+			w.seen[name] = true
+		}
+		st := w.state[name]
+		skip := w.settings.exclude[name] || w.stopped[name] || strings.HasSuffix(name, "-forge-old")
+		if reason == "" || skip {
+			// Forget failures once the container has stayed up
+			if st != nil && ct.State == "running" && reason == "" && now.Sub(st.LastRestart) > watchdogStable {
+				delete(w.state, name)
+			}
+			w.mu.Unlock()
+			continue
+		}
+		if st == nil {
+			st = &WatchedContainer{Name: name}
+			w.state[name] = st
+		}
+		if st.GaveUp || now.Before(st.NextAttempt) {
+			w.mu.Unlock()
+			continue
+		}
+		if st.Restarts >= w.settings.maxRestarts {
+			st.GaveUp = true
+			w.recordLocked(Incident{Time: now, Container: name, Reason: reason, Action: ActionGaveUp, Attempt: st.Restarts})
+			w.mu.Unlock()
+			continue
+		}
+		st.Restarts++
+		st.LastRestart = now
+		delay := w.settings.backoff << (st.Restarts - 1)
+		if delay <= 0 || delay > w.settings.maxBackoff {
+			delay = w.settings.maxBackoff
+		}
+		st.NextAttempt = now.Add(delay)
+		attempt := st.Restarts
+		w.mu.Unlock()
+
+		incident := Incident{Time: now, Container: name, Reason: reason, Action: ActionRestarted, Attempt: attempt}
+		result := "success"
+		if err := w.docker.post(ctx, "/containers/"+ct.ID+"/restart?t=10", nil); err != nil {
+			incident.Action = ActionRestartFailed
+			incident.Error = err.Error()
+			result = "failure"
+		}
+		metrics.WatchdogRestartsTotal.WithLabelValues(name, reason, result).Inc()
+
+		w.mu.Lock()
+		w.recordLocked(incident)
+		w.mu.Unlock()
+	}
+}
+
+// recordLocked keeps and logs an incident; the caller holds w.mu
+func (w *Watchdog) recordLocked(i Incident) {
+	w.incidents = append(w.incidents, i)
+	if len(w.incidents) > watchdogIncidents {
+		w.incidents = w.incidents[len(w.incidents)-watchdogIncidents:]
+	}
+	logger.Warn("Watchdog: " + i.Message())
+	for _, fn := range w.handlers {
+		fn(i)
+	}
+}
+
+// OnIncident calls fn for every incident. It must be called before Run;
+// fn runs while the watchdog's state is locked and must not block.
+func (w *Watchdog) OnIncident(fn func(Incident)) {
+	w.handlers = append(w.handlers, fn)
+}
@@ -0,0 +1,173 @@
+// Package targets holds what webhook and MQTT bridge targets share: the
+// sinks log and metric targets write to, ${selector} templates and label
+// checks
+package targets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/forge/api/internal/observe"
+)
+
+var (
+	labelPattern    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+	templatePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+	logLevels       = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+)
+
+// LogSink receives the lines of log targets; *observe.LokiClient
+// implements it
+type LogSink interface {
+	PushEntry(ctx context.Context, e observe.Entry) error
+}
+
+// MetricSink records the values of metric targets;
+// *observe.MetricsRegistry implements it
+type MetricSink interface {
+	Push(name, kind string, value float64, labels map[string]string) error
+}
+
+// Source is what selectors are looked up in, such as a hook request or an
+// MQTT message
+type Source interface {
+	Lookup(sel string) (string, bool)
+}
+
+// Selectors checks the selectors of a source; Hint lists them for errors
+type Selectors struct {
+	Valid func(sel string) bool
+	Hint  string // e.g. "header.<name>, body or body.<path>"
+}
+
+// DecodeJSON decodes data with numbers kept as written; nil when data is
+// not a single JSON value
+func DecodeJSON(data []byte) any {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) == nil && !dec.More() {
+		return v
+	}
+	return nil
+}
+
+// Path returns the value at p, dot-separated keys and array indexes, in v
+// as decoded by DecodeJSON. Strings are returned as they are, other values
+// as JSON.
+func Path(v any, p string) (string, bool) {
+	for _, key := range strings.Split(p, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return "", false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch leaf := v.(type) {
+	case string:
+		return leaf, true
+	case json.Number:
+		return leaf.String(), true
+	case bool:
+		return strconv.FormatBool(leaf), true
+	case nil:
+		return "", true
+	}
+	b, _ := json.Marshal(v)
+	return string(b), true
+}
+
+// ValidPath reports whether p is a usable path for Path
+func ValidPath(p string) bool {
+	return p != "" && !strings.Contains(p, "..") && !strings.HasSuffix(p, ".")
+}
+
+// Expand replaces the ${selector} templates in s; missing values are empty
+func Expand(src Source, s string) string {
+	return templatePattern.ReplaceAllStringFunc(s, func(t string) string {
+		v, _ := src.Lookup(t[2 : len(t)-1])
+		return v
+	})
+}
+
+// ExpandLabels returns base with the labels of a target added, their
+// templates expanded
+func ExpandLabels(src Source, labels, base map[string]string) map[string]string {
+	out := make(map[string]string, len(base)+len(labels))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range labels {
+		out[k] = Expand(src, v)
+	}
+	return out
+}
+
+// ValidateTemplates checks the ${selector} templates in s
+func (s Selectors) ValidateTemplates(text string) error {
+	for _, m := range templatePattern.FindAllStringSubmatch(text, -1) {
+		if !s.Valid(m[1]) {
+			return fmt.Errorf("invalid selector in template: %q (%s)", m[1], s.Hint)
+		}
+	}
+	return nil
+}
+
+// ValidateLabels checks label names and the templates in their values
+func (s Selectors) ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelPattern.MatchString(k) || strings.HasPrefix(k, "__") {
+			return fmt.Errorf("invalid label name: %q", k)
+		}
+		if err := s.ValidateTemplates(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateLog checks the level, defaulting to info, and message of a log
+// target, and that it sets none of the labels Forge sets
+func (s Selectors) ValidateLog(level *string, message string, labels map[string]string, reserved ...string) error {
+	if *level == "" {
+		*level = "info"
+	}
+	if !logLevels[*level] {
+		return fmt.Errorf("level must be debug, info, warn or error")
+	}
+	if err := s.ValidateTemplates(message); err != nil {
+		return err
+	}
+	for _, k := range reserved {
+		if _, ok := labels[k]; ok {
+			return fmt.Errorf("label %s is set by Forge", k)
+		}
+	}
+	return nil
+}
+
+// ValidateMetric checks the name of a metric target of kind and its label
+// names
+func ValidateMetric(name, kind string, labels map[string]string) error {
+	names := make(map[string]string, len(labels))
+	for k := range labels {
+		names[k] = ""
+	}
+	return observe.ValidateMetric(name, kind, names)
+}
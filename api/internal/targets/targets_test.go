@@ -0,0 +1,94 @@
+package targets
+
+import (
+	"strings"
+	"testing"
+)
+
+// mapSource looks selectors up in a map
+type mapSource map[string]string
+
+func (m mapSource) Lookup(sel string) (string, bool) {
+	v, ok := m[sel]
+	return v, ok
+}
+
+func TestPath(t *testing.T) {
+	v := DecodeJSON([]byte(`{"repo":{"name":"forge","stars":12,"private":false},"tags":["a","b"],"none":null}`))
+	if v == nil {
+		t.Fatal("DecodeJSON() = nil for a JSON object")
+	}
+
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"repo.name", "forge", true},
+		{"repo.stars", "12", true},
+		{"repo.private", "false", true},
+		{"tags.1", "b", true},
+		{"tags", `["a","b"]`, true},
+		{"none", "", true},
+		{"repo.missing", "", false},
+		{"tags.2", "", false},
+		{"tags.x", "", false},
+		{"repo.name.first", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := Path(v, tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Path(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if DecodeJSON([]byte("not json")) != nil || DecodeJSON([]byte(`{} {}`)) != nil {
+		t.Error("DecodeJSON() decoded something that is not a single JSON value")
+	}
+}
+
+func TestExpandLabels(t *testing.T) {
+	src := mapSource{"topic": "home/kitchen", "payload.temp": "21"}
+	got := ExpandLabels(src, map[string]string{"room": "${topic}", "temp": "${payload.temp}C", "gone": "${payload.x}"}, map[string]string{"source": "mqtt"})
+	want := map[string]string{"source": "mqtt", "room": "home/kitchen", "temp": "21C", "gone": ""}
+	if len(got) != len(want) {
+		t.Fatalf("ExpandLabels() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("ExpandLabels()[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestSelectorsValidate(t *testing.T) {
+	sel := Selectors{Valid: func(s string) bool { return s == "body" }, Hint: "body"}
+
+	if err := sel.ValidateLabels(map[string]string{"event": "${body}"}); err != nil {
+		t.Errorf("ValidateLabels() error = %v", err)
+	}
+	for _, labels := range []map[string]string{
+		{"__name": "x"},
+		{"1st": "x"},
+		{"event": "${header.X}"},
+	} {
+		if err := sel.ValidateLabels(labels); err == nil {
+			t.Errorf("ValidateLabels(%v) succeeded, want an error", labels)
+		}
+	}
+
+	level := ""
+	if err := sel.ValidateLog(&level, "got ${body}", nil, "job"); err != nil || level != "info" {
+		t.Errorf("ValidateLog() = %v with level %q, want nil and info", err, level)
+	}
+	level = "trace"
+	if err := sel.ValidateLog(&level, "", nil); err == nil {
+		t.Error("ValidateLog() accepted level trace")
+	}
+	level = "info"
+	if err := sel.ValidateLog(&level, "", map[string]string{"job": "x"}, "job"); err == nil || !strings.Contains(err.Error(), "set by Forge") {
+		t.Errorf("ValidateLog() error = %v, want a reserved label error", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package tracing instruments the Forge API with OpenTelemetry and exports
+// spans to the bundled Tempo over OTLP/HTTP.
+//
+// Until Init is called the global no-op tracer provider is used, so Start
+// is always safe to call.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/forge/api/internal/config"
+	"github.com/forge/api/internal/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/forge/api"
+
+// Init configures the global tracer provider. Spans are sent to
+// cfg.TempoURL (OTLP/HTTP) unless cfg.Tracing is off. Sampling follows
+// the standard OTEL_TRACES_SAMPLER variables.
+// The returned function flushes and stops the exporter.
+func Init(ctx context.Context, cfg config.Observe, serviceName, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Tracing {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(cfg.TempoURL, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	)
+
+	// Export failures (e.g. Tempo not running) are expected without the
+	// observability profile; keep them out of the default stderr logger
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log := logger.Get()
+		log.Debug().Err(err).Msg("Trace export failed")
+	}))
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartKind is Start with an explicit span kind (server, client, ...)
+func StartKind(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End records err (if any) on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,82 @@
+// Package version reports the API's build identity and enabled features
+//
+// Version, Commit and BuildDate are set at build time:
+//
+//	go build -ldflags "-X github.com/forge/api/internal/version.Version=1.2.0 \
+//	  -X github.com/forge/api/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/forge/api/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them, the commit comes from the VCS stamp Go embeds when building
+// inside a checkout, and the version is "dev".
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// Set via -ldflags -X
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info is the build and runtime identity of the running API
+type Info struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	BuildDate string   `json:"build_date,omitempty"`
+	Modified  bool     `json:"modified,omitempty"` // built from a checkout with uncommitted changes
+	GoVersion string   `json:"go_version"`
+	OS        string   `json:"os"`
+	Arch      string   `json:"arch"`
+	Features  []string `json:"features"`
+}
+
+var (
+	mu       sync.RWMutex
+	features = map[string]bool{}
+)
+
+// Enable records that an optional feature is on, e.g. "tls" or "oidc"
+func Enable(feature string) {
+	mu.Lock()
+	defer mu.Unlock()
+	features[feature] = true
+}
+
+// Get returns the build identity and the features enabled so far
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Features:  []string{},
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	mu.RLock()
+	for f := range features {
+		info.Features = append(info.Features, f)
+	}
+	mu.RUnlock()
+	sort.Strings(info.Features)
+	return info
+}
@@ -0,0 +1,190 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/forge/api/internal/events"
+	"github.com/forge/api/internal/logger"
+	"github.com/forge/api/internal/metrics"
+)
+
+const (
+	attemptTimeout = 10 * time.Second
+	maxConcurrent  = 8
+	dispatchTick   = time.Second
+	// maxResponse bounds the response body kept per delivery
+	maxResponse = 1024
+)
+
+// retryDelays are the waits before each retry; a delivery gets
+// len(retryDelays)+1 attempts over about 3 hours
+var retryDelays = []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// Delivery metric results
+const (
+	resultDelivered = "delivered"
+	resultRetry     = "retry"
+	resultFailed    = "failed"
+	resultDropped   = "dropped" // evicted from the delivery log while pending
+)
+
+var deliveryResults = []string{resultDelivered, resultRetry, resultFailed, resultDropped}
+
+// enqueue adds a delivery of e to a webhook; m.mu must be held
+func (m *Manager) enqueue(name string, e events.Event) *Delivery {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		payload, _ = json.Marshal(events.Event{ID: e.ID, Type: e.Type, Time: e.Time, Data: map[string]string{"error": err.Error()}})
+	}
+	return m.add(name, e.ID, e.Type, payload)
+}
+
+// add appends a pending delivery to the log of a webhook, evicting the
+// oldest beyond maxDeliveries; m.mu must be held
+func (m *Manager) add(name, eventID, eventType string, payload []byte) *Delivery {
+	d := &Delivery{
+		ID:          newID("dlv_"),
+		Webhook:     name,
+		EventID:     eventID,
+		Event:       eventType,
+		Created:     time.Now().UTC(),
+		Status:      StatusPending,
+		NextAttempt: time.Now().UTC(),
+		payload:     payload,
+	}
+	list := append(m.deliveries[name], d)
+	if over := len(list) - maxDeliveries; over > 0 {
+		for _, old := range list[:over] {
+			if old.Status == StatusPending {
+				metrics.WebhookDeliveriesTotal.WithLabelValues(name, resultDropped).Inc()
+				logger.Warn(fmt.Sprintf("Webhook %s: dropped pending delivery %s of %s", name, old.ID, old.Event))
+			}
+		}
+		list = list[over:]
+	}
+	m.deliveries[name] = list
+	return d
+}
+
+// Run sends due deliveries until ctx is cancelled. Pending deliveries are
+// kept in memory only, so those not sent before a restart are lost.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatchTick)
+	defer ticker.Stop()
+
+	for {
+		m.sendDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue starts an attempt for every pending delivery whose time has come
+func (m *Manager) sendDue(ctx context.Context, now time.Time) {
+	type due struct {
+		hook Webhook
+		d    *Delivery
+	}
+	var batch []due
+
+	m.mu.Lock()
+	for name, list := range m.deliveries {
+		hook := m.webhooks[name]
+		for _, d := range list {
+			if d.Status == StatusPending && !d.inFlight && !now.Before(d.NextAttempt) {
+				d.inFlight = true
+				batch = append(batch, due{hook, d})
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, b := range batch {
+		select {
+		case m.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		go func(hook Webhook, d *Delivery) {
+			defer func() { <-m.sem }()
+			m.attempt(ctx, hook, d)
+		}(b.hook, b.d)
+	}
+}
+
+// attempt sends a delivery once and schedules a retry if it fails
+func (m *Manager) attempt(ctx context.Context, hook Webhook, d *Delivery) {
+	start := time.Now()
+	status, response, err := m.send(ctx, hook, d)
+	elapsed := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d.inFlight = false
+	d.Attempts++
+	d.LastAttempt = start.UTC()
+	d.DurationMs = float64(elapsed.Microseconds()) / 1000
+	d.StatusCode = status
+	d.Response = response
+	d.Error = ""
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("status %d", status)
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	result := resultDelivered
+	switch {
+	case err == nil:
+		d.Status = StatusDelivered
+		d.NextAttempt = time.Time{}
+	case d.Attempts > len(retryDelays):
+		d.Status = StatusFailed
+		d.NextAttempt = time.Time{}
+		result = resultFailed
+		logger.Warn(fmt.Sprintf("Webhook %s: giving up on delivery %s of %s after %d attempts: %s", hook.Name, d.ID, d.Event, d.Attempts, d.Error))
+	default:
+		d.NextAttempt = time.Now().UTC().Add(retryDelays[d.Attempts-1])
+		result = resultRetry
+	}
+	if _, ok := m.webhooks[hook.Name]; ok {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(hook.Name, result).Inc()
+	}
+}
+
+// send posts the payload of a delivery, signed with the webhook's secret
+func (m *Manager) send(ctx context.Context, hook Webhook, d *Delivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", hook.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, "", err
+	}
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Forge-Webhooks/1.0")
+	req.Header.Set("X-Forge-Event", d.Event)
+	req.Header.Set("X-Forge-Delivery", d.ID)
+	req.Header.Set("X-Forge-Signature", Sign(hook.Secret, time.Now(), d.payload))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, string(body), nil
+}
@@ -0,0 +1,410 @@
+// Package webhooks delivers events from the internal event bus to
+// user-registered HTTP endpoints
+//
+// Each delivery is a JSON POST of the event, signed with the webhook's
+// secret in the X-Forge-Signature header:
+//
+//	X-Forge-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// Receivers recompute the HMAC over the timestamp and raw body and reject
+// stale timestamps. Deliveries that fail (a network error or a status
+// outside 2xx) are retried with backoff; the recent deliveries of each
+// webhook are kept in memory for inspection and redelivery.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forge/api/internal/events"
+	"github.com/forge/api/internal/metrics"
+	"github.com/forge/api/internal/resources"
+	"gopkg.in/yaml.v3"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending" // waiting for its first or next attempt
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed" // out of attempts
+)
+
+const (
+	maxDeliveries = 100
+	maxEvents     = 20
+	maxHeaders    = 20
+	secretPrefix  = "whsec_"
+)
+
+var (
+	// ErrInvalidWebhook is returned for webhooks that cannot be stored
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookNotFound is returned for unknown webhook names
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned for unknown delivery IDs
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	// reservedHeaders are set by Forge on every delivery
+	reservedHeaders = map[string]bool{
+		"Content-Type": true, "Content-Length": true, "Host": true, "User-Agent": true,
+		"X-Forge-Event": true, "X-Forge-Delivery": true, "X-Forge-Signature": true,
+	}
+)
+
+// Webhook is a subscription of an HTTP endpoint to events
+type Webhook struct {
+	Name     string            `json:"name" yaml:"name"`
+	URL      string            `json:"url" yaml:"url"`
+	Events   []string          `json:"events" yaml:"events"` // event types, families such as "container.*", or "*"
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Disabled bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Secret signs deliveries; it is only returned when a webhook is created
+	Secret string `json:"-" yaml:"secret"`
+}
+
+// Delivery is one event sent, or being sent, to a webhook
+type Delivery struct {
+	ID          string    `json:"id"`
+	Webhook     string    `json:"webhook"`
+	EventID     string    `json:"event_id"`
+	Event       string    `json:"event"` // event type
+	Created     time.Time `json:"created"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+	StatusCode  int       `json:"status_code,omitempty"` // of the last attempt
+	DurationMs  float64   `json:"duration_ms,omitempty"` // of the last attempt
+	Response    string    `json:"response,omitempty"`    // start of the last response body
+	Error       string    `json:"error,omitempty"`
+
+	payload  []byte
+	inFlight bool
+}
+
+// Status is a webhook with its delivery state
+type Status struct {
+	Webhook
+	Pending      int       `json:"pending"`
+	LastDelivery *Delivery `json:"last_delivery,omitempty"`
+}
+
+type webhooksFile struct {
+	Webhooks []Webhook `yaml:"webhooks"`
+}
+
+// Manager stores webhooks and delivers events to them
+type Manager struct {
+	mu         sync.RWMutex
+	webhooks   map[string]Webhook
+	deliveries map[string][]*Delivery // by webhook, oldest first
+	configPath string
+	client     *http.Client
+	sem        chan struct{} // bounds concurrent attempts
+}
+
+// NewManager loads webhooks from configPath
+func NewManager(configPath string) (*Manager, error) {
+	m := &Manager{
+		webhooks:   make(map[string]Webhook),
+		deliveries: make(map[string][]*Delivery),
+		configPath: configPath,
+		client: &http.Client{
+			Timeout: attemptTimeout,
+			// A redirect is an answer; the receiver should be registered at
+			// its final URL
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		sem: make(chan struct{}, maxConcurrent),
+	}
+	if err := m.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns all webhooks with their delivery state, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Status, 0, len(m.webhooks))
+	for name := range m.webhooks {
+		list = append(list, m.status(name))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a webhook with its delivery state
+func (m *Manager) Get(name string) (Status, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.webhooks[name]; !ok {
+		return Status{}, ErrWebhookNotFound
+	}
+	return m.status(name), nil
+}
+
+// status builds a Status; m.mu must be held
+func (m *Manager) status(name string) Status {
+	st := Status{Webhook: m.webhooks[name]}
+	list := m.deliveries[name]
+	for _, d := range list {
+		if d.Status == StatusPending {
+			st.Pending++
+		}
+	}
+	if n := len(list); n > 0 {
+		last := *list[n-1]
+		st.LastDelivery = &last
+	}
+	return st
+}
+
+// Deliveries returns the recent deliveries of a webhook, newest first
+func (m *Manager) Deliveries(name string) ([]Delivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.webhooks[name]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+	list := m.deliveries[name]
+	out := make([]Delivery, len(list))
+	for i, d := range list {
+		out[len(list)-1-i] = *d
+	}
+	return out, nil
+}
+
+// Save creates or replaces a webhook. A webhook saved without a secret
+// keeps its current one, or gets a generated one when new. It returns the
+// secret and whether the webhook was created.
+func (m *Manager) Save(hook Webhook) (string, bool, error) {
+	if err := validateWebhook(&hook); err != nil {
+		return "", false, err
+	}
+
+	m.mu.Lock()
+	old, exists := m.webhooks[hook.Name]
+	if hook.Secret == "" {
+		hook.Secret = old.Secret
+	}
+	if hook.Secret == "" {
+		hook.Secret = newSecret()
+	}
+	m.webhooks[hook.Name] = hook
+	m.mu.Unlock()
+
+	return hook.Secret, !exists, m.save()
+}
+
+// SetDisabled stops or resumes sending new events to a webhook.
+// Deliveries already queued are still attempted.
+func (m *Manager) SetDisabled(name string, disabled bool) (Status, error) {
+	m.mu.Lock()
+	hook, ok := m.webhooks[name]
+	if !ok {
+		m.mu.Unlock()
+		return Status{}, ErrWebhookNotFound
+	}
+	hook.Disabled = disabled
+	m.webhooks[name] = hook
+	st := m.status(name)
+	m.mu.Unlock()
+	return st, m.save()
+}
+
+// RotateSecret replaces the secret of a webhook with a generated one and
+// returns it
+func (m *Manager) RotateSecret(name string) (string, error) {
+	m.mu.Lock()
+	hook, ok := m.webhooks[name]
+	if !ok {
+		m.mu.Unlock()
+		return "", ErrWebhookNotFound
+	}
+	hook.Secret = newSecret()
+	m.webhooks[name] = hook
+	m.mu.Unlock()
+	return hook.Secret, m.save()
+}
+
+// Remove deletes a webhook and drops its pending deliveries and metrics
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	if _, ok := m.webhooks[name]; !ok {
+		m.mu.Unlock()
+		return ErrWebhookNotFound
+	}
+	delete(m.webhooks, name)
+	delete(m.deliveries, name)
+	m.mu.Unlock()
+
+	for _, result := range deliveryResults {
+		metrics.WebhookDeliveriesTotal.DeleteLabelValues(name, result)
+	}
+	return m.save()
+}
+
+// Dispatch queues e for every enabled webhook subscribed to its type. It
+// does not block, so it can be registered on the event bus.
+func (m *Manager) Dispatch(e events.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, hook := range m.webhooks {
+		if !hook.Disabled && subscribed(hook, e.Type) {
+			m.enqueue(name, e)
+		}
+	}
+}
+
+// Test queues a webhook.test event for a webhook, even a disabled one
+func (m *Manager) Test(name string) (Delivery, error) {
+	e := events.New(events.WebhookTest, map[string]any{"webhook": name, "message": "Test delivery from Forge"})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[name]; !ok {
+		return Delivery{}, ErrWebhookNotFound
+	}
+	return *m.enqueue(name, e), nil
+}
+
+// Redeliver queues the event of a past delivery again, as a new delivery
+func (m *Manager) Redeliver(name, id string) (Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[name]; !ok {
+		return Delivery{}, ErrWebhookNotFound
+	}
+	for _, d := range m.deliveries[name] {
+		if d.ID == id {
+			again := m.add(name, d.EventID, d.Event, d.payload)
+			return *again, nil
+		}
+	}
+	return Delivery{}, ErrDeliveryNotFound
+}
+
+// subscribed reports whether hook wants events of type typ
+func subscribed(hook Webhook, typ string) bool {
+	for _, p := range hook.Events {
+		if events.Matches(p, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWebhook checks a webhook
+func validateWebhook(hook *Webhook) error {
+	if !namePattern.MatchString(hook.Name) {
+		return fmt.Errorf("%w: name must be 1-64 characters of letters, digits, '_' or '-'", ErrInvalidWebhook)
+	}
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidWebhook)
+	}
+	if len(hook.Events) == 0 || len(hook.Events) > maxEvents {
+		return fmt.Errorf("%w: 1-%d events are required (types such as %s, families such as container.*, or *)", ErrInvalidWebhook, maxEvents, events.RouteChanged)
+	}
+	for _, p := range hook.Events {
+		if !events.ValidPattern(p) {
+			return fmt.Errorf("%w: unknown event %q (one of %s, a family such as container.*, or *)", ErrInvalidWebhook, p, strings.Join(events.Types, ", "))
+		}
+	}
+	if len(hook.Headers) > maxHeaders {
+		return fmt.Errorf("%w: too many headers (max %d)", ErrInvalidWebhook, maxHeaders)
+	}
+	for k := range hook.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(k)] {
+			return fmt.Errorf("%w: header %s is set by Forge", ErrInvalidWebhook, k)
+		}
+	}
+	if hook.Secret != "" && len(hook.Secret) < 16 {
+		return fmt.Errorf("%w: secret must be at least 16 characters", ErrInvalidWebhook)
+	}
+	if err := resources.ValidateLabels(hook.Labels); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	return nil
+}
+
+// Sign returns the X-Forge-Signature value of body sent at t
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := fmt.Sprint(t.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return secretPrefix + hex.EncodeToString(b)
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// load reads webhooks from the config file
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return err
+	}
+
+	var f webhooksFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, hook := range f.Webhooks {
+		m.webhooks[hook.Name] = hook
+	}
+	return nil
+}
+
+// save writes webhooks to the config file, readable only by Forge as it
+// holds their secrets
+func (m *Manager) save() error {
+	m.mu.RLock()
+	f := webhooksFile{Webhooks: make([]Webhook, 0, len(m.webhooks))}
+	for _, hook := range m.webhooks {
+		f.Webhooks = append(f.Webhooks, hook)
+	}
+	m.mu.RUnlock()
+	sort.Slice(f.Webhooks, func(i, j int) bool { return f.Webhooks[i].Name < f.Webhooks[j].Name })
+
+	data, err := yaml.Marshal(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0600)
+}
@@ -13,12 +13,24 @@ except PackageNotFoundError:
 from .client import Forge
 from .db import DatabaseClient
 from .cache import CacheClient
+from .storage import StorageClient
+from .files import FilesClient
+from .notify import NotifyClient
+from .messaging import MessagingClient
+from .mqtt import MQTTClient
+from .services import ServicesClient
 from .observe import LogsClient, MetricsClient, TracesClient
 
 __all__ = [
     "Forge",
     "DatabaseClient",
     "CacheClient",
+    "StorageClient",
+    "FilesClient",
+    "NotifyClient",
+    "MessagingClient",
+    "MQTTClient",
+    "ServicesClient",
     "LogsClient",
     "MetricsClient",
     "TracesClient",
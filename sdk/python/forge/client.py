@@ -2,6 +2,9 @@
 Main Forge client
 """
 
+import time
+import uuid
+
 import requests
 from typing import Optional, Dict, Any
 
@@ -9,6 +12,12 @@ from importlib.metadata import version, PackageNotFoundError
 
 from .db import DatabaseClient
 from .cache import CacheClient
+from .storage import StorageClient
+from .files import FilesClient
+from .notify import NotifyClient
+from .messaging import MessagingClient
+from .mqtt import MQTTClient
+from .services import ServicesClient
 from .observe import LogsClient, MetricsClient, TracesClient
 
 
@@ -35,18 +44,52 @@ class Forge:
         f.cache.set("key", "value")
         value = f.cache.get("key")
         
+        # Object storage
+        f.storage.put("key", b"data")
+        s3 = f.storage.client()  # boto3 client
+        
+        # Files on the local volume
+        f.files.upload("reports/q1.pdf", data)
+        
+        # Notifications
+        f.notify.send("ops", "Deploy finished")
+        
+        # Messaging
+        f.messaging.publish("orders.created", b"{}")
+        for msg in f.messaging.subscribe("orders.>"): ...
+        
+        # MQTT
+        f.mqtt.publish("home/living/light/set", "ON")
+        
+        # Service registry
+        f.services.register("orders", "http://orders-1:8000", health_url="/healthz")
+        f.services.lookup("payments", passing=True)
+        
         # Observability
         f.logs.info("User logged in", user_id=123)
         f.metrics.increment("requests_total")
     """
     
-    def __init__(self, host: str = "localhost", port: int = 80):
+    # Answers worth retrying: the server or a proxy in front was unavailable
+    RETRY_STATUSES = (502, 503, 504)
+
+    def __init__(
+        self,
+        host: str = "localhost",
+        port: int = 80,
+        api_key: Optional[str] = None,
+        retries: int = 2,
+    ):
         """
         Initialize Forge client.
         
         Args:
             host: Forge server hostname (default: localhost)
             port: Forge server port (default: 80)
+            api_key: Forge API key, needed once the server enforces keys
+            retries: How often writes are retried after connection errors
+                or 502/503/504 answers; they carry an Idempotency-Key, so
+                a retry never applies a write twice
         """
         # Handle host with path (e.g., "myserver.com/forge")
         if "/" in host:
@@ -57,22 +100,64 @@ class Forge:
             else:
                 self.base_url = f"http://{host}:{port}"
         
+        self.retries = retries
         self._session = requests.Session()
+        if api_key:
+            self._session.headers["Authorization"] = f"Bearer {api_key}"
         self._info_cache: Optional[Dict[str, Any]] = None
         
         # Initialize sub-clients
         self.db = DatabaseClient(self)
         self.cache = CacheClient(self)
+        self.storage = StorageClient(self)
+        self.files = FilesClient(self)
+        self.notify = NotifyClient(self)
+        self.messaging = MessagingClient(self)
+        self.mqtt = MQTTClient(self)
+        self.services = ServicesClient(self)
         self.logs = LogsClient(self)
         self.metrics = MetricsClient(self)
         self.traces = TracesClient(self)
     
-    def _request(self, method: str, path: str, **kwargs) -> requests.Response:
-        """Make HTTP request to Forge API."""
+    def _request(
+        self,
+        method: str,
+        path: str,
+        idempotency_key: Optional[str] = None,
+        **kwargs,
+    ) -> requests.Response:
+        """
+        Make HTTP request to Forge API.
+
+        Requests with an idempotency key are retried with the same key; the
+        server replays the first response instead of running them again.
+        """
         url = f"{self.base_url}/api/v1{path}"
-        response = self._session.request(method, url, **kwargs)
-        response.raise_for_status()
-        return response
+        attempts = 1
+        if idempotency_key:
+            headers = dict(kwargs.pop("headers", None) or {})
+            headers["Idempotency-Key"] = idempotency_key
+            kwargs["headers"] = headers
+            attempts += max(self.retries, 0)
+
+        for attempt in range(attempts):
+            last = attempt == attempts - 1
+            try:
+                response = self._session.request(method, url, **kwargs)
+            except (requests.ConnectionError, requests.Timeout):
+                if last:
+                    raise
+            else:
+                if response.status_code not in self.RETRY_STATUSES or last:
+                    response.raise_for_status()
+                    return response
+            time.sleep(0.5 * 2 ** attempt)
+        raise AssertionError("unreachable")
+
+    @staticmethod
+    def new_idempotency_key() -> str:
+        """Return a fresh key for a write that may be retried."""
+        return str(uuid.uuid4())
     
     def health(self) -> Dict[str, bool]:
         """
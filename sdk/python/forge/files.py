@@ -0,0 +1,121 @@
+"""
+Files client for Forge SDK
+"""
+
+import hashlib
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+from urllib.parse import quote
+
+if TYPE_CHECKING:
+    from .client import Forge
+
+
+class FilesClient:
+    """
+    Files client for uploads kept on Forge's local volume.
+
+    Usage:
+        f = Forge("localhost")
+
+        f.files.upload("reports/q1.pdf", data)
+        data = f.files.download("reports/q1.pdf")
+        head = f.files.download("videos/intro.mp4", start=0, end=1023)
+        paths = [x["path"] for x in f.files.list(prefix="reports/")]
+        f.files.delete("reports/q1.pdf")
+
+    Uploads send their SHA-256 so the API refuses corrupted ones. Sizes are
+    bounded per file and in total; see usage().
+    """
+
+    def __init__(self, forge: "Forge"):
+        self._forge = forge
+
+    def _path(self, path: str) -> str:
+        return f"/files/{quote(path.lstrip('/'), safe='/')}"
+
+    def upload(
+        self,
+        path: str,
+        data: bytes,
+        content_type: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        """
+        Store a file, replacing any at the same path.
+
+        Args:
+            path: File path, e.g. "reports/q1.pdf"
+            data: File contents
+            content_type: MIME type (default: detected from name and contents)
+
+        Returns:
+            The stored file's path, size, sha256, content_type and modified
+        """
+        headers = {"X-Checksum-Sha256": hashlib.sha256(data).hexdigest()}
+        if content_type:
+            headers["Content-Type"] = content_type
+        response = self._forge._request("PUT", self._path(path), data=data, headers=headers)
+        return response.json()
+
+    def download(
+        self,
+        path: str,
+        start: Optional[int] = None,
+        end: Optional[int] = None,
+    ) -> bytes:
+        """
+        Read a file, or the bytes from start to end (inclusive) of it.
+
+        Args:
+            path: File path
+            start: First byte to read (optional)
+            end: Last byte to read (optional, default: end of file)
+
+        Returns:
+            File contents
+        """
+        headers = None
+        if start is not None or end is not None:
+            first = "" if start is None else str(start)
+            last = "" if end is None else str(end)
+            headers = {"Range": f"bytes={first}-{last}"}
+        response = self._forge._request("GET", self._path(path), headers=headers)
+        return response.content
+
+    def list(self, prefix: str = "") -> List[Dict[str, Any]]:
+        """
+        List files.
+
+        Args:
+            prefix: Only paths starting with this
+
+        Returns:
+            Files with path, size, sha256, content_type and modified
+        """
+        response = self._forge._request("GET", "/files", params={"prefix": prefix})
+        return response.json().get("items", [])
+
+    def usage(self) -> Dict[str, Any]:
+        """
+        Get how much space files use.
+
+        Returns:
+            files, used_bytes, quota_bytes (0 = no quota) and max_file_bytes
+        """
+        response = self._forge._request("GET", "/files")
+        return response.json().get("usage", {})
+
+    def delete(self, path: str) -> bool:
+        """
+        Delete a file.
+
+        Args:
+            path: File path
+
+        Returns:
+            True if successful
+        """
+        response = self._forge._request("DELETE", self._path(path))
+        return response.json().get("ok", False)
+
+    def __repr__(self) -> str:
+        return "FilesClient()"
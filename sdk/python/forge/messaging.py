@@ -0,0 +1,121 @@
+"""
+Messaging client for Forge SDK
+"""
+
+import base64
+import json
+from typing import Any, Dict, Iterator, Optional, Union, TYPE_CHECKING
+from urllib.parse import quote
+
+if TYPE_CHECKING:
+    from .client import Forge
+
+
+class MessagingClient:
+    """
+    Messaging client for the message bus (NATS) behind Forge.
+
+    Usage:
+        f = Forge("localhost")
+
+        f.messaging.publish("orders.created", b'{"id": 42}')
+
+        for msg in f.messaging.subscribe("orders.>", queue="workers"):
+            print(msg["subject"], msg["data"])
+
+    Subscriptions in the same queue group share a subject's messages, each
+    going to one of them. For a native NATS client, info() returns the
+    server's address and an account.
+    """
+
+    def __init__(self, forge: "Forge"):
+        self._forge = forge
+
+    def publish(
+        self,
+        subject: str,
+        data: Union[bytes, str],
+        reply_to: Optional[str] = None,
+    ) -> bool:
+        """
+        Publish a message and wait for the bus to take it.
+
+        Args:
+            subject: Subject without wildcards, such as "orders.created"
+            data: Message body (up to 1MB)
+            reply_to: Subject for subscribers to reply on (optional)
+
+        Returns:
+            True if successful
+        """
+        if isinstance(data, str):
+            data = data.encode()
+        params = {"reply_to": reply_to} if reply_to else None
+        response = self._forge._request(
+            "POST",
+            f"/messaging/publish/{quote(subject, safe='.')}",
+            data=data,
+            params=params,
+            headers={"Content-Type": "application/octet-stream"},
+        )
+        return response.json().get("ok", False)
+
+    def subscribe(self, subject: str, queue: Optional[str] = None) -> Iterator[Dict[str, Any]]:
+        """
+        Receive messages on a subject until the iteration is stopped.
+
+        Args:
+            subject: Subject, which may hold the wildcards "*" (one token)
+                and ">" (the rest)
+            queue: Queue group to join (optional)
+
+        Yields:
+            Messages with subject, data (bytes), reply_to and received_at
+
+        Raises:
+            ConnectionError: When the server loses its connection to the bus
+        """
+        params = {"queue": queue} if queue else None
+        response = self._forge._request(
+            "GET",
+            f"/messaging/subscribe/{quote(subject, safe='.')}",
+            params=params,
+            stream=True,
+        )
+        try:
+            event, data = "message", []
+            for line in response.iter_lines(decode_unicode=True):
+                if line:
+                    field, _, value = line.partition(":")
+                    if field == "event":
+                        event = value.strip()
+                    elif field == "data":
+                        data.append(value.lstrip())
+                    continue
+                if data and event == "error":
+                    raise ConnectionError(json.loads("\n".join(data)).get("error"))
+                if data:
+                    msg = json.loads("\n".join(data))
+                    msg["data"] = base64.b64decode(msg.get("data") or "")
+                    yield msg
+                event, data = "message", []
+        finally:
+            response.close()
+
+    def info(self, access: Optional[str] = None) -> Dict[str, Any]:
+        """
+        Get connection details for a native NATS client.
+
+        Args:
+            access: "read" (subscribe only) or "write" (default: the most
+                the API key's role allows)
+
+        Returns:
+            Dict with url, user, password, access and max_payload
+        """
+        params = {"access": access} if access else None
+        response = self._forge._request("GET", "/messaging/info", params=params)
+        return response.json()
+
+    def __repr__(self) -> str:
+        return "MessagingClient()"
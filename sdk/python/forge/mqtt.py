@@ -0,0 +1,76 @@
+"""
+MQTT client for Forge SDK
+"""
+
+import json
+from typing import Any, Dict, Union, TYPE_CHECKING
+from urllib.parse import quote
+
+if TYPE_CHECKING:
+    from .client import Forge
+
+
+class MQTTClient:
+    """
+    MQTT client for the broker Forge bridges into its observability stack.
+
+    Usage:
+        f = Forge("localhost")
+
+        f.mqtt.publish("home/living/light/set", "ON")
+        f.mqtt.publish("home/sensors/config", {"interval": 30}, retain=True)
+
+    Messages arriving on the broker are forwarded to logs, metrics and
+    webhooks by bridges, set up by admins at /api/v1/mqtt/bridges.
+    """
+
+    def __init__(self, forge: "Forge"):
+        self._forge = forge
+
+    def publish(
+        self,
+        topic: str,
+        payload: Union[bytes, str, Dict[str, Any], list],
+        qos: int = 0,
+        retain: bool = False,
+    ) -> bool:
+        """
+        Publish a message to the broker.
+
+        Args:
+            topic: Topic without wildcards, such as "home/living/light/set"
+            payload: Message body (up to 1MB); dicts and lists are sent as JSON
+            qos: 0 (default) or 1, which waits for the broker to take it
+            retain: Have the broker keep the message for later subscribers
+
+        Returns:
+            True if successful
+        """
+        if isinstance(payload, (dict, list)):
+            payload = json.dumps(payload)
+        if isinstance(payload, str):
+            payload = payload.encode()
+        params = {"qos": qos}
+        if retain:
+            params["retain"] = "true"
+        response = self._forge._request(
+            "POST",
+            f"/mqtt/publish/{quote(topic, safe='/')}",
+            data=payload,
+            params=params,
+            headers={"Content-Type": "application/octet-stream"},
+        )
+        return response.json().get("ok", False)
+
+    def status(self) -> Dict[str, Any]:
+        """
+        Get the connection to the broker.
+
+        Returns:
+            Dict with url, client_id, connected and subscriptions
+        """
+        response = self._forge._request("GET", "/mqtt/status")
+        return response.json()
+
+    def __repr__(self) -> str:
+        return "MQTTClient()"
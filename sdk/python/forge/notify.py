@@ -0,0 +1,65 @@
+"""
+Notifications client for Forge SDK
+"""
+
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+
+if TYPE_CHECKING:
+    from .client import Forge
+
+
+class NotifyClient:
+    """
+    Notifications client for channels defined on the Forge server.
+
+    Usage:
+        f = Forge("localhost")
+
+        f.notify.send("ops", "Nightly import finished")
+        f.notify.send("ops", "Import failed: 3 rows rejected",
+                      title="Import", priority="high")
+
+    Channels (Slack, Discord, Telegram, Gotify, ntfy or a webhook) are set
+    up by an admin, so apps only need a channel's name.
+    """
+
+    def __init__(self, forge: "Forge"):
+        self._forge = forge
+
+    def send(
+        self,
+        channel: str,
+        text: str,
+        title: Optional[str] = None,
+        priority: str = "normal",
+    ) -> bool:
+        """
+        Send a message and wait for the channel's service to accept it.
+
+        Args:
+            channel: Channel name
+            text: Message text (up to 4000 characters)
+            title: Title (optional)
+            priority: "low", "normal" or "high"
+
+        Returns:
+            True if successful
+        """
+        body = {"channel": channel, "text": text, "priority": priority}
+        if title:
+            body["title"] = title
+        response = self._forge._request("POST", "/notify", json=body)
+        return response.json().get("ok", False)
+
+    def channels(self) -> List[Dict[str, Any]]:
+        """
+        List channels (needs an admin key).
+
+        Returns:
+            Channels with name, type, events and send counts
+        """
+        response = self._forge._request("GET", "/notify/channels")
+        return response.json().get("items", [])
+
+    def __repr__(self) -> str:
+        return "NotifyClient()"
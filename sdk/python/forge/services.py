@@ -0,0 +1,172 @@
+"""
+Service registry client for Forge SDK
+"""
+
+import threading
+from typing import Any, Callable, Dict, List, Optional, TYPE_CHECKING
+from urllib.parse import quote
+
+import requests
+
+if TYPE_CHECKING:
+    from .client import Forge
+
+
+class ServicesClient:
+    """
+    Service registry client: apps register themselves and look up others.
+
+    Usage:
+        f = Forge("localhost", api_key="...")
+
+        instance = f.services.register(
+            "orders", "http://orders-1:8000",
+            health_url="/healthz",
+            route={"path": "/orders/", "strip_prefix": True},
+            metrics_path="/metrics",
+        )
+        stop = f.services.keep_alive(instance)
+
+        for inst in f.services.lookup("payments", passing=True):
+            print(inst["address"])
+
+    Instances are dropped unless they send a heartbeat within their TTL
+    (default 30s); keep_alive() sends them from a background thread.
+    """
+
+    def __init__(self, forge: "Forge"):
+        self._forge = forge
+
+    def register(
+        self,
+        name: str,
+        address: str,
+        health_url: Optional[str] = None,
+        metadata: Optional[Dict[str, str]] = None,
+        ttl: Optional[str] = None,
+        route: Optional[Dict[str, Any]] = None,
+        metrics_path: Optional[str] = None,
+        id: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        """
+        Register an instance, or replace the one with the same name and id.
+
+        Args:
+            name: Service name
+            address: Base URL of the instance, such as "http://orders-1:8000"
+            health_url: Path relative to address, such as "/healthz", or a
+                URL on the same host; checked every 10s (optional)
+            metadata: Free-form string entries (optional)
+            ttl: Heartbeat deadline such as "30s" (default 30s)
+            route: Route named after the service, such as
+                {"path": "/orders/", "strip_prefix": True} (optional)
+            metrics_path: Path Prometheus scrapes, such as "/metrics" (optional)
+            id: Instance ID (default derived from the address)
+
+        Returns:
+            The registered instance, with its id, status and expiry
+        """
+        body: Dict[str, Any] = {"name": name, "address": address}
+        for key, value in (
+            ("id", id),
+            ("health_url", health_url),
+            ("metadata", metadata),
+            ("ttl", ttl),
+            ("route", route),
+            ("metrics_path", metrics_path),
+        ):
+            if value is not None:
+                body[key] = value
+        response = self._forge._request("POST", "/services", json=body)
+        return response.json()["instance"]
+
+    def heartbeat(self, name: str, id: str) -> bool:
+        """
+        Keep an instance for another TTL.
+
+        Raises:
+            requests.HTTPError: 404 when the instance expired; register again
+        """
+        response = self._forge._request(
+            "POST", f"/services/{quote(name, safe='')}/{quote(id, safe='')}/heartbeat"
+        )
+        return response.json().get("ok", False)
+
+    def deregister(self, name: str, id: str) -> bool:
+        """Remove an instance."""
+        response = self._forge._request(
+            "DELETE", f"/services/{quote(name, safe='')}/{quote(id, safe='')}"
+        )
+        return response.json().get("ok", False)
+
+    def lookup(self, name: str, passing: bool = False) -> List[Dict[str, Any]]:
+        """
+        Get the instances of a service.
+
+        Args:
+            name: Service name
+            passing: Only return instances whose health check passes
+
+        Returns:
+            Instances with address, metadata and status
+        """
+        params = {"passing": "true"} if passing else None
+        response = self._forge._request(
+            "GET", f"/services/{quote(name, safe='')}", params=params
+        )
+        return response.json().get("instances", [])
+
+    def list(self) -> List[Dict[str, Any]]:
+        """List registered services with their status and instances."""
+        response = self._forge._request("GET", "/services")
+        return response.json().get("items", [])
+
+    def keep_alive(
+        self,
+        instance: Dict[str, Any],
+        interval: float = 10.0,
+    ) -> Callable[[], None]:
+        """
+        Send heartbeats for an instance from a daemon thread, registering it
+        again if it expired.
+
+        Args:
+            instance: An instance returned by register()
+            interval: Seconds between heartbeats; keep it well under the TTL
+
+        Returns:
+            A function that stops the heartbeats and deregisters the instance
+        """
+        stopped = threading.Event()
+        registration = {
+            key: instance[key]
+            for key in ("name", "address", "health_url", "metadata", "ttl", "route", "metrics_path", "id")
+            if instance.get(key) is not None
+        }
+
+        def run() -> None:
+            while not stopped.wait(interval):
+                try:
+                    self.heartbeat(instance["name"], instance["id"])
+                except requests.HTTPError as e:
+                    if e.response is not None and e.response.status_code == 404:
+                        try:
+                            self.register(**registration)
+                        except requests.RequestException:
+                            pass
+                except requests.RequestException:
+                    pass
+
+        threading.Thread(target=run, name=f"forge-heartbeat-{instance['name']}", daemon=True).start()
+
+        def stop() -> None:
+            stopped.set()
+            try:
+                self.deregister(instance["name"], instance["id"])
+            except requests.RequestException:
+                pass
+
+        return stop
+
+    def __repr__(self) -> str:
+        return "ServicesClient()"
@@ -0,0 +1,206 @@
+"""
+Object storage client for Forge SDK
+"""
+
+import time
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+from urllib.parse import quote
+
+if TYPE_CHECKING:
+    from .client import Forge
+
+
+class StorageClient:
+    """
+    Object storage client (MinIO or any S3 endpoint behind Forge).
+
+    Usage:
+        f = Forge("localhost")
+
+        # Simple operations
+        f.storage.put("reports/q1.pdf", data, content_type="application/pdf")
+        data = f.storage.get("reports/q1.pdf")
+        keys = [o["key"] for o in f.storage.list(prefix="reports/")]
+        f.storage.delete("reports/q1.pdf")
+
+        # Large files go straight to the store
+        url = f.storage.presign("videos/intro.mp4", method="PUT")
+
+        # boto3 integration
+        s3 = f.storage.client()
+        s3 = f.storage.client(prefix="myapp/")
+
+    Calls use the bucket named by `bucket`, the server's default bucket
+    ("forge") unless changed. client() uses temporary keys issued by the
+    API, limited to a bucket and key prefix (read-only for keys with the
+    read role). New keys are requested once the previous ones are about
+    to expire.
+    """
+
+    def __init__(self, forge: "Forge", bucket: str = "forge"):
+        self._forge = forge
+        self.bucket = bucket
+        self._info_cache: Dict[str, Dict[str, Any]] = {}
+
+    def _object_path(self, key: str, bucket: Optional[str]) -> str:
+        return f"/storage/buckets/{bucket or self.bucket}/objects/{quote(key, safe='/')}"
+
+    def put(
+        self,
+        key: str,
+        data: bytes,
+        content_type: Optional[str] = None,
+        bucket: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        """
+        Store an object of up to 8MB; use presign() for larger ones.
+
+        Args:
+            key: Object key
+            data: Object contents
+            content_type: MIME type (default: application/octet-stream)
+            bucket: Bucket (default: self.bucket)
+
+        Returns:
+            The stored object's key, size and etag
+        """
+        headers = {"Content-Type": content_type} if content_type else None
+        response = self._forge._request(
+            "PUT", self._object_path(key, bucket), data=data, headers=headers
+        )
+        return response.json().get("object", {})
+
+    def get(self, key: str, bucket: Optional[str] = None) -> bytes:
+        """
+        Read an object.
+
+        Args:
+            key: Object key
+            bucket: Bucket (default: self.bucket)
+
+        Returns:
+            Object contents
+        """
+        response = self._forge._request("GET", self._object_path(key, bucket))
+        return response.content
+
+    def list(
+        self,
+        prefix: str = "",
+        bucket: Optional[str] = None,
+    ) -> List[Dict[str, Any]]:
+        """
+        List objects, following truncated listings.
+
+        Args:
+            prefix: Only keys starting with this
+            bucket: Bucket (default: self.bucket)
+
+        Returns:
+            Objects with key, size, etag and last_modified
+        """
+        objects: List[Dict[str, Any]] = []
+        params = {"prefix": prefix}
+        while True:
+            response = self._forge._request(
+                "GET", f"/storage/buckets/{bucket or self.bucket}/objects", params=params
+            )
+            data = response.json()
+            objects.extend(data.get("objects") or [])
+            if not data.get("truncated"):
+                return objects
+            params["continuation_token"] = data.get("next_continuation_token", "")
+
+    def delete(self, key: str, bucket: Optional[str] = None) -> bool:
+        """
+        Delete an object; deleting a missing one succeeds.
+
+        Args:
+            key: Object key
+            bucket: Bucket (default: self.bucket)
+
+        Returns:
+            True if successful
+        """
+        response = self._forge._request("DELETE", self._object_path(key, bucket))
+        return response.json().get("ok", False)
+
+    def presign(
+        self,
+        key: str,
+        method: str = "GET",
+        expires: int = 900,
+        bucket: Optional[str] = None,
+    ) -> str:
+        """
+        Get a URL that downloads (GET) or uploads (PUT) an object without
+        the API. Upload URLs need an API key with the write role.
+
+        Args:
+            key: Object key
+            method: "GET" or "PUT"
+            expires: Lifetime in seconds (max 7 days)
+            bucket: Bucket (default: self.bucket)
+
+        Returns:
+            Presigned URL
+        """
+        params = {
+            "bucket": bucket or self.bucket,
+            "key": key,
+            "method": method,
+            "expires": expires,
+        }
+        response = self._forge._request("GET", "/storage/presign", params=params)
+        return response.json()["url"]
+
+    def _get_info(self, bucket: Optional[str] = None, prefix: Optional[str] = None) -> Dict[str, Any]:
+        """Get storage keys from API, renewed before they expire."""
+        bucket = bucket or self.bucket
+        key = f"{bucket}/{prefix or ''}"
+        info = self._info_cache.get(key)
+        if info is None or info.get("expires_at", 0) - 60 < time.time():
+            params = {"bucket": bucket}
+            if prefix:
+                params["prefix"] = prefix
+            response = self._forge._request("GET", "/storage/info", params=params)
+            info = response.json()
+            self._info_cache[key] = info
+        return info
+
+    def client(self, bucket: Optional[str] = None, prefix: Optional[str] = None, **kwargs):
+        """
+        Get a boto3 S3 client.
+
+        Args:
+            bucket: Bucket the keys are limited to (default: self.bucket)
+            prefix: Key prefix the keys are limited to (optional)
+            **kwargs: Additional arguments passed to boto3.client
+
+        Returns:
+            boto3 S3 client instance
+        """
+        try:
+            import boto3
+            from botocore.config import Config
+        except ImportError:
+            raise ImportError(
+                "boto3 is required for client(). "
+                "Install it with: pip install boto3"
+            )
+
+        info = self._get_info(bucket, prefix)
+        addressing = "path" if info.get("path_style", True) else "virtual"
+        return boto3.client(
+            "s3",
+            endpoint_url=info.get("endpoint"),
+            region_name=info.get("region"),
+            aws_access_key_id=info.get("access_key_id"),
+            aws_secret_access_key=info.get("secret_access_key"),
+            aws_session_token=info.get("session_token") or None,
+            config=kwargs.pop("config", None) or Config(s3={"addressing_style": addressing}),
+            **kwargs
+        )
+
+    def __repr__(self) -> str:
+        return f"StorageClient(bucket={self.bucket!r})"
@@ -47,6 +47,8 @@ from forge import Forge
 # Configuration - can be overridden via environment variables
 FORGE_HOST = os.getenv("FORGE_HOST", "localhost")
 FORGE_PORT = int(os.getenv("FORGE_PORT", "80"))
+# Admin API key, needed once the server enforces keys
+FORGE_API_KEY = os.getenv("FORGE_API_KEY")
 
 # Direct service ports for observability stack
 # These are exposed to host and configurable via env
@@ -62,7 +64,51 @@ def forge():
     Returns:
         Forge: Configured Forge client
     """
-    return Forge(host=FORGE_HOST, port=FORGE_PORT)
+    return Forge(host=FORGE_HOST, port=FORGE_PORT, api_key=FORGE_API_KEY)
+
+
+@pytest.fixture(scope="session")
+def admin_key():
+    """
+    Admin API key for tests that issue keys, which makes the server enforce
+    them. Skips the test when FORGE_API_KEY is not set.
+    
+    Returns:
+        str: The admin API key
+    """
+    if not FORGE_API_KEY:
+        pytest.skip("FORGE_API_KEY is not set")
+    return FORGE_API_KEY
+
+
+@pytest.fixture
+def issue_key(http_client, forge, admin_key, test_id):
+    """
+    Issue API keys with the admin key and revoke them after the test.
+    
+    Returns:
+        callable: Takes a role and optional per-class rate limits and
+        returns (key id, key)
+    """
+    issued = []
+
+    def issue(role, rate_limits=None):
+        body = {"name": f"{test_id}_{role}", "role": role, "expires_in": "1h"}
+        if rate_limits:
+            body["rate_limits"] = rate_limits
+        response = http_client.post(f"{forge.base_url}/api/v1/auth/keys", json=body)
+        assert response.status_code == 201, response.text
+        data = response.json()
+        issued.append(data["key"]["id"])
+        return data["key"]["id"], data["api_key"]
+
+    yield issue
+
+    for key_id in issued:
+        try:
+            http_client.delete(f"{forge.base_url}/api/v1/auth/keys/{key_id}")
+        except Exception:
+            pass
 
 
 class RetryTransport(httpx.HTTPTransport):
@@ -102,7 +148,8 @@ def http_client():
         httpx.Client: HTTP client for direct requests
     """
     transport = RetryTransport(retries=3)
-    with httpx.Client(timeout=30.0, transport=transport) as client:
+    headers = {"Authorization": f"Bearer {FORGE_API_KEY}"} if FORGE_API_KEY else {}
+    with httpx.Client(timeout=30.0, transport=transport, headers=headers) as client:
         yield client
 
 
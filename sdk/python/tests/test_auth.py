@@ -0,0 +1,123 @@
+"""
+Tests for Forge API key authentication.
+
+These tests verify:
+- Unknown keys are refused, public endpoints stay open
+- Read keys may only read, write keys may not manage keys
+- Revoked keys stop working
+
+Issuing keys makes the server enforce them, so the role tests only run
+with an admin key in FORGE_API_KEY.
+"""
+
+import pytest
+
+
+def bearer(key):
+    """Authorization header for key."""
+    return {"Authorization": f"Bearer {key}"}
+
+
+class TestAuthentication:
+    """Tests for keys the server does not know."""
+
+    def test_unknown_key_refused(self, http_client, forge):
+        """Test that an unknown key is refused even before keys are enforced."""
+        response = http_client.get(
+            f"{forge.base_url}/api/v1/routes",
+            headers=bearer("fk_unknown"),
+        )
+
+        assert response.status_code == 401
+        assert response.headers["www-authenticate"].startswith("Bearer")
+
+    def test_public_endpoint_open(self, http_client, forge):
+        """Test that the health endpoint needs no key."""
+        response = http_client.get(
+            f"{forge.base_url}/api/v1/health",
+            headers=bearer("fk_unknown"),
+        )
+
+        assert response.status_code == 200
+
+
+class TestRoles:
+    """Tests for what each role may do."""
+
+    def test_whoami(self, http_client, forge, issue_key):
+        """Test that whoami reports the key's role."""
+        key_id, key = issue_key("read")
+
+        response = http_client.get(f"{forge.base_url}/api/v1/auth/whoami", headers=bearer(key))
+
+        assert response.status_code == 200
+        data = response.json()
+        assert data["authenticated"] is True
+        assert data["enforced"] is True
+        assert data["identity"]["key_id"] == key_id
+        assert data["identity"]["role"] == "read"
+        assert data["identity"]["method"] == "api_key"
+
+    def test_read_key_reads_only(self, http_client, forge, issue_key, test_id):
+        """Test that a read key may list routes but not add one."""
+        _, key = issue_key("read")
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes", headers=bearer(key))
+        assert response.status_code == 200
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/preview",
+            headers=bearer(key),
+            json={"name": f"auth_{test_id}", "path": "/auth/", "target": "http://example.com"},
+        )
+        assert response.status_code == 403
+
+    def test_write_key_cannot_manage_keys(self, http_client, forge, issue_key, test_id):
+        """Test that a write key may write but not list or issue keys."""
+        _, key = issue_key("write")
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/preview",
+            headers=bearer(key),
+            json={"name": f"auth_{test_id}", "path": "/auth/", "target": "http://example.com"},
+        )
+        assert response.status_code == 200
+
+        response = http_client.get(f"{forge.base_url}/api/v1/auth/keys", headers=bearer(key))
+        assert response.status_code == 403
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/auth/keys",
+            headers=bearer(key),
+            json={"name": f"escalate_{test_id}", "role": "admin"},
+        )
+        assert response.status_code == 403
+
+    def test_key_not_listed_in_plaintext(self, http_client, forge, issue_key):
+        """Test that listed keys show only their prefix."""
+        key_id, key = issue_key("read")
+
+        response = http_client.get(f"{forge.base_url}/api/v1/auth/keys")
+
+        assert response.status_code == 200
+        assert key not in response.text
+        assert key_id in response.text
+
+    def test_revoked_key_refused(self, http_client, forge, issue_key):
+        """Test that a revoked key stops working."""
+        key_id, key = issue_key("write")
+
+        response = http_client.delete(f"{forge.base_url}/api/v1/auth/keys/{key_id}")
+        assert response.status_code == 200
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes", headers=bearer(key))
+        assert response.status_code == 401
+
+    def test_invalid_role_refused(self, http_client, forge, admin_key, test_id):
+        """Test that keys are only issued for known roles."""
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/auth/keys",
+            json={"name": f"bad_role_{test_id}", "role": "owner"},
+        )
+
+        assert response.status_code == 400
@@ -0,0 +1,115 @@
+"""
+Tests for the Forge files API (uploads on the local volume).
+
+These tests verify:
+- Upload, download and delete via SDK
+- Checksums of uploads
+- Ranged downloads
+- Listing by prefix and usage
+"""
+
+import hashlib
+
+import pytest
+import requests
+
+
+@pytest.fixture
+def cleanup_files(forge):
+    """
+    Clean up uploaded files after the test.
+
+    Yields:
+        list: File paths that need cleanup
+    """
+    pending = []
+    yield pending
+
+    for path in pending:
+        try:
+            forge.files.delete(path)
+        except Exception:
+            pass
+
+
+class TestFilesBasicOperations:
+    """Tests for storing and reading files."""
+
+    def test_upload_and_download(self, forge, cleanup_files, test_id):
+        """Test uploading a file and reading it back."""
+        path = f"{test_id}/hello.txt"
+        data = b"hello from the sdk tests"
+        cleanup_files.append(path)
+
+        stored = forge.files.upload(path, data, content_type="text/plain")
+        assert stored["path"] == path
+        assert stored["size"] == len(data)
+        assert stored["sha256"] == hashlib.sha256(data).hexdigest()
+
+        assert forge.files.download(path) == data
+
+    def test_upload_replaces(self, forge, cleanup_files, test_id):
+        """Test that uploading to the same path replaces the file."""
+        path = f"{test_id}/replace.txt"
+        cleanup_files.append(path)
+
+        forge.files.upload(path, b"first")
+        forge.files.upload(path, b"second")
+
+        assert forge.files.download(path) == b"second"
+
+    def test_ranged_download(self, forge, cleanup_files, test_id):
+        """Test reading part of a file."""
+        path = f"{test_id}/digits.txt"
+        cleanup_files.append(path)
+        forge.files.upload(path, b"0123456789")
+
+        assert forge.files.download(path, start=2, end=5) == b"2345"
+        assert forge.files.download(path, start=7) == b"789"
+
+    def test_delete(self, forge, cleanup_files, test_id):
+        """Test deleting a file."""
+        path = f"{test_id}/delete.txt"
+        cleanup_files.append(path)
+        forge.files.upload(path, b"to delete")
+
+        assert forge.files.delete(path) is True
+
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.files.download(path)
+        assert exc.value.response.status_code == 404
+
+    def test_checksum_mismatch_rejected(self, http_client, forge, test_id):
+        """Test that an upload whose checksum does not match is refused."""
+        response = http_client.put(
+            f"{forge.base_url}/api/v1/files/{test_id}/corrupt.txt",
+            content=b"actual contents",
+            headers={"X-Checksum-Sha256": hashlib.sha256(b"other").hexdigest()},
+        )
+        assert response.status_code == 400
+
+
+class TestFilesListing:
+    """Tests for listing files and usage."""
+
+    def test_list_by_prefix(self, forge, cleanup_files, test_id):
+        """Test that listing returns only paths under the prefix."""
+        paths = [f"{test_id}/a/one.txt", f"{test_id}/a/two.txt", f"{test_id}/b/three.txt"]
+        for path in paths:
+            cleanup_files.append(path)
+            forge.files.upload(path, b"x")
+
+        listed = sorted(item["path"] for item in forge.files.list(prefix=f"{test_id}/a/"))
+        assert listed == paths[:2]
+
+    def test_usage(self, forge, cleanup_files, test_id):
+        """Test that usage reports the space files take."""
+        path = f"{test_id}/usage.bin"
+        cleanup_files.append(path)
+        forge.files.upload(path, b"\0" * 1024)
+
+        usage = forge.files.usage()
+        assert usage["files"] >= 1
+        assert usage["used_bytes"] >= 1024
+        assert "quota_bytes" in usage
+        assert "max_file_bytes" in usage
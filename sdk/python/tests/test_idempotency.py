@@ -0,0 +1,113 @@
+"""
+Tests for Idempotency-Key replays.
+
+These tests verify:
+- A retried POST gets the first response without running again
+- Reusing a key for a different request is refused
+- Concurrent retries run the request once
+- Invalid keys are refused
+"""
+
+import uuid
+from concurrent.futures import ThreadPoolExecutor
+
+import pytest
+
+
+@pytest.fixture(autouse=True)
+def idempotency_enabled(http_client, forge):
+    """Skip the tests when the server does not keep idempotent responses."""
+    response = http_client.get(f"{forge.base_url}/api/v1/version")
+    if "idempotency" not in response.json().get("features", []):
+        pytest.skip("idempotency keys are disabled (IDEMPOTENCY_TTL=off)")
+
+
+def idempotency_key():
+    """A fresh Idempotency-Key header."""
+    return {"Idempotency-Key": str(uuid.uuid4())}
+
+
+class TestIdempotencyReplay:
+    """Tests for replayed responses."""
+
+    def test_retry_replayed(self, http_client, forge, cleanup_routes, test_id):
+        """Test that a retry gets the kept response and does not run again."""
+        route_name = f"idem_{test_id}"
+        cleanup_routes.append(route_name)
+        headers = idempotency_key()
+        route = {"name": route_name, "path": f"/idem/{test_id}/", "target": "http://example.com"}
+
+        first = http_client.post(f"{forge.base_url}/api/v1/routes", json=route, headers=headers)
+        assert first.status_code == 201, first.text
+        assert "idempotent-replayed" not in first.headers
+
+        # Deleted in between: a retry that ran again would recreate it
+        http_client.delete(f"{forge.base_url}/api/v1/routes/{route_name}")
+
+        retry = http_client.post(f"{forge.base_url}/api/v1/routes", json=route, headers=headers)
+        assert retry.status_code == 201
+        assert retry.headers["idempotent-replayed"] == "true"
+        assert retry.json() == first.json()
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}")
+        assert response.status_code == 404
+
+    def test_client_errors_replayed(self, http_client, forge):
+        """Test that refused requests are kept and replayed like others."""
+        headers = idempotency_key()
+        route = {"name": "idem_no_target", "path": "/idem-no-target/"}
+
+        first = http_client.post(f"{forge.base_url}/api/v1/routes", json=route, headers=headers)
+        retry = http_client.post(f"{forge.base_url}/api/v1/routes", json=route, headers=headers)
+
+        assert first.status_code == 400
+        assert retry.status_code == 400
+        assert retry.headers["idempotent-replayed"] == "true"
+
+    def test_concurrent_retries_run_once(self, http_client, forge):
+        """Test that retries sent together run the request only once."""
+        headers = idempotency_key()
+
+        def reload():
+            return http_client.post(f"{forge.base_url}/api/v1/routes/reload", headers=headers)
+
+        with ThreadPoolExecutor(max_workers=4) as pool:
+            responses = list(pool.map(lambda _: reload(), range(4)))
+
+        ran = [r for r in responses if r.status_code == 200 and "idempotent-replayed" not in r.headers]
+        assert len(ran) == 1
+        for r in responses:
+            assert r.status_code in (200, 409)
+
+
+class TestIdempotencyConflict:
+    """Tests for keys reused or malformed."""
+
+    def test_key_reused_for_other_request(self, http_client, forge, cleanup_routes, test_id):
+        """Test that a key reused with a different body is refused with 422."""
+        route_name = f"idem_reuse_{test_id}"
+        cleanup_routes.append(route_name)
+        headers = idempotency_key()
+        route = {"name": route_name, "path": f"/idem-reuse/{test_id}/", "target": "http://example.com"}
+
+        first = http_client.post(f"{forge.base_url}/api/v1/routes", json=route, headers=headers)
+        assert first.status_code == 201, first.text
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={**route, "target": "http://httpbin.org"},
+            headers=headers,
+        )
+        assert response.status_code == 422
+
+        saved = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}").json()
+        assert saved["target"] == "http://example.com"
+
+    def test_invalid_key(self, http_client, forge):
+        """Test that an overlong key is refused."""
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/reload",
+            headers={"Idempotency-Key": "k" * 256},
+        )
+
+        assert response.status_code == 400
@@ -0,0 +1,175 @@
+"""
+Tests for scheduled jobs.
+
+These tests verify:
+- Jobs can be created, listed and deleted
+- Invalid schedules and actions are refused
+- A job can be run on demand and its run is recorded
+- Pausing a job clears its next run and resuming schedules it again
+"""
+
+import time
+
+import pytest
+
+
+@pytest.fixture
+def cleanup_jobs(http_client, forge):
+    """
+    Fixture that deletes jobs after test.
+
+    Yields:
+        list: List to track job names that need cleanup
+    """
+    jobs_to_cleanup = []
+    yield jobs_to_cleanup
+
+    for name in jobs_to_cleanup:
+        try:
+            http_client.delete(f"{forge.base_url}/api/v1/jobs/{name}")
+        except Exception:
+            pass  # Ignore cleanup errors
+
+
+def cache_job(name, key, schedule="@hourly"):
+    """A job that sets a cache key."""
+    return {
+        "name": name,
+        "schedule": schedule,
+        "action": {"type": "cache", "op": "set", "key": key, "value": "ran"},
+    }
+
+
+def scheduled(status):
+    """Whether a job status has a next run; a zero time means none."""
+    next_run = status.get("next_run", "")
+    return bool(next_run) and not next_run.startswith("0001-")
+
+
+def wait_for_run(http_client, forge, name, timeout=10):
+    """Poll the run history of a job until a run has finished."""
+    deadline = time.time() + timeout
+    while time.time() < deadline:
+        response = http_client.get(f"{forge.base_url}/api/v1/jobs/{name}/runs")
+        assert response.status_code == 200
+        finished = [r for r in response.json()["items"] if r["status"] not in ("running", "queued")]
+        if finished:
+            return finished[-1]
+        time.sleep(0.2)
+    pytest.fail(f"job {name} did not finish a run within {timeout}s")
+
+
+class TestJobs:
+    """Tests for creating and listing jobs."""
+
+    def test_create_job(self, http_client, forge, cleanup_jobs, cleanup_cache, test_id):
+        """Test that a new job is saved with its next run scheduled."""
+        name = f"job_{test_id}"
+        cleanup_jobs.append(name)
+        cleanup_cache.append(f"job_{test_id}")
+
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=cache_job(name, f"job_{test_id}"))
+        assert response.status_code == 201
+        job = response.json()["job"]
+        assert job["name"] == name
+        assert job["schedule"] == "@hourly"
+        assert scheduled(job)
+
+        response = http_client.get(f"{forge.base_url}/api/v1/jobs")
+        assert response.status_code == 200
+        assert name in [j["name"] for j in response.json()["items"]]
+
+    def test_delete_job(self, http_client, forge, cleanup_jobs, test_id):
+        """Test that a deleted job is gone."""
+        name = f"job_{test_id}"
+        cleanup_jobs.append(name)
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=cache_job(name, f"job_{test_id}"))
+        assert response.status_code == 201
+
+        response = http_client.delete(f"{forge.base_url}/api/v1/jobs/{name}")
+        assert response.status_code == 200
+        assert response.json()["deleted"] == name
+
+        response = http_client.get(f"{forge.base_url}/api/v1/jobs/{name}")
+        assert response.status_code == 404
+
+    def test_unknown_job(self, http_client, forge, test_id):
+        """Test that an unknown job is not found."""
+        response = http_client.get(f"{forge.base_url}/api/v1/jobs/missing_{test_id}")
+        assert response.status_code == 404
+
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs/missing_{test_id}/run")
+        assert response.status_code == 404
+
+    @pytest.mark.parametrize("schedule", ["not a schedule", "61 * * * *", "* * *", "@sometimes"])
+    def test_invalid_schedule(self, http_client, forge, cleanup_jobs, test_id, schedule):
+        """Test that an invalid cron expression is refused."""
+        name = f"job_{test_id}"
+        cleanup_jobs.append(name)
+
+        job = cache_job(name, f"job_{test_id}", schedule=schedule)
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=job)
+        assert response.status_code == 400
+
+    def test_invalid_action(self, http_client, forge, cleanup_jobs, test_id):
+        """Test that an unknown action type is refused."""
+        name = f"job_{test_id}"
+        cleanup_jobs.append(name)
+
+        job = {"name": name, "schedule": "@daily", "action": {"type": "teleport"}}
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=job)
+        assert response.status_code == 400
+
+
+class TestJobRuns:
+    """Tests for running jobs."""
+
+    def test_run_now(self, http_client, forge, cleanup_jobs, cleanup_cache, test_id):
+        """Test that a job run on demand performs its action and is recorded."""
+        name = f"job_{test_id}"
+        key = f"job_{test_id}"
+        cleanup_jobs.append(name)
+        cleanup_cache.append(key)
+
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=cache_job(name, key))
+        assert response.status_code == 201
+
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs/{name}/run")
+        assert response.status_code == 202
+        assert response.json()["trigger"] == "manual"
+
+        run = wait_for_run(http_client, forge, name)
+        assert run["status"] == "succeeded", run.get("error")
+        assert run["trigger"] == "manual"
+        assert forge.cache.get(key) == "ran"
+
+    def test_pause_and_resume(self, http_client, forge, cleanup_jobs, cleanup_cache, test_id):
+        """Test that a paused job has no next run until it is resumed."""
+        name = f"job_{test_id}"
+        cleanup_jobs.append(name)
+        cleanup_cache.append(f"job_{test_id}")
+
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=cache_job(name, f"job_{test_id}"))
+        assert response.status_code == 201
+
+        response = http_client.patch(f"{forge.base_url}/api/v1/jobs/{name}", json={"paused": True})
+        assert response.status_code == 200
+        assert response.json()["paused"] is True
+        assert not scheduled(response.json())
+
+        response = http_client.patch(f"{forge.base_url}/api/v1/jobs/{name}", json={"paused": False})
+        assert response.status_code == 200
+        assert not response.json().get("paused")
+        assert scheduled(response.json())
+
+    def test_pause_requires_flag(self, http_client, forge, cleanup_jobs, cleanup_cache, test_id):
+        """Test that a PATCH without paused is refused."""
+        name = f"job_{test_id}"
+        cleanup_jobs.append(name)
+        cleanup_cache.append(f"job_{test_id}")
+
+        response = http_client.post(f"{forge.base_url}/api/v1/jobs", json=cache_job(name, f"job_{test_id}"))
+        assert response.status_code == 201
+
+        response = http_client.patch(f"{forge.base_url}/api/v1/jobs/{name}", json={})
+        assert response.status_code == 400
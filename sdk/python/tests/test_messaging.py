@@ -0,0 +1,98 @@
+"""
+Tests for Forge messaging (NATS).
+
+These tests verify:
+- Publishing via SDK
+- Streaming subscriptions receiving published messages
+- Subject validation
+- Connection info for native clients
+"""
+
+import queue
+import threading
+import time
+
+import pytest
+import requests
+
+
+def receive_one(forge, subject, publish_subject, data, timeout=10.0):
+    """Subscribe to subject in a thread and publish until a message arrives."""
+    received = queue.Queue()
+
+    def subscribe():
+        try:
+            for msg in forge.messaging.subscribe(subject):
+                received.put(msg)
+                return
+        except Exception as e:
+            received.put(e)
+
+    threading.Thread(target=subscribe, daemon=True).start()
+
+    # The subscription starts asynchronously, so publish until it sees one
+    deadline = time.time() + timeout
+    while time.time() < deadline:
+        forge.messaging.publish(publish_subject, data)
+        try:
+            msg = received.get(timeout=0.5)
+        except queue.Empty:
+            continue
+        if isinstance(msg, Exception):
+            raise msg
+        return msg
+    pytest.fail(f"no message on {subject} within {timeout}s")
+
+
+class TestMessagingPublish:
+    """Tests for publishing messages."""
+
+    def test_publish(self, forge, test_id):
+        """Test publishing a message."""
+        assert forge.messaging.publish(f"tests.{test_id}.created", b'{"id": 1}') is True
+
+    def test_publish_string(self, forge, test_id):
+        """Test that string payloads are accepted."""
+        assert forge.messaging.publish(f"tests.{test_id}.text", "hello") is True
+
+    def test_publish_wildcard_rejected(self, forge, test_id):
+        """Test that subjects with wildcards cannot be published to."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.messaging.publish(f"tests.{test_id}.*", b"x")
+        assert exc.value.response.status_code == 400
+
+
+class TestMessagingSubscribe:
+    """Tests for streaming subscriptions."""
+
+    def test_subscribe_receives(self, forge, test_id):
+        """Test that a subscription receives a published message."""
+        subject = f"tests.{test_id}.events"
+        msg = receive_one(forge, subject, subject, b"payload")
+
+        assert msg["subject"] == subject
+        assert msg["data"] == b"payload"
+
+    def test_subscribe_wildcard(self, forge, test_id):
+        """Test that wildcard subscriptions match deeper subjects."""
+        msg = receive_one(forge, f"tests.{test_id}.>", f"tests.{test_id}.orders.created", b"{}")
+
+        assert msg["subject"] == f"tests.{test_id}.orders.created"
+
+
+class TestMessagingInfo:
+    """Tests for native client connection info."""
+
+    def test_info(self, forge):
+        """Test that info returns a server address and account."""
+        info = forge.messaging.info()
+
+        assert info["url"]
+        assert info["user"]
+        assert info["password"]
+
+    def test_info_read_access(self, forge):
+        """Test requesting a subscribe-only account."""
+        info = forge.messaging.info(access="read")
+
+        assert info["access"] == "read"
@@ -0,0 +1,65 @@
+"""
+Tests for Forge MQTT publishing.
+
+These tests verify:
+- Broker connection status
+- Publishing text, bytes and JSON payloads via SDK
+- QoS and topic validation
+
+The broker is opt-in: start it with docker compose --profile mqtt up -d.
+Tests are skipped while the API is not connected to one.
+"""
+
+import pytest
+import requests
+
+
+@pytest.fixture(scope="module", autouse=True)
+def require_broker(forge):
+    """Skip the module when the API has no broker connection."""
+    try:
+        connected = forge.mqtt.status().get("connected")
+    except requests.HTTPError:
+        connected = False
+    if not connected:
+        pytest.skip("MQTT broker not connected (docker compose --profile mqtt up -d)")
+
+
+class TestMQTTStatus:
+    """Tests for the broker connection."""
+
+    def test_status(self, forge):
+        """Test that status reports the broker connection."""
+        status = forge.mqtt.status()
+
+        assert status["connected"] is True
+        assert status["url"]
+        assert status["client_id"]
+
+
+class TestMQTTPublish:
+    """Tests for publishing messages."""
+
+    def test_publish_text(self, forge, test_id):
+        """Test publishing a text payload."""
+        assert forge.mqtt.publish(f"tests/{test_id}/text", "ON") is True
+
+    def test_publish_json(self, forge, test_id):
+        """Test that dict payloads are sent as JSON."""
+        assert forge.mqtt.publish(f"tests/{test_id}/json", {"interval": 30}) is True
+
+    def test_publish_qos1(self, forge, test_id):
+        """Test publishing with QoS 1, which waits for the broker."""
+        assert forge.mqtt.publish(f"tests/{test_id}/qos", b"\x01\x02", qos=1) is True
+
+    def test_publish_wildcard_rejected(self, forge, test_id):
+        """Test that topics with wildcards cannot be published to."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.mqtt.publish(f"tests/{test_id}/#", "x")
+        assert exc.value.response.status_code == 400
+
+    def test_publish_invalid_qos_rejected(self, forge, test_id):
+        """Test that QoS 2 is refused."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.mqtt.publish(f"tests/{test_id}/qos", "x", qos=2)
+        assert exc.value.response.status_code == 400
@@ -0,0 +1,93 @@
+"""
+Tests for Forge notifications.
+
+These tests verify:
+- Listing channels via SDK, without their tokens
+- Sending to unknown, disabled and unreachable channels
+- Message validation
+"""
+
+import pytest
+import requests
+
+
+@pytest.fixture
+def cleanup_channels(forge):
+    """
+    Delete notification channels after the test.
+
+    Yields:
+        list: Channel names that need cleanup
+    """
+    pending = []
+    yield pending
+
+    for name in pending:
+        try:
+            forge._request("DELETE", f"/notify/channels/{name}")
+        except Exception:
+            pass
+
+
+@pytest.fixture
+def webhook_channel(http_client, forge, cleanup_channels, test_id):
+    """
+    Create a webhook channel whose endpoint refuses connections.
+
+    Returns:
+        str: The channel name
+    """
+    name = f"ch_{test_id}"
+    cleanup_channels.append(name)
+    response = http_client.post(
+        f"{forge.base_url}/api/v1/notify/channels",
+        json={"name": name, "type": "webhook", "url": "http://127.0.0.1:9/hook"},
+    )
+    assert response.status_code == 201
+    return name
+
+
+class TestNotifyChannels:
+    """Tests for listing channels."""
+
+    def test_channels_lists_created(self, forge, webhook_channel):
+        """Test that a created channel is listed."""
+        channels = {c["name"]: c for c in forge.notify.channels()}
+
+        assert webhook_channel in channels
+        assert channels[webhook_channel]["type"] == "webhook"
+        assert "token" not in channels[webhook_channel]
+
+
+class TestNotifySend:
+    """Tests for sending messages."""
+
+    def test_send_unknown_channel(self, forge, test_id):
+        """Test that sending to an unknown channel fails with 404."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.notify.send(f"missing_{test_id}", "hello")
+        assert exc.value.response.status_code == 404
+
+    def test_send_disabled_channel(self, http_client, forge, webhook_channel):
+        """Test that a disabled channel refuses messages."""
+        response = http_client.patch(
+            f"{forge.base_url}/api/v1/notify/channels/{webhook_channel}",
+            json={"disabled": True},
+        )
+        assert response.status_code == 200
+
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.notify.send(webhook_channel, "hello")
+        assert exc.value.response.status_code == 409
+
+    def test_send_unreachable_channel(self, forge, webhook_channel):
+        """Test that a channel whose service cannot be reached fails with 502."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.notify.send(webhook_channel, "hello", title="Test")
+        assert exc.value.response.status_code == 502
+
+    def test_send_invalid_priority(self, forge, webhook_channel):
+        """Test that an unknown priority is refused."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.notify.send(webhook_channel, "hello", priority="urgent")
+        assert exc.value.response.status_code == 400
@@ -0,0 +1,102 @@
+"""
+Tests for Forge rate limiting.
+
+These tests verify:
+- Requests over a key's limit get 429 with Retry-After
+- Limits are counted per endpoint class and per client
+- Connect RPCs share the REST limits
+
+The tests issue keys with their own low limits, so they only run with an
+admin key in FORGE_API_KEY and when rate limiting is enabled.
+"""
+
+import pytest
+
+
+@pytest.fixture(autouse=True)
+def rate_limits_enabled(http_client, forge):
+    """Skip the tests when the server does not limit rates."""
+    response = http_client.get(f"{forge.base_url}/api/v1/version")
+    if "rate_limits" not in response.json().get("features", []):
+        pytest.skip("rate limiting is disabled (RATE_LIMITS=off)")
+
+
+def exhaust(http_client, url, headers, limit):
+    """
+    Send GETs to url until the limit is used up.
+
+    Returns:
+        httpx.Response: The first response over the limit
+    """
+    for _ in range(limit):
+        response = http_client.get(url, headers=headers)
+        assert response.status_code == 200, response.text
+    return http_client.get(url, headers=headers)
+
+
+class TestRateLimit:
+    """Tests for REST rate limits."""
+
+    def test_over_limit(self, http_client, forge, issue_key):
+        """Test that requests over the limit get 429 with Retry-After."""
+        _, key = issue_key("read", rate_limits={"read": 3})
+        headers = {"Authorization": f"Bearer {key}"}
+
+        response = exhaust(http_client, f"{forge.base_url}/api/v1/routes", headers, 3)
+
+        assert response.status_code == 429
+        assert int(response.headers["retry-after"]) >= 1
+        assert "error" in response.json()
+
+    def test_classes_counted_apart(self, http_client, forge, issue_key, test_id):
+        """Test that using up the read limit leaves writes allowed."""
+        _, key = issue_key("write", rate_limits={"read": 2})
+        headers = {"Authorization": f"Bearer {key}"}
+
+        response = exhaust(http_client, f"{forge.base_url}/api/v1/routes", headers, 2)
+        assert response.status_code == 429
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/preview",
+            headers=headers,
+            json={"name": f"rl_{test_id}", "path": "/rl/", "target": "http://example.com"},
+        )
+        assert response.status_code == 200
+
+    def test_clients_counted_apart(self, http_client, forge, issue_key):
+        """Test that one key over its limit does not limit others."""
+        _, key = issue_key("read", rate_limits={"read": 2})
+
+        response = exhaust(
+            http_client, f"{forge.base_url}/api/v1/routes",
+            {"Authorization": f"Bearer {key}"}, 2,
+        )
+        assert response.status_code == 429
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes")
+        assert response.status_code == 200
+
+    def test_rpc_limited(self, http_client, forge, issue_key):
+        """Test that Connect RPCs count against the same limit."""
+        _, key = issue_key("read", rate_limits={"read": 2})
+        headers = {"Authorization": f"Bearer {key}"}
+
+        exhaust(http_client, f"{forge.base_url}/api/v1/routes", headers, 2)
+        response = http_client.post(
+            f"{forge.base_url}/forge.v1.ForgeService/Info",
+            headers={**headers, "Content-Type": "application/json"},
+            json={},
+        )
+
+        assert response.status_code == 429
+        assert response.json()["code"] == "resource_exhausted"
+        assert int(response.headers["retry-after"]) >= 1
+
+    def test_unknown_class_refused(self, http_client, forge, admin_key, test_id):
+        """Test that keys are only issued with limits for known classes."""
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/auth/keys",
+            json={"name": f"rl_bad_{test_id}", "role": "read", "rate_limits": {"reads": 10}},
+        )
+
+        assert response.status_code == 400
@@ -7,11 +7,34 @@ These tests verify:
 - Getting specific routes
 - Deleting routes
 - Nginx reload functionality
+- Per-route rate limits
+- Domains served over HTTPS
+- Host-based routing
+- Previewing routes without applying them
+- Timeout, body size and buffering settings
+- Exact and regex match types
+- Exporting and importing routes
+- Canary traffic splitting
+- gRPC routes
+- Per-route access logs shipped to Loki
+- WebSocket support and timeouts
 """
 
 import pytest
 
 
+def preview_route(http_client, forge, route):
+    """
+    Preview a route without saving it.
+
+    Returns:
+        dict: The preview, with the generated nginx location block in "config"
+    """
+    response = http_client.post(f"{forge.base_url}/api/v1/routes/preview", json=route)
+    assert response.status_code == 200
+    return response.json()
+
+
 class TestRoutesListing:
     """Tests for listing routes."""
 
@@ -340,3 +363,593 @@ class TestRoutePersistence:
         
         assert route_name in route_names
 
+
+class TestRouteRateLimit:
+    """Tests for per-route rate limits."""
+
+    def test_rate_limit_emits_limit_req(self, http_client, forge, test_id):
+        """Test that a rate limit adds limit_req to the location."""
+        preview = preview_route(http_client, forge, {
+            "name": f"rl_{test_id}",
+            "path": f"/rl/{test_id}/",
+            "target": "http://example.com",
+            "rate_limit": {"rps": 10, "burst": 5},
+        })
+
+        assert preview["valid"] is True
+        assert "limit_req zone=" in preview["config"]
+        assert "burst=5 nodelay;" in preview["config"]
+        assert "limit_req_status 429;" in preview["config"]
+
+    def test_rate_limit_saved(self, http_client, forge, cleanup_routes, test_id):
+        """Test that a rate limit is kept on the saved route."""
+        route_name = f"rl_saved_{test_id}"
+        cleanup_routes.append(route_name)
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": route_name,
+                "path": f"/rl-saved/{test_id}/",
+                "target": "http://example.com",
+                "rate_limit": {"rps": 20, "burst": 40},
+            }
+        )
+        assert response.status_code == 201, response.text
+
+        route = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}").json()
+        assert route["rate_limit"] == {"rps": 20, "burst": 40}
+
+    def test_rate_limit_requires_rps(self, http_client, forge, test_id):
+        """Test that a rate limit without rps is refused."""
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": f"rl_bad_{test_id}",
+                "path": f"/rl-bad/{test_id}/",
+                "target": "http://example.com",
+                "rate_limit": {"rps": 0},
+            }
+        )
+
+        assert response.status_code == 400
+
+
+class TestRouteDomain:
+    """Tests for routes served on a domain with a certificate."""
+
+    def test_domain_normalized(self, http_client, forge, test_id):
+        """Test that a domain is lowercased and gets its own server block."""
+        label = test_id.replace("_", "-")
+        preview = preview_route(http_client, forge, {
+            "name": f"domain_{test_id}",
+            "path": "/",
+            "target": "http://example.com",
+            "domain": f"App-{label}.Example.com",
+        })
+
+        assert preview["valid"] is True
+        assert preview["route"]["domain"] == f"app-{label}.example.com"
+        assert preview["host"] == f"app-{label}.example.com"
+
+    def test_invalid_domain(self, http_client, forge, test_id):
+        """Test that an invalid domain is refused."""
+        preview = preview_route(http_client, forge, {
+            "name": f"domain_bad_{test_id}",
+            "path": "/",
+            "target": "http://example.com",
+            "domain": "not a domain",
+        })
+
+        assert preview["valid"] is False
+        assert "domain" in preview["error"]
+
+
+class TestRouteHost:
+    """Tests for host-based routing."""
+
+    def test_add_host_route(self, http_client, forge, cleanup_routes, test_id):
+        """Test that a route can match a host instead of the main server."""
+        route_name = f"host_{test_id}"
+        host = f"{test_id.replace('_', '-')}.example.com"
+        cleanup_routes.append(route_name)
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": route_name,
+                "path": "/",
+                "target": "http://example.com",
+                "host": host.upper(),
+            }
+        )
+        assert response.status_code == 201, response.text
+
+        route = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}").json()
+        assert route["host"] == host
+
+    def test_wildcard_host(self, http_client, forge, test_id):
+        """Test that a wildcard host is accepted."""
+        preview = preview_route(http_client, forge, {
+            "name": f"host_wild_{test_id}",
+            "path": "/",
+            "target": "http://example.com",
+            "host": "*.example.com",
+        })
+
+        assert preview["valid"] is True
+        assert preview["host"] == "*.example.com"
+
+    def test_host_and_domain_must_match(self, http_client, forge, test_id):
+        """Test that a host and a different domain are refused together."""
+        preview = preview_route(http_client, forge, {
+            "name": f"host_domain_{test_id}",
+            "path": "/",
+            "target": "http://example.com",
+            "host": "a.example.com",
+            "domain": "b.example.com",
+        })
+
+        assert preview["valid"] is False
+        assert "must match" in preview["error"]
+
+
+class TestRoutePreview:
+    """Tests for previewing routes."""
+
+    def test_preview_not_saved(self, http_client, forge, test_id):
+        """Test that a previewed route is normalized but not saved."""
+        route_name = f"preview_{test_id}"
+        preview = preview_route(http_client, forge, {
+            "name": route_name,
+            "path": f"preview/{test_id}",
+            "target": "http://example.com",
+        })
+
+        assert preview["valid"] is True
+        assert preview["route"]["path"] == f"/preview/{test_id}/"
+        assert f"location /preview/{test_id}/ {{" in preview["config"]
+        assert "current" not in preview
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}")
+        assert response.status_code == 404
+
+    def test_preview_shows_current(self, http_client, forge, cleanup_routes, test_id):
+        """Test that previewing a change to a route shows the current block."""
+        route_name = f"preview_cur_{test_id}"
+        cleanup_routes.append(route_name)
+        route = {
+            "name": route_name,
+            "path": f"/preview-cur/{test_id}/",
+            "target": "http://example.com",
+        }
+        response = http_client.post(f"{forge.base_url}/api/v1/routes", json=route)
+        assert response.status_code == 201, response.text
+
+        preview = preview_route(http_client, forge, {**route, "target": "http://httpbin.org"})
+
+        assert "proxy_pass http://example.com;" in preview["current"]
+        assert "proxy_pass http://httpbin.org;" in preview["config"]
+
+    def test_preview_invalid_route(self, http_client, forge, test_id):
+        """Test that an invalid route is reported rather than refused."""
+        preview = preview_route(http_client, forge, {
+            "name": f"preview_bad_{test_id}",
+            "path": "/preview-bad/",
+        })
+
+        assert preview["valid"] is False
+        assert "target" in preview["error"]
+        assert "config" not in preview
+
+
+class TestRouteProxySettings:
+    """Tests for per-route timeout, body size and buffering settings."""
+
+    def test_settings_emitted(self, http_client, forge, test_id):
+        """Test that the settings become nginx directives."""
+        preview = preview_route(http_client, forge, {
+            "name": f"settings_{test_id}",
+            "path": f"/settings/{test_id}/",
+            "target": "http://example.com",
+            "read_timeout": "5m",
+            "max_body_size": "100m",
+            "buffering": False,
+        })
+
+        assert preview["valid"] is True
+        assert "proxy_read_timeout 300s;" in preview["config"]
+        assert "client_max_body_size 100m;" in preview["config"]
+        assert "proxy_buffering off;" in preview["config"]
+
+    def test_defaults_omitted(self, http_client, forge, test_id):
+        """Test that unset settings leave the nginx defaults."""
+        preview = preview_route(http_client, forge, {
+            "name": f"settings_def_{test_id}",
+            "path": f"/settings-def/{test_id}/",
+            "target": "http://example.com",
+        })
+
+        assert "proxy_read_timeout" not in preview["config"]
+        assert "client_max_body_size" not in preview["config"]
+        assert "proxy_buffering" not in preview["config"]
+
+    @pytest.mark.parametrize("field,value", [
+        ("read_timeout", "forever"),
+        ("read_timeout", "500ms"),
+        ("max_body_size", "100 MB"),
+    ])
+    def test_invalid_settings(self, http_client, forge, test_id, field, value):
+        """Test that invalid settings are refused."""
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": f"settings_bad_{test_id}",
+                "path": f"/settings-bad/{test_id}/",
+                "target": "http://example.com",
+                field: value,
+            }
+        )
+
+        assert response.status_code == 400
+        assert field in response.text
+
+
+class TestRouteMatchTypes:
+    """Tests for exact and regex location types."""
+
+    def test_exact_match(self, http_client, forge, test_id):
+        """Test that an exact route keeps its path as given."""
+        preview = preview_route(http_client, forge, {
+            "name": f"exact_{test_id}",
+            "path": f"/exact/{test_id}",
+            "match_type": "exact",
+            "target": "http://example.com",
+        })
+
+        assert preview["valid"] is True
+        assert f"location = /exact/{test_id} {{" in preview["config"]
+
+    def test_regex_match(self, http_client, forge, cleanup_routes, test_id):
+        """Test that a regex route is saved and quoted in nginx."""
+        route_name = f"regex_{test_id}"
+        cleanup_routes.append(route_name)
+        path = f"^/regex/{test_id}/users/[0-9]+$"
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": route_name,
+                "path": path,
+                "match_type": "regex",
+                "target": "http://example.com",
+            }
+        )
+        assert response.status_code == 201, response.text
+
+        preview = preview_route(http_client, forge, {
+            "name": route_name,
+            "path": path,
+            "match_type": "regex",
+            "target": "http://example.com",
+        })
+        assert f'location ~ "{path}" {{' in preview["current"]
+
+    @pytest.mark.parametrize("route", [
+        {"path": "/users/[0-9", "match_type": "regex"},
+        {"path": "/users/[0-9]+", "match_type": "regex", "strip_prefix": True},
+        {"path": "/users", "match_type": "glob"},
+    ])
+    def test_invalid_match(self, http_client, forge, test_id, route):
+        """Test that invalid patterns and match types are refused."""
+        preview = preview_route(http_client, forge, {
+            "name": f"match_bad_{test_id}",
+            "target": "http://example.com",
+            **route,
+        })
+
+        assert preview["valid"] is False
+
+
+class TestRoutesExportImport:
+    """Tests for bulk export and import of routes."""
+
+    def test_round_trip(self, http_client, forge, cleanup_routes, test_id):
+        """Test that an exported route is restored by importing it."""
+        route_name = f"export_{test_id}"
+        cleanup_routes.append(route_name)
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": route_name,
+                "path": f"/export/{test_id}/",
+                "target": "http://example.com",
+                "strip_prefix": True,
+                "headers": {"X-Test": test_id},
+                "read_timeout": "2m",
+            }
+        )
+        assert response.status_code == 201, response.text
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/export", params={"format": "json"})
+        assert response.status_code == 200
+        exported = {r["name"]: r for r in response.json()["routes"]}
+        assert route_name in exported
+
+        http_client.delete(f"{forge.base_url}/api/v1/routes/{route_name}")
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/import",
+            json={"routes": [exported[route_name]]},
+        )
+        assert response.status_code == 200, response.text
+        assert response.json()["imported"] == 1
+        assert response.json()["mode"] == "merge"
+
+        restored = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}").json()
+        assert restored == exported[route_name]
+
+    def test_export_yaml(self, http_client, forge):
+        """Test that routes are exported as YAML by default."""
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/export")
+
+        assert response.status_code == 200
+        assert response.headers["content-type"].startswith("application/yaml")
+        assert "routes:" in response.text
+
+    def test_import_yaml(self, http_client, forge, cleanup_routes, test_id):
+        """Test that a YAML routes file is imported."""
+        route_name = f"import_yaml_{test_id}"
+        cleanup_routes.append(route_name)
+        body = (
+            "routes:\n"
+            f"  - name: {route_name}\n"
+            f"    path: /import-yaml/{test_id}/\n"
+            "    target: http://example.com\n"
+        )
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/import",
+            content=body,
+            headers={"Content-Type": "application/yaml"},
+        )
+        assert response.status_code == 200, response.text
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}")
+        assert response.status_code == 200
+
+    def test_import_is_atomic(self, http_client, forge, cleanup_routes, test_id):
+        """Test that nothing is imported when one route is invalid."""
+        route_name = f"import_ok_{test_id}"
+        cleanup_routes.append(route_name)
+
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/import",
+            json={"routes": [
+                {"name": route_name, "path": f"/import-ok/{test_id}/", "target": "http://example.com"},
+                {"name": f"import_bad_{test_id}", "path": "/import-bad/"},
+            ]},
+        )
+        assert response.status_code == 400
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/{route_name}")
+        assert response.status_code == 404
+
+    def test_import_rejects_unknown_fields(self, http_client, forge, test_id):
+        """Test that a misspelled field fails the import."""
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes/import",
+            json={"routes": [{
+                "name": f"import_typo_{test_id}",
+                "path": f"/import-typo/{test_id}/",
+                "target": "http://example.com",
+                "strip_prefx": True,
+            }]},
+        )
+
+        assert response.status_code == 400
+        assert "strip_prefx" in response.text
+
+
+@pytest.fixture
+def canary_route(http_client, forge, cleanup_routes, test_id):
+    """
+    Create a route sending 10% of clients to a canary target.
+
+    Returns:
+        str: The route name
+    """
+    route_name = f"canary_{test_id}"
+    cleanup_routes.append(route_name)
+    response = http_client.post(
+        f"{forge.base_url}/api/v1/routes",
+        json={
+            "name": route_name,
+            "path": f"/canary/{test_id}/",
+            "target": "http://example.com",
+            "canary": {"target": "http://httpbin.org", "weight": 10},
+        }
+    )
+    assert response.status_code == 201, response.text
+    return route_name
+
+
+class TestRouteCanary:
+    """Tests for weighted canary targets."""
+
+    def test_shift_weight(self, http_client, forge, canary_route):
+        """Test that the canary weight can be changed."""
+        response = http_client.put(
+            f"{forge.base_url}/api/v1/routes/{canary_route}/canary",
+            json={"weight": 50},
+        )
+
+        assert response.status_code == 200, response.text
+        assert response.json()["route"]["canary"]["weight"] == 50
+
+    def test_invalid_weight(self, http_client, forge, canary_route):
+        """Test that a weight above 100 is refused."""
+        response = http_client.put(
+            f"{forge.base_url}/api/v1/routes/{canary_route}/canary",
+            json={"weight": 150},
+        )
+
+        assert response.status_code == 400
+
+    def test_promote(self, http_client, forge, canary_route):
+        """Test that promoting makes the canary the only target."""
+        response = http_client.post(f"{forge.base_url}/api/v1/routes/{canary_route}/canary/promote")
+        assert response.status_code == 200, response.text
+
+        route = http_client.get(f"{forge.base_url}/api/v1/routes/{canary_route}").json()
+        assert route["target"] == "http://httpbin.org"
+        assert "canary" not in route
+
+    def test_remove(self, http_client, forge, canary_route):
+        """Test that removing the canary keeps the primary target."""
+        response = http_client.delete(f"{forge.base_url}/api/v1/routes/{canary_route}/canary")
+        assert response.status_code == 200, response.text
+
+        route = http_client.get(f"{forge.base_url}/api/v1/routes/{canary_route}").json()
+        assert route["target"] == "http://example.com"
+        assert "canary" not in route
+
+    def test_unknown_route(self, http_client, forge, test_id):
+        """Test that shifting the canary of an unknown route fails with 404."""
+        response = http_client.put(
+            f"{forge.base_url}/api/v1/routes/missing_{test_id}/canary",
+            json={"weight": 50},
+        )
+
+        assert response.status_code == 404
+
+    def test_target_with_path_refused(self, http_client, forge, test_id):
+        """Test that canary targets must not include a path."""
+        preview = preview_route(http_client, forge, {
+            "name": f"canary_path_{test_id}",
+            "path": f"/canary-path/{test_id}/",
+            "target": "http://example.com",
+            "canary": {"target": "http://httpbin.org/v2", "weight": 10},
+        })
+
+        assert preview["valid"] is False
+
+
+class TestRouteGRPC:
+    """Tests for gRPC routes."""
+
+    def test_grpc_pass(self, http_client, forge, test_id):
+        """Test that a gRPC route uses grpc_pass with a gRPC scheme."""
+        preview = preview_route(http_client, forge, {
+            "name": f"grpc_{test_id}",
+            "path": f"/grpc/{test_id}/",
+            "target": "http://orders:9000",
+            "protocol": "grpc",
+            "read_timeout": "1m",
+        })
+
+        assert preview["valid"] is True
+        assert "grpc_pass grpc://orders:9000;" in preview["config"]
+        assert "grpc_read_timeout 60s;" in preview["config"]
+        assert "proxy_pass" not in preview["config"]
+
+    @pytest.mark.parametrize("route", [
+        {"target": "ftp://orders:9000"},
+        {"target": "grpc://orders:9000/v1"},
+        {"target": "grpc://orders:9000", "strip_prefix": True},
+        {"target": "grpc://orders:9000", "websocket": {}},
+        {"target": "grpc://orders:9000", "protocol": "http3"},
+    ])
+    def test_invalid_grpc_route(self, http_client, forge, test_id, route):
+        """Test that options gRPC routes cannot use are refused."""
+        preview = preview_route(http_client, forge, {
+            "name": f"grpc_bad_{test_id}",
+            "path": f"/grpc-bad/{test_id}/",
+            "protocol": "grpc",
+            **route,
+        })
+
+        assert preview["valid"] is False
+
+
+class TestRouteAccessLog:
+    """Tests for per-route access logs."""
+
+    def test_access_log_emitted(self, http_client, forge, test_id):
+        """Test that an access log keeps the shared log and adds the route's."""
+        preview = preview_route(http_client, forge, {
+            "name": f"alog_{test_id}",
+            "path": f"/alog/{test_id}/",
+            "target": "http://example.com",
+            "access_log": True,
+        })
+
+        assert "access_log /dev/stdout json_combined;" in preview["config"]
+        assert f"alog_{test_id}.log json_combined;" in preview["config"]
+
+    def test_access_log_source(self, http_client, forge, test_id):
+        """Test that the route's log source follows the route."""
+        route_name = f"alog_src_{test_id}"
+        response = http_client.post(
+            f"{forge.base_url}/api/v1/routes",
+            json={
+                "name": route_name,
+                "path": f"/alog-src/{test_id}/",
+                "target": "http://example.com",
+                "access_log": True,
+            }
+        )
+        assert response.status_code == 201, response.text
+
+        try:
+            sources = http_client.get(f"{forge.base_url}/api/v1/logs/sources").json()["sources"]
+            source = next((s for s in sources if s["name"] == f"route-{route_name}"), None)
+            assert source is not None
+            assert source["labels"]["route"] == route_name
+        finally:
+            http_client.delete(f"{forge.base_url}/api/v1/routes/{route_name}")
+
+        sources = http_client.get(f"{forge.base_url}/api/v1/logs/sources").json()["sources"]
+        assert f"route-{route_name}" not in [s["name"] for s in sources]
+
+
+class TestRouteWebSocket:
+    """Tests for the per-route WebSocket option."""
+
+    def test_websocket_upgrade(self, http_client, forge, test_id):
+        """Test that WebSocket routes pass upgrades with their timeouts."""
+        preview = preview_route(http_client, forge, {
+            "name": f"ws_{test_id}",
+            "path": f"/ws/{test_id}/",
+            "target": "http://example.com",
+            "websocket": {"read_timeout": "1h", "send_timeout": "30m"},
+        })
+
+        assert preview["valid"] is True
+        assert "proxy_set_header Upgrade $http_upgrade;" in preview["config"]
+        assert 'proxy_set_header Connection "upgrade";' in preview["config"]
+        assert "proxy_read_timeout 3600s;" in preview["config"]
+        assert "proxy_send_timeout 1800s;" in preview["config"]
+
+    def test_no_upgrade_by_default(self, http_client, forge, test_id):
+        """Test that routes without the option do not pass upgrades."""
+        preview = preview_route(http_client, forge, {
+            "name": f"ws_off_{test_id}",
+            "path": f"/ws-off/{test_id}/",
+            "target": "http://example.com",
+        })
+
+        assert "Upgrade" not in preview["config"]
+        assert 'proxy_set_header Connection "";' in preview["config"]
+
+    def test_one_read_timeout(self, http_client, forge, test_id):
+        """Test that read_timeout and websocket.read_timeout are not set together."""
+        preview = preview_route(http_client, forge, {
+            "name": f"ws_both_{test_id}",
+            "path": f"/ws-both/{test_id}/",
+            "target": "http://example.com",
+            "read_timeout": "5m",
+            "websocket": {"read_timeout": "1h"},
+        })
+
+        assert preview["valid"] is False
+        assert "read_timeout" in preview["error"]
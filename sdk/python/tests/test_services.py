@@ -0,0 +1,180 @@
+"""
+Tests for the Forge service registry.
+
+These tests verify:
+- Register, lookup, heartbeat and deregister via SDK
+- Background health checks
+- Registration validation
+- Routes made for services
+- Heartbeats sent by keep_alive
+"""
+
+import time
+
+import pytest
+import requests
+
+
+@pytest.fixture
+def cleanup_services(forge):
+    """
+    Deregister service instances after the test.
+
+    Yields:
+        list: (name, id) pairs that need cleanup
+    """
+    pending = []
+    yield pending
+
+    for name, instance_id in pending:
+        try:
+            forge.services.deregister(name, instance_id)
+        except Exception:
+            pass
+
+
+# The API checks health URLs itself, so the address is the API's own
+# name on the compose network
+API_ADDRESS = "http://api:8080"
+
+
+def wait_for_status(forge, name, instance_id, status, timeout=15.0):
+    """Poll an instance until it reaches status."""
+    deadline = time.time() + timeout
+    while time.time() < deadline:
+        for inst in forge.services.lookup(name):
+            if inst["id"] == instance_id and inst["status"] == status:
+                return inst
+        time.sleep(0.5)
+    pytest.fail(f"{name}/{instance_id} did not become {status} within {timeout}s")
+
+
+class TestServicesRegistration:
+    """Tests for registering and looking up instances."""
+
+    def test_register_and_lookup(self, forge, cleanup_services, test_id):
+        """Test registering an instance and finding it."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, "http://orders-1:8000", metadata={"version": "1.0"})
+        cleanup_services.append((name, inst["id"]))
+
+        assert inst["id"] == "orders-1-8000"
+        assert inst["status"] == "passing"
+
+        instances = forge.services.lookup(name)
+        assert [i["id"] for i in instances] == [inst["id"]]
+        assert instances[0]["metadata"] == {"version": "1.0"}
+
+        assert name in [s["name"] for s in forge.services.list()]
+
+    def test_heartbeat(self, forge, cleanup_services, test_id):
+        """Test that a heartbeat pushes the expiry back."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, "http://orders-1:8000", ttl="10s")
+        cleanup_services.append((name, inst["id"]))
+
+        time.sleep(1)
+        assert forge.services.heartbeat(name, inst["id"]) is True
+        assert forge.services.lookup(name)[0]["expires"] > inst["expires"]
+
+    def test_deregister(self, forge, test_id):
+        """Test that a deregistered service is gone."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, "http://orders-1:8000")
+
+        assert forge.services.deregister(name, inst["id"]) is True
+
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.services.lookup(name)
+        assert exc.value.response.status_code == 404
+
+    def test_heartbeat_unknown_instance(self, forge, test_id):
+        """Test that heartbeats for unknown instances fail with 404."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.services.heartbeat(f"svc_{test_id}", "missing")
+        assert exc.value.response.status_code == 404
+
+    @pytest.mark.parametrize("address", ["orders-1:8000", "ftp://orders-1", "http://orders-1:8000/api"])
+    def test_invalid_address(self, forge, test_id, address):
+        """Test that addresses must be http(s) URLs without a path."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.services.register(f"svc_{test_id}", address)
+        assert exc.value.response.status_code == 400
+
+    def test_health_url_on_other_host(self, forge, test_id):
+        """Test that health URLs must be on the instance's host."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.services.register(
+                f"svc_{test_id}", "http://orders-1:8000",
+                health_url="http://169.254.169.254/latest/meta-data/",
+            )
+        assert exc.value.response.status_code == 400
+
+
+class TestServicesHealth:
+    """Tests for health checks."""
+
+    def test_health_check_passes(self, forge, cleanup_services, test_id):
+        """Test that an instance becomes passing once its check answers."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, API_ADDRESS, health_url="/metrics")
+        cleanup_services.append((name, inst["id"]))
+
+        wait_for_status(forge, name, inst["id"], "passing")
+        assert forge.services.lookup(name, passing=True)
+
+    def test_health_check_fails(self, forge, cleanup_services, test_id):
+        """Test that an instance whose check fails is critical."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, API_ADDRESS, health_url="/no-such-page")
+        cleanup_services.append((name, inst["id"]))
+
+        critical = wait_for_status(forge, name, inst["id"], "critical")
+        assert critical["output"]
+        assert forge.services.lookup(name, passing=True) == []
+
+    def test_health_reported(self, http_client, forge, cleanup_services, test_id):
+        """Test that registered services appear in the health report."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, "http://orders-1:8000")
+        cleanup_services.append((name, inst["id"]))
+
+        response = http_client.get(f"{forge.base_url}/api/v1/health")
+        assert name in response.json().get("registered", {})
+
+
+class TestServicesRoutes:
+    """Tests for routes made for services."""
+
+    def test_route_follows_registration(self, http_client, forge, test_id):
+        """Test that a service's route exists while it is registered."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, API_ADDRESS, route={"path": f"/{name}/", "strip_prefix": True})
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/{name}")
+        assert response.status_code == 200
+        assert response.json()["target"] == API_ADDRESS
+
+        forge.services.deregister(name, inst["id"])
+
+        response = http_client.get(f"{forge.base_url}/api/v1/routes/{name}")
+        assert response.status_code == 404
+
+
+class TestServicesKeepAlive:
+    """Tests for background heartbeats."""
+
+    @pytest.mark.slow
+    def test_keep_alive(self, forge, cleanup_services, test_id):
+        """Test that keep_alive holds an instance past its TTL and stop removes it."""
+        name = f"svc_{test_id}"
+        inst = forge.services.register(name, "http://orders-1:8000", ttl="5s")
+        cleanup_services.append((name, inst["id"]))
+
+        stop = forge.services.keep_alive(inst, interval=1.0)
+        time.sleep(8)
+        assert [i["id"] for i in forge.services.lookup(name)] == [inst["id"]]
+
+        stop()
+        with pytest.raises(requests.HTTPError):
+            forge.services.lookup(name)
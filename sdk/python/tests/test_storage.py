@@ -0,0 +1,107 @@
+"""
+Tests for Forge object storage (MinIO).
+
+These tests verify:
+- Put, get, list and delete via SDK
+- Presigned URLs
+- Connection info with scoped credentials
+"""
+
+import pytest
+import requests
+
+
+@pytest.fixture
+def cleanup_objects(forge):
+    """
+    Clean up storage objects after the test.
+
+    Yields:
+        list: Object keys that need cleanup
+    """
+    pending = []
+    yield pending
+
+    for key in pending:
+        try:
+            forge.storage.delete(key)
+        except Exception:
+            pass
+
+
+class TestStorageBasicOperations:
+    """Tests for basic object operations."""
+
+    def test_put_and_get(self, forge, cleanup_objects, test_id):
+        """Test storing an object and reading it back."""
+        key = f"{test_id}/hello.txt"
+        data = b"hello from the sdk tests"
+        cleanup_objects.append(key)
+
+        stored = forge.storage.put(key, data, content_type="text/plain")
+        assert stored["key"] == key
+        assert stored["size"] == len(data)
+
+        assert forge.storage.get(key) == data
+
+    def test_list_by_prefix(self, forge, cleanup_objects, test_id):
+        """Test that listing returns only keys under the prefix."""
+        keys = [f"{test_id}/a/one", f"{test_id}/a/two", f"{test_id}/b/three"]
+        for key in keys:
+            cleanup_objects.append(key)
+            forge.storage.put(key, b"x")
+
+        listed = sorted(o["key"] for o in forge.storage.list(prefix=f"{test_id}/a/"))
+        assert listed == keys[:2]
+
+    def test_delete(self, forge, cleanup_objects, test_id):
+        """Test deleting an object."""
+        key = f"{test_id}/delete.txt"
+        cleanup_objects.append(key)
+        forge.storage.put(key, b"to delete")
+
+        assert forge.storage.delete(key) is True
+
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.storage.get(key)
+        assert exc.value.response.status_code == 404
+
+    def test_delete_missing_succeeds(self, forge, test_id):
+        """Test that deleting a missing object succeeds."""
+        assert forge.storage.delete(f"{test_id}/missing") is True
+
+
+class TestStoragePresign:
+    """Tests for presigned URLs."""
+
+    def test_presign_get(self, forge, cleanup_objects, test_id):
+        """Test that a download URL is signed for the object."""
+        key = f"{test_id}/presigned.txt"
+        cleanup_objects.append(key)
+        forge.storage.put(key, b"presigned")
+
+        url = forge.storage.presign(key, expires=60)
+        assert key in url
+        assert "X-Amz-Signature" in url
+
+    def test_presign_invalid_method(self, forge, test_id):
+        """Test that only GET and PUT can be presigned."""
+        with pytest.raises(requests.HTTPError) as exc:
+            forge.storage.presign(f"{test_id}/x", method="DELETE")
+        assert exc.value.response.status_code == 400
+
+
+class TestStorageInfo:
+    """Tests for connection info."""
+
+    def test_info_scoped_to_prefix(self, http_client, forge, test_id):
+        """Test that info issues credentials for the bucket and prefix."""
+        response = http_client.get(
+            f"{forge.base_url}/api/v1/storage/info",
+            params={"bucket": forge.storage.bucket, "prefix": f"{test_id}/"},
+        )
+        assert response.status_code == 200
+        info = response.json()
+        assert info["endpoint"]
+        assert info["access_key_id"]
+        assert info["secret_access_key"]